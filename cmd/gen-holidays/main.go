@@ -0,0 +1,57 @@
+// Package main regenerates the offline holiday dataset embedded in the
+// widgets package. Run it once a year, after holiday-cn publishes the next
+// year's schedule:
+//
+//	go generate ./internal/widgets
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/morezhou/hearth/internal/widgets"
+)
+
+func main() {
+	out := flag.String("out", "internal/widgets/data/holidays.json", "output file")
+	countries := flag.String("countries", strings.Join(widgets.DatasetCountries, ","), "comma-separated country codes")
+	years := flag.Int("years", 2, "number of years to include, starting with the current one")
+	flag.Parse()
+
+	first := time.Now().Year()
+	list := make([]int, 0, *years)
+	for y := first; y < first+*years; y++ {
+		list = append(list, y)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	ds, err := widgets.BuildHolidayDataset(ctx, strings.Split(*countries, ","), list)
+	if ds == nil {
+		fmt.Fprintf(os.Stderr, "Error building dataset: %v\n", err)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: kept previous data for: %v\n", err)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(ds); err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding dataset: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(*out, buf.Bytes(), 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", *out, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %d countries to %s\n", len(ds.Holidays), *out)
+}
@@ -0,0 +1,104 @@
+// Package main regenerates the Lucide tags and SVGs embedded in the lucide
+// package from the pinned lucide-static release. Run it after bumping lucide.Version:
+//
+//	go generate ./internal/lucide
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/morezhou/hearth/internal/icon"
+	"github.com/morezhou/hearth/internal/lucide"
+)
+
+func main() {
+	out := flag.String("out", "internal/lucide/data", "output directory")
+	cdn := flag.String("cdn", lucide.DefaultCDN, "lucide-static CDN root")
+	svgs := flag.Bool("svgs", true, "also download every icon's SVG into <out>/icons")
+	flag.Parse()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	url := fmt.Sprintf("%s@%s/tags.json", *cdn, lucide.Version)
+	body, err := get(ctx, url)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error fetching %s: %v\n", url, err)
+		os.Exit(1)
+	}
+	path := filepath.Join(*out, "tags.json")
+	if err := os.WriteFile(path, body, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", path, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote Lucide %s tags to %s\n", lucide.Version, path)
+	if !*svgs {
+		return
+	}
+
+	var tags map[string][]string
+	if err := json.Unmarshal(body, &tags); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing tags: %v\n", err)
+		os.Exit(1)
+	}
+	dir := filepath.Join(*out, "icons")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating %s: %v\n", dir, err)
+		os.Exit(1)
+	}
+	names := make(chan string)
+	var (
+		wg     sync.WaitGroup
+		failed atomic.Int32
+	)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range names {
+				svg, err := get(ctx, fmt.Sprintf("%s@%s/icons/%s.svg", *cdn, lucide.Version, name))
+				if err == nil {
+					svg, err = icon.SanitizeSVG(svg)
+				}
+				if err == nil {
+					err = os.WriteFile(filepath.Join(dir, name+".svg"), svg, 0o644)
+				}
+				if err != nil {
+					failed.Add(1)
+					fmt.Fprintf(os.Stderr, "Warning: %s: %v\n", name, err)
+				}
+			}
+		}()
+	}
+	for name := range tags {
+		names <- name
+	}
+	close(names)
+	wg.Wait()
+	fmt.Printf("Wrote %d SVGs to %s\n", len(tags)-int(failed.Load()), dir)
+}
+
+func get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status=%d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/morezhou/hearth/internal/auth"
+	"github.com/morezhou/hearth/internal/server"
+	"github.com/morezhou/hearth/internal/store"
+)
+
+const adminUsage = `Usage: hearth admin <command> [flags]
+
+Commands work on the configured database (HEARTH_DB_DSN, or -db) and don't
+need a running server; stop it first for import and vacuum.
+
+  reset-password  set a user's password
+  create-user     add a user
+  export          write a backup as JSON
+  import          restore a JSON backup
+  vacuum          checkpoint, vacuum and check the database
+
+Run "hearth admin <command> -h" for the flags of a command.
+`
+
+// runAdmin runs an admin subcommand and returns the exit code.
+func runAdmin(args []string) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "help" {
+		fmt.Fprint(os.Stderr, adminUsage)
+		return 2
+	}
+	cmds := map[string]func([]string) error{
+		"reset-password": adminResetPassword,
+		"create-user":    adminCreateUser,
+		"export":         adminExport,
+		"import":         adminImport,
+		"vacuum":         adminVacuum,
+	}
+	cmd, ok := cmds[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown command %q\n\n%s", args[0], adminUsage)
+		return 2
+	}
+	if err := cmd(args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// adminFlags is the flag set of a subcommand with the -db flag every command
+// takes.
+func adminFlags(name string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet("hearth admin "+name, flag.ExitOnError)
+	db := fs.String("db", server.LoadConfigFromEnv().DatabaseDSN, "SQLite database")
+	return fs, db
+}
+
+// openDatabase opens an existing database; a mistyped path would otherwise
+// create an empty one.
+func openDatabase(dsn string) (*store.Store, *auth.Service, error) {
+	path, _, _ := strings.Cut(strings.TrimPrefix(dsn, "file:"), "?")
+	if _, err := os.Stat(path); err != nil {
+		return nil, nil, fmt.Errorf("no database at %s", path)
+	}
+	cfg := server.LoadConfigFromEnv()
+	cfg.DatabaseDSN = dsn
+	return server.Open(cfg)
+}
+
+func adminResetPassword(args []string) error {
+	fs, db := adminFlags("reset-password")
+	username := fs.String("user", "admin", "username to reset")
+	password := fs.String("password", "", "new password (required)")
+	_ = fs.Parse(args)
+	if *password == "" {
+		fs.Usage()
+		return errors.New("-password is required")
+	}
+
+	st, authSvc, err := openDatabase(*db)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	if err := authSvc.ResetPassword(*username, *password); err != nil {
+		return err
+	}
+	fmt.Printf("Password for user '%s' has been reset successfully.\n", *username)
+	return nil
+}
+
+func adminCreateUser(args []string) error {
+	fs, db := adminFlags("create-user")
+	username := fs.String("user", "", "username (required)")
+	password := fs.String("password", "", "password (required)")
+	_ = fs.Parse(args)
+	if *username == "" || *password == "" {
+		fs.Usage()
+		return errors.New("-user and -password are required")
+	}
+
+	st, authSvc, err := openDatabase(*db)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	if err := authSvc.CreateUser(*username, *password); err != nil {
+		return err
+	}
+	fmt.Printf("User '%s' has been created.\n", *username)
+	return nil
+}
+
+func adminExport(args []string) error {
+	fs, db := adminFlags("export")
+	out := fs.String("out", "-", "output file, - for stdout")
+	_ = fs.Parse(args)
+
+	st, _, err := openDatabase(*db)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	b, err := st.ExportJSON()
+	if err != nil {
+		return err
+	}
+	if *out == "-" {
+		_, err = os.Stdout.Write(append(b, '\n'))
+		return err
+	}
+	if err := os.WriteFile(*out, b, 0o600); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Wrote backup to %s\n", *out)
+	return nil
+}
+
+func adminImport(args []string) error {
+	fs, db := adminFlags("import")
+	replace := fs.Bool("replace", false, "remove settings, groups and apps missing from the backup instead of merging")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: hearth admin import [flags] <backup.json|->")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("a backup file is required")
+	}
+
+	var b []byte
+	var err error
+	if name := fs.Arg(0); name == "-" {
+		b, err = io.ReadAll(os.Stdin)
+	} else {
+		b, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return err
+	}
+	var payload store.Export
+	if err := json.Unmarshal(b, &payload); err != nil {
+		return fmt.Errorf("parse backup: %w", err)
+	}
+
+	st, _, err := openDatabase(*db)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	if *replace {
+		err = st.ReplaceAll(payload)
+	} else {
+		err = st.ImportAll(payload)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d groups and %d apps.\n", len(payload.Groups), len(payload.Apps))
+	return nil
+}
+
+func adminVacuum(args []string) error {
+	fs, db := adminFlags("vacuum")
+	_ = fs.Parse(args)
+
+	st, _, err := openDatabase(*db)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	rep, err := st.Maintain(ctx, true)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Vacuumed in %dms: %d bytes before (%d free), %d after.\n", rep.DurationMs, rep.SizeBefore, rep.FreeBefore, rep.SizeAfter)
+	if !rep.IntegrityOK {
+		return fmt.Errorf("integrity check failed: %s", strings.Join(rep.Integrity, "; "))
+	}
+	fmt.Println("Integrity check: ok")
+	return nil
+}
+
+// runSeed runs "hearth seed -demo", which adds the demo data to the
+// configured database like HEARTH_DEMO=1 does at startup.
+func runSeed(args []string) int {
+	fs := flag.NewFlagSet("hearth seed", flag.ExitOnError)
+	demo := fs.Bool("demo", false, "add sample groups, apps and widgets")
+	_ = fs.Parse(args)
+	if !*demo {
+		fmt.Fprintln(os.Stderr, "Usage: hearth seed -demo")
+		return 2
+	}
+
+	srv, err := server.New(server.LoadConfigFromEnv())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	added, err := srv.SeedDemo(context.Background())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if added {
+		fmt.Println("Demo data has been added.")
+	} else {
+		fmt.Println("Demo data was already added.")
+	}
+	return 0
+}
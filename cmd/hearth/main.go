@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
 	"net/http"
 	"os"
@@ -10,10 +11,27 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/morezhou/hearth/internal/agent"
+	"github.com/morezhou/hearth/internal/metrics"
 	"github.com/morezhou/hearth/internal/server"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "admin":
+			os.Exit(runAdmin(os.Args[2:]))
+		case "seed":
+			os.Exit(runSeed(os.Args[2:]))
+		}
+	}
+	agentMode := flag.Bool("agent", false, "run as a metrics agent for another Hearth instance instead of the dashboard")
+	flag.Parse()
+	if *agentMode {
+		runAgent()
+		return
+	}
+
 	cfg := server.LoadConfigFromEnv()
 	absDataDir, _ := filepath.Abs(cfg.DataDir)
 	log.Printf("config storage (DataDir): %s", absDataDir)
@@ -25,6 +43,10 @@ func main() {
 		log.Fatalf("server init: %v", err)
 	}
 
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	srv.StartBackgroundJobs(jobsCtx)
+
 	httpServer := &http.Server{
 		Addr:              cfg.Addr,
 		Handler:           srv.Router(),
@@ -42,7 +64,42 @@ func main() {
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
 	<-stop
 
+	// Drain in-flight requests first, then stop the background jobs and close
+	// the database once nothing uses it anymore.
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
+	if err := httpServer.Shutdown(ctx); err != nil {
+		log.Printf("http shutdown: %v", err)
+	}
+	stopJobs()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("background jobs shutdown: %v", err)
+	}
+}
+
+// runAgent serves this machine's metrics to a dashboard until interrupted.
+func runAgent() {
+	cfg, err := agent.ConfigFromEnv()
+	if err != nil {
+		log.Fatalf("agent init: %v", err)
+	}
+	httpServer := &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           agent.Handler(cfg, metrics.NewCollector()),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		log.Printf("agent listening on %s", cfg.Addr)
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("listen: %v", err)
+		}
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	<-stop
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 	_ = httpServer.Shutdown(ctx)
 }
@@ -0,0 +1,137 @@
+// Package agent implements Hearth's metrics agent: a small token-protected
+// HTTP server reporting the metrics of the machine it runs on, and the client
+// a dashboard polls agents with.
+package agent
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/morezhou/hearth/internal/metrics"
+)
+
+// MetricsPath is where agents serve host metrics.
+const MetricsPath = "/api/agent/metrics"
+
+// Config configures an agent.
+type Config struct {
+	Addr string
+	// Token must be sent by the dashboard as "Authorization: Bearer <token>".
+	Token   string
+	Options metrics.Options
+}
+
+// ConfigFromEnv reads the agent configuration: HEARTH_AGENT_ADDR (default
+// :8788), HEARTH_AGENT_TOKEN (required) and the comma-separated
+// HEARTH_AGENT_INTERFACES / HEARTH_AGENT_EXCLUDE_MOUNTS filters, plus
+// HEARTH_AGENT_DISK_PATHS (see metrics.ParseDiskPaths) and the
+// HEARTH_AGENT_UPS daemon address.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Addr:  getEnv("HEARTH_AGENT_ADDR", ":8788"),
+		Token: strings.TrimSpace(os.Getenv("HEARTH_AGENT_TOKEN")),
+		Options: metrics.Options{
+			Interfaces:    splitList(os.Getenv("HEARTH_AGENT_INTERFACES")),
+			ExcludeMounts: metrics.DefaultExcludedMounts,
+			DiskPaths:     metrics.ParseDiskPaths(os.Getenv("HEARTH_AGENT_DISK_PATHS")),
+			UPS:           strings.TrimSpace(os.Getenv("HEARTH_AGENT_UPS")),
+		},
+	}
+	if v, ok := os.LookupEnv("HEARTH_AGENT_EXCLUDE_MOUNTS"); ok {
+		cfg.Options.ExcludeMounts = splitList(v)
+	}
+	if cfg.Token == "" {
+		return cfg, errors.New("HEARTH_AGENT_TOKEN is required in agent mode")
+	}
+	return cfg, nil
+}
+
+// Handler serves the agent API.
+func Handler(cfg Config, c *metrics.Collector) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "agent": true})
+	})
+	mux.HandleFunc("GET "+MetricsPath, func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r, cfg.Token) {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+		m, err := c.Collect(r.Context(), cfg.Options)
+		if err != nil {
+			log.Printf("[agent] collect partial: %v", err)
+		}
+		writeJSON(w, http.StatusOK, m)
+	})
+	return mux
+}
+
+func authorized(r *http.Request, token string) bool {
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && token != "" && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+// Client fetches metrics from agents.
+type Client struct {
+	HTTP *http.Client
+}
+
+// NewClient returns a client with a short timeout; agents answer within a
+// few hundred milliseconds.
+func NewClient() *Client {
+	return &Client{HTTP: &http.Client{Timeout: 5 * time.Second}}
+}
+
+// Fetch returns the host metrics of the agent at baseURL.
+func (c *Client) Fetch(ctx context.Context, baseURL, token string) (metrics.HostMetrics, error) {
+	var m metrics.HostMetrics
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(baseURL, "/")+MetricsPath, nil)
+	if err != nil {
+		return m, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return m, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		return m, fmt.Errorf("agent returned status %d", resp.StatusCode)
+	}
+	err = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&m)
+	return m, err
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func getEnv(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func splitList(s string) []string {
+	out := []string{}
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
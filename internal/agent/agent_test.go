@@ -0,0 +1,48 @@
+package agent
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/morezhou/hearth/internal/metrics"
+)
+
+func TestAgentRoundTrip(t *testing.T) {
+	srv := httptest.NewServer(Handler(Config{Token: "s3cret"}, metrics.NewCollector()))
+	defer srv.Close()
+
+	c := NewClient()
+	m, err := c.Fetch(context.Background(), srv.URL+"/", "s3cret")
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if m.CollectedAt == 0 || m.CPUCores == 0 {
+		t.Fatalf("unexpected metrics: %+v", m)
+	}
+
+	if _, err := c.Fetch(context.Background(), srv.URL, "wrong"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("expected 401 for a wrong token, got %v", err)
+	}
+	if _, err := c.Fetch(context.Background(), srv.URL, ""); err == nil {
+		t.Fatal("expected an error without a token")
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("HEARTH_AGENT_TOKEN", "")
+	if _, err := ConfigFromEnv(); err == nil {
+		t.Fatal("expected an error without a token")
+	}
+	t.Setenv("HEARTH_AGENT_TOKEN", "tok")
+	t.Setenv("HEARTH_AGENT_INTERFACES", "eth0, wlan*")
+	t.Setenv("HEARTH_AGENT_EXCLUDE_MOUNTS", "")
+	cfg, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Addr != ":8788" || len(cfg.Options.Interfaces) != 2 || cfg.Options.Interfaces[1] != "wlan*" || len(cfg.Options.ExcludeMounts) != 0 {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+}
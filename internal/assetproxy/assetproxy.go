@@ -0,0 +1,370 @@
+// Package assetproxy fetches small images from vetted upstream hosts and
+// caches them in a storage backend whose size is bounded by evicting the least
+// recently used assets.
+//
+// Only the market icon source is registered so far. RSS feed favicons and
+// integration logos are meant to become sources too once those features
+// fetch images; until then nothing else goes through the proxy.
+package assetproxy
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/morezhou/hearth/internal/storage"
+)
+
+var (
+	// ErrNotFound means no upstream had the asset.
+	ErrNotFound = errors.New("assetproxy: not found")
+	// ErrUnknownSource is returned for names of unregistered sources.
+	ErrUnknownSource = errors.New("assetproxy: unknown source")
+)
+
+// ImageTypes are the raster formats sources accept by default. SVG is left
+// out: served from our origin it could run scripts.
+var ImageTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp", "image/x-icon"}
+
+const (
+	// DefaultMaxAssetBytes caps one asset when Source.MaxBytes is 0.
+	DefaultMaxAssetBytes = 2 << 20
+	// DefaultMaxCacheBytes caps the cache when New is given 0.
+	DefaultMaxCacheBytes = 64 << 20
+)
+
+var nameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`)
+
+// Source is a kind of asset and where it may come from.
+type Source struct {
+	// Name namespaces the cached assets, e.g. "markets".
+	Name string
+	// Hosts are the hosts upstream URLs may point to: "example.com" or
+	// "*.example.com" for its subdomains.
+	Hosts []string
+	// Types are the accepted content types, checked against both the
+	// upstream header and the sniffed content; empty means ImageTypes.
+	Types    []string
+	MaxBytes int64
+}
+
+func (s Source) allowsHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, h := range s.Hosts {
+		h = strings.ToLower(h)
+		if sub, ok := strings.CutPrefix(h, "*."); ok {
+			if strings.HasSuffix(host, "."+sub) {
+				return true
+			}
+		} else if host == h {
+			return true
+		}
+	}
+	return false
+}
+
+func (s Source) types() []string {
+	if len(s.Types) == 0 {
+		return ImageTypes
+	}
+	return s.Types
+}
+
+// Asset is a cached or fetched asset.
+type Asset struct {
+	Data        []byte
+	ContentType string
+}
+
+// entry is a cached asset in the LRU list, most recently used first.
+type entry struct {
+	key  string
+	size int64
+}
+
+// Proxy fetches and caches the assets of registered sources.
+type Proxy struct {
+	store    storage.Backend
+	client   *http.Client
+	maxBytes int64
+
+	mu      sync.Mutex
+	sources map[string]Source
+	loaded  bool
+	lru     *list.List
+	index   map[string]*list.Element
+	total   int64
+}
+
+// New returns a proxy caching in store, fetching with client (which should
+// guard against internal addresses) and keeping at most maxBytes cached.
+func New(store storage.Backend, client *http.Client, maxBytes int64) *Proxy {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxCacheBytes
+	}
+	// Redirects must stay on the hosts of the source being fetched.
+	c := *client
+	next := client.CheckRedirect
+	c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if src, ok := req.Context().Value(sourceKey{}).(Source); ok {
+			if _, err := checkURL(src, req.URL.String()); err != nil {
+				return err
+			}
+		}
+		if next != nil {
+			return next(req, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("assetproxy: too many redirects")
+		}
+		return nil
+	}
+	return &Proxy{store: store, client: &c, maxBytes: maxBytes, sources: map[string]Source{}, lru: list.New(), index: map[string]*list.Element{}}
+}
+
+// sourceKey carries the Source of a fetch to CheckRedirect.
+type sourceKey struct{}
+
+// Register adds or replaces a source.
+func (p *Proxy) Register(s Source) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sources[s.Name] = s
+}
+
+// Owns reports whether key, a key of the store, belongs to a source.
+func (p *Proxy) Owns(key string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	src, _, ok := strings.Cut(key, "/")
+	_, known := p.sources[src]
+	return ok && known
+}
+
+// Stats returns the number and total size of the cached assets.
+func (p *Proxy) Stats(ctx context.Context) (int, int64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.load(ctx); err != nil {
+		return 0, 0, err
+	}
+	return p.lru.Len(), p.total, nil
+}
+
+// CheckURL reports why source may not fetch from u, or nil.
+func (p *Proxy) CheckURL(source, u string) error {
+	p.mu.Lock()
+	src, ok := p.sources[source]
+	p.mu.Unlock()
+	if !ok {
+		return ErrUnknownSource
+	}
+	_, err := checkURL(src, u)
+	return err
+}
+
+func checkURL(src Source, raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || u.User != nil {
+		return nil, fmt.Errorf("assetproxy: invalid url %q", raw)
+	}
+	if !src.allowsHost(u.Hostname()) {
+		return nil, fmt.Errorf("assetproxy: host %q not allowed for %s", u.Hostname(), src.Name)
+	}
+	return u, nil
+}
+
+// Get returns the asset name of source from the cache, or else from the
+// first of urls that has it, caching it. URLs outside the source's hosts are
+// skipped, and so are responses that are too large or of a type it doesn't
+// accept. When caching fails, the error comes with the fetched asset.
+func (p *Proxy) Get(ctx context.Context, source, name string, urls []string) (Asset, error) {
+	p.mu.Lock()
+	src, ok := p.sources[source]
+	p.mu.Unlock()
+	if !ok {
+		return Asset{}, ErrUnknownSource
+	}
+	if !nameRe.MatchString(name) {
+		return Asset{}, fmt.Errorf("assetproxy: invalid name %q", name)
+	}
+	key := source + "/" + name
+	if a, ok := p.cached(ctx, src, key); ok {
+		return a, nil
+	}
+	var lastErr error = ErrNotFound
+	for _, raw := range urls {
+		u, err := checkURL(src, raw)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		a, err := p.fetch(ctx, src, u)
+		if err != nil {
+			lastErr = err
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		if err := p.put(ctx, key, a); err != nil {
+			return a, err
+		}
+		return a, nil
+	}
+	return Asset{}, lastErr
+}
+
+// cached returns the stored asset under key, if it still passes the
+// source's checks.
+func (p *Proxy) cached(ctx context.Context, src Source, key string) (Asset, bool) {
+	rc, _, err := p.store.Get(ctx, key)
+	if err != nil {
+		return Asset{}, false
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, maxBytes(src)+1))
+	if err != nil || int64(len(data)) > maxBytes(src) {
+		return Asset{}, false
+	}
+	ct := http.DetectContentType(data)
+	if !slices.Contains(src.types(), ct) {
+		return Asset{}, false
+	}
+	p.mu.Lock()
+	if p.load(ctx) == nil {
+		p.touch(key, int64(len(data)))
+	}
+	p.mu.Unlock()
+	return Asset{Data: data, ContentType: ct}, true
+}
+
+func maxBytes(src Source) int64 {
+	if src.MaxBytes > 0 {
+		return src.MaxBytes
+	}
+	return DefaultMaxAssetBytes
+}
+
+// fetch downloads u and verifies its size and type.
+func (p *Proxy) fetch(ctx context.Context, src Source, u *url.URL) (Asset, error) {
+	ctx, cancel := context.WithTimeout(context.WithValue(ctx, sourceKey{}, src), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return Asset{}, err
+	}
+	req.Header.Set("Accept", strings.Join(src.types(), ",")+";q=0.9,*/*;q=0.1")
+	req.Header.Set("User-Agent", "Hearth/0.1")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return Asset{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return Asset{}, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Asset{}, fmt.Errorf("assetproxy: %s returned %d", u.Host, resp.StatusCode)
+	}
+	max := maxBytes(src)
+	if resp.ContentLength > max {
+		return Asset{}, fmt.Errorf("assetproxy: asset larger than %d bytes", max)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, max+1))
+	if err != nil {
+		return Asset{}, err
+	}
+	if int64(len(data)) > max {
+		return Asset{}, fmt.Errorf("assetproxy: asset larger than %d bytes", max)
+	}
+	sniffed := http.DetectContentType(data)
+	if !slices.Contains(src.types(), sniffed) {
+		return Asset{}, fmt.Errorf("assetproxy: unexpected content %s", sniffed)
+	}
+	if h := resp.Header.Get("Content-Type"); h != "" {
+		declared, _, _ := mime.ParseMediaType(h)
+		// Hosts serving raw files often say octet-stream; anything else
+		// has to agree with the content.
+		if declared != sniffed && declared != "application/octet-stream" && !(declared == "image/vnd.microsoft.icon" && sniffed == "image/x-icon") {
+			return Asset{}, fmt.Errorf("assetproxy: content type %s does not match content %s", declared, sniffed)
+		}
+	}
+	return Asset{Data: data, ContentType: sniffed}, nil
+}
+
+// put stores a under key and evicts the least recently used assets beyond
+// the cache size.
+func (p *Proxy) put(ctx context.Context, key string, a Asset) error {
+	if err := p.store.Put(ctx, key, a.Data, a.ContentType); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.load(ctx); err != nil {
+		return err
+	}
+	p.touch(key, int64(len(a.Data)))
+	return p.evict(ctx)
+}
+
+// load builds the LRU list from the stored assets of the registered sources
+// on first use, ordered by modification time.
+func (p *Proxy) load(ctx context.Context) error {
+	if p.loaded {
+		return nil
+	}
+	var objs []storage.Object
+	for name := range p.sources {
+		list, err := p.store.List(ctx, name+"/")
+		if err != nil {
+			return err
+		}
+		objs = append(objs, list...)
+	}
+	slices.SortFunc(objs, func(a, b storage.Object) int { return a.ModTime.Compare(b.ModTime) })
+	for _, o := range objs {
+		p.touch(o.Key, o.Size)
+	}
+	p.loaded = true
+	return nil
+}
+
+// touch marks key as just used.
+func (p *Proxy) touch(key string, size int64) {
+	if el, ok := p.index[key]; ok {
+		e := el.Value.(*entry)
+		p.total += size - e.size
+		e.size = size
+		p.lru.MoveToFront(el)
+		return
+	}
+	p.index[key] = p.lru.PushFront(&entry{key: key, size: size})
+	p.total += size
+}
+
+// evict deletes the least recently used assets until the cache fits,
+// keeping the newest one even if it alone is too large.
+func (p *Proxy) evict(ctx context.Context) error {
+	for p.total > p.maxBytes && p.lru.Len() > 1 {
+		el := p.lru.Back()
+		e := el.Value.(*entry)
+		if err := p.store.Delete(ctx, e.key); err != nil {
+			return err
+		}
+		p.lru.Remove(el)
+		delete(p.index, e.key)
+		p.total -= e.size
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package assetproxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/morezhou/hearth/internal/storage"
+)
+
+var png = append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 100)...)
+
+func TestProxy(t *testing.T) {
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		switch r.URL.Path {
+		case "/a.png", "/b.png", "/c.png":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write(png)
+		case "/page.png":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte("<html><script>alert(1)</script></html>"))
+		case "/lying.png":
+			w.Header().Set("Content-Type", "image/jpeg")
+			_, _ = w.Write(png)
+		case "/huge.png":
+			_, _ = w.Write(append(append([]byte{}, png...), make([]byte, 1000)...))
+		case "/away":
+			http.Redirect(w, r, "http://elsewhere.invalid/a.png", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer upstream.Close()
+
+	local, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Room for two assets.
+	p := New(local, upstream.Client(), int64(2*len(png)+10))
+	p.Register(Source{Name: "logos", Hosts: []string{"127.0.0.1"}, MaxBytes: 500})
+	ctx := context.Background()
+	get := func(name, path string) (Asset, error) {
+		return p.Get(ctx, "logos", name, []string{upstream.URL + path})
+	}
+
+	if _, err := p.Get(ctx, "logos", "x.png", []string{"https://evil.example/a.png"}); err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Fatalf("a host outside the allowlist: %v", err)
+	}
+	if _, err := p.Get(ctx, "other", "x.png", nil); !errors.Is(err, ErrUnknownSource) {
+		t.Fatalf("an unknown source: %v", err)
+	}
+	if _, err := get("../x.png", "/a.png"); err == nil {
+		t.Fatal("expected a traversing name to be rejected")
+	}
+	for _, path := range []string{"/page.png", "/lying.png", "/huge.png", "/away"} {
+		if _, err := get("bad.png", path); err == nil {
+			t.Errorf("%s: expected the asset to be rejected", path)
+		}
+	}
+	if _, err := get("missing.png", "/missing.png"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("missing asset: %v", err)
+	}
+
+	a, err := get("a.png", "/a.png")
+	if err != nil || a.ContentType != "image/png" || len(a.Data) != len(png) {
+		t.Fatalf("Get: %+v %v", a.ContentType, err)
+	}
+	before := hits.Load()
+	if _, err := get("a.png", "/a.png"); err != nil || hits.Load() != before {
+		t.Fatalf("expected a cache hit: %v (%d upstream hits)", err, hits.Load()-before)
+	}
+
+	// b is cached, a used again, so c evicts b.
+	if _, err := get("b.png", "/b.png"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := get("a.png", "/a.png"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := get("c.png", "/c.png"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := local.Stat(ctx, "logos/b.png"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("expected the least recently used asset to be evicted: %v", err)
+	}
+	if _, err := local.Stat(ctx, "logos/a.png"); err != nil {
+		t.Errorf("expected the recently used asset to stay: %v", err)
+	}
+	if n, size, err := p.Stats(ctx); err != nil || n != 2 || size != int64(2*len(png)) {
+		t.Errorf("Stats = %d, %d, %v", n, size, err)
+	}
+	if !p.Owns("logos/a.png") || p.Owns("apps/a.png") {
+		t.Error("Owns should match the registered sources only")
+	}
+}
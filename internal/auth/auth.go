@@ -1,12 +1,16 @@
 package auth
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"database/sql"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"log/slog"
+	"os"
 	"sync"
 	"time"
 
@@ -15,8 +19,22 @@ import (
 )
 
 type Config struct {
-	DB         *sql.DB
+	DB *sql.DB
+	// SessionTTL is how long a session lasts without being used; every use
+	// extends it by as much.
 	SessionTTL string
+	// SessionMaxAge is how long a session lasts at most, however often it is
+	// used. Empty means no limit.
+	SessionMaxAge string
+	// TokenKey, when set, makes the sessions table hold HMACs of the session
+	// tokens instead of the tokens, so a copy of the database can't be used
+	// to log in. Enabling it ends the sessions stored without it.
+	TokenKey []byte
+	// InitialPassword is the password of the admin created on first boot.
+	// When empty a random one is generated, logged and written to
+	// InitialPasswordFile, and must be changed after logging in.
+	InitialPassword     string
+	InitialPasswordFile string
 }
 
 // loginAttempt tracks failed login attempts for rate limiting.
@@ -27,8 +45,13 @@ type loginAttempt struct {
 }
 
 type Service struct {
-	db         *sql.DB
-	sessionTTL time.Duration
+	db            *sql.DB
+	sessionTTL    time.Duration
+	sessionMaxAge time.Duration
+	tokenKey      []byte
+
+	initialPassword     string
+	initialPasswordFile string
 
 	// Rate limiting for login attempts (in-memory, resets on restart).
 	rateMu       sync.Mutex
@@ -43,41 +66,128 @@ func New(cfg Config) (*Service, error) {
 	if err != nil {
 		return nil, err
 	}
+	var maxAge time.Duration
+	if cfg.SessionMaxAge != "" {
+		if maxAge, err = time.ParseDuration(cfg.SessionMaxAge); err != nil {
+			return nil, err
+		}
+	}
 	s := &Service{
-		db:           cfg.DB,
-		sessionTTL:   ttl,
-		loginAttemps: make(map[string]*loginAttempt),
+		db:            cfg.DB,
+		sessionTTL:    ttl,
+		sessionMaxAge: maxAge,
+		tokenKey:      cfg.TokenKey,
+		loginAttemps:  make(map[string]*loginAttempt),
+
+		initialPassword:     cfg.InitialPassword,
+		initialPasswordFile: cfg.InitialPasswordFile,
+	}
+	if s.tokenKey != nil {
+		if _, err := s.db.Exec(`DELETE FROM sessions WHERE token NOT LIKE 'h:%'`); err != nil {
+			return nil, err
+		}
 	}
-	if err := s.ensureDefaultAdmin(); err != nil {
+	if err := s.EnsureDefaultAdmin(); err != nil {
 		return nil, err
 	}
 	return s, nil
 }
 
-// Default admin credentials:
-// username: admin
-// password: admin
-// (You should change it after first login.)
-func (s *Service) ensureDefaultAdmin() error {
+// EnsureDefaultAdmin creates the "admin" user when there are no users, on
+// first boot and after a full reset. Unless Config.InitialPassword is set, its
+// password is random: it is logged, written to Config.InitialPasswordFile and
+// has to be changed after logging in.
+func (s *Service) EnsureDefaultAdmin() error {
 	var cnt int
 	if err := s.db.QueryRow(`SELECT COUNT(1) FROM users`).Scan(&cnt); err != nil {
 		return err
 	}
 	if cnt > 0 {
+		// A file whose removal failed would outlive the password it holds.
+		var pending int
+		if err := s.db.QueryRow(`SELECT COUNT(1) FROM users WHERE must_change_password = 1`).Scan(&pending); err == nil && pending == 0 {
+			s.passwordChanged()
+		}
 		return nil
 	}
 
-	hash, err := bcrypt.GenerateFromPassword([]byte("admin"), bcrypt.DefaultCost)
+	password, mustChange := s.initialPassword, false
+	if password == "" {
+		var err error
+		if password, err = newToken(12); err != nil {
+			return err
+		}
+		mustChange = true
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return err
 	}
 
 	now := time.Now().Unix()
-	_, err = s.db.Exec(`INSERT INTO users (id, username, password_hash, created_at) VALUES (?, ?, ?, ?)`,
-		uuid.NewString(), "admin", string(hash), now,
-	)
-	slog.Info("created default admin user", "username", "admin")
-	return err
+	if _, err := s.db.Exec(`INSERT INTO users (id, username, password_hash, created_at, must_change_password) VALUES (?, ?, ?, ?, ?)`,
+		uuid.NewString(), "admin", string(hash), now, mustChange,
+	); err != nil {
+		return err
+	}
+	if !mustChange {
+		slog.Info("created default admin user", "username", "admin")
+		return nil
+	}
+	slog.Warn("created admin user with a generated password; change it after logging in", "username", "admin", "password", password)
+	if s.initialPasswordFile != "" {
+		if err := os.WriteFile(s.initialPasswordFile, []byte(password+"\n"), 0o600); err != nil {
+			slog.Warn("failed to write initial password file", "path", s.initialPasswordFile, "error", err)
+		}
+	}
+	return nil
+}
+
+// PasswordChangeRequired reports whether the user still has a generated
+// password, which has to be changed before anything else.
+func (s *Service) PasswordChangeRequired(userID string) (bool, error) {
+	var must bool
+	if err := s.db.QueryRow(`SELECT must_change_password FROM users WHERE id = ?`, userID).Scan(&must); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, errors.New("user not found")
+		}
+		return false, err
+	}
+	return must, nil
+}
+
+// Username returns the name of the user with id.
+func (s *Service) Username(userID string) (string, error) {
+	var name string
+	if err := s.db.QueryRow(`SELECT username FROM users WHERE id = ?`, userID).Scan(&name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", errors.New("user not found")
+		}
+		return "", err
+	}
+	return name, nil
+}
+
+// UserID returns the id of the user named username.
+func (s *Service) UserID(username string) (string, error) {
+	var id string
+	if err := s.db.QueryRow(`SELECT id FROM users WHERE username = ?`, username).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", errors.New("user not found")
+		}
+		return "", err
+	}
+	return id, nil
+}
+
+// passwordChanged forgets the generated password once it is replaced.
+func (s *Service) passwordChanged() {
+	if s.initialPasswordFile == "" {
+		return
+	}
+	if err := os.Remove(s.initialPasswordFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Warn("failed to remove initial password file", "path", s.initialPasswordFile, "error", err)
+	}
 }
 
 // Rate limiting constants.
@@ -189,8 +299,8 @@ func (s *Service) Login(username, password string) (string, error) {
 	}
 
 	now := time.Now()
-	exp := now.Add(s.sessionTTL).Unix()
-	_, err = s.db.Exec(`INSERT INTO sessions (token, user_id, expires_at, created_at) VALUES (?, ?, ?, ?)`, token, userID, exp, now.Unix())
+	exp := s.sessionExpiry(now, now.Unix())
+	_, err = s.db.Exec(`INSERT INTO sessions (token, user_id, expires_at, created_at) VALUES (?, ?, ?, ?)`, s.sessionKey(token), userID, exp, now.Unix())
 	if err != nil {
 		return "", err
 	}
@@ -200,26 +310,79 @@ func (s *Service) Login(username, password string) (string, error) {
 }
 
 func (s *Service) Logout(token string) error {
-	_, err := s.db.Exec(`DELETE FROM sessions WHERE token = ?`, token)
+	_, err := s.db.Exec(`DELETE FROM sessions WHERE token = ?`, s.sessionKey(token))
 	return err
 }
 
+// sessionRefreshStep is how far a session's expiry has to move before
+// Validate writes it, so busy sessions don't write on every request.
+const sessionRefreshStep = time.Minute
+
+// Validate returns the user of a session and extends the session by
+// SessionTTL, up to SessionMaxAge after it was created.
 func (s *Service) Validate(token string) (string, error) {
+	token = s.sessionKey(token)
 	var userID string
-	var expiresAt int64
-	if err := s.db.QueryRow(`SELECT user_id, expires_at FROM sessions WHERE token = ?`, token).Scan(&userID, &expiresAt); err != nil {
+	var expiresAt, createdAt int64
+	if err := s.db.QueryRow(`SELECT user_id, expires_at, created_at FROM sessions WHERE token = ?`, token).Scan(&userID, &expiresAt, &createdAt); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return "", errors.New("unauthorized")
 		}
 		return "", err
 	}
-	if time.Now().Unix() > expiresAt {
+	now := time.Now()
+	if now.Unix() > expiresAt || (s.sessionMaxAge > 0 && now.Unix() > createdAt+int64(s.sessionMaxAge/time.Second)) {
 		_, _ = s.db.Exec(`DELETE FROM sessions WHERE token = ?`, token)
 		return "", errors.New("unauthorized")
 	}
+	if exp := s.sessionExpiry(now, createdAt); exp-expiresAt >= int64(sessionRefreshStep/time.Second) {
+		if _, err := s.db.Exec(`UPDATE sessions SET expires_at = ? WHERE token = ?`, exp, token); err != nil {
+			slog.Warn("failed to refresh session", "error", err)
+		}
+	}
 	return userID, nil
 }
 
+// sessionExpiry is the expiry of a session created at createdAt and used at
+// now.
+func (s *Service) sessionExpiry(now time.Time, createdAt int64) int64 {
+	exp := now.Add(s.sessionTTL).Unix()
+	if s.sessionMaxAge > 0 {
+		exp = min(exp, createdAt+int64(s.sessionMaxAge/time.Second))
+	}
+	return exp
+}
+
+// SessionMaxAge is the longest a session lasts, or 0 without a limit.
+func (s *Service) SessionMaxAge() time.Duration {
+	return s.sessionMaxAge
+}
+
+// PurgeExpiredSessions deletes the sessions that expired or outlived
+// SessionMaxAge and returns how many there were.
+func (s *Service) PurgeExpiredSessions() (int64, error) {
+	now := time.Now().Unix()
+	oldest := int64(0)
+	if s.sessionMaxAge > 0 {
+		oldest = now - int64(s.sessionMaxAge/time.Second)
+	}
+	res, err := s.db.Exec(`DELETE FROM sessions WHERE expires_at < ? OR created_at < ?`, now, oldest)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// sessionKey returns the sessions table key of a session token.
+func (s *Service) sessionKey(token string) string {
+	if s.tokenKey == nil {
+		return token
+	}
+	mac := hmac.New(sha256.New, s.tokenKey)
+	mac.Write([]byte(token))
+	return "h:" + hex.EncodeToString(mac.Sum(nil))
+}
+
 func newToken(n int) (string, error) {
 	b := make([]byte, n)
 	if _, err := rand.Read(b); err != nil {
@@ -228,6 +391,26 @@ func newToken(n int) (string, error) {
 	return base64.RawURLEncoding.EncodeToString(b), nil
 }
 
+// VerifyPassword checks password against the user's, for actions that ask
+// for it again. Failures count towards the login rate limit.
+func (s *Service) VerifyPassword(userID, password string) error {
+	var username, passwordHash string
+	if err := s.db.QueryRow(`SELECT username, password_hash FROM users WHERE id = ?`, userID).Scan(&username, &passwordHash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("user not found")
+		}
+		return err
+	}
+	if err := s.checkRateLimit(username); err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password)); err != nil {
+		s.recordFailedLogin(username)
+		return errors.New("incorrect password")
+	}
+	return nil
+}
+
 // --------------------------------------------------------------------------- //
 // ChangePassword changes a user's password after verifying the old password.
 func (s *Service) ChangePassword(userID string, oldPassword, newPassword string) error {
@@ -256,10 +439,11 @@ func (s *Service) ChangePassword(userID string, oldPassword, newPassword string)
 		return fmt.Errorf("failed to hash password: %w", err)
 	}
 
-	_, err = s.db.Exec(`UPDATE users SET password_hash = ? WHERE id = ?`, string(newHash), userID)
+	_, err = s.db.Exec(`UPDATE users SET password_hash = ?, must_change_password = 0 WHERE id = ?`, string(newHash), userID)
 	if err != nil {
 		return fmt.Errorf("failed to update password: %w", err)
 	}
+	s.passwordChanged()
 
 	slog.Info("password changed", "user_id", userID)
 	return nil
@@ -281,7 +465,7 @@ func (s *Service) ResetPassword(username, newPassword string) error {
 		return fmt.Errorf("failed to hash password: %w", err)
 	}
 
-	result, err := s.db.Exec(`UPDATE users SET password_hash = ? WHERE username = ?`, string(newHash), username)
+	result, err := s.db.Exec(`UPDATE users SET password_hash = ?, must_change_password = 0 WHERE username = ?`, string(newHash), username)
 	if err != nil {
 		return fmt.Errorf("failed to update password: %w", err)
 	}
@@ -290,7 +474,40 @@ func (s *Service) ResetPassword(username, newPassword string) error {
 	if rows == 0 {
 		return errors.New("user not found")
 	}
+	s.passwordChanged()
 
 	slog.Info("password reset", "username", username)
 	return nil
 }
+
+// --------------------------------------------------------------------------- //
+// CreateUser adds a user with the given password. Every user is an admin.
+func (s *Service) CreateUser(username, password string) error {
+	if username == "" {
+		return errors.New("username cannot be empty")
+	}
+	if len(password) < 4 {
+		return errors.New("password must be at least 4 characters")
+	}
+
+	var cnt int
+	if err := s.db.QueryRow(`SELECT COUNT(1) FROM users WHERE username = ?`, username).Scan(&cnt); err != nil {
+		return fmt.Errorf("failed to query user: %w", err)
+	}
+	if cnt > 0 {
+		return errors.New("user already exists")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	if _, err := s.db.Exec(`INSERT INTO users (id, username, password_hash, created_at) VALUES (?, ?, ?, ?)`,
+		uuid.NewString(), username, string(hash), time.Now().Unix(),
+	); err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+
+	slog.Info("user created", "username", username)
+	return nil
+}
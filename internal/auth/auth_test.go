@@ -2,7 +2,11 @@ package auth
 
 import (
 	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	_ "modernc.org/sqlite"
 )
@@ -39,6 +43,58 @@ func TestDefaultAdmin(t *testing.T) {
 	}
 }
 
+func TestGeneratedInitialPassword(t *testing.T) {
+	db := newTestDB(t)
+	setupSchema(t, db)
+	file := filepath.Join(t.TempDir(), "initial-password")
+
+	svc, err := New(Config{DB: db, SessionTTL: "1h", InitialPasswordFile: file})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("initial password not written: %v", err)
+	}
+	password := strings.TrimSpace(string(b))
+	if len(password) < 12 || password == "admin" {
+		t.Fatalf("weak generated password %q", password)
+	}
+	if _, err := svc.Login("admin", "admin"); err == nil {
+		t.Fatal("admin/admin must not work")
+	}
+	svc.clearLoginAttempts("admin")
+
+	token, err := svc.Login("admin", password)
+	if err != nil {
+		t.Fatalf("login with the generated password failed: %v", err)
+	}
+	userID, _ := svc.Validate(token)
+	if must, err := svc.PasswordChangeRequired(userID); err != nil || !must {
+		t.Fatalf("expected a required password change, got %v %v", must, err)
+	}
+	if err := svc.ChangePassword(userID, password, "newpassword"); err != nil {
+		t.Fatalf("change password failed: %v", err)
+	}
+	if must, _ := svc.PasswordChangeRequired(userID); must {
+		t.Error("the flag should clear after changing the password")
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("initial password file should be removed, got %v", err)
+	}
+
+	// A leftover file goes on the next boot.
+	if err := os.WriteFile(file, []byte(password+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.EnsureDefaultAdmin(); err != nil {
+		t.Fatalf("EnsureDefaultAdmin: %v", err)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("leftover initial password file should be removed, got %v", err)
+	}
+}
+
 func TestLoginLogout(t *testing.T) {
 	svc := newTestService(t)
 
@@ -76,6 +132,108 @@ func TestLoginLogout(t *testing.T) {
 	}
 }
 
+func TestHashedSessionTokens(t *testing.T) {
+	db := newTestDB(t)
+	setupSchema(t, db)
+	svc, err := New(Config{DB: db, SessionTTL: "1h", TokenKey: []byte("k"), InitialPassword: "admin"})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	token, err := svc.Login("admin", "admin")
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	var stored string
+	if err := db.QueryRow(`SELECT token FROM sessions`).Scan(&stored); err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if stored == token {
+		t.Fatal("session token stored in plain text")
+	}
+	if _, err := svc.Validate(token); err != nil {
+		t.Fatalf("validate failed: %v", err)
+	}
+	if _, err := svc.Validate(stored); err == nil {
+		t.Error("the stored value must not work as a token")
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	db := newTestDB(t)
+	setupSchema(t, db)
+	svc, err := New(Config{DB: db, SessionTTL: "1h", SessionMaxAge: "24h", InitialPassword: "admin"})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	login := func() string {
+		t.Helper()
+		token, err := svc.Login("admin", "admin")
+		if err != nil {
+			t.Fatalf("login failed: %v", err)
+		}
+		return token
+	}
+	expiry := func(token string) int64 {
+		t.Helper()
+		var exp int64
+		if err := db.QueryRow(`SELECT expires_at FROM sessions WHERE token = ?`, token).Scan(&exp); err != nil {
+			t.Fatalf("query failed: %v", err)
+		}
+		return exp
+	}
+	now := time.Now().Unix()
+
+	// Using a session moves its expiry forward.
+	active := login()
+	if _, err := db.Exec(`UPDATE sessions SET expires_at = ? WHERE token = ?`, now+60, active); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Validate(active); err != nil {
+		t.Fatalf("validate failed: %v", err)
+	}
+	if exp := expiry(active); exp < now+3500 {
+		t.Errorf("expiry not refreshed: %d s left", exp-now)
+	}
+
+	// Not past the maximum age, though.
+	old := login()
+	if _, err := db.Exec(`UPDATE sessions SET created_at = ?, expires_at = ? WHERE token = ?`, now-23*3600-1800, now+60, old); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Validate(old); err != nil {
+		t.Fatalf("validate failed: %v", err)
+	}
+	if exp := expiry(old); exp > now+1800 {
+		t.Errorf("expiry past the maximum age: %d s left", exp-now)
+	}
+	if _, err := db.Exec(`UPDATE sessions SET created_at = ? WHERE token = ?`, now-25*3600, old); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Validate(old); err == nil {
+		t.Error("a session older than the maximum age should be invalid")
+	}
+
+	idle := login()
+	expired := login()
+	if _, err := db.Exec(`UPDATE sessions SET expires_at = ? WHERE token = ?`, now-10, idle); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`UPDATE sessions SET created_at = ? WHERE token = ?`, now-25*3600, expired); err != nil {
+		t.Fatal(err)
+	}
+	n, err := svc.PurgeExpiredSessions()
+	if err != nil {
+		t.Fatalf("purge failed: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("purged %d sessions, want 2", n)
+	}
+	if _, err := svc.Validate(active); err != nil {
+		t.Errorf("active session purged: %v", err)
+	}
+}
+
 func TestChangePassword(t *testing.T) {
 	svc := newTestService(t)
 
@@ -109,6 +267,46 @@ func TestChangePassword(t *testing.T) {
 	}
 }
 
+func TestVerifyPassword(t *testing.T) {
+	svc := newTestService(t)
+
+	token, err := svc.Login("admin", "admin")
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	userID, err := svc.Validate(token)
+	if err != nil {
+		t.Fatalf("validate failed: %v", err)
+	}
+
+	if err := svc.VerifyPassword(userID, "admin"); err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	if err := svc.VerifyPassword(userID, "wrong"); err == nil {
+		t.Error("wrong password should not verify")
+	}
+	if err := svc.VerifyPassword("missing", "admin"); err == nil {
+		t.Error("unknown user should not verify")
+	}
+}
+
+func TestCreateUser(t *testing.T) {
+	svc := newTestService(t)
+
+	if err := svc.CreateUser("maria", "secret"); err != nil {
+		t.Fatalf("create user failed: %v", err)
+	}
+	if err := svc.CreateUser("maria", "other"); err == nil {
+		t.Error("duplicate username should fail")
+	}
+	if err := svc.CreateUser("short", "abc"); err == nil {
+		t.Error("short password should fail")
+	}
+	if _, err := svc.Login("maria", "secret"); err != nil {
+		t.Fatalf("login as the new user failed: %v", err)
+	}
+}
+
 func newTestDB(t *testing.T) *sql.DB {
 	t.Helper()
 	db, err := sql.Open("sqlite", ":memory:")
@@ -122,7 +320,7 @@ func newTestDB(t *testing.T) *sql.DB {
 func setupSchema(t *testing.T, db *sql.DB) {
 	t.Helper()
 	stmts := []string{
-		"CREATE TABLE IF NOT EXISTS users (id TEXT PRIMARY KEY, username TEXT NOT NULL UNIQUE, password_hash TEXT NOT NULL, created_at INTEGER NOT NULL)",
+		"CREATE TABLE IF NOT EXISTS users (id TEXT PRIMARY KEY, username TEXT NOT NULL UNIQUE, password_hash TEXT NOT NULL, created_at INTEGER NOT NULL, must_change_password INTEGER NOT NULL DEFAULT 0)",
 		"CREATE TABLE IF NOT EXISTS sessions (token TEXT PRIMARY KEY, user_id TEXT NOT NULL, expires_at INTEGER NOT NULL, created_at INTEGER NOT NULL, FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE)",
 	}
 	for _, stmt := range stmts {
@@ -136,7 +334,7 @@ func newTestService(t *testing.T) *Service {
 	t.Helper()
 	db := newTestDB(t)
 	setupSchema(t, db)
-	svc, err := New(Config{DB: db, SessionTTL: "1h"})
+	svc, err := New(Config{DB: db, SessionTTL: "1h", InitialPassword: "admin"})
 	if err != nil {
 		t.Fatalf("failed to create service: %v", err)
 	}
@@ -0,0 +1,135 @@
+package background
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/jpeg"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/morezhou/hearth/internal/imaging"
+	"github.com/morezhou/hearth/internal/storage"
+)
+
+// Variants of a background, served by /api/background/image?variant=.
+const (
+	VariantBlur  = "blur"  // blurred and dimmed, for behind the dashboard
+	VariantThumb = "thumb" // tiny placeholder shown while the image loads
+)
+
+// DefaultMaxDimension is the longest side fetched backgrounds are scaled
+// down to unless configured otherwise.
+const DefaultMaxDimension = 2560
+
+const (
+	blurWidth  = 640 // browsers stretch the blurred variant; detail is lost anyway
+	blurRadius = 6
+	blurDim    = 0.6
+	thumbWidth = 32
+)
+
+// variantPrefix is where rendered variants live in the background storage.
+const variantPrefix = "variants/"
+
+// ErrUnsupported is returned for images the standard library cannot decode
+// (WebP); callers serve the original instead.
+var ErrUnsupported = errors.New("image format not supported")
+
+// ValidVariant reports whether v names a variant.
+func ValidVariant(v string) bool {
+	return v == VariantBlur || v == VariantThumb
+}
+
+// RenderVariant renders a variant of an encoded image as JPEG.
+func RenderVariant(data []byte, variant string) ([]byte, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+	var out *image.RGBA
+	quality := 80
+	switch variant {
+	case VariantBlur:
+		out = imaging.Fit(img, blurWidth, blurWidth)
+		imaging.Blur(out, blurRadius)
+		imaging.Dim(out, blurDim)
+	case VariantThumb:
+		out = imaging.Fit(img, thumbWidth, thumbWidth)
+		quality = 60
+	default:
+		return nil, errors.New("unknown variant")
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, out, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// downscale re-encodes photos larger than maxDim so their longest side is
+// maxDim. Anything else is returned unchanged.
+func downscale(data []byte, mimeType string, maxDim int) ([]byte, string) {
+	if maxDim <= 0 {
+		return data, mimeType
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || (cfg.Width <= maxDim && cfg.Height <= maxDim) {
+		return data, mimeType
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return data, mimeType
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, imaging.Fit(img, maxDim, maxDim), &jpeg.Options{Quality: 88}); err != nil {
+		return data, mimeType
+	}
+	return buf.Bytes(), "image/jpeg"
+}
+
+func variantKey(key, variant string) string {
+	base := strings.TrimSuffix(key, path.Ext(key))
+	return variantPrefix + strings.ReplaceAll(base, "/", "_") + "-" + variant + ".jpg"
+}
+
+// Variant returns the storage key of a variant of the stored image key,
+// rendering and storing it on first use.
+func (s *Service) Variant(ctx context.Context, key, variant string) (string, error) {
+	if !ValidVariant(variant) {
+		return "", errors.New("unknown variant")
+	}
+	vkey := variantKey(key, variant)
+	if _, err := s.storage.Stat(ctx, vkey); err == nil {
+		return vkey, nil
+	}
+	rc, _, err := s.storage.Get(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	data, err := io.ReadAll(io.LimitReader(rc, MaxUploadSize))
+	rc.Close()
+	if err != nil {
+		return "", err
+	}
+	out, err := RenderVariant(data, variant)
+	if err != nil {
+		return "", err
+	}
+	if err := s.storage.Put(ctx, vkey, out, "image/jpeg"); err != nil {
+		return "", err
+	}
+	return vkey, nil
+}
+
+// DeleteImage removes a stored image together with its variants.
+func (s *Service) DeleteImage(ctx context.Context, key string) error {
+	for _, v := range []string{VariantBlur, VariantThumb} {
+		if err := s.storage.Delete(ctx, variantKey(key, v)); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+	}
+	return s.storage.Delete(ctx, key)
+}
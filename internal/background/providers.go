@@ -0,0 +1,188 @@
+package background
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Providers with a picture of the day or a curated collection; their
+// resolvers also return attribution.
+const (
+	ProviderAPOD       Provider = "apod"
+	ProviderWikimedia  Provider = "wikimedia"
+	ProviderChromecast Provider = "chromecast"
+)
+
+// Meta is the attribution shown with a background.
+type Meta struct {
+	Title  string `json:"title,omitempty"`
+	Credit string `json:"credit,omitempty"`
+	Link   string `json:"link,omitempty"` // page describing the image
+}
+
+// wikimediaWidth is the width Wikimedia pictures are scaled to.
+const wikimediaWidth = 1920
+
+// chromecastListTTL is how long the Chromecast background list is reused.
+const chromecastListTTL = 24 * time.Hour
+
+var thumbWidthRe = regexp.MustCompile(`/\d+px-`)
+
+func (s *Service) getJSON(ctx context.Context, u string, out any) error {
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	req.Header.Set("User-Agent", "Hearth/0.1")
+	req.Header.Set("Accept", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("bad status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// ResolveAPODURL returns NASA's Astronomy Picture of the Day. On days the
+// picture is a video, its thumbnail is used.
+func (s *Service) ResolveAPODURL(ctx context.Context) (string, Meta, error) {
+	var payload struct {
+		Date         string `json:"date"`
+		Title        string `json:"title"`
+		Copyright    string `json:"copyright"`
+		MediaType    string `json:"media_type"`
+		URL          string `json:"url"`
+		ThumbnailURL string `json:"thumbnail_url"`
+	}
+	q := url.Values{"api_key": {s.apodKey}, "thumbs": {"true"}}
+	if err := s.getJSON(ctx, s.urls.APOD+"/planetary/apod?"+q.Encode(), &payload); err != nil {
+		return "", Meta{}, err
+	}
+	imgURL := payload.URL
+	if payload.MediaType != "image" {
+		imgURL = payload.ThumbnailURL
+	}
+	if imgURL == "" {
+		return "", Meta{}, errors.New("no image")
+	}
+	meta := Meta{Title: strings.TrimSpace(payload.Title), Credit: "NASA"}
+	if c := strings.Join(strings.Fields(payload.Copyright), " "); c != "" {
+		meta.Credit = c
+	}
+	if d, err := time.Parse("2006-01-02", payload.Date); err == nil {
+		meta.Link = "https://apod.nasa.gov/apod/ap" + d.Format("060102") + ".html"
+	}
+	return imgURL, meta, nil
+}
+
+// ResolveWikimediaURL returns Wikimedia Commons' Picture of the Day, scaled
+// down to a background-sized rendition.
+func (s *Service) ResolveWikimediaURL(ctx context.Context) (string, Meta, error) {
+	var payload struct {
+		Image struct {
+			Title     string `json:"title"`
+			FilePage  string `json:"file_page"`
+			Thumbnail struct {
+				Source string `json:"source"`
+			} `json:"thumbnail"`
+			Image struct {
+				Source string `json:"source"`
+				Width  int    `json:"width"`
+			} `json:"image"`
+			Artist struct {
+				Text string `json:"text"`
+			} `json:"artist"`
+			License struct {
+				Type string `json:"type"`
+			} `json:"license"`
+			Description struct {
+				Text string `json:"text"`
+			} `json:"description"`
+		} `json:"image"`
+	}
+	day := time.Now().UTC().Format("2006/01/02")
+	if err := s.getJSON(ctx, s.urls.Wikimedia+"/feed/v1/wikipedia/en/featured/"+day, &payload); err != nil {
+		return "", Meta{}, err
+	}
+	img := payload.Image
+	imgURL := img.Image.Source
+	if img.Image.Width > wikimediaWidth && strings.Contains(img.Thumbnail.Source, "/thumb/") && thumbWidthRe.MatchString(img.Thumbnail.Source) {
+		imgURL = thumbWidthRe.ReplaceAllString(img.Thumbnail.Source, fmt.Sprintf("/%dpx-", wikimediaWidth))
+	}
+	if imgURL == "" {
+		return "", Meta{}, errors.New("no image")
+	}
+	title := strings.TrimSpace(img.Description.Text)
+	if title == "" {
+		title = strings.TrimPrefix(img.Title, "File:")
+		if i := strings.LastIndexByte(title, '.'); i > 0 {
+			title = title[:i]
+		}
+	}
+	credit := strings.Join(strings.Fields(img.Artist.Text), " ")
+	if lic := strings.TrimSpace(img.License.Type); lic != "" {
+		if credit != "" {
+			credit += ", "
+		}
+		credit += lic
+	}
+	return imgURL, Meta{Title: title, Credit: credit, Link: img.FilePage}, nil
+}
+
+type chromecastImage struct {
+	URL    string `json:"url"`
+	Author string `json:"author"`
+}
+
+type chromecastList struct {
+	mu      sync.Mutex
+	images  []chromecastImage
+	fetched time.Time
+}
+
+// ResolveChromecastURL returns a random image of the Chromecast ambient
+// mode collection.
+func (s *Service) ResolveChromecastURL(ctx context.Context) (string, Meta, error) {
+	list, err := s.chromecastImages(ctx)
+	if err != nil {
+		return "", Meta{}, err
+	}
+	if len(list) == 0 {
+		return "", Meta{}, errors.New("no image")
+	}
+	img := list[rand.IntN(len(list))]
+	return img.URL, Meta{Credit: strings.TrimSpace(img.Author)}, nil
+}
+
+func (s *Service) chromecastImages(ctx context.Context) ([]chromecastImage, error) {
+	c := &s.chromecast
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.images) > 0 && time.Since(c.fetched) < chromecastListTTL {
+		return c.images, nil
+	}
+	var list []chromecastImage
+	if err := s.getJSON(ctx, s.urls.Chromecast+"/backgrounds.json", &list); err != nil {
+		if len(c.images) > 0 {
+			return c.images, nil // keep using the stale list
+		}
+		return nil, err
+	}
+	images := list[:0]
+	for _, img := range list {
+		if strings.HasPrefix(img.URL, "https://") || strings.HasPrefix(img.URL, "http://") {
+			images = append(images, img)
+		}
+	}
+	c.images, c.fetched = images, time.Now()
+	return images, nil
+}
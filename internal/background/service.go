@@ -2,18 +2,20 @@ package background
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"io"
 	"math/rand/v2"
 	"mime"
 	"net/http"
 	"net/url"
-	"os"
-	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/morezhou/hearth/internal/storage"
 )
 
 type Provider string
@@ -29,27 +31,66 @@ const (
 )
 
 type Config struct {
-	CacheDir string
+	Storage  storage.Backend
 	Client   *http.Client
+	BaseURLs BaseURLs // optional; empty fields use the public providers
+	APODKey  string   // NASA API key; empty uses the rate-limited DEMO_KEY
+	// MaxDimension is the longest side fetched images are scaled down to;
+	// 0 keeps them as downloaded.
+	MaxDimension int
+}
+
+// BaseURLs are the upstream roots of the image providers.
+type BaseURLs struct {
+	Bing       string
+	Unsplash   string
+	Picsum     string
+	APOD       string
+	Wikimedia  string
+	Chromecast string
 }
 
 type Service struct {
-	cacheDir string
-	client   *http.Client
+	storage    storage.Backend
+	client     *http.Client
+	urls       BaseURLs
+	apodKey    string
+	maxDim     int
+	chromecast chromecastList
 }
 
 func New(cfg Config) (*Service, error) {
-	if cfg.CacheDir == "" {
-		return nil, errors.New("cache dir required")
-	}
-	if err := os.MkdirAll(cfg.CacheDir, 0o755); err != nil {
-		return nil, err
+	if cfg.Storage == nil {
+		return nil, errors.New("storage required")
 	}
 	c := cfg.Client
 	if c == nil {
 		c = &http.Client{Timeout: 15 * time.Second}
 	}
-	return &Service{cacheDir: cfg.CacheDir, client: c}, nil
+	urls := cfg.BaseURLs
+	if urls.Bing == "" {
+		urls.Bing = "https://www.bing.com"
+	}
+	if urls.Unsplash == "" {
+		urls.Unsplash = "https://source.unsplash.com"
+	}
+	if urls.Picsum == "" {
+		urls.Picsum = "https://picsum.photos"
+	}
+	if urls.APOD == "" {
+		urls.APOD = "https://api.nasa.gov"
+	}
+	if urls.Wikimedia == "" {
+		urls.Wikimedia = "https://api.wikimedia.org"
+	}
+	if urls.Chromecast == "" {
+		urls.Chromecast = "https://raw.githubusercontent.com/dconnolly/chromecast-backgrounds/master"
+	}
+	apodKey := cfg.APODKey
+	if apodKey == "" {
+		apodKey = "DEMO_KEY"
+	}
+	return &Service{storage: cfg.Storage, client: c, urls: urls, apodKey: apodKey, maxDim: cfg.MaxDimension}, nil
 }
 
 type ImageResult struct {
@@ -57,7 +98,8 @@ type ImageResult struct {
 	MimeType string
 }
 
-// Fetches an image and stores it to cacheDir, returning the cached filename.
+// Fetches an image and stores it in the cache storage under its content hash,
+// returning the cached filename.
 func (s *Service) FetchToFile(ctx context.Context, imageURL string) (ImageResult, error) {
 	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
 	req.Header.Set("User-Agent", "Hearth/0.1")
@@ -75,11 +117,6 @@ func (s *Service) FetchToFile(ctx context.Context, imageURL string) (ImageResult
 		mt = "image/jpeg"
 	}
 
-	ext := extFromMime(mt)
-	if ext == "" {
-		ext = ".jpg"
-	}
-
 	b, err := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
 	if err != nil {
 		return ImageResult{}, err
@@ -87,19 +124,37 @@ func (s *Service) FetchToFile(ctx context.Context, imageURL string) (ImageResult
 	if len(b) == 0 {
 		return ImageResult{}, errors.New("empty")
 	}
-
-	name := "background" + ext
-	full := filepath.Join(s.cacheDir, name)
-	tmp := full + ".tmp"
-	if err := os.WriteFile(tmp, b, 0o644); err != nil {
-		return ImageResult{}, err
+	b, mt = downscale(b, mt, s.maxDim)
+	ext := extFromMime(mt)
+	if ext == "" {
+		ext = ".jpg"
 	}
-	if err := os.Rename(tmp, full); err != nil {
+
+	// Name by content so earlier images stay around for the history. The
+	// download is complete: store it even if the caller gave up meanwhile,
+	// rather than abort a remote write halfway.
+	sum := sha256.Sum256(b)
+	name := historyPrefix + hex.EncodeToString(sum[:16]) + ext
+	if err := s.storage.Put(context.WithoutCancel(ctx), name, b, mt); err != nil {
 		return ImageResult{}, err
 	}
 	return ImageResult{FileName: name, MimeType: mt}, nil
 }
 
+// historyPrefix is where fetched images live in the background storage.
+const historyPrefix = "history/"
+
+var historyNameRe = regexp.MustCompile(`^[0-9a-f]{32}\.(jpg|png|webp)$`)
+
+// HistoryKey returns the storage key of a fetched image, or false for names
+// that FetchToFile cannot have produced.
+func HistoryKey(name string) (string, bool) {
+	if !historyNameRe.MatchString(name) {
+		return "", false
+	}
+	return historyPrefix + name, true
+}
+
 func extFromMime(mt string) string {
 	switch strings.ToLower(mt) {
 	case "image/png":
@@ -113,53 +168,81 @@ func extFromMime(mt string) string {
 	}
 }
 
-func (s *Service) resolveBingURL(ctx context.Context, idx int) (string, error) {
+// DefaultBingMarket is the Bing market used when none is configured.
+const DefaultBingMarket = "en-US"
+
+// BingMarkets are the markets HPImageArchive publishes images for.
+var BingMarkets = []string{
+	"en-US", "en-GB", "en-CA", "en-AU", "en-NZ", "en-IN",
+	"zh-CN", "ja-JP", "de-DE", "fr-FR", "fr-CA", "it-IT", "es-ES", "pt-BR",
+}
+
+// NormalizeBingMarket returns the canonical form of a supported market, or
+// DefaultBingMarket.
+func NormalizeBingMarket(mkt string) string {
+	for _, m := range BingMarkets {
+		if strings.EqualFold(m, strings.TrimSpace(mkt)) {
+			return m
+		}
+	}
+	return DefaultBingMarket
+}
+
+// bingCopyrightRe splits "Description (© Photographer/Agency)".
+var bingCopyrightRe = regexp.MustCompile(`^(.*?)\s*\(\s*(?:©|\(c\))\s*(.+)\)\s*$`)
+
+func (s *Service) resolveBingURL(ctx context.Context, idx int, mkt string) (string, Meta, error) {
 	if idx < 0 {
 		idx = 0
 	}
 	if idx > 7 {
 		idx = 7
 	}
-	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://www.bing.com/HPImageArchive.aspx?format=js&idx="+strconv.Itoa(idx)+"&n=1&mkt=en-US", nil)
-	req.Header.Set("User-Agent", "Hearth/0.1")
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", errors.New("bad status")
-	}
+	q := url.Values{"format": {"js"}, "idx": {strconv.Itoa(idx)}, "n": {"1"}, "mkt": {NormalizeBingMarket(mkt)}}
 	var payload struct {
 		Images []struct {
-			URL string `json:"url"`
+			URL           string `json:"url"`
+			Title         string `json:"title"`
+			Copyright     string `json:"copyright"`
+			CopyrightLink string `json:"copyrightlink"`
 		} `json:"images"`
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
-		return "", err
+	if err := s.getJSON(ctx, s.urls.Bing+"/HPImageArchive.aspx?"+q.Encode(), &payload); err != nil {
+		return "", Meta{}, err
 	}
 	if len(payload.Images) == 0 || payload.Images[0].URL == "" {
-		return "", errors.New("no image")
+		return "", Meta{}, errors.New("no image")
+	}
+	img := payload.Images[0]
+	meta := Meta{Title: strings.TrimSpace(img.Title), Credit: strings.TrimSpace(img.Copyright)}
+	if m := bingCopyrightRe.FindStringSubmatch(img.Copyright); m != nil {
+		meta.Credit = strings.TrimSpace(m[2])
+		if meta.Title == "" || meta.Title == "Info" {
+			meta.Title = strings.TrimSpace(m[1])
+		}
+	}
+	if strings.HasPrefix(img.CopyrightLink, "https://") || strings.HasPrefix(img.CopyrightLink, "http://") {
+		meta.Link = img.CopyrightLink
 	}
-	return "https://www.bing.com" + payload.Images[0].URL, nil
+	return s.urls.Bing + img.URL, meta, nil
 }
 
-// Bing daily image URL.
-func (s *Service) ResolveBingDailyURL(ctx context.Context) (string, error) {
-	return s.resolveBingURL(ctx, 0)
+// Bing daily image URL for a market.
+func (s *Service) ResolveBingDailyURL(ctx context.Context, mkt string) (string, Meta, error) {
+	return s.resolveBingURL(ctx, 0, mkt)
 }
 
-// Bing pseudo-random image URL (random day within the last week).
-func (s *Service) ResolveBingRandomURL(ctx context.Context) (string, error) {
+// Bing pseudo-random image URL (random day within the last week) for a market.
+func (s *Service) ResolveBingRandomURL(ctx context.Context, mkt string) (string, Meta, error) {
 	idx := rand.IntN(8)
-	return s.resolveBingURL(ctx, idx)
+	return s.resolveBingURL(ctx, idx, mkt)
 }
 
 // Unsplash URL without API key via source.unsplash.com.
 // - empty query: random
 // - non-empty query: random image for query
 func (s *Service) ResolveUnsplashURL(query string) (string, error) {
-	base := "https://source.unsplash.com/1920x1080"
+	base := s.urls.Unsplash + "/1920x1080"
 	sig := url.QueryEscape(strconv.FormatInt(time.Now().UnixNano(), 10))
 	if strings.TrimSpace(query) == "" {
 		return base + "?random=1&sig=" + sig, nil
@@ -171,5 +254,5 @@ func (s *Service) ResolveUnsplashURL(query string) (string, error) {
 // Picsum random image URL.
 func (s *Service) ResolvePicsumURL() (string, error) {
 	// Picsum may cache by URL; add a varying query so manual refresh reliably changes.
-	return "https://picsum.photos/1920/1080?rand=" + url.QueryEscape(strconv.FormatInt(time.Now().UnixNano(), 10)), nil
+	return s.urls.Picsum + "/1920/1080?rand=" + url.QueryEscape(strconv.FormatInt(time.Now().UnixNano(), 10)), nil
 }
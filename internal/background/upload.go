@@ -0,0 +1,158 @@
+package background
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // register decoders for uploads
+	_ "image/jpeg"
+	_ "image/png"
+	"mime"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/morezhou/hearth/internal/storage"
+)
+
+// ProviderCustom rotates through uploaded images.
+const ProviderCustom Provider = "custom"
+
+// Upload limits.
+const (
+	MaxUploadSize      = 16 << 20
+	MaxUploadDimension = 12000
+)
+
+// uploadPrefix is where uploads live in the background storage.
+const uploadPrefix = "uploads/"
+
+// ErrInvalidUpload is wrapped by SaveUpload errors caused by the file itself.
+var ErrInvalidUpload = errors.New("invalid background image")
+
+var uploadNameRe = regexp.MustCompile(`^[0-9a-f]{32}\.(jpg|png|gif|webp)$`)
+
+// Upload is a stored background image.
+type Upload struct {
+	Name       string    `json:"name"`
+	Key        string    `json:"-"` // storage key
+	Size       int64     `json:"size"`
+	UploadedAt time.Time `json:"uploadedAt"`
+}
+
+// UploadKey returns the storage key of an upload, or false for names that
+// SaveUpload cannot have produced.
+func UploadKey(name string) (string, bool) {
+	if !uploadNameRe.MatchString(name) {
+		return "", false
+	}
+	return uploadPrefix + name, true
+}
+
+// SaveUpload validates an uploaded image and stores it under its content hash.
+func (s *Service) SaveUpload(ctx context.Context, data []byte) (Upload, error) {
+	if len(data) == 0 {
+		return Upload{}, fmt.Errorf("%w: empty file", ErrInvalidUpload)
+	}
+	if len(data) > MaxUploadSize {
+		return Upload{}, fmt.Errorf("%w: larger than %d bytes", ErrInvalidUpload, MaxUploadSize)
+	}
+	var ext string
+	if len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP" {
+		ext = ".webp" // no WebP decoder in the standard library; trust the header
+	} else {
+		cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return Upload{}, fmt.Errorf("%w: not a JPEG, PNG, GIF or WebP image", ErrInvalidUpload)
+		}
+		if cfg.Width > MaxUploadDimension || cfg.Height > MaxUploadDimension {
+			return Upload{}, fmt.Errorf("%w: larger than %dx%d", ErrInvalidUpload, MaxUploadDimension, MaxUploadDimension)
+		}
+		ext = "." + format
+		if format == "jpeg" {
+			ext = ".jpg"
+		}
+	}
+
+	sum := sha256.Sum256(data)
+	name := hex.EncodeToString(sum[:16]) + ext
+	key := uploadPrefix + name
+	if info, err := s.storage.Stat(ctx, key); err == nil {
+		return Upload{Name: name, Key: key, Size: info.Size, UploadedAt: info.ModTime}, nil
+	}
+	if err := s.storage.Put(ctx, key, data, mime.TypeByExtension(ext)); err != nil {
+		return Upload{}, err
+	}
+	return Upload{Name: name, Key: key, Size: int64(len(data)), UploadedAt: time.Now()}, nil
+}
+
+// ListUploads returns the uploaded images, oldest first.
+func (s *Service) ListUploads(ctx context.Context) ([]Upload, error) {
+	objs, err := s.storage.List(ctx, uploadPrefix)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Upload, 0, len(objs))
+	for _, o := range objs {
+		name := path.Base(o.Key)
+		if !uploadNameRe.MatchString(name) || !strings.HasPrefix(o.Key, uploadPrefix) {
+			continue
+		}
+		out = append(out, Upload{Name: name, Key: o.Key, Size: o.Size, UploadedAt: o.ModTime})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].UploadedAt.Equal(out[j].UploadedAt) {
+			return out[i].UploadedAt.Before(out[j].UploadedAt)
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out, nil
+}
+
+// DeleteUpload removes an uploaded image.
+func (s *Service) DeleteUpload(ctx context.Context, name string) error {
+	key, ok := UploadKey(name)
+	if !ok {
+		return storage.ErrNotFound
+	}
+	if _, err := s.storage.Stat(ctx, key); err != nil {
+		return err
+	}
+	return s.DeleteImage(ctx, key)
+}
+
+// NextUpload returns the storage key of the upload after current, in upload
+// order, wrapping around; "" when there are no uploads.
+func (s *Service) NextUpload(ctx context.Context, current string) (string, error) {
+	list, err := s.ListUploads(ctx)
+	if err != nil || len(list) == 0 {
+		return "", err
+	}
+	for i, u := range list {
+		if u.Key == current {
+			return list[(i+1)%len(list)].Key, nil
+		}
+	}
+	return list[0].Key, nil
+}
+
+// PreviousUpload returns the storage key of the upload before current,
+// wrapping around; "" when there are no uploads.
+func (s *Service) PreviousUpload(ctx context.Context, current string) (string, error) {
+	list, err := s.ListUploads(ctx)
+	if err != nil || len(list) == 0 {
+		return "", err
+	}
+	for i, u := range list {
+		if u.Key == current {
+			return list[(i+len(list)-1)%len(list)].Key, nil
+		}
+	}
+	return list[len(list)-1].Key, nil
+}
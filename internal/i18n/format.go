@@ -0,0 +1,130 @@
+package i18n
+
+import "strings"
+
+// Format tells clients how numbers, dates and times are written in a locale,
+// so they don't have to guess from the browser. The data is a small subset of
+// CLDR covering common locales; anything else gets the defaults (1,234.56,
+// 24-hour clock, weeks starting on Monday, day-month-year).
+type Format struct {
+	Locale  string `json:"locale"`  // the tag the hints were derived from
+	Decimal string `json:"decimal"` // decimal separator
+	Group   string `json:"group"`   // thousands separator
+	// Hour12 is true where times are read on a 12-hour clock (3:05 PM).
+	Hour12 bool `json:"hour12"`
+	// FirstDayOfWeek is 0 for Sunday through 6 for Saturday, as in JavaScript's
+	// Date.getDay.
+	FirstDayOfWeek int `json:"firstDayOfWeek"`
+	// DateOrder is the order of day, month and year in numeric dates: ymd, dmy
+	// or mdy.
+	DateOrder string `json:"dateOrder"`
+	// CurrencyAfter puts the currency symbol after the amount (1.234,56 €).
+	CurrencyAfter bool `json:"currencyAfter"`
+}
+
+const (
+	nbsp       = "\u00a0"
+	narrowNbsp = "\u202f"
+)
+
+// likelyRegions is the region assumed for a bare language ("de" is "de-DE").
+var likelyRegions = map[string]string{
+	"ar": "EG", "cs": "CZ", "da": "DK", "de": "DE", "el": "GR", "en": "US",
+	"es": "ES", "fa": "IR", "fi": "FI", "fr": "FR", "he": "IL", "hi": "IN",
+	"hu": "HU", "id": "ID", "it": "IT", "ja": "JP", "ko": "KR", "nb": "NO",
+	"nl": "NL", "pl": "PL", "pt": "BR", "ro": "RO", "ru": "RU", "sk": "SK",
+	"sv": "SE", "th": "TH", "tr": "TR", "uk": "UA", "vi": "VN", "zh": "CN",
+}
+
+// separators lists the decimal and group separators of languages that don't
+// write 1,234.56.
+var separators = map[string][2]string{
+	"de": {",", "."}, "es": {",", "."}, "it": {",", "."}, "nl": {",", "."},
+	"pt": {",", "."}, "da": {",", "."}, "el": {",", "."}, "id": {",", "."},
+	"ro": {",", "."}, "tr": {",", "."}, "vi": {",", "."},
+	"fr": {",", narrowNbsp},
+	"cs": {",", nbsp}, "fi": {",", nbsp}, "hu": {",", nbsp}, "nb": {",", nbsp},
+	"pl": {",", nbsp}, "ru": {",", nbsp}, "sk": {",", nbsp}, "sv": {",", nbsp},
+	"uk": {",", nbsp},
+}
+
+// regionSeparators override separators for a language in a region.
+var regionSeparators = map[string][2]string{
+	"de-CH": {".", "’"}, "it-CH": {".", "’"}, "fr-CH": {",", narrowNbsp},
+	"es-MX": {".", ","}, "es-US": {".", ","}, "fr-CA": {",", nbsp},
+}
+
+// hour12Regions use a 12-hour clock; fr-CA is the exception in CA.
+var hour12Regions = setOf("US", "CA", "AU", "NZ", "IN", "PH", "PK", "BD", "EG", "SA", "KR", "TW", "HK", "MX", "CO")
+
+// Regions whose week starts on Sunday or Saturday (CLDR weekData); the rest
+// start on Monday.
+var (
+	sundayRegions   = setOf("AG", "AS", "BD", "BR", "BS", "BT", "BW", "BZ", "CA", "CN", "CO", "DM", "DO", "ET", "GT", "GU", "HK", "HN", "ID", "IL", "IN", "JM", "JP", "KE", "KH", "KR", "LA", "MH", "MM", "MO", "MT", "MX", "MZ", "NI", "NP", "PA", "PE", "PH", "PK", "PR", "PT", "PY", "SA", "SG", "SV", "TH", "TT", "TW", "UM", "US", "VE", "VI", "WS", "YE", "ZA", "ZW")
+	saturdayRegions = setOf("AE", "AF", "BH", "DJ", "DZ", "EG", "IQ", "IR", "JO", "KW", "LY", "OM", "QA", "SD", "SY")
+)
+
+// Languages writing dates year first, and regions writing them month first.
+var (
+	ymdLanguages = setOf("zh", "ja", "ko", "hu", "lt", "sv")
+	mdyRegions   = setOf("US", "PH")
+)
+
+// currencyAfterLanguages write the currency symbol after the amount.
+var currencyAfterLanguages = setOf("de", "fr", "es", "it", "pl", "ru", "sv", "cs", "fi", "da", "sk", "hu", "ro", "uk", "vi")
+
+func setOf(items ...string) map[string]bool {
+	out := make(map[string]bool, len(items))
+	for _, it := range items {
+		out[it] = true
+	}
+	return out
+}
+
+// FormatFor returns the formatting hints for a language tag. Invalid tags
+// get the defaults.
+func FormatFor(tag string) Format {
+	tag, ok := Normalize(tag)
+	if !ok {
+		tag = Fallback
+	}
+	lang := Language(tag)
+	region := ""
+	for _, p := range strings.Split(tag, "-")[1:] {
+		if len(p) == 1 {
+			break
+		}
+		if len(p) == 2 {
+			region = p
+			break
+		}
+	}
+	if region == "" {
+		region = likelyRegions[lang]
+	}
+
+	f := Format{Locale: tag, Decimal: ".", Group: ",", DateOrder: "dmy", FirstDayOfWeek: 1}
+	if sep, ok := regionSeparators[lang+"-"+region]; ok {
+		f.Decimal, f.Group = sep[0], sep[1]
+	} else if sep, ok := separators[lang]; ok {
+		f.Decimal, f.Group = sep[0], sep[1]
+	}
+	f.Hour12 = hour12Regions[region] && lang != "fr"
+	switch {
+	case sundayRegions[region]:
+		f.FirstDayOfWeek = 0
+	case saturdayRegions[region]:
+		f.FirstDayOfWeek = 6
+	}
+	switch {
+	case ymdLanguages[lang]:
+		f.DateOrder = "ymd"
+	case mdyRegions[region]:
+		f.DateOrder = "mdy"
+	}
+	f.CurrencyAfter = currencyAfterLanguages[lang] || (lang == "pt" && region != "BR")
+	if lang == "es" && (region == "MX" || region == "US") {
+		f.CurrencyAfter = false
+	}
+	return f
+}
@@ -0,0 +1,22 @@
+package i18n
+
+import "testing"
+
+func TestFormatFor(t *testing.T) {
+	for tag, want := range map[string]Format{
+		"en":     {Locale: "en", Decimal: ".", Group: ",", Hour12: true, FirstDayOfWeek: 0, DateOrder: "mdy"},
+		"en-GB":  {Locale: "en-GB", Decimal: ".", Group: ",", FirstDayOfWeek: 1, DateOrder: "dmy"},
+		"de":     {Locale: "de", Decimal: ",", Group: ".", FirstDayOfWeek: 1, DateOrder: "dmy", CurrencyAfter: true},
+		"de-CH":  {Locale: "de-CH", Decimal: ".", Group: "’", FirstDayOfWeek: 1, DateOrder: "dmy", CurrencyAfter: true},
+		"fr-CA":  {Locale: "fr-CA", Decimal: ",", Group: nbsp, FirstDayOfWeek: 0, DateOrder: "dmy", CurrencyAfter: true},
+		"zh":     {Locale: "zh", Decimal: ".", Group: ",", FirstDayOfWeek: 0, DateOrder: "ymd"},
+		"pt-BR":  {Locale: "pt-BR", Decimal: ",", Group: ".", FirstDayOfWeek: 0, DateOrder: "dmy"},
+		"pt-PT":  {Locale: "pt-PT", Decimal: ",", Group: ".", FirstDayOfWeek: 0, DateOrder: "dmy", CurrencyAfter: true},
+		"ar-AE":  {Locale: "ar-AE", Decimal: ".", Group: ",", FirstDayOfWeek: 6, DateOrder: "dmy"},
+		"bogus!": {Locale: "en", Decimal: ".", Group: ",", Hour12: true, FirstDayOfWeek: 0, DateOrder: "mdy"},
+	} {
+		if got := FormatFor(tag); got != want {
+			t.Errorf("FormatFor(%q) = %+v, want %+v", tag, got, want)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+// Package i18n holds the translation catalogs for server-generated strings
+// (weather labels, widget names, ...) and maps BCP-47 language tags onto
+// them.
+//
+// A catalog is a flat JSON object of message keys to text; {name}
+// placeholders are filled in by T. The built-in catalogs can be extended or
+// overridden with <locale>.json files loaded by LoadDir. Keys missing from a
+// catalog fall back to English.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Fallback is the locale whose catalog fills in missing keys.
+const Fallback = "en"
+
+// NameKey is the catalog key holding the language's own name ("Deutsch").
+const NameKey = "locale.name"
+
+// maxCatalogSize bounds catalog files read from disk.
+const maxCatalogSize = 1 << 20
+
+//go:embed locales/*.json
+var builtin embed.FS
+
+var catalogs = struct {
+	mu    sync.RWMutex
+	items map[string]map[string]string
+}{items: map[string]map[string]string{}}
+
+func init() {
+	entries, _ := builtin.ReadDir("locales")
+	for _, e := range entries {
+		raw, err := builtin.ReadFile(path.Join("locales", e.Name()))
+		if err != nil {
+			panic(err)
+		}
+		if err := add(strings.TrimSuffix(e.Name(), ".json"), raw); err != nil {
+			panic(fmt.Sprintf("i18n: %s: %v", e.Name(), err))
+		}
+	}
+}
+
+// add merges the catalog raw into locale's.
+func add(locale string, raw []byte) error {
+	tag, ok := Normalize(locale)
+	if !ok {
+		return fmt.Errorf("%q is not a language tag", locale)
+	}
+	var msgs map[string]string
+	if err := json.Unmarshal(raw, &msgs); err != nil {
+		return err
+	}
+	catalogs.mu.Lock()
+	defer catalogs.mu.Unlock()
+	c := catalogs.items[tag]
+	if c == nil {
+		c = map[string]string{}
+		catalogs.items[tag] = c
+	}
+	for k, v := range msgs {
+		c[k] = v
+	}
+	return nil
+}
+
+// LoadDir adds the catalogs in dir, one <locale>.json per locale, over the
+// built-in ones. Invalid files are logged and skipped; a missing dir is not
+// an error.
+func LoadDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		file := filepath.Join(dir, e.Name())
+		raw, err := os.ReadFile(file)
+		if err == nil && len(raw) > maxCatalogSize {
+			err = fmt.Errorf("larger than %d bytes", maxCatalogSize)
+		}
+		if err == nil {
+			err = add(strings.TrimSuffix(e.Name(), ".json"), raw)
+		}
+		if err != nil {
+			log.Printf("[i18n] skipping %s: %v", file, err)
+			continue
+		}
+		log.Printf("[i18n] loaded %s", file)
+	}
+	return nil
+}
+
+var tagRe = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{1,8})*$`)
+
+// Normalize checks that tag is a well-formed BCP-47 language tag and returns
+// it in canonical case: "zh_hant_tw" becomes "zh-Hant-TW".
+func Normalize(tag string) (string, bool) {
+	tag = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+	if len(tag) > 35 || !tagRe.MatchString(tag) {
+		return "", false
+	}
+	parts := strings.Split(tag, "-")
+	for i := 1; i < len(parts); i++ {
+		p := parts[i]
+		if len(p) == 1 {
+			break // extensions and private use keep their case
+		}
+		switch {
+		case len(p) == 4 && isAlpha(p): // script
+			parts[i] = strings.ToUpper(p[:1]) + p[1:]
+		case len(p) == 2 && isAlpha(p): // region
+			parts[i] = strings.ToUpper(p)
+		}
+	}
+	return strings.Join(parts, "-"), true
+}
+
+func isAlpha(s string) bool {
+	for _, r := range s {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// Match returns the catalog locale for tag, dropping subtags from the end
+// until one exists ("de-AT" uses "de"), or "" when none does.
+func Match(tag string) string {
+	catalogs.mu.RLock()
+	defer catalogs.mu.RUnlock()
+	return match(tag)
+}
+
+// match is Match for callers holding catalogs.mu.
+func match(tag string) string {
+	tag, ok := Normalize(tag)
+	if !ok {
+		return ""
+	}
+	for {
+		if _, ok := catalogs.items[tag]; ok {
+			return tag
+		}
+		i := strings.LastIndex(tag, "-")
+		if i < 0 {
+			return ""
+		}
+		tag = tag[:i]
+	}
+}
+
+// Language is the primary language subtag of tag: "zh" for "zh-Hant-TW".
+func Language(tag string) string {
+	tag = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+	lang, _, _ := strings.Cut(tag, "-")
+	return lang
+}
+
+// Locales lists the locales with a catalog, sorted.
+func Locales() []string {
+	catalogs.mu.RLock()
+	defer catalogs.mu.RUnlock()
+	out := make([]string, 0, len(catalogs.items))
+	for l := range catalogs.items {
+		out = append(out, l)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Bundle returns every message for locale, with English filling the gaps.
+func Bundle(locale string) map[string]string {
+	catalogs.mu.RLock()
+	defer catalogs.mu.RUnlock()
+	out := map[string]string{}
+	for k, v := range catalogs.items[Fallback] {
+		out[k] = v
+	}
+	for k, v := range catalogs.items[match(locale)] {
+		out[k] = v
+	}
+	return out
+}
+
+// T translates key for locale, falling back to English and then to the key
+// itself. args are name/value pairs for the message's {name} placeholders.
+func T(locale, key string, args ...string) string {
+	catalogs.mu.RLock()
+	msg, ok := catalogs.items[match(locale)][key]
+	if !ok {
+		msg, ok = catalogs.items[Fallback][key]
+	}
+	catalogs.mu.RUnlock()
+	if !ok {
+		msg = key
+	}
+	for i := 0; i+1 < len(args); i += 2 {
+		msg = strings.ReplaceAll(msg, "{"+args[i]+"}", args[i+1])
+	}
+	return msg
+}
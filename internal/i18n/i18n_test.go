@@ -0,0 +1,90 @@
+package i18n
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	for in, want := range map[string]string{
+		"en":         "en",
+		"zh_hant_tw": "zh-Hant-TW",
+		" DE-at ":    "de-AT",
+		"es-419":     "es-419",
+		"en-US-x-ab": "en-US-x-ab",
+	} {
+		if got, ok := Normalize(in); !ok || got != want {
+			t.Errorf("Normalize(%q) = %q, %v; want %q", in, got, ok, want)
+		}
+	}
+	for _, in := range []string{"", "e", "english", "en--us", "../en", "en-US-toolongsubtag"} {
+		if got, ok := Normalize(in); ok {
+			t.Errorf("Normalize(%q) = %q, want invalid", in, got)
+		}
+	}
+}
+
+func TestMatchAndT(t *testing.T) {
+	if got := Match("de-AT"); got != "de" {
+		t.Fatalf("Match(de-AT) = %q", got)
+	}
+	if got := Match("zh-Hans-CN"); got != "zh" {
+		t.Fatalf("Match(zh-Hans-CN) = %q", got)
+	}
+	if got := Match("sv-SE"); got != "" {
+		t.Fatalf("Match(sv-SE) = %q", got)
+	}
+	if got := T("fr-CA", "weather.rain"); got != "Pluie" {
+		t.Fatalf("T(fr-CA) = %q", got)
+	}
+	if got := T("sv", "weather.code", "code", "3"); got != "Code 3" {
+		t.Fatalf("expected the English fallback, got %q", got)
+	}
+	if got := T("en", "no.such.key"); got != "no.such.key" {
+		t.Fatalf("expected the key, got %q", got)
+	}
+}
+
+// Every built-in catalog should translate everything English has.
+func TestBuiltinCatalogsComplete(t *testing.T) {
+	en := catalogs.items[Fallback]
+	for _, l := range Locales() {
+		for k := range en {
+			if _, ok := catalogs.items[l][k]; !ok {
+				t.Errorf("%s: missing %s", l, k)
+			}
+		}
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, body string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("sv.json", `{"locale.name":"Svenska","weather.rain":"Regn"}`)
+	write("nope.json", `{"weather.rain":`)
+	write("Not A Tag.json", `{}`)
+	t.Cleanup(func() {
+		catalogs.mu.Lock()
+		delete(catalogs.items, "sv")
+		catalogs.mu.Unlock()
+	})
+
+	if err := LoadDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if got := Match("sv-FI"); got != "sv" {
+		t.Fatalf("Match(sv-FI) = %q", got)
+	}
+	b := Bundle("sv")
+	if b["weather.rain"] != "Regn" || b["weather.snow"] != "Snow" || b[NameKey] != "Svenska" {
+		t.Fatalf("unexpected bundle: %v", b)
+	}
+	if err := LoadDir(filepath.Join(dir, "absent")); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,82 @@
+package icon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// webManifest is the part of a web app manifest the resolver reads.
+type webManifest struct {
+	Icons []struct {
+		Src     string `json:"src"`
+		Sizes   string `json:"sizes"`
+		Type    string `json:"type"`
+		Purpose string `json:"purpose"`
+	} `json:"icons"`
+}
+
+// manifestIcons fetches a web app manifest and returns its icons. Maskable and
+// monochrome icons are padded or flattened for launchers, so they are only
+// returned when the manifest has nothing else.
+func (r *Resolver) manifestIcons(ctx context.Context, manifestURL string) []iconCandidate {
+	body, err := r.fetchManifest(ctx, manifestURL, r.Client)
+	if err != nil && isTLSError(err) {
+		body, err = r.fetchManifest(ctx, manifestURL, r.InsecureClient)
+	}
+	if err != nil {
+		slog.Debug("failed to fetch manifest", "url", manifestURL, "error", err)
+		return nil
+	}
+	var m webManifest
+	if err := json.Unmarshal(body, &m); err != nil {
+		slog.Debug("failed to parse manifest", "url", manifestURL, "error", err)
+		return nil
+	}
+
+	var regular, special []iconCandidate
+	for _, ic := range m.Icons {
+		src := strings.TrimSpace(ic.Src)
+		if src == "" {
+			continue
+		}
+		c := iconCandidate{
+			href:     resolveURL(manifestURL, src),
+			size:     parseIconSizes(ic.Sizes),
+			priority: 60 + formatPriority(src, strings.ToLower(ic.Type)),
+		}
+		purpose := strings.Fields(strings.ToLower(ic.Purpose))
+		if len(purpose) == 0 || slices.Contains(purpose, "any") {
+			regular = append(regular, c)
+		} else {
+			special = append(special, c)
+		}
+	}
+	if len(regular) > 0 {
+		return regular
+	}
+	return special
+}
+
+func (r *Resolver) fetchManifest(ctx context.Context, manifestURL string, client *http.Client) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "application/manifest+json,application/json;q=0.9,*/*;q=0.8")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("bad status: %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 256<<10))
+}
@@ -0,0 +1,151 @@
+package icon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+
+	"github.com/morezhou/hearth/internal/imaging"
+)
+
+// DefaultMaxIconSize is the longest side, in pixels, raster icons are scaled
+// down to before they are stored. Tiles render icons at 64px or less, so this
+// leaves room for high-DPI screens.
+const DefaultMaxIconSize = 256
+
+// normalizeIcon scales raster icons down to maxSize and converts ICO files to
+// PNG. SVG and WebP icons, undecodable data and icons already small enough
+// are returned unchanged.
+func normalizeIcon(data []byte, ext string, maxSize int) ([]byte, string) {
+	if maxSize <= 0 {
+		return data, ext
+	}
+	var (
+		img image.Image
+		err error
+	)
+	switch ext {
+	case ".ico":
+		img, err = decodeICO(data)
+	case ".png", ".jpg", ".gif":
+		img, _, err = image.Decode(bytes.NewReader(data))
+	default:
+		return data, ext
+	}
+	if err != nil {
+		return data, ext
+	}
+	b := img.Bounds()
+	if ext != ".ico" && b.Dx() <= maxSize && b.Dy() <= maxSize {
+		return data, ext
+	}
+	if b.Dx() > maxSize || b.Dy() > maxSize {
+		img = imaging.Fit(img, maxSize, maxSize)
+	}
+
+	var buf bytes.Buffer
+	outExt := ".png"
+	if ext == ".jpg" {
+		// Photos compress far better as JPEG, and have no transparency to keep.
+		outExt = ".jpg"
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90})
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		return data, ext
+	}
+	return buf.Bytes(), outExt
+}
+
+// decodeICO decodes the largest image of an ICO file. Entries may be PNG or
+// uncompressed 24/32-bit bitmaps; paletted bitmaps are not supported.
+func decodeICO(data []byte) (image.Image, error) {
+	if len(data) < 6 || binary.LittleEndian.Uint16(data[2:]) != 1 {
+		return nil, errors.New("ico: bad header")
+	}
+	count := int(binary.LittleEndian.Uint16(data[4:]))
+	best, bestScore := -1, -1
+	for i := 0; i < count; i++ {
+		e := 6 + 16*i
+		if e+16 > len(data) {
+			break
+		}
+		w := int(data[e])
+		if w == 0 {
+			w = 256
+		}
+		bpp := int(binary.LittleEndian.Uint16(data[e+6:]))
+		if score := w*64 + bpp; score > bestScore {
+			best, bestScore = e, score
+		}
+	}
+	if best < 0 {
+		return nil, errors.New("ico: no images")
+	}
+	size := int(binary.LittleEndian.Uint32(data[best+8:]))
+	off := int(binary.LittleEndian.Uint32(data[best+12:]))
+	if off < 0 || size <= 0 || off+size > len(data) {
+		return nil, errors.New("ico: bad entry")
+	}
+	payload := data[off : off+size]
+	if bytes.HasPrefix(payload, []byte("\x89PNG")) {
+		return png.Decode(bytes.NewReader(payload))
+	}
+	return decodeICOBitmap(payload)
+}
+
+func decodeICOBitmap(p []byte) (image.Image, error) {
+	if len(p) < 40 {
+		return nil, errors.New("ico: short bitmap")
+	}
+	hdr := int(binary.LittleEndian.Uint32(p[0:]))
+	w := int(int32(binary.LittleEndian.Uint32(p[4:])))
+	h := int(int32(binary.LittleEndian.Uint32(p[8:]))) / 2 // XOR bitmap + AND mask
+	bpp := int(binary.LittleEndian.Uint16(p[14:]))
+	compression := binary.LittleEndian.Uint32(p[16:])
+	if w <= 0 || h <= 0 || w > 1024 || h > 1024 || compression != 0 || (bpp != 24 && bpp != 32) || hdr < 40 {
+		return nil, errors.New("ico: unsupported bitmap")
+	}
+	stride := (w*bpp/8 + 3) &^ 3
+	maskStride := ((w+7)/8 + 3) &^ 3
+	pix := p[hdr:]
+	if len(pix) < stride*h {
+		return nil, errors.New("ico: short bitmap")
+	}
+	mask := pix[stride*h:]
+	hasMask := len(mask) >= maskStride*h
+
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	anyAlpha := false
+	for y := 0; y < h; y++ {
+		row := pix[(h-1-y)*stride:] // rows are stored bottom-up
+		for x := 0; x < w; x++ {
+			c := color.NRGBA{B: row[x*bpp/8], G: row[x*bpp/8+1], R: row[x*bpp/8+2], A: 255}
+			if bpp == 32 {
+				c.A = row[x*4+3]
+				anyAlpha = anyAlpha || c.A != 0
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	// Without an alpha channel, transparency comes from the AND mask.
+	if (bpp == 24 || !anyAlpha) && hasMask {
+		for y := 0; y < h; y++ {
+			row := mask[(h-1-y)*maskStride:]
+			for x := 0; x < w; x++ {
+				i := img.PixOffset(x, y) + 3
+				if row[x/8]&(0x80>>(x%8)) != 0 {
+					img.Pix[i] = 0
+				} else {
+					img.Pix[i] = 255
+				}
+			}
+		}
+	}
+	return img, nil
+}
@@ -0,0 +1,88 @@
+package icon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func testPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: uint8(x ^ y), A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// testICO wraps one image entry into an ICO container.
+func testICO(width int, payload []byte) []byte {
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.LittleEndian, []uint16{0, 1, 1})
+	buf.Write([]byte{byte(width), byte(width), 0, 0})
+	_ = binary.Write(&buf, binary.LittleEndian, []uint16{1, 32})
+	_ = binary.Write(&buf, binary.LittleEndian, []uint32{uint32(len(payload)), 22})
+	buf.Write(payload)
+	return buf.Bytes()
+}
+
+func TestNormalizeIcon(t *testing.T) {
+	big := testPNG(t, 600, 300)
+	out, ext := normalizeIcon(big, ".png", 256)
+	cfg, err := png.DecodeConfig(bytes.NewReader(out))
+	if err != nil || ext != ".png" || cfg.Width != 256 || cfg.Height != 128 {
+		t.Fatalf("expected a 256x128 PNG, got %s %dx%d (%v)", ext, cfg.Width, cfg.Height, err)
+	}
+
+	small := testPNG(t, 64, 64)
+	if out, ext := normalizeIcon(small, ".png", 256); ext != ".png" || !bytes.Equal(out, small) {
+		t.Fatalf("small icons must be stored as is")
+	}
+	svg := []byte(`<svg xmlns="http://www.w3.org/2000/svg"/>`)
+	if out, ext := normalizeIcon(svg, ".svg", 256); ext != ".svg" || !bytes.Equal(out, svg) {
+		t.Fatalf("SVG icons must be stored as is")
+	}
+	if out, _ := normalizeIcon(big, ".png", 0); !bytes.Equal(out, big) {
+		t.Fatalf("a zero max size disables resizing")
+	}
+
+	// PNG-in-ICO becomes a plain PNG.
+	out, ext = normalizeIcon(testICO(64, small), ".ico", 256)
+	if cfg, err := png.DecodeConfig(bytes.NewReader(out)); err != nil || ext != ".png" || cfg.Width != 64 {
+		t.Fatalf("expected a 64px PNG from the ICO, got %s %+v (%v)", ext, cfg, err)
+	}
+
+	// A 2x2 32-bit bitmap, stored bottom-up in BGRA, plus its AND mask.
+	var bmp bytes.Buffer
+	_ = binary.Write(&bmp, binary.LittleEndian, []uint32{40, 2, 4})
+	_ = binary.Write(&bmp, binary.LittleEndian, []uint16{1, 32})
+	_ = binary.Write(&bmp, binary.LittleEndian, []uint32{0, 0, 0, 0, 0, 0})
+	bmp.Write([]byte{0, 0, 255, 255, 0, 255, 0, 255}) // bottom row: red, green
+	bmp.Write([]byte{255, 0, 0, 255, 0, 0, 0, 0})     // top row: blue, transparent
+	bmp.Write(make([]byte, 8))
+	out, ext = normalizeIcon(testICO(2, bmp.Bytes()), ".ico", 256)
+	img, err := png.Decode(bytes.NewReader(out))
+	if err != nil || ext != ".png" {
+		t.Fatalf("expected a PNG from the bitmap ICO, got %s (%v)", ext, err)
+	}
+	want := map[image.Point]color.NRGBA{
+		{0, 0}: {B: 255, A: 255},
+		{1, 0}: {},
+		{0, 1}: {R: 255, A: 255},
+		{1, 1}: {G: 255, A: 255},
+	}
+	for p, c := range want {
+		if got := color.NRGBAModel.Convert(img.At(p.X, p.Y)).(color.NRGBA); got != c {
+			t.Errorf("pixel %v = %+v, want %+v", p, got, c)
+		}
+	}
+}
@@ -0,0 +1,435 @@
+package icon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PackSource is an icon set published as one PNG per service under
+// BaseURL/png/<slug>.png, with an index of the available slugs.
+type PackSource struct {
+	Name      string
+	BaseURL   string
+	IndexPath string
+	// parseIndex extracts the slugs from the index file.
+	parseIndex func([]byte) ([]string, error)
+}
+
+// Known icon packs, by name.
+var PackSources = map[string]PackSource{
+	"dashboard-icons": {
+		Name:       "dashboard-icons",
+		BaseURL:    "https://cdn.jsdelivr.net/gh/walkxcode/dashboard-icons",
+		IndexPath:  "/tree.json",
+		parseIndex: parseDashboardIconsTree,
+	},
+	"selfhst": {
+		Name:       "selfhst",
+		BaseURL:    "https://cdn.jsdelivr.net/gh/selfhst/icons",
+		IndexPath:  "/index.json",
+		parseIndex: parseSelfhstIndex,
+	},
+}
+
+// parseDashboardIconsTree reads dashboard-icons' tree.json:
+// {"png": ["plex.png", ...], "svg": [...]}.
+func parseDashboardIconsTree(b []byte) ([]string, error) {
+	var tree struct {
+		PNG []string `json:"png"`
+	}
+	if err := json.Unmarshal(b, &tree); err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, len(tree.PNG))
+	for _, f := range tree.PNG {
+		out = append(out, strings.TrimSuffix(f, ".png"))
+	}
+	return out, nil
+}
+
+// parseSelfhstIndex reads selfh.st's index.json:
+// [{"Name": "Plex", "Reference": "plex", "PNG": "Yes"}, ...].
+func parseSelfhstIndex(b []byte) ([]string, error) {
+	var list []struct {
+		Reference string `json:"Reference"`
+		PNG       string `json:"PNG"`
+	}
+	if err := json.Unmarshal(b, &list); err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, len(list))
+	for _, e := range list {
+		if e.Reference != "" && !strings.EqualFold(e.PNG, "no") {
+			out = append(out, e.Reference)
+		}
+	}
+	return out, nil
+}
+
+var packSlugRe = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// ValidPackSlug reports whether slug is safe to use as a pack file name.
+func ValidPackSlug(slug string) bool {
+	return len(slug) <= 128 && packSlugRe.MatchString(slug) && !strings.Contains(slug, "..")
+}
+
+// PackStatus describes the local copy of an icon pack.
+type PackStatus struct {
+	Source     string `json:"source"`
+	Icons      int    `json:"icons"`      // slugs in the index
+	Downloaded int    `json:"downloaded"` // icons on disk
+	Syncing    bool   `json:"syncing"`
+	SyncedAt   int64  `json:"syncedAt,omitempty"` // unix seconds of the last full sync
+	Error      string `json:"error,omitempty"`    // of the last sync
+}
+
+// PackMatch is an icon pack search result.
+type PackMatch struct {
+	Slug  string `json:"slug"`
+	Score int    `json:"score"`
+}
+
+// Pack keeps an icon pack under Dir: the index plus the icons fetched so far,
+// either by a full Sync or one at a time on first use.
+type Pack struct {
+	Source PackSource
+	Dir    string
+	Client *http.Client
+
+	mu       sync.Mutex
+	slugs    []string // sorted
+	known    map[string]bool
+	syncing  bool
+	syncedAt int64
+	syncErr  string
+}
+
+// NewPack opens the pack stored in dir, loading a previously synced index.
+func NewPack(src PackSource, dir string) *Pack {
+	p := &Pack{Source: src, Dir: dir, Client: &http.Client{Timeout: 30 * time.Second}}
+	if b, err := os.ReadFile(filepath.Join(dir, "index.json")); err == nil {
+		if slugs, err := src.parseIndex(b); err == nil {
+			p.setSlugs(slugs)
+		}
+	}
+	if fi, err := os.Stat(filepath.Join(dir, ".synced")); err == nil {
+		p.syncedAt = fi.ModTime().Unix()
+	}
+	return p
+}
+
+func (p *Pack) setSlugs(slugs []string) {
+	known := make(map[string]bool, len(slugs))
+	clean := make([]string, 0, len(slugs))
+	for _, s := range slugs {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if ValidPackSlug(s) && !known[s] {
+			known[s] = true
+			clean = append(clean, s)
+		}
+	}
+	sort.Strings(clean)
+	p.mu.Lock()
+	p.slugs, p.known = clean, known
+	p.mu.Unlock()
+}
+
+func (p *Pack) fetch(ctx context.Context, rawURL string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Hearth/0.1")
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("icon pack %s: status=%d body=%s", p.Source.Name, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, limit))
+}
+
+func (p *Pack) writeFile(name string, data []byte) error {
+	if err := os.MkdirAll(p.Dir, 0o755); err != nil {
+		return err
+	}
+	tmp := filepath.Join(p.Dir, name+".tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(p.Dir, name))
+}
+
+// RefreshIndex downloads the pack's index.
+func (p *Pack) RefreshIndex(ctx context.Context) error {
+	b, err := p.fetch(ctx, p.Source.BaseURL+p.Source.IndexPath, 16<<20)
+	if err != nil {
+		return err
+	}
+	slugs, err := p.Source.parseIndex(b)
+	if err != nil {
+		return fmt.Errorf("icon pack %s: %w", p.Source.Name, err)
+	}
+	if len(slugs) == 0 {
+		return fmt.Errorf("icon pack %s: empty index", p.Source.Name)
+	}
+	if err := p.writeFile("index.json", b); err != nil {
+		return err
+	}
+	p.setSlugs(slugs)
+	return nil
+}
+
+// ensureIndex loads the index on first use.
+func (p *Pack) ensureIndex(ctx context.Context) error {
+	p.mu.Lock()
+	loaded := p.slugs != nil
+	p.mu.Unlock()
+	if loaded {
+		return nil
+	}
+	return p.RefreshIndex(ctx)
+}
+
+// Has reports whether slug is in the pack's index, fetching the index if needed.
+func (p *Pack) Has(ctx context.Context, slug string) (bool, error) {
+	if err := p.ensureIndex(ctx); err != nil {
+		return false, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.known[slug], nil
+}
+
+// Icon returns the PNG for slug, downloading it on first use.
+func (p *Pack) Icon(ctx context.Context, slug string) ([]byte, error) {
+	if !ValidPackSlug(slug) {
+		return nil, os.ErrNotExist
+	}
+	if b, err := os.ReadFile(filepath.Join(p.Dir, slug+".png")); err == nil {
+		return b, nil
+	}
+	if ok, err := p.Has(ctx, slug); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, os.ErrNotExist
+	}
+	return p.download(ctx, slug)
+}
+
+func (p *Pack) download(ctx context.Context, slug string) ([]byte, error) {
+	b, err := p.fetch(ctx, p.Source.BaseURL+"/png/"+slug+".png", 1<<20)
+	if err != nil {
+		return nil, err
+	}
+	if detectImageExt(b) != ".png" {
+		return nil, fmt.Errorf("icon pack %s: %s is not a PNG", p.Source.Name, slug)
+	}
+	// Pack icons are often 512px or more; store them at tile-friendly sizes.
+	b, _ = normalizeIcon(b, ".png", DefaultMaxIconSize)
+	if err := p.writeFile(slug+".png", b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Sync refreshes the index and downloads every icon not yet on disk. Only one
+// sync runs at a time; a concurrent call returns an error right away.
+func (p *Pack) Sync(ctx context.Context) error {
+	p.mu.Lock()
+	if p.syncing {
+		p.mu.Unlock()
+		return errors.New("icon pack sync already running")
+	}
+	p.syncing = true
+	p.mu.Unlock()
+
+	err := p.sync(ctx)
+
+	p.mu.Lock()
+	p.syncing = false
+	p.syncErr = ""
+	if err != nil {
+		p.syncErr = err.Error()
+	} else {
+		p.syncedAt = time.Now().Unix()
+	}
+	p.mu.Unlock()
+	return err
+}
+
+func (p *Pack) sync(ctx context.Context) error {
+	if err := p.RefreshIndex(ctx); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	slugs := p.slugs
+	p.mu.Unlock()
+
+	jobs := make(chan string)
+	var (
+		wg       sync.WaitGroup
+		failMu   sync.Mutex
+		failures int
+	)
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for slug := range jobs {
+				if _, err := p.download(ctx, slug); err != nil {
+					slog.Debug("icon pack download failed", "pack", p.Source.Name, "slug", slug, "error", err)
+					failMu.Lock()
+					failures++
+					failMu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, slug := range slugs {
+		if _, err := os.Stat(filepath.Join(p.Dir, slug+".png")); err == nil {
+			continue
+		}
+		select {
+		case jobs <- slug:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if failures > 0 {
+		return fmt.Errorf("icon pack %s: %d of %d icons failed to download", p.Source.Name, failures, len(slugs))
+	}
+	return p.writeFile(".synced", nil)
+}
+
+// Status reports the local copy of the pack.
+func (p *Pack) Status() PackStatus {
+	p.mu.Lock()
+	st := PackStatus{Source: p.Source.Name, Icons: len(p.slugs), Syncing: p.syncing, SyncedAt: p.syncedAt, Error: p.syncErr}
+	p.mu.Unlock()
+	if entries, err := os.ReadDir(p.Dir); err == nil {
+		for _, e := range entries {
+			if strings.HasSuffix(e.Name(), ".png") {
+				st.Downloaded++
+			}
+		}
+	}
+	return st
+}
+
+// Search fuzzy-matches query against the pack's slugs, best first: exact
+// names, then prefixes, substrings, in-order letters ("hass" for
+// "home-assistant") and near misses ("jellyfn").
+func (p *Pack) Search(ctx context.Context, query string, limit int) ([]PackMatch, error) {
+	if err := p.ensureIndex(ctx); err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	slugs := p.slugs
+	p.mu.Unlock()
+
+	q := normalizePackName(query)
+	if q == "" {
+		return []PackMatch{}, nil
+	}
+	out := make([]PackMatch, 0)
+	for _, slug := range slugs {
+		if score := packMatchScore(q, normalizePackName(slug)); score > 0 {
+			out = append(out, PackMatch{Slug: slug, Score: score})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		return len(out[i].Slug) < len(out[j].Slug)
+	})
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+// normalizePackName lowercases s and drops everything but letters and digits,
+// so "Home Assistant" matches "home-assistant".
+func normalizePackName(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func packMatchScore(q, name string) int {
+	switch {
+	case name == q:
+		return 1000
+	case strings.HasPrefix(name, q):
+		return 800 - min(len(name)-len(q), 100)
+	case strings.Contains(name, q):
+		return 600 - min(len(name)-len(q), 100)
+	case len(q) >= 3 && isSubsequence(q, name):
+		return 400 - min(len(name)-len(q), 100)
+	}
+	if len(q) >= 4 {
+		if d := levenshtein(q, name); d <= len(q)/4 {
+			return 200 - 50*d
+		}
+	}
+	return 0
+}
+
+func isSubsequence(q, s string) bool {
+	i := 0
+	for j := 0; j < len(s) && i < len(q); j++ {
+		if s[j] == q[i] {
+			i++
+		}
+	}
+	return i == len(q)
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,93 @@
+package icon
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func newTestPackUpstream(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, image.NewGray(image.Rect(0, 0, 4, 4)))
+	pngData := buf.Bytes()
+	var icons atomic.Int32
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/tree.json":
+			_, _ = w.Write([]byte(`{"png":["plex.png","home-assistant.png","jellyfin.png","jellyseerr.png","portainer.png","../etc.png"],"svg":["plex.svg"]}`))
+		case strings.HasPrefix(r.URL.Path, "/png/"):
+			icons.Add(1)
+			_, _ = w.Write(pngData)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(up.Close)
+	return up, &icons
+}
+
+func TestPackSearch(t *testing.T) {
+	up, _ := newTestPackUpstream(t)
+	src := PackSources["dashboard-icons"]
+	src.BaseURL = up.URL
+	p := NewPack(src, t.TempDir())
+
+	cases := map[string]string{
+		"Plex":           "plex",
+		"home assistant": "home-assistant",
+		"hass":           "home-assistant",
+		"jelly":          "jellyfin",
+		"portianer":      "portainer",
+	}
+	for q, want := range cases {
+		got, err := p.Search(context.Background(), q, 5)
+		if err != nil || len(got) == 0 || got[0].Slug != want {
+			t.Errorf("Search(%q) = %+v (%v), want %s first", q, got, err, want)
+		}
+	}
+	if got, _ := p.Search(context.Background(), "zzzz", 5); len(got) != 0 {
+		t.Errorf("expected no matches, got %+v", got)
+	}
+	if ok, _ := p.Has(context.Background(), "../etc"); ok {
+		t.Errorf("unsafe slugs must be dropped from the index")
+	}
+}
+
+func TestPackSyncAndIcon(t *testing.T) {
+	up, icons := newTestPackUpstream(t)
+	src := PackSources["dashboard-icons"]
+	src.BaseURL = up.URL
+	dir := t.TempDir()
+	p := NewPack(src, dir)
+
+	// Fetched on first use, then served from disk.
+	if _, err := p.Icon(context.Background(), "plex"); err != nil {
+		t.Fatalf("Icon: %v", err)
+	}
+	if _, err := p.Icon(context.Background(), "plex"); err != nil || icons.Load() != 1 {
+		t.Fatalf("expected one download, got %d (%v)", icons.Load(), err)
+	}
+	if _, err := p.Icon(context.Background(), "unknown"); err == nil {
+		t.Fatalf("expected an error for an icon outside the index")
+	}
+
+	if err := p.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	st := p.Status()
+	if st.Icons != 5 || st.Downloaded != 5 || st.SyncedAt == 0 || st.Syncing || icons.Load() != 5 {
+		t.Fatalf("unexpected status after sync: %+v (downloads=%d)", st, icons.Load())
+	}
+
+	// A restart picks the index up from disk.
+	if st := NewPack(src, dir).Status(); st.Icons != 5 || st.SyncedAt == 0 {
+		t.Fatalf("expected the synced pack to reload, got %+v", st)
+	}
+}
@@ -0,0 +1,53 @@
+package icon
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits of a link preview's fields, in characters.
+const (
+	maxPreviewTitle       = 200
+	maxPreviewDescription = 500
+)
+
+// Preview is the title and description a page gives of itself.
+type Preview struct {
+	Title       string
+	Description string
+}
+
+// Preview fetches pageURL the way ResolveAndCache does and returns its title
+// and description, without downloading any icon.
+func (r *Resolver) Preview(ctx context.Context, pageURL string) (Preview, error) {
+	u, err := url.Parse(pageURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return Preview{}, errors.New("invalid url")
+	}
+	htmlBytes, finalURL, err := r.fetchHTML(ctx, u.String())
+	if err != nil {
+		return Preview{}, err
+	}
+	page := parsePage(finalURL, htmlBytes)
+	title := page.title
+	if title == "" {
+		title = page.ogTitle
+	}
+	p := Preview{Title: clip(title, maxPreviewTitle), Description: clip(page.description, maxPreviewDescription)}
+	if p.Title == "" && p.Description == "" {
+		return Preview{}, errors.New("page has no title or description")
+	}
+	return p, nil
+}
+
+// clip collapses the whitespace of s and cuts it to n characters.
+func clip(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
@@ -14,32 +14,36 @@ import (
 	"mime"
 	"net/http"
 	"net/url"
-	"os"
 	"path"
-	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"golang.org/x/net/html"
+
+	"github.com/morezhou/hearth/internal/storage"
 )
 
 type Result struct {
 	Title      string
-	IconPath   string // local file name within icons dir
-	IconSource string // site|fallback|google
+	IconPath   string // object key within the icon storage
+	IconSource string // site|fallback|og|google
 }
 
 type Resolver struct {
 	Client         *http.Client
 	InsecureClient *http.Client // For sites with self-signed certs
-	IconsDir       string
+	Storage        storage.Backend
+	// MaxIconSize is the longest side raster icons are scaled down to before
+	// they are stored; 0 stores icons as downloaded.
+	MaxIconSize int
 }
 
 // Common browser User-Agent for better compatibility with websites
 const userAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
 
-func New(iconsDir string) *Resolver {
+func New(st storage.Backend) *Resolver {
 	return &Resolver{
 		Client: &http.Client{Timeout: 15 * time.Second},
 		InsecureClient: &http.Client{
@@ -48,7 +52,8 @@ func New(iconsDir string) *Resolver {
 				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 			},
 		},
-		IconsDir: iconsDir,
+		Storage:     st,
+		MaxIconSize: DefaultMaxIconSize,
 	}
 }
 
@@ -66,31 +71,45 @@ func (r *Resolver) ResolveAndCache(ctx context.Context, pageURL string) (Result,
 	if err != nil {
 		slog.Debug("failed to fetch HTML", "url", pageURL, "error", err)
 		// Try direct favicon paths as fallback
-		return r.tryFallbacks(ctx, u, pageKey)
+		return r.tryFallbacks(ctx, u, pageKey, "")
 	}
 
-	title, iconHref := parseTitleAndIcon(finalURL, htmlBytes)
+	page := parsePage(finalURL, htmlBytes)
+	title := page.title
 
-	// If we found an icon in HTML, try to download it
-	if iconHref != "" {
-		// Handle data: URI (base64 encoded icons)
-		if strings.HasPrefix(iconHref, "data:") {
-			iconFile, err := r.saveDataURI(iconHref, pageKey)
-			if err == nil {
-				return Result{Title: title, IconPath: iconFile, IconSource: "site"}, nil
+	// Many self-hosted apps only list their icons in a web app manifest.
+	icons := page.icons
+	if page.manifest != "" {
+		icons = append(icons, r.manifestIcons(ctx, page.manifest)...)
+	} else if len(icons) == 0 {
+		for _, p := range []string{"/site.webmanifest", "/manifest.json"} {
+			if m := r.manifestIcons(ctx, resolveURL(finalURL, p)); len(m) > 0 {
+				icons = m
+				break
 			}
-			slog.Debug("failed to save data URI", "error", err)
+		}
+	}
+	sortIconCandidates(icons)
+
+	// Try the best few candidates; the first is sometimes a dead link.
+	for i, ic := range icons {
+		if i == maxIconAttempts {
+			break
+		}
+		var iconFile string
+		if strings.HasPrefix(ic.href, "data:") {
+			iconFile, err = r.saveDataURI(ctx, ic.href, pageKey)
 		} else {
-			iconFile, err := r.downloadIconForPage(ctx, iconHref, pageKey)
-			if err == nil {
-				return Result{Title: title, IconPath: iconFile, IconSource: "site"}, nil
-			}
-			slog.Debug("failed to download icon from HTML", "url", iconHref, "error", err)
+			iconFile, err = r.downloadIconForPage(ctx, ic.href, pageKey)
+		}
+		if err == nil {
+			return Result{Title: title, IconPath: iconFile, IconSource: "site"}, nil
 		}
+		slog.Debug("failed to download icon from page", "url", ic.href, "error", err)
 	}
 
 	// Try fallback methods
-	result, err := r.tryFallbacks(ctx, u, pageKey)
+	result, err := r.tryFallbacks(ctx, u, pageKey, page.ogImage)
 	if err == nil {
 		result.Title = title
 		return result, nil
@@ -100,8 +119,9 @@ func (r *Resolver) ResolveAndCache(ctx context.Context, pageURL string) (Result,
 	return Result{Title: title}, nil
 }
 
-// tryFallbacks tries multiple fallback methods to get an icon
-func (r *Resolver) tryFallbacks(ctx context.Context, u *url.URL, pageKey string) (Result, error) {
+// tryFallbacks tries multiple fallback methods to get an icon. ogImage, the
+// page's Open Graph image if any, is only used when the site has no favicon.
+func (r *Resolver) tryFallbacks(ctx context.Context, u *url.URL, pageKey, ogImage string) (Result, error) {
 	baseURL := fmt.Sprintf("%s://%s", u.Scheme, u.Host)
 
 	// Common favicon paths to try
@@ -123,6 +143,14 @@ func (r *Resolver) tryFallbacks(ctx context.Context, u *url.URL, pageKey string)
 		}
 	}
 
+	if ogImage != "" {
+		iconFile, err := r.downloadIconForPage(ctx, ogImage, pageKey)
+		if err == nil {
+			return Result{IconPath: iconFile, IconSource: "og"}, nil
+		}
+		slog.Debug("og:image download failed", "url", ogImage, "error", err)
+	}
+
 	// Try Google's favicon service as last resort (only for public domains)
 	if !isPrivateHost(u.Host) {
 		googleURL := fmt.Sprintf("https://www.google.com/s2/favicons?domain=%s&sz=128", u.Host)
@@ -167,6 +195,13 @@ func isPrivateHost(host string) bool {
 	return false
 }
 
+// isTLSError reports whether err looks like a certificate problem, worth
+// retrying with the insecure client.
+func isTLSError(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "certificate") || strings.Contains(msg, "x509") || strings.Contains(msg, "tls")
+}
+
 // hashString returns a short hash of the input string
 func hashString(s string) string {
 	h := sha256.Sum256([]byte(s))
@@ -178,9 +213,7 @@ func (r *Resolver) fetchHTML(ctx context.Context, pageURL string) ([]byte, strin
 	htmlBytes, finalURL, err := r.fetchHTMLWithClient(ctx, pageURL, r.Client)
 	if err != nil {
 		// If it failed due to TLS error, retry with insecure client
-		if strings.Contains(err.Error(), "certificate") ||
-			strings.Contains(err.Error(), "x509") ||
-			strings.Contains(err.Error(), "tls") {
+		if isTLSError(err) {
 			slog.Debug("retrying with insecure client due to TLS error", "url", pageURL)
 			return r.fetchHTMLWithClient(ctx, pageURL, r.InsecureClient)
 		}
@@ -222,81 +255,146 @@ func (r *Resolver) fetchHTMLWithClient(ctx context.Context, pageURL string, clie
 	return b, finalURL, nil
 }
 
-func parseTitleAndIcon(baseURL string, htmlBytes []byte) (string, string) {
-	doc, err := html.Parse(bytes.NewReader(htmlBytes))
-	if err != nil {
-		return "", ""
+// maxIconAttempts caps how many of a page's icon candidates are downloaded.
+const maxIconAttempts = 3
+
+// scalableIconSize ranks SVG icons above any raster size.
+const scalableIconSize = 1 << 16
+
+type iconCandidate struct {
+	href     string
+	size     int // longest side in pixels; 0 when unknown
+	priority int // breaks ties between equally large icons; higher is better
+}
+
+// sortIconCandidates orders icons best first: the highest resolution wins,
+// then the preferred rel and format.
+func sortIconCandidates(icons []iconCandidate) {
+	sort.SliceStable(icons, func(i, j int) bool {
+		if icons[i].size != icons[j].size {
+			return icons[i].size > icons[j].size
+		}
+		return icons[i].priority > icons[j].priority
+	})
+}
+
+// parseIconSizes returns the largest size in a sizes attribute such as
+// "16x16 32x32"; "any" means a scalable icon.
+func parseIconSizes(sizes string) int {
+	best := 0
+	for _, f := range strings.Fields(strings.ToLower(sizes)) {
+		if f == "any" {
+			return scalableIconSize
+		}
+		w, h, ok := strings.Cut(f, "x")
+		if !ok {
+			continue
+		}
+		wi, err1 := strconv.Atoi(w)
+		hi, err2 := strconv.Atoi(h)
+		if err1 == nil && err2 == nil {
+			best = max(best, wi, hi)
+		}
 	}
+	return best
+}
 
-	var title string
+// formatPriority prefers SVG and PNG over other formats, and ICO last.
+func formatPriority(href, mimeType string) int {
+	h := strings.ToLower(href)
+	switch {
+	case strings.HasSuffix(h, ".svg") || strings.Contains(mimeType, "svg"):
+		return 25
+	case strings.HasSuffix(h, ".png") || mimeType == "image/png":
+		return 20
+	case strings.HasSuffix(h, ".webp") || mimeType == "image/webp":
+		return 15
+	}
+	return 0
+}
+
+// pageInfo is what the resolver uses from a page's HTML.
+type pageInfo struct {
+	title       string
+	ogTitle     string
+	description string          // meta description, or the Open Graph one
+	icons       []iconCandidate // from <link rel=icon> and friends, unsorted
+	manifest    string          // web app manifest URL
+	ogImage     string          // Open Graph image URL
+}
 
-	// Collect all icon candidates with priority
-	type iconCandidate struct {
-		href     string
-		priority int // higher is better
-		size     int // parsed from sizes attribute
+func parsePage(baseURL string, htmlBytes []byte) pageInfo {
+	var page pageInfo
+	doc, err := html.Parse(bytes.NewReader(htmlBytes))
+	if err != nil {
+		return page
 	}
-	var icons []iconCandidate
 
 	var walk func(*html.Node)
 	walk = func(n *html.Node) {
-		if n.Type == html.ElementNode && n.Data == "title" && n.FirstChild != nil && title == "" {
-			title = strings.TrimSpace(n.FirstChild.Data)
+		if n.Type == html.ElementNode && n.Data == "title" && n.FirstChild != nil && page.title == "" {
+			page.title = strings.TrimSpace(n.FirstChild.Data)
+		}
+		if n.Type == html.ElementNode && n.Data == "meta" {
+			var prop, content string
+			for _, a := range n.Attr {
+				switch strings.ToLower(a.Key) {
+				case "property", "name":
+					prop = strings.ToLower(a.Val)
+				case "content":
+					content = strings.TrimSpace(a.Val)
+				}
+			}
+			if (prop == "og:image" || prop == "og:image:url" || prop == "og:image:secure_url") && content != "" && page.ogImage == "" {
+				page.ogImage = resolveURL(baseURL, content)
+			}
+			if prop == "og:title" && content != "" && page.ogTitle == "" {
+				page.ogTitle = content
+			}
+			if prop == "description" && content != "" {
+				page.description = content
+			} else if prop == "og:description" && content != "" && page.description == "" {
+				page.description = content
+			}
 		}
 		if n.Type == html.ElementNode && n.Data == "link" {
-			var rel, href, sizes string
+			var rel, href, sizes, typ string
 			for _, a := range n.Attr {
 				switch strings.ToLower(a.Key) {
 				case "rel":
 					rel = strings.ToLower(a.Val)
 				case "href":
-					href = a.Val
+					href = strings.TrimSpace(a.Val)
 				case "sizes":
-					sizes = strings.ToLower(a.Val)
+					sizes = a.Val
+				case "type":
+					typ = strings.ToLower(a.Val)
 				}
 			}
+			if href != "" && rel == "manifest" && page.manifest == "" {
+				page.manifest = resolveURL(baseURL, href)
+			}
 			if href != "" && strings.Contains(rel, "icon") {
 				priority := 0
-				size := 0
+				size := parseIconSizes(sizes)
 
 				// Priority based on rel type
 				if strings.Contains(rel, "apple-touch-icon") {
 					priority = 100 // Apple touch icons are usually high quality
-				} else if strings.Contains(rel, "icon") {
-					priority = 50
+					if size == 0 {
+						size = 180 // the size iOS asks for when none is given
+					}
 				} else if strings.Contains(rel, "shortcut") {
 					priority = 10
+				} else {
+					priority = 50
 				}
-
-				// Parse size (e.g., "192x192" -> 192)
-				if sizes != "" && sizes != "any" {
-					parts := strings.Split(sizes, "x")
-					if len(parts) >= 1 {
-						if s, err := strconv.Atoi(parts[0]); err == nil {
-							size = s
-							// Prefer larger icons up to 192px
-							if size >= 128 && size <= 192 {
-								priority += 30
-							} else if size >= 64 {
-								priority += 20
-							} else if size >= 32 {
-								priority += 10
-							}
-						}
-					}
-				}
-
-				// Prefer PNG and SVG over ICO
-				hrefLower := strings.ToLower(href)
-				if strings.HasSuffix(hrefLower, ".svg") {
-					priority += 25
-				} else if strings.HasSuffix(hrefLower, ".png") {
-					priority += 20
-				} else if strings.HasSuffix(hrefLower, ".webp") {
-					priority += 15
+				if strings.HasSuffix(strings.ToLower(href), ".svg") || typ == "image/svg+xml" {
+					size = scalableIconSize
 				}
+				priority += formatPriority(href, typ)
 
-				icons = append(icons, iconCandidate{
+				page.icons = append(page.icons, iconCandidate{
 					href:     resolveURL(baseURL, href),
 					priority: priority,
 					size:     size,
@@ -308,18 +406,7 @@ func parseTitleAndIcon(baseURL string, htmlBytes []byte) (string, string) {
 		}
 	}
 	walk(doc)
-
-	// Select best icon
-	var bestIcon string
-	bestPriority := -1
-	for _, ic := range icons {
-		if ic.priority > bestPriority {
-			bestPriority = ic.priority
-			bestIcon = ic.href
-		}
-	}
-
-	return title, bestIcon
+	return page
 }
 
 func resolveURL(base, href string) string {
@@ -342,8 +429,8 @@ func (r *Resolver) downloadIcon(ctx context.Context, iconURL string) (string, er
 	return r.downloadIconForPage(ctx, iconURL, "")
 }
 
-// saveDataURI handles data: URI (base64 encoded) icons and saves them to disk
-func (r *Resolver) saveDataURI(dataURI string, pageKey string) (string, error) {
+// saveDataURI handles data: URI (base64 encoded) icons and saves them to storage
+func (r *Resolver) saveDataURI(ctx context.Context, dataURI string, pageKey string) (string, error) {
 	// Format: data:[<mediatype>][;base64],<data>
 	// Example: data:image/x-icon;base64,AAABAAMAEBAAAAEAIABoBAA...
 	if !strings.HasPrefix(dataURI, "data:") {
@@ -387,6 +474,10 @@ func (r *Resolver) saveDataURI(dataURI string, pageKey string) (string, error) {
 	if ext == "" {
 		ext = ".ico" // default
 	}
+	if data, err = sanitizeIcon(data, ext); err != nil {
+		return "", err
+	}
+	data, ext = normalizeIcon(data, ext, r.MaxIconSize)
 
 	// Include pageKey in the hash to ensure each page URL gets its own icon file
 	h := sha256.New()
@@ -398,8 +489,7 @@ func (r *Resolver) saveDataURI(dataURI string, pageKey string) (string, error) {
 	sum := hex.EncodeToString(h.Sum(nil))
 
 	filename := sum + ext
-	full := filepath.Join(r.IconsDir, filename)
-	if err := osWriteFileAtomic(full, data); err != nil {
+	if err := r.Storage.Put(ctx, filename, data, mime.TypeByExtension(ext)); err != nil {
 		return "", err
 	}
 	return filename, nil
@@ -413,9 +503,7 @@ func (r *Resolver) downloadIconForPage(ctx context.Context, iconURL string, page
 	iconFile, err := r.downloadIconWithClient(ctx, iconURL, pageKey, r.Client)
 	if err != nil {
 		// If it failed due to TLS error, retry with insecure client
-		if strings.Contains(err.Error(), "certificate") ||
-			strings.Contains(err.Error(), "x509") ||
-			strings.Contains(err.Error(), "tls") {
+		if isTLSError(err) {
 			slog.Debug("retrying icon download with insecure client", "url", iconURL)
 			return r.downloadIconWithClient(ctx, iconURL, pageKey, r.InsecureClient)
 		}
@@ -450,15 +538,6 @@ func (r *Resolver) downloadIconWithClient(ctx context.Context, iconURL string, p
 		return "", errors.New("response doesn't look like an image")
 	}
 
-	// Include pageKey in the hash to ensure each page URL gets its own icon file
-	h := sha256.New()
-	if pageKey != "" {
-		h.Write([]byte(pageKey))
-		h.Write([]byte(":"))
-	}
-	h.Write(data)
-	sum := hex.EncodeToString(h.Sum(nil))
-
 	ext := extFromContentType(resp.Header.Get("Content-Type"))
 	if ext == "" {
 		ext = path.Ext(resp.Request.URL.Path)
@@ -473,10 +552,22 @@ func (r *Resolver) downloadIconWithClient(ctx context.Context, iconURL string, p
 	if !strings.HasPrefix(ext, ".") {
 		ext = "." + ext
 	}
+	if data, err = sanitizeIcon(data, ext); err != nil {
+		return "", err
+	}
+	data, ext = normalizeIcon(data, ext, r.MaxIconSize)
+
+	// Include pageKey in the hash to ensure each page URL gets its own icon file
+	h := sha256.New()
+	if pageKey != "" {
+		h.Write([]byte(pageKey))
+		h.Write([]byte(":"))
+	}
+	h.Write(data)
+	sum := hex.EncodeToString(h.Sum(nil))
 
 	filename := sum + ext
-	full := filepath.Join(r.IconsDir, filename)
-	if err := osWriteFileAtomic(full, data); err != nil {
+	if err := r.Storage.Put(ctx, filename, data, mime.TypeByExtension(ext)); err != nil {
 		return "", err
 	}
 	return filename, nil
@@ -575,11 +666,3 @@ func extFromMediaType(mt string) string {
 		return ""
 	}
 }
-
-func osWriteFileAtomic(p string, data []byte) error {
-	tmp := p + ".tmp"
-	if err := os.WriteFile(tmp, data, 0o644); err != nil {
-		return err
-	}
-	return os.Rename(tmp, p)
-}
@@ -0,0 +1,122 @@
+package icon
+
+import (
+	"context"
+	"image"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/morezhou/hearth/internal/storage"
+)
+
+func TestResolveManifestAndOpenGraph(t *testing.T) {
+	pages := map[string]string{
+		// The manifest's 512px icon beats the small favicon; the maskable
+		// one is skipped.
+		"/manifest": `<html><head><title>App</title><link rel="icon" sizes="16x16" href="/favicon-16.png"><link rel="manifest" href="/app.webmanifest"></head></html>`,
+		// No manifest link and no icons: the well-known manifest path is probed.
+		"/probe": `<html><head><title>Probe</title></head></html>`,
+		// Nothing but an Open Graph image.
+		"/og": `<html><head><title>OG</title><meta property="og:image" content="/og.png"></head></html>`,
+	}
+	small, medium, large := testPNG(t, 16, 16), testPNG(t, 192, 192), testPNG(t, 512, 512)
+	hits := map[string]int{}
+	serve := func(w http.ResponseWriter, r *http.Request) {
+		hits[r.URL.Path]++
+		switch r.URL.Path {
+		case "/manifest", "/probe", "/og":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(pages[r.URL.Path]))
+		case "/app.webmanifest":
+			_, _ = w.Write([]byte(`{"icons":[{"src":"/icon-192.png","sizes":"192x192"},{"src":"icons/icon-512.png","sizes":"512x512","type":"image/png"},{"src":"/mask-1024.png","sizes":"1024x1024","purpose":"maskable"}]}`))
+		case "/site.webmanifest":
+			_, _ = w.Write([]byte(`{"icons":[{"src":"/icon-192.png","sizes":"192x192"}]}`))
+		case "/favicon-16.png":
+			_, _ = w.Write(small)
+		case "/icon-192.png", "/og.png":
+			_, _ = w.Write(medium)
+		case "/icons/icon-512.png", "/mask-1024.png":
+			_, _ = w.Write(large)
+		default:
+			http.NotFound(w, r)
+		}
+	}
+	up := httptest.NewServer(http.HandlerFunc(serve))
+	defer up.Close()
+	// A second site without a well-known manifest.
+	bare := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/site.webmanifest" {
+			http.NotFound(w, r)
+			return
+		}
+		serve(w, r)
+	}))
+	defer bare.Close()
+
+	st, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := New(st)
+	ctx := context.Background()
+
+	res, err := r.ResolveAndCache(ctx, up.URL+"/manifest")
+	if err != nil || res.IconSource != "site" || res.Title != "App" || hits["/icons/icon-512.png"] != 1 || hits["/mask-1024.png"] != 0 {
+		t.Fatalf("manifest: %+v %v hits=%v", res, err, hits)
+	}
+	if cfg := storedConfig(t, st, res.IconPath); cfg.Width != DefaultMaxIconSize {
+		t.Fatalf("expected the 512px icon scaled to %d, got %d", DefaultMaxIconSize, cfg.Width)
+	}
+
+	if res, err := r.ResolveAndCache(ctx, up.URL+"/probe"); err != nil || res.IconSource != "site" || hits["/site.webmanifest"] != 1 {
+		t.Fatalf("probe: %+v %v hits=%v", res, err, hits)
+	}
+
+	res, err = r.ResolveAndCache(ctx, bare.URL+"/og")
+	if err != nil || res.IconSource != "og" || hits["/og.png"] != 1 || hits["/favicon.ico"] == 0 {
+		t.Fatalf("og:image: %+v %v hits=%v", res, err, hits)
+	}
+}
+
+func TestPreview(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/meta":
+			_, _ = w.Write([]byte(`<html><head><title> Paperless-ngx </title><meta property="og:description" content="OG text"><meta name="description" content="Document
+				management"></head></html>`))
+		case "/og":
+			_, _ = w.Write([]byte(`<html><head><meta property="og:title" content="Wiki"><meta property="og:description" content="Notes"></head></html>`))
+		default:
+			_, _ = w.Write([]byte(`<html><body>nothing</body></html>`))
+		}
+	}))
+	defer up.Close()
+
+	r := New(nil)
+	ctx := context.Background()
+	if p, err := r.Preview(ctx, up.URL+"/meta"); err != nil || p.Title != "Paperless-ngx" || p.Description != "Document management" {
+		t.Fatalf("meta: %+v %v", p, err)
+	}
+	if p, err := r.Preview(ctx, up.URL+"/og"); err != nil || p.Title != "Wiki" || p.Description != "Notes" {
+		t.Fatalf("og: %+v %v", p, err)
+	}
+	if _, err := r.Preview(ctx, up.URL+"/empty"); err == nil {
+		t.Fatalf("expected an error for a page without title or description")
+	}
+}
+
+func storedConfig(t *testing.T, st storage.Backend, key string) image.Config {
+	t.Helper()
+	rc, _, err := st.Get(context.Background(), key)
+	if err != nil {
+		t.Fatalf("get %s: %v", key, err)
+	}
+	defer rc.Close()
+	cfg, _, err := image.DecodeConfig(rc)
+	if err != nil {
+		t.Fatalf("decode %s: %v", key, err)
+	}
+	return cfg
+}
@@ -0,0 +1,144 @@
+package icon
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+// svgDroppedElements are removed along with everything inside them.
+var svgDroppedElements = map[string]bool{
+	"script":        true,
+	"foreignobject": true,
+	"iframe":        true,
+	"embed":         true,
+	"object":        true,
+	"handler":       true, // SVG Tiny event handlers
+	"listener":      true,
+}
+
+// SanitizeSVG rewrites an SVG document without scripts: script and
+// foreignObject elements, on* event attributes, javascript: links, DOCTYPEs
+// (and with them custom entities), comments and processing instructions are
+// dropped. It fails for documents that are not well-formed SVG.
+func SanitizeSVG(data []byte) ([]byte, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	var out bytes.Buffer
+	var open []xml.Name // RawToken does not check that tags match
+	skip := 0           // depth inside a dropped element
+	sawRoot := false
+	for {
+		tok, err := d.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			local := strings.ToLower(t.Name.Local)
+			if !sawRoot {
+				if local != "svg" {
+					return nil, errors.New("svg: root element is not svg")
+				}
+				sawRoot = true
+			}
+			open = append(open, t.Name)
+			if skip > 0 || svgDroppedElements[local] || scriptsAnimation(t) {
+				skip++
+				continue
+			}
+			out.WriteByte('<')
+			out.WriteString(rawName(t.Name))
+			for _, a := range t.Attr {
+				if !safeSVGAttr(a) {
+					continue
+				}
+				out.WriteByte(' ')
+				out.WriteString(rawName(a.Name))
+				out.WriteString(`="`)
+				out.WriteString(svgEscaper.Replace(a.Value))
+				out.WriteByte('"')
+			}
+			out.WriteByte('>')
+		case xml.EndElement:
+			if len(open) == 0 || open[len(open)-1] != t.Name {
+				return nil, errors.New("svg: mismatched tags")
+			}
+			open = open[:len(open)-1]
+			if skip > 0 {
+				skip--
+				continue
+			}
+			out.WriteString("</")
+			out.WriteString(rawName(t.Name))
+			out.WriteByte('>')
+		case xml.CharData:
+			if skip == 0 && sawRoot {
+				out.WriteString(svgEscaper.Replace(string(t)))
+			}
+		}
+	}
+	if !sawRoot || len(open) > 0 {
+		return nil, errors.New("svg: incomplete document")
+	}
+	return out.Bytes(), nil
+}
+
+func rawName(n xml.Name) string {
+	if n.Space != "" {
+		return n.Space + ":" + n.Local
+	}
+	return n.Local
+}
+
+func safeSVGAttr(a xml.Attr) bool {
+	local := strings.ToLower(a.Name.Local)
+	if strings.HasPrefix(local, "on") {
+		return false
+	}
+	// Collapse whitespace and control characters browsers ignore in URLs,
+	// so "java\nscript:" is caught too.
+	v := strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(a.Value))
+	switch local {
+	case "href", "src", "action", "formaction", "to", "from", "values":
+		if strings.HasPrefix(v, "javascript:") || strings.HasPrefix(v, "vbscript:") || strings.HasPrefix(v, "data:text/html") || strings.HasPrefix(v, "data:image/svg") {
+			return false
+		}
+	case "style":
+		if strings.Contains(v, "javascript:") || strings.Contains(v, "expression(") {
+			return false
+		}
+	}
+	return true
+}
+
+// scriptsAnimation reports whether an animation element targets an event
+// handler or a link, e.g. <set attributeName="onclick" to="...">.
+func scriptsAnimation(t xml.StartElement) bool {
+	for _, a := range t.Attr {
+		if strings.EqualFold(a.Name.Local, "attributeName") {
+			v := strings.ToLower(strings.TrimSpace(a.Value))
+			return strings.HasPrefix(v, "on") || strings.HasSuffix(v, "href")
+		}
+	}
+	return false
+}
+
+var svgEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+// sanitizeIcon sanitizes SVG icons; other formats pass through.
+func sanitizeIcon(data []byte, ext string) ([]byte, error) {
+	if ext != ".svg" {
+		return data, nil
+	}
+	return SanitizeSVG(data)
+}
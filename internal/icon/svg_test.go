@@ -0,0 +1,41 @@
+package icon
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeSVG(t *testing.T) {
+	in := `<?xml version="1.0"?>
+<!DOCTYPE svg [<!ENTITY x "boom">]>
+<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" viewBox="0 0 24 24" onload="alert(1)">
+  <!-- comment -->
+  <script>alert(2)</script>
+  <foreignObject><div xmlns="http://www.w3.org/1999/xhtml"><iframe src="x"/></div></foreignObject>
+  <a xlink:href="java&#x0A;script:alert(3)"><circle cx="12" cy="12" r="10" fill="#f00" onclick="alert(4)"/></a>
+  <a href="https://example.com"><rect width="4" height="4" style="fill: blue"/></a>
+  <set attributeName="onmouseover" to="alert(5)"/>
+  <style><![CDATA[circle { stroke: #000 }]]></style>
+</svg>`
+	out, err := SanitizeSVG([]byte(in))
+	if err != nil {
+		t.Fatalf("SanitizeSVG: %v", err)
+	}
+	got := string(out)
+	for _, bad := range []string{"alert", "script", "foreignObject", "iframe", "onload", "onclick", "DOCTYPE", "ENTITY", "comment"} {
+		if strings.Contains(got, bad) {
+			t.Errorf("sanitized SVG still contains %q:\n%s", bad, got)
+		}
+	}
+	for _, keep := range []string{`xmlns:xlink="http://www.w3.org/1999/xlink"`, `<circle cx="12" cy="12" r="10" fill="#f00"></circle>`, `href="https://example.com"`, `style="fill: blue"`, "stroke: #000"} {
+		if !strings.Contains(got, keep) {
+			t.Errorf("sanitized SVG lost %q:\n%s", keep, got)
+		}
+	}
+
+	for _, bad := range []string{`<html><script>alert(1)</script></html>`, `<svg><g></svg>`, `not xml`} {
+		if _, err := SanitizeSVG([]byte(bad)); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package icon
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Tile kinds: an emoji, or one or two letters, on a colored square.
+const (
+	TileEmoji = "emoji"
+	TileText  = "text"
+)
+
+// ErrInvalidTile is returned for glyphs or colors a tile cannot show.
+var ErrInvalidTile = errors.New("invalid tile icon")
+
+// tilePalette holds the background colors picked when none is given.
+var tilePalette = []string{
+	"#ef4444", "#f97316", "#d97706", "#65a30d", "#16a34a", "#0d9488",
+	"#0891b2", "#2563eb", "#4f46e5", "#7c3aed", "#c026d3", "#db2777", "#475569",
+}
+
+var tileColorRe = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// Tile is a rendered tile icon.
+type Tile struct {
+	Key  string // storage key, derived from the content
+	Data []byte
+}
+
+// RenderTile draws glyph on a rounded square as an SVG, which leaves
+// rendering the glyph, emoji included, to the browser's fonts. color is a
+// #rrggbb background; when empty one is picked from seed.
+func RenderTile(kind, glyph, color, seed string) (Tile, error) {
+	glyph = strings.TrimSpace(glyph)
+	switch kind {
+	case TileText:
+		if !validTileText(glyph) {
+			return Tile{}, fmt.Errorf("%w: text must be 1-2 letters or digits", ErrInvalidTile)
+		}
+		glyph = strings.ToUpper(glyph)
+	case TileEmoji:
+		if !validTileEmoji(glyph) {
+			return Tile{}, fmt.Errorf("%w: not an emoji", ErrInvalidTile)
+		}
+	default:
+		return Tile{}, fmt.Errorf("%w: unknown kind %q", ErrInvalidTile, kind)
+	}
+	if color == "" {
+		h := fnv.New32a()
+		h.Write([]byte(seed))
+		color = tilePalette[h.Sum32()%uint32(len(tilePalette))]
+	}
+	if !tileColorRe.MatchString(color) {
+		return Tile{}, fmt.Errorf("%w: color must be #rrggbb", ErrInvalidTile)
+	}
+	color = strings.ToLower(color)
+
+	var text string
+	if kind == TileEmoji {
+		text = `<text x="64" y="68" font-size="76" font-family="Apple Color Emoji,Segoe UI Emoji,Noto Color Emoji,sans-serif"`
+	} else {
+		size := 64
+		if utf8.RuneCountInString(glyph) > 1 {
+			size = 52
+		}
+		text = fmt.Sprintf(`<text x="64" y="66" font-size="%d" font-weight="600" font-family="system-ui,-apple-system,Segoe UI,Roboto,sans-serif" fill="%s"`, size, tileForeground(color))
+	}
+	svg := fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 128 128"><rect width="128" height="128" rx="28" fill="%s"/>%s text-anchor="middle" dominant-baseline="central">%s</text></svg>`,
+		color, text, svgEscaper.Replace(glyph))
+	sum := sha256.Sum256([]byte(svg))
+	return Tile{Key: fmt.Sprintf("tiles/%x.svg", sum[:16]), Data: []byte(svg)}, nil
+}
+
+// tileForeground picks white or near-black text, whichever reads better on bg.
+func tileForeground(bg string) string {
+	var r, g, b int
+	_, _ = fmt.Sscanf(bg, "#%02x%02x%02x", &r, &g, &b)
+	if 299*r+587*g+114*b > 160000 {
+		return "#111827"
+	}
+	return "#ffffff"
+}
+
+func validTileText(s string) bool {
+	n := utf8.RuneCountInString(s)
+	if n < 1 || n > 2 {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// validTileEmoji accepts a single emoji, including skin tones, flags, keycaps
+// and ZWJ sequences, loosely: symbols plus the joiners and modifiers that
+// combine them.
+func validTileEmoji(s string) bool {
+	if s == "" || len(s) > 32 || utf8.RuneCountInString(s) > 10 {
+		return false
+	}
+	symbol := false
+	for _, r := range s {
+		switch {
+		case r == 0x200d, r == 0xfe0f: // ZWJ, emoji presentation
+		case r == 0x20e3: // keycap
+			symbol = true
+		case r >= 0x1f3fb && r <= 0x1f3ff: // skin tones
+		case r >= 0xe0020 && r <= 0xe007f: // subdivision flag tags
+		case r >= 0x1f1e6 && r <= 0x1f1ff: // regional indicators
+			symbol = true
+		case r < 0x80:
+			// Only keycap bases, e.g. "1️⃣".
+			if !strings.ContainsRune("0123456789#*", r) {
+				return false
+			}
+		case unicode.Is(unicode.So, r) || unicode.Is(unicode.Sk, r):
+			symbol = true
+		default:
+			return false
+		}
+	}
+	return symbol
+}
+
+// Initials returns up to two letters for a text tile: the first letters of
+// the first two words of name, or its first letter.
+func Initials(name string) string {
+	var out []rune
+	for _, w := range strings.FieldsFunc(name, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		r, _ := utf8.DecodeRuneInString(w)
+		out = append(out, unicode.ToUpper(r))
+		if len(out) == 2 {
+			break
+		}
+	}
+	return string(out)
+}
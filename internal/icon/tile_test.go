@@ -0,0 +1,55 @@
+package icon
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRenderTile(t *testing.T) {
+	a, err := RenderTile(TileText, "px", "", "Plex")
+	if err != nil || !strings.HasPrefix(a.Key, "tiles/") || !strings.Contains(string(a.Data), ">PX</text>") {
+		t.Fatalf("text tile: %+v %v", a, err)
+	}
+	if b, _ := RenderTile(TileText, "PX", "", "Plex"); b.Key != a.Key {
+		t.Fatalf("expected the same tile for the same input")
+	}
+	light, err := RenderTile(TileText, "A", "#FDE68A", "")
+	if err != nil || !strings.Contains(string(light.Data), `fill="#fde68a"`) || !strings.Contains(string(light.Data), `fill="#111827"`) {
+		t.Fatalf("expected dark text on a light color: %s %v", light.Data, err)
+	}
+
+	for _, e := range []string{"🍕", "👍🏽", "🇯🇵", "1️⃣", "👨‍👩‍👧", "❤️"} {
+		if _, err := RenderTile(TileEmoji, e, "", "x"); err != nil {
+			t.Errorf("emoji %q: %v", e, err)
+		}
+	}
+	for _, tc := range []struct{ kind, glyph, color string }{
+		{TileText, "ABC", ""},
+		{TileText, "<", ""},
+		{TileText, "", ""},
+		{TileEmoji, "hi", ""},
+		{TileEmoji, "🍕<script>", ""},
+		{TileText, "A", "red"},
+		{TileText, "A", `#000000" onload="x`},
+		{"svg", "A", ""},
+	} {
+		if _, err := RenderTile(tc.kind, tc.glyph, tc.color, ""); !errors.Is(err, ErrInvalidTile) {
+			t.Errorf("RenderTile(%q, %q, %q): expected ErrInvalidTile, got %v", tc.kind, tc.glyph, tc.color, err)
+		}
+	}
+}
+
+func TestInitials(t *testing.T) {
+	for in, want := range map[string]string{
+		"Plex":           "P",
+		"home assistant": "HA",
+		"Uptime-Kuma v2": "UK",
+		"家庭影院":           "家",
+		"  ":             "",
+	} {
+		if got := Initials(in); got != want {
+			t.Errorf("Initials(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
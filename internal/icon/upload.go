@@ -0,0 +1,89 @@
+package icon
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // register decoders for uploads
+	_ "image/jpeg"
+	"image/png"
+	"mime"
+)
+
+// Upload limits.
+const (
+	MaxUploadSize      = 2 << 20
+	MaxUploadDimension = 4096
+)
+
+// ErrInvalidUpload is wrapped by SaveUpload errors caused by the file itself.
+var ErrInvalidUpload = errors.New("invalid icon")
+
+// UploadResult is a stored upload.
+type UploadResult struct {
+	IconPath string
+	// Existing is set when identical content was already stored.
+	Existing bool
+}
+
+// SaveUpload validates an uploaded icon and stores it under its content hash,
+// so the same artwork uploaded twice shares one file. With reencodePNG, PNG,
+// JPEG and GIF images are decoded and written back as PNG, which also drops
+// any embedded metadata; other formats are stored as is.
+func (r *Resolver) SaveUpload(ctx context.Context, data []byte, reencodePNG bool) (UploadResult, error) {
+	if len(data) == 0 {
+		return UploadResult{}, fmt.Errorf("%w: empty file", ErrInvalidUpload)
+	}
+	if len(data) > MaxUploadSize {
+		return UploadResult{}, fmt.Errorf("%w: larger than %d bytes", ErrInvalidUpload, MaxUploadSize)
+	}
+	if !looksLikeImage(data) {
+		return UploadResult{}, fmt.Errorf("%w: not an image", ErrInvalidUpload)
+	}
+	ext := detectImageExt(data)
+	if ext == "" {
+		return UploadResult{}, fmt.Errorf("%w: unsupported format", ErrInvalidUpload)
+	}
+
+	switch ext {
+	case ".png", ".jpg", ".gif":
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return UploadResult{}, fmt.Errorf("%w: %v", ErrInvalidUpload, err)
+		}
+		if cfg.Width > MaxUploadDimension || cfg.Height > MaxUploadDimension {
+			return UploadResult{}, fmt.Errorf("%w: larger than %dx%d", ErrInvalidUpload, MaxUploadDimension, MaxUploadDimension)
+		}
+		if reencodePNG {
+			img, _, err := image.Decode(bytes.NewReader(data))
+			if err != nil {
+				return UploadResult{}, fmt.Errorf("%w: %v", ErrInvalidUpload, err)
+			}
+			var buf bytes.Buffer
+			if err := png.Encode(&buf, img); err != nil {
+				return UploadResult{}, err
+			}
+			data, ext = buf.Bytes(), ".png"
+		}
+	}
+
+	data, err := sanitizeIcon(data, ext)
+	if err != nil {
+		return UploadResult{}, fmt.Errorf("%w: %v", ErrInvalidUpload, err)
+	}
+	data, ext = normalizeIcon(data, ext, r.MaxIconSize)
+
+	h := sha256.Sum256(data)
+	filename := hex.EncodeToString(h[:]) + ext
+	if _, err := r.Storage.Stat(ctx, filename); err == nil {
+		return UploadResult{IconPath: filename, Existing: true}, nil
+	}
+	if err := r.Storage.Put(ctx, filename, data, mime.TypeByExtension(ext)); err != nil {
+		return UploadResult{}, err
+	}
+	return UploadResult{IconPath: filename}, nil
+}
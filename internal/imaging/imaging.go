@@ -0,0 +1,111 @@
+// Package imaging has the few raster operations Hearth needs for icons and
+// backgrounds, implemented on the standard library only.
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// Fit scales img down so it fits within maxW x maxH, keeping its aspect
+// ratio and averaging the source pixels under each destination pixel. Images
+// that already fit are returned as RGBA copies of the same size.
+func Fit(img image.Image, maxW, maxH int) *image.RGBA {
+	sb := img.Bounds()
+	sw, sh := sb.Dx(), sb.Dy()
+	dw, dh := sw, sh
+	if dw > maxW {
+		dw, dh = maxW, max(1, sh*maxW/sw)
+	}
+	if dh > maxH {
+		dw, dh = max(1, sw*maxH/sh), maxH
+	}
+
+	src := toRGBA(img)
+	if dw == sw && dh == sh {
+		return src
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*sh/dh, max((y+1)*sh/dh, y*sh/dh+1)
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*sw/dw, max((x+1)*sw/dw, x*sw/dw+1)
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				i := src.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += uint32(src.Pix[i])
+					g += uint32(src.Pix[i+1])
+					b += uint32(src.Pix[i+2])
+					a += uint32(src.Pix[i+3])
+					n++
+					i += 4
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: uint8(a / n)})
+		}
+	}
+	return dst
+}
+
+// Blur applies three passes of a box blur of the given radius in place,
+// which approximates a Gaussian blur.
+func Blur(img *image.RGBA, radius int) {
+	if radius < 1 {
+		return
+	}
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	tmp := make([]uint8, len(img.Pix))
+	for pass := 0; pass < 3; pass++ {
+		boxBlur(tmp, img.Pix, h, w, img.Stride, 4, radius) // rows
+		boxBlur(img.Pix, tmp, w, h, 4, img.Stride, radius) // columns
+	}
+}
+
+// boxBlur blurs lines lines of length pixels from src into dst; lineStride
+// and pixStride are the byte distances between lines and between the pixels
+// of a line.
+func boxBlur(dst, src []uint8, lines, length, lineStride, pixStride, radius int) {
+	for line := 0; line < lines; line++ {
+		base := line * lineStride
+		for c := 0; c < 4; c++ {
+			var sum, cnt int
+			for i := 0; i <= radius && i < length; i++ {
+				sum += int(src[base+i*pixStride+c])
+				cnt++
+			}
+			for i := 0; i < length; i++ {
+				dst[base+i*pixStride+c] = uint8(sum / cnt)
+				if out := i - radius; out >= 0 {
+					sum -= int(src[base+out*pixStride+c])
+					cnt--
+				}
+				if in := i + radius + 1; in < length {
+					sum += int(src[base+in*pixStride+c])
+					cnt++
+				}
+			}
+		}
+	}
+}
+
+// Dim scales the colour channels of img by factor (0..1) in place.
+func Dim(img *image.RGBA, factor float64) {
+	if factor >= 1 {
+		return
+	}
+	f := uint32(max(factor, 0) * 256)
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i] = uint8(uint32(img.Pix[i]) * f >> 8)
+		img.Pix[i+1] = uint8(uint32(img.Pix[i+1]) * f >> 8)
+		img.Pix[i+2] = uint8(uint32(img.Pix[i+2]) * f >> 8)
+	}
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+	return dst
+}
@@ -0,0 +1,39 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestFit(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 400, 100))
+	for _, tc := range []struct{ maxW, maxH, w, h int }{
+		{100, 100, 100, 25},
+		{1000, 50, 200, 50},
+		{1000, 1000, 400, 100},
+	} {
+		if b := Fit(src, tc.maxW, tc.maxH).Bounds(); b.Dx() != tc.w || b.Dy() != tc.h {
+			t.Fatalf("Fit(%d, %d) = %dx%d, want %dx%d", tc.maxW, tc.maxH, b.Dx(), b.Dy(), tc.w, tc.h)
+		}
+	}
+}
+
+func TestBlurAndDim(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	for y := 0; y < 20; y++ {
+		for x := 10; x < 20; x++ {
+			img.SetRGBA(x, y, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+		}
+	}
+	Blur(img, 2)
+	left, edge, right := img.RGBAAt(0, 10).R, img.RGBAAt(10, 10).R, img.RGBAAt(19, 10).R
+	if left != 0 || right != 255 || edge == 0 || edge == 255 {
+		t.Fatalf("expected a soft edge, got %d %d %d", left, edge, right)
+	}
+
+	Dim(img, 0.5)
+	if c := img.RGBAAt(19, 10); c.R != 127 || c.A != 255 {
+		t.Fatalf("expected half brightness with alpha kept, got %+v", c)
+	}
+}
@@ -0,0 +1,162 @@
+// Package lucide serves the Lucide icon set without a runtime dependency on a
+// CDN: icon tags and SVGs for a pinned release are embedded in the binary
+// (icons missing from it are downloaded once and kept on disk), and tags
+// refreshed from the CDN can replace the bundled ones.
+package lucide
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+//go:generate go run ../../cmd/gen-lucide -out data
+
+// Version is the lucide-static release the bundled data was generated from;
+// keep it in step with lucide-react in web/package.json.
+const Version = "0.562.0"
+
+// DefaultCDN hosts lucide-static releases.
+const DefaultCDN = "https://unpkg.com/lucide-static"
+
+//go:embed data
+var bundled embed.FS
+
+// tagsFile is the name of the tag list, both embedded and on disk.
+const tagsFile = "tags.json"
+
+// Set is the Lucide icon set: the bundled tags, or a refreshed copy kept in
+// Dir.
+type Set struct {
+	Dir    string // optional; where refreshed data is kept
+	CDN    string
+	Client *http.Client
+
+	tags      atomic.Pointer[map[string][]string]
+	refreshed atomic.Bool // tags come from the latest release rather than Version
+}
+
+// New returns the icon set, preferring tags refreshed into dir over the
+// bundled ones.
+func New(dir string) *Set {
+	s := &Set{Dir: dir, CDN: DefaultCDN, Client: &http.Client{Timeout: 15 * time.Second}}
+	tags, refreshed, err := s.loadTags()
+	if err != nil {
+		slog.Warn("lucide tags unavailable", "error", err)
+		tags = map[string][]string{}
+	}
+	s.tags.Store(&tags)
+	s.refreshed.Store(refreshed)
+	return s
+}
+
+func (s *Set) loadTags() (tags map[string][]string, refreshed bool, err error) {
+	if s.Dir != "" {
+		if b, err := os.ReadFile(filepath.Join(s.Dir, tagsFile)); err == nil {
+			if tags, err := parseTags(b); err == nil {
+				return tags, true, nil
+			}
+			slog.Warn("ignoring invalid refreshed lucide tags", "dir", s.Dir)
+		}
+	}
+	b, err := bundled.ReadFile("data/" + tagsFile)
+	if err != nil {
+		return nil, false, err
+	}
+	tags, err = parseTags(b)
+	return tags, false, err
+}
+
+func parseTags(b []byte) (map[string][]string, error) {
+	var tags map[string][]string
+	if err := json.Unmarshal(b, &tags); err != nil {
+		return nil, err
+	}
+	if len(tags) == 0 {
+		return nil, errors.New("lucide: empty tag list")
+	}
+	return tags, nil
+}
+
+// Tags returns icon names mapped to their search tags. The map must not be
+// modified.
+func (s *Set) Tags() map[string][]string { return *s.tags.Load() }
+
+// Names returns all icon names, sorted.
+func (s *Set) Names() []string {
+	tags := s.Tags()
+	names := make([]string, 0, len(tags))
+	for name := range tags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Has reports whether name is a known icon.
+func (s *Set) Has(name string) bool {
+	_, ok := s.Tags()[name]
+	return ok
+}
+
+// RefreshTags downloads the tag list of the latest Lucide release, keeps it
+// in Dir and uses it from then on. It returns the number of icons.
+func (s *Set) RefreshTags(ctx context.Context) (int, error) {
+	b, err := s.fetch(ctx, "latest", tagsFile, 4<<20)
+	if err != nil {
+		return 0, err
+	}
+	tags, err := parseTags(b)
+	if err != nil {
+		return 0, err
+	}
+	if s.Dir != "" {
+		if err := writeFileAtomic(filepath.Join(s.Dir, tagsFile), b); err != nil {
+			return 0, err
+		}
+	}
+	s.tags.Store(&tags)
+	s.refreshed.Store(true)
+	return len(tags), nil
+}
+
+// fetch downloads a file of the given lucide-static release from the CDN.
+func (s *Set) fetch(ctx context.Context, version, file string, limit int64) ([]byte, error) {
+	u := fmt.Sprintf("%s@%s/%s", strings.TrimRight(s.CDN, "/"), version, file)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Hearth/0.1")
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("lucide %s: status=%d", file, resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, limit))
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,76 @@
+package lucide
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBundledAndRefreshedTags(t *testing.T) {
+	dir := t.TempDir()
+	s := New(dir)
+	if !s.Has("house") || len(s.Tags()["house"]) == 0 {
+		t.Fatalf("expected the bundled tags to include house")
+	}
+
+	cdn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/lucide-static@latest/tags.json" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"house":["home"],"brand-new":["fresh"]}`))
+	}))
+	defer cdn.Close()
+	s.CDN = cdn.URL + "/lucide-static"
+	if n, err := s.RefreshTags(context.Background()); err != nil || n != 2 {
+		t.Fatalf("RefreshTags = %d, %v", n, err)
+	}
+	if !s.Has("brand-new") {
+		t.Fatalf("expected the refreshed tags to be used")
+	}
+	// The refreshed copy survives a restart.
+	if again := New(dir); !again.Has("brand-new") || len(again.Names()) != 2 {
+		t.Fatalf("expected the refreshed tags to be loaded from disk, got %v", again.Names())
+	}
+
+	s.CDN = cdn.URL + "/missing"
+	if _, err := s.RefreshTags(context.Background()); err == nil || !s.Has("brand-new") {
+		t.Fatalf("a failed refresh must keep the current tags")
+	}
+}
+
+func TestSVG(t *testing.T) {
+	hits := 0
+	cdn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if r.URL.Path != "/lucide-static@"+Version+"/icons/house.svg" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`<svg xmlns="http://www.w3.org/2000/svg" onload="alert(1)"><path d="M3 10v11"/></svg>`))
+	}))
+	defer cdn.Close()
+
+	s := New(t.TempDir())
+	s.CDN = cdn.URL + "/lucide-static"
+	for i := 0; i < 2; i++ {
+		svg, err := s.SVG(context.Background(), "house")
+		if err != nil || !strings.Contains(string(svg), `<path d="M3 10v11">`) || strings.Contains(string(svg), "alert") {
+			t.Fatalf("SVG = %q, %v", svg, err)
+		}
+	}
+	if hits != 1 {
+		t.Fatalf("expected the icon to be downloaded once, got %d requests", hits)
+	}
+	for _, name := range []string{"no-such-icon", "../tags", "House"} {
+		if _, err := s.SVG(context.Background(), name); !errors.Is(err, ErrNotFound) {
+			t.Errorf("SVG(%q): expected ErrNotFound, got %v", name, err)
+		}
+	}
+	if hits != 1 {
+		t.Fatalf("unknown names must not reach the CDN")
+	}
+}
@@ -0,0 +1,55 @@
+package lucide
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/morezhou/hearth/internal/icon"
+)
+
+// ErrNotFound is returned for names that are not Lucide icons.
+var ErrNotFound = errors.New("lucide: icon not found")
+
+var validName = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// SVG returns the markup of the named icon: bundled when the binary has it,
+// otherwise downloaded from the CDN once and kept in Dir/icons.
+func (s *Set) SVG(ctx context.Context, name string) ([]byte, error) {
+	if !validName.MatchString(name) || !s.Has(name) {
+		return nil, ErrNotFound
+	}
+	if b, err := bundled.ReadFile("data/icons/" + name + ".svg"); err == nil {
+		return b, nil
+	}
+	var cached string
+	if s.Dir != "" {
+		cached = filepath.Join(s.Dir, "icons", s.version(), name+".svg")
+		if b, err := os.ReadFile(cached); err == nil {
+			return b, nil
+		}
+	}
+	b, err := s.fetch(ctx, s.version(), "icons/"+name+".svg", 64<<10)
+	if err != nil {
+		return nil, err
+	}
+	// The markup ends up inlined in the page, so never trust the CDN with it.
+	if b, err = icon.SanitizeSVG(b); err != nil {
+		return nil, err
+	}
+	if cached != "" {
+		_ = writeFileAtomic(cached, b)
+	}
+	return b, nil
+}
+
+// version is the release the current tags belong to, so downloaded icons
+// match the names being offered.
+func (s *Set) version() string {
+	if s.refreshed.Load() {
+		return "latest"
+	}
+	return Version
+}
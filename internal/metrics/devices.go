@@ -0,0 +1,226 @@
+package metrics
+
+import (
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/net"
+)
+
+// NetInterface is the traffic of one network interface. Rates are bytes per
+// second since the previous collection and zero on the first one.
+type NetInterface struct {
+	Name      string  `json:"name"`
+	BytesSent uint64  `json:"bytesSent"`
+	BytesRecv uint64  `json:"bytesRecv"`
+	SendRate  float64 `json:"sendRate"`
+	RecvRate  float64 `json:"recvRate"`
+}
+
+// Mount is the usage of one mounted filesystem.
+type Mount struct {
+	Path string `json:"path"`
+	// Label names a configured DiskPath.
+	Label   string  `json:"label,omitempty"`
+	FSType  string  `json:"fsType"`
+	Used    uint64  `json:"used"`
+	Total   uint64  `json:"total"`
+	Percent float64 `json:"percent"`
+}
+
+// DiskPath is a path whose filesystem is reported instead of the
+// discovered mounts, typically a host directory mounted into the container.
+type DiskPath struct {
+	Path  string `json:"path"`
+	Label string `json:"label,omitempty"`
+}
+
+// ParseDiskPaths parses a comma-separated list of paths, each optionally
+// labeled: "Data=/hostfs/data,/mnt/media".
+func ParseDiskPaths(s string) []DiskPath {
+	out := []DiskPath{}
+	for _, item := range strings.Split(s, ",") {
+		label, p, ok := strings.Cut(item, "=")
+		if !ok {
+			label, p = "", label
+		}
+		if dp, ok := CleanDiskPath(DiskPath{Path: p, Label: label}); ok {
+			out = append(out, dp)
+		}
+	}
+	return out
+}
+
+// CleanDiskPath trims and cleans d, reporting false when it has no path.
+func CleanDiskPath(d DiskPath) (DiskPath, bool) {
+	d.Path, d.Label = strings.TrimSpace(d.Path), strings.TrimSpace(d.Label)
+	if d.Path == "" {
+		return d, false
+	}
+	d.Path = filepath.Clean(d.Path)
+	return d, true
+}
+
+// DefaultIgnoredInterfaces are left out when no interface filter is set:
+// loopback and the virtual links container runtimes create per container.
+var DefaultIgnoredInterfaces = []string{"lo", "veth*", "docker*", "br-*", "virbr*", "cni*", "flannel*"}
+
+// DefaultExcludedMounts are filesystem types whose usage says nothing about
+// the host's disks.
+var DefaultExcludedMounts = []string{"tmpfs", "devtmpfs", "overlay", "squashfs"}
+
+// matchInterface reports whether name matches one of the glob patterns.
+func matchInterface(name string, patterns []string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// selectInterfaces keeps the counters of the interfaces matching include, or
+// of all but the DefaultIgnoredInterfaces when include is empty.
+func selectInterfaces(counters []net.IOCountersStat, include []string) []net.IOCountersStat {
+	out := make([]net.IOCountersStat, 0, len(counters))
+	for _, c := range counters {
+		if len(include) > 0 {
+			if !matchInterface(c.Name, include) {
+				continue
+			}
+		} else if matchInterface(c.Name, DefaultIgnoredInterfaces) {
+			continue
+		}
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// interfaceRates turns counters into NetInterfaces, deriving rates from the
+// previous counters taken elapsed ago. Counters that went backwards (an
+// interface reset) yield a zero rate.
+func interfaceRates(counters []net.IOCountersStat, prev map[string]net.IOCountersStat, elapsed time.Duration) []NetInterface {
+	out := make([]NetInterface, 0, len(counters))
+	for _, c := range counters {
+		ni := NetInterface{Name: c.Name, BytesSent: c.BytesSent, BytesRecv: c.BytesRecv}
+		if p, ok := prev[c.Name]; ok && elapsed > 0 {
+			secs := elapsed.Seconds()
+			if c.BytesSent >= p.BytesSent {
+				ni.SendRate = float64(c.BytesSent-p.BytesSent) / secs
+			}
+			if c.BytesRecv >= p.BytesRecv {
+				ni.RecvRate = float64(c.BytesRecv-p.BytesRecv) / secs
+			}
+		}
+		out = append(out, ni)
+	}
+	return out
+}
+
+// excludedMount reports whether a partition is filtered out: entries starting
+// with "/" exclude a mountpoint and everything below it, others a filesystem
+// type.
+func excludedMount(p disk.PartitionStat, exclude []string) bool {
+	for _, e := range exclude {
+		e = strings.TrimSpace(e)
+		switch {
+		case e == "":
+		case strings.HasPrefix(e, "/"):
+			e = strings.TrimSuffix(e, "/")
+			if p.Mountpoint == e || strings.HasPrefix(p.Mountpoint, e+"/") {
+				return true
+			}
+		case strings.EqualFold(p.Fstype, e):
+			return true
+		}
+	}
+	return false
+}
+
+// selectPartitions drops excluded partitions and repeated mounts of the same
+// device (bind mounts), keeping the shortest mountpoint.
+func selectPartitions(parts []disk.PartitionStat, exclude []string) []disk.PartitionStat {
+	byDevice := map[string]int{}
+	out := make([]disk.PartitionStat, 0, len(parts))
+	for _, p := range parts {
+		if excludedMount(p, exclude) {
+			continue
+		}
+		if i, ok := byDevice[p.Device]; ok && p.Device != "" && p.Device != "none" {
+			if len(p.Mountpoint) < len(out[i].Mountpoint) {
+				out[i] = p
+			}
+			continue
+		}
+		byDevice[p.Device] = len(out)
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Mountpoint < out[j].Mountpoint })
+	return out
+}
+
+// physicalDisks keeps the counters of whole disks, dropping partitions of
+// disks that are listed too (sda1, nvme0n1p2) and virtual devices stacked on
+// top of them, which would count the same I/O twice.
+func physicalDisks(counters map[string]disk.IOCountersStat) map[string]disk.IOCountersStat {
+	out := make(map[string]disk.IOCountersStat, len(counters))
+	for name, c := range counters {
+		if isVirtualDisk(name) || isPartitionOf(name, counters) {
+			continue
+		}
+		out[name] = c
+	}
+	return out
+}
+
+func isVirtualDisk(name string) bool {
+	for _, p := range []string{"loop", "ram", "zram", "dm-", "md", "sr", "fd"} {
+		if strings.HasPrefix(name, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPartitionOf reports whether name is a partition of another listed disk.
+func isPartitionOf(name string, counters map[string]disk.IOCountersStat) bool {
+	base := strings.TrimRight(name, "0123456789")
+	if base == name || base == "" {
+		return false
+	}
+	if _, ok := counters[base]; ok {
+		return true
+	}
+	// nvme0n1p1, mmcblk0p1
+	if strings.HasSuffix(base, "p") {
+		_, ok := counters[strings.TrimSuffix(base, "p")]
+		return ok
+	}
+	return false
+}
+
+// diskRates sums the counters and derives read and write rates from the
+// previous counters taken elapsed ago. Disks that are new or whose counters
+// went backwards don't contribute to the rates.
+func diskRates(counters, prev map[string]disk.IOCountersStat, elapsed time.Duration) (read, written uint64, readRate, writeRate float64) {
+	for name, c := range counters {
+		read += c.ReadBytes
+		written += c.WriteBytes
+		p, ok := prev[name]
+		if !ok || elapsed <= 0 {
+			continue
+		}
+		if c.ReadBytes >= p.ReadBytes {
+			readRate += float64(c.ReadBytes-p.ReadBytes) / elapsed.Seconds()
+		}
+		if c.WriteBytes >= p.WriteBytes {
+			writeRate += float64(c.WriteBytes-p.WriteBytes) / elapsed.Seconds()
+		}
+	}
+	return read, written, readRate, writeRate
+}
@@ -0,0 +1,144 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/net"
+)
+
+func TestSelectInterfaces(t *testing.T) {
+	counters := []net.IOCountersStat{{Name: "lo"}, {Name: "eth0"}, {Name: "veth12ab"}, {Name: "docker0"}, {Name: "wlan0"}}
+	names := func(cs []net.IOCountersStat) []string {
+		var out []string
+		for _, c := range cs {
+			out = append(out, c.Name)
+		}
+		return out
+	}
+	if got := names(selectInterfaces(counters, nil)); len(got) != 2 || got[0] != "eth0" || got[1] != "wlan0" {
+		t.Fatalf("default selection = %v", got)
+	}
+	if got := names(selectInterfaces(counters, []string{"eth*", "docker0"})); len(got) != 2 || got[0] != "docker0" || got[1] != "eth0" {
+		t.Fatalf("filtered selection = %v", got)
+	}
+}
+
+func TestInterfaceRates(t *testing.T) {
+	prev := map[string]net.IOCountersStat{
+		"eth0": {Name: "eth0", BytesSent: 1000, BytesRecv: 5000},
+		"eth1": {Name: "eth1", BytesSent: 9000, BytesRecv: 9000},
+	}
+	cur := []net.IOCountersStat{
+		{Name: "eth0", BytesSent: 3000, BytesRecv: 9000},
+		{Name: "eth1", BytesSent: 10, BytesRecv: 10}, // counters reset
+		{Name: "eth2", BytesSent: 500, BytesRecv: 500},
+	}
+	got := interfaceRates(cur, prev, 2*time.Second)
+	if got[0].SendRate != 1000 || got[0].RecvRate != 2000 {
+		t.Fatalf("eth0 rates = %v/%v", got[0].SendRate, got[0].RecvRate)
+	}
+	if got[1].SendRate != 0 || got[1].RecvRate != 0 {
+		t.Fatalf("reset interface should report zero, got %v/%v", got[1].SendRate, got[1].RecvRate)
+	}
+	if got[2].SendRate != 0 || got[2].BytesSent != 500 {
+		t.Fatalf("new interface = %+v", got[2])
+	}
+}
+
+func TestSelectPartitions(t *testing.T) {
+	parts := []disk.PartitionStat{
+		{Device: "overlay", Mountpoint: "/", Fstype: "overlay"},
+		{Device: "/dev/sda1", Mountpoint: "/etc/hosts", Fstype: "ext4"},
+		{Device: "/dev/sda1", Mountpoint: "/data", Fstype: "ext4"},
+		{Device: "tmpfs", Mountpoint: "/dev/shm", Fstype: "tmpfs"},
+		{Device: "/dev/sdb1", Mountpoint: "/mnt/media", Fstype: "xfs"},
+		{Device: "/dev/sdc1", Mountpoint: "/mnt/backup/snap", Fstype: "btrfs"},
+	}
+	got := selectPartitions(parts, append([]string{"/mnt/backup"}, DefaultExcludedMounts...))
+	if len(got) != 2 || got[0].Mountpoint != "/data" || got[1].Mountpoint != "/mnt/media" {
+		t.Fatalf("partitions = %+v", got)
+	}
+}
+
+func TestPhysicalDisks(t *testing.T) {
+	counters := map[string]disk.IOCountersStat{
+		"sda":       {ReadBytes: 100},
+		"sda1":      {ReadBytes: 60},
+		"sda2":      {ReadBytes: 40},
+		"nvme0n1":   {ReadBytes: 10},
+		"nvme0n1p1": {ReadBytes: 10},
+		"dm-0":      {ReadBytes: 50},
+		"loop3":     {ReadBytes: 5},
+		"vdb1":      {ReadBytes: 7}, // partition without its disk listed
+	}
+	got := physicalDisks(counters)
+	if len(got) != 3 {
+		t.Fatalf("disks = %v", got)
+	}
+	for _, name := range []string{"sda", "nvme0n1", "vdb1"} {
+		if _, ok := got[name]; !ok {
+			t.Fatalf("missing %s in %v", name, got)
+		}
+	}
+}
+
+func TestDiskRates(t *testing.T) {
+	prev := map[string]disk.IOCountersStat{
+		"sda": {ReadBytes: 1000, WriteBytes: 1000},
+		"sdb": {ReadBytes: 5000, WriteBytes: 5000},
+	}
+	cur := map[string]disk.IOCountersStat{
+		"sda": {ReadBytes: 5000, WriteBytes: 3000},
+		"sdb": {ReadBytes: 10, WriteBytes: 10}, // counters reset
+		"sdc": {ReadBytes: 700, WriteBytes: 700},
+	}
+	read, written, readRate, writeRate := diskRates(cur, prev, 4*time.Second)
+	if read != 5710 || written != 3710 {
+		t.Fatalf("totals = %d/%d", read, written)
+	}
+	if readRate != 1000 || writeRate != 500 {
+		t.Fatalf("rates = %v/%v", readRate, writeRate)
+	}
+}
+
+func TestCollectorRateWindow(t *testing.T) {
+	c := NewCollector()
+	ctx := context.Background()
+	if _, err := c.Collect(ctx, Options{}); err != nil {
+		t.Logf("partial collect: %v", err)
+	}
+	first := c.prevTime
+	if _, err := c.Collect(ctx, Options{}); err != nil {
+		t.Logf("partial collect: %v", err)
+	}
+	if !c.prevTime.Equal(first) {
+		t.Fatal("sample advanced before the rate window elapsed")
+	}
+}
+
+func TestRedactBucketsMounts(t *testing.T) {
+	m := HostMetrics{Mounts: []Mount{{Path: "/data", Used: 300 << 30, Total: 1000 << 30, Percent: 30}}}
+	r := m.Redact(Privacy{BucketDisk: true})
+	if r.Mounts[0].Total != 1<<40 {
+		t.Fatalf("bucketed total = %d", r.Mounts[0].Total)
+	}
+	if m.Mounts[0].Total != 1000<<30 {
+		t.Fatal("redact modified the original mounts")
+	}
+}
+
+func TestParseDiskPaths(t *testing.T) {
+	got := ParseDiskPaths(" Data = /hostfs/data/ ,/mnt/media,, Empty= ")
+	want := []DiskPath{{Path: "/hostfs/data", Label: "Data"}, {Path: "/mnt/media"}}
+	if len(got) != len(want) {
+		t.Fatalf("paths = %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("paths[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDockerHost is the Docker Engine socket used unless configured
+// otherwise.
+const DefaultDockerHost = "unix:///var/run/docker.sock"
+
+// dockerCacheTTL is how long container stats are reused. Docker computes
+// stats per request, which is slow with many containers.
+const dockerCacheTTL = 5 * time.Second
+
+// dockerStatsConcurrency caps the parallel stats requests.
+const dockerStatsConcurrency = 8
+
+// ErrDockerUnavailable is returned when the Docker Engine cannot be reached.
+var ErrDockerUnavailable = errors.New("docker unavailable")
+
+// Container is the resource usage of one running container. Rates are per
+// second since the previous collection and zero on the first one.
+type Container struct {
+	ID         string  `json:"id"`
+	Name       string  `json:"name"`
+	Image      string  `json:"image"`
+	State      string  `json:"state"`
+	Status     string  `json:"status"`
+	CPUPercent float64 `json:"cpuPercent"`
+	MemUsed    uint64  `json:"memUsed"`
+	MemLimit   uint64  `json:"memLimit"`
+	MemPercent float64 `json:"memPercent"`
+	NetRx      uint64  `json:"netRx"`
+	NetTx      uint64  `json:"netTx"`
+	NetRxRate  float64 `json:"netRxRate"`
+	NetTxRate  float64 `json:"netTxRate"`
+}
+
+// DockerClient reads container stats from the Docker Engine API. It keeps
+// the previous CPU and network counters of each container to derive usage
+// from one-shot stats, and caches the result for dockerCacheTTL.
+type DockerClient struct {
+	base   string
+	client *http.Client
+
+	mu       sync.Mutex
+	prev     map[string]dockerSample
+	cached   []Container
+	cachedAt time.Time
+}
+
+type dockerSample struct {
+	cpuTotal, system uint64
+	cpus             int
+	rx, tx           uint64
+	at               time.Time
+}
+
+// NewDockerClient returns a client for host, a unix:// socket path or a
+// tcp:// or http:// address.
+func NewDockerClient(host string) (*DockerClient, error) {
+	if host == "" {
+		host = DefaultDockerHost
+	}
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid docker host %q: %w", host, err)
+	}
+	c := &DockerClient{prev: map[string]dockerSample{}}
+	switch u.Scheme {
+	case "unix":
+		sock := u.Path
+		c.base = "http://docker"
+		c.client = &http.Client{
+			Timeout: 10 * time.Second,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", sock)
+				},
+			},
+		}
+	case "tcp", "http":
+		c.base = "http://" + u.Host
+		c.client = &http.Client{Timeout: 10 * time.Second}
+	default:
+		return nil, fmt.Errorf("unsupported docker host scheme %q", u.Scheme)
+	}
+	return c, nil
+}
+
+func (c *DockerClient) get(ctx context.Context, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.base+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDockerUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("docker %s: status %d", path, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Restart restarts the container with the given name or ID, giving it
+// timeout to stop gracefully.
+func (c *DockerClient) Restart(ctx context.Context, name string, timeout time.Duration) error {
+	path := fmt.Sprintf("/containers/%s/restart?t=%d", url.PathEscape(name), int(timeout.Seconds()))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.base+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDockerUnavailable, err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusNoContent, http.StatusOK:
+	case http.StatusNotFound:
+		return fmt.Errorf("no such container %q", name)
+	default:
+		return fmt.Errorf("docker restart %s: status %d", name, resp.StatusCode)
+	}
+	// The next collection should show the new state.
+	c.mu.Lock()
+	c.cached = nil
+	c.mu.Unlock()
+	return nil
+}
+
+type dockerListEntry struct {
+	ID     string   `json:"Id"`
+	Names  []string `json:"Names"`
+	Image  string   `json:"Image"`
+	State  string   `json:"State"`
+	Status string   `json:"Status"`
+}
+
+type dockerStats struct {
+	CPUStats struct {
+		CPUUsage struct {
+			TotalUsage uint64 `json:"total_usage"`
+		} `json:"cpu_usage"`
+		SystemUsage uint64 `json:"system_cpu_usage"`
+		OnlineCPUs  int    `json:"online_cpus"`
+	} `json:"cpu_stats"`
+	MemoryStats struct {
+		Usage uint64            `json:"usage"`
+		Limit uint64            `json:"limit"`
+		Stats map[string]uint64 `json:"stats"`
+	} `json:"memory_stats"`
+	Networks map[string]struct {
+		RxBytes uint64 `json:"rx_bytes"`
+		TxBytes uint64 `json:"tx_bytes"`
+	} `json:"networks"`
+}
+
+// Containers returns the running containers whose name matches one of the
+// glob patterns in allow (all when empty), sorted by name.
+func (c *DockerClient) Containers(ctx context.Context, allow []string) ([]Container, error) {
+	all, err := c.collect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Container, 0, len(all))
+	for _, ct := range all {
+		if len(allow) == 0 || matchInterface(ct.Name, allow) {
+			out = append(out, ct)
+		}
+	}
+	return out, nil
+}
+
+func (c *DockerClient) collect(ctx context.Context) ([]Container, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cached != nil && time.Since(c.cachedAt) < dockerCacheTTL {
+		return c.cached, nil
+	}
+
+	var list []dockerListEntry
+	if err := c.get(ctx, "/containers/json", &list); err != nil {
+		return nil, err
+	}
+	stats := make([]dockerStats, len(list))
+	errs := make([]error, len(list))
+	sem := make(chan struct{}, dockerStatsConcurrency)
+	var wg sync.WaitGroup
+	for i, e := range list {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			errs[i] = c.get(ctx, "/containers/"+url.PathEscape(e.ID)+"/stats?stream=false&one-shot=true", &stats[i])
+		}()
+	}
+	wg.Wait()
+
+	now := time.Now()
+	prev := make(map[string]dockerSample, len(list))
+	out := make([]Container, 0, len(list))
+	for i, e := range list {
+		ct := Container{ID: e.ID, Image: e.Image, State: e.State, Status: e.Status}
+		if len(ct.ID) > 12 {
+			ct.ID = ct.ID[:12]
+		}
+		if len(e.Names) > 0 {
+			ct.Name = strings.TrimPrefix(e.Names[0], "/")
+		}
+		if errs[i] == nil {
+			cur := containerUsage(&ct, stats[i], now)
+			containerRates(&ct, cur, c.prev[e.ID])
+			prev[e.ID] = cur
+		}
+		out = append(out, ct)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	c.prev = prev
+	c.cached, c.cachedAt = out, now
+	return out, nil
+}
+
+// containerUsage fills in memory and network totals from st and returns the
+// counters rates are derived from. Page cache that can be reclaimed is not
+// counted as used, matching `docker stats`.
+func containerUsage(ct *Container, st dockerStats, now time.Time) dockerSample {
+	ms := st.MemoryStats
+	cache := ms.Stats["inactive_file"] // cgroup v2
+	if v, ok := ms.Stats["total_inactive_file"]; ok {
+		cache = v // cgroup v1
+	}
+	if ms.Usage > cache {
+		ct.MemUsed = ms.Usage - cache
+	}
+	ct.MemLimit = ms.Limit
+	if ms.Limit > 0 {
+		ct.MemPercent = float64(ct.MemUsed) / float64(ms.Limit) * 100
+	}
+	for _, n := range st.Networks {
+		ct.NetRx += n.RxBytes
+		ct.NetTx += n.TxBytes
+	}
+	return dockerSample{
+		cpuTotal: st.CPUStats.CPUUsage.TotalUsage,
+		system:   st.CPUStats.SystemUsage,
+		cpus:     max(st.CPUStats.OnlineCPUs, 1),
+		rx:       ct.NetRx,
+		tx:       ct.NetTx,
+		at:       now,
+	}
+}
+
+// containerRates derives CPU usage and network rates from the previous
+// sample. CPU usage is relative to one core, like `docker stats`, so a busy
+// container on a 4-core host can report up to 400%.
+func containerRates(ct *Container, cur, prev dockerSample) {
+	if prev.at.IsZero() {
+		return
+	}
+	if cur.cpuTotal >= prev.cpuTotal && cur.system > prev.system {
+		ct.CPUPercent = float64(cur.cpuTotal-prev.cpuTotal) / float64(cur.system-prev.system) * float64(cur.cpus) * 100
+	}
+	if secs := cur.at.Sub(prev.at).Seconds(); secs > 0 {
+		if cur.rx >= prev.rx {
+			ct.NetRxRate = float64(cur.rx-prev.rx) / secs
+		}
+		if cur.tx >= prev.tx {
+			ct.NetTxRate = float64(cur.tx-prev.tx) / secs
+		}
+	}
+}
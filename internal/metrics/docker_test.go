@@ -0,0 +1,134 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeDocker serves two containers whose CPU and network counters grow by a
+// fixed step on every stats request.
+func fakeDocker(t *testing.T) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+	var round atomic.Int64
+	mux := http.NewServeMux()
+	mux.HandleFunc("/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[
+			{"Id":"aaaaaaaaaaaaaaaaaaaa","Names":["/jellyfin"],"Image":"jellyfin/jellyfin","State":"running","Status":"Up 2 hours"},
+			{"Id":"bbbbbbbbbbbbbbbbbbbb","Names":["/adguard"],"Image":"adguard/adguardhome","State":"running","Status":"Up 3 days"}
+		]`)
+	})
+	mux.HandleFunc("/containers/", func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/stats") || r.URL.Query().Get("stream") != "false" {
+			http.NotFound(w, r)
+			return
+		}
+		n := uint64(round.Load())
+		fmt.Fprintf(w, `{
+			"cpu_stats":{"cpu_usage":{"total_usage":%d},"system_cpu_usage":%d,"online_cpus":4},
+			"memory_stats":{"usage":600,"limit":2000,"stats":{"inactive_file":100}},
+			"networks":{"eth0":{"rx_bytes":%d,"tx_bytes":%d}}
+		}`, 1000+n*500, 10000+n*4000, 100+n*1000, 50+n*100)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv, &round
+}
+
+func TestDockerContainers(t *testing.T) {
+	srv, round := fakeDocker(t)
+	c, err := NewDockerClient("tcp://" + strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	list, err := c.Containers(ctx, nil)
+	if err != nil {
+		t.Fatalf("Containers: %v", err)
+	}
+	if len(list) != 2 || list[0].Name != "adguard" || list[1].ID != "aaaaaaaaaaaa" {
+		t.Fatalf("unexpected containers: %+v", list)
+	}
+	if ct := list[0]; ct.MemUsed != 500 || ct.MemPercent != 25 || ct.CPUPercent != 0 {
+		t.Fatalf("unexpected first sample: %+v", ct)
+	}
+
+	// Within the cache TTL the same result is served without new requests.
+	round.Add(1)
+	if list, _ = c.Containers(ctx, nil); list[0].CPUPercent != 0 {
+		t.Fatalf("expected cached result, got %+v", list[0])
+	}
+
+	c.mu.Lock()
+	c.cachedAt = time.Time{}
+	for id, p := range c.prev {
+		p.at = p.at.Add(-2 * time.Second)
+		c.prev[id] = p
+	}
+	c.mu.Unlock()
+	list, err = c.Containers(ctx, []string{"jelly*"})
+	if err != nil {
+		t.Fatalf("Containers: %v", err)
+	}
+	if len(list) != 1 || list[0].Name != "jellyfin" {
+		t.Fatalf("allow list not applied: %+v", list)
+	}
+	ct := list[0]
+	// 500 of 4000 system ticks on 4 cores is half a core.
+	if ct.CPUPercent != 50 {
+		t.Fatalf("cpu = %v", ct.CPUPercent)
+	}
+	if ct.NetRxRate < 400 || ct.NetRxRate > 600 || ct.NetRx != 1100 {
+		t.Fatalf("net rx = %d at %v/s", ct.NetRx, ct.NetRxRate)
+	}
+}
+
+func TestDockerUnavailable(t *testing.T) {
+	c, err := NewDockerClient("unix://" + t.TempDir() + "/docker.sock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Containers(context.Background(), nil); !errors.Is(err, ErrDockerUnavailable) {
+		t.Fatalf("expected unavailable error, got %v", err)
+	}
+	if _, err := NewDockerClient("ssh://nas"); err == nil {
+		t.Fatal("expected unsupported scheme error")
+	}
+}
+
+func TestDockerRestart(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method", http.StatusMethodNotAllowed)
+			return
+		}
+		if r.URL.Path == "/containers/missing/restart" {
+			http.NotFound(w, r)
+			return
+		}
+		got = r.URL.Path + "?" + r.URL.RawQuery
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+	c, err := NewDockerClient(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Restart(context.Background(), "jellyfin", 10*time.Second); err != nil {
+		t.Fatalf("Restart: %v", err)
+	}
+	if got != "/containers/jellyfin/restart?t=10" {
+		t.Fatalf("unexpected request %q", got)
+	}
+	if err := c.Restart(context.Background(), "missing", time.Second); err == nil || !strings.Contains(err.Error(), "no such container") {
+		t.Fatalf("expected a missing container error, got %v", err)
+	}
+}
@@ -4,21 +4,36 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/shirou/gopsutil/v3/cpu"
 	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/host"
+	"github.com/shirou/gopsutil/v3/load"
 	"github.com/shirou/gopsutil/v3/mem"
 	"github.com/shirou/gopsutil/v3/net"
 )
 
 type HostMetrics struct {
-	CollectedAt int64 `json:"collectedAt"`
+	CollectedAt int64  `json:"collectedAt"`
+	Hostname    string `json:"hostname,omitempty"`
 
 	CPUPercent float64 `json:"cpuPercent"`
 	CPUCores   int     `json:"cpuCores"`
 	CPUModel   string  `json:"cpuModel"`
+	// CPUPerCore is the usage of each logical core; empty on platforms that
+	// only report a total.
+	CPUPerCore []float64 `json:"cpuPerCore,omitempty"`
+
+	// Uptime is in seconds. Load averages are zero on Windows, which has
+	// none.
+	Uptime uint64  `json:"uptime"`
+	Load1  float64 `json:"load1"`
+	Load5  float64 `json:"load5"`
+	Load15 float64 `json:"load15"`
 
 	MemUsed    uint64  `json:"memUsed"`
 	MemTotal   uint64  `json:"memTotal"`
@@ -28,13 +43,75 @@ type HostMetrics struct {
 	DiskTotal   uint64  `json:"diskTotal"`
 	DiskPercent float64 `json:"diskPercent"`
 
-	NetBytesSent uint64 `json:"netBytesSent"`
-	NetBytesRecv uint64 `json:"netBytesRecv"`
+	// Disk I/O is summed over whole physical disks, in bytes and bytes per
+	// second.
+	DiskReadBytes  uint64  `json:"diskReadBytes"`
+	DiskWriteBytes uint64  `json:"diskWriteBytes"`
+	DiskReadRate   float64 `json:"diskReadRate"`
+	DiskWriteRate  float64 `json:"diskWriteRate"`
+
+	// Net counters and rates are summed over the selected interfaces.
+	NetBytesSent uint64  `json:"netBytesSent"`
+	NetBytesRecv uint64  `json:"netBytesRecv"`
+	NetSendRate  float64 `json:"netSendRate"`
+	NetRecvRate  float64 `json:"netRecvRate"`
+
+	Interfaces []NetInterface `json:"interfaces"`
+	Mounts     []Mount        `json:"mounts"`
+
+	// Sensor readings, including NVIDIA GPUs when nvidia-smi is installed.
+	Temperatures []Temperature `json:"temperatures"`
+	Fans         []Fan         `json:"fans"`
+
+	// Batteries are laptop batteries; UPS is set when Options.UPS is.
+	Batteries []Battery `json:"batteries"`
+	UPS       *UPS      `json:"ups,omitempty"`
+}
+
+// Options selects what Collect reports.
+type Options struct {
+	// Interfaces are glob patterns of the network interfaces to report. When
+	// empty, all but DefaultIgnoredInterfaces are reported.
+	Interfaces []string
+	// ExcludeMounts are filesystem types, or mountpoints when starting with
+	// "/", left out of Mounts.
+	ExcludeMounts []string
+	// DiskPaths, when set, replace the discovered mounts, and the first one
+	// readable stands in for "/" in the disk totals.
+	DiskPaths []DiskPath
+	// UPS is the address of a UPS daemon to report, see ReadUPS.
+	UPS string
 }
 
-func Collect(ctx context.Context) (HostMetrics, error) {
+// minRateWindow is the shortest time between the samples rates are derived
+// from. Several dashboards polling at once would otherwise measure rates over
+// a few milliseconds.
+const minRateWindow = time.Second
+
+// Collector collects host metrics, remembering the previous network and disk
+// counters so it can report rates.
+type Collector struct {
+	mu       sync.Mutex
+	prevNet  map[string]net.IOCountersStat
+	prevDisk map[string]disk.IOCountersStat
+	prevTime time.Time
+
+	gpuTemps []Temperature
+	gpuFans  []Fan
+	gpuAt    time.Time
+}
+
+// NewCollector returns a Collector with no previous sample.
+func NewCollector() *Collector {
+	return &Collector{}
+}
+
+func (c *Collector) Collect(ctx context.Context, opts Options) (HostMetrics, error) {
 	now := time.Now()
 	m := HostMetrics{CollectedAt: now.UnixMilli()}
+	if hn, err := os.Hostname(); err == nil {
+		m.Hostname = hn
+	}
 
 	var errs []string
 	recordErr := func(prefix string, err error) {
@@ -79,6 +156,16 @@ func Collect(ctx context.Context) (HostMetrics, error) {
 		}
 	}
 	m.CPUPercent = cpuPercent
+	m.CPUPerCore = percents
+
+	if up, err := host.UptimeWithContext(ctx); err == nil {
+		m.Uptime = up
+	} else {
+		recordErr("host.uptime", err)
+	}
+	if avg, err := load.AvgWithContext(ctx); err == nil {
+		m.Load1, m.Load5, m.Load15 = avg.Load1, avg.Load5, avg.Load15
+	}
 
 	cores, err := cpu.CountsWithContext(ctx, true)
 	recordErr("cpu.counts", err)
@@ -101,24 +188,81 @@ func Collect(ctx context.Context) (HostMetrics, error) {
 		m.MemPercent = vm.UsedPercent
 	}
 
-	diskUsage, err := disk.UsageWithContext(ctx, "/")
-	if err != nil {
-		recordErr("disk.usage", err)
-		diskUsage = &disk.UsageStat{}
+	if len(opts.DiskPaths) > 0 {
+		m.Mounts = diskPathMounts(ctx, opts.DiskPaths, recordErr)
+		if len(m.Mounts) > 0 {
+			m.DiskUsed, m.DiskTotal, m.DiskPercent = m.Mounts[0].Used, m.Mounts[0].Total, m.Mounts[0].Percent
+		}
+	} else {
+		diskUsage, err := disk.UsageWithContext(ctx, "/")
+		if err != nil {
+			recordErr("disk.usage", err)
+			diskUsage = &disk.UsageStat{}
+		}
+		m.DiskUsed = diskUsage.Used
+		m.DiskTotal = diskUsage.Total
+		m.DiskPercent = diskUsage.UsedPercent
+
+		parts, err := disk.PartitionsWithContext(ctx, false)
+		recordErr("disk.partitions", err)
+		m.Mounts = []Mount{}
+		for _, p := range selectPartitions(parts, opts.ExcludeMounts) {
+			u, err := disk.UsageWithContext(ctx, p.Mountpoint)
+			if err != nil || u.Total == 0 {
+				continue // unreadable or pseudo filesystem
+			}
+			m.Mounts = append(m.Mounts, Mount{Path: p.Mountpoint, FSType: p.Fstype, Used: u.Used, Total: u.Total, Percent: u.UsedPercent})
+		}
 	}
-	m.DiskUsed = diskUsage.Used
-	m.DiskTotal = diskUsage.Total
-	m.DiskPercent = diskUsage.UsedPercent
 
-	ioCounters, err := net.IOCountersWithContext(ctx, false)
+	ioCounters, err := net.IOCountersWithContext(ctx, true)
 	recordErr("net.ioCounters", err)
-	var sent, recv uint64
-	if len(ioCounters) > 0 {
-		sent = ioCounters[0].BytesSent
-		recv = ioCounters[0].BytesRecv
+	selected := selectInterfaces(ioCounters, opts.Interfaces)
+
+	diskCounters, err := disk.IOCountersWithContext(ctx)
+	recordErr("disk.ioCounters", err)
+	disks := physicalDisks(diskCounters)
+
+	c.mu.Lock()
+	var elapsed time.Duration
+	if !c.prevTime.IsZero() {
+		elapsed = now.Sub(c.prevTime)
+	}
+	m.Interfaces = interfaceRates(selected, c.prevNet, elapsed)
+	m.DiskReadBytes, m.DiskWriteBytes, m.DiskReadRate, m.DiskWriteRate = diskRates(disks, c.prevDisk, elapsed)
+	// Keep the older sample until the window is long enough, so rates are
+	// measured over at least minRateWindow.
+	if c.prevTime.IsZero() || elapsed >= minRateWindow {
+		c.prevNet = make(map[string]net.IOCountersStat, len(ioCounters))
+		for _, ic := range ioCounters {
+			c.prevNet[ic.Name] = ic
+		}
+		c.prevDisk = disks
+		c.prevTime = now
+	}
+	c.mu.Unlock()
+
+	m.Temperatures = collectTemperatures(ctx)
+	m.Fans = readHwmonFans(hwmonRoot)
+	gpuTemps, gpuFans := c.gpuSensors(ctx, now)
+	m.Temperatures = append(m.Temperatures, gpuTemps...)
+	m.Fans = append(m.Fans, gpuFans...)
+
+	m.Batteries = readBatteries(powerSupplyRoot)
+	if opts.UPS != "" {
+		if u, err := ReadUPS(ctx, opts.UPS); err == nil {
+			m.UPS = &u
+		} else {
+			recordErr("ups", err)
+		}
+	}
+
+	for _, ni := range m.Interfaces {
+		m.NetBytesSent += ni.BytesSent
+		m.NetBytesRecv += ni.BytesRecv
+		m.NetSendRate += ni.SendRate
+		m.NetRecvRate += ni.RecvRate
 	}
-	m.NetBytesSent = sent
-	m.NetBytesRecv = recv
 
 	if ctx.Err() != nil {
 		return m, ctx.Err()
@@ -128,3 +272,72 @@ func Collect(ctx context.Context) (HostMetrics, error) {
 	}
 	return m, nil
 }
+
+// diskPathMounts reports the filesystems of the configured paths, in order.
+// Unreadable paths are skipped and recorded as errors.
+func diskPathMounts(ctx context.Context, paths []DiskPath, recordErr func(string, error)) []Mount {
+	out := make([]Mount, 0, len(paths))
+	for _, p := range paths {
+		u, err := disk.UsageWithContext(ctx, p.Path)
+		if err != nil {
+			recordErr("disk.usage "+p.Path, err)
+			continue
+		}
+		out = append(out, Mount{Path: p.Path, Label: p.Label, FSType: u.Fstype, Used: u.Used, Total: u.Total, Percent: u.UsedPercent})
+	}
+	return out
+}
+
+// gpuSensors returns GPU readings, refreshed at most every gpuSensorTTL.
+func (c *Collector) gpuSensors(ctx context.Context, now time.Time) ([]Temperature, []Fan) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gpuAt.IsZero() || now.Sub(c.gpuAt) >= gpuSensorTTL {
+		c.gpuTemps, c.gpuFans = gpuSensors(ctx)
+		c.gpuAt = now
+	}
+	return c.gpuTemps, c.gpuFans
+}
+
+// Privacy controls which host details are exposed to unauthenticated viewers.
+type Privacy struct {
+	HideCPUModel bool
+	HideHostname bool
+	// BucketDisk replaces exact disk sizes with a coarse power-of-two bucket.
+	BucketDisk bool
+}
+
+// Redact returns a copy of m with the details selected by p removed.
+func (m HostMetrics) Redact(p Privacy) HostMetrics {
+	if p.HideCPUModel {
+		m.CPUModel = ""
+	}
+	if p.HideHostname {
+		m.Hostname = ""
+	}
+	if p.BucketDisk && m.DiskTotal > 0 {
+		m.DiskTotal = bucketBytes(m.DiskTotal)
+		// Keep used consistent with the reported percentage so the exact
+		// total can't be derived from used/percent.
+		m.DiskUsed = uint64(float64(m.DiskTotal) * m.DiskPercent / 100)
+	}
+	if p.BucketDisk && len(m.Mounts) > 0 {
+		mounts := make([]Mount, len(m.Mounts))
+		for i, mt := range m.Mounts {
+			mt.Total = bucketBytes(mt.Total)
+			mt.Used = uint64(float64(mt.Total) * mt.Percent / 100)
+			mounts[i] = mt
+		}
+		m.Mounts = mounts
+	}
+	return m
+}
+
+// bucketBytes rounds n up to the next power of two, with a floor of 1 GiB.
+func bucketBytes(n uint64) uint64 {
+	b := uint64(1 << 30)
+	for b < n && b < 1<<62 {
+		b <<= 1
+	}
+	return b
+}
@@ -0,0 +1,25 @@
+package metrics
+
+import (
+	"context"
+	"runtime"
+	"testing"
+)
+
+func TestCollectHostDetails(t *testing.T) {
+	m, err := NewCollector().Collect(context.Background(), Options{})
+	if err != nil {
+		t.Logf("partial collect: %v", err)
+	}
+	if len(m.CPUPerCore) != 0 && len(m.CPUPerCore) != m.CPUCores {
+		t.Fatalf("per-core has %d entries for %d cores", len(m.CPUPerCore), m.CPUCores)
+	}
+	if runtime.GOOS == "linux" {
+		if m.Uptime == 0 {
+			t.Fatal("expected an uptime")
+		}
+		if m.Load1 < 0 || m.Load5 < 0 || m.Load15 < 0 {
+			t.Fatalf("negative load: %v %v %v", m.Load1, m.Load5, m.Load15)
+		}
+	}
+}
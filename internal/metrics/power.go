@@ -0,0 +1,239 @@
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// powerSupplyRoot is where Linux exposes batteries and AC adapters.
+const powerSupplyRoot = "/sys/class/power_supply"
+
+// upsTimeout bounds a UPS query; the daemons answer from memory.
+const upsTimeout = 2 * time.Second
+
+// Battery is the charge of a laptop battery.
+type Battery struct {
+	Name    string  `json:"name"`
+	Percent float64 `json:"percent"`
+	// Status is "charging", "discharging", "full", "not charging" or
+	// "unknown".
+	Status string `json:"status"`
+}
+
+// UPS is the state of an uninterruptible power supply, read from NUT or
+// apcupsd.
+type UPS struct {
+	Name string `json:"name"`
+	// Status is the daemon's raw status, e.g. "OL CHRG" (NUT) or "ONBATT"
+	// (apcupsd).
+	Status    string  `json:"status"`
+	OnBattery bool    `json:"onBattery"`
+	LowBatt   bool    `json:"lowBattery"`
+	Charge    float64 `json:"charge"`            // percent
+	Runtime   int64   `json:"runtime,omitempty"` // seconds left on battery
+	Load      float64 `json:"load,omitempty"`    // percent of capacity
+}
+
+// readBatteries returns the batteries under root (a power_supply directory).
+func readBatteries(root string) []Battery {
+	dirs, _ := filepath.Glob(filepath.Join(root, "*"))
+	out := []Battery{}
+	for _, dir := range dirs {
+		typ, err := os.ReadFile(filepath.Join(dir, "type"))
+		if err != nil || strings.TrimSpace(string(typ)) != "Battery" {
+			continue
+		}
+		capacity, err := readUint(filepath.Join(dir, "capacity"))
+		if err != nil {
+			continue
+		}
+		status := "unknown"
+		if b, err := os.ReadFile(filepath.Join(dir, "status")); err == nil && strings.TrimSpace(string(b)) != "" {
+			status = strings.ToLower(strings.TrimSpace(string(b)))
+		}
+		out = append(out, Battery{Name: filepath.Base(dir), Percent: float64(capacity), Status: status})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// ReadUPS queries the UPS at addr, either nut://host[:3493]/upsname for a
+// Network UPS Tools server or apcupsd://host[:3551] for apcupsd's network
+// information server.
+func ReadUPS(ctx context.Context, addr string) (UPS, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return UPS{}, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, upsTimeout)
+	defer cancel()
+	switch u.Scheme {
+	case "nut":
+		name := strings.Trim(u.Path, "/")
+		if name == "" {
+			return UPS{}, errors.New("nut address needs a ups name: nut://host/ups")
+		}
+		return readNUT(ctx, hostWithPort(u, "3493"), name)
+	case "apcupsd":
+		return readApcupsd(ctx, hostWithPort(u, "3551"))
+	default:
+		return UPS{}, fmt.Errorf("unsupported ups address %q (want nut:// or apcupsd://)", addr)
+	}
+}
+
+func hostWithPort(u *url.URL, port string) string {
+	host := u.Hostname()
+	if host == "" {
+		host = "localhost"
+	}
+	if p := u.Port(); p != "" {
+		port = p
+	}
+	return net.JoinHostPort(host, port)
+}
+
+func dialUPS(ctx context.Context, addr string) (net.Conn, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if dl, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(dl)
+	}
+	return conn, nil
+}
+
+// readNUT lists the variables of one UPS over the NUT network protocol.
+func readNUT(ctx context.Context, addr, name string) (UPS, error) {
+	conn, err := dialUPS(ctx, addr)
+	if err != nil {
+		return UPS{}, err
+	}
+	defer conn.Close()
+	if _, err := fmt.Fprintf(conn, "LIST VAR %s\n", name); err != nil {
+		return UPS{}, err
+	}
+	vars := map[string]string{}
+	sc := bufio.NewScanner(conn)
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.HasPrefix(line, "ERR "):
+			return UPS{}, fmt.Errorf("nut: %s", strings.TrimPrefix(line, "ERR "))
+		case strings.HasPrefix(line, "END LIST"):
+			_, _ = io.WriteString(conn, "LOGOUT\n")
+			return nutUPS(name, vars), nil
+		case strings.HasPrefix(line, "VAR "):
+			// VAR <ups> <name> "<value>"
+			f := strings.SplitN(line, " ", 4)
+			if len(f) == 4 {
+				if v, err := strconv.Unquote(f[3]); err == nil {
+					vars[f[2]] = v
+				}
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return UPS{}, err
+	}
+	return UPS{}, errors.New("nut: connection closed before END LIST")
+}
+
+func nutUPS(name string, vars map[string]string) UPS {
+	status := vars["ups.status"]
+	flags := strings.Fields(status)
+	u := UPS{
+		Name:      name,
+		Status:    status,
+		OnBattery: slices.Contains(flags, "OB"),
+		LowBatt:   slices.Contains(flags, "LB"),
+	}
+	u.Charge, _ = strconv.ParseFloat(vars["battery.charge"], 64)
+	u.Load, _ = strconv.ParseFloat(vars["ups.load"], 64)
+	if rt, err := strconv.ParseFloat(vars["battery.runtime"], 64); err == nil {
+		u.Runtime = int64(rt)
+	}
+	if m := vars["device.model"]; m != "" {
+		u.Name = m
+	}
+	return u
+}
+
+// readApcupsd sends the "status" command to apcupsd's NIS. Messages in both
+// directions are framed with a two-byte big-endian length; an empty frame
+// ends the reply.
+func readApcupsd(ctx context.Context, addr string) (UPS, error) {
+	conn, err := dialUPS(ctx, addr)
+	if err != nil {
+		return UPS{}, err
+	}
+	defer conn.Close()
+	cmd := "status"
+	if err := binary.Write(conn, binary.BigEndian, uint16(len(cmd))); err != nil {
+		return UPS{}, err
+	}
+	if _, err := io.WriteString(conn, cmd); err != nil {
+		return UPS{}, err
+	}
+	vars := map[string]string{}
+	r := bufio.NewReader(conn)
+	for {
+		var n uint16
+		if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+			return UPS{}, err
+		}
+		if n == 0 {
+			return apcupsdUPS(vars), nil
+		}
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return UPS{}, err
+		}
+		// "BCHARGE  : 100.0 Percent"
+		if k, v, ok := strings.Cut(string(buf), ":"); ok {
+			vars[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+}
+
+func apcupsdUPS(vars map[string]string) UPS {
+	status := vars["STATUS"]
+	flags := strings.Fields(status)
+	u := UPS{
+		Name:      vars["UPSNAME"],
+		Status:    status,
+		OnBattery: slices.Contains(flags, "ONBATT"),
+		LowBatt:   slices.Contains(flags, "LOWBATT"),
+	}
+	if m := vars["MODEL"]; m != "" && u.Name == "" {
+		u.Name = m
+	}
+	u.Charge = leadingFloat(vars["BCHARGE"])
+	u.Load = leadingFloat(vars["LOADPCT"])
+	u.Runtime = int64(leadingFloat(vars["TIMELEFT"]) * 60) // minutes
+	return u
+}
+
+// leadingFloat parses the number in values like "45.0 Minutes".
+func leadingFloat(s string) float64 {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return 0
+	}
+	f, _ := strconv.ParseFloat(fields[0], 64)
+	return f
+}
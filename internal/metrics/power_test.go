@@ -0,0 +1,139 @@
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadBatteries(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel, content string) {
+		t.Helper()
+		p := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("AC/type", "Mains\n")
+	write("AC/online", "1\n")
+	write("BAT1/type", "Battery\n")
+	write("BAT1/capacity", "87\n")
+	write("BAT1/status", "Charging\n")
+	write("BAT0/type", "Battery\n")
+	write("BAT0/capacity", "100\n")
+	write("hidpp_battery_0/type", "Battery\n") // no capacity
+
+	got := readBatteries(root)
+	if len(got) != 2 {
+		t.Fatalf("batteries = %+v", got)
+	}
+	if got[0] != (Battery{Name: "BAT0", Percent: 100, Status: "unknown"}) {
+		t.Errorf("BAT0 = %+v", got[0])
+	}
+	if got[1] != (Battery{Name: "BAT1", Percent: 87, Status: "charging"}) {
+		t.Errorf("BAT1 = %+v", got[1])
+	}
+}
+
+// serveOnce accepts one connection on a local listener and hands it to fn.
+func serveOnce(t *testing.T, fn func(net.Conn)) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fn(conn)
+	}()
+	return ln.Addr().String()
+}
+
+func TestReadUPSNUT(t *testing.T) {
+	addr := serveOnce(t, func(conn net.Conn) {
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		if line != "LIST VAR eaton\n" {
+			_, _ = io.WriteString(conn, "ERR UNKNOWN-UPS\n")
+			return
+		}
+		_, _ = io.WriteString(conn, "BEGIN LIST VAR eaton\n"+
+			"VAR eaton battery.charge \"64\"\n"+
+			"VAR eaton battery.runtime \"1260\"\n"+
+			"VAR eaton device.model \"Eaton 5E\"\n"+
+			"VAR eaton ups.load \"23\"\n"+
+			"VAR eaton ups.status \"OB DISCHRG\"\n"+
+			"END LIST VAR eaton\n")
+	})
+	u, err := ReadUPS(context.Background(), "nut://"+addr+"/eaton")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := UPS{Name: "Eaton 5E", Status: "OB DISCHRG", OnBattery: true, Charge: 64, Runtime: 1260, Load: 23}
+	if u != want {
+		t.Errorf("ups = %+v, want %+v", u, want)
+	}
+
+	addr = serveOnce(t, func(conn net.Conn) {
+		_, _ = bufio.NewReader(conn).ReadString('\n')
+		_, _ = io.WriteString(conn, "ERR UNKNOWN-UPS\n")
+	})
+	if _, err := ReadUPS(context.Background(), "nut://"+addr+"/nope"); err == nil {
+		t.Error("expected an error for an unknown ups")
+	}
+	if _, err := ReadUPS(context.Background(), "nut://"+addr); err == nil {
+		t.Error("expected an error without a ups name")
+	}
+}
+
+func TestReadUPSApcupsd(t *testing.T) {
+	addr := serveOnce(t, func(conn net.Conn) {
+		var n uint16
+		if binary.Read(conn, binary.BigEndian, &n) != nil {
+			return
+		}
+		cmd := make([]byte, n)
+		if _, err := io.ReadFull(conn, cmd); err != nil || string(cmd) != "status" {
+			return
+		}
+		for _, l := range []string{
+			"APC      : 001,036,0857\n",
+			"UPSNAME  : rack\n",
+			"STATUS   : ONLINE \n",
+			"LOADPCT  : 12.0 Percent\n",
+			"BCHARGE  : 100.0 Percent\n",
+			"TIMELEFT : 45.5 Minutes\n",
+			"",
+		} {
+			_ = binary.Write(conn, binary.BigEndian, uint16(len(l)))
+			_, _ = io.WriteString(conn, l)
+		}
+	})
+	u, err := ReadUPS(context.Background(), "apcupsd://"+addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := UPS{Name: "rack", Status: "ONLINE", Charge: 100, Runtime: 2730, Load: 12}
+	if u != want {
+		t.Errorf("ups = %+v, want %+v", u, want)
+	}
+}
+
+func TestReadUPSBadAddress(t *testing.T) {
+	if _, err := ReadUPS(context.Background(), "snmp://ups"); err == nil {
+		t.Error("expected an error for an unsupported scheme")
+	}
+}
@@ -0,0 +1,129 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/host"
+)
+
+// Temperature is one sensor reading in degrees Celsius. High and Critical
+// are the thresholds reported by the sensor, zero when unknown.
+type Temperature struct {
+	Sensor   string  `json:"sensor"`
+	Celsius  float64 `json:"celsius"`
+	High     float64 `json:"high,omitempty"`
+	Critical float64 `json:"critical,omitempty"`
+}
+
+// Fan is one fan reading. Motherboard fans report RPM, GPU fans a duty cycle
+// in percent.
+type Fan struct {
+	Sensor  string  `json:"sensor"`
+	RPM     uint64  `json:"rpm,omitempty"`
+	Percent float64 `json:"percent,omitempty"`
+}
+
+// gpuSensorTTL is how long nvidia-smi readings are reused; running it takes
+// tens of milliseconds, too slow for every dashboard poll.
+const gpuSensorTTL = 10 * time.Second
+
+const hwmonRoot = "/sys/class/hwmon"
+
+// collectTemperatures returns the plausible readings of the host's
+// temperature sensors, sorted by sensor. Hosts without sensors (VMs,
+// containers without /sys) report none, which is not an error.
+func collectTemperatures(ctx context.Context) []Temperature {
+	stats, _ := host.SensorsTemperaturesWithContext(ctx)
+	out := make([]Temperature, 0, len(stats))
+	for _, st := range stats {
+		if st.Temperature <= 0 || st.Temperature > 150 {
+			continue // unconnected sensor inputs read 0 or garbage
+		}
+		out = append(out, Temperature{Sensor: st.SensorKey, Celsius: st.Temperature, High: st.High, Critical: st.Critical})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Sensor < out[j].Sensor })
+	return out
+}
+
+// readHwmonFans reads the fan speeds exposed by Linux hwmon drivers under
+// root. Sensors are named after the chip and the fan label, if any.
+func readHwmonFans(root string) []Fan {
+	inputs, _ := filepath.Glob(filepath.Join(root, "*", "fan*_input"))
+	out := make([]Fan, 0, len(inputs))
+	for _, in := range inputs {
+		rpm, err := readUint(in)
+		if err != nil {
+			continue
+		}
+		dir := filepath.Dir(in)
+		fan := strings.TrimSuffix(filepath.Base(in), "_input")
+		name := fan
+		if label, err := os.ReadFile(filepath.Join(dir, fan+"_label")); err == nil && strings.TrimSpace(string(label)) != "" {
+			name = strings.TrimSpace(string(label))
+		}
+		if chip, err := os.ReadFile(filepath.Join(dir, "name")); err == nil {
+			name = strings.TrimSpace(string(chip)) + "_" + name
+		}
+		out = append(out, Fan{Sensor: name, RPM: rpm})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Sensor < out[j].Sensor })
+	return out
+}
+
+func readUint(path string) (uint64, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+}
+
+// gpuSensors returns the temperatures and fans of NVIDIA GPUs, or nothing
+// when nvidia-smi is not installed.
+func gpuSensors(ctx context.Context) ([]Temperature, []Fan) {
+	bin, err := exec.LookPath("nvidia-smi")
+	if err != nil {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, bin,
+		"--query-gpu=index,name,temperature.gpu,fan.speed", "--format=csv,noheader,nounits").Output()
+	if err != nil {
+		return nil, nil
+	}
+	return parseNvidiaSMI(out)
+}
+
+// parseNvidiaSMI parses nvidia-smi's "index, name, temperature.gpu,
+// fan.speed" CSV output. Passively cooled GPUs report "[N/A]" for the fan.
+func parseNvidiaSMI(out []byte) ([]Temperature, []Fan) {
+	r := csv.NewReader(bytes.NewReader(out))
+	r.TrimLeadingSpace = true
+	r.FieldsPerRecord = 4
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, nil
+	}
+	var temps []Temperature
+	var fans []Fan
+	for _, rec := range records {
+		sensor := "gpu" + rec[0] + "_" + strings.TrimSpace(rec[1])
+		if c, err := strconv.ParseFloat(strings.TrimSpace(rec[2]), 64); err == nil {
+			temps = append(temps, Temperature{Sensor: sensor, Celsius: c})
+		}
+		if p, err := strconv.ParseFloat(strings.TrimSpace(rec[3]), 64); err == nil {
+			fans = append(fans, Fan{Sensor: sensor, Percent: p})
+		}
+	}
+	return temps, fans
+}
@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadHwmonFans(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel, content string) {
+		t.Helper()
+		p := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("hwmon0/name", "coretemp\n")
+	write("hwmon1/name", "nct6775\n")
+	write("hwmon1/fan1_input", "1200\n")
+	write("hwmon1/fan2_input", "850\n")
+	write("hwmon1/fan2_label", "CPU Fan\n")
+	write("hwmon1/fan3_input", "garbage\n")
+
+	got := readHwmonFans(root)
+	if len(got) != 2 {
+		t.Fatalf("fans = %+v", got)
+	}
+	if got[0].Sensor != "nct6775_CPU Fan" || got[0].RPM != 850 || got[1].Sensor != "nct6775_fan1" || got[1].RPM != 1200 {
+		t.Fatalf("fans = %+v", got)
+	}
+}
+
+func TestParseNvidiaSMI(t *testing.T) {
+	out := []byte("0, NVIDIA GeForce RTX 3060, 54, 30\n1, Tesla T4, 41, [N/A]\n")
+	temps, fans := parseNvidiaSMI(out)
+	if len(temps) != 2 || temps[0].Sensor != "gpu0_NVIDIA GeForce RTX 3060" || temps[0].Celsius != 54 || temps[1].Celsius != 41 {
+		t.Fatalf("temps = %+v", temps)
+	}
+	if len(fans) != 1 || fans[0].Percent != 30 {
+		t.Fatalf("fans = %+v", fans)
+	}
+	if temps, fans := parseNvidiaSMI([]byte("not csv, at all")); temps != nil || fans != nil {
+		t.Fatalf("expected nothing from malformed output, got %+v %+v", temps, fans)
+	}
+}
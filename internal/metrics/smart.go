@@ -0,0 +1,164 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os/exec"
+	"sort"
+	"sync"
+	"time"
+)
+
+// smartCacheTTL is how long SMART readings are reused. Reading SMART data
+// is slow and drive attributes change slowly.
+const smartCacheTTL = 5 * time.Minute
+
+// Drive health values.
+const (
+	DriveHealthPassed  = "passed"
+	DriveHealthFailed  = "failed"
+	DriveHealthUnknown = "unknown"
+	// DriveHealthStandby marks a spun-down drive that was not woken up.
+	DriveHealthStandby = "standby"
+)
+
+// Drive is the SMART health of one disk.
+type Drive struct {
+	Device       string  `json:"device"`
+	Model        string  `json:"model,omitempty"`
+	Serial       string  `json:"serial,omitempty"`
+	Health       string  `json:"health"`
+	Temperature  float64 `json:"temperature,omitempty"` // Celsius
+	Reallocated  uint64  `json:"reallocated"`           // reallocated sectors (ATA) or media errors (NVMe)
+	PowerOnHours uint64  `json:"powerOnHours,omitempty"`
+	PercentUsed  float64 `json:"percentUsed,omitempty"` // NVMe endurance used
+}
+
+// SMARTReader reads drive health with smartctl, which needs root or the
+// CAP_SYS_RAWIO capability and access to the block devices.
+type SMARTReader struct {
+	run func(ctx context.Context, args ...string) ([]byte, error)
+
+	mu       sync.Mutex
+	cached   []Drive
+	cachedAt time.Time
+}
+
+// NewSMARTReader returns a reader running the smartctl binary at bin.
+func NewSMARTReader(bin string) *SMARTReader {
+	if bin == "" {
+		bin = "smartctl"
+	}
+	return &SMARTReader{run: func(ctx context.Context, args ...string) ([]byte, error) {
+		ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+		out, err := exec.CommandContext(ctx, bin, args...).Output()
+		// smartctl's exit status is a bit mask that is also set for
+		// warnings (and for drives in standby); the JSON output is what
+		// counts.
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(out) > 0 {
+			err = nil
+		}
+		return out, err
+	}}
+}
+
+// Drives returns the health of every drive smartctl finds, sorted by
+// device. Drives in standby are reported without waking them.
+func (r *SMARTReader) Drives(ctx context.Context) ([]Drive, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cached != nil && time.Since(r.cachedAt) < smartCacheTTL {
+		return r.cached, nil
+	}
+	out, err := r.run(ctx, "--scan", "--json")
+	if err != nil {
+		return nil, err
+	}
+	var scan struct {
+		Devices []struct {
+			Name string `json:"name"`
+			Type string `json:"type"`
+		} `json:"devices"`
+	}
+	if err := json.Unmarshal(out, &scan); err != nil {
+		return nil, err
+	}
+	drives := make([]Drive, 0, len(scan.Devices))
+	for _, d := range scan.Devices {
+		args := []string{"--all", "--json", "--nocheck=standby"}
+		if d.Type != "" {
+			args = append(args, "--device="+d.Type)
+		}
+		out, err := r.run(ctx, append(args, d.Name)...)
+		drive := Drive{Device: d.Name, Health: DriveHealthUnknown}
+		if err == nil {
+			drive = parseSmartctl(d.Name, out)
+		}
+		drives = append(drives, drive)
+	}
+	sort.Slice(drives, func(i, j int) bool { return drives[i].Device < drives[j].Device })
+	r.cached, r.cachedAt = drives, time.Now()
+	return drives, nil
+}
+
+// parseSmartctl reads the `smartctl --all --json` output of one drive.
+func parseSmartctl(device string, out []byte) Drive {
+	d := Drive{Device: device, Health: DriveHealthUnknown}
+	var v struct {
+		ModelName    string `json:"model_name"`
+		SerialNumber string `json:"serial_number"`
+		Smartctl     struct {
+			ExitStatus int `json:"exit_status"`
+		} `json:"smartctl"`
+		PowerMode   string `json:"power_mode"`
+		SmartStatus *struct {
+			Passed bool `json:"passed"`
+		} `json:"smart_status"`
+		Temperature struct {
+			Current float64 `json:"current"`
+		} `json:"temperature"`
+		PowerOnTime struct {
+			Hours uint64 `json:"hours"`
+		} `json:"power_on_time"`
+		ATA struct {
+			Table []struct {
+				ID  int `json:"id"`
+				Raw struct {
+					Value uint64 `json:"value"`
+				} `json:"raw"`
+			} `json:"table"`
+		} `json:"ata_smart_attributes"`
+		NVMe *struct {
+			MediaErrors    uint64  `json:"media_errors"`
+			PercentageUsed float64 `json:"percentage_used"`
+		} `json:"nvme_smart_health_information_log"`
+	}
+	if err := json.Unmarshal(out, &v); err != nil {
+		return d
+	}
+	d.Model, d.Serial = v.ModelName, v.SerialNumber
+	d.Temperature = v.Temperature.Current
+	d.PowerOnHours = v.PowerOnTime.Hours
+	switch {
+	case v.SmartStatus != nil && v.SmartStatus.Passed:
+		d.Health = DriveHealthPassed
+	case v.SmartStatus != nil:
+		d.Health = DriveHealthFailed
+	case v.Smartctl.ExitStatus&2 != 0 && v.PowerMode != "":
+		// --nocheck=standby skipped a sleeping drive.
+		d.Health = DriveHealthStandby
+	}
+	for _, a := range v.ATA.Table {
+		if a.ID == 5 { // Reallocated_Sector_Ct
+			d.Reallocated = a.Raw.Value
+		}
+	}
+	if v.NVMe != nil {
+		d.Reallocated = v.NVMe.MediaErrors
+		d.PercentUsed = v.NVMe.PercentageUsed
+	}
+	return d
+}
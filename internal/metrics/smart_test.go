@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestSMARTReaderDrives(t *testing.T) {
+	outputs := map[string]string{
+		"--scan": `{"devices":[{"name":"/dev/sdb","type":"sat"},{"name":"/dev/nvme0","type":"nvme"},{"name":"/dev/sda","type":"sat"},{"name":"/dev/sdc","type":"sat"}]}`,
+		"/dev/sda": `{"model_name":"WDC WD40EFRX","serial_number":"WD-123","smart_status":{"passed":true},
+			"temperature":{"current":34},"power_on_time":{"hours":20000},
+			"ata_smart_attributes":{"table":[{"id":1,"raw":{"value":7}},{"id":5,"raw":{"value":8}}]}}`,
+		"/dev/sdb":   `{"smartctl":{"exit_status":2},"power_mode":"STANDBY"}`,
+		"/dev/sdc":   `{"model_name":"Old","smart_status":{"passed":false}}`,
+		"/dev/nvme0": `{"model_name":"Samsung 980","smart_status":{"passed":true},"nvme_smart_health_information_log":{"media_errors":1,"percentage_used":3}}`,
+	}
+	calls := 0
+	r := &SMARTReader{run: func(_ context.Context, args ...string) ([]byte, error) {
+		calls++
+		if args[0] == "--scan" {
+			return []byte(outputs["--scan"]), nil
+		}
+		if !strings.Contains(strings.Join(args, " "), "--nocheck=standby") {
+			t.Errorf("args %v would wake sleeping drives", args)
+		}
+		return []byte(outputs[args[len(args)-1]]), nil
+	}}
+
+	drives, err := r.Drives(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drives) != 4 || drives[0].Device != "/dev/nvme0" || drives[1].Device != "/dev/sda" {
+		t.Fatalf("drives = %+v", drives)
+	}
+	if d := drives[0]; d.Health != DriveHealthPassed || d.Reallocated != 1 || d.PercentUsed != 3 {
+		t.Errorf("nvme = %+v", d)
+	}
+	if d := drives[1]; d.Health != DriveHealthPassed || d.Temperature != 34 || d.Reallocated != 8 || d.PowerOnHours != 20000 || d.Serial != "WD-123" {
+		t.Errorf("sda = %+v", d)
+	}
+	if d := drives[2]; d.Health != DriveHealthStandby {
+		t.Errorf("sdb = %+v", d)
+	}
+	if d := drives[3]; d.Health != DriveHealthFailed {
+		t.Errorf("sdc = %+v", d)
+	}
+
+	// Readings are cached.
+	before := calls
+	if _, err := r.Drives(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if calls != before {
+		t.Errorf("smartctl ran again within the cache window")
+	}
+}
+
+func TestParseSmartctlGarbage(t *testing.T) {
+	if d := parseSmartctl("/dev/sda", []byte("not json")); d.Health != DriveHealthUnknown || d.Device != "/dev/sda" {
+		t.Errorf("drive = %+v", d)
+	}
+}
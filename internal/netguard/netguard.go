@@ -0,0 +1,147 @@
+// Package netguard restricts the addresses outbound HTTP requests may reach,
+// so URLs supplied by users (or redirects from them) cannot probe the host or
+// its network.
+package netguard
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrBlocked is returned for requests to addresses the policy does not allow.
+var ErrBlocked = errors.New("netguard: destination not allowed")
+
+// DefaultMaxRedirects caps redirect chains when Policy.MaxRedirects is 0.
+const DefaultMaxRedirects = 5
+
+// Policy decides which addresses outbound requests may connect to. Loopback,
+// link-local (including cloud metadata endpoints), multicast and unspecified
+// addresses are always blocked; private ranges are blocked unless
+// AllowPrivate is set. Addresses inside Allow are permitted regardless.
+type Policy struct {
+	AllowPrivate bool
+	Allow        []netip.Prefix
+	MaxRedirects int
+}
+
+// ParseAllowList parses a comma-separated list of CIDRs or IPs; the word
+// "private" allows all private ranges.
+func ParseAllowList(s string) (Policy, error) {
+	var p Policy
+	for _, f := range strings.Split(s, ",") {
+		f = strings.TrimSpace(f)
+		switch {
+		case f == "":
+		case strings.EqualFold(f, "private"):
+			p.AllowPrivate = true
+		case strings.Contains(f, "/"):
+			pfx, err := netip.ParsePrefix(f)
+			if err != nil {
+				return Policy{}, fmt.Errorf("netguard: %w", err)
+			}
+			p.Allow = append(p.Allow, pfx.Masked())
+		default:
+			addr, err := netip.ParseAddr(f)
+			if err != nil {
+				return Policy{}, fmt.Errorf("netguard: %w", err)
+			}
+			p.Allow = append(p.Allow, netip.PrefixFrom(addr, addr.BitLen()))
+		}
+	}
+	return p, nil
+}
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), used by
+// Tailscale among others; net/netip does not count it as private.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// CheckAddr reports whether the policy allows connecting to addr.
+func (p *Policy) CheckAddr(addr netip.Addr) error {
+	addr = addr.Unmap()
+	for _, pfx := range p.Allow {
+		if pfx.Contains(addr) {
+			return nil
+		}
+	}
+	switch {
+	case !addr.IsValid(), addr.IsUnspecified(), addr.IsLoopback(), addr.IsMulticast(),
+		addr.IsLinkLocalUnicast(), addr.IsLinkLocalMulticast(), addr.IsInterfaceLocalMulticast():
+		return fmt.Errorf("%w: %s", ErrBlocked, addr)
+	case addr.IsPrivate(), sharedAddressSpace.Contains(addr):
+		if !p.AllowPrivate {
+			return fmt.Errorf("%w: %s is a private address", ErrBlocked, addr)
+		}
+	}
+	return nil
+}
+
+// CheckHost resolves host and fails if any of its addresses is blocked, so
+// callers can reject a URL before fetching it.
+func (p *Policy) CheckHost(ctx context.Context, host string) error {
+	if addr, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil {
+		return p.CheckAddr(addr)
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return err
+	}
+	for _, a := range addrs {
+		if err := p.CheckAddr(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// control runs after DNS resolution, on the address actually dialed, so a
+// host that resolves differently on a second lookup cannot slip through.
+func (p *Policy) control(_, address string, _ syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrBlocked, address)
+	}
+	return p.CheckAddr(ap.Addr())
+}
+
+// Client returns an HTTP client that only connects to allowed addresses and
+// follows at most MaxRedirects http(s) redirects. Proxies from the
+// environment are ignored, since they would hide the real destination.
+func (p *Policy) Client(timeout time.Duration, insecureTLS bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second, Control: p.control}
+	tr := &http.Transport{
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          20,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+	if insecureTLS {
+		tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	maxRedirects := p.MaxRedirects
+	if maxRedirects <= 0 {
+		maxRedirects = DefaultMaxRedirects
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: tr,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > maxRedirects {
+				return fmt.Errorf("netguard: stopped after %d redirects", maxRedirects)
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("%w: redirect to %s", ErrBlocked, req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+}
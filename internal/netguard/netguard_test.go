@@ -0,0 +1,75 @@
+package netguard
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestCheckAddr(t *testing.T) {
+	var strict Policy
+	lan, err := ParseAllowList("private, 127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		addr          string
+		strict, lanOK bool
+	}{
+		{"93.184.216.34", true, true},
+		{"2606:2800:220:1::1", true, true},
+		{"127.0.0.1", false, true},
+		{"::1", false, false},
+		{"169.254.169.254", false, false},
+		{"::ffff:10.0.0.1", false, true},
+		{"192.168.1.10", false, true},
+		{"100.100.1.1", false, true},
+		{"fd00::1", false, true},
+		{"0.0.0.0", false, false},
+		{"224.0.0.1", false, false},
+	} {
+		addr := netip.MustParseAddr(tc.addr)
+		if got := strict.CheckAddr(addr) == nil; got != tc.strict {
+			t.Errorf("strict %s: allowed=%v, want %v", tc.addr, got, tc.strict)
+		}
+		if got := lan.CheckAddr(addr) == nil; got != tc.lanOK {
+			t.Errorf("lan %s: allowed=%v, want %v", tc.addr, got, tc.lanOK)
+		}
+	}
+	if _, err := ParseAllowList("10.0.0.0/33"); err == nil {
+		t.Fatal("expected an error for a bad CIDR")
+	}
+}
+
+func TestClient(t *testing.T) {
+	hops := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/loop" {
+			hops++
+			http.Redirect(w, r, "/loop", http.StatusFound)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	var strict Policy
+	if _, err := strict.Client(time.Second, false).Get(srv.URL); !errors.Is(err, ErrBlocked) {
+		t.Fatalf("expected a blocked loopback request, got %v", err)
+	}
+
+	p, _ := ParseAllowList("127.0.0.0/8")
+	p.MaxRedirects = 2
+	c := p.Client(time.Second, false)
+	resp, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("allowed request: %v", err)
+	}
+	resp.Body.Close()
+	if _, err := c.Get(srv.URL + "/loop"); err == nil || hops != 3 {
+		t.Fatalf("expected the redirect chain to stop after 2 redirects, got %v after %d hops", err, hops)
+	}
+}
@@ -0,0 +1,323 @@
+// Package plugins runs third-party widget providers. A plugin is a
+// directory with a plugin.json manifest and an executable; Hearth writes a
+// JSON request to its stdin and reads the widget content from its stdout.
+//
+// Plugins run with a timeout, a minimal environment, the plugin directory as
+// working directory and a cap on how much output is read. They are not
+// otherwise isolated, so only install plugins you trust.
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	manifestFile = "plugin.json"
+
+	defaultTimeout = 10 * time.Second
+	maxTimeout     = 60 * time.Second
+	defaultRefresh = 60 * time.Second
+	minRefresh     = 5 * time.Second
+
+	// maxOutput bounds what is read from a plugin's stdout.
+	maxOutput = 256 << 10
+	// MaxItems bounds the rows of a plugin's output.
+	MaxItems = 20
+)
+
+var idRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// Option is a setting a plugin accepts from the widget config. Options are
+// passed to the plugin as strings.
+type Option struct {
+	Name    string `json:"name"`
+	Label   string `json:"label,omitempty"`
+	Default string `json:"default,omitempty"`
+}
+
+// Manifest is a plugin's plugin.json.
+type Manifest struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Command     string   `json:"command"` // executable, relative to the plugin directory
+	Args        []string `json:"args,omitempty"`
+	Options     []Option `json:"options,omitempty"`
+	TimeoutSec  int      `json:"timeoutSec,omitempty"` // default 10, at most 60
+	RefreshSec  int      `json:"refreshSec,omitempty"` // how long output is reused; default 60
+}
+
+// Request is written to the plugin's stdin.
+type Request struct {
+	Options map[string]string `json:"options"`
+	Locale  string            `json:"locale"`
+}
+
+// Item is one row of a plugin's output.
+type Item struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// Output is what a plugin prints to stdout.
+type Output struct {
+	Title string `json:"title,omitempty"`
+	Value string `json:"value,omitempty"`
+	Unit  string `json:"unit,omitempty"`
+	Items []Item `json:"items,omitempty"`
+	URL   string `json:"url,omitempty"` // opened when the widget is clicked
+}
+
+type plugin struct {
+	Manifest
+	dir     string
+	command string // absolute path of the executable
+	timeout time.Duration
+	refresh time.Duration
+
+	mu    sync.Mutex // one run per plugin at a time
+	cache map[string]cachedOutput
+}
+
+type cachedOutput struct {
+	out Output
+	at  time.Time
+}
+
+// Registry holds the plugins found in a directory.
+type Registry struct {
+	plugins map[string]*plugin
+	run     func(ctx context.Context, p *plugin, stdin []byte) ([]byte, error)
+}
+
+// Load scans dir for plugins, one per subdirectory. Invalid plugins are
+// logged and skipped; a missing dir gives an empty registry.
+func Load(dir string) (*Registry, error) {
+	r := &Registry{plugins: map[string]*plugin{}, run: runCommand}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return r, nil
+		}
+		return nil, err
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		p, err := loadPlugin(filepath.Join(dir, e.Name()))
+		if err != nil {
+			log.Printf("[plugins] skipping %s: %v", e.Name(), err)
+			continue
+		}
+		if _, dup := r.plugins[p.ID]; dup {
+			log.Printf("[plugins] skipping %s: duplicate id %q", e.Name(), p.ID)
+			continue
+		}
+		r.plugins[p.ID] = p
+		log.Printf("[plugins] loaded %s (%s)", p.ID, p.dir)
+	}
+	return r, nil
+}
+
+func loadPlugin(dir string) (*plugin, error) {
+	raw, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("%s: %w", manifestFile, err)
+	}
+	if !idRe.MatchString(m.ID) {
+		return nil, fmt.Errorf("id %q must be 1-32 lowercase letters, digits, - or _", m.ID)
+	}
+	if strings.TrimSpace(m.Name) == "" {
+		m.Name = m.ID
+	}
+	for _, o := range m.Options {
+		if !idRe.MatchString(o.Name) {
+			return nil, fmt.Errorf("option %q must be 1-32 lowercase letters, digits, - or _", o.Name)
+		}
+	}
+
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	// The executable must live inside the plugin directory.
+	if m.Command == "" || filepath.IsAbs(m.Command) {
+		return nil, errors.New("command must be a path inside the plugin directory")
+	}
+	command := filepath.Join(dir, m.Command)
+	if rel, err := filepath.Rel(dir, command); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, errors.New("command must be a path inside the plugin directory")
+	}
+	if st, err := os.Stat(command); err != nil || st.IsDir() {
+		return nil, fmt.Errorf("command %s not found", m.Command)
+	}
+
+	p := &plugin{Manifest: m, dir: dir, command: command, timeout: defaultTimeout, refresh: defaultRefresh, cache: map[string]cachedOutput{}}
+	if m.TimeoutSec > 0 {
+		p.timeout = min(time.Duration(m.TimeoutSec)*time.Second, maxTimeout)
+	}
+	if m.RefreshSec > 0 {
+		p.refresh = max(time.Duration(m.RefreshSec)*time.Second, minRefresh)
+	}
+	return p, nil
+}
+
+// List returns the manifests of the loaded plugins, sorted by ID. A nil
+// registry (plugins disabled) has none.
+func (r *Registry) List() []Manifest {
+	if r == nil {
+		return []Manifest{}
+	}
+	out := make([]Manifest, 0, len(r.plugins))
+	for _, p := range r.plugins {
+		out = append(out, p.Manifest)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// Get returns the manifest of plugin id.
+func (r *Registry) Get(id string) (Manifest, bool) {
+	if r == nil {
+		return Manifest{}, false
+	}
+	p, ok := r.plugins[id]
+	if !ok {
+		return Manifest{}, false
+	}
+	return p.Manifest, true
+}
+
+// ErrNotFound is returned by Run for unknown plugins.
+var ErrNotFound = errors.New("plugin not found")
+
+// Run runs plugin id with options, or returns its cached output from a run
+// with the same options within the plugin's refresh interval. Options the
+// manifest does not declare are dropped; missing or empty ones get their
+// default.
+func (r *Registry) Run(ctx context.Context, id string, options map[string]string, locale string) (Output, error) {
+	if r == nil {
+		return Output{}, ErrNotFound
+	}
+	p, ok := r.plugins[id]
+	if !ok {
+		return Output{}, ErrNotFound
+	}
+	req := Request{Options: map[string]string{}, Locale: locale}
+	for _, o := range p.Options {
+		if v := options[o.Name]; v != "" {
+			req.Options[o.Name] = v
+		} else {
+			req.Options[o.Name] = o.Default
+		}
+	}
+	stdin, _ := json.Marshal(req)
+	sum := sha256.Sum256(stdin)
+	key := hex.EncodeToString(sum[:])
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if c, ok := p.cache[key]; ok && time.Since(c.at) < p.refresh {
+		return c.out, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	raw, err := r.run(ctx, p, stdin)
+	if err != nil {
+		return Output{}, fmt.Errorf("plugin %s: %w", id, err)
+	}
+	var out Output
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return Output{}, fmt.Errorf("plugin %s: invalid output: %w", id, err)
+	}
+	if len(out.Items) > MaxItems {
+		out.Items = out.Items[:MaxItems]
+	}
+	if out.URL != "" && !strings.HasPrefix(out.URL, "http://") && !strings.HasPrefix(out.URL, "https://") {
+		out.URL = ""
+	}
+
+	for k, c := range p.cache {
+		if time.Since(c.at) >= p.refresh {
+			delete(p.cache, k)
+		}
+	}
+	p.cache[key] = cachedOutput{out: out, at: time.Now()}
+	return out, nil
+}
+
+// runCommand runs the plugin executable with stdin, returning its stdout.
+func runCommand(ctx context.Context, p *plugin, stdin []byte) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, p.command, p.Args...)
+	cmd.Dir = p.dir
+	// Don't hand Hearth's environment (S3 keys and the like) to plugins.
+	cmd.Env = []string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + p.dir,
+		"LANG=C.UTF-8",
+		"HEARTH_PLUGIN_DIR=" + p.dir,
+	}
+	cmd.Stdin = bytes.NewReader(stdin)
+	stdout := &limitedBuffer{max: maxOutput}
+	stderr := &limitedBuffer{max: 4096}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	// Don't wait forever for children that inherited the pipes.
+	cmd.WaitDelay = 2 * time.Second
+
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("timed out after %s", p.timeout)
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	if stdout.overflow {
+		return nil, fmt.Errorf("output larger than %d bytes", maxOutput)
+	}
+	return stdout.Bytes(), nil
+}
+
+// limitedBuffer keeps the first max bytes written to it and discards the
+// rest, so a chatty plugin can't exhaust memory.
+type limitedBuffer struct {
+	bytes.Buffer
+	max      int
+	overflow bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.Len(); room < len(p) {
+		b.overflow = true
+		if room > 0 {
+			b.Buffer.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
@@ -0,0 +1,134 @@
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writePlugin(t *testing.T, root, name, manifest, script string) {
+	t.Helper()
+	dir := filepath.Join(root, name)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, manifestFile), []byte(manifest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if script != "" {
+		if err := os.WriteFile(filepath.Join(dir, "run.sh"), []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	root := t.TempDir()
+	writePlugin(t, root, "ok", `{"id":"ok","name":"OK","command":"run.sh","options":[{"name":"city","default":"Paris"}]}`, "echo '{}'")
+	writePlugin(t, root, "escape", `{"id":"escape","command":"../ok/run.sh"}`, "")
+	writePlugin(t, root, "badid", `{"id":"Bad ID","command":"run.sh"}`, "echo '{}'")
+	writePlugin(t, root, "missing", `{"id":"missing","command":"nope.sh"}`, "")
+	writePlugin(t, root, "zdup", `{"id":"ok","command":"run.sh"}`, "echo '{}'")
+	writePlugin(t, root, "unknown", `{"id":"unknown","command":"run.sh","extra":true}`, "echo '{}'")
+
+	r, err := Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := r.List()
+	if len(list) != 1 || list[0].ID != "ok" || list[0].Name != "OK" {
+		t.Fatalf("plugins = %+v", list)
+	}
+
+	if r, err := Load(filepath.Join(root, "absent")); err != nil || len(r.List()) != 0 {
+		t.Fatalf("expected an empty registry for a missing dir, got %v %v", r, err)
+	}
+}
+
+func TestRunCachesAndFiltersOptions(t *testing.T) {
+	root := t.TempDir()
+	writePlugin(t, root, "echo", `{"id":"echo","command":"run.sh","options":[{"name":"city","default":"Paris"},{"name":"unit"}]}`, "")
+	if err := os.WriteFile(filepath.Join(root, "echo", "run.sh"), []byte{}, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	r, err := Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var runs []Request
+	r.run = func(_ context.Context, _ *plugin, stdin []byte) ([]byte, error) {
+		var req Request
+		_ = json.Unmarshal(stdin, &req)
+		runs = append(runs, req)
+		items := make([]Item, MaxItems+5)
+		out, _ := json.Marshal(Output{Title: req.Options["city"], Items: items, URL: "javascript:alert(1)"})
+		return out, nil
+	}
+
+	out, err := r.Run(context.Background(), "echo", map[string]string{"unit": "C", "secret": "x"}, "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Title != "Paris" || len(out.Items) != MaxItems || out.URL != "" {
+		t.Fatalf("out = %+v", out)
+	}
+	if got := runs[0]; got.Locale != "en" || len(got.Options) != 2 || got.Options["unit"] != "C" {
+		t.Fatalf("request = %+v", got)
+	}
+
+	if _, err := r.Run(context.Background(), "echo", map[string]string{"unit": "C"}, "en"); err != nil || len(runs) != 1 {
+		t.Fatalf("expected a cached result, got %d runs (%v)", len(runs), err)
+	}
+	if _, err := r.Run(context.Background(), "echo", map[string]string{"unit": "F"}, "en"); err != nil || len(runs) != 2 {
+		t.Fatalf("expected other options to run again, got %d runs (%v)", len(runs), err)
+	}
+	if _, err := r.Run(context.Background(), "nope", nil, "en"); err != ErrNotFound {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs /bin/sh")
+	}
+	root := t.TempDir()
+	writePlugin(t, root, "hello", `{"id":"hello","command":"run.sh"}`,
+		`read req
+case "$req" in *'"locale":"zh"'*) echo '{"title":"你好","value":"42"}' ;; *) echo '{"title":"hi","value":"'"$SECRET"'"}' ;; esac`)
+	writePlugin(t, root, "slow", `{"id":"slow","command":"run.sh","timeoutSec":1}`, "sleep 5\necho '{}'")
+	writePlugin(t, root, "broken", `{"id":"broken","command":"run.sh"}`, "echo boom >&2\nexit 3")
+	writePlugin(t, root, "garbage", `{"id":"garbage","command":"run.sh"}`, "echo not json")
+
+	t.Setenv("SECRET", "leaked")
+	r, err := Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if out, err := r.Run(ctx, "hello", nil, "zh"); err != nil || out.Title != "你好" || out.Value != "42" {
+		t.Fatalf("out = %+v, err = %v", out, err)
+	}
+	if out, err := r.Run(ctx, "hello", nil, "en"); err != nil || out.Value != "" {
+		t.Fatalf("expected the environment not to be passed on, got %+v (%v)", out, err)
+	}
+
+	start := time.Now()
+	if _, err := r.Run(ctx, "slow", nil, "en"); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("err = %v", err)
+	}
+	if d := time.Since(start); d > 4*time.Second {
+		t.Fatalf("timeout took %s", d)
+	}
+	if _, err := r.Run(ctx, "broken", nil, "en"); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("err = %v", err)
+	}
+	if _, err := r.Run(ctx, "garbage", nil, "en"); err == nil || !strings.Contains(err.Error(), "invalid output") {
+		t.Fatalf("err = %v", err)
+	}
+}
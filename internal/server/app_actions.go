@@ -0,0 +1,287 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/morezhou/hearth/internal/store"
+)
+
+// Quick action kinds.
+const (
+	actionWebhook       = "webhook"
+	actionDockerRestart = "docker_restart"
+	actionSSH           = "ssh"
+)
+
+const (
+	maxAppActions = 10
+	// actionTimeout bounds one action run, SSH commands included.
+	actionTimeout = 30 * time.Second
+	// maxActionDetail is how much of a response or command output is kept
+	// in the audit trail.
+	maxActionDetail = 2048
+)
+
+// sshCommand is the ssh client SSH runners are started with.
+var sshCommand = "ssh"
+
+var (
+	actionIDRe      = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+	containerNameRe = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]{0,127}$`)
+)
+
+// sshRunner is an SSH command app actions may run. Runners are defined by the
+// operator in the HEARTH_ACTION_RUNNERS file, never through the API, so the
+// admin UI can only pick from commands allowed on the host.
+type sshRunner struct {
+	Host         string `json:"host"`
+	Port         int    `json:"port"`
+	User         string `json:"user"`
+	IdentityFile string `json:"identityFile"`
+	Command      string `json:"command"`
+}
+
+// loadActionRunners reads the runners file: a JSON object of runner name to
+// sshRunner.
+func loadActionRunners(path string) (map[string]sshRunner, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var runners map[string]sshRunner
+	if err := json.Unmarshal(b, &runners); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for name, r := range runners {
+		if !actionIDRe.MatchString(name) {
+			return nil, fmt.Errorf("invalid runner name %q", name)
+		}
+		if r.Host == "" || r.Command == "" {
+			return nil, fmt.Errorf("runner %q needs a host and a command", name)
+		}
+	}
+	return runners, nil
+}
+
+// args are the ssh arguments running r.
+func (r sshRunner) args() []string {
+	args := []string{"-o", "BatchMode=yes", "-o", "ConnectTimeout=10"}
+	if r.Port > 0 {
+		args = append(args, "-p", strconv.Itoa(r.Port))
+	}
+	if r.IdentityFile != "" {
+		args = append(args, "-i", r.IdentityFile)
+	}
+	target := r.Host
+	if r.User != "" {
+		target = r.User + "@" + r.Host
+	}
+	return append(args, "--", target, r.Command)
+}
+
+// normalizeAppActions validates the actions of req, assigning IDs to new
+// ones. Widgets have no actions.
+func (s *Server) normalizeAppActions(req *createAppRequest) string {
+	if strings.HasPrefix(req.URL, "widget:") || len(req.Actions) == 0 {
+		req.Actions = nil
+		return ""
+	}
+	if len(req.Actions) > maxAppActions {
+		return fmt.Sprintf("at most %d actions", maxAppActions)
+	}
+	seen := map[string]bool{}
+	for i := range req.Actions {
+		a := &req.Actions[i]
+		a.Label = strings.TrimSpace(a.Label)
+		if a.ID == "" {
+			a.ID = uuid.NewString()[:8]
+		}
+		if !actionIDRe.MatchString(a.ID) || seen[a.ID] {
+			return fmt.Sprintf("invalid action id %q", a.ID)
+		}
+		seen[a.ID] = true
+		if a.Label == "" {
+			return "action label required"
+		}
+		kind, target := a.Kind, *a
+		*a = store.AppAction{ID: a.ID, Label: a.Label, Kind: kind}
+		switch kind {
+		case actionWebhook:
+			u, err := url.Parse(strings.TrimSpace(target.URL))
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return "action url must be an http(s) url"
+			}
+			method := strings.ToUpper(strings.TrimSpace(target.Method))
+			switch method {
+			case "":
+				method = http.MethodPost
+			case http.MethodGet, http.MethodPost, http.MethodPut:
+			default:
+				return fmt.Sprintf("invalid action method %q", target.Method)
+			}
+			a.URL, a.Method, a.Body = u.String(), method, target.Body
+		case actionDockerRestart:
+			if !containerNameRe.MatchString(target.Container) {
+				return fmt.Sprintf("invalid container name %q", target.Container)
+			}
+			a.Container = target.Container
+		case actionSSH:
+			if _, ok := s.runners[target.Runner]; !ok {
+				return fmt.Sprintf("unknown runner %q", target.Runner)
+			}
+			a.Runner = target.Runner
+		default:
+			return fmt.Sprintf("invalid action kind %q", kind)
+		}
+	}
+	return ""
+}
+
+// runAppAction performs a and returns a short description of the outcome.
+func (s *Server) runAppAction(ctx context.Context, a store.AppAction) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, actionTimeout)
+	defer cancel()
+	switch a.Kind {
+	case actionWebhook:
+		var body io.Reader
+		if a.Body != "" {
+			body = strings.NewReader(a.Body)
+		}
+		req, err := http.NewRequestWithContext(ctx, a.Method, a.URL, body)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("User-Agent", "Hearth/0.1")
+		if b := strings.TrimSpace(a.Body); strings.HasPrefix(b, "{") || strings.HasPrefix(b, "[") {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		resp, err := s.webhookClient.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return "", fmt.Errorf("status %d", resp.StatusCode)
+		}
+		return fmt.Sprintf("status %d", resp.StatusCode), nil
+	case actionDockerRestart:
+		if s.docker == nil {
+			return "", errors.New("docker is disabled")
+		}
+		if err := s.docker.Restart(ctx, a.Container, 10*time.Second); err != nil {
+			return "", err
+		}
+		return "restarted " + a.Container, nil
+	case actionSSH:
+		r, ok := s.runners[a.Runner]
+		if !ok {
+			return "", fmt.Errorf("unknown runner %q", a.Runner)
+		}
+		out, err := exec.CommandContext(ctx, sshCommand, r.args()...).CombinedOutput()
+		detail := truncateDetail(strings.TrimSpace(string(out)))
+		if err != nil {
+			if detail != "" {
+				return "", fmt.Errorf("%v: %s", err, detail)
+			}
+			return "", err
+		}
+		return detail, nil
+	}
+	return "", fmt.Errorf("invalid action kind %q", a.Kind)
+}
+
+func truncateDetail(s string) string {
+	if len(s) <= maxActionDetail {
+		return s
+	}
+	return s[:maxActionDetail] + "…"
+}
+
+// handleRunAppAction handles POST /api/apps/{id}/actions/{actionId}. Every
+// run is recorded in the audit trail, failed ones included.
+func (s *Server) handleRunAppAction(w http.ResponseWriter, r *http.Request) {
+	id, actionID := chi.URLParam(r, "id"), chi.URLParam(r, "actionId")
+	app, ok, err := s.store.AppByID(id)
+	if err != nil {
+		slog.Error("failed to load app", "error", err, "id", id)
+		writeError(w, http.StatusInternalServerError, "failed to load app")
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "app not found")
+		return
+	}
+	var action *store.AppAction
+	for i := range app.Actions {
+		if app.Actions[i].ID == actionID {
+			action = &app.Actions[i]
+		}
+	}
+	if action == nil {
+		writeError(w, http.StatusNotFound, "action not found")
+		return
+	}
+
+	detail, runErr := s.runAppAction(r.Context(), *action)
+	entry := store.ActionAudit{AppID: app.ID, ActionID: action.ID, Label: action.Label, Kind: action.Kind, OK: runErr == nil, Detail: detail, RemoteIP: clientIP(r)}
+	if runErr != nil {
+		entry.Detail = truncateDetail(runErr.Error())
+	}
+	if recorded, err := s.store.RecordActionAudit(entry); err != nil {
+		slog.Error("failed to record action audit", "error", err, "id", id, "action", actionID)
+	} else {
+		entry = recorded
+	}
+	if runErr != nil {
+		slog.Warn("app action failed", "id", id, "action", actionID, "kind", action.Kind, "error", runErr)
+		writeError(w, http.StatusBadGateway, entry.Detail)
+		return
+	}
+	slog.Info("app action run", "id", id, "action", actionID, "kind", action.Kind)
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "audit": entry})
+}
+
+// handleListActionAudit handles GET /api/admin/actions/audit, optionally
+// filtered by ?appId=.
+func (s *Server) handleListActionAudit(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	list, err := s.store.ListActionAudit(r.URL.Query().Get("appId"), limit)
+	if err != nil {
+		slog.Error("failed to list action audit", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to list action audit")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"entries": list})
+}
+
+// handleListActionRunners handles GET /api/admin/actions/runners, the SSH
+// runners actions can pick from. Hosts and commands stay on the server.
+func (s *Server) handleListActionRunners(w http.ResponseWriter, r *http.Request) {
+	names := make([]string, 0, len(s.runners))
+	for name := range s.runners {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	writeJSON(w, http.StatusOK, map[string]any{"runners": names})
+}
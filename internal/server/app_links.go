@@ -0,0 +1,168 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+
+	"github.com/morezhou/hearth/internal/store"
+)
+
+// App open modes.
+const (
+	OpenModeNewTab  = "new_tab"
+	OpenModeSameTab = "same_tab"
+	OpenModeIframe  = "iframe"
+)
+
+// appView is an app as the dashboard gets it: Href is the link to open from
+// the requesting client, DescriptionHTML the rendered markdown description.
+type appView struct {
+	store.AppItem
+	Href            string `json:"href"`
+	DescriptionHTML string `json:"descriptionHtml,omitempty"`
+}
+
+// descriptionHTML renders the description of a, except for widgets, whose
+// description is their config.
+func descriptionHTML(a store.AppItem) string {
+	if a.Description == nil || strings.HasPrefix(a.URL, "widget:") {
+		return ""
+	}
+	return renderMarkdown(*a.Description)
+}
+
+// NetworkSettings tells internal clients from external ones.
+type NetworkSettings struct {
+	// InternalCIDRs are the client networks served the apps' internal URLs;
+	// empty means loopback, private and link-local addresses.
+	InternalCIDRs []string `json:"internalCidrs"`
+}
+
+// defaultInternalCIDRs apply when no internal CIDRs are configured.
+var defaultInternalCIDRs = []string{
+	"127.0.0.0/8", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "169.254.0.0/16",
+	"::1/128", "fc00::/7", "fe80::/10",
+}
+
+// parseCIDRs parses a list of CIDRs; a bare address stands for itself.
+func parseCIDRs(list []string) ([]netip.Prefix, error) {
+	out := make([]netip.Prefix, 0, len(list))
+	for _, v := range list {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		if !strings.Contains(v, "/") {
+			addr, err := netip.ParseAddr(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cidr %q", v)
+			}
+			out = append(out, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cidr %q", v)
+		}
+		out = append(out, p.Masked())
+	}
+	return out, nil
+}
+
+// cleanInternalCIDRs validates list and returns it in canonical form.
+func cleanInternalCIDRs(list []string) ([]string, error) {
+	prefixes, err := parseCIDRs(list)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, len(prefixes))
+	for i, p := range prefixes {
+		out[i] = p.String()
+	}
+	return out, nil
+}
+
+// internalCIDRs returns the configured internal networks, or the defaults.
+func (s *Server) internalCIDRs() []string {
+	var list []string
+	if raw := s.getStringSetting(kvNetworkInternalCIDRs, ""); raw != "" {
+		_ = json.Unmarshal([]byte(raw), &list)
+	}
+	if len(list) == 0 {
+		return defaultInternalCIDRs
+	}
+	return list
+}
+
+// internalClient reports whether the request comes from one of the internal
+// networks. The address is the peer's, or the client a trusted proxy names;
+// a LAN address in a header from anyone else doesn't count.
+func (s *Server) internalClient(r *http.Request) bool {
+	addr, err := netip.ParseAddr(clientIP(r))
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	prefixes, _ := parseCIDRs(s.internalCIDRs())
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// appHref picks the internal URL of a for internal clients when it has one.
+func appHref(a store.AppItem, internal bool) string {
+	if internal && a.InternalURL != nil && *a.InternalURL != "" {
+		return *a.InternalURL
+	}
+	return a.URL
+}
+
+// normalizeAppLink validates the open mode, internal URL and Wake-on-LAN MAC
+// of req. Widgets have none of them.
+func normalizeAppLink(req *createAppRequest) string {
+	mode := strings.ToLower(strings.TrimSpace(req.OpenMode))
+	if strings.HasPrefix(req.URL, "widget:") {
+		req.OpenMode, req.InternalURL, req.WakeMAC = store.DefaultOpenMode, nil, nil
+		return ""
+	}
+	switch mode {
+	case "":
+		mode = store.DefaultOpenMode
+	case OpenModeNewTab, OpenModeSameTab, OpenModeIframe:
+	default:
+		return "invalid open mode"
+	}
+	req.OpenMode = mode
+
+	if req.WakeMAC != nil {
+		if v := strings.TrimSpace(*req.WakeMAC); v == "" {
+			req.WakeMAC = nil
+		} else if mac, err := normalizeMAC(v); err != nil {
+			return err.Error()
+		} else {
+			req.WakeMAC = &mac
+		}
+	}
+
+	if req.InternalURL == nil {
+		return ""
+	}
+	raw := strings.TrimSpace(*req.InternalURL)
+	if raw == "" {
+		req.InternalURL = nil
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "internal url must be an http(s) url"
+	}
+	req.InternalURL = &raw
+	return ""
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/morezhou/hearth/internal/netguard"
+	"github.com/morezhou/hearth/internal/store"
+)
+
+// previewTimeout bounds fetching the page of a link preview.
+const previewTimeout = 15 * time.Second
+
+// handleFetchAppPreview handles POST /api/apps/{id}/preview: fetches the
+// title and description of the app's public page through the icon resolver
+// and stores them with the app, replacing any earlier preview.
+func (s *Server) handleFetchAppPreview(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	app, ok, err := s.store.AppByID(id)
+	if err != nil {
+		slog.Error("failed to load app", "error", err, "id", id)
+		writeError(w, http.StatusInternalServerError, "failed to load app")
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "app not found")
+		return
+	}
+	if strings.HasPrefix(app.URL, "widget:") {
+		writeError(w, http.StatusBadRequest, "widgets have no link preview")
+		return
+	}
+	u, err := url.Parse(app.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		writeError(w, http.StatusBadRequest, "app url is not an http(s) url")
+		return
+	}
+	if err := s.outbound.CheckHost(r.Context(), u.Hostname()); err != nil {
+		if errors.Is(err, netguard.ErrBlocked) {
+			writeError(w, http.StatusForbidden, "url points to an internal address; allow it with HEARTH_OUTBOUND_ALLOW")
+		} else {
+			writeError(w, http.StatusBadRequest, "cannot resolve host")
+		}
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), previewTimeout)
+	defer cancel()
+	p, err := s.iconResolver.Preview(ctx, app.URL)
+	if err != nil {
+		slog.Warn("link preview failed", "id", id, "url", app.URL, "error", err)
+		writeError(w, upstreamStatus(ctx, err, http.StatusBadGateway), "failed to fetch preview: "+err.Error())
+		return
+	}
+	preview := &store.LinkPreview{Title: p.Title, Description: p.Description, FetchedAt: time.Now().Unix()}
+	if ok, err := s.store.SetAppPreview(id, preview); err != nil || !ok {
+		if err != nil {
+			slog.Error("failed to save link preview", "error", err, "id", id)
+		}
+		writeError(w, http.StatusInternalServerError, "failed to save preview")
+		return
+	}
+	writeJSON(w, http.StatusOK, preview)
+}
+
+// handleDeleteAppPreview handles DELETE /api/apps/{id}/preview.
+func (s *Server) handleDeleteAppPreview(w http.ResponseWriter, r *http.Request) {
+	ok, err := s.store.SetAppPreview(chi.URLParam(r, "id"), nil)
+	if err != nil {
+		slog.Error("failed to clear link preview", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to clear preview")
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "app not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// maxShortcutKeys bounds the length of a shortcut's key sequence.
+const maxShortcutKeys = 3
+
+// normalizeShortcut parses a key sequence such as "g p", "g+p" or "G-P" into
+// its canonical form: lowercase letters and digits separated by spaces. A
+// sequence typed without separators ("gp") is read key by key.
+func normalizeShortcut(raw string) (string, error) {
+	fields := strings.FieldsFunc(strings.ToLower(raw), func(r rune) bool {
+		return r == ' ' || r == '+' || r == '-' || r == ','
+	})
+	if len(fields) == 1 {
+		fields = strings.Split(fields[0], "")
+	}
+	if len(fields) == 0 || len(fields) > maxShortcutKeys {
+		return "", fmt.Errorf("shortcut must be 1 to %d keys", maxShortcutKeys)
+	}
+	for _, k := range fields {
+		if len(k) != 1 || !(k[0] >= 'a' && k[0] <= 'z' || k[0] >= '0' && k[0] <= '9') {
+			return "", errors.New("shortcut keys must be letters or digits")
+		}
+	}
+	return strings.Join(fields, " "), nil
+}
+
+// shortcutsClash reports whether pressing one sequence would trigger the
+// other: equal sequences, or one being a prefix of the other.
+func shortcutsClash(a, b string) bool {
+	return a == b || strings.HasPrefix(a, b+" ") || strings.HasPrefix(b, a+" ")
+}
+
+// normalizeAppShortcut validates the shortcut of req, which is saved as the
+// app id (empty when creating), and checks it against every other app's.
+// Widgets have no shortcut.
+func (s *Server) normalizeAppShortcut(req *createAppRequest, id string) string {
+	if req.Shortcut == nil || strings.HasPrefix(req.URL, "widget:") || strings.TrimSpace(*req.Shortcut) == "" {
+		req.Shortcut = nil
+		return ""
+	}
+	sc, err := normalizeShortcut(*req.Shortcut)
+	if err != nil {
+		return err.Error()
+	}
+	req.Shortcut = &sc
+	apps, err := s.store.ListApps()
+	if err != nil {
+		slog.Error("failed to list apps", "error", err)
+		return "failed to validate shortcut"
+	}
+	for _, a := range apps {
+		if a.ID != id && a.Shortcut != nil && shortcutsClash(sc, *a.Shortcut) {
+			return fmt.Sprintf("shortcut %q clashes with %q of %s", sc, *a.Shortcut, a.Name)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/morezhou/hearth/internal/store"
+)
+
+const (
+	// appProbeInterval is how often apps are checked while a webhook
+	// subscribes to app.down or app.up.
+	appProbeInterval = time.Minute
+	// appDownAfter is how many failed checks in a row mark an app down, so a
+	// single slow response doesn't page anyone.
+	appDownAfter = 2
+)
+
+// appProbeClient only checks that apps answer. Home lab services often use
+// self-signed certificates, which don't make them any less up.
+var appProbeClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	},
+}
+
+// appStatus tracks the reachability of each app between probes.
+type appStatus struct {
+	mu    sync.Mutex
+	up    map[string]bool // app id -> last reported state
+	fails map[string]int  // app id -> consecutive failed checks
+}
+
+// observe records a check and returns the event to emit, if the app changed
+// state. The first observation of an app only sets its state.
+func (a *appStatus) observe(id string, ok bool) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.up == nil {
+		a.up, a.fails = map[string]bool{}, map[string]int{}
+	}
+	if ok {
+		a.fails[id] = 0
+	} else {
+		a.fails[id]++
+	}
+	wasUp, known := a.up[id]
+	switch {
+	case ok && !known, !ok && !known && a.fails[id] >= appDownAfter:
+		a.up[id] = ok
+	case ok && !wasUp:
+		a.up[id] = true
+		return eventAppUp
+	case !ok && wasUp && a.fails[id] >= appDownAfter:
+		a.up[id] = false
+		return eventAppDown
+	}
+	return ""
+}
+
+// forget drops the state of apps that no longer exist.
+func (a *appStatus) forget(keep map[string]bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for id := range a.up {
+		if !keep[id] {
+			delete(a.up, id)
+			delete(a.fails, id)
+		}
+	}
+}
+
+// runAppProber checks the apps every appProbeInterval while a webhook wants
+// their state.
+func (s *Server) runAppProber(ctx context.Context) {
+	t := time.NewTicker(appProbeInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		if s.subscribedToAny(eventAppDown, eventAppUp) {
+			s.probeApps(ctx)
+		}
+	}
+}
+
+// probeApps checks every app link once, archived apps aside, and emits
+// app.down / app.up for changes.
+func (s *Server) probeApps(ctx context.Context) {
+	apps, err := s.store.ListApps()
+	if err != nil {
+		slog.Warn("app probe: list apps failed", "error", err)
+		return
+	}
+	keep := map[string]bool{}
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 8)
+	for _, a := range apps {
+		if !strings.HasPrefix(a.URL, "http://") && !strings.HasPrefix(a.URL, "https://") {
+			continue // widgets
+		}
+		if a.Archived {
+			continue
+		}
+		keep[a.ID] = true
+		wg.Add(1)
+		go func(a store.AppItem) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			// The server sits on the LAN, so it checks the internal address.
+			target := appHref(a, true)
+			status, err := s.checkApp(ctx, target)
+			if ctx.Err() != nil {
+				return
+			}
+			if event := s.appStatus.observe(a.ID, err == nil); event != "" {
+				data := map[string]any{"id": a.ID, "name": a.Name, "url": target}
+				if status != 0 {
+					data["status"] = status
+				}
+				if err != nil {
+					data["error"] = err.Error()
+				}
+				s.emitEvent(event, data)
+			}
+		}(a)
+	}
+	wg.Wait()
+	s.appStatus.forget(keep)
+}
+
+// checkApp requests url and treats any response below 500 as up: login
+// pages and 401s still mean the service is there.
+func (s *Server) checkApp(ctx context.Context, url string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", "Hearth/0.1")
+	resp, err := appProbeClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode >= 500 {
+		return resp.StatusCode, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
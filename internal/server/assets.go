@@ -0,0 +1,87 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/morezhou/hearth/internal/storage"
+)
+
+// newAssetStorage builds the backends that hold cached icons and
+// backgrounds. Locally each gets its own directory as root, so no key can
+// reach the database or anything else in DataDir.
+func newAssetStorage(cfg Config) (icons, cache storage.Backend, err error) {
+	switch cfg.StorageBackend {
+	case "", "local":
+		if icons, err = storage.NewLocal(filepath.Join(cfg.DataDir, "icons")); err != nil {
+			return nil, nil, err
+		}
+		if cache, err = storage.NewLocal(filepath.Join(cfg.DataDir, "cache")); err != nil {
+			return nil, nil, err
+		}
+		return icons, cache, nil
+	case "s3":
+		b, err := storage.NewS3(cfg.S3)
+		if err != nil {
+			return nil, nil, err
+		}
+		return storage.Sub(b, "icons"), storage.Sub(b, "cache"), nil
+	default:
+		return nil, nil, fmt.Errorf("unknown storage backend %q", cfg.StorageBackend)
+	}
+}
+
+// svgCSP stops scripts in SVGs opened directly in the browser.
+const svgCSP = "default-src 'none'; style-src 'unsafe-inline'; img-src data:"
+
+// serveStored writes a stored object to the response, honoring conditional
+// and range requests. It returns false when the object does not exist.
+func serveStored(w http.ResponseWriter, r *http.Request, b storage.Backend, key string) (bool, error) {
+	rc, info, err := b.Get(r.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, 16<<20))
+	if err != nil {
+		return false, err
+	}
+	if info.ContentType != "" {
+		w.Header().Set("Content-Type", info.ContentType)
+	}
+	if strings.EqualFold(path.Ext(key), ".svg") {
+		// Icons stored before sanitization may still carry scripts.
+		w.Header().Set("Content-Security-Policy", svgCSP)
+	}
+	http.ServeContent(w, r, path.Base(key), info.ModTime, bytes.NewReader(data))
+	return true, nil
+}
+
+// storedAssetHandler serves objects below a storage namespace, keyed by the
+// remaining URL path.
+func storedAssetHandler(b storage.Backend) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/")
+		if key == "" || strings.HasSuffix(key, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		ok, err := serveStored(w, r, b, key)
+		if err != nil {
+			http.Error(w, "storage error", http.StatusBadGateway)
+			return
+		}
+		if !ok {
+			http.NotFound(w, r)
+		}
+	})
+}
@@ -1,32 +1,233 @@
 package server
 
-import "os"
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/morezhou/hearth/internal/background"
+	"github.com/morezhou/hearth/internal/icon"
+	"github.com/morezhou/hearth/internal/metrics"
+	"github.com/morezhou/hearth/internal/storage"
+)
 
 type Config struct {
 	Addr        string
 	DataDir     string
 	DatabaseDSN string
 	SessionTTL  string
+	// SessionMaxAge caps how long a session lasts however often it is used;
+	// SessionTTL is how long it lasts idle. Empty means no cap.
+	SessionMaxAge string
+	// InitialPassword is the admin password set on first boot; empty
+	// generates one that has to be changed after logging in.
+	InitialPassword string
+	// DBKey is the passphrase secrets in the database are encrypted with:
+	// webhook secrets, agent tokens, API keys and session tokens. Empty
+	// leaves them in plain text.
+	DBKey string
 	// Optional: when set, server can fetch and cache market icons on-demand.
 	// Example: https://raw.githubusercontent.com/<owner>/<repo>/main
 	MarketIconBaseURL string
+	// AssetCacheSize bounds the proxied assets (market icons) kept in the
+	// icon storage, in bytes; the least recently used are evicted.
+	AssetCacheSize int64
+	// Asset storage for cached icons and backgrounds: "local" (DataDir) or "s3".
+	StorageBackend string
+	S3             storage.S3Config
+	// GeoNames enables offline city search from the GeoNames cities15000
+	// dump, downloaded once into DataDir/geonames.
+	GeoNames bool
+	// IconPack names the icon set apps can pick icons from ("dashboard-icons"
+	// or "selfhst"), kept in DataDir/iconpacks; "off" disables it.
+	IconPack        string
+	IconPackBaseURL string // optional mirror of the pack's CDN
+	// IconMaxSize is the longest side cached icons are scaled down to; 0
+	// keeps them as downloaded.
+	IconMaxSize int
+	// OutboundAllow lists CIDRs (or "private") that icon, background and
+	// market icon fetches may reach despite being internal addresses.
+	OutboundAllow string
+	// BackgroundHistory is how many fetched backgrounds are kept per
+	// provider for the previous/next actions.
+	BackgroundHistory int
+	// BackgroundMaxSize is the longest side fetched backgrounds are scaled
+	// down to; 0 keeps them as downloaded.
+	BackgroundMaxSize int
+	// NASAAPIKey is used by the APOD background provider; empty falls back
+	// to NASA's rate-limited DEMO_KEY.
+	NASAAPIKey string
+	// MetricsSampleInterval is how often host metrics are recorded for the
+	// history charts; 0 disables recording.
+	MetricsSampleInterval time.Duration
+	// MetricsCollectInterval is how often the local host metrics served to
+	// the widget are refreshed.
+	MetricsCollectInterval time.Duration
+	// DockerHost is the Docker Engine API container metrics are read from
+	// (unix:// socket or tcp:// address); "off" disables them.
+	DockerHost string
+	// MetricsDiskPaths are the paths whose filesystems the host metrics
+	// report instead of "/" and the discovered mounts; the
+	// settings.metrics.diskPaths setting overrides them.
+	MetricsDiskPaths []metrics.DiskPath
+	// UPS is the NUT (nut://host/ups) or apcupsd (apcupsd://host) daemon
+	// whose UPS is reported with the host metrics; empty disables it.
+	UPS string
+	// SMART enables drive health reporting via smartctl, which needs root
+	// (or CAP_SYS_RAWIO) and the block devices.
+	SMART bool
+	// SmartctlPath is the smartctl binary; empty looks it up in PATH.
+	SmartctlPath string
+	// RateLimits overrides the per-IP limits of the public route groups,
+	// e.g. "lookup=10/m,metrics=off"; "off" disables rate limiting.
+	RateLimits string
+	// TrustedProxies lists the CIDRs of the reverse proxies whose
+	// X-Forwarded-For and X-Real-IP headers name the client; requests from
+	// anywhere else are keyed on their own address.
+	TrustedProxies string
+	// PluginsDir is scanned at startup for widget plugins, one per
+	// subdirectory; "off" disables plugins.
+	PluginsDir string
+	// ActionRunners is a JSON file of the SSH commands app quick actions may
+	// run, keyed by runner name; empty disables SSH actions.
+	ActionRunners string
+	// Demo fills a new dashboard with sample groups, apps and widgets once,
+	// for screenshots and trials.
+	Demo bool
+	// UndoWindow is how long deleted apps and groups, imports and resets
+	// can be undone via /api/admin/undo; 0 disables the undo journal.
+	UndoWindow time.Duration
+	// CustomJS lets the admin add a script to every page via
+	// /api/customization. Off by default: the script runs for all visitors.
+	CustomJS bool
+	// ReplicaOf is the URL of the primary Hearth this instance copies its
+	// configuration from every ReplicaInterval, authenticating with
+	// ReplicaToken; empty disables replication.
+	ReplicaOf       string
+	ReplicaToken    string
+	ReplicaInterval time.Duration
 }
 
 const defaultMarketIconBaseURL = "https://raw.githubusercontent.com/nvstly/icons/main"
 
+const defaultBackgroundHistory = 10
+
 func LoadConfigFromEnv() Config {
 	addr := getEnv("HEARTH_ADDR", ":8787")
 	dataDir := getEnv("HEARTH_DATA_DIR", "./data")
 	dsn := getEnv("HEARTH_DB_DSN", dataDir+"/hearth.db")
 	sessionTTL := getEnv("HEARTH_SESSION_TTL", "168h")
+	sessionMaxAge := getEnv("HEARTH_SESSION_MAX_AGE", "720h")
+	if sessionMaxAge == "0" {
+		sessionMaxAge = ""
+	}
 	marketIconBaseURL := getEnv("HEARTH_MARKET_ICON_BASE_URL", defaultMarketIconBaseURL)
+	dbKey := getEnv("HEARTH_DB_KEY", "")
+	if f := getEnv("HEARTH_DB_KEY_FILE", ""); f != "" && dbKey == "" {
+		// Docker and Kubernetes secrets are mounted as files.
+		if b, err := os.ReadFile(f); err == nil {
+			dbKey = strings.TrimSpace(string(b))
+		} else {
+			log.Fatalf("read HEARTH_DB_KEY_FILE: %v", err)
+		}
+	}
+	storageBackend := strings.ToLower(getEnv("HEARTH_STORAGE", "local"))
+	iconMaxSize, err := strconv.Atoi(getEnv("HEARTH_ICON_MAX_SIZE", strconv.Itoa(icon.DefaultMaxIconSize)))
+	if err != nil || iconMaxSize < 0 {
+		iconMaxSize = icon.DefaultMaxIconSize
+	}
+	bgMaxSize, err := strconv.Atoi(getEnv("HEARTH_BACKGROUND_MAX_SIZE", strconv.Itoa(background.DefaultMaxDimension)))
+	if err != nil || bgMaxSize < 0 {
+		bgMaxSize = background.DefaultMaxDimension
+	}
+	bgHistory, err := strconv.Atoi(getEnv("HEARTH_BACKGROUND_HISTORY", strconv.Itoa(defaultBackgroundHistory)))
+	if err != nil || bgHistory < 1 {
+		bgHistory = defaultBackgroundHistory
+	}
+	metricsInterval, err := time.ParseDuration(getEnv("HEARTH_METRICS_SAMPLE_INTERVAL", defaultMetricsSampleInterval.String()))
+	if err != nil || metricsInterval < 0 {
+		metricsInterval = defaultMetricsSampleInterval
+	} else if metricsInterval > 0 && metricsInterval < time.Second {
+		metricsInterval = time.Second
+	}
+	collectInterval, err := time.ParseDuration(getEnv("HEARTH_METRICS_INTERVAL", defaultMetricsCollectInterval.String()))
+	if err != nil || collectInterval <= 0 {
+		collectInterval = defaultMetricsCollectInterval
+	} else if collectInterval < time.Second {
+		collectInterval = time.Second
+	}
+	undoWindow, err := time.ParseDuration(getEnv("HEARTH_UNDO_WINDOW", defaultUndoWindow.String()))
+	if err != nil || undoWindow < 0 {
+		undoWindow = defaultUndoWindow
+	}
+	assetCacheMB, err := strconv.ParseInt(getEnv("HEARTH_ASSET_CACHE_MB", "64"), 10, 64)
+	if err != nil || assetCacheMB <= 0 {
+		assetCacheMB = 64
+	}
+	demo := getEnv("HEARTH_DEMO", "false")
+	replicaToken := getEnv("HEARTH_REPLICA_TOKEN", "")
+	if f := getEnv("HEARTH_REPLICA_TOKEN_FILE", ""); f != "" && replicaToken == "" {
+		if b, err := os.ReadFile(f); err == nil {
+			replicaToken = strings.TrimSpace(string(b))
+		} else {
+			log.Fatalf("read HEARTH_REPLICA_TOKEN_FILE: %v", err)
+		}
+	}
+	replicaInterval, err := time.ParseDuration(getEnv("HEARTH_REPLICA_INTERVAL", defaultReplicaInterval.String()))
+	if err != nil || replicaInterval <= 0 {
+		replicaInterval = defaultReplicaInterval
+	} else if replicaInterval < time.Minute {
+		replicaInterval = time.Minute
+	}
 
 	return Config{
 		Addr:              addr,
 		DataDir:           dataDir,
 		DatabaseDSN:       dsn,
 		SessionTTL:        sessionTTL,
+		SessionMaxAge:     sessionMaxAge,
+		InitialPassword:   getEnv("HEARTH_INITIAL_PASSWORD", ""),
+		DBKey:             dbKey,
 		MarketIconBaseURL: marketIconBaseURL,
+		AssetCacheSize:    assetCacheMB << 20,
+		StorageBackend:    storageBackend,
+		S3: storage.S3Config{
+			Endpoint:  getEnv("HEARTH_S3_ENDPOINT", ""),
+			Region:    getEnv("HEARTH_S3_REGION", "us-east-1"),
+			Bucket:    getEnv("HEARTH_S3_BUCKET", ""),
+			AccessKey: getEnv("HEARTH_S3_ACCESS_KEY", ""),
+			SecretKey: getEnv("HEARTH_S3_SECRET_KEY", ""),
+			Prefix:    getEnv("HEARTH_S3_PREFIX", ""),
+			PathStyle: getEnv("HEARTH_S3_PATH_STYLE", "false") == "true",
+		},
+		GeoNames:               getEnv("HEARTH_GEONAMES", "false") == "true",
+		IconPack:               strings.ToLower(getEnv("HEARTH_ICON_PACK", "dashboard-icons")),
+		IconPackBaseURL:        getEnv("HEARTH_ICON_PACK_BASE_URL", ""),
+		IconMaxSize:            iconMaxSize,
+		OutboundAllow:          getEnv("HEARTH_OUTBOUND_ALLOW", ""),
+		BackgroundHistory:      bgHistory,
+		BackgroundMaxSize:      bgMaxSize,
+		NASAAPIKey:             getEnv("HEARTH_NASA_API_KEY", ""),
+		MetricsSampleInterval:  metricsInterval,
+		MetricsCollectInterval: collectInterval,
+		DockerHost:             getEnv("HEARTH_DOCKER_HOST", metrics.DefaultDockerHost),
+		MetricsDiskPaths:       metrics.ParseDiskPaths(getEnv("HEARTH_METRICS_DISK_PATHS", "")),
+		UPS:                    getEnv("HEARTH_UPS", ""),
+		SMART:                  getEnv("HEARTH_SMART", "false") == "true",
+		SmartctlPath:           getEnv("HEARTH_SMARTCTL", ""),
+		RateLimits:             getEnv("HEARTH_RATE_LIMITS", ""),
+		TrustedProxies:         getEnv("HEARTH_TRUSTED_PROXIES", ""),
+		PluginsDir:             getEnv("HEARTH_PLUGINS_DIR", filepath.Join(dataDir, "plugins")),
+		CustomJS:               getEnv("HEARTH_CUSTOM_JS", "false") == "true",
+		ActionRunners:          getEnv("HEARTH_ACTION_RUNNERS", ""),
+		UndoWindow:             undoWindow,
+		ReplicaOf:              getEnv("HEARTH_REPLICA_OF", ""),
+		ReplicaToken:           replicaToken,
+		ReplicaInterval:        replicaInterval,
+		Demo:                   demo == "1" || demo == "true",
 	}
 }
 
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/morezhou/hearth/internal/store"
+)
+
+const (
+	// dbMaintenanceInterval is how often the WAL is checkpointed and the
+	// database checked.
+	dbMaintenanceInterval = 24 * time.Hour
+	// dbVacuumFreeRatio is the share of free pages above which the periodic
+	// maintenance also vacuums; below it the rewrite isn't worth it.
+	dbVacuumFreeRatio = 0.2
+)
+
+// dbMaintenance serializes maintenance runs and remembers the latest report.
+type dbMaintenance struct {
+	running sync.Mutex
+
+	mu   sync.Mutex
+	last *store.MaintenanceReport
+	at   time.Time
+}
+
+// runDBMaintenance maintains the database every dbMaintenanceInterval,
+// starting one interval after startup.
+func (s *Server) runDBMaintenance(ctx context.Context) {
+	t := time.NewTicker(dbMaintenanceInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		vacuum := false
+		if ratio, err := s.store.FreeRatio(ctx); err == nil {
+			vacuum = ratio >= dbVacuumFreeRatio
+		}
+		rep, err := s.maintainDB(ctx, vacuum)
+		switch {
+		case err != nil:
+			if ctx.Err() == nil {
+				slog.Warn("database maintenance failed", "error", err)
+			}
+		case !rep.IntegrityOK:
+			slog.Error("database integrity check failed", "problems", rep.Integrity)
+		default:
+			slog.Info("database maintenance", "vacuumed", rep.Vacuumed, "sizeBefore", rep.SizeBefore, "sizeAfter", rep.SizeAfter, "durationMs", rep.DurationMs)
+		}
+	}
+}
+
+// maintainDB runs one maintenance pass; concurrent calls wait for each
+// other.
+func (s *Server) maintainDB(ctx context.Context, vacuum bool) (store.MaintenanceReport, error) {
+	s.dbMaint.running.Lock()
+	defer s.dbMaint.running.Unlock()
+	rep, err := s.store.Maintain(ctx, vacuum)
+	if err != nil {
+		return rep, err
+	}
+	s.dbMaint.mu.Lock()
+	s.dbMaint.last, s.dbMaint.at = &rep, time.Now()
+	s.dbMaint.mu.Unlock()
+	return rep, nil
+}
+
+// handleDBMaintenance handles POST /api/admin/db/maintenance[?vacuum=false]:
+// checkpoint the WAL, vacuum and check integrity now.
+func (s *Server) handleDBMaintenance(w http.ResponseWriter, r *http.Request) {
+	if !s.dbMaint.running.TryLock() {
+		writeError(w, http.StatusConflict, "maintenance already running")
+		return
+	}
+	s.dbMaint.running.Unlock()
+	rep, err := s.maintainDB(r.Context(), r.URL.Query().Get("vacuum") != "false")
+	if err != nil {
+		slog.Error("database maintenance failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "database maintenance failed")
+		return
+	}
+	writeJSON(w, http.StatusOK, rep)
+}
+
+// handleGetDBMaintenance handles GET /api/admin/db/maintenance: the report of
+// the latest run since startup, if any.
+func (s *Server) handleGetDBMaintenance(w http.ResponseWriter, r *http.Request) {
+	s.dbMaint.mu.Lock()
+	defer s.dbMaint.mu.Unlock()
+	if s.dbMaint.last == nil {
+		writeJSON(w, http.StatusOK, map[string]any{"last": nil})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"last": s.dbMaint.last, "at": s.dbMaint.at})
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Deadlines for the widget endpoints that call upstream APIs. The fetchers
+// have their own client timeouts, but a handler may chain several calls
+// (geocoding, then the forecast; one quote per symbol), so the request as a
+// whole is bounded as well. When a deadline passes, the fetchers fall back to
+// their stale caches where they have one.
+const (
+	deadlineGeocode  = 5 * time.Second
+	deadlineWeather  = 8 * time.Second
+	deadlineMarkets  = 8 * time.Second
+	deadlineHolidays = 8 * time.Second
+	deadlineQuote    = 5 * time.Second
+)
+
+// withDeadline bounds the request context of the wrapped handler to d.
+func withDeadline(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// upstreamStatus returns 504 when err is the request deadline running out,
+// and status otherwise.
+func upstreamStatus(ctx context.Context, err error, status int) int {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+	return status
+}
@@ -1,15 +1,89 @@
 package server
 
-import "net/http"
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
 
+	"github.com/morezhou/hearth/internal/store"
+)
+
+// resetFull is the reset scope that clears everything, users included.
+const resetFull = "full"
+
+type adminResetRequest struct {
+	Scope    string `json:"scope"`
+	Password string `json:"password"`
+}
+
+// handleAdminReset handles POST /api/admin/reset. The scope picks what is
+// cleared: apps, widgets, caches, settings, or everything with "full" (the
+// default), which signs everyone out, recreates the admin like on first boot
+// and needs the admin's password.
 func (s *Server) handleAdminReset(w http.ResponseWriter, r *http.Request) {
-    if err := s.store.ResetAll(); err != nil {
-        writeError(w, http.StatusInternalServerError, "failed")
-        return
-    }
-    if err := s.ensureDefaultSystemTools(); err != nil {
-        writeError(w, http.StatusInternalServerError, "failed")
-        return
-    }
-    writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+	var req adminResetRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4<<10)).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if req.Scope == "" {
+		req.Scope = resetFull
+	}
+	switch req.Scope {
+	case resetFull:
+		userID, _ := userIDFromContext(r)
+		if req.Password == "" {
+			writeError(w, http.StatusBadRequest, "password required for a full reset")
+			return
+		}
+		if err := s.auth.VerifyPassword(userID, req.Password); err != nil {
+			writeError(w, http.StatusForbidden, err.Error())
+			return
+		}
+	case store.ResetApps, store.ResetWidgets, store.ResetCaches, store.ResetSettings:
+	default:
+		writeError(w, http.StatusBadRequest, "scope must be full, apps, widgets, caches or settings")
+		return
+	}
+
+	// Caches are rebuilt on demand, so only the other scopes can be undone.
+	var snapshot store.Export
+	if req.Scope != store.ResetCaches {
+		var err error
+		if snapshot, err = s.store.ExportAll(); err != nil {
+			slog.Error("failed to snapshot before reset", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed")
+			return
+		}
+	}
+	var err error
+	if req.Scope == resetFull {
+		err = s.store.ResetAll()
+	} else {
+		err = s.store.ResetScope(req.Scope)
+	}
+	if err != nil {
+		slog.Error("failed to reset", "error", err, "scope", req.Scope)
+		writeError(w, http.StatusInternalServerError, "failed")
+		return
+	}
+	if req.Scope != store.ResetCaches {
+		s.recordUndo(undoReset, "reset "+req.Scope, snapshot, true)
+	}
+	if req.Scope == resetFull {
+		if err := s.auth.EnsureDefaultAdmin(); err != nil {
+			slog.Error("failed to recreate admin user", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed")
+			return
+		}
+		if err := s.ensureDefaultSystemTools(); err != nil {
+			writeError(w, http.StatusInternalServerError, "failed")
+			return
+		}
+		s.loadHolidayDataset()
+	}
+	slog.Info("admin reset", "scope", req.Scope)
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "scope": req.Scope})
 }
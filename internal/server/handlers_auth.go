@@ -1,7 +1,10 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
+	"log/slog"
+	"net"
 	"net/http"
 	"time"
 )
@@ -13,6 +16,9 @@ type loginRequest struct {
 
 type meResponse struct {
 	Admin bool `json:"admin"`
+	// MustChangePassword is set for a user signed in with a generated
+	// password, who can do nothing but change it.
+	MustChangePassword bool `json:"mustChangePassword,omitempty"`
 }
 
 func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
@@ -32,6 +38,12 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The session, not the cookie, decides when the login ends; the cookie
+	// only has to outlive it.
+	cookieTTL := 365 * 24 * time.Hour
+	if maxAge := s.auth.SessionMaxAge(); maxAge > 0 {
+		cookieTTL = maxAge
+	}
 	http.SetCookie(w, &http.Cookie{
 		Name:     "hearth_session",
 		Value:    token,
@@ -39,11 +51,51 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 		HttpOnly: true,
 		SameSite: http.SameSiteLaxMode,
 		// Secure: true, // enable behind HTTPS
-		Expires: time.Now().Add(365 * 24 * time.Hour),
+		Expires: time.Now().Add(cookieTTL),
 	})
+	s.noteLoginIP(r, req.Username)
 	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
 }
 
+// noteLoginIP remembers the address of a successful login and emits
+// auth.login_new_ip the first time an address is seen.
+func (s *Server) noteLoginIP(r *http.Request, username string) {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	known, err := s.store.RecordLoginIP(ip)
+	if err != nil {
+		slog.Warn("failed to record login ip", "error", err)
+		return
+	}
+	if !known {
+		s.emitEvent(eventLoginNewIP, map[string]any{"ip": ip, "username": username, "userAgent": r.UserAgent()})
+	}
+}
+
+// sessionCleanupInterval is how often expired sessions are deleted.
+const sessionCleanupInterval = time.Hour
+
+// runSessionCleanup deletes expired sessions every sessionCleanupInterval;
+// Validate only removes the ones that are still presented.
+func (s *Server) runSessionCleanup(ctx context.Context) {
+	t := time.NewTicker(sessionCleanupInterval)
+	defer t.Stop()
+	for {
+		if n, err := s.auth.PurgeExpiredSessions(); err != nil {
+			slog.Warn("failed to purge expired sessions", "error", err)
+		} else if n > 0 {
+			slog.Info("purged expired sessions", "count", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
 func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
 	cookie, err := r.Cookie("hearth_session")
 	if err == nil && cookie.Value != "" {
@@ -63,7 +115,8 @@ func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleMe(w http.ResponseWriter, r *http.Request) {
-	writeJSON(w, http.StatusOK, meResponse{Admin: isAdmin(r)})
+	mustChange, _ := r.Context().Value(ctxPasswordChange).(bool)
+	writeJSON(w, http.StatusOK, meResponse{Admin: isAdmin(r), MustChangePassword: mustChange})
 }
 
 type changePasswordRequest struct {
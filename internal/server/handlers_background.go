@@ -4,41 +4,85 @@ import (
 	"bytes"
 	"context"
 	"embed"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
-	"os"
-	"path/filepath"
+	"path"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/go-chi/chi/v5"
+
 	"github.com/morezhou/hearth/internal/background"
+	"github.com/morezhou/hearth/internal/storage"
+	"github.com/morezhou/hearth/internal/store"
 )
 
 type backgroundInfo struct {
 	Provider string `json:"provider"`
 	ImageURL string `json:"imageUrl"`
+	background.Meta
 }
 
 //go:embed background-default.jpg
 var defaultBackgroundFS embed.FS
 
+// defaultBackgroundVariants caches the rendered variants of the bundled
+// background by name.
+var defaultBackgroundVariants sync.Map
+
 func serveDefaultBackground(w http.ResponseWriter, r *http.Request) bool {
 	b, err := defaultBackgroundFS.ReadFile("background-default.jpg")
 	if err != nil || len(b) == 0 {
 		return false
 	}
+	if v := r.URL.Query().Get("variant"); background.ValidVariant(v) {
+		if cached, ok := defaultBackgroundVariants.Load(v); ok {
+			b = cached.([]byte)
+		} else if out, err := background.RenderVariant(b, v); err == nil {
+			defaultBackgroundVariants.Store(v, out)
+			b = out
+		}
+	}
 	w.Header().Set("Content-Type", "image/jpeg")
 	http.ServeContent(w, r, "background-default.jpg", time.Time{}, bytes.NewReader(b))
 	return true
 }
 
+// serveBackgroundFile serves a stored background, or the variant named by
+// ?variant= when it can be rendered.
+func (s *Server) serveBackgroundFile(w http.ResponseWriter, r *http.Request, key string) (bool, error) {
+	if v := r.URL.Query().Get("variant"); v != "" {
+		vkey, err := s.bgSvc.Variant(r.Context(), key, v)
+		if err == nil {
+			return serveStored(w, r, s.bgStore, vkey)
+		}
+		if !errors.Is(err, background.ErrUnsupported) {
+			log.Printf("[bg] variant %s of %q: %v", v, key, err)
+		}
+	}
+	return serveStored(w, r, s.bgStore, key)
+}
+
 func (s *Server) handleGetBackground(w http.ResponseWriter, r *http.Request) {
 	provider := s.getStringSetting(kvBackgroundProvider, "default")
-	writeJSON(w, http.StatusOK, backgroundInfo{
+	info := backgroundInfo{
 		Provider: provider,
 		ImageURL: "/api/background/image",
-	})
+	}
+	if provider == string(background.ProviderBing) {
+		provider = string(background.ProviderBingDaily)
+	}
+	cacheKey := s.backgroundCacheKey(provider)
+	if entry, ok, err := s.store.GetBackgroundCache(cacheKey); err == nil && ok {
+		if h, ok, err := s.store.GetBackgroundHistory(cacheKey, entry.FilePath); err == nil && ok {
+			info.Meta = background.Meta{Title: h.Title, Credit: h.Credit, Link: h.Link}
+		}
+	}
+	writeJSON(w, http.StatusOK, info)
 }
 
 func (s *Server) handleGetBackgroundImage(w http.ResponseWriter, r *http.Request) {
@@ -46,6 +90,10 @@ func (s *Server) handleGetBackgroundImage(w http.ResponseWriter, r *http.Request
 	// Backgrounds are large and can be aggressively cached by browsers/proxies.
 	// Manual refresh should always take effect immediately.
 	w.Header().Set("Cache-Control", "no-store")
+	if v := r.URL.Query().Get("variant"); v != "" && !background.ValidVariant(v) {
+		writeError(w, http.StatusBadRequest, "variant must be blur or thumb")
+		return
+	}
 
 	provider := s.getStringSetting(kvBackgroundProvider, "default")
 	intervalStr := s.getStringSetting(kvBackgroundInterval, "0")
@@ -64,24 +112,24 @@ func (s *Server) handleGetBackgroundImage(w http.ResponseWriter, r *http.Request
 		writeError(w, http.StatusInternalServerError, "default background missing")
 		return
 	}
-
-	cacheKey := "bg:" + provider
-	if provider == string(background.ProviderUnsplash) {
-		cacheKey = cacheKey + ":" + s.getStringSetting(kvBackgroundUnsplashQuery, "")
+	if provider == string(background.ProviderCustom) {
+		s.serveCustomBackground(w, r, interval)
+		return
 	}
+
+	cacheKey := s.backgroundCacheKey(provider)
 	log.Printf("[bg] cacheKey=%q", cacheKey)
 	if entry, ok, err := s.store.GetBackgroundCache(cacheKey); err == nil && ok {
-		full := filepath.Join(s.cfg.DataDir, "cache", entry.FilePath)
-		if st, err := os.Stat(full); err == nil {
-			fresh := interval == 0 || time.Since(st.ModTime()) < interval
-			if provider == string(background.ProviderBingDaily) {
-				// Bing daily: always daily (ignore interval selection).
-				fresh = time.Since(st.ModTime()) < 24*time.Hour
-			}
-			log.Printf("[bg] cacheHit file=%q mod=%s age=%s fresh=%v", full, st.ModTime().Format(time.RFC3339), time.Since(st.ModTime()), fresh)
+		if _, err := s.bgStore.Stat(r.Context(), entry.FilePath); err == nil {
+			shownAt := time.Unix(entry.FetchedAt, 0)
+			fresh := backgroundFresh(provider, shownAt, interval, 0)
+			log.Printf("[bg] cacheHit file=%q shown=%s age=%s fresh=%v", entry.FilePath, shownAt.Format(time.RFC3339), time.Since(shownAt), fresh)
 			if fresh {
-				http.ServeFile(w, r, full)
-				return
+				if ok, err := s.serveBackgroundFile(w, r, entry.FilePath); ok {
+					return
+				} else if err != nil {
+					log.Printf("[bg] cache read error: %v; will refetch", err)
+				}
 			}
 			log.Printf("[bg] cacheStale; will refetch")
 		} else {
@@ -96,7 +144,7 @@ func (s *Server) handleGetBackgroundImage(w http.ResponseWriter, r *http.Request
 	}
 
 	log.Printf("[bg] resolving background url")
-	imgURL, err := s.resolveBackgroundURL(r.Context(), provider)
+	imgURL, meta, err := s.resolveBackgroundURL(r.Context(), provider)
 	if err != nil {
 		log.Printf("[bg] resolveBackgroundURL error: %v", err)
 		if serveDefaultBackground(w, r) {
@@ -116,54 +164,152 @@ func (s *Server) handleGetBackgroundImage(w http.ResponseWriter, r *http.Request
 		return
 	}
 	log.Printf("[bg] fetched ok file=%q mime=%q", res.FileName, res.MimeType)
-	_ = s.store.SetBackgroundCache(cacheKey, res.FileName)
+	if err := s.recordBackground(r.Context(), cacheKey, res.FileName, imgURL, meta); err != nil {
+		log.Printf("[bg] record background error: %v", err)
+	}
 
-	full := filepath.Join(s.cfg.DataDir, "cache", res.FileName)
-	http.ServeFile(w, r, full)
+	if ok, err := s.serveBackgroundFile(w, r, res.FileName); !ok {
+		log.Printf("[bg] serve cached file error: %v", err)
+		if serveDefaultBackground(w, r) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to read background image")
+	}
 }
 
-func (s *Server) prefetchBackground(cacheKey string, provider string) {
-	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
-	defer cancel()
+// backgroundFresh reports whether an image shown since shownAt is still
+// current for the interval setting, treating it as stale lead early.
+func backgroundFresh(provider string, shownAt time.Time, interval, lead time.Duration) bool {
+	if provider == string(background.ProviderBingDaily) {
+		// Bing daily: always daily (ignore interval selection).
+		interval = 24 * time.Hour
+	}
+	// Age counts from when the image became current, so stepping back
+	// through the history restarts the interval.
+	return interval == 0 || time.Since(shownAt) < interval-lead
+}
 
-	// If another request already cached it, avoid duplicate work.
-	if entry, ok, err := s.store.GetBackgroundCache(cacheKey); err == nil && ok {
-		full := filepath.Join(s.cfg.DataDir, "cache", entry.FilePath)
-		if _, err2 := os.Stat(full); err2 == nil {
+const (
+	// bgPrefetchTick is how often the scheduler checks whether the current
+	// background is due; images are replaced up to one tick early so
+	// visitors never wait for a fetch.
+	bgPrefetchTick = time.Minute
+	// bgPrefetchRetry is the pause after a failed prefetch.
+	bgPrefetchRetry = 15 * time.Minute
+)
+
+// runBackgroundPrefetch keeps the background of the configured provider
+// fetched ahead of its interval until ctx is done.
+func (s *Server) runBackgroundPrefetch(ctx context.Context) {
+	t := time.NewTicker(bgPrefetchTick)
+	defer t.Stop()
+	var retryAt time.Time
+	for {
+		if time.Now().After(retryAt) {
+			if _, err := s.prefetchDueBackground(ctx); err != nil {
+				log.Printf("[bg] prefetch error: %v", err)
+				retryAt = time.Now().Add(bgPrefetchRetry)
+			}
+		}
+		select {
+		case <-ctx.Done():
 			return
+		case <-t.C:
 		}
 	}
+}
+
+// prefetchDueBackground fetches a new background for the configured provider
+// when there is none yet or the current one is about to go stale. It reports
+// whether it fetched.
+func (s *Server) prefetchDueBackground(ctx context.Context) (bool, error) {
+	provider := s.getStringSetting(kvBackgroundProvider, "default")
+	if provider == string(background.ProviderBing) {
+		provider = string(background.ProviderBingDaily)
+	}
+	// Nothing remote to fetch; custom rotation is a pointer update at request time.
+	if provider == "default" || provider == string(background.ProviderCustom) {
+		return false, nil
+	}
+	interval, _ := time.ParseDuration(s.getStringSetting(kvBackgroundInterval, "0"))
+	cacheKey := s.backgroundCacheKey(provider)
+	if entry, ok, err := s.store.GetBackgroundCache(cacheKey); err != nil {
+		return false, err
+	} else if ok && backgroundFresh(provider, time.Unix(entry.FetchedAt, 0), interval, bgPrefetchTick) {
+		if _, err := s.bgStore.Stat(ctx, entry.FilePath); err == nil {
+			return false, nil
+		}
+	}
+	return true, s.prefetchBackground(ctx, provider, cacheKey)
+}
+
+// prefetchBackground fetches a new image for the provider and makes it current.
+func (s *Server) prefetchBackground(ctx context.Context, provider, cacheKey string) error {
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
+	defer cancel()
 
-	imgURL, err := s.resolveBackgroundURL(ctx, provider)
+	imgURL, meta, err := s.resolveBackgroundURL(ctx, provider)
 	if err != nil {
-		log.Printf("[bg] prefetch resolve error: %v", err)
-		return
+		return fmt.Errorf("resolve: %w", err)
 	}
 	res, err := s.bgSvc.FetchToFile(ctx, imgURL)
 	if err != nil {
-		log.Printf("[bg] prefetch fetch error: %v", err)
-		return
+		return fmt.Errorf("fetch: %w", err)
 	}
-	_ = s.store.SetBackgroundCache(cacheKey, res.FileName)
+	log.Printf("[bg] prefetched provider=%s file=%q", provider, res.FileName)
+	// The image is stored; record it even when shutting down, so it isn't
+	// left behind unreferenced.
+	return s.recordBackground(context.WithoutCancel(ctx), cacheKey, res.FileName, imgURL, meta)
 }
 
-func (s *Server) resolveBackgroundURL(ctx context.Context, provider string) (string, error) {
+func (s *Server) resolveBackgroundURL(ctx context.Context, provider string) (string, background.Meta, error) {
+	var u string
+	var err error
 	switch provider {
+	case string(background.ProviderAPOD):
+		return s.bgSvc.ResolveAPODURL(ctx)
+	case string(background.ProviderWikimedia):
+		return s.bgSvc.ResolveWikimediaURL(ctx)
+	case string(background.ProviderChromecast):
+		return s.bgSvc.ResolveChromecastURL(ctx)
 	case string(background.ProviderPicsum):
-		return s.bgSvc.ResolvePicsumURL()
+		u, err = s.bgSvc.ResolvePicsumURL()
 	case string(background.ProviderUnsplash):
 		q := s.getStringSetting(kvBackgroundUnsplashQuery, "")
-		return s.bgSvc.ResolveUnsplashURL(q)
+		u, err = s.bgSvc.ResolveUnsplashURL(q)
 	case string(background.ProviderBingRandom):
-		return s.bgSvc.ResolveBingRandomURL(ctx)
+		return s.bgSvc.ResolveBingRandomURL(ctx, s.bingMarket())
 	case string(background.ProviderBingDaily), string(background.ProviderBing), "":
 		fallthrough
 	default:
-		return s.bgSvc.ResolveBingDailyURL(ctx)
+		return s.bgSvc.ResolveBingDailyURL(ctx, s.bingMarket())
 	}
+	return u, background.Meta{}, err
 }
 
-func (s *Server) handleRefreshBackground(w http.ResponseWriter, r *http.Request) {
+// backgroundCacheKey is the background_cache key the provider's current
+// image (and its history) is stored under.
+func (s *Server) backgroundCacheKey(provider string) string {
+	cacheKey := "bg:" + provider
+	switch provider {
+	case string(background.ProviderUnsplash):
+		cacheKey = cacheKey + ":" + s.getStringSetting(kvBackgroundUnsplashQuery, "")
+	case string(background.ProviderBingDaily), string(background.ProviderBingRandom):
+		// Keys from before markets were configurable stand for en-US.
+		if mkt := s.bingMarket(); mkt != background.DefaultBingMarket {
+			cacheKey = cacheKey + ":" + mkt
+		}
+	}
+	return cacheKey
+}
+
+func (s *Server) bingMarket() string {
+	return background.NormalizeBingMarket(s.getStringSetting(kvBackgroundBingMarket, ""))
+}
+
+// requestedBackgroundProvider returns the ?provider= of an admin action,
+// defaulting to the saved one.
+func (s *Server) requestedBackgroundProvider(r *http.Request) string {
 	provider := strings.TrimSpace(r.URL.Query().Get("provider"))
 	if provider == "" {
 		provider = s.getStringSetting(kvBackgroundProvider, "default")
@@ -171,10 +317,45 @@ func (s *Server) handleRefreshBackground(w http.ResponseWriter, r *http.Request)
 	if provider == string(background.ProviderBing) {
 		provider = string(background.ProviderBingDaily)
 	}
-	cacheKey := "bg:" + provider
-	if provider == string(background.ProviderUnsplash) {
-		cacheKey = cacheKey + ":" + s.getStringSetting(kvBackgroundUnsplashQuery, "")
+	return provider
+}
+
+// recordBackground makes a fetched image the provider's current one and
+// adds it to the history, deleting images that fell out of it.
+func (s *Server) recordBackground(ctx context.Context, cacheKey, filePath, sourceURL string, meta background.Meta) error {
+	if err := s.store.SetBackgroundCache(cacheKey, filePath); err != nil {
+		return err
+	}
+	removed, err := s.store.AddBackgroundHistory(store.BackgroundHistoryEntry{
+		CacheKey:  cacheKey,
+		FilePath:  filePath,
+		SourceURL: sourceURL,
+		Title:     meta.Title,
+		Credit:    meta.Credit,
+		Link:      meta.Link,
+	}, s.backgroundHistoryLimit())
+	if err != nil {
+		return err
 	}
+	for _, p := range removed {
+		if err := s.bgSvc.DeleteImage(ctx, p); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("[bg] delete old background %q: %v", p, err)
+		}
+	}
+	s.emitEvent(eventBackgroundRefreshed, map[string]any{"cacheKey": cacheKey, "sourceUrl": sourceURL, "title": meta.Title})
+	return nil
+}
+
+func (s *Server) backgroundHistoryLimit() int {
+	if s.cfg.BackgroundHistory > 0 {
+		return s.cfg.BackgroundHistory
+	}
+	return defaultBackgroundHistory
+}
+
+func (s *Server) handleRefreshBackground(w http.ResponseWriter, r *http.Request) {
+	provider := s.requestedBackgroundProvider(r)
+	cacheKey := s.backgroundCacheKey(provider)
 	log.Printf("[bg] refresh requested provider=%s cacheKey=%q", provider, cacheKey)
 
 	// Default provider: nothing remote to fetch.
@@ -182,13 +363,28 @@ func (s *Server) handleRefreshBackground(w http.ResponseWriter, r *http.Request)
 		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
 		return
 	}
+	if provider == string(background.ProviderCustom) {
+		if _, err := s.rotateCustomBackground(r.Context(), cacheKey, 0, true); err != nil {
+			log.Printf("[bg] refresh custom error: %v", err)
+			writeError(w, http.StatusInternalServerError, "failed to rotate background")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+		return
+	}
+
+	s.fetchNewBackground(w, r, provider, cacheKey)
+}
 
+// fetchNewBackground fetches a new image for the provider and makes it
+// current, writing the response of a refresh.
+func (s *Server) fetchNewBackground(w http.ResponseWriter, r *http.Request, provider, cacheKey string) {
 	// Actually prefetch the next image here so the UI can surface errors.
 	// Keep this under the frontend timeout (15s).
 	ctx, cancel := context.WithTimeout(r.Context(), 14*time.Second)
 	defer cancel()
 
-	imgURL, err := s.resolveBackgroundURL(ctx, provider)
+	imgURL, meta, err := s.resolveBackgroundURL(ctx, provider)
 	if err != nil {
 		log.Printf("[bg] refresh resolve error: %v", err)
 		writeError(w, http.StatusBadGateway, fmt.Sprintf("failed to resolve background url: %v", err))
@@ -200,7 +396,9 @@ func (s *Server) handleRefreshBackground(w http.ResponseWriter, r *http.Request)
 		writeError(w, http.StatusBadGateway, fmt.Sprintf("failed to fetch background image: %v", err))
 		return
 	}
-	if err := s.store.SetBackgroundCache(cacheKey, res.FileName); err != nil {
+	// Past this point the request timing out must not leave the stored image
+	// unreferenced.
+	if err := s.recordBackground(context.WithoutCancel(ctx), cacheKey, res.FileName, imgURL, meta); err != nil {
 		log.Printf("[bg] refresh set cache error: %v", err)
 		writeError(w, http.StatusInternalServerError, "failed to update background cache")
 		return
@@ -208,3 +406,282 @@ func (s *Server) handleRefreshBackground(w http.ResponseWriter, r *http.Request)
 
 	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
 }
+
+type backgroundHistoryItem struct {
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	SourceURL string    `json:"sourceUrl,omitempty"`
+	FetchedAt time.Time `json:"fetchedAt"`
+	Current   bool      `json:"current"`
+	background.Meta
+}
+
+// handleBackgroundHistory handles GET /api/background/history: the images
+// kept for the provider, newest first.
+func (s *Server) handleBackgroundHistory(w http.ResponseWriter, r *http.Request) {
+	provider := s.requestedBackgroundProvider(r)
+	cacheKey := s.backgroundCacheKey(provider)
+	list, err := s.store.ListBackgroundHistory(cacheKey)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load background history")
+		return
+	}
+	var current string
+	if entry, ok, err := s.store.GetBackgroundCache(cacheKey); err == nil && ok {
+		current = entry.FilePath
+	}
+	items := make([]backgroundHistoryItem, 0, len(list))
+	for _, e := range list {
+		name := path.Base(e.FilePath)
+		items = append(items, backgroundHistoryItem{
+			Name:      name,
+			URL:       "/api/background/history/" + name,
+			SourceURL: e.SourceURL,
+			FetchedAt: time.Unix(e.FetchedAt, 0).UTC(),
+			Current:   e.FilePath == current,
+			Meta:      background.Meta{Title: e.Title, Credit: e.Credit, Link: e.Link},
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"provider": provider, "items": items})
+}
+
+// handleGetBackgroundHistoryImage serves one image of the history.
+func (s *Server) handleGetBackgroundHistoryImage(w http.ResponseWriter, r *http.Request) {
+	key, ok := background.HistoryKey(chi.URLParam(r, "name"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable") // content-addressed
+	if ok, err := serveStored(w, r, s.bgStore, key); !ok {
+		if err != nil {
+			log.Printf("[bg] history read error: %v", err)
+		}
+		http.NotFound(w, r)
+	}
+}
+
+// handlePreviousBackground handles POST /api/background/previous: go back to
+// the image shown before the current one.
+func (s *Server) handlePreviousBackground(w http.ResponseWriter, r *http.Request) {
+	s.stepBackground(w, r, -1)
+}
+
+// handleNextBackground handles POST /api/background/next: go forward in the
+// history, fetching a new image once at its newest entry.
+func (s *Server) handleNextBackground(w http.ResponseWriter, r *http.Request) {
+	s.stepBackground(w, r, 1)
+}
+
+func (s *Server) stepBackground(w http.ResponseWriter, r *http.Request, step int) {
+	provider := s.requestedBackgroundProvider(r)
+	cacheKey := s.backgroundCacheKey(provider)
+	log.Printf("[bg] step=%d provider=%s cacheKey=%q", step, provider, cacheKey)
+
+	if provider == "default" {
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+		return
+	}
+	if provider == string(background.ProviderCustom) {
+		var err error
+		if step > 0 {
+			_, err = s.rotateCustomBackground(r.Context(), cacheKey, 0, true)
+		} else {
+			err = s.rewindCustomBackground(r.Context(), cacheKey)
+		}
+		if err != nil {
+			log.Printf("[bg] step custom error: %v", err)
+			writeError(w, http.StatusInternalServerError, "failed to rotate background")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+		return
+	}
+
+	list, err := s.store.ListBackgroundHistory(cacheKey)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load background history")
+		return
+	}
+	idx := -1
+	if entry, ok, err := s.store.GetBackgroundCache(cacheKey); err == nil && ok {
+		for i, e := range list {
+			if e.FilePath == entry.FilePath {
+				idx = i
+				break
+			}
+		}
+	}
+	// The history is newest first: going back means a higher index.
+	if step > 0 && idx <= 0 {
+		s.fetchNewBackground(w, r, provider, cacheKey)
+		return
+	}
+	target := idx - step
+	if target >= len(list) {
+		writeError(w, http.StatusConflict, "no earlier background")
+		return
+	}
+	if err := s.store.SetBackgroundCache(cacheKey, list[target].FilePath); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to update background cache")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+// maxBackgroundUploadRequest bounds a multipart request carrying several images.
+const maxBackgroundUploadRequest = 4*background.MaxUploadSize + 1<<20
+
+type backgroundUploadResponse struct {
+	background.Upload
+	URL string `json:"url"`
+}
+
+func backgroundUploadInfo(u background.Upload) backgroundUploadResponse {
+	return backgroundUploadResponse{Upload: u, URL: "/api/background/uploads/" + u.Name}
+}
+
+// handleUploadBackground stores the images of one or more multipart "file"
+// fields for the custom provider.
+func (s *Server) handleUploadBackground(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBackgroundUploadRequest)
+	if err := r.ParseMultipartForm(8 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, "request too large")
+			return
+		}
+		writeError(w, http.StatusBadRequest, "multipart form required")
+		return
+	}
+	files := r.MultipartForm.File["file"]
+	if len(files) == 0 {
+		writeError(w, http.StatusBadRequest, "file required")
+		return
+	}
+	out := make([]backgroundUploadResponse, 0, len(files))
+	for _, fh := range files {
+		f, err := fh.Open()
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		data, err := io.ReadAll(io.LimitReader(f, background.MaxUploadSize+1))
+		f.Close()
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		u, err := s.bgSvc.SaveUpload(r.Context(), data)
+		if err != nil {
+			if errors.Is(err, background.ErrInvalidUpload) {
+				writeError(w, http.StatusBadRequest, fh.Filename+": "+err.Error())
+				return
+			}
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		out = append(out, backgroundUploadInfo(u))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"uploads": out})
+}
+
+// handleListBackgroundUploads handles GET /api/background/uploads.
+func (s *Server) handleListBackgroundUploads(w http.ResponseWriter, r *http.Request) {
+	list, err := s.bgSvc.ListUploads(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list uploads")
+		return
+	}
+	out := make([]backgroundUploadResponse, 0, len(list))
+	for _, u := range list {
+		out = append(out, backgroundUploadInfo(u))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"uploads": out})
+}
+
+// handleGetBackgroundUpload serves one uploaded image.
+func (s *Server) handleGetBackgroundUpload(w http.ResponseWriter, r *http.Request) {
+	key, ok := background.UploadKey(chi.URLParam(r, "name"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable") // content-addressed
+	if ok, err := serveStored(w, r, s.bgStore, key); !ok {
+		if err != nil {
+			log.Printf("[bg] upload read error: %v", err)
+		}
+		http.NotFound(w, r)
+	}
+}
+
+// handleDeleteBackgroundUpload handles DELETE /api/background/uploads/{name}.
+func (s *Server) handleDeleteBackgroundUpload(w http.ResponseWriter, r *http.Request) {
+	if err := s.bgSvc.DeleteUpload(r.Context(), chi.URLParam(r, "name")); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "upload not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to delete upload")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+// serveCustomBackground serves the current upload of the custom provider,
+// moving on to the next one when interval has passed (or next is set).
+func (s *Server) serveCustomBackground(w http.ResponseWriter, r *http.Request, interval time.Duration) {
+	cacheKey := "bg:" + string(background.ProviderCustom)
+	key, err := s.rotateCustomBackground(r.Context(), cacheKey, interval, false)
+	if err != nil {
+		log.Printf("[bg] custom rotation error: %v", err)
+	}
+	if key != "" {
+		if ok, _ := s.serveBackgroundFile(w, r, key); ok {
+			return
+		}
+	}
+	if serveDefaultBackground(w, r) {
+		return
+	}
+	writeError(w, http.StatusNotFound, "no background uploaded")
+}
+
+// rotateCustomBackground returns the upload to show, advancing to the next
+// one when forced or when the current one has been shown for interval.
+func (s *Server) rotateCustomBackground(ctx context.Context, cacheKey string, interval time.Duration, force bool) (string, error) {
+	var current string
+	if entry, ok, err := s.store.GetBackgroundCache(cacheKey); err == nil && ok {
+		current = entry.FilePath
+		fresh := interval == 0 || time.Since(time.Unix(entry.FetchedAt, 0)) < interval
+		if !force && fresh {
+			if _, err := s.bgStore.Stat(ctx, current); err == nil {
+				return current, nil
+			}
+		}
+	}
+	next, err := s.bgSvc.NextUpload(ctx, current)
+	if err != nil || next == "" {
+		return "", err
+	}
+	if next != current {
+		if err := s.store.SetBackgroundCache(cacheKey, next); err != nil {
+			return next, err
+		}
+	}
+	return next, nil
+}
+
+// rewindCustomBackground makes the upload before the current one current.
+func (s *Server) rewindCustomBackground(ctx context.Context, cacheKey string) error {
+	var current string
+	if entry, ok, err := s.store.GetBackgroundCache(cacheKey); err == nil && ok {
+		current = entry.FilePath
+	}
+	prev, err := s.bgSvc.PreviousUpload(ctx, current)
+	if err != nil || prev == "" {
+		return err
+	}
+	return s.store.SetBackgroundCache(cacheKey, prev)
+}
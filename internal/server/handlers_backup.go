@@ -1,13 +1,76 @@
 package server
 
 import (
+	"cmp"
+	"encoding/json"
+	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/morezhou/hearth/internal/store"
+)
+
+// Scopes of a partial export, given as ?scope=groups,apps.
+const (
+	exportSettings = "settings" // shared settings and customization, without credentials and host settings
+	exportGroups   = "groups"   // app groups
+	exportApps     = "apps"     // apps, widgets aside
+	exportWidgets  = "widgets"  // widgets and the system group, with holdings, events and quotes
+	exportUsers    = "users"    // users' own settings by username; never credentials
 )
 
+var exportScopes = []string{exportSettings, exportGroups, exportApps, exportWidgets, exportUsers}
+
+// hostSettings describe the machine Hearth runs on, so partial exports leave
+// them out along with sensitiveSettings.
+var hostSettings = []string{kvNetworkInternalCIDRs, kvMetricsInterfaces, kvMetricsContainers, kvMetricsExcludeMounts, kvMetricsDiskPaths}
+
+// parseExportScopes reads the scope parameters, each a comma separated list,
+// in the order of exportScopes. None means a full backup.
+func parseExportScopes(values []string) ([]string, error) {
+	var scopes []string
+	for _, v := range values {
+		for _, sc := range strings.Split(v, ",") {
+			sc = strings.TrimSpace(sc)
+			if sc == "" {
+				continue
+			}
+			if !slices.Contains(exportScopes, sc) {
+				return nil, fmt.Errorf("scope must be one of %s", strings.Join(exportScopes, ", "))
+			}
+			if !slices.Contains(scopes, sc) {
+				scopes = append(scopes, sc)
+			}
+		}
+	}
+	slices.SortFunc(scopes, func(a, b string) int {
+		return cmp.Compare(slices.Index(exportScopes, a), slices.Index(exportScopes, b))
+	})
+	return scopes, nil
+}
+
+// handleExport handles GET /api/export: a full backup, or only the parts
+// named by ?scope= for copying them to another instance.
 func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
-	b, err := s.store.ExportJSON()
+	scopes, err := parseExportScopes(r.URL.Query()["scope"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var b []byte
+	if len(scopes) == 0 {
+		b, err = s.store.ExportJSON()
+	} else {
+		var p store.Export
+		if p, err = s.scopedExport(scopes); err == nil {
+			b, err = json.MarshalIndent(p, "", "  ")
+		}
+	}
 	if err != nil {
+		slog.Error("failed to export", "error", err, "scopes", scopes)
 		writeError(w, http.StatusInternalServerError, "failed")
 		return
 	}
@@ -16,15 +79,176 @@ func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
 	_, _ = w.Write(b)
 }
 
+// scopedExport is the part of the configuration in scopes.
+func (s *Server) scopedExport(scopes []string) (store.Export, error) {
+	full, err := s.store.ExportAll()
+	if err != nil {
+		return store.Export{}, err
+	}
+	p := store.Export{
+		Version:  full.Version,
+		Exported: full.Exported,
+		Settings: map[string]string{},
+		Groups:   []store.Group{},
+		Apps:     []store.AppItem{},
+		Scopes:   scopes,
+	}
+	if slices.Contains(scopes, exportSettings) {
+		for k, v := range full.Settings {
+			shared := strings.HasPrefix(k, "settings.") || strings.HasPrefix(k, "customization.")
+			if shared && !slices.Contains(sensitiveSettings, k) && !slices.Contains(hostSettings, k) {
+				p.Settings[k] = v
+			}
+		}
+	}
+	for _, g := range full.Groups {
+		scope := exportGroups
+		if g.Kind == GroupKindSystem {
+			scope = exportWidgets
+		}
+		if slices.Contains(scopes, scope) {
+			p.Groups = append(p.Groups, g)
+		}
+	}
+	for _, a := range full.Apps {
+		scope := exportApps
+		if strings.HasPrefix(a.URL, "widget:") {
+			scope = exportWidgets
+		}
+		if slices.Contains(scopes, scope) {
+			p.Apps = append(p.Apps, a)
+		}
+	}
+	if slices.Contains(scopes, exportWidgets) {
+		p.Holdings, p.Events, p.Quotes = full.Holdings, full.Events, full.Quotes
+	}
+	if slices.Contains(scopes, exportUsers) {
+		if p.Users, err = s.exportUserSettings(); err != nil {
+			return store.Export{}, err
+		}
+	}
+	return p, nil
+}
+
+// exportUserSettings lists users' own settings by username, sorted.
+func (s *Server) exportUserSettings() ([]store.UserExport, error) {
+	all, err := s.store.ListAllUserKV()
+	if err != nil {
+		return nil, err
+	}
+	out := []store.UserExport{}
+	for id, kv := range all {
+		name, err := s.auth.Username(id)
+		if err != nil {
+			continue
+		}
+		u := store.UserExport{Username: name, Settings: map[string]string{}}
+		for k, v := range kv {
+			if slices.Contains(userSettingKeys, k) {
+				u.Settings[k] = v
+			}
+		}
+		out = append(out, u)
+	}
+	slices.SortFunc(out, func(a, b store.UserExport) int { return cmp.Compare(a.Username, b.Username) })
+	return out, nil
+}
+
+// handleImport handles POST /api/import, merging a backup or a partial
+// export into the configuration. The system group of another instance is
+// merged into this one's, apps of groups that are neither in the import nor
+// here end up without a group, and users' settings go to the users of the
+// same name; the others are skipped and listed.
 func (s *Server) handleImport(w http.ResponseWriter, r *http.Request) {
 	b, err := io.ReadAll(io.LimitReader(r.Body, 5<<20))
 	if err != nil {
 		writeError(w, http.StatusBadRequest, "invalid")
 		return
 	}
-	if err := s.store.ImportJSON(b); err != nil {
+	var payload store.Export
+	if err := json.Unmarshal(b, &payload); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	snapshot, err := s.store.ExportAll()
+	if err != nil {
+		slog.Error("failed to snapshot before import", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed")
+		return
+	}
+	reconcileGroups(&payload, snapshot.Groups)
+	if err := s.store.ImportAll(payload); err != nil {
 		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+	s.recordUndo(undoImport, "import", snapshot, true)
+	skipped, err := s.importUserSettings(payload.Users)
+	if err != nil {
+		slog.Error("failed to import user settings", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to import user settings")
+		return
+	}
+	s.loadHolidayDataset()
+	s.emitEvent(eventImportCompleted, map[string]any{"bytes": len(b)})
+	resp := map[string]any{"ok": true}
+	if len(skipped) > 0 {
+		resp["skippedUsers"] = skipped
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// reconcileGroups moves the apps of payload from its system group to the
+// existing one, and ungroups those whose group is neither in payload nor in
+// existing.
+func reconcileGroups(payload *store.Export, existing []store.Group) {
+	known := map[string]bool{}
+	system := ""
+	for _, g := range existing {
+		known[g.ID] = true
+		if g.Kind == GroupKindSystem && system == "" {
+			system = g.ID
+		}
+	}
+	moved := map[string]bool{}
+	groups := payload.Groups[:0]
+	for _, g := range payload.Groups {
+		if g.Kind == GroupKindSystem && system != "" && g.ID != system {
+			moved[g.ID] = true
+			continue
+		}
+		known[g.ID] = true
+		groups = append(groups, g)
+	}
+	payload.Groups = groups
+	for i, a := range payload.Apps {
+		switch {
+		case a.GroupID == nil:
+		case moved[*a.GroupID]:
+			payload.Apps[i].GroupID = &system
+		case !known[*a.GroupID]:
+			payload.Apps[i].GroupID = nil
+		}
+	}
+}
+
+// importUserSettings saves the per-user settings of users, returning the
+// usernames unknown here.
+func (s *Server) importUserSettings(users []store.UserExport) ([]string, error) {
+	var skipped []string
+	for _, u := range users {
+		id, err := s.auth.UserID(u.Username)
+		if err != nil {
+			skipped = append(skipped, u.Username)
+			continue
+		}
+		for k, v := range u.Settings {
+			if !slices.Contains(userSettingKeys, k) {
+				continue
+			}
+			if err := s.store.SetUserKV(id, k, v); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return skipped, nil
 }
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+const (
+	kvCustomCSS             = "customization.css"
+	kvCustomJS              = "customization.js"
+	kvCustomAllowRemoteURLs = "customization.allowRemoteUrls" // "true"|"false"
+
+	maxCustomCSS = 64 << 10
+	maxCustomJS  = 64 << 10
+)
+
+// Customization is the admin's CSS and JS added to every page.
+type Customization struct {
+	CSS string `json:"css"`
+	// JS only runs when the server is started with HEARTH_CUSTOM_JS=true.
+	JS        string `json:"js"`
+	JSEnabled bool   `json:"jsEnabled"`
+	// AllowRemoteURLs keeps @import and url() pointing at other hosts in the
+	// CSS. They are stripped by default so pages don't load third-party
+	// resources behind the visitors' backs.
+	AllowRemoteURLs *bool `json:"allowRemoteUrls,omitempty"`
+}
+
+var (
+	cssImportRe    = regexp.MustCompile(`(?i)@import\s[^;]*;?`)
+	cssRemoteURLRe = regexp.MustCompile(`(?i)url\(\s*['"]?\s*(?:https?:)?//[^)]*\)`)
+	// Legacy ways of running script from CSS, stripped regardless.
+	cssScriptRe = regexp.MustCompile(`(?i)expression\s*\(|javascript:|-moz-binding|behavior\s*:`)
+)
+
+// sanitizeCustomCSS strips script from css, and remote imports and URLs
+// unless allowRemote.
+func sanitizeCustomCSS(css string, allowRemote bool) string {
+	css = cssScriptRe.ReplaceAllString(css, "/* removed */")
+	if !allowRemote {
+		css = cssImportRe.ReplaceAllString(css, "/* removed */")
+		css = cssRemoteURLRe.ReplaceAllString(css, "none")
+	}
+	return strings.TrimSpace(css)
+}
+
+// customCSSAllowsRemote reports whether the custom stylesheet may load
+// remote imports and URLs.
+func (s *Server) customCSSAllowsRemote() bool {
+	return s.getStringSetting(kvCustomAllowRemoteURLs, "false") == "true"
+}
+
+// handleGetCustomization handles GET /api/customization. Stored JS is left
+// out while custom JS is disabled.
+func (s *Server) handleGetCustomization(w http.ResponseWriter, r *http.Request) {
+	allowRemote := s.customCSSAllowsRemote()
+	c := Customization{
+		CSS:             s.getStringSetting(kvCustomCSS, ""),
+		JSEnabled:       s.cfg.CustomJS,
+		AllowRemoteURLs: &allowRemote,
+	}
+	if c.JSEnabled {
+		c.JS = s.getStringSetting(kvCustomJS, "")
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+// handlePutCustomization handles PUT /api/customization, replacing the CSS
+// and JS. The sanitized CSS is returned so the admin sees what was removed.
+func (s *Server) handlePutCustomization(w http.ResponseWriter, r *http.Request) {
+	var req Customization
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxCustomCSS+maxCustomJS+4<<10)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	req.JS = strings.TrimSpace(req.JS)
+	switch {
+	case len(req.CSS) > maxCustomCSS:
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("css must be at most %d bytes", maxCustomCSS))
+		return
+	case len(req.JS) > maxCustomJS:
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("js must be at most %d bytes", maxCustomJS))
+		return
+	case req.JS != "" && !s.cfg.CustomJS:
+		writeError(w, http.StatusBadRequest, "custom js is disabled; start the server with HEARTH_CUSTOM_JS=true")
+		return
+	// The snippets end up in <style> and <script> elements; don't let them
+	// close those.
+	case strings.Contains(strings.ToLower(req.CSS), "</style"):
+		writeError(w, http.StatusBadRequest, "css must not contain </style>")
+		return
+	case strings.Contains(strings.ToLower(req.JS), "</script"):
+		writeError(w, http.StatusBadRequest, "js must not contain </script>")
+		return
+	}
+	if req.AllowRemoteURLs == nil {
+		allow := s.customCSSAllowsRemote()
+		req.AllowRemoteURLs = &allow
+	}
+	req.CSS = sanitizeCustomCSS(req.CSS, *req.AllowRemoteURLs)
+	req.JSEnabled = s.cfg.CustomJS
+
+	kv := s.recordSettings(r)
+	_ = kv.SetKV(kvCustomCSS, req.CSS)
+	_ = kv.SetKV(kvCustomAllowRemoteURLs, boolString(*req.AllowRemoteURLs))
+	if s.cfg.CustomJS {
+		_ = kv.SetKV(kvCustomJS, req.JS)
+	}
+	writeJSON(w, http.StatusOK, req)
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Embed is a page the iframe widget may show. Widgets only reference an
+// embed by ID, so the URL and sandbox always come from the admin's settings
+// and never from an item's description.
+type Embed struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// Sandbox lists the iframe sandbox tokens granted to the page.
+	Sandbox []string `json:"sandbox"`
+	// Height is the frame height in CSS pixels.
+	Height int `json:"height"`
+}
+
+// EmbedSettings registers the pages iframe widgets may show.
+type EmbedSettings struct {
+	// Allowlist holds host globs ("grafana.lan", "*.home.arpa"); frames on
+	// other hosts are rejected, and dropped when the list changes.
+	Allowlist []string `json:"allowlist"`
+	Frames    []Embed  `json:"frames"`
+}
+
+const (
+	defaultEmbedHeight = 300
+	maxEmbeds          = 50
+)
+
+var embedIDRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// embedSandboxTokens are the sandbox tokens an embed may be granted.
+// Top-level navigation is left out so a frame can't take over the dashboard.
+var embedSandboxTokens = []string{
+	"allow-downloads",
+	"allow-forms",
+	"allow-modals",
+	"allow-popups",
+	"allow-popups-to-escape-sandbox",
+	"allow-presentation",
+	"allow-same-origin",
+	"allow-scripts",
+}
+
+var defaultEmbedSandbox = []string{"allow-forms", "allow-popups", "allow-scripts"}
+
+// embedHostAllowed reports whether host matches one of the allowlist globs.
+func embedHostAllowed(host string, allowlist []string) bool {
+	host = strings.ToLower(host)
+	for _, p := range allowlist {
+		if ok, _ := path.Match(strings.ToLower(p), host); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanEmbed validates e against the allowlist and fills in defaults.
+func cleanEmbed(e Embed, allowlist []string) (Embed, error) {
+	e.ID = strings.ToLower(strings.TrimSpace(e.ID))
+	if !embedIDRe.MatchString(e.ID) {
+		return Embed{}, fmt.Errorf("embed id %q must be 1-64 lowercase letters, digits, - or _", e.ID)
+	}
+	e.Name = strings.TrimSpace(e.Name)
+	u, err := url.Parse(strings.TrimSpace(e.URL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return Embed{}, fmt.Errorf("embed %s: url must be http(s)", e.ID)
+	}
+	if u.User != nil {
+		// Embeds are served to visitors; credentials would leak.
+		return Embed{}, fmt.Errorf("embed %s: url must not contain credentials", e.ID)
+	}
+	if !embedHostAllowed(u.Hostname(), allowlist) {
+		return Embed{}, fmt.Errorf("embed %s: host %s is not in the allowlist", e.ID, u.Hostname())
+	}
+	e.URL = u.String()
+	if e.Sandbox == nil {
+		e.Sandbox = defaultEmbedSandbox
+	} else {
+		tokens := make([]string, 0, len(e.Sandbox))
+		for _, t := range e.Sandbox {
+			t = strings.ToLower(strings.TrimSpace(t))
+			if t == "" || slices.Contains(tokens, t) {
+				continue
+			}
+			if !slices.Contains(embedSandboxTokens, t) {
+				return Embed{}, fmt.Errorf("embed %s: sandbox token %q is not allowed", e.ID, t)
+			}
+			tokens = append(tokens, t)
+		}
+		e.Sandbox = tokens
+	}
+	switch {
+	case e.Height <= 0:
+		e.Height = defaultEmbedHeight
+	case e.Height < 100:
+		e.Height = 100
+	case e.Height > 1200:
+		e.Height = 1200
+	}
+	return e, nil
+}
+
+// cleanEmbeds validates a list of frames, rejecting duplicate IDs.
+func cleanEmbeds(frames []Embed, allowlist []string) ([]Embed, error) {
+	if len(frames) > maxEmbeds {
+		return nil, fmt.Errorf("at most %d embeds", maxEmbeds)
+	}
+	out := make([]Embed, 0, len(frames))
+	for _, f := range frames {
+		e, err := cleanEmbed(f, allowlist)
+		if err != nil {
+			return nil, err
+		}
+		if slices.ContainsFunc(out, func(o Embed) bool { return o.ID == e.ID }) {
+			return nil, fmt.Errorf("duplicate embed id %q", e.ID)
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+func (s *Server) embedAllowlist() []string {
+	var list []string
+	if raw := s.getStringSetting(kvEmbedsAllowlist, ""); raw != "" {
+		_ = json.Unmarshal([]byte(raw), &list)
+	}
+	return list
+}
+
+// embedSettings loads the registered embeds. Frames no longer matching the
+// allowlist are left out.
+func (s *Server) embedSettings() *EmbedSettings {
+	st := &EmbedSettings{Allowlist: s.embedAllowlist(), Frames: []Embed{}}
+	if st.Allowlist == nil {
+		st.Allowlist = []string{}
+	}
+	var frames []Embed
+	if raw := s.getStringSetting(kvEmbedsFrames, ""); raw != "" {
+		_ = json.Unmarshal([]byte(raw), &frames)
+	}
+	for _, f := range frames {
+		if e, err := cleanEmbed(f, st.Allowlist); err == nil {
+			st.Frames = append(st.Frames, e)
+		}
+	}
+	return st
+}
+
+// handleGetEmbeds handles GET /api/widgets/embeds, read by iframe widgets.
+func (s *Server) handleGetEmbeds(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"frames": s.embedSettings().Frames})
+}
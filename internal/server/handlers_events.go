@@ -0,0 +1,78 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/morezhou/hearth/internal/store"
+	"github.com/morezhou/hearth/internal/widgets"
+)
+
+// maxCustomEvents bounds the personal dates merged into the holidays widget.
+const maxCustomEvents = 100
+
+func (s *Server) handleListCustomEvents(w http.ResponseWriter, r *http.Request) {
+	list, err := s.store.ListCustomEvents()
+	if err != nil {
+		slog.Error("failed to list custom events", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to list custom events")
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// handlePutCustomEvents replaces all personal dates with the posted list.
+func (s *Server) handlePutCustomEvents(w http.ResponseWriter, r *http.Request) {
+	var req []store.CustomEvent
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if len(req) > maxCustomEvents {
+		writeError(w, http.StatusBadRequest, "too many events")
+		return
+	}
+	list := make([]store.CustomEvent, 0, len(req))
+	for _, e := range req {
+		e.Name = strings.TrimSpace(e.Name)
+		if e.Name == "" || utf8.RuneCountInString(e.Name) > 100 {
+			writeError(w, http.StatusBadRequest, "name required (max 100 characters)")
+			return
+		}
+		if !widgets.ValidEventDate(e.Month, e.Day, e.Lunar) {
+			writeError(w, http.StatusBadRequest, "invalid month/day for "+e.Name)
+			return
+		}
+		if e.Year < 0 || e.Year > time.Now().Year()+1 {
+			writeError(w, http.StatusBadRequest, "invalid year for "+e.Name)
+			return
+		}
+		e.Kind = widgets.NormalizeEventKind(e.Kind)
+		list = append(list, e)
+	}
+	if err := s.store.ReplaceCustomEvents(list); err != nil {
+		slog.Error("failed to save custom events", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to save custom events")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+// customEvents loads the personal dates for the holidays widget. Errors are
+// logged and treated as "no events" so public holidays still render.
+func (s *Server) customEvents() []widgets.CustomEvent {
+	list, err := s.store.ListCustomEvents()
+	if err != nil {
+		slog.Error("failed to list custom events", "error", err)
+		return nil
+	}
+	out := make([]widgets.CustomEvent, 0, len(list))
+	for _, e := range list {
+		out = append(out, widgets.CustomEvent{Name: e.Name, Kind: e.Kind, Month: e.Month, Day: e.Day, Year: e.Year, Lunar: e.Lunar})
+	}
+	return out
+}
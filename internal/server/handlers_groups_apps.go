@@ -4,9 +4,13 @@ import (
 	"encoding/json"
 	"log/slog"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/go-chi/chi/v5"
+
+	"github.com/morezhou/hearth/internal/store"
 )
 
 // Group kind constants.
@@ -25,12 +29,38 @@ type reorderRequest struct {
 }
 
 type createAppRequest struct {
-	GroupID     *string `json:"groupId"`
-	Name        string  `json:"name"`
-	Description *string `json:"description"`
-	URL         string  `json:"url"`
-	IconPath    *string `json:"iconPath"`
-	IconSource  *string `json:"iconSource"`
+	GroupID     *string           `json:"groupId"`
+	Name        string            `json:"name"`
+	Description *string           `json:"description"`
+	URL         string            `json:"url"`
+	InternalURL *string           `json:"internalUrl"`
+	OpenMode    string            `json:"openMode"`
+	WakeMAC     *string           `json:"wakeMac"`
+	Shortcut    *string           `json:"shortcut"`
+	Actions     []store.AppAction `json:"actions"`
+	IconPath    *string           `json:"iconPath"`
+	IconSource  *string           `json:"iconSource"`
+	// IconColor is the background of emoji and text tiles; it is baked into
+	// the rendered tile rather than stored.
+	IconColor string `json:"iconColor,omitempty"`
+}
+
+// item is the stored form of the request for the app id.
+func (req createAppRequest) item(id string) store.AppItem {
+	return store.AppItem{
+		ID:          id,
+		GroupID:     req.GroupID,
+		Name:        req.Name,
+		Description: req.Description,
+		URL:         req.URL,
+		InternalURL: req.InternalURL,
+		OpenMode:    req.OpenMode,
+		WakeMAC:     req.WakeMAC,
+		Shortcut:    req.Shortcut,
+		Actions:     req.Actions,
+		IconPath:    req.IconPath,
+		IconSource:  req.IconSource,
+	}
 }
 
 func (s *Server) handleListGroups(w http.ResponseWriter, r *http.Request) {
@@ -95,19 +125,84 @@ func (s *Server) handleUpdateGroup(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
 }
 
+// handleDeleteGroup handles DELETE /api/groups/{id}. ?apps= says what
+// happens to the group's apps: delete (the default) removes them, ungroup
+// keeps them without a group, move puts them at the end of group ?target=.
+// The response counts the apps affected.
 func (s *Server) handleDeleteGroup(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
-	// Delete all apps in the group first
-	if err := s.store.DeleteAppsByGroupID(id); err != nil {
-		slog.Warn("failed to delete apps in group", "groupId", id, "error", err)
+	q := r.URL.Query()
+	mode := q.Get("apps")
+	if mode == "" {
+		mode = "delete"
+	}
+	kind, ok, err := s.store.GroupKindByID(id)
+	if err != nil {
+		slog.Error("failed to get group kind", "error", err, "id", id)
+		writeError(w, http.StatusInternalServerError, "failed to delete group")
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "group not found")
+		return
+	}
+	snapshot, err := s.groupSnapshot(id)
+	if err != nil {
+		slog.Error("failed to snapshot group", "error", err, "id", id)
+		writeError(w, http.StatusInternalServerError, "failed to delete group")
+		return
+	}
+
+	var affected int
+	switch mode {
+	case "delete":
+		affected, err = s.store.DeleteAppsByGroupID(id)
+	case "ungroup":
+		if kind == GroupKindSystem {
+			writeError(w, http.StatusBadRequest, "widgets must be in system group")
+			return
+		}
+		affected, err = s.store.MoveGroupApps(id, nil)
+	case "move":
+		target := q.Get("target")
+		if target == "" || target == id {
+			writeError(w, http.StatusBadRequest, "target group required")
+			return
+		}
+		targetKind, ok, kerr := s.store.GroupKindByID(target)
+		if kerr != nil {
+			slog.Error("failed to get group kind", "error", kerr, "id", target)
+			writeError(w, http.StatusInternalServerError, "failed to validate group")
+			return
+		}
+		if !ok {
+			writeError(w, http.StatusBadRequest, "invalid target group")
+			return
+		}
+		if (targetKind == GroupKindSystem) != (kind == GroupKindSystem) {
+			writeError(w, http.StatusBadRequest, "widgets and apps can't share a group")
+			return
+		}
+		affected, err = s.store.MoveGroupApps(id, &target)
+	default:
+		writeError(w, http.StatusBadRequest, "apps must be delete, ungroup or move")
+		return
+	}
+	if err != nil {
+		slog.Error("failed to handle apps of deleted group", "error", err, "id", id, "apps", mode)
+		writeError(w, http.StatusInternalServerError, "failed to delete group")
+		return
 	}
 	if err := s.store.DeleteGroup(id); err != nil {
 		slog.Error("failed to delete group", "error", err, "id", id)
 		writeError(w, http.StatusInternalServerError, "failed to delete group")
 		return
 	}
-	slog.Info("group deleted with all apps", "id", id)
-	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+	if len(snapshot.Groups) > 0 {
+		s.recordUndo(undoDeleteGroup, snapshot.Groups[0].Name, snapshot, false)
+	}
+	slog.Info("group deleted", "id", id, "apps", mode, "affected", affected)
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "affected": affected})
 }
 
 func (s *Server) handleReorderGroups(w http.ResponseWriter, r *http.Request) {
@@ -124,6 +219,8 @@ func (s *Server) handleReorderGroups(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
 }
 
+// handleListApps handles GET /api/apps. Archived apps are left out unless
+// the admin asks for them with ?includeArchived=1.
 func (s *Server) handleListApps(w http.ResponseWriter, r *http.Request) {
 	apps, err := s.store.ListApps()
 	if err != nil {
@@ -131,7 +228,131 @@ func (s *Server) handleListApps(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, "failed to list apps")
 		return
 	}
-	writeJSON(w, http.StatusOK, apps)
+	if r.URL.Query().Get("includeArchived") != "1" || !isAdmin(r) {
+		apps = slices.DeleteFunc(apps, func(a store.AppItem) bool { return a.Archived })
+	}
+	writeJSON(w, http.StatusOK, s.appViews(r, apps))
+}
+
+// handleArchiveApp handles PUT /api/apps/{id}/archive with
+// {"archived": true|false}. Archiving hides the app from the dashboard, its
+// shares and status checks, keeping everything else.
+func (s *Server) handleArchiveApp(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var req struct {
+		Archived bool `json:"archived"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	ok, err := s.store.SetAppArchived(id, req.Archived)
+	if err != nil {
+		slog.Error("failed to archive app", "error", err, "id", id)
+		writeError(w, http.StatusInternalServerError, "failed to archive app")
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "app not found")
+		return
+	}
+	slog.Info("app archived", "id", id, "archived", req.Archived)
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+// appViews resolves the links of apps for the client of r.
+func (s *Server) appViews(r *http.Request, apps []store.AppItem) []appView {
+	internal, admin := s.internalClient(r), isAdmin(r)
+	out := make([]appView, len(apps))
+	for i, a := range apps {
+		if !admin {
+			// Only the admin can run actions, and their targets may
+			// carry tokens.
+			a.Actions = []store.AppAction{}
+		}
+		out[i] = appView{AppItem: a, Href: appHref(a, internal), DescriptionHTML: descriptionHTML(a)}
+	}
+	return out
+}
+
+// handleListRecentApps handles GET /api/apps/recent: the newest apps,
+// widgets aside, for a "what's new" strip. ?sort=updated orders them by
+// their last change instead of when they were added; ?limit= defaults to 10.
+func (s *Server) handleListRecentApps(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	byUpdate := false
+	switch q.Get("sort") {
+	case "", "created":
+	case "updated":
+		byUpdate = true
+	default:
+		writeError(w, http.StatusBadRequest, "sort must be created or updated")
+		return
+	}
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	if limit <= 0 || limit > 50 {
+		limit = 10
+	}
+	apps, err := s.store.ListRecentApps(byUpdate, limit)
+	if err != nil {
+		slog.Error("failed to list recent apps", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to list apps")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.appViews(r, apps))
+}
+
+// normalizeWidgetDescription validates a widget's description against the
+// schema of its kind and replaces it with the canonical encoding.
+func normalizeWidgetDescription(req *createAppRequest) string {
+	var raw []byte
+	if req.Description != nil {
+		raw = []byte(*req.Description)
+	}
+	config, err := validateWidgetConfig(widgetKindFromURL(req.URL), raw)
+	if err != nil {
+		return err.Error()
+	}
+	desc := string(config)
+	req.Description = &desc
+	return ""
+}
+
+// syncWidgetConfig keeps widget_configs in step with an item that was just
+// saved through the apps API.
+func (s *Server) syncWidgetConfig(id string, req createAppRequest) error {
+	kind := widgetKindFromURL(req.URL)
+	if kind == "" {
+		return s.store.DeleteWidgetConfig(id)
+	}
+	return s.store.PutWidgetConfig(id, kind, []byte(*req.Description))
+}
+
+// checkAppGroup checks that an app may go in groupID: widgets live in the
+// system group, apps in app groups or in no group. It returns the error
+// status and message, or 0.
+func (s *Server) checkAppGroup(groupID *string, isWidget bool) (int, string) {
+	if groupID == nil {
+		if isWidget {
+			return http.StatusBadRequest, "widgets must be in system group"
+		}
+		return 0, ""
+	}
+	kind, ok, err := s.store.GroupKindByID(*groupID)
+	if err != nil {
+		slog.Error("failed to get group kind", "error", err, "groupId", *groupID)
+		return http.StatusInternalServerError, "failed to validate group"
+	}
+	if !ok {
+		return http.StatusBadRequest, "invalid group"
+	}
+	if kind == GroupKindSystem && !isWidget {
+		return http.StatusBadRequest, "system group only allows widgets"
+	}
+	if kind != GroupKindSystem && isWidget {
+		return http.StatusBadRequest, "app group does not allow widgets"
+	}
+	return 0, ""
 }
 
 func (s *Server) handleCreateApp(w http.ResponseWriter, r *http.Request) {
@@ -145,37 +366,44 @@ func (s *Server) handleCreateApp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	isWidget := strings.HasPrefix(req.URL, "widget:")
-	if req.GroupID == nil {
-		if isWidget {
-			writeError(w, http.StatusBadRequest, "widgets must be in system group")
-			return
-		}
-	} else {
-		kind, ok, err := s.store.GroupKindByID(*req.GroupID)
-		if err != nil {
-			slog.Error("failed to get group kind", "error", err, "groupId", *req.GroupID)
-			writeError(w, http.StatusInternalServerError, "failed to validate group")
-			return
-		}
-		if !ok {
-			writeError(w, http.StatusBadRequest, "invalid group")
-			return
-		}
-		if kind == GroupKindSystem && !isWidget {
-			writeError(w, http.StatusBadRequest, "system group only allows widgets")
-			return
-		}
-		if kind != GroupKindSystem && isWidget {
-			writeError(w, http.StatusBadRequest, "app group does not allow widgets")
+	if status, msg := s.checkAppGroup(req.GroupID, isWidget); status != 0 {
+		writeError(w, status, msg)
+		return
+	}
+	if msg := normalizeAppLink(&req); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+	if msg := s.normalizeAppActions(&req); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+	if msg := s.normalizeAppShortcut(&req, ""); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+	if msg := s.normalizeAppIcon(r.Context(), &req); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+	if isWidget {
+		if msg := normalizeWidgetDescription(&req); msg != "" {
+			writeError(w, http.StatusBadRequest, msg)
 			return
 		}
+	} else if msg := normalizeAppDescription(&req); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
 	}
-	app, err := s.store.CreateApp(req.GroupID, req.Name, req.Description, req.URL, req.IconPath, req.IconSource)
+	app, err := s.store.CreateApp(req.item(""))
 	if err != nil {
 		slog.Error("failed to create app", "error", err, "name", req.Name)
 		writeError(w, http.StatusInternalServerError, "failed to create app")
 		return
 	}
+	if err := s.syncWidgetConfig(app.ID, req); err != nil {
+		slog.Error("failed to save widget config", "error", err, "id", app.ID)
+	}
 	slog.Info("app created", "id", app.ID, "name", app.Name)
 	writeJSON(w, http.StatusCreated, app)
 }
@@ -192,47 +420,63 @@ func (s *Server) handleUpdateApp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	isWidget := strings.HasPrefix(req.URL, "widget:")
-	if req.GroupID == nil {
-		if isWidget {
-			writeError(w, http.StatusBadRequest, "widgets must be in system group")
-			return
-		}
-	} else {
-		kind, ok, err := s.store.GroupKindByID(*req.GroupID)
-		if err != nil {
-			slog.Error("failed to get group kind", "error", err, "groupId", *req.GroupID)
-			writeError(w, http.StatusInternalServerError, "failed to validate group")
-			return
-		}
-		if !ok {
-			writeError(w, http.StatusBadRequest, "invalid group")
-			return
-		}
-		if kind == GroupKindSystem && !isWidget {
-			writeError(w, http.StatusBadRequest, "system group only allows widgets")
-			return
-		}
-		if kind != GroupKindSystem && isWidget {
-			writeError(w, http.StatusBadRequest, "app group does not allow widgets")
+	if status, msg := s.checkAppGroup(req.GroupID, isWidget); status != 0 {
+		writeError(w, status, msg)
+		return
+	}
+	if msg := normalizeAppLink(&req); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+	if msg := s.normalizeAppActions(&req); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+	if msg := s.normalizeAppShortcut(&req, id); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+	if msg := s.normalizeAppIcon(r.Context(), &req); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+	if isWidget {
+		if msg := normalizeWidgetDescription(&req); msg != "" {
+			writeError(w, http.StatusBadRequest, msg)
 			return
 		}
+	} else if msg := normalizeAppDescription(&req); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
 	}
-	if err := s.store.UpdateApp(id, req.GroupID, req.Name, req.Description, req.URL, req.IconPath, req.IconSource); err != nil {
+	if err := s.store.UpdateApp(req.item(id)); err != nil {
 		slog.Warn("failed to update app", "error", err, "id", id)
 		writeError(w, http.StatusNotFound, "app not found")
 		return
 	}
+	if err := s.syncWidgetConfig(id, req); err != nil {
+		slog.Error("failed to save widget config", "error", err, "id", id)
+	}
 	slog.Info("app updated", "id", id, "name", req.Name)
 	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
 }
 
 func (s *Server) handleDeleteApp(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
+	app, found, err := s.store.AppByID(id)
+	if err != nil {
+		slog.Error("failed to load app", "error", err, "id", id)
+		writeError(w, http.StatusInternalServerError, "failed to delete app")
+		return
+	}
 	if err := s.store.DeleteApp(id); err != nil {
 		slog.Error("failed to delete app", "error", err, "id", id)
 		writeError(w, http.StatusInternalServerError, "failed to delete app")
 		return
 	}
+	if found {
+		s.recordUndo(undoDeleteApp, app.Name, store.Export{Apps: []store.AppItem{app}}, false)
+	}
 	slog.Info("app deleted", "id", id)
 	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
 }
@@ -253,3 +497,44 @@ func (s *Server) handleReorderApps(w http.ResponseWriter, r *http.Request) {
 	}
 	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
 }
+
+// handleMoveApp handles POST /api/apps/move: puts an app at targetIndex of
+// targetGroupId (null for ungrouped), renumbering the groups it leaves and
+// joins in one step. The index counts the apps shown on the dashboard, so
+// archived apps don't shift it.
+func (s *Server) handleMoveApp(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		AppID         string  `json:"appId"`
+		TargetGroupID *string `json:"targetGroupId"`
+		TargetIndex   int     `json:"targetIndex"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	app, ok, err := s.store.AppByID(req.AppID)
+	if err != nil {
+		slog.Error("failed to load app", "error", err, "id", req.AppID)
+		writeError(w, http.StatusInternalServerError, "failed to load app")
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "app not found")
+		return
+	}
+	if status, msg := s.checkAppGroup(req.TargetGroupID, strings.HasPrefix(app.URL, "widget:")); status != 0 {
+		writeError(w, status, msg)
+		return
+	}
+	ok, err = s.store.MoveApp(req.AppID, req.TargetGroupID, req.TargetIndex)
+	if err != nil {
+		slog.Error("failed to move app", "error", err, "id", req.AppID)
+		writeError(w, http.StatusInternalServerError, "failed to move app")
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "app not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
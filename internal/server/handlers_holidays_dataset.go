@@ -0,0 +1,132 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/morezhou/hearth/internal/widgets"
+)
+
+// kvHolidayDataset holds the admin's override of the bundled offline holiday
+// dataset, as JSON.
+const kvHolidayDataset = "holidays.dataset"
+
+// loadHolidayDataset installs the stored override, if any, as the offline
+// holiday fallback.
+func (s *Server) loadHolidayDataset() {
+	raw, ok, err := s.store.GetKV(kvHolidayDataset)
+	if err != nil {
+		slog.Error("failed to load holiday dataset", "error", err)
+		return
+	}
+	if !ok {
+		widgets.SetHolidayDataset(nil)
+		return
+	}
+	ds, err := widgets.ParseHolidayDataset([]byte(raw))
+	if err != nil {
+		slog.Warn("ignoring invalid holiday dataset override", "error", err)
+		widgets.SetHolidayDataset(nil)
+		return
+	}
+	widgets.SetHolidayDataset(ds)
+}
+
+func (s *Server) saveHolidayDataset(ds *widgets.HolidayDataset) error {
+	b, err := json.Marshal(ds)
+	if err != nil {
+		return err
+	}
+	if err := s.store.SetKV(kvHolidayDataset, string(b)); err != nil {
+		return err
+	}
+	widgets.SetHolidayDataset(ds)
+	return nil
+}
+
+func (s *Server) handleGetHolidayDataset(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, widgets.HolidayDatasetSummary())
+}
+
+// handlePutHolidayDataset uploads a dataset that replaces the bundled one.
+func (s *Server) handlePutHolidayDataset(w http.ResponseWriter, r *http.Request) {
+	b, err := io.ReadAll(io.LimitReader(r.Body, 2<<20))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid body")
+		return
+	}
+	ds, err := widgets.ParseHolidayDataset(b)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := s.saveHolidayDataset(ds); err != nil {
+		slog.Error("failed to save holiday dataset", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to save holiday dataset")
+		return
+	}
+	writeJSON(w, http.StatusOK, widgets.HolidayDatasetSummary())
+}
+
+// handleRefreshHolidayDataset rebuilds the dataset from the live sources.
+// Countries default to the bundled ones and years to this year and the next.
+func (s *Server) handleRefreshHolidayDataset(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Countries []string `json:"countries"`
+		Years     []int    `json:"years"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			writeError(w, http.StatusBadRequest, "invalid json")
+			return
+		}
+	}
+	if len(req.Countries) == 0 {
+		req.Countries = widgets.DatasetCountries
+	}
+	if len(req.Years) == 0 {
+		y := time.Now().Year()
+		req.Years = []int{y, y + 1}
+	}
+	if len(req.Countries) > 50 || len(req.Years) > 5 {
+		writeError(w, http.StatusBadRequest, "too many countries or years")
+		return
+	}
+	for _, y := range req.Years {
+		if y < 1900 || y > 2999 {
+			writeError(w, http.StatusBadRequest, "invalid year")
+			return
+		}
+	}
+
+	ds, buildErr := widgets.BuildHolidayDataset(r.Context(), req.Countries, req.Years)
+	if ds == nil {
+		writeError(w, http.StatusBadGateway, "refresh failed: "+buildErr.Error())
+		return
+	}
+	if err := s.saveHolidayDataset(ds); err != nil {
+		slog.Error("failed to save holiday dataset", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to save holiday dataset")
+		return
+	}
+	resp := map[string]any{"dataset": widgets.HolidayDatasetSummary()}
+	if buildErr != nil {
+		resp["warning"] = buildErr.Error()
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleDeleteHolidayDataset drops the override and reverts to the bundled
+// dataset.
+func (s *Server) handleDeleteHolidayDataset(w http.ResponseWriter, r *http.Request) {
+	if err := s.store.DeleteKV(kvHolidayDataset); err != nil {
+		slog.Error("failed to delete holiday dataset", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to delete holiday dataset")
+		return
+	}
+	widgets.SetHolidayDataset(nil)
+	writeJSON(w, http.StatusOK, widgets.HolidayDatasetSummary())
+}
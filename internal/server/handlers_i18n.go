@@ -0,0 +1,45 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/morezhou/hearth/internal/i18n"
+)
+
+// localeInfo is a locale with a catalog, named in its own language.
+type localeInfo struct {
+	Locale string `json:"locale"`
+	Name   string `json:"name"`
+}
+
+// handleListLocales handles GET /api/i18n.
+func (s *Server) handleListLocales(w http.ResponseWriter, r *http.Request) {
+	locales := i18n.Locales()
+	out := make([]localeInfo, 0, len(locales))
+	for _, l := range locales {
+		out = append(out, localeInfo{Locale: l, Name: i18n.T(l, i18n.NameKey)})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// handleGetLocaleBundle handles GET /api/i18n/{locale}, serving the messages
+// of the closest catalog ("de-AT" gets "de") with English filling the gaps.
+func (s *Server) handleGetLocaleBundle(w http.ResponseWriter, r *http.Request) {
+	tag := chi.URLParam(r, "locale")
+	if _, ok := i18n.Normalize(tag); !ok {
+		writeError(w, http.StatusBadRequest, "invalid locale")
+		return
+	}
+	loc := i18n.Match(tag)
+	if loc == "" {
+		writeError(w, http.StatusNotFound, "no catalog for locale")
+		return
+	}
+	w.Header().Set("Content-Language", loc)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"locale":   loc,
+		"messages": i18n.Bundle(loc),
+	})
+}
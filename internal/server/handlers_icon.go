@@ -1,12 +1,19 @@
 package server
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
 	"net/http"
-	"os"
-	"path/filepath"
+	"net/url"
+	"strings"
+
+	"github.com/morezhou/hearth/internal/icon"
+	"github.com/morezhou/hearth/internal/netguard"
 )
 
 type resolveIconRequest struct {
@@ -32,6 +39,20 @@ func (s *Server) handleResolveIcon(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		writeError(w, http.StatusBadRequest, "invalid url")
+		return
+	}
+	if err := s.outbound.CheckHost(r.Context(), u.Hostname()); err != nil {
+		if errors.Is(err, netguard.ErrBlocked) {
+			writeError(w, http.StatusForbidden, "url points to an internal address; allow it with HEARTH_OUTBOUND_ALLOW")
+		} else {
+			writeError(w, http.StatusBadRequest, "cannot resolve host")
+		}
+		return
+	}
+
 	cacheKey := sha256Hex(req.URL)
 
 	// If refresh is requested, delete the existing cache entry
@@ -40,8 +61,7 @@ func (s *Server) handleResolveIcon(w http.ResponseWriter, r *http.Request) {
 	} else {
 		// Check cache only if not refreshing
 		if e, ok, err := s.store.GetIconCache(cacheKey); err == nil && ok {
-			full := filepath.Join(s.cfg.DataDir, "icons", e.IconPath)
-			if _, err := os.Stat(full); err == nil {
+			if _, err := s.iconStore.Stat(r.Context(), e.IconPath); err == nil {
 				writeJSON(w, http.StatusOK, resolveIconResponse{
 					Title:      "",
 					IconURL:    "/assets/icons/" + e.IconPath,
@@ -81,3 +101,103 @@ func sha256Hex(s string) string {
 	h := sha256.Sum256([]byte(s))
 	return hex.EncodeToString(h[:])
 }
+
+type uploadIconResponse struct {
+	IconURL    string `json:"iconUrl"`
+	IconPath   string `json:"iconPath"`
+	IconSource string `json:"iconSource"`
+	Existing   bool   `json:"existing"` // identical content was already stored
+}
+
+// handleUploadIcon stores a multipart "file" as a custom icon. Set the form
+// field format=png to normalize raster images to PNG.
+func (s *Server) handleUploadIcon(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, icon.MaxUploadSize+64<<10)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, "file too large")
+			return
+		}
+		writeError(w, http.StatusBadRequest, "file required")
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, icon.MaxUploadSize+1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var reencode bool
+	switch format := strings.ToLower(strings.TrimSpace(r.FormValue("format"))); format {
+	case "", "original":
+	case "png":
+		reencode = true
+	default:
+		writeError(w, http.StatusBadRequest, "unsupported format: "+format)
+		return
+	}
+
+	res, err := s.iconResolver.SaveUpload(r.Context(), data, reencode)
+	if err != nil {
+		if errors.Is(err, icon.ErrInvalidUpload) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, uploadIconResponse{
+		IconURL:    iconURLFromPath(res.IconPath),
+		IconPath:   res.IconPath,
+		IconSource: "upload",
+		Existing:   res.Existing,
+	})
+}
+
+// Apps with iconSource "emoji" or "text" give the glyph as iconPath (for text,
+// empty means the app's initials); it is rendered into a tile stored under
+// "tiles/".
+const (
+	iconSourceEmoji = icon.TileEmoji
+	iconSourceText  = icon.TileText
+	tileIconPrefix  = "tiles/"
+)
+
+// normalizeAppIcon resolves pack and tile icon references in an app request
+// into stored icon paths. It returns a message for the client when the
+// reference is invalid.
+func (s *Server) normalizeAppIcon(ctx context.Context, req *createAppRequest) string {
+	if msg := s.normalizePackIconPath(ctx, req); msg != "" {
+		return msg
+	}
+	if req.IconSource == nil || (*req.IconSource != iconSourceEmoji && *req.IconSource != iconSourceText) {
+		return ""
+	}
+	var glyph string
+	if req.IconPath != nil {
+		glyph = *req.IconPath
+	}
+	// Re-saving an app keeps its rendered tile.
+	if strings.HasPrefix(glyph, tileIconPrefix) && req.IconColor == "" {
+		if _, err := s.iconStore.Stat(ctx, glyph); err == nil {
+			return ""
+		}
+		return "unknown tile icon"
+	}
+	if glyph == "" && *req.IconSource == iconSourceText {
+		glyph = icon.Initials(req.Name)
+	}
+	tile, err := icon.RenderTile(*req.IconSource, glyph, req.IconColor, req.Name)
+	if err != nil {
+		return err.Error()
+	}
+	if err := s.iconStore.Put(ctx, tile.Key, tile.Data, "image/svg+xml"); err != nil {
+		slog.Error("failed to store tile icon", "error", err)
+		return "failed to store icon"
+	}
+	req.IconPath = &tile.Key
+	return ""
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/morezhou/hearth/internal/icon"
+)
+
+// Apps reference pack icons with iconSource "pack" and iconPath
+// "pack/<slug>.png", served below /assets/icons/ like cached icons.
+const (
+	iconSourcePack = "pack"
+	packIconPrefix = "pack/"
+)
+
+type packSearchResult struct {
+	Slug     string `json:"slug"`
+	Score    int    `json:"score"`
+	IconPath string `json:"iconPath"`
+	IconURL  string `json:"iconUrl"`
+}
+
+// handleSearchPackIcons handles GET /api/icons/pack/search?q=plex&limit=20.
+func (s *Server) handleSearchPackIcons(w http.ResponseWriter, r *http.Request) {
+	if s.iconPack == nil {
+		writeError(w, http.StatusNotFound, "icon pack disabled")
+		return
+	}
+	limit := 20
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 200 {
+		limit = l
+	}
+	matches, err := s.iconPack.Search(r.Context(), r.URL.Query().Get("q"), limit)
+	if err != nil {
+		slog.Warn("icon pack search failed", "error", err)
+		writeError(w, http.StatusBadGateway, "icon pack index unavailable")
+		return
+	}
+	res := make([]packSearchResult, 0, len(matches))
+	for _, m := range matches {
+		p := packIconPrefix + m.Slug + ".png"
+		res = append(res, packSearchResult{Slug: m.Slug, Score: m.Score, IconPath: p, IconURL: iconURLFromPath(p)})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"source": s.iconPack.Source.Name, "results": res})
+}
+
+// handleGetPackIcon serves /assets/icons/pack/{file}, fetching the icon on
+// first use when the pack has not been synced.
+func (s *Server) handleGetPackIcon(w http.ResponseWriter, r *http.Request) {
+	slug, ok := strings.CutSuffix(chi.URLParam(r, "file"), ".png")
+	if s.iconPack == nil || !ok {
+		http.NotFound(w, r)
+		return
+	}
+	b, err := s.iconPack.Icon(r.Context(), slug)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			slog.Debug("icon pack fetch failed", "slug", slug, "error", err)
+		}
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	_, _ = w.Write(b)
+}
+
+func (s *Server) handleGetIconPackStatus(w http.ResponseWriter, r *http.Request) {
+	if s.iconPack == nil {
+		writeError(w, http.StatusNotFound, "icon pack disabled")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.iconPack.Status())
+}
+
+// handleSyncIconPack starts downloading the whole pack in the background;
+// poll the status endpoint for progress.
+func (s *Server) handleSyncIconPack(w http.ResponseWriter, r *http.Request) {
+	if s.iconPack == nil {
+		writeError(w, http.StatusNotFound, "icon pack disabled")
+		return
+	}
+	if s.iconPack.Status().Syncing {
+		writeError(w, http.StatusConflict, "sync already running")
+		return
+	}
+	s.goWork(func(ctx context.Context) {
+		if err := s.iconPack.Sync(ctx); err != nil {
+			slog.Warn("icon pack sync failed", "pack", s.iconPack.Source.Name, "error", err)
+		}
+	})
+	writeJSON(w, http.StatusAccepted, map[string]any{"ok": true})
+}
+
+// normalizePackIconPath rewrites a pack icon reference, given as a slug or
+// "pack/<slug>.png", to its icon path. It returns an error message for
+// unknown icons; other icon sources pass through.
+func (s *Server) normalizePackIconPath(ctx context.Context, req *createAppRequest) string {
+	if req.IconSource == nil || *req.IconSource != iconSourcePack {
+		return ""
+	}
+	if s.iconPack == nil {
+		return "icon pack disabled"
+	}
+	if req.IconPath == nil {
+		return "iconPath required"
+	}
+	slug := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(*req.IconPath), packIconPrefix), ".png")
+	slug = strings.ToLower(slug)
+	if !icon.ValidPackSlug(slug) {
+		return "invalid pack icon"
+	}
+	// An unreachable index must not block saving the app.
+	if ok, err := s.iconPack.Has(ctx, slug); err == nil && !ok {
+		return "unknown pack icon: " + slug
+	}
+	p := packIconPrefix + slug + ".png"
+	req.IconPath = &p
+	return ""
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// kvIngestTokenHash holds the SHA-256 of the ingest token; the token itself
+// is only shown when it is generated.
+const kvIngestTokenHash = "ingest.tokenHash"
+
+const (
+	// maxIngestPayload bounds one pushed value.
+	maxIngestPayload = 64 << 10
+	// maxIngestKeys bounds how many keys scripts can create.
+	maxIngestKeys = 100
+)
+
+var ingestKeyRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+func hashIngestToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ingestAuthorized checks the request's bearer token against the stored
+// hash. Ingest is disabled until a token was generated.
+func (s *Server) ingestAuthorized(r *http.Request) bool {
+	want := s.getStringSetting(kvIngestTokenHash, "")
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if want == "" || !ok || got == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hashIngestToken(got)), []byte(want)) == 1
+}
+
+// ingestValueView is a pushed value as served to widgets.
+type ingestValueView struct {
+	Key       string          `json:"key"`
+	Value     json.RawMessage `json:"value"`
+	UpdatedAt int64           `json:"updatedAt"`
+}
+
+// handleIngest handles POST /api/ingest/{key}: stores the JSON body as the
+// latest value of key.
+func (s *Server) handleIngest(w http.ResponseWriter, r *http.Request) {
+	if !s.ingestAuthorized(r) {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	key := chi.URLParam(r, "key")
+	if !ingestKeyRe.MatchString(key) {
+		writeError(w, http.StatusBadRequest, "key must be 1-64 lowercase letters, digits, - or _")
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxIngestPayload+1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid body")
+		return
+	}
+	if len(body) > maxIngestPayload {
+		writeError(w, http.StatusRequestEntityTooLarge, "payload too large (max 64 KiB)")
+		return
+	}
+	if !json.Valid(body) {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if _, exists, err := s.store.GetIngestValue(key); err == nil && !exists {
+		if n, err := s.store.CountIngestValues(); err == nil && n >= maxIngestKeys {
+			writeError(w, http.StatusConflict, "too many keys")
+			return
+		}
+	}
+	if err := s.store.PutIngestValue(key, body); err != nil {
+		slog.Error("failed to store ingest value", "key", key, "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to store value")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGetIngest handles GET /api/ingest/{key}, read by the custom widget.
+func (s *Server) handleGetIngest(w http.ResponseWriter, r *http.Request) {
+	v, ok, err := s.store.GetIngestValue(chi.URLParam(r, "key"))
+	if err != nil {
+		slog.Error("failed to load ingest value", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to load value")
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "no value pushed yet")
+		return
+	}
+	writeJSON(w, http.StatusOK, ingestValueView{Key: v.Key, Value: v.Payload, UpdatedAt: v.UpdatedAt})
+}
+
+// handleListIngest handles GET /api/admin/ingest.
+func (s *Server) handleListIngest(w http.ResponseWriter, r *http.Request) {
+	list, err := s.store.ListIngestValues()
+	if err != nil {
+		slog.Error("failed to list ingest values", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to list values")
+		return
+	}
+	out := make([]ingestValueView, 0, len(list))
+	for _, v := range list {
+		out = append(out, ingestValueView{Key: v.Key, Value: v.Payload, UpdatedAt: v.UpdatedAt})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"enabled": s.getStringSetting(kvIngestTokenHash, "") != "",
+		"values":  out,
+	})
+}
+
+// handleRotateIngestToken handles POST /api/admin/ingest/token: generates a
+// new token, invalidating the previous one, and returns it once.
+func (s *Server) handleRotateIngestToken(w http.ResponseWriter, r *http.Request) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to generate token")
+		return
+	}
+	token := hex.EncodeToString(b)
+	if err := s.store.SetKV(kvIngestTokenHash, hashIngestToken(token)); err != nil {
+		slog.Error("failed to save ingest token", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to save token")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"token": token})
+}
+
+// handleDeleteIngest handles DELETE /api/admin/ingest/{key}.
+func (s *Server) handleDeleteIngest(w http.ResponseWriter, r *http.Request) {
+	ok, err := s.store.DeleteIngestValue(chi.URLParam(r, "key"))
+	if err != nil {
+		slog.Error("failed to delete ingest value", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to delete value")
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
@@ -1,80 +1,15 @@
 package server
 
 import (
-	"encoding/json"
-	"io"
+	"errors"
+	"log/slog"
 	"net/http"
 	"strings"
-	"sync"
-	"time"
-)
-
-// Lucide icon metadata cache
-var (
-	lucideTagsCache     map[string][]string // icon name -> tags
-	lucideTagsCacheTime time.Time
-	lucideTagsMutex     sync.RWMutex
-	lucideTagsCacheTTL  = 24 * time.Hour
-)
-
-const lucideTagsURL = "https://unpkg.com/lucide-static@latest/tags.json"
-
-// fetchLucideTags fetches and caches the Lucide icon tags from CDN
-func fetchLucideTags() (map[string][]string, error) {
-	lucideTagsMutex.RLock()
-	if lucideTagsCache != nil && time.Since(lucideTagsCacheTime) < lucideTagsCacheTTL {
-		cache := lucideTagsCache
-		lucideTagsMutex.RUnlock()
-		return cache, nil
-	}
-	lucideTagsMutex.RUnlock()
-
-	lucideTagsMutex.Lock()
-	defer lucideTagsMutex.Unlock()
 
-	// Double-check after acquiring write lock
-	if lucideTagsCache != nil && time.Since(lucideTagsCacheTime) < lucideTagsCacheTTL {
-		return lucideTagsCache, nil
-	}
+	"github.com/go-chi/chi/v5"
 
-	client := &http.Client{Timeout: 15 * time.Second}
-	resp, err := client.Get(lucideTagsURL)
-	if err != nil {
-		// Return cached data if available, even if expired
-		if lucideTagsCache != nil {
-			return lucideTagsCache, nil
-		}
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		if lucideTagsCache != nil {
-			return lucideTagsCache, nil
-		}
-		return nil, err
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		if lucideTagsCache != nil {
-			return lucideTagsCache, nil
-		}
-		return nil, err
-	}
-
-	var tags map[string][]string
-	if err := json.Unmarshal(body, &tags); err != nil {
-		if lucideTagsCache != nil {
-			return lucideTagsCache, nil
-		}
-		return nil, err
-	}
-
-	lucideTagsCache = tags
-	lucideTagsCacheTime = time.Now()
-	return tags, nil
-}
+	"github.com/morezhou/hearth/internal/lucide"
+)
 
 type lucideSearchResult struct {
 	Name string   `json:"name"`
@@ -92,24 +27,19 @@ func (s *Server) handleSearchLucideIcons(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	tags, err := fetchLucideTags()
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to fetch icon data")
-		return
-	}
-
-	var results []lucideSearchResult
+	tags := s.lucide.Tags()
+	results := []lucideSearchResult{}
 
 	// If no query, return popular icons
 	if query == "" {
 		popularIcons := []string{
-			"home", "settings", "user", "mail", "calendar", "clock", "search", "bell",
+			"house", "settings", "user", "mail", "calendar", "clock", "search", "bell",
 			"heart", "star", "bookmark", "folder", "file", "image", "camera", "video",
 			"music", "play", "globe", "map", "map-pin", "phone", "monitor", "laptop",
-			"shopping-cart", "credit-card", "dollar-sign", "trending-up", "bar-chart",
+			"shopping-cart", "credit-card", "dollar-sign", "trending-up", "chart-line",
 			"code", "terminal", "database", "server", "hard-drive", "cpu", "layers",
 			"link", "external-link", "download", "upload", "share", "send", "inbox",
-			"trash", "edit", "copy", "check", "x", "plus", "minus", "lock", "key",
+			"trash", "pencil", "copy", "check", "x", "plus", "minus", "lock", "key",
 		}
 		for _, name := range popularIcons {
 			if iconTags, ok := tags[name]; ok {
@@ -123,50 +53,66 @@ func (s *Server) handleSearchLucideIcons(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Search by icon name and tags
-	for name, iconTags := range tags {
-		// Check if name contains query
+	// Name matches first, then tag matches; each in name order.
+	var tagMatches []lucideSearchResult
+	for _, name := range s.lucide.Names() {
+		if len(results) >= limit {
+			break
+		}
+		iconTags := tags[name]
 		if strings.Contains(name, query) {
 			results = append(results, lucideSearchResult{Name: name, Tags: iconTags})
 			continue
 		}
-
-		// Check if any tag contains query
 		for _, tag := range iconTags {
 			if strings.Contains(strings.ToLower(tag), query) {
-				results = append(results, lucideSearchResult{Name: name, Tags: iconTags})
+				tagMatches = append(tagMatches, lucideSearchResult{Name: name, Tags: iconTags})
 				break
 			}
 		}
-
-		if len(results) >= limit {
-			break
-		}
 	}
-
-	// Sort results: exact name match first, then name contains, then tag match
-	// For simplicity, we'll just return as-is (Go maps are unordered anyway)
+	results = append(results, tagMatches[:min(len(tagMatches), limit-len(results))]...)
 
 	writeJSON(w, http.StatusOK, results)
 }
 
 // handleListAllLucideIcons handles GET /api/icons/lucide/all - returns all icon names
 func (s *Server) handleListAllLucideIcons(w http.ResponseWriter, r *http.Request) {
-	tags, err := fetchLucideTags()
+	names := s.lucide.Names()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"icons": names,
+		"count": len(names),
+	})
+}
+
+// handleGetLucideSVG handles GET /api/icons/lucide/{name}.svg.
+func (s *Server) handleGetLucideSVG(w http.ResponseWriter, r *http.Request) {
+	svg, err := s.lucide.SVG(r.Context(), chi.URLParam(r, "name"))
+	if errors.Is(err, lucide.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to fetch icon data")
+		slog.Warn("lucide icon unavailable", "name", chi.URLParam(r, "name"), "error", err)
+		writeError(w, http.StatusBadGateway, "icon unavailable")
 		return
 	}
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Content-Security-Policy", svgCSP)
+	w.Header().Set("Cache-Control", "public, max-age=604800")
+	_, _ = w.Write(svg)
+}
 
-	names := make([]string, 0, len(tags))
-	for name := range tags {
-		names = append(names, name)
+// handleRefreshLucideIcons handles POST /api/admin/icons/lucide/refresh,
+// replacing the bundled icon tags with those of the latest Lucide release.
+func (s *Server) handleRefreshLucideIcons(w http.ResponseWriter, r *http.Request) {
+	n, err := s.lucide.RefreshTags(r.Context())
+	if err != nil {
+		slog.Warn("lucide refresh failed", "error", err)
+		writeError(w, http.StatusBadGateway, "failed to fetch icon data")
+		return
 	}
-
-	writeJSON(w, http.StatusOK, map[string]any{
-		"icons": names,
-		"count": len(names),
-	})
+	writeJSON(w, http.StatusOK, map[string]any{"count": n})
 }
 
 func parseInt(s string) (int, error) {
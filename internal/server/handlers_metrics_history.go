@@ -0,0 +1,94 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/morezhou/hearth/internal/store"
+)
+
+// metricsRetention is how long host metrics samples are kept; it covers the
+// longest history range.
+const metricsRetention = 7 * 24 * time.Hour
+
+// metricsHistoryPoints is roughly how many points a history range is
+// downsampled to, plenty for a sparkline.
+const metricsHistoryPoints = 120
+
+const defaultMetricsSampleInterval = 30 * time.Second
+
+var metricsHistoryRanges = map[string]time.Duration{
+	"1h":  time.Hour,
+	"24h": 24 * time.Hour,
+	"7d":  metricsRetention,
+}
+
+// runMetricsSampler records a host metrics sample every
+// cfg.MetricsSampleInterval and drops samples older than metricsRetention.
+func (s *Server) runMetricsSampler(ctx context.Context) {
+	if s.cfg.MetricsSampleInterval <= 0 {
+		return
+	}
+	t := time.NewTicker(s.cfg.MetricsSampleInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		if err := s.sampleMetrics(ctx); err != nil {
+			slog.Warn("metrics sample failed", "error", err)
+		}
+	}
+}
+
+func (s *Server) sampleMetrics(ctx context.Context) error {
+	m, _ := s.latestHostMetrics(ctx)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := s.store.AddMetricsSample(store.MetricsSample{
+		At:        m.CollectedAt / 1000,
+		CPU:       m.CPUPercent,
+		Mem:       m.MemPercent,
+		Disk:      m.DiskPercent,
+		NetSend:   m.NetSendRate,
+		NetRecv:   m.NetRecvRate,
+		DiskRead:  m.DiskReadRate,
+		DiskWrite: m.DiskWriteRate,
+	}); err != nil {
+		return err
+	}
+	_, err := s.store.PruneMetricsSamples(time.Now().Add(-metricsRetention).Unix())
+	return err
+}
+
+type metricsHistoryResponse struct {
+	Range  string                `json:"range"`
+	Step   int64                 `json:"step"` // seconds per point
+	Points []store.MetricsSample `json:"points"`
+}
+
+// handleGetHostMetricsHistory handles GET /api/metrics/host/history?range=1h|24h|7d.
+func (s *Server) handleGetHostMetricsHistory(w http.ResponseWriter, r *http.Request) {
+	rng := r.URL.Query().Get("range")
+	if rng == "" {
+		rng = "1h"
+	}
+	span, ok := metricsHistoryRanges[rng]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "range must be 1h, 24h or 7d")
+		return
+	}
+	step := max(int64(s.cfg.MetricsSampleInterval/time.Second), int64(span/time.Second)/metricsHistoryPoints, 1)
+	points, err := s.store.MetricsHistory(time.Now().Add(-span).Unix(), step)
+	if err != nil {
+		slog.Error("metrics history failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to load metrics history")
+		return
+	}
+	writeJSON(w, http.StatusOK, metricsHistoryResponse{Range: rng, Step: step, Points: points})
+}
@@ -0,0 +1,209 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/morezhou/hearth/internal/metrics"
+	"github.com/morezhou/hearth/internal/store"
+)
+
+// Remote hosts run `hearth --agent`; the dashboard polls them every
+// agentPollInterval and serves the latest reading, fetching on demand when it
+// is older than agentStaleAfter.
+const (
+	agentPollInterval = 10 * time.Second
+	agentStaleAfter   = 30 * time.Second
+)
+
+var metricHostNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// agentReadings holds the latest metrics of each remote host.
+type agentReadings struct {
+	mu      sync.Mutex
+	entries map[string]agentReading
+}
+
+type agentReading struct {
+	metrics metrics.HostMetrics
+	err     error
+	at      time.Time
+}
+
+func (a *agentReadings) get(name string) (agentReading, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	r, ok := a.entries[name]
+	return r, ok
+}
+
+func (a *agentReadings) set(name string, r agentReading) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.entries == nil {
+		a.entries = map[string]agentReading{}
+	}
+	a.entries[name] = r
+}
+
+func (a *agentReadings) drop(name string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.entries, name)
+}
+
+// pollAgent fetches and records the metrics of one host.
+func (s *Server) pollAgent(ctx context.Context, h store.MetricHost) agentReading {
+	m, err := s.agents.Fetch(ctx, h.URL, h.Token)
+	r := agentReading{metrics: m, err: err, at: time.Now()}
+	if err != nil {
+		// Keep serving the last good reading; the error marks it offline.
+		if prev, ok := s.agentReadings.get(h.Name); ok {
+			r.metrics = prev.metrics
+		}
+	}
+	s.agentReadings.set(h.Name, r)
+	return r
+}
+
+func (s *Server) runAgentPoller(ctx context.Context) {
+	t := time.NewTicker(agentPollInterval)
+	defer t.Stop()
+	for {
+		hosts, err := s.store.ListMetricHosts()
+		if err != nil {
+			slog.Warn("list metric hosts failed", "error", err)
+		}
+		var wg sync.WaitGroup
+		for _, h := range hosts {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if r := s.pollAgent(ctx, h); r.err != nil && ctx.Err() == nil {
+					slog.Debug("agent poll failed", "host", h.Name, "error", r.err)
+				}
+			}()
+		}
+		wg.Wait()
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// remoteHostMetrics returns the latest metrics of a registered host.
+func (s *Server) remoteHostMetrics(ctx context.Context, name string) (metrics.HostMetrics, bool, error) {
+	h, ok, err := s.store.GetMetricHost(name)
+	if err != nil || !ok {
+		return metrics.HostMetrics{}, false, err
+	}
+	r, ok := s.agentReadings.get(name)
+	if !ok || r.err != nil || time.Since(r.at) > agentStaleAfter {
+		r = s.pollAgent(ctx, h)
+	}
+	return r.metrics, true, r.err
+}
+
+// metricHostView is a registered host as listed by GET /api/metrics/hosts.
+// The URL is only shown to the admin; the token never leaves the server.
+type metricHostView struct {
+	Name     string `json:"name"`
+	URL      string `json:"url,omitempty"`
+	Online   bool   `json:"online"`
+	LastSeen int64  `json:"lastSeen,omitempty"` // unix ms of the last good reading
+	Error    string `json:"error,omitempty"`
+}
+
+// handleListMetricHosts handles GET /api/metrics/hosts.
+func (s *Server) handleListMetricHosts(w http.ResponseWriter, r *http.Request) {
+	hosts, err := s.store.ListMetricHosts()
+	if err != nil {
+		slog.Error("failed to list metric hosts", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to list hosts")
+		return
+	}
+	admin := isAdmin(r)
+	out := make([]metricHostView, 0, len(hosts))
+	for _, h := range hosts {
+		v := metricHostView{Name: h.Name}
+		if admin {
+			v.URL = h.URL
+		}
+		if rd, ok := s.agentReadings.get(h.Name); ok {
+			v.Online = rd.err == nil
+			v.LastSeen = rd.metrics.CollectedAt
+			if admin && rd.err != nil {
+				v.Error = rd.err.Error()
+			}
+		}
+		out = append(out, v)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// handlePutMetricHost handles PUT /api/admin/metrics/hosts/{name}. An empty
+// token keeps the stored one.
+func (s *Server) handlePutMetricHost(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	if !metricHostNameRe.MatchString(name) || name == "local" {
+		writeError(w, http.StatusBadRequest, "name must be 1-32 lowercase letters, digits, - or _")
+		return
+	}
+	var req struct {
+		URL   string `json:"url"`
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	req.URL = strings.TrimRight(strings.TrimSpace(req.URL), "/")
+	if u, err := url.Parse(req.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		writeError(w, http.StatusBadRequest, "url must be an http(s) address")
+		return
+	}
+	req.Token = strings.TrimSpace(req.Token)
+	if req.Token == "" {
+		existing, ok, err := s.store.GetMetricHost(name)
+		if err != nil || !ok {
+			writeError(w, http.StatusBadRequest, "token required")
+			return
+		}
+		req.Token = existing.Token
+	}
+	if err := s.store.PutMetricHost(store.MetricHost{Name: name, URL: req.URL, Token: req.Token}); err != nil {
+		slog.Error("failed to save metric host", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to save host")
+		return
+	}
+	s.agentReadings.drop(name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDeleteMetricHost handles DELETE /api/admin/metrics/hosts/{name}.
+func (s *Server) handleDeleteMetricHost(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	ok, err := s.store.DeleteMetricHost(name)
+	if err != nil {
+		slog.Error("failed to delete metric host", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to delete host")
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "host not found")
+		return
+	}
+	s.agentReadings.drop(name)
+	w.WriteHeader(http.StatusNoContent)
+}
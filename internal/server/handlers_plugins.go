@@ -0,0 +1,69 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/morezhou/hearth/internal/plugins"
+)
+
+// pluginInfo is a plugin as listed to clients; the command stays private.
+type pluginInfo struct {
+	ID          string           `json:"id"`
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Options     []plugins.Option `json:"options"`
+}
+
+// handleListPlugins handles GET /api/widgets/plugins.
+func (s *Server) handleListPlugins(w http.ResponseWriter, r *http.Request) {
+	list := s.plugins.List()
+	out := make([]pluginInfo, 0, len(list))
+	for _, m := range list {
+		opts := m.Options
+		if opts == nil {
+			opts = []plugins.Option{}
+		}
+		out = append(out, pluginInfo{ID: m.ID, Name: m.Name, Description: m.Description, Options: opts})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// handleRunPlugin handles GET /api/widgets/{appId}/plugin, running the plugin
+// a plugin widget is configured with. Only stored widget configs are run, so
+// visitors can't feed plugins arbitrary options.
+func (s *Server) handleRunPlugin(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "appId")
+	c, ok, err := s.store.GetWidgetConfig(id)
+	if err != nil {
+		slog.Error("failed to get widget config", "error", err, "id", id)
+		writeError(w, http.StatusInternalServerError, "failed to get widget config")
+		return
+	}
+	if !ok || c.Kind != "plugin" {
+		writeError(w, http.StatusNotFound, "widget not found")
+		return
+	}
+	var cfg struct {
+		Plugin  string            `json:"plugin"`
+		Options map[string]string `json:"options"`
+	}
+	_ = json.Unmarshal(c.Config, &cfg)
+
+	out, err := s.plugins.Run(r.Context(), cfg.Plugin, cfg.Options, localeFromRequest(r))
+	if errors.Is(err, plugins.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "plugin not installed")
+		return
+	}
+	if err != nil {
+		log.Printf("[plugins] %v", err)
+		writeError(w, http.StatusBadGateway, "plugin failed")
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"log/slog"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/morezhou/hearth/internal/store"
+	"github.com/morezhou/hearth/internal/widgets"
+)
+
+// maxHoldings bounds the portfolio; every position is a quote request upstream.
+const maxHoldings = 50
+
+func (s *Server) handleListHoldings(w http.ResponseWriter, r *http.Request) {
+	list, err := s.store.ListHoldings()
+	if err != nil {
+		slog.Error("failed to list holdings", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to list holdings")
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// handlePutHoldings replaces the whole portfolio with the posted list.
+func (s *Server) handlePutHoldings(w http.ResponseWriter, r *http.Request) {
+	var req []store.Holding
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if len(req) > maxHoldings {
+		writeError(w, http.StatusBadRequest, "too many holdings")
+		return
+	}
+	seen := map[string]bool{}
+	list := make([]store.Holding, 0, len(req))
+	for _, h := range req {
+		h.Symbol = strings.ToUpper(strings.TrimSpace(h.Symbol))
+		if h.Symbol == "" {
+			writeError(w, http.StatusBadRequest, "symbol required")
+			return
+		}
+		if seen[h.Symbol] {
+			writeError(w, http.StatusBadRequest, "duplicate symbol "+h.Symbol)
+			return
+		}
+		if !validAmount(h.Quantity) || !validAmount(h.CostBasis) {
+			writeError(w, http.StatusBadRequest, "quantity and costBasis must be non-negative numbers")
+			return
+		}
+		seen[h.Symbol] = true
+		list = append(list, h)
+	}
+	if err := s.store.ReplaceHoldings(list); err != nil {
+		slog.Error("failed to save holdings", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to save holdings")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+func validAmount(v float64) bool {
+	return v >= 0 && !math.IsInf(v, 0) && !math.IsNaN(v)
+}
+
+func (s *Server) handleGetPortfolio(w http.ResponseWriter, r *http.Request) {
+	currency := widgets.NormalizeCurrency(r.URL.Query().Get("currency"))
+	if currency == "" {
+		writeError(w, http.StatusBadRequest, "unsupported currency (use one of "+strings.Join(widgets.SupportedCurrencies(), ", ")+")")
+		return
+	}
+	list, err := s.store.ListHoldings()
+	if err != nil {
+		slog.Error("failed to list holdings", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to list holdings")
+		return
+	}
+	holdings := make([]widgets.Holding, 0, len(list))
+	for _, h := range list {
+		holdings = append(holdings, widgets.Holding{Symbol: h.Symbol, Quantity: h.Quantity, CostBasis: h.CostBasis})
+	}
+
+	var rates map[string]float64
+	if currency != widgets.CurrencyUSD {
+		if rates, err = widgets.FetchUSDRates(r.Context()); err != nil {
+			log.Printf("[markets] fx rates: %v", err)
+			currency = widgets.CurrencyUSD
+		}
+	}
+	res, err := widgets.FetchPortfolio(r.Context(), holdings, currency, rates, s.stockRouter())
+	if err != nil {
+		writeError(w, upstreamStatus(r.Context(), err, http.StatusBadGateway), err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/morezhou/hearth/internal/widgets"
+)
+
+const (
+	maxCustomQuotes     = 500
+	maxQuoteTextRunes   = 500
+	maxQuoteAuthorRunes = 100
+)
+
+// quoteDay is today in the dashboard's timezone, so the quote changes at the
+// viewer's midnight rather than UTC's.
+func (s *Server) quoteDay() time.Time {
+	loc, err := time.LoadLocation(normalizeIanaTimezone(s.getStringSetting(kvTimeTimezone, "Asia/Shanghai")))
+	if err != nil {
+		loc = time.UTC
+	}
+	return time.Now().In(loc)
+}
+
+// handleGetQuote handles GET /api/widgets/quote?source=bundled|quotable|custom.
+// Quotable and custom fall back to the bundled quotes when they have nothing
+// to offer; the response's source tells which one was used.
+func (s *Server) handleGetQuote(w http.ResponseWriter, r *http.Request) {
+	day := s.quoteDay()
+	switch widgets.NormalizeQuoteSource(r.URL.Query().Get("source")) {
+	case widgets.QuoteSourceQuotable:
+		q, err := widgets.FetchQuotableQuote(r.Context(), day)
+		if err == nil {
+			writeJSON(w, http.StatusOK, q)
+			return
+		}
+		log.Printf("[quote] quotable failed, using bundled quotes: %v", err)
+	case widgets.QuoteSourceCustom:
+		list, err := s.store.ListCustomQuotes()
+		if err != nil {
+			slog.Error("failed to list quotes", "error", err)
+		}
+		quotes := make([]widgets.Quote, 0, len(list))
+		for _, c := range list {
+			quotes = append(quotes, widgets.Quote{Text: c.Text, Author: c.Author, Source: widgets.QuoteSourceCustom})
+		}
+		if q, ok := widgets.DailyQuote(quotes, day); ok {
+			writeJSON(w, http.StatusOK, q)
+			return
+		}
+	}
+	q, ok := widgets.DailyQuote(widgets.BundledQuotes(localeFromRequest(r)), day)
+	if !ok {
+		writeError(w, http.StatusNotFound, "no quotes")
+		return
+	}
+	writeJSON(w, http.StatusOK, q)
+}
+
+// handleListQuotes handles GET /api/admin/quotes.
+func (s *Server) handleListQuotes(w http.ResponseWriter, r *http.Request) {
+	list, err := s.store.ListCustomQuotes()
+	if err != nil {
+		slog.Error("failed to list quotes", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to list quotes")
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// handleAddQuote handles POST /api/admin/quotes with {text, author}.
+func (s *Server) handleAddQuote(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Text   string `json:"text"`
+		Author string `json:"author"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	req.Text, req.Author = strings.TrimSpace(req.Text), strings.TrimSpace(req.Author)
+	if req.Text == "" {
+		writeError(w, http.StatusBadRequest, "text required")
+		return
+	}
+	if utf8.RuneCountInString(req.Text) > maxQuoteTextRunes || utf8.RuneCountInString(req.Author) > maxQuoteAuthorRunes {
+		writeError(w, http.StatusBadRequest, "quote too long")
+		return
+	}
+	if list, err := s.store.ListCustomQuotes(); err == nil && len(list) >= maxCustomQuotes {
+		writeError(w, http.StatusConflict, "too many quotes")
+		return
+	}
+	q, err := s.store.AddCustomQuote(req.Text, req.Author)
+	if err != nil {
+		slog.Error("failed to add quote", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to add quote")
+		return
+	}
+	writeJSON(w, http.StatusCreated, q)
+}
+
+// handleDeleteQuote handles DELETE /api/admin/quotes/{id}.
+func (s *Server) handleDeleteQuote(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	ok, err := s.store.DeleteCustomQuote(id)
+	if err != nil {
+		slog.Error("failed to delete quote", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to delete quote")
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
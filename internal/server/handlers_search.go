@@ -0,0 +1,202 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// SearchEngine is a search provider the search widget can send queries to.
+// URL is a template where {q} is replaced by the escaped query.
+type SearchEngine struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// SearchSettings configures the search widget and the /search redirect.
+type SearchSettings struct {
+	Engines       []SearchEngine `json:"engines"`
+	DefaultEngine string         `json:"defaultEngine"`
+	// Bangs map a shortcut ("gh" for "!gh query") to a URL template; engine
+	// IDs work as bangs too.
+	Bangs map[string]string `json:"bangs"`
+	// History lets browsers remember recent queries locally; nothing is
+	// stored on the server.
+	History *bool `json:"history,omitempty"`
+}
+
+var defaultSearchEngines = []SearchEngine{
+	{ID: "google", Name: "Google", URL: "https://www.google.com/search?q={q}"},
+	{ID: "ddg", Name: "DuckDuckGo", URL: "https://duckduckgo.com/?q={q}"},
+	{ID: "bing", Name: "Bing", URL: "https://www.bing.com/search?q={q}"},
+	{ID: "baidu", Name: "百度", URL: "https://www.baidu.com/s?wd={q}"},
+}
+
+var defaultSearchBangs = map[string]string{
+	"w":  "https://en.wikipedia.org/wiki/Special:Search?search={q}",
+	"gh": "https://github.com/search?q={q}",
+	"yt": "https://www.youtube.com/results?search_query={q}",
+}
+
+const maxSearchEngines = 20
+
+var searchIDRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// cleanSearchTemplate checks that tmpl is an http(s) URL with a {q}
+// placeholder.
+func cleanSearchTemplate(tmpl string) (string, bool) {
+	tmpl = strings.TrimSpace(tmpl)
+	if !strings.Contains(tmpl, "{q}") {
+		return "", false
+	}
+	u, err := url.Parse(strings.ReplaceAll(tmpl, "{q}", "q"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", false
+	}
+	return tmpl, true
+}
+
+// cleanSearchSettings validates engines and bangs. It fills in the default
+// engine with the first engine when unset or unknown.
+func cleanSearchSettings(st SearchSettings) (SearchSettings, error) {
+	if len(st.Engines) == 0 {
+		return SearchSettings{}, fmt.Errorf("at least one search engine is required")
+	}
+	if len(st.Engines) > maxSearchEngines {
+		return SearchSettings{}, fmt.Errorf("at most %d search engines", maxSearchEngines)
+	}
+	out := SearchSettings{Engines: make([]SearchEngine, 0, len(st.Engines)), Bangs: map[string]string{}, History: st.History}
+	for _, e := range st.Engines {
+		e.ID = strings.ToLower(strings.TrimSpace(e.ID))
+		e.Name = strings.TrimSpace(e.Name)
+		if !searchIDRe.MatchString(e.ID) {
+			return SearchSettings{}, fmt.Errorf("search engine id %q must be 1-32 lowercase letters, digits, - or _", e.ID)
+		}
+		if slices.ContainsFunc(out.Engines, func(o SearchEngine) bool { return o.ID == e.ID }) {
+			return SearchSettings{}, fmt.Errorf("duplicate search engine id %q", e.ID)
+		}
+		tmpl, ok := cleanSearchTemplate(e.URL)
+		if !ok {
+			return SearchSettings{}, fmt.Errorf("search engine %s: url must be http(s) and contain {q}", e.ID)
+		}
+		e.URL = tmpl
+		if e.Name == "" {
+			e.Name = e.ID
+		}
+		out.Engines = append(out.Engines, e)
+	}
+	for bang, tmpl := range st.Bangs {
+		bang = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(bang), "!"))
+		if !searchIDRe.MatchString(bang) {
+			return SearchSettings{}, fmt.Errorf("bang %q must be 1-32 lowercase letters, digits, - or _", bang)
+		}
+		clean, ok := cleanSearchTemplate(tmpl)
+		if !ok {
+			return SearchSettings{}, fmt.Errorf("bang !%s: url must be http(s) and contain {q}", bang)
+		}
+		out.Bangs[bang] = clean
+	}
+	out.DefaultEngine = strings.ToLower(strings.TrimSpace(st.DefaultEngine))
+	if !slices.ContainsFunc(out.Engines, func(e SearchEngine) bool { return e.ID == out.DefaultEngine }) {
+		out.DefaultEngine = out.Engines[0].ID
+	}
+	return out, nil
+}
+
+// searchSettings loads the search configuration, falling back to the built-in
+// engines and bangs.
+func (s *Server) searchSettings() SearchSettings {
+	st := SearchSettings{Engines: defaultSearchEngines, Bangs: defaultSearchBangs}
+	if raw := s.getStringSetting(kvSearchEngines, ""); raw != "" {
+		var engines []SearchEngine
+		if json.Unmarshal([]byte(raw), &engines) == nil && len(engines) > 0 {
+			st.Engines = engines
+		}
+	}
+	if raw := s.getStringSetting(kvSearchBangs, ""); raw != "" {
+		var bangs map[string]string
+		if json.Unmarshal([]byte(raw), &bangs) == nil {
+			st.Bangs = bangs
+		}
+	}
+	st.DefaultEngine = s.getStringSetting(kvSearchDefault, "")
+	history := s.getStringSetting(kvSearchHistory, "false") == "true"
+	st.History = &history
+	if clean, err := cleanSearchSettings(st); err == nil {
+		return clean
+	}
+	st.DefaultEngine = st.Engines[0].ID
+	return st
+}
+
+func (st SearchSettings) engineURL(id string) string {
+	for _, e := range st.Engines {
+		if e.ID == id {
+			return e.URL
+		}
+	}
+	return ""
+}
+
+// searchTarget resolves a query to the URL it redirects to. A "!bang" as the
+// first or last word picks the bang or engine with that ID; otherwise engine
+// (or the default engine) is used.
+func (st SearchSettings) searchTarget(q, engine string) string {
+	q = strings.TrimSpace(q)
+	words := strings.Fields(q)
+	tmpl := ""
+	lookup := func(bang string) string {
+		bang = strings.ToLower(bang)
+		if t, ok := st.Bangs[bang]; ok {
+			return t
+		}
+		return st.engineURL(bang)
+	}
+	if len(words) > 0 {
+		first, last := words[0], words[len(words)-1]
+		switch {
+		case len(first) > 1 && first[0] == '!':
+			if tmpl = lookup(first[1:]); tmpl != "" {
+				q = strings.Join(words[1:], " ")
+			}
+		case len(last) > 1 && last[0] == '!':
+			if tmpl = lookup(last[1:]); tmpl != "" {
+				q = strings.Join(words[:len(words)-1], " ")
+			}
+		}
+	}
+	if tmpl == "" {
+		tmpl = st.engineURL(engine)
+	}
+	if tmpl == "" {
+		tmpl = st.engineURL(st.DefaultEngine)
+	}
+	if tmpl == "" {
+		tmpl = st.Engines[0].URL
+	}
+	return strings.ReplaceAll(tmpl, "{q}", url.QueryEscape(q))
+}
+
+// handleGetSearchConfig handles GET /api/widgets/search-config.
+func (s *Server) handleGetSearchConfig(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.searchSettings())
+}
+
+// handleSearch handles GET /search?q=...[&engine=id], redirecting to the
+// engine or bang the query asks for. Browsers can use
+// "https://dashboard/search?q=%s" as a custom search engine.
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		http.Redirect(w, r, "/", http.StatusFound)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	http.Redirect(w, r, s.searchSettings().searchTarget(q, r.URL.Query().Get("engine")), http.StatusFound)
+}
@@ -3,48 +3,127 @@ package server
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"path"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/morezhou/hearth/internal/background"
+	"github.com/morezhou/hearth/internal/i18n"
+	"github.com/morezhou/hearth/internal/metrics"
+	"github.com/morezhou/hearth/internal/widgets"
 )
 
 const (
 	kvSiteTitle               = "settings.siteTitle"
-	kvLanguage                = "settings.language"            // "zh"|"en"
-	kvBackgroundProvider      = "settings.background.provider" // bing|picsum (unsplash kept for backward compatibility)
+	kvLanguage                = "settings.language"            // BCP-47 tag, e.g. "zh", "en", "de-AT"
+	kvBackgroundProvider      = "settings.background.provider" // bing|picsum|apod|wikimedia|chromecast|custom (unsplash kept for backward compatibility)
 	kvBackgroundUnsplashQuery = "settings.background.unsplash.query"
-	kvBackgroundInterval      = "settings.background.interval" // duration string, 0 means never auto refresh
-	kvTimezones               = "settings.timezones"           // JSON array
+	kvBackgroundInterval      = "settings.background.interval"   // duration string, 0 means never auto refresh
+	kvBackgroundBingMarket    = "settings.background.bingMarket" // one of background.BingMarkets
+	kvTimezones               = "settings.timezones"             // JSON array
 	kvWeatherCity             = "settings.weather.city"
+	kvWeatherCities           = "settings.weather.cities" // JSON array of extra locations
 	kvWeatherLat              = "settings.weather.lat"
 	kvWeatherLon              = "settings.weather.lon"
-	kvTimeEnabled             = "settings.time.enabled"     // "true"|"false"
-	kvTimeTimezone            = "settings.time.timezone"    // IANA timezone
-	kvTimeShowSeconds         = "settings.time.showSeconds" // "true"|"false"
-	kvTimeMode                = "settings.time.mode"        // digital|clock
-	kvTitleSortOrder          = "settings.title.sortOrder"  // int, position of title block among groups
+	kvWeatherProvider         = "settings.weather.provider" // open-meteo|openweathermap|metno
+	kvWeatherAPIKey           = "settings.weather.apiKey"   // API key for keyed providers
+	kvWeatherForecastDays     = "settings.weather.forecastDays"
+	kvWeatherFreshMinutes     = "settings.weather.cacheFreshMinutes"
+	kvWeatherMaxStaleMinutes  = "settings.weather.cacheMaxStaleMinutes"
+	kvWeatherAlerts           = "settings.weather.alerts"        // "true"|"false"
+	kvWeatherAlertWebhook     = "settings.weather.alertWebhook"  // optional URL notified of new alerts
+	kvTimeEnabled             = "settings.time.enabled"          // "true"|"false"
+	kvTimeTimezone            = "settings.time.timezone"         // IANA timezone
+	kvTimeShowSeconds         = "settings.time.showSeconds"      // "true"|"false"
+	kvTimeMode                = "settings.time.mode"             // digital|clock
+	kvMetricsHideCPUModel     = "settings.metrics.hideCpuModel"  // "true"|"false"
+	kvMetricsHideHostname     = "settings.metrics.hideHostname"  // "true"|"false"
+	kvMetricsBucketDisk       = "settings.metrics.bucketDisk"    // "true"|"false"
+	kvMetricsInterfaces       = "settings.metrics.interfaces"    // JSON array of interface globs
+	kvMetricsExcludeMounts    = "settings.metrics.excludeMounts" // JSON array of fs types or paths
+	kvMetricsContainers       = "settings.metrics.containers"    // JSON array of container name globs
+	kvMetricsDiskPaths        = "settings.metrics.diskPaths"     // JSON array of {path,label}
+	kvMarketsStockProvider    = "settings.markets.stockProvider" // stooq|finnhub|twelvedata|yahoo
+	kvMarketsFinnhubKey       = "settings.markets.finnhubKey"
+	kvMarketsTwelveDataKey    = "settings.markets.twelveDataKey"
+	kvMarketsRoutes           = "settings.markets.routes"        // JSON object: symbol or pattern -> provider
+	kvMarketsCryptoQuote      = "settings.markets.cryptoQuote"   // USDT|USDC|FDUSD|BTC|ETH|BNB|EUR
+	kvUnitsSystem             = "settings.units.system"          // metric|imperial
+	kvUnitsBytes              = "settings.units.bytes"           // binary|decimal
+	kvTitleSortOrder          = "settings.title.sortOrder"       // int, position of title block among groups
+	kvEmbedsAllowlist         = "settings.embeds.allowlist"      // JSON array of host globs
+	kvEmbedsFrames            = "settings.embeds.frames"         // JSON array of Embed
+	kvSearchEngines           = "settings.search.engines"        // JSON array of SearchEngine
+	kvSearchDefault           = "settings.search.default"        // engine ID
+	kvSearchBangs             = "settings.search.bangs"          // JSON object: bang -> URL template
+	kvSearchHistory           = "settings.search.history"        // "true"|"false"
+	kvThemeAccent             = "settings.theme.accent"          // #rrggbb
+	kvThemeMode               = "settings.theme.mode"            // light|dark|auto
+	kvThemeTileOpacity        = "settings.theme.tileOpacity"     // float, 0-1
+	kvThemeFontScale          = "settings.theme.fontScale"       // float, 0.75-1.5
+	kvNetworkInternalCIDRs    = "settings.network.internalCidrs" // JSON array of CIDRs
+	kvPinnedWidgets           = "settings.pinnedWidgets"         // JSON array of widget app IDs
 )
 
+// sensitiveSettings are encrypted at rest when a database key is configured.
+var sensitiveSettings = []string{kvWeatherAPIKey, kvWeatherAlertWebhook, kvMarketsFinnhubKey, kvMarketsTwelveDataKey}
+
 const defaultWeatherCity = "Shanghai, Shanghai, China"
 
 type Settings struct {
 	SiteTitle string `json:"siteTitle"`
 	Language  string `json:"language"`
+	// Formatting is derived from Language and ignored on PUT.
+	Formatting *i18n.Format `json:"formatting,omitempty"`
 
 	Background struct {
 		Provider      string `json:"provider"`
 		UnsplashQuery string `json:"unsplashQuery"`
 		Interval      string `json:"interval"`
+		BingMarket    string `json:"bingMarket"` // en-US, zh-CN, de-DE, ...
 	} `json:"background"`
 
 	Timezones []string `json:"timezones"`
 
 	Weather struct {
-		City string `json:"city"`
+		City         string   `json:"city"`
+		Cities       []string `json:"cities"`
+		Provider     string   `json:"provider"`
+		APIKey       string   `json:"apiKey"`
+		ForecastDays int      `json:"forecastDays"`
+		// Cache TTLs in minutes: how long a forecast is served without
+		// refetching, and how long it may be served when upstream fails.
+		CacheFreshMinutes    int    `json:"cacheFreshMinutes"`
+		CacheMaxStaleMinutes int    `json:"cacheMaxStaleMinutes"`
+		Alerts               *bool  `json:"alerts,omitempty"`
+		AlertWebhook         string `json:"alertWebhook"`
 	} `json:"weather"`
 
 	Time *TimeSettings `json:"time"`
 
+	Metrics *MetricsSettings `json:"metrics"`
+
+	Units *UnitsSettings `json:"units"`
+
+	Markets *MarketsSettings `json:"markets"`
+
+	Embeds *EmbedSettings `json:"embeds"`
+
+	Search *SearchSettings `json:"search"`
+
+	Theme *ThemeSettings `json:"theme"`
+
+	// Network is only returned to the admin.
+	Network *NetworkSettings `json:"network,omitempty"`
+
 	TitleSortOrder int `json:"titleSortOrder"` // Position of title block among groups, default 0 (top)
+
+	// PinnedWidgets are the IDs of the widgets kept in view; nil on PUT keeps
+	// the stored list.
+	PinnedWidgets []string `json:"pinnedWidgets"`
 }
 
 type TimeSettings struct {
@@ -54,6 +133,101 @@ type TimeSettings struct {
 	Mode        string `json:"mode"` // digital|clock
 }
 
+// MetricsSettings controls what the public host metrics endpoint reveals.
+// Admins always see the full payload.
+type MetricsSettings struct {
+	HideCPUModel bool `json:"hideCpuModel"`
+	HideHostname bool `json:"hideHostname"`
+	BucketDisk   bool `json:"bucketDisk"`
+	// Interfaces are glob patterns of the network interfaces reported; empty
+	// means all but loopback and container links.
+	Interfaces []string `json:"interfaces"`
+	// ExcludeMounts are filesystem types, or mountpoints when starting with
+	// "/", left out of the per-mount disk usage.
+	ExcludeMounts []string `json:"excludeMounts"`
+	// Containers are glob patterns of the Docker containers reported; empty
+	// means all running containers.
+	Containers []string `json:"containers"`
+	// DiskPaths are the labeled paths whose filesystems are reported instead
+	// of the discovered mounts; empty reports the mounts.
+	DiskPaths []metrics.DiskPath `json:"diskPaths"`
+}
+
+// UnitsSettings selects how measurements are reported by the widget APIs.
+type UnitsSettings struct {
+	System string `json:"system"` // metric|imperial (temperature, wind speed)
+	Bytes  string `json:"bytes"`  // binary (KiB, base 2)|decimal (kB, base 10)
+}
+
+// weatherOptions reads the forecast length and cache TTL settings.
+func (s *Server) weatherOptions() widgets.WeatherOptions {
+	set := s.settings()
+	return widgets.WeatherOptions{
+		ForecastDays: set.Int(kvWeatherForecastDays),
+		FreshTTL:     time.Duration(set.Int(kvWeatherFreshMinutes)) * time.Minute,
+		MaxStale:     time.Duration(set.Int(kvWeatherMaxStaleMinutes)) * time.Minute,
+	}.Normalized()
+}
+
+// MarketsSettings selects stock quote providers. API keys are only returned to
+// the admin.
+type MarketsSettings struct {
+	StockProvider string            `json:"stockProvider"` // default provider
+	FinnhubKey    string            `json:"finnhubKey"`
+	TwelveDataKey string            `json:"twelveDataKey"`
+	Routes        map[string]string `json:"routes"`      // "SAP.DE" or "*.HK" -> provider
+	CryptoQuote   string            `json:"cryptoQuote"` // default quote asset of crypto symbols
+}
+
+// normalizeCryptoQuote returns a supported crypto quote asset, defaulting to USDT.
+func normalizeCryptoQuote(v string) string {
+	if q, ok := widgets.NormalizeCryptoQuote(v); ok {
+		return q
+	}
+	return widgets.DefaultCryptoQuote
+}
+
+func normalizeStockProvider(v string) string {
+	switch v = strings.ToLower(strings.TrimSpace(v)); v {
+	case widgets.StockProviderFinnhub, widgets.StockProviderTwelveData, widgets.StockProviderYahoo:
+		return v
+	default:
+		return widgets.StockProviderStooq
+	}
+}
+
+// stockRouter builds the per-symbol stock provider routing from settings.
+func (s *Server) stockRouter() widgets.StockRouter {
+	var routes map[string]string
+	if raw := s.getStringSetting(kvMarketsRoutes, ""); raw != "" {
+		_ = json.Unmarshal([]byte(raw), &routes)
+	}
+	return widgets.NewStockRouter(
+		s.getStringSetting(kvMarketsStockProvider, widgets.StockProviderStooq),
+		widgets.StockAPIKeys{
+			Finnhub:    s.getStringSetting(kvMarketsFinnhubKey, ""),
+			TwelveData: s.getStringSetting(kvMarketsTwelveDataKey, ""),
+		},
+		routes,
+	)
+}
+
+func normalizeWeatherProvider(v string) string {
+	switch v {
+	case widgets.WeatherProviderOpenWeatherMap, widgets.WeatherProviderMetNo:
+		return v
+	default:
+		return widgets.WeatherProviderOpenMeteo
+	}
+}
+
+func normalizeByteUnits(v string) string {
+	if v == "decimal" {
+		return "decimal"
+	}
+	return "binary"
+}
+
 func normalizeIanaTimezone(tz string) string {
 	// Keep behavior consistent with the UI defaults.
 	const fallback = "Asia/Shanghai"
@@ -67,25 +241,38 @@ func normalizeIanaTimezone(tz string) string {
 }
 
 func (s *Server) handleGetSettings(w http.ResponseWriter, r *http.Request) {
+	set := s.settings()
 	st := Settings{}
-	st.SiteTitle = s.getStringSetting(kvSiteTitle, "My Home")
-	st.Language = s.getStringSetting(kvLanguage, "zh")
-	if st.Language != "zh" && st.Language != "en" {
-		st.Language = "zh"
-	}
-	st.Background.Provider = s.getStringSetting(kvBackgroundProvider, "default")
+	st.SiteTitle = set.String(kvSiteTitle)
+	st.Language = set.String(kvLanguage)
+	format := i18n.FormatFor(st.Language)
+	st.Formatting = &format
+	st.Background.Provider = set.String(kvBackgroundProvider)
 	if st.Background.Provider == "bing" {
 		st.Background.Provider = "bing_daily"
 	}
-	st.Background.UnsplashQuery = s.getStringSetting(kvBackgroundUnsplashQuery, "")
-	st.Background.Interval = s.getStringSetting(kvBackgroundInterval, "0")
-	st.Weather.City = s.getStringSetting(kvWeatherCity, defaultWeatherCity)
+	st.Background.UnsplashQuery = set.String(kvBackgroundUnsplashQuery)
+	st.Background.Interval = set.String(kvBackgroundInterval)
+	st.Background.BingMarket = set.String(kvBackgroundBingMarket)
+	st.Weather.City = set.String(kvWeatherCity)
+	st.Weather.Cities = s.weatherLocations()[1:]
+	st.Weather.Provider = set.String(kvWeatherProvider)
+	wopts := s.weatherOptions()
+	st.Weather.ForecastDays = wopts.ForecastDays
+	st.Weather.CacheFreshMinutes = int(wopts.FreshTTL / time.Minute)
+	st.Weather.CacheMaxStaleMinutes = int(wopts.MaxStale / time.Minute)
+	alerts := set.Bool(kvWeatherAlerts)
+	st.Weather.Alerts = &alerts
+	if isAdmin(r) {
+		// The webhook URL may embed credentials; only show it to the admin.
+		st.Weather.AlertWebhook = set.String(kvWeatherAlertWebhook)
+		st.Weather.APIKey = set.String(kvWeatherAPIKey)
+	}
 
 	st.Time = &TimeSettings{}
-	// default enabled=true for fresh installs
-	st.Time.Enabled = s.getStringSetting(kvTimeEnabled, "true") == "true"
-	st.Time.Timezone = normalizeIanaTimezone(s.getStringSetting(kvTimeTimezone, "Asia/Shanghai"))
-	st.Time.ShowSeconds = s.getStringSetting(kvTimeShowSeconds, "true") == "true"
+	st.Time.Enabled = set.Bool(kvTimeEnabled)
+	st.Time.Timezone = set.String(kvTimeTimezone)
+	st.Time.ShowSeconds = set.Bool(kvTimeShowSeconds)
 	// UI is digital-only.
 	st.Time.Mode = "digital"
 
@@ -96,26 +283,88 @@ func (s *Server) handleGetSettings(w http.ResponseWriter, r *http.Request) {
 		st.Timezones = []string{"Asia/Shanghai", "America/New_York"}
 	}
 
+	st.Metrics = &MetricsSettings{
+		HideCPUModel: set.Bool(kvMetricsHideCPUModel),
+		HideHostname: set.Bool(kvMetricsHideHostname),
+		BucketDisk:   set.Bool(kvMetricsBucketDisk),
+	}
+	opts := s.metricsOptions()
+	st.Metrics.Interfaces, st.Metrics.ExcludeMounts = opts.Interfaces, opts.ExcludeMounts
+	st.Metrics.DiskPaths = opts.DiskPaths
+	st.Metrics.Containers = s.dockerContainerAllowList()
+
+	st.Units = &UnitsSettings{
+		System: set.String(kvUnitsSystem),
+		Bytes:  set.String(kvUnitsBytes),
+	}
+
+	st.Markets = &MarketsSettings{
+		StockProvider: set.String(kvMarketsStockProvider),
+		Routes:        map[string]string{},
+		CryptoQuote:   set.String(kvMarketsCryptoQuote),
+	}
+	if raw := s.getStringSetting(kvMarketsRoutes, ""); raw != "" {
+		_ = json.Unmarshal([]byte(raw), &st.Markets.Routes)
+	}
+	if isAdmin(r) {
+		st.Markets.FinnhubKey = set.String(kvMarketsFinnhubKey)
+		st.Markets.TwelveDataKey = set.String(kvMarketsTwelveDataKey)
+	}
+
+	st.Embeds = s.embedSettings()
+	search := s.searchSettings()
+	st.Search = &search
+	theme := s.themeSettings()
+	st.Theme = &theme
+	if isAdmin(r) {
+		st.Network = &NetworkSettings{InternalCIDRs: s.internalCIDRs()}
+	}
+
 	// Title sort order (default 0 = at top)
-	st.TitleSortOrder = s.getIntSetting(kvTitleSortOrder, 0)
+	st.TitleSortOrder = set.Int(kvTitleSortOrder)
+
+	st.PinnedWidgets = []string{}
+	if raw := s.getStringSetting(kvPinnedWidgets, ""); raw != "" {
+		_ = json.Unmarshal([]byte(raw), &st.PinnedWidgets)
+	}
+
+	// Signed-in users see their own language, units, theme and pins.
+	applyUserSettings(&st, s.userOverrides(r))
 
 	writeJSON(w, http.StatusOK, st)
 }
 
+// maxSettingsBody bounds a PUT /api/settings body.
+const maxSettingsBody = 1 << 20
+
 func (s *Server) handlePutSettings(w http.ResponseWriter, r *http.Request) {
-	var req Settings
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxSettingsBody))
+	if err != nil {
 		writeError(w, http.StatusBadRequest, "invalid json")
 		return
 	}
+	// Every invalid field is reported at once, before anything is saved.
+	errs := validateSettingsJSON(body)
+	var req Settings
+	if err := json.Unmarshal(body, &req); err != nil {
+		if len(errs) > 0 && errs[""] == "" {
+			// A mistyped declared field, such as a fractional titleSortOrder.
+			writeFieldErrors(w, errs)
+		} else {
+			writeError(w, http.StatusBadRequest, "invalid json")
+		}
+		return
+	}
 	if req.SiteTitle == "" {
 		req.SiteTitle = "My Home"
 	}
 	if req.Language == "" {
 		req.Language = "zh"
 	}
-	if req.Language != "zh" && req.Language != "en" {
-		req.Language = "zh"
+	// Any language tag is accepted; server strings fall back to English for
+	// languages without a catalog.
+	if lang, ok := i18n.Normalize(req.Language); ok {
+		req.Language = lang
 	}
 	if req.Background.Provider == "" {
 		req.Background.Provider = "default"
@@ -131,36 +380,232 @@ func (s *Server) handlePutSettings(w http.ResponseWriter, r *http.Request) {
 		// UI is digital-only.
 		req.Time.Mode = "digital"
 	}
-	_ = s.store.SetKV(kvSiteTitle, req.SiteTitle)
-	_ = s.store.SetKV(kvLanguage, req.Language)
-	_ = s.store.SetKV(kvBackgroundProvider, req.Background.Provider)
-	_ = s.store.SetKV(kvBackgroundUnsplashQuery, req.Background.UnsplashQuery)
-	_ = s.store.SetKV(kvBackgroundInterval, req.Background.Interval)
+	// Embeds are validated up front so a rejected frame doesn't leave the
+	// other settings half saved.
+	var embedFrames []Embed
+	if req.Embeds != nil {
+		if req.Embeds.Allowlist != nil {
+			req.Embeds.Allowlist = trimmedList(req.Embeds.Allowlist)
+			for _, p := range req.Embeds.Allowlist {
+				if _, err := path.Match(p, ""); err != nil {
+					errs["embeds.allowlist"] = fmt.Sprintf("invalid allowlist pattern %q", p)
+				}
+			}
+		} else {
+			req.Embeds.Allowlist = s.embedAllowlist()
+		}
+		if req.Embeds.Frames != nil {
+			var err error
+			if embedFrames, err = cleanEmbeds(req.Embeds.Frames, req.Embeds.Allowlist); err != nil {
+				errs["embeds.frames"] = err.Error()
+			}
+		}
+	}
+	if req.Search != nil {
+		// Omitted engines or bangs keep the stored ones.
+		cur := s.searchSettings()
+		if req.Search.Engines == nil {
+			req.Search.Engines = cur.Engines
+		}
+		if req.Search.Bangs == nil {
+			req.Search.Bangs = cur.Bangs
+		}
+		if req.Search.DefaultEngine == "" {
+			req.Search.DefaultEngine = cur.DefaultEngine
+		}
+		if req.Search.History == nil {
+			req.Search.History = cur.History
+		}
+		if clean, err := cleanSearchSettings(*req.Search); err != nil {
+			errs["search"] = err.Error()
+		} else {
+			req.Search = &clean
+		}
+	}
+	if req.Theme != nil {
+		if clean, err := cleanThemeSettings(*req.Theme, s.themeSettings()); err != nil {
+			// The schema already names the field when it is a declared one.
+			if !errs.under("theme") {
+				errs["theme"] = err.Error()
+			}
+		} else {
+			if req.Theme.CustomCSS != nil {
+				css := sanitizeCustomCSS(*clean.CustomCSS, s.customCSSAllowsRemote())
+				clean.CustomCSS = &css
+			}
+			req.Theme = &clean
+		}
+	}
+	if req.Network != nil && req.Network.InternalCIDRs != nil {
+		if cidrs, err := cleanInternalCIDRs(req.Network.InternalCIDRs); err != nil {
+			errs["network.internalCidrs"] = err.Error()
+		} else {
+			req.Network.InternalCIDRs = cidrs
+		}
+	}
+	if req.PinnedWidgets != nil {
+		if ids, err := cleanPinnedWidgets(req.PinnedWidgets); err != nil {
+			errs["pinnedWidgets"] = err.Error()
+		} else {
+			req.PinnedWidgets = ids
+		}
+	}
+	if len(errs) > 0 {
+		writeFieldErrors(w, errs)
+		return
+	}
+	// Changes of the shared settings go to the settings history; per-user
+	// settings the user has overridden are saved as theirs.
+	kv := s.recordSettings(r)
+	setSetting := s.settingWriter(r, kv)
+	set := settingsService{kv: kv}
+	_ = kv.SetKV(kvSiteTitle, req.SiteTitle)
+	_ = setSetting(kvLanguage, req.Language)
+	_ = kv.SetKV(kvBackgroundProvider, req.Background.Provider)
+	_ = kv.SetKV(kvBackgroundUnsplashQuery, req.Background.UnsplashQuery)
+	_ = kv.SetKV(kvBackgroundInterval, req.Background.Interval)
+	_ = kv.SetKV(kvBackgroundBingMarket, background.NormalizeBingMarket(req.Background.BingMarket))
 
 	if b, err := json.Marshal(req.Timezones); err == nil {
-		_ = s.store.SetKV(kvTimezones, string(b))
+		_ = kv.SetKV(kvTimezones, string(b))
+	}
+	_ = kv.SetKV(kvWeatherCity, req.Weather.City)
+	if req.Weather.Cities != nil {
+		cities := make([]string, 0, len(req.Weather.Cities))
+		for _, c := range req.Weather.Cities {
+			if c = strings.TrimSpace(c); c != "" && len(cities) < maxWeatherLocations-1 {
+				cities = append(cities, c)
+			}
+		}
+		if b, err := json.Marshal(cities); err == nil {
+			_ = kv.SetKV(kvWeatherCities, string(b))
+		}
+	}
+	if req.Weather.Alerts != nil {
+		_ = set.Set(kvWeatherAlerts, *req.Weather.Alerts)
+	}
+	_ = kv.SetKV(kvWeatherAlertWebhook, strings.TrimSpace(req.Weather.AlertWebhook))
+	if req.Weather.Provider != "" {
+		_ = kv.SetKV(kvWeatherProvider, normalizeWeatherProvider(req.Weather.Provider))
+	}
+	_ = kv.SetKV(kvWeatherAPIKey, strings.TrimSpace(req.Weather.APIKey))
+	// Zero leaves the stored value alone.
+	if req.Weather.ForecastDays > 0 {
+		_ = set.Set(kvWeatherForecastDays, req.Weather.ForecastDays)
+	}
+	if req.Weather.CacheFreshMinutes > 0 {
+		_ = set.Set(kvWeatherFreshMinutes, req.Weather.CacheFreshMinutes)
+	}
+	if req.Weather.CacheMaxStaleMinutes > 0 {
+		_ = set.Set(kvWeatherMaxStaleMinutes, req.Weather.CacheMaxStaleMinutes)
 	}
-	_ = s.store.SetKV(kvWeatherCity, req.Weather.City)
 	// Keep DB clean: lat/lon are no longer used (city-only weather).
-	_ = s.store.SetKV(kvWeatherLat, "")
-	_ = s.store.SetKV(kvWeatherLon, "")
+	_ = kv.SetKV(kvWeatherLat, "")
+	_ = kv.SetKV(kvWeatherLon, "")
 	if req.Time != nil {
-		if req.Time.Enabled {
-			_ = s.store.SetKV(kvTimeEnabled, "true")
-		} else {
-			_ = s.store.SetKV(kvTimeEnabled, "false")
+		_ = set.Set(kvTimeEnabled, req.Time.Enabled)
+		_ = set.Set(kvTimeShowSeconds, req.Time.ShowSeconds)
+		_ = set.Set(kvTimeTimezone, req.Time.Timezone)
+		_ = kv.SetKV(kvTimeMode, "digital")
+	}
+
+	if req.Metrics != nil {
+		_ = set.Set(kvMetricsHideCPUModel, req.Metrics.HideCPUModel)
+		_ = set.Set(kvMetricsHideHostname, req.Metrics.HideHostname)
+		_ = set.Set(kvMetricsBucketDisk, req.Metrics.BucketDisk)
+		if req.Metrics.Interfaces != nil {
+			if b, err := json.Marshal(trimmedList(req.Metrics.Interfaces)); err == nil {
+				_ = kv.SetKV(kvMetricsInterfaces, string(b))
+			}
 		}
-		if req.Time.ShowSeconds {
-			_ = s.store.SetKV(kvTimeShowSeconds, "true")
-		} else {
-			_ = s.store.SetKV(kvTimeShowSeconds, "false")
+		if req.Metrics.Containers != nil {
+			if b, err := json.Marshal(trimmedList(req.Metrics.Containers)); err == nil {
+				_ = kv.SetKV(kvMetricsContainers, string(b))
+			}
+		}
+		if req.Metrics.ExcludeMounts != nil {
+			if b, err := json.Marshal(trimmedList(req.Metrics.ExcludeMounts)); err == nil {
+				_ = kv.SetKV(kvMetricsExcludeMounts, string(b))
+			}
+		}
+		if req.Metrics.DiskPaths != nil {
+			paths := make([]metrics.DiskPath, 0, len(req.Metrics.DiskPaths))
+			for _, d := range req.Metrics.DiskPaths {
+				if d, ok := metrics.CleanDiskPath(d); ok {
+					paths = append(paths, d)
+				}
+			}
+			if b, err := json.Marshal(paths); err == nil {
+				_ = kv.SetKV(kvMetricsDiskPaths, string(b))
+			}
+		}
+	}
+
+	if req.Units != nil {
+		_ = setSetting(kvUnitsSystem, widgets.NormalizeUnits(req.Units.System))
+		_ = setSetting(kvUnitsBytes, normalizeByteUnits(req.Units.Bytes))
+	}
+
+	if req.Markets != nil {
+		_ = kv.SetKV(kvMarketsStockProvider, normalizeStockProvider(req.Markets.StockProvider))
+		_ = kv.SetKV(kvMarketsFinnhubKey, strings.TrimSpace(req.Markets.FinnhubKey))
+		_ = kv.SetKV(kvMarketsTwelveDataKey, strings.TrimSpace(req.Markets.TwelveDataKey))
+		_ = kv.SetKV(kvMarketsCryptoQuote, normalizeCryptoQuote(req.Markets.CryptoQuote))
+		routes := map[string]string{}
+		for pattern, provider := range req.Markets.Routes {
+			if pattern = strings.ToUpper(strings.TrimSpace(pattern)); pattern != "" {
+				routes[pattern] = normalizeStockProvider(provider)
+			}
+		}
+		if b, err := json.Marshal(routes); err == nil {
+			_ = kv.SetKV(kvMarketsRoutes, string(b))
+		}
+	}
+
+	if req.Embeds != nil {
+		if b, err := json.Marshal(req.Embeds.Allowlist); err == nil {
+			_ = kv.SetKV(kvEmbedsAllowlist, string(b))
+		}
+		if embedFrames != nil {
+			if b, err := json.Marshal(embedFrames); err == nil {
+				_ = kv.SetKV(kvEmbedsFrames, string(b))
+			}
+		}
+	}
+
+	if req.Search != nil {
+		if b, err := json.Marshal(req.Search.Engines); err == nil {
+			_ = kv.SetKV(kvSearchEngines, string(b))
+		}
+		if b, err := json.Marshal(req.Search.Bangs); err == nil {
+			_ = kv.SetKV(kvSearchBangs, string(b))
+		}
+		_ = kv.SetKV(kvSearchDefault, req.Search.DefaultEngine)
+		_ = kv.SetKV(kvSearchHistory, boolString(req.Search.History != nil && *req.Search.History))
+	}
+
+	if req.Theme != nil {
+		_ = setSetting(kvThemeAccent, req.Theme.Accent)
+		_ = setSetting(kvThemeMode, req.Theme.Mode)
+		_ = setSetting(kvThemeTileOpacity, strconv.FormatFloat(*req.Theme.TileOpacity, 'f', -1, 64))
+		_ = setSetting(kvThemeFontScale, strconv.FormatFloat(*req.Theme.FontScale, 'f', -1, 64))
+		_ = kv.SetKV(kvCustomCSS, *req.Theme.CustomCSS)
+	}
+
+	if req.Network != nil && req.Network.InternalCIDRs != nil {
+		if b, err := json.Marshal(req.Network.InternalCIDRs); err == nil {
+			_ = kv.SetKV(kvNetworkInternalCIDRs, string(b))
 		}
-		_ = s.store.SetKV(kvTimeTimezone, req.Time.Timezone)
-		_ = s.store.SetKV(kvTimeMode, "digital")
 	}
 
 	// Save title sort order
-	_ = s.store.SetKV(kvTitleSortOrder, fmt.Sprintf("%d", req.TitleSortOrder))
+	_ = kv.SetKV(kvTitleSortOrder, fmt.Sprintf("%d", req.TitleSortOrder))
+
+	if req.PinnedWidgets != nil {
+		if b, err := json.Marshal(req.PinnedWidgets); err == nil {
+			_ = setSetting(kvPinnedWidgets, string(b))
+		}
+	}
 
 	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
 }
@@ -176,6 +621,13 @@ func (s *Server) getStringSetting(key, def string) string {
 	return v
 }
 
+func boolString(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
+
 func (s *Server) getIntSetting(key string, def int) int {
 	v, ok, err := s.store.GetKV(key)
 	if err != nil || !ok {
@@ -187,3 +639,15 @@ func (s *Server) getIntSetting(key string, def int) int {
 	}
 	return i
 }
+
+func (s *Server) getFloatSetting(key string, def float64) float64 {
+	v, ok, err := s.store.GetKV(key)
+	if err != nil || !ok {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return def
+	}
+	return f
+}
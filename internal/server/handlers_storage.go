@@ -0,0 +1,172 @@
+package server
+
+import (
+	"context"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/morezhou/hearth/internal/storage"
+)
+
+// Icon garbage collection.
+const (
+	iconGCInterval = 24 * time.Hour
+	// iconGCGrace spares recent icons: a resolved or uploaded icon is only
+	// referenced once the app using it is saved.
+	iconGCGrace = 24 * time.Hour
+)
+
+// iconGCReport is the outcome of one icon garbage collection.
+type iconGCReport struct {
+	Scanned int      `json:"scanned"`
+	Removed []string `json:"removed"`
+	Bytes   int64    `json:"bytes"` // freed, or that would be freed on a dry run
+	DryRun  bool     `json:"dryRun,omitempty"`
+}
+
+// collectIconGarbage deletes stored icons that no app or icon_cache row
+// references. Market icons are keyed by symbol and always kept.
+func (s *Server) collectIconGarbage(ctx context.Context, dryRun bool) (iconGCReport, error) {
+	rep := iconGCReport{Removed: []string{}, DryRun: dryRun}
+	// Read references after listing, so an icon saved in between is kept.
+	list, err := s.iconStore.List(ctx, "")
+	if err != nil {
+		return rep, err
+	}
+	refs, err := s.store.ReferencedIconPaths()
+	if err != nil {
+		return rep, err
+	}
+	cutoff := time.Now().Add(-iconGCGrace)
+	for _, obj := range list {
+		rep.Scanned++
+		if refs[obj.Key] || s.assetProxy.Owns(obj.Key) || obj.ModTime.After(cutoff) {
+			continue
+		}
+		if !dryRun {
+			if err := s.iconStore.Delete(ctx, obj.Key); err != nil {
+				return rep, err
+			}
+		}
+		rep.Removed = append(rep.Removed, obj.Key)
+		rep.Bytes += obj.Size
+	}
+	return rep, nil
+}
+
+// StartBackgroundJobs runs periodic maintenance and the background prefetch
+// scheduler until ctx is done or the server shuts down.
+func (s *Server) StartBackgroundJobs(ctx context.Context) {
+	context.AfterFunc(ctx, s.work.cancel)
+	s.syncAlertWebhook()
+	s.goWork(s.runBackgroundPrefetch)
+	s.goWork(s.runHostMetricsCollector)
+	s.goWork(s.runMetricsSampler)
+	s.goWork(s.runAgentPoller)
+	s.goWork(s.runWebhookDispatcher)
+	s.goWork(s.runAppProber)
+	s.goWork(s.runDBMaintenance)
+	s.goWork(s.runSessionCleanup)
+	s.goWork(s.runReplication)
+	s.goWork(s.runRemoteBackups)
+	s.goWork(func(ctx context.Context) {
+		t := time.NewTicker(iconGCInterval)
+		defer t.Stop()
+		for {
+			if rep, err := s.collectIconGarbage(ctx, false); err != nil {
+				slog.Warn("icon gc failed", "error", err)
+			} else if len(rep.Removed) > 0 {
+				slog.Info("icon gc", "removed", len(rep.Removed), "bytes", rep.Bytes)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+		}
+	})
+}
+
+// handleIconGC handles POST /api/admin/icons/gc[?dryRun=true].
+func (s *Server) handleIconGC(w http.ResponseWriter, r *http.Request) {
+	rep, err := s.collectIconGarbage(r.Context(), r.URL.Query().Get("dryRun") == "true")
+	if err != nil {
+		slog.Error("icon gc failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "icon gc failed")
+		return
+	}
+	writeJSON(w, http.StatusOK, rep)
+}
+
+// storageUsage is the disk use of one kind of data.
+type storageUsage struct {
+	Files int   `json:"files"`
+	Bytes int64 `json:"bytes"`
+}
+
+// handleGetStorage handles GET /api/admin/storage.
+func (s *Server) handleGetStorage(w http.ResponseWriter, r *http.Request) {
+	out := map[string]storageUsage{}
+	for name, st := range map[string]storage.Backend{"icons": s.iconStore, "cache": s.bgStore} {
+		list, err := st.List(r.Context(), "")
+		if err != nil {
+			slog.Warn("storage listing failed", "area", name, "error", err)
+			writeError(w, http.StatusBadGateway, "storage error")
+			return
+		}
+		var u storageUsage
+		for _, obj := range list {
+			u.Files++
+			u.Bytes += obj.Size
+		}
+		out[name] = u
+	}
+	out["backups"] = dirUsage(filepath.Join(s.cfg.DataDir, "backups"))
+	out["iconPacks"] = dirUsage(filepath.Join(s.cfg.DataDir, "iconpacks"))
+	out["geonames"] = dirUsage(filepath.Join(s.cfg.DataDir, "geonames"))
+	var db storageUsage
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		if fi, err := os.Stat(sqlitePath(s.cfg.DatabaseDSN) + suffix); err == nil {
+			db.Files++
+			db.Bytes += fi.Size()
+		}
+	}
+	out["database"] = db
+
+	var total int64
+	for _, u := range out {
+		total += u.Bytes
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"areas": out, "totalBytes": total})
+}
+
+// dirUsage sums the files below dir; a missing dir is empty.
+func dirUsage(dir string) storageUsage {
+	var u storageUsage
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if fi, err := d.Info(); err == nil {
+			u.Files++
+			u.Bytes += fi.Size()
+		}
+		return nil
+	})
+	return u
+}
+
+// sqlitePath extracts the database file from a DSN such as
+// "file:/data/hearth.db?_pragma=...".
+func sqlitePath(dsn string) string {
+	dsn = strings.TrimPrefix(dsn, "file:")
+	if i := strings.IndexByte(dsn, '?'); i >= 0 {
+		dsn = dsn[:i]
+	}
+	return dsn
+}
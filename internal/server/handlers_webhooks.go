@@ -0,0 +1,395 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/morezhou/hearth/internal/store"
+)
+
+// Events sent to webhooks.
+const (
+	eventAppDown             = "app.down"
+	eventAppUp               = "app.up"
+	eventBackgroundRefreshed = "background.refreshed"
+	eventImportCompleted     = "import.completed"
+	eventLoginNewIP          = "auth.login_new_ip"
+	eventWeatherAlert        = "weather.alert"
+	eventPing                = "ping" // sent by the test endpoint only
+)
+
+var webhookEvents = []string{eventAppDown, eventAppUp, eventBackgroundRefreshed, eventImportCompleted, eventLoginNewIP, eventWeatherAlert}
+
+const (
+	// webhookTick is how often the outbox is checked for due retries; new
+	// events wake the dispatcher right away.
+	webhookTick = 15 * time.Second
+	// maxWebhookAttempts is when a failing delivery is given up, after
+	// roughly an hour of backoff.
+	maxWebhookAttempts = 8
+	webhookBackoffBase = 30 * time.Second
+	webhookBackoffMax  = time.Hour
+	// webhookRetention is how long finished deliveries are listed.
+	webhookRetention = 7 * 24 * time.Hour
+	webhookBatch     = 50
+)
+
+// webhookEnvelope is the JSON body POSTed to webhooks.
+type webhookEnvelope struct {
+	ID    string    `json:"id"`
+	Event string    `json:"event"`
+	Time  time.Time `json:"time"`
+	Data  any       `json:"data"`
+}
+
+// emitEvent queues event for every enabled webhook subscribed to it. It
+// never fails the caller; problems are logged.
+func (s *Server) emitEvent(event string, data any) {
+	hooks, err := s.store.ListWebhooks()
+	if err != nil {
+		slog.Warn("webhook: list failed", "event", event, "error", err)
+		return
+	}
+	var payload []byte
+	for _, h := range hooks {
+		if !h.Subscribed(event) && !(event == eventPing && h.Enabled) {
+			continue
+		}
+		if payload == nil {
+			if payload, err = json.Marshal(webhookEnvelope{ID: uuid.NewString(), Event: event, Time: time.Now().UTC(), Data: data}); err != nil {
+				slog.Warn("webhook: encode failed", "event", event, "error", err)
+				return
+			}
+		}
+		if _, err := s.store.EnqueueWebhookDelivery(h.ID, event, payload); err != nil {
+			slog.Warn("webhook: enqueue failed", "event", event, "webhook", h.ID, "error", err)
+		}
+	}
+	if payload != nil {
+		select {
+		case s.webhookWake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// subscribedToAny reports whether an enabled webhook wants one of events.
+func (s *Server) subscribedToAny(events ...string) bool {
+	hooks, err := s.store.ListWebhooks()
+	if err != nil {
+		return false
+	}
+	for _, h := range hooks {
+		for _, e := range events {
+			if h.Subscribed(e) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// runWebhookDispatcher delivers queued events until ctx is done.
+func (s *Server) runWebhookDispatcher(ctx context.Context) {
+	t := time.NewTicker(webhookTick)
+	defer t.Stop()
+	lastPrune := time.Time{}
+	for {
+		s.deliverDueWebhooks(ctx, time.Now())
+		if time.Since(lastPrune) > time.Hour {
+			if _, err := s.store.PruneWebhookDeliveries(time.Now().Add(-webhookRetention).Unix()); err != nil {
+				slog.Warn("webhook: prune failed", "error", err)
+			}
+			lastPrune = time.Now()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		case <-s.webhookWake:
+		}
+	}
+}
+
+// deliverDueWebhooks attempts every delivery due at now.
+func (s *Server) deliverDueWebhooks(ctx context.Context, now time.Time) {
+	due, err := s.store.DueWebhookDeliveries(now.Unix(), webhookBatch)
+	if err != nil || len(due) == 0 {
+		if err != nil {
+			slog.Warn("webhook: load outbox failed", "error", err)
+		}
+		return
+	}
+	hooks, err := s.store.ListWebhooks()
+	if err != nil {
+		slog.Warn("webhook: list failed", "error", err)
+		return
+	}
+	byID := make(map[int64]store.Webhook, len(hooks))
+	for _, h := range hooks {
+		byID[h.ID] = h
+	}
+	for _, d := range due {
+		if ctx.Err() != nil {
+			return
+		}
+		h, ok := byID[d.WebhookID]
+		if !ok || !h.Enabled {
+			_ = s.store.RecordWebhookAttempt(d.ID, 0, "webhook disabled", 0)
+			continue
+		}
+		code, err := s.postWebhook(ctx, h, d)
+		next := int64(0)
+		msg := ""
+		if err != nil {
+			msg = err.Error()
+			if d.Attempts+1 < maxWebhookAttempts {
+				next = now.Add(webhookBackoff(d.Attempts + 1)).Unix()
+			} else {
+				slog.Warn("webhook: giving up", "webhook", h.ID, "event", d.Event, "error", err)
+			}
+		}
+		if err := s.store.RecordWebhookAttempt(d.ID, code, msg, next); err != nil {
+			slog.Warn("webhook: record attempt failed", "error", err)
+		}
+	}
+}
+
+// webhookBackoff is the delay before retry attempt n (1-based): 30s
+// doubling up to an hour.
+func webhookBackoff(n int) time.Duration {
+	d := webhookBackoffBase
+	for i := 1; i < n && d < webhookBackoffMax; i++ {
+		d *= 2
+	}
+	return min(d, webhookBackoffMax)
+}
+
+// signWebhook returns the X-Hearth-Signature of a body: "sha256=" and the
+// hex HMAC-SHA256 of the body keyed with the webhook secret.
+func signWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Server) postWebhook(ctx context.Context, h store.Webhook, d store.WebhookDelivery) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Hearth/0.1")
+	req.Header.Set("X-Hearth-Event", d.Event)
+	req.Header.Set("X-Hearth-Delivery", strconv.FormatInt(d.ID, 10))
+	if h.Secret != "" {
+		req.Header.Set("X-Hearth-Signature", signWebhook(h.Secret, d.Payload))
+	}
+	resp, err := s.webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// webhookView is a webhook as shown to the admin; the secret is write-only.
+type webhookView struct {
+	ID        int64    `json:"id"`
+	URL       string   `json:"url"`
+	Events    []string `json:"events"`
+	Enabled   bool     `json:"enabled"`
+	HasSecret bool     `json:"hasSecret"`
+	CreatedAt int64    `json:"createdAt"`
+	// Secret is only returned when it was generated by the server.
+	Secret string `json:"secret,omitempty"`
+}
+
+func newWebhookView(h store.Webhook) webhookView {
+	return webhookView{ID: h.ID, URL: h.URL, Events: h.Events, Enabled: h.Enabled, HasSecret: h.Secret != "", CreatedAt: h.CreatedAt}
+}
+
+type webhookRequest struct {
+	URL     string   `json:"url"`
+	Secret  string   `json:"secret"`
+	Events  []string `json:"events"`
+	Enabled *bool    `json:"enabled"`
+}
+
+// validate normalizes the request and returns a message for the first
+// invalid field.
+func (req *webhookRequest) validate() string {
+	req.URL = strings.TrimSpace(req.URL)
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "url must be an http(s) URL"
+	}
+	events := trimmedList(req.Events)
+	for _, e := range events {
+		if !slices.Contains(webhookEvents, e) {
+			return "unknown event " + e + " (want one of " + strings.Join(webhookEvents, ", ") + ")"
+		}
+	}
+	req.Events = events
+	req.Secret = strings.TrimSpace(req.Secret)
+	return ""
+}
+
+func webhookID(r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	return id, err == nil && id > 0
+}
+
+// handleListWebhooks handles GET /api/admin/webhooks.
+func (s *Server) handleListWebhooks(w http.ResponseWriter, r *http.Request) {
+	hooks, err := s.store.ListWebhooks()
+	if err != nil {
+		slog.Error("failed to list webhooks", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to list webhooks")
+		return
+	}
+	out := make([]webhookView, 0, len(hooks))
+	for _, h := range hooks {
+		out = append(out, newWebhookView(h))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"webhooks": out, "events": webhookEvents})
+}
+
+// handleCreateWebhook handles POST /api/admin/webhooks. Without a secret one
+// is generated and returned once.
+func (s *Server) handleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+	generated := req.Secret == ""
+	if generated {
+		b := make([]byte, 24)
+		_, _ = rand.Read(b)
+		req.Secret = hex.EncodeToString(b)
+	}
+	h, err := s.store.CreateWebhook(store.Webhook{URL: req.URL, Secret: req.Secret, Events: req.Events, Enabled: req.Enabled == nil || *req.Enabled})
+	if err != nil {
+		slog.Error("failed to create webhook", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to create webhook")
+		return
+	}
+	view := newWebhookView(h)
+	if generated {
+		view.Secret = h.Secret
+	}
+	writeJSON(w, http.StatusCreated, view)
+}
+
+// handleUpdateWebhook handles PUT /api/admin/webhooks/{id}. An empty secret
+// keeps the stored one.
+func (s *Server) handleUpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var req webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+	h, ok, err := s.store.GetWebhook(id)
+	if err != nil {
+		slog.Error("failed to load webhook", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to update webhook")
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	h.URL, h.Events = req.URL, req.Events
+	if req.Secret != "" {
+		h.Secret = req.Secret
+	}
+	if req.Enabled != nil {
+		h.Enabled = *req.Enabled
+	}
+	if _, err := s.store.UpdateWebhook(h); err != nil {
+		slog.Error("failed to update webhook", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to update webhook")
+		return
+	}
+	writeJSON(w, http.StatusOK, newWebhookView(h))
+}
+
+// handleDeleteWebhook handles DELETE /api/admin/webhooks/{id}.
+func (s *Server) handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	ok, err := s.store.DeleteWebhook(id)
+	if err != nil {
+		slog.Error("failed to delete webhook", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to delete webhook")
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+// handleListWebhookDeliveries handles GET /api/admin/webhooks/{id}/deliveries.
+func (s *Server) handleListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	list, err := s.store.ListWebhookDeliveries(id, 50)
+	if err != nil {
+		slog.Error("failed to list webhook deliveries", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to list deliveries")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"deliveries": list})
+}
+
+// handleTestWebhooks handles POST /api/admin/webhooks/test, queuing a ping
+// for every enabled webhook.
+func (s *Server) handleTestWebhooks(w http.ResponseWriter, r *http.Request) {
+	s.emitEvent(eventPing, map[string]any{})
+	writeJSON(w, http.StatusAccepted, map[string]any{"ok": true})
+}
@@ -1,63 +1,173 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
-	"os"
-	"path/filepath"
+	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/morezhou/hearth/internal/assetproxy"
 	"github.com/morezhou/hearth/internal/metrics"
 	"github.com/morezhou/hearth/internal/widgets"
 )
 
+// maxWeatherLocations caps how many locations a single batched request may ask for.
+const maxWeatherLocations = 8
+
+type weatherBatchItem struct {
+	Query   string           `json:"query"`
+	Weather *widgets.Weather `json:"weather,omitempty"`
+	Error   string           `json:"error,omitempty"`
+}
+
 func (s *Server) handleGetWeather(w http.ResponseWriter, r *http.Request) {
+	lang := localeFromRequest(r)
+	hourly := strings.EqualFold(strings.TrimSpace(r.URL.Query().Get("detail")), "hourly")
+	units := s.unitsSystem(r)
+
+	// Batched mode: ?cities=A|B (or repeated ?cities=), or ?batch=1 for the saved list.
+	if cities, ok := s.weatherBatchCities(r); ok {
+		if len(cities) == 0 {
+			writeError(w, http.StatusBadRequest, "cities required")
+			return
+		}
+		if len(cities) > maxWeatherLocations {
+			cities = cities[:maxWeatherLocations]
+		}
+		items := make([]weatherBatchItem, len(cities))
+		var wg sync.WaitGroup
+		for i, c := range cities {
+			wg.Add(1)
+			go func(i int, city string) {
+				defer wg.Done()
+				items[i].Query = city
+				wx, _, err := s.fetchWeather(r.Context(), city, "", "", lang, hourly)
+				if err != nil {
+					items[i].Error = err.Error()
+					return
+				}
+				wx = widgets.LocalizeWeather(widgets.ApplyUnits(wx, units), lang)
+				items[i].Weather = &wx
+			}(i, c)
+		}
+		wg.Wait()
+		writeJSON(w, http.StatusOK, map[string]any{"items": items})
+		return
+	}
+
 	lat := strings.TrimSpace(r.URL.Query().Get("lat"))
 	lon := strings.TrimSpace(r.URL.Query().Get("lon"))
 	city := strings.TrimSpace(r.URL.Query().Get("city"))
-	lang := strings.TrimSpace(r.URL.Query().Get("lang"))
 	if city == "" {
 		city = s.getStringSetting(kvWeatherCity, "")
 	}
+	wx, status, err := s.fetchWeather(r.Context(), city, lat, lon, lang, hourly)
+	if err != nil {
+		writeError(w, status, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, widgets.LocalizeWeather(widgets.ApplyUnits(wx, units), lang))
+}
+
+// unitsSystem returns the unit system for a request: ?units= overrides the
+// user's own setting, which overrides the shared one.
+func (s *Server) unitsSystem(r *http.Request) string {
+	if v := strings.TrimSpace(r.URL.Query().Get("units")); v != "" {
+		return widgets.NormalizeUnits(strings.ToLower(v))
+	}
+	return widgets.NormalizeUnits(s.userStringSetting(r, kvUnitsSystem, widgets.UnitsMetric))
+}
+
+// weatherBatchCities returns the requested location list and whether the
+// request is a batched one at all.
+func (s *Server) weatherBatchCities(r *http.Request) ([]string, bool) {
+	q := r.URL.Query()
+	if raw, ok := q["cities"]; ok {
+		out := make([]string, 0, len(raw))
+		for _, v := range raw {
+			// City labels contain commas ("Tokyo, Tokyo, Japan"), so split on | and ; only.
+			for _, c := range strings.FieldsFunc(v, func(r rune) bool { return r == '|' || r == ';' }) {
+				if c = strings.TrimSpace(c); c != "" {
+					out = append(out, c)
+				}
+			}
+		}
+		return out, true
+	}
+	if q.Get("batch") == "1" || strings.EqualFold(q.Get("batch"), "true") {
+		return s.weatherLocations(), true
+	}
+	return nil, false
+}
+
+// weatherLocations returns the primary weather city followed by any extra saved locations.
+func (s *Server) weatherLocations() []string {
+	out := []string{s.getStringSetting(kvWeatherCity, defaultWeatherCity)}
+	var extra []string
+	if raw := s.getStringSetting(kvWeatherCities, ""); raw != "" {
+		_ = json.Unmarshal([]byte(raw), &extra)
+	}
+	for _, c := range extra {
+		c = strings.TrimSpace(c)
+		if c == "" || c == out[0] {
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
 
+// fetchWeather geocodes city (unless lat/lon are given) and fetches its forecast.
+// The returned status is the HTTP status to use when err is non-nil.
+func (s *Server) fetchWeather(ctx context.Context, city, lat, lon, lang string, hourly bool) (widgets.Weather, int, error) {
 	cityLabel := city
+	countryCode := ""
 	if lat == "" || lon == "" {
-		pt, err := widgets.GeocodeCityLocalized(r.Context(), city, lang)
+		pt, err := widgets.GeocodeCityLocalized(ctx, city, lang)
 		if err != nil && strings.HasPrefix(strings.ToLower(lang), "zh") {
-			pt, err = widgets.GeocodeCityLocalized(r.Context(), city, "en")
+			pt, err = widgets.GeocodeCityLocalized(ctx, city, "en")
 		}
 		if err != nil {
-			writeError(w, http.StatusBadRequest, err.Error())
-			return
+			return widgets.Weather{}, upstreamStatus(ctx, err, http.StatusBadRequest), err
 		}
 		lat = fmt.Sprintf("%f", pt.Lat)
 		lon = fmt.Sprintf("%f", pt.Lon)
 		if strings.TrimSpace(pt.DisplayName) != "" {
 			cityLabel = pt.DisplayName
 		}
+		countryCode = pt.CountryCode
 	}
 
-	wx, err := widgets.FetchOpenMeteo(r.Context(), lat, lon, cityLabel)
+	provider := widgets.NewWeatherProvider(
+		s.getStringSetting(kvWeatherProvider, widgets.WeatherProviderOpenMeteo),
+		s.getStringSetting(kvWeatherAPIKey, ""),
+	)
+	wx, err := widgets.FetchWeather(ctx, provider, lat, lon, cityLabel, s.weatherOptions())
 	if err != nil {
-		msg := err.Error()
-		if strings.Contains(msg, "status=429") {
-			writeError(w, http.StatusTooManyRequests, msg)
-			return
+		if strings.Contains(err.Error(), "status=429") {
+			return widgets.Weather{}, http.StatusTooManyRequests, err
 		}
-		writeError(w, http.StatusBadRequest, msg)
-		return
+		return widgets.Weather{}, upstreamStatus(ctx, err, http.StatusBadRequest), err
 	}
-	writeJSON(w, http.StatusOK, wx)
+	// The intraday series is only sent on request (?detail=hourly) to keep the
+	// default payload small.
+	if !hourly {
+		wx.Hourly = nil
+	}
+	wx.Alerts = s.weatherAlertsFor(ctx, countryCode, cityLabel)
+	return wx, http.StatusOK, nil
 }
 
 func (s *Server) handleSearchCity(w http.ResponseWriter, r *http.Request) {
 	q := strings.TrimSpace(r.URL.Query().Get("query"))
-	lang := strings.TrimSpace(r.URL.Query().Get("lang"))
+	lang := localeFromRequest(r)
 	if q == "" {
 		q = strings.TrimSpace(r.URL.Query().Get("q"))
 	}
@@ -73,7 +183,7 @@ func (s *Server) handleSearchCity(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 	if err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		writeError(w, upstreamStatus(r.Context(), err, http.StatusBadRequest), err.Error())
 		return
 	}
 	type cityResult struct {
@@ -90,7 +200,7 @@ func (s *Server) handleSearchCity(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleGetCityTimezone(w http.ResponseWriter, r *http.Request) {
 	city := strings.TrimSpace(r.URL.Query().Get("city"))
-	lang := strings.TrimSpace(r.URL.Query().Get("lang"))
+	lang := localeFromRequest(r)
 	if city == "" {
 		city = strings.TrimSpace(r.URL.Query().Get("q"))
 	}
@@ -103,7 +213,7 @@ func (s *Server) handleGetCityTimezone(w http.ResponseWriter, r *http.Request) {
 		pt, err = widgets.GeocodeCityLocalized(r.Context(), city, "en")
 	}
 	if err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		writeError(w, upstreamStatus(r.Context(), err, http.StatusBadRequest), err.Error())
 		return
 	}
 	tz := strings.TrimSpace(pt.Timezone)
@@ -111,7 +221,7 @@ func (s *Server) handleGetCityTimezone(w http.ResponseWriter, r *http.Request) {
 		// Fallback path (older payloads / unexpected upstream changes).
 		tz, err = widgets.ResolveTimezone(r.Context(), fmt.Sprintf("%f", pt.Lat), fmt.Sprintf("%f", pt.Lon))
 		if err != nil {
-			writeError(w, http.StatusBadRequest, err.Error())
+			writeError(w, upstreamStatus(r.Context(), err, http.StatusBadRequest), err.Error())
 			return
 		}
 	}
@@ -123,22 +233,151 @@ func (s *Server) handleGetCityTimezone(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleGetTimezones(w http.ResponseWriter, r *http.Request) {
-	st := Settings{}
+	writeJSON(w, http.StatusOK, map[string]any{"timezones": s.savedTimezones()})
+}
+
+// savedTimezones returns the world clock zones from settings.
+func (s *Server) savedTimezones() []string {
+	var list []string
 	if tz := s.getStringSetting(kvTimezones, ""); tz != "" {
-		_ = json.Unmarshal([]byte(tz), &st.Timezones)
+		_ = json.Unmarshal([]byte(tz), &list)
+	}
+	if len(list) == 0 {
+		list = []string{"Asia/Shanghai", "America/New_York"}
+	}
+	return list
+}
+
+// handleGetClocks returns the current time, UTC offset and DST state of each
+// zone in ?timezones= (default: the saved list), relative to ?viewer= (default:
+// the clock widget's timezone).
+func (s *Server) handleGetClocks(w http.ResponseWriter, r *http.Request) {
+	zones := splitCSVish(strings.TrimSpace(r.URL.Query().Get("timezones")))
+	if len(zones) == 0 {
+		zones = s.savedTimezones()
+	}
+	viewer := strings.TrimSpace(r.URL.Query().Get("viewer"))
+	if viewer == "" {
+		viewer = normalizeIanaTimezone(s.getStringSetting(kvTimeTimezone, "Asia/Shanghai"))
+	}
+	res, err := widgets.WorldClocks(zones, viewer, time.Now())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// hostMetricsResponse is the metrics payload plus formatting hints so clients
+// render byte counts consistently with the configured units.
+type hostMetricsResponse struct {
+	metrics.HostMetrics
+	Format metricsFormat `json:"format"`
+}
+
+type metricsFormat struct {
+	ByteBase  int      `json:"byteBase"`  // 1024 (binary) or 1000 (decimal)
+	ByteUnits []string `json:"byteUnits"` // suffixes for successive powers of ByteBase
+	Units     string   `json:"units"`     // metric|imperial
+}
+
+func (s *Server) metricsFormat(r *http.Request) metricsFormat {
+	f := metricsFormat{
+		ByteBase:  1024,
+		ByteUnits: []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB"},
+		Units:     s.unitsSystem(r),
 	}
-	if len(st.Timezones) == 0 {
-		st.Timezones = []string{"Asia/Shanghai", "America/New_York"}
+	if normalizeByteUnits(s.userStringSetting(r, kvUnitsBytes, "binary")) == "decimal" {
+		f.ByteBase = 1000
+		f.ByteUnits = []string{"B", "kB", "MB", "GB", "TB", "PB"}
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"timezones": st.Timezones})
+	return f
 }
 
+// handleGetHostMetrics handles GET /api/metrics/host[?host=name]; host
+// selects a registered agent instead of this machine.
 func (s *Server) handleGetHostMetrics(w http.ResponseWriter, r *http.Request) {
-	m, err := metrics.Collect(r.Context())
+	var m metrics.HostMetrics
+	if host := r.URL.Query().Get("host"); host != "" && host != "local" {
+		var ok bool
+		var err error
+		m, ok, err = s.remoteHostMetrics(r.Context(), host)
+		switch {
+		case !ok && err == nil:
+			writeError(w, http.StatusNotFound, "unknown host")
+			return
+		case err != nil && m.CollectedAt == 0:
+			log.Printf("[metrics] agent %s: %v", host, err)
+			writeError(w, http.StatusBadGateway, "host unreachable")
+			return
+		}
+	} else {
+		m, _ = s.latestHostMetrics(r.Context())
+	}
+	if !isAdmin(r) {
+		m = m.Redact(metrics.Privacy{
+			HideCPUModel: s.getStringSetting(kvMetricsHideCPUModel, "false") == "true",
+			HideHostname: s.getStringSetting(kvMetricsHideHostname, "true") == "true",
+			BucketDisk:   s.getStringSetting(kvMetricsBucketDisk, "false") == "true",
+		})
+	}
+	writeJSON(w, http.StatusOK, hostMetricsResponse{HostMetrics: m, Format: s.metricsFormat(r)})
+}
+
+// metricsOptions returns the configured interface filter, mount excludes and
+// disk paths. Unset excludes default to metrics.DefaultExcludedMounts; an
+// empty list stored by the admin reports every mount. Unset disk paths fall
+// back to HEARTH_METRICS_DISK_PATHS.
+func (s *Server) metricsOptions() metrics.Options {
+	opts := metrics.Options{Interfaces: []string{}, ExcludeMounts: metrics.DefaultExcludedMounts, DiskPaths: s.cfg.MetricsDiskPaths, UPS: s.cfg.UPS}
+	if opts.DiskPaths == nil {
+		opts.DiskPaths = []metrics.DiskPath{}
+	}
+	if raw := s.getStringSetting(kvMetricsDiskPaths, ""); raw != "" {
+		_ = json.Unmarshal([]byte(raw), &opts.DiskPaths)
+	}
+	if raw := s.getStringSetting(kvMetricsInterfaces, ""); raw != "" {
+		_ = json.Unmarshal([]byte(raw), &opts.Interfaces)
+	}
+	if raw := s.getStringSetting(kvMetricsExcludeMounts, ""); raw != "" {
+		_ = json.Unmarshal([]byte(raw), &opts.ExcludeMounts)
+	}
+	return opts
+}
+
+// dockerContainerAllowList returns the configured container name globs.
+func (s *Server) dockerContainerAllowList() []string {
+	allow := []string{}
+	if raw := s.getStringSetting(kvMetricsContainers, ""); raw != "" {
+		_ = json.Unmarshal([]byte(raw), &allow)
+	}
+	return allow
+}
+
+// handleGetDockerMetrics handles GET /api/metrics/docker.
+func (s *Server) handleGetDockerMetrics(w http.ResponseWriter, r *http.Request) {
+	if s.docker == nil {
+		writeError(w, http.StatusServiceUnavailable, "docker metrics are disabled")
+		return
+	}
+	list, err := s.docker.Containers(r.Context(), s.dockerContainerAllowList())
 	if err != nil {
-		log.Printf("[metrics] Collect partial: %v", err)
+		log.Printf("[metrics] docker: %v", err)
+		writeError(w, http.StatusServiceUnavailable, "docker unavailable")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"containers": list})
+}
+
+// trimmedList drops blank entries and surrounding whitespace.
+func trimmedList(in []string) []string {
+	out := make([]string, 0, len(in))
+	for _, v := range in {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
 	}
-	writeJSON(w, http.StatusOK, m)
+	return out
 }
 
 func splitCSVish(s string) []string {
@@ -171,10 +410,57 @@ func (s *Server) handleGetMarkets(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	currency := widgets.NormalizeCurrency(r.URL.Query().Get("currency"))
+	if currency == "" {
+		writeError(w, http.StatusBadRequest, "unsupported currency (use one of "+strings.Join(widgets.SupportedCurrencies(), ", ")+")")
+		return
+	}
+
+	rng, ok := widgets.NormalizeMarketRange(r.URL.Query().Get("range"))
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid range (use 1d, 7d, 1m, 3m or 1y)")
+		return
+	}
+
+	// Per-widget refresh interval in seconds; clamped to 1 minute .. 1 hour.
+	var ttl time.Duration
+	if v := strings.TrimSpace(r.URL.Query().Get("refresh")); v != "" {
+		secs, err := strconv.Atoi(v)
+		if err != nil || secs <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid refresh (seconds)")
+			return
+		}
+		ttl = widgets.NormalizeMarketsTTL(time.Duration(secs) * time.Second)
+	}
+
+	// Default crypto quote asset: the widget's ?quote, else the settings value.
+	quote := strings.TrimSpace(r.URL.Query().Get("quote"))
+	if quote == "" {
+		quote = s.getStringSetting(kvMarketsCryptoQuote, "")
+	}
+	quote, ok = widgets.NormalizeCryptoQuote(quote)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "unsupported quote asset")
+		return
+	}
+
 	symbols := splitCSVish(raw)
-	res, err := widgets.FetchMarkets(r.Context(), symbols)
+	res, err := widgets.FetchMarkets(r.Context(), symbols, widgets.MarketOptions{Range: rng, Stocks: s.stockRouter(), TTL: ttl, CryptoQuote: quote})
 	if err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		writeError(w, upstreamStatus(r.Context(), err, http.StatusBadRequest), err.Error())
+		return
+	}
+	var rates map[string]float64
+	if currency != widgets.CurrencyUSD {
+		if rates, err = widgets.FetchUSDRates(r.Context()); err != nil {
+			// Prices stay usable in USD; the response currency tells the client.
+			log.Printf("[markets] fx rates: %v", err)
+			currency = widgets.CurrencyUSD
+		}
+	}
+	res, err = widgets.ConvertMarkets(res, currency, rates)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
 		return
 	}
 	writeJSON(w, http.StatusOK, res)
@@ -212,6 +498,24 @@ func normalizeMarketIconSymbol(raw string) string {
 	return strings.TrimSpace(string(out))
 }
 
+// assetMarkets is the asset proxy source of market icons.
+const assetMarkets = "markets"
+
+// marketIconSource allows PNG icons from the host of base only; without a
+// usable base URL nothing is fetched and only cached icons are served.
+func marketIconSource(base string) assetproxy.Source {
+	src := assetproxy.Source{Name: assetMarkets, Types: []string{"image/png"}, MaxBytes: 512 << 10}
+	u, err := url.Parse(strings.TrimSpace(base))
+	switch {
+	case strings.TrimSpace(base) == "":
+	case err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Hostname() == "" || u.User != nil:
+		log.Printf("[markets] ignoring invalid icon base url %q", base)
+	default:
+		src.Hosts = []string{u.Hostname()}
+	}
+	return src
+}
+
 func (s *Server) handleGetMarketIcon(w http.ResponseWriter, r *http.Request) {
 	sym := strings.TrimSpace(r.URL.Query().Get("symbol"))
 	if sym == "" {
@@ -223,129 +527,86 @@ func (s *Server) handleGetMarketIcon(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Serve from local cache if present.
-	localDir := filepath.Join(s.cfg.DataDir, "icons", "markets")
-	localPath := filepath.Join(localDir, norm+".png")
-	if st, err := os.Stat(localPath); err == nil && !st.IsDir() {
-		w.Header().Set("Content-Type", "image/png")
-		w.Header().Set("Cache-Control", "public, max-age=604800")
-		if r.Method == http.MethodHead {
-			w.WriteHeader(http.StatusOK)
-			return
+	var candidates []string
+	if base := strings.TrimRight(strings.TrimSpace(s.cfg.MarketIconBaseURL), "/"); base != "" {
+		candidates = []string{
+			fmt.Sprintf("%s/ticker_icons/%s.png", base, norm),
+			fmt.Sprintf("%s/crypto_icons/%s.png", base, norm),
 		}
-		http.ServeFile(w, r, localPath)
-		return
 	}
-
-	base := strings.TrimRight(strings.TrimSpace(s.cfg.MarketIconBaseURL), "/")
-	if base == "" {
+	a, err := s.assetProxy.Get(r.Context(), assetMarkets, norm+".png", candidates)
+	if a.Data == nil {
+		if err != nil && !errors.Is(err, assetproxy.ErrNotFound) && r.Context().Err() == nil {
+			log.Printf("[markets] icon %s: %v", norm, err)
+		}
 		http.NotFound(w, r)
 		return
 	}
-
-	if err := os.MkdirAll(localDir, 0o755); err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-
-	client := &http.Client{Timeout: 8 * time.Second}
-	candidates := []string{
-		fmt.Sprintf("%s/ticker_icons/%s.png", base, norm),
-		fmt.Sprintf("%s/crypto_icons/%s.png", base, norm),
+	if err != nil {
+		log.Printf("[markets] cache icon %s: %v", norm, err)
 	}
 
+	w.Header().Set("Content-Type", a.ContentType)
+	w.Header().Set("Cache-Control", "public, max-age=604800")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Length", strconv.Itoa(len(a.Data)))
 	if r.Method == http.MethodHead {
-		for _, url := range candidates {
-			req, err := http.NewRequestWithContext(r.Context(), http.MethodHead, url, nil)
-			if err != nil {
-				continue
-			}
-			resp, err := client.Do(req)
-			if err != nil {
-				continue
-			}
-			resp.Body.Close()
-			if resp.StatusCode == http.StatusOK {
-				w.Header().Set("Content-Type", "image/png")
-				w.Header().Set("Cache-Control", "public, max-age=300")
-				w.WriteHeader(http.StatusOK)
-				return
-			}
-		}
-		http.NotFound(w, r)
+		w.WriteHeader(http.StatusOK)
 		return
 	}
+	_, _ = w.Write(a.Data)
+}
 
-	var body []byte
-	for _, url := range candidates {
-		req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, url, nil)
-		if err != nil {
-			continue
-		}
-		req.Header.Set("Accept", "image/png,image/*;q=0.9,*/*;q=0.1")
-		resp, err := client.Do(req)
-		if err != nil {
-			continue
-		}
-		func() {
-			defer resp.Body.Close()
-			if resp.StatusCode != http.StatusOK {
-				return
-			}
-			ct := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Type")))
-			if ct != "" && !strings.HasPrefix(ct, "image/") {
-				return
-			}
-			b, err := io.ReadAll(io.LimitReader(resp.Body, 2*1024*1024))
-			if err != nil {
-				return
-			}
-			// Very small guard: PNG signature.
-			if len(b) < 8 || string(b[:4]) != "\x89PNG" {
-				return
-			}
-			body = b
-		}()
-		if len(body) > 0 {
-			break
-		}
+func (s *Server) handleGetHolidays(w http.ResponseWriter, r *http.Request) {
+	raw := strings.TrimSpace(r.URL.Query().Get("countries"))
+	if raw == "" {
+		raw = strings.TrimSpace(r.URL.Query().Get("c"))
 	}
-
-	if len(body) == 0 {
-		http.NotFound(w, r)
+	events := s.customEvents()
+	if raw == "" && len(events) == 0 {
+		writeError(w, http.StatusBadRequest, "countries required")
 		return
 	}
 
-	// Atomic write.
-	tmp := localPath + ".tmp"
-	if err := os.WriteFile(tmp, body, 0o644); err == nil {
-		_ = os.Rename(tmp, localPath)
-	} else {
-		_ = os.Remove(tmp)
+	countries := splitCSVish(raw)
+	res, err := widgets.UpcomingPublicHolidays(r.Context(), countries, events, time.Now(), 4)
+	if err != nil {
+		writeError(w, upstreamStatus(r.Context(), err, http.StatusBadRequest), err.Error())
+		return
 	}
-
-	w.Header().Set("Content-Type", "image/png")
-	w.Header().Set("Cache-Control", "public, max-age=604800")
-	_, _ = w.Write(body)
+	res.Items = widgets.LocalizeHolidays(res.Items, localeFromRequest(r))
+	res.Calendars = widgets.LocalizeWorkCalendars(res.Calendars, localeFromRequest(r))
+	writeJSON(w, http.StatusOK, res)
 }
 
-func (s *Server) handleGetHolidays(w http.ResponseWriter, r *http.Request) {
+// handleGetHolidaysICS serves the coming year's holidays and custom events as
+// an iCalendar feed for calendar subscriptions. ?events=false leaves out the
+// custom events.
+func (s *Server) handleGetHolidaysICS(w http.ResponseWriter, r *http.Request) {
 	raw := strings.TrimSpace(r.URL.Query().Get("countries"))
 	if raw == "" {
 		raw = strings.TrimSpace(r.URL.Query().Get("c"))
 	}
-	if raw == "" {
+	var events []widgets.CustomEvent
+	if v, err := strconv.ParseBool(r.URL.Query().Get("events")); err != nil || v {
+		events = s.customEvents()
+	}
+	if raw == "" && len(events) == 0 {
 		writeError(w, http.StatusBadRequest, "countries required")
 		return
 	}
 
-	countries := splitCSVish(raw)
-	res, err := widgets.UpcomingPublicHolidays(r.Context(), countries, time.Now(), 4)
+	now := time.Now()
+	list, err := widgets.CalendarHolidays(r.Context(), splitCSVish(raw), events, now)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		writeError(w, upstreamStatus(r.Context(), err, http.StatusBadGateway), err.Error())
 		return
 	}
-	writeJSON(w, http.StatusOK, res)
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="holidays.ics"`)
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(widgets.RenderHolidaysICS(list, localeFromRequest(r), now))
 }
 
 func (s *Server) handleListHolidayCountries(w http.ResponseWriter, r *http.Request) {
@@ -383,3 +644,27 @@ func (s *Server) handleListHolidayCountries(w http.ResponseWriter, r *http.Reque
 	}
 	writeJSON(w, http.StatusOK, map[string]any{"results": results})
 }
+
+// handleGetSMARTMetrics handles GET /api/metrics/smart. Serial numbers are
+// only shown to admins.
+func (s *Server) handleGetSMARTMetrics(w http.ResponseWriter, r *http.Request) {
+	if s.smart == nil {
+		writeError(w, http.StatusServiceUnavailable, "smart metrics are disabled")
+		return
+	}
+	drives, err := s.smart.Drives(r.Context())
+	if err != nil {
+		log.Printf("[metrics] smartctl: %v", err)
+		writeError(w, http.StatusServiceUnavailable, "smartctl unavailable")
+		return
+	}
+	if !isAdmin(r) {
+		redacted := make([]metrics.Drive, len(drives))
+		for i, d := range drives {
+			d.Serial = ""
+			redacted[i] = d
+		}
+		drives = redacted
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"drives": drives})
+}
@@ -0,0 +1,214 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+
+	"github.com/morezhou/hearth/internal/widgets"
+)
+
+const (
+	// healthMinFreeDisk is the free space below which the data directory
+	// counts as full: SQLite and the caches can't write anymore soon after.
+	healthMinFreeDisk = 100 << 20
+	// upstreamHealthTTL is how long upstream reachability is reused, so that
+	// polling the detailed health doesn't hammer the third-party APIs.
+	upstreamHealthTTL = 5 * time.Minute
+	upstreamTimeout   = 5 * time.Second
+)
+
+// healthCheck is the outcome of one dependency check.
+type healthCheck struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// diskCheck reports the free space of the data directory's filesystem.
+type diskCheck struct {
+	healthCheck
+	FreeBytes    uint64  `json:"freeBytes"`
+	UsedPct      float64 `json:"usedPercent"`
+	MinFreeBytes uint64  `json:"minFreeBytes"`
+}
+
+// upstreamCheck reports whether an upstream API answered at all; any HTTP
+// response counts, only network errors and timeouts don't.
+type upstreamCheck struct {
+	healthCheck
+	LatencyMs int64     `json:"latencyMs"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// upstreamHealth caches the latest upstream reachability results.
+type upstreamHealth struct {
+	mu      sync.Mutex
+	results map[string]upstreamCheck
+	at      time.Time
+}
+
+// checkDatabase pings the database.
+func (s *Server) checkDatabase() healthCheck {
+	if err := s.store.Ping(); err != nil {
+		return healthCheck{Error: "database unreachable"}
+	}
+	return healthCheck{OK: true}
+}
+
+// checkDataDir verifies the data directory accepts new files.
+func (s *Server) checkDataDir() healthCheck {
+	f, err := os.CreateTemp(s.cfg.DataDir, ".health-*")
+	if err != nil {
+		return healthCheck{Error: "data directory is not writable"}
+	}
+	name := f.Name()
+	_ = f.Close()
+	_ = os.Remove(name)
+	return healthCheck{OK: true}
+}
+
+// checkDisk reports the free space left for the data directory.
+func (s *Server) checkDisk(ctx context.Context) diskCheck {
+	c := diskCheck{MinFreeBytes: healthMinFreeDisk}
+	u, err := disk.UsageWithContext(ctx, s.cfg.DataDir)
+	if err != nil {
+		c.Error = "disk usage unavailable"
+		return c
+	}
+	c.FreeBytes, c.UsedPct = u.Free, u.UsedPercent
+	c.OK = u.Free >= healthMinFreeDisk
+	if !c.OK {
+		c.Error = "low disk space"
+	}
+	return c
+}
+
+// checkUpstreams returns the reachability of the critical upstreams,
+// probing them again once the cached results are older than
+// upstreamHealthTTL. Concurrent callers share one round of probes.
+func (s *Server) checkUpstreams(ctx context.Context) map[string]upstreamCheck {
+	h := &s.upstreamHealth
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.results != nil && time.Since(h.at) < upstreamHealthTTL {
+		return h.results
+	}
+
+	client := &http.Client{Timeout: upstreamTimeout}
+	targets := widgets.CriticalUpstreams()
+	results := make(map[string]upstreamCheck, len(targets))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, base := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c := probeUpstream(ctx, client, base)
+			mu.Lock()
+			results[name] = c
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		// Don't cache failures caused by the caller going away.
+		return results
+	}
+	h.results, h.at = results, time.Now()
+	return results
+}
+
+func probeUpstream(ctx context.Context, client *http.Client, base string) upstreamCheck {
+	c := upstreamCheck{CheckedAt: time.Now()}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, base, nil)
+	if err != nil {
+		c.Error = err.Error()
+		return c
+	}
+	req.Header.Set("User-Agent", "Hearth/"+Version)
+	start := time.Now()
+	resp, err := client.Do(req)
+	c.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		c.Error = "unreachable"
+		return c
+	}
+	resp.Body.Close()
+	c.OK = true
+	return c
+}
+
+// handleLiveness handles GET /api/health/live: the process is up and serving
+// requests. Orchestrators restart the container when it fails.
+func (s *Server) handleLiveness(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "version": Version})
+}
+
+// handleReadiness handles GET /api/health/ready: the database answers and the
+// data directory can be written to, so requests can be served. Upstream
+// outages don't make the instance unready; widgets fall back to caches.
+func (s *Server) handleReadiness(w http.ResponseWriter, r *http.Request) {
+	db := s.checkDatabase()
+	dataDir := s.checkDataDir()
+	dsk := s.checkDisk(r.Context())
+	ok := db.OK && dataDir.OK && dsk.OK
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, map[string]any{
+		"ok":      ok,
+		"version": Version,
+		"checks":  map[string]any{"database": db, "dataDir": dataDir, "disk": dsk},
+	})
+}
+
+// handleHealth handles GET /api/health[?detail=true]. Without detail it only
+// checks the database; with detail it runs the readiness checks and reports
+// the upstream APIs, which mark the instance degraded but not unhealthy.
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	db := s.checkDatabase()
+	if r.URL.Query().Get("detail") != "true" {
+		status := http.StatusOK
+		if !db.OK {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, map[string]any{
+			"ok":       db.OK,
+			"version":  Version,
+			"database": db.OK,
+		})
+		return
+	}
+
+	dataDir := s.checkDataDir()
+	dsk := s.checkDisk(r.Context())
+	upstreams := s.checkUpstreams(r.Context())
+	ok := db.OK && dataDir.OK && dsk.OK
+	degraded := false
+	for _, c := range upstreams {
+		if !c.OK {
+			degraded = true
+		}
+	}
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, map[string]any{
+		"ok":       ok,
+		"degraded": degraded,
+		"version":  Version,
+		"database": db.OK,
+		"checks": map[string]any{
+			"database":  db,
+			"dataDir":   dataDir,
+			"disk":      dsk,
+			"upstreams": upstreams,
+		},
+	})
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/morezhou/hearth/internal/metrics"
+)
+
+const defaultMetricsCollectInterval = 2 * time.Second
+
+// hostSnapshot is the latest local host metrics reading, shared by every
+// request so that viewers don't each pay for a blocking CPU sample.
+type hostSnapshot struct {
+	collectMu sync.Mutex // serializes collections
+
+	mu      sync.Mutex
+	metrics metrics.HostMetrics
+	err     error
+	at      time.Time
+}
+
+func (h *hostSnapshot) get() (metrics.HostMetrics, error, time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.metrics, h.err, h.at
+}
+
+func (h *hostSnapshot) set(m metrics.HostMetrics, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.metrics, h.err, h.at = m, err, time.Now()
+}
+
+// runHostMetricsCollector refreshes the host metrics snapshot every
+// cfg.MetricsCollectInterval.
+func (s *Server) runHostMetricsCollector(ctx context.Context) {
+	t := time.NewTicker(s.cfg.MetricsCollectInterval)
+	defer t.Stop()
+	for {
+		s.refreshHostMetrics(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (s *Server) refreshHostMetrics(ctx context.Context) {
+	s.hostSnapshot.collectMu.Lock()
+	defer s.hostSnapshot.collectMu.Unlock()
+	m, err := s.hostMetrics.Collect(ctx, s.metricsOptions())
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("[metrics] Collect partial: %v", err)
+	}
+	s.hostSnapshot.set(m, err)
+}
+
+// latestHostMetrics returns the snapshot, collecting one first when it is
+// missing or stale (the collector isn't running, or fell behind). Concurrent
+// callers share that collection.
+func (s *Server) latestHostMetrics(ctx context.Context) (metrics.HostMetrics, error) {
+	fresh := func() (metrics.HostMetrics, error, bool) {
+		m, err, at := s.hostSnapshot.get()
+		return m, err, !at.IsZero() && time.Since(at) < 2*s.cfg.MetricsCollectInterval
+	}
+	if m, err, ok := fresh(); ok {
+		return m, err
+	}
+	s.hostSnapshot.collectMu.Lock()
+	m, err, ok := fresh()
+	s.hostSnapshot.collectMu.Unlock()
+	if ok {
+		return m, err
+	}
+	s.refreshHostMetrics(ctx)
+	m, err, _ = s.hostSnapshot.get()
+	return m, err
+}
@@ -10,6 +10,13 @@ type apiError struct {
 	Error string `json:"error"`
 }
 
+// apiFieldErrors is a 400 response naming the invalid fields of a request
+// by their dotted JSON path.
+type apiFieldErrors struct {
+	Error  string            `json:"error"`
+	Fields map[string]string `json:"fields"`
+}
+
 func writeJSON(w http.ResponseWriter, status int, v any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
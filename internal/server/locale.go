@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/morezhou/hearth/internal/i18n"
+)
+
+const ctxLocale ctxKey = "locale"
+
+const defaultLocale = "zh"
+
+// withLocale resolves the effective locale for the request and stores it in
+// the context. Precedence: ?lang= > the user's language > saved language
+// setting > Accept-Language > default.
+// The locale is always one with an i18n catalog, so "de-AT" resolves to "de".
+func (s *Server) withLocale(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		loc := ""
+		if q := strings.TrimSpace(r.URL.Query().Get("lang")); q != "" {
+			loc = matchLocale(q)
+		}
+		if loc == "" {
+			if v := s.userStringSetting(r, kvLanguage, ""); v != "" {
+				loc = matchLocale(v)
+			}
+		}
+		if loc == "" {
+			loc = negotiateLocale(r.Header.Get("Accept-Language"))
+		}
+		if loc == "" {
+			loc = defaultLocale
+		}
+		w.Header().Add("Vary", "Accept-Language")
+		w.Header().Set("Content-Language", loc)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxLocale, loc)))
+	})
+}
+
+// localeFromRequest returns the negotiated locale, falling back to the raw
+// ?lang= value for handlers mounted outside the locale middleware.
+func localeFromRequest(r *http.Request) string {
+	if v, ok := r.Context().Value(ctxLocale).(string); ok && v != "" {
+		return v
+	}
+	if loc := matchLocale(r.URL.Query().Get("lang")); loc != "" {
+		return loc
+	}
+	return defaultLocale
+}
+
+// matchLocale maps a language tag (e.g. "zh-CN", "en_US") to the locale of an
+// i18n catalog, or "" when none matches.
+func matchLocale(tag string) string {
+	return i18n.Match(tag)
+}
+
+// negotiateLocale picks the best supported locale from an Accept-Language header.
+func negotiateLocale(header string) string {
+	type candidate struct {
+		tag string
+		q   float64
+	}
+	var cands []candidate
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.TrimSpace(fields[0])
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		for _, f := range fields[1:] {
+			f = strings.TrimSpace(f)
+			if strings.HasPrefix(f, "q=") {
+				if v, err := strconv.ParseFloat(strings.TrimPrefix(f, "q="), 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		cands = append(cands, candidate{tag: tag, q: q})
+	}
+	sort.SliceStable(cands, func(i, j int) bool { return cands[i].q > cands[j].q })
+	for _, c := range cands {
+		if loc := matchLocale(c.tag); loc != "" {
+			return loc
+		}
+	}
+	return ""
+}
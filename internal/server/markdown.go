@@ -0,0 +1,193 @@
+package server
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxDescriptionLen bounds an app's description, in characters.
+const maxDescriptionLen = 2000
+
+// normalizeAppDescription trims the markdown description of an app; an empty
+// one is dropped. Widgets keep their JSON config there instead.
+func normalizeAppDescription(req *createAppRequest) string {
+	if req.Description == nil {
+		return ""
+	}
+	desc := strings.TrimSpace(strings.ReplaceAll(*req.Description, "\r\n", "\n"))
+	if desc == "" {
+		req.Description = nil
+		return ""
+	}
+	if utf8.RuneCountInString(desc) > maxDescriptionLen {
+		return fmt.Sprintf("description must be at most %d characters", maxDescriptionLen)
+	}
+	req.Description = &desc
+	return ""
+}
+
+// renderMarkdown renders the markdown subset of app descriptions to HTML:
+// paragraphs, "-" and "*" lists, **bold**, *italic*, `code` and
+// [links](https://...). All text is escaped and only those elements are
+// produced, so the result is safe to insert as is; links other than http(s),
+// mailto and same-site ones render as plain text.
+func renderMarkdown(src string) string {
+	var b strings.Builder
+	var para []string
+	inList := false
+	flushPara := func() {
+		if len(para) > 0 {
+			b.WriteString("<p>")
+			for i, line := range para {
+				if i > 0 {
+					b.WriteString("<br>")
+				}
+				b.WriteString(renderInline(line))
+			}
+			b.WriteString("</p>")
+			para = nil
+		}
+	}
+	closeList := func() {
+		if inList {
+			b.WriteString("</ul>")
+			inList = false
+		}
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			flushPara()
+			closeList()
+			continue
+		}
+		if item, ok := listItem(trimmed); ok {
+			flushPara()
+			if !inList {
+				b.WriteString("<ul>")
+				inList = true
+			}
+			b.WriteString("<li>" + renderInline(item) + "</li>")
+			continue
+		}
+		closeList()
+		para = append(para, trimmed)
+	}
+	flushPara()
+	closeList()
+	return b.String()
+}
+
+func listItem(line string) (string, bool) {
+	for _, marker := range []string{"- ", "* "} {
+		if item, ok := strings.CutPrefix(line, marker); ok {
+			return strings.TrimSpace(item), true
+		}
+	}
+	return "", false
+}
+
+// renderInline renders the inline markup of one line.
+func renderInline(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && strings.IndexByte("\\`*_[]()", s[i+1]) >= 0:
+			b.WriteString(html.EscapeString(s[i+1 : i+2]))
+			i += 2
+			continue
+		case c == '`':
+			if end := strings.IndexByte(s[i+1:], '`'); end > 0 {
+				b.WriteString("<code>" + html.EscapeString(s[i+1:i+1+end]) + "</code>")
+				i += end + 2
+				continue
+			}
+		case strings.HasPrefix(s[i:], "**"):
+			if end := closingDelim(s, i+2, "**"); end > 0 {
+				b.WriteString("<strong>" + renderInline(s[i+2:end]) + "</strong>")
+				i = end + 2
+				continue
+			}
+		case (c == '*' || c == '_') && (c == '*' || i == 0 || !isWordByte(s[i-1])):
+			if end := closingDelim(s, i+1, s[i:i+1]); end > 0 && (c == '*' || end+1 == len(s) || !isWordByte(s[end+1])) {
+				b.WriteString("<em>" + renderInline(s[i+1:end]) + "</em>")
+				i = end + 1
+				continue
+			}
+		case c == '[':
+			if text, href, n, ok := parseLink(s[i:]); ok {
+				if safe, ok := safeLinkURL(href); ok {
+					b.WriteString(`<a href="` + html.EscapeString(safe) + `" target="_blank" rel="noopener noreferrer nofollow">` + renderInline(text) + "</a>")
+				} else {
+					b.WriteString(renderInline(text))
+				}
+				i += n
+				continue
+			}
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		b.WriteString(html.EscapeString(s[i : i+size]))
+		i += size
+	}
+	return b.String()
+}
+
+// closingDelim returns the index of the delim closing a span that starts at
+// from, or -1. Spans can't be empty or start with a space.
+func closingDelim(s string, from int, delim string) int {
+	if from >= len(s) || s[from] == ' ' {
+		return -1
+	}
+	end := strings.Index(s[from+1:], delim)
+	if end < 0 {
+		return -1
+	}
+	return from + 1 + end
+}
+
+// parseLink parses "[text](href)" at the start of s and returns its length.
+func parseLink(s string) (text, href string, n int, ok bool) {
+	closeText := strings.Index(s, "](")
+	if closeText < 1 {
+		return "", "", 0, false
+	}
+	closeHref := strings.IndexByte(s[closeText+2:], ')')
+	if closeHref < 0 {
+		return "", "", 0, false
+	}
+	return s[1:closeText], strings.TrimSpace(s[closeText+2 : closeText+2+closeHref]), closeText + 3 + closeHref, true
+}
+
+// safeLinkURL accepts http(s) and mailto links, and paths on this site.
+func safeLinkURL(raw string) (string, bool) {
+	if strings.ContainsRune(raw, '\\') {
+		// Browsers read "/\host" as "//host".
+		return "", false
+	}
+	if strings.HasPrefix(raw, "/") && !strings.HasPrefix(raw, "//") || strings.HasPrefix(raw, "#") {
+		return raw, true
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		if u.Host == "" {
+			return "", false
+		}
+	case "mailto":
+	default:
+		return "", false
+	}
+	return u.String(), true
+}
+
+func isWordByte(c byte) bool {
+	return c >= utf8.RuneSelf || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))
+}
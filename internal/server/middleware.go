@@ -9,6 +9,9 @@ type ctxKey string
 
 const (
 	ctxUserID ctxKey = "userID"
+	// ctxPasswordChange marks a signed-in user who still has to replace a
+	// generated password; such users count as visitors everywhere else.
+	ctxPasswordChange ctxKey = "passwordChange"
 )
 
 func withUserID(r *http.Request, userID string) *http.Request {
@@ -22,15 +25,45 @@ func userIDFromContext(r *http.Request) (string, bool) {
 	return id, ok && id != ""
 }
 
+// sessionUser returns the user of the request's session and whether they
+// must change their password first.
+func (s *Server) sessionUser(r *http.Request) (userID string, mustChange, ok bool) {
+	cookie, err := r.Cookie("hearth_session")
+	if err != nil || cookie.Value == "" {
+		return "", false, false
+	}
+	userID, err = s.auth.Validate(cookie.Value)
+	if err != nil {
+		return "", false, false
+	}
+	mustChange, err = s.auth.PasswordChangeRequired(userID)
+	if err != nil {
+		return "", false, false
+	}
+	return userID, mustChange, true
+}
+
 func (s *Server) requireAdmin(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		cookie, err := r.Cookie("hearth_session")
-		if err != nil || cookie.Value == "" {
+		userID, mustChange, ok := s.sessionUser(r)
+		if !ok {
 			http.Error(w, "unauthorized", http.StatusUnauthorized)
 			return
 		}
-		userID, err := s.auth.Validate(cookie.Value)
-		if err != nil {
+		if mustChange {
+			http.Error(w, "password change required", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, withUserID(r, userID))
+	})
+}
+
+// requireSession is requireAdmin for the password change itself, which users
+// with a generated password may reach.
+func (s *Server) requireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, _, ok := s.sessionUser(r)
+		if !ok {
 			http.Error(w, "unauthorized", http.StatusUnauthorized)
 			return
 		}
@@ -40,12 +73,13 @@ func (s *Server) requireAdmin(next http.Handler) http.Handler {
 
 func (s *Server) optionalUser(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		cookie, err := r.Cookie("hearth_session")
-		if err == nil && cookie.Value != "" {
-			if userID, err := s.auth.Validate(cookie.Value); err == nil {
-				next.ServeHTTP(w, withUserID(r, userID))
+		if userID, mustChange, ok := s.sessionUser(r); ok {
+			if mustChange {
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxPasswordChange, true)))
 				return
 			}
+			next.ServeHTTP(w, withUserID(r, userID))
+			return
 		}
 		next.ServeHTTP(w, r)
 	})
@@ -0,0 +1,207 @@
+package server
+
+import (
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Route groups with their own per-IP rate limit.
+const (
+	rateGroupWidgets = "widgets" // /api/widgets/*
+	rateGroupLookup  = "lookup"  // city, timezone and symbol search, which call upstream APIs per keystroke
+	rateGroupMetrics = "metrics" // /api/metrics/*
+)
+
+// rateLimit allows Burst requests per Per from one IP, refilled continuously.
+type rateLimit struct {
+	Burst int
+	Per   time.Duration
+}
+
+var defaultRateLimits = map[string]rateLimit{
+	rateGroupWidgets: {Burst: 300, Per: time.Minute},
+	rateGroupLookup:  {Burst: 30, Per: time.Minute},
+	rateGroupMetrics: {Burst: 600, Per: time.Minute},
+}
+
+// parseRateLimits reads HEARTH_RATE_LIMITS, e.g. "lookup=10/m,metrics=off".
+// Groups not mentioned keep their defaults; "off" disables all limits.
+// Invalid entries are logged and ignored.
+func parseRateLimits(spec string) map[string]rateLimit {
+	out := map[string]rateLimit{}
+	spec = strings.TrimSpace(spec)
+	if strings.EqualFold(spec, "off") {
+		return out
+	}
+	for g, l := range defaultRateLimits {
+		out[g] = l
+	}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		group, val, ok := strings.Cut(part, "=")
+		group, val = strings.ToLower(strings.TrimSpace(group)), strings.ToLower(strings.TrimSpace(val))
+		if _, known := defaultRateLimits[group]; !ok || !known {
+			slog.Warn("ignoring rate limit", "entry", part)
+			continue
+		}
+		if val == "off" {
+			delete(out, group)
+			continue
+		}
+		n, unit, _ := strings.Cut(val, "/")
+		burst, err := strconv.Atoi(n)
+		per := map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour}[unit]
+		if err != nil || burst < 1 || per == 0 {
+			slog.Warn("ignoring rate limit", "entry", part)
+			continue
+		}
+		out[group] = rateLimit{Burst: burst, Per: per}
+	}
+	return out
+}
+
+// rateLimiter is a set of per-key token buckets.
+type rateLimiter struct {
+	limit rateLimit
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(l rateLimit) *rateLimiter {
+	return &rateLimiter{limit: l, now: time.Now, buckets: map[string]*tokenBucket{}}
+}
+
+// allow takes a token for key. When none is left it reports how long until
+// the next one.
+func (l *rateLimiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	rate := float64(l.limit.Burst) / l.limit.Per.Seconds() // tokens per second
+
+	// Buckets idle for a full period are full again; drop them.
+	if now.Sub(l.lastSweep) > l.limit.Per {
+		for k, b := range l.buckets {
+			if now.Sub(b.last) > l.limit.Per {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(l.limit.Burst), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(l.limit.Burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// clientIP is the request's IP. realIP has already replaced the peer address
+// with the forwarded client when the peer is a trusted proxy.
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// realIP sets RemoteAddr to the client named by X-Forwarded-For, X-Real-IP or
+// True-Client-IP, but only for requests from a trusted proxy: anyone else
+// could name any address and get a fresh rate limit, or pass for a LAN
+// client.
+func (s *Server) realIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ip, ok := s.forwardedClient(r); ok {
+			r.RemoteAddr = ip.String()
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) trustedProxy(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, p := range s.proxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedClient returns the client a trusted proxy forwarded r for. In
+// X-Forwarded-For it is the last address not of a trusted proxy, since each
+// proxy appends the address it got the request from and only the entries our
+// proxies added can be believed.
+func (s *Server) forwardedClient(r *http.Request) (netip.Addr, bool) {
+	peer, err := netip.ParseAddr(clientIP(r))
+	if err != nil || !s.trustedProxy(peer) {
+		return netip.Addr{}, false
+	}
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		var client netip.Addr
+		for i := len(hops) - 1; i >= 0; i-- {
+			addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+			if err != nil {
+				break
+			}
+			client = addr.Unmap()
+			if !s.trustedProxy(client) {
+				break
+			}
+		}
+		if client.IsValid() {
+			return client, true
+		}
+	}
+	for _, h := range []string{"X-Real-IP", "True-Client-IP"} {
+		if addr, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get(h))); err == nil {
+			return addr.Unmap(), true
+		}
+	}
+	return netip.Addr{}, false
+}
+
+// rateLimited limits requests per client IP with the group's limit, answering
+// 429 with Retry-After once it is used up.
+func (s *Server) rateLimited(group string) func(http.Handler) http.Handler {
+	l := s.limiters[group]
+	return func(next http.Handler) http.Handler {
+		if l == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ok, wait := l.allow(clientIP(r)); !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				writeError(w, http.StatusTooManyRequests, "rate limit exceeded")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,492 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/morezhou/hearth/internal/storage"
+	"github.com/morezhou/hearth/internal/store"
+)
+
+// Kinds of remote backup targets.
+const (
+	backupTargetS3     = "s3"
+	backupTargetWebDAV = "webdav"
+	backupTargetSFTP   = "sftp"
+)
+
+var backupTargetKinds = []string{backupTargetS3, backupTargetWebDAV, backupTargetSFTP}
+
+const (
+	// remoteBackupTick is how often targets are checked for a due backup.
+	remoteBackupTick = time.Minute
+	// remoteBackupRetryAfter is how soon a failed target is tried again
+	// when its interval is longer.
+	remoteBackupRetryAfter = time.Hour
+	// remoteBackupAttempts is how often one upload is tried before the run
+	// fails.
+	remoteBackupAttempts = 3
+	remoteBackupTimeout  = 2 * time.Minute
+	// remoteBackupPrefix starts the key of every uploaded backup; pruning
+	// only considers keys with it.
+	remoteBackupPrefix = "hearth-backup-"
+
+	defaultBackupInterval = 24 * time.Hour
+	minBackupInterval     = 15 * time.Minute
+	defaultBackupKeep     = 7
+	maxBackupKeep         = 1000
+)
+
+// remoteBackupRetryDelay is the wait before the second upload attempt; it
+// doubles for each further one.
+var remoteBackupRetryDelay = 10 * time.Second
+
+var remoteBackupClient = &http.Client{Timeout: remoteBackupTimeout}
+
+// backupTargetConfig holds the settings of every kind; each kind uses its own
+// fields. Secrets aren't part of it.
+type backupTargetConfig struct {
+	// s3: the secret is the secret access key.
+	Endpoint  string `json:"endpoint,omitempty"`
+	Region    string `json:"region,omitempty"`
+	Bucket    string `json:"bucket,omitempty"`
+	AccessKey string `json:"accessKey,omitempty"`
+	Prefix    string `json:"prefix,omitempty"`
+	PathStyle bool   `json:"pathStyle,omitempty"`
+	// webdav: the secret is the password.
+	URL      string `json:"url,omitempty"`
+	Username string `json:"username,omitempty"`
+	// sftp: the secret is an optional private key.
+	Host    string `json:"host,omitempty"`
+	Port    int    `json:"port,omitempty"`
+	User    string `json:"user,omitempty"`
+	Path    string `json:"path,omitempty"`
+	HostKey string `json:"hostKey,omitempty"`
+}
+
+// backupBackend returns the storage backend of a target.
+func backupBackend(kind string, c backupTargetConfig, secret string) (storage.Backend, error) {
+	switch kind {
+	case backupTargetS3:
+		return storage.NewS3(storage.S3Config{
+			Endpoint: c.Endpoint, Region: c.Region, Bucket: c.Bucket, AccessKey: c.AccessKey, SecretKey: secret,
+			Prefix: c.Prefix, PathStyle: c.PathStyle, Client: remoteBackupClient,
+		})
+	case backupTargetWebDAV:
+		return storage.NewWebDAV(storage.WebDAVConfig{URL: c.URL, Username: c.Username, Password: secret, Client: remoteBackupClient})
+	case backupTargetSFTP:
+		return storage.NewSFTP(storage.SFTPConfig{Host: c.Host, Port: c.Port, User: c.User, Path: c.Path, PrivateKey: secret, HostKey: c.HostKey})
+	}
+	return nil, fmt.Errorf("kind must be one of %s", strings.Join(backupTargetKinds, ", "))
+}
+
+func targetBackend(t store.BackupTarget) (storage.Backend, error) {
+	var c backupTargetConfig
+	if err := json.Unmarshal([]byte(t.Config), &c); err != nil {
+		return nil, err
+	}
+	return backupBackend(t.Kind, c, t.Secret)
+}
+
+// backupTargetView is a backup target as shown to the admin; the secret is
+// write-only.
+type backupTargetView struct {
+	ID              int64              `json:"id"`
+	Name            string             `json:"name"`
+	Kind            string             `json:"kind"`
+	Config          backupTargetConfig `json:"config"`
+	HasSecret       bool               `json:"hasSecret"`
+	Keep            int                `json:"keep"`
+	IntervalSeconds int64              `json:"intervalSeconds"`
+	Enabled         bool               `json:"enabled"`
+	CreatedAt       int64              `json:"createdAt"`
+	LastAttemptAt   int64              `json:"lastAttemptAt,omitempty"`
+	LastSuccessAt   int64              `json:"lastSuccessAt,omitempty"`
+	LastError       string             `json:"lastError,omitempty"`
+}
+
+func newBackupTargetView(t store.BackupTarget) backupTargetView {
+	v := backupTargetView{
+		ID: t.ID, Name: t.Name, Kind: t.Kind, HasSecret: t.Secret != "", Keep: t.Keep,
+		IntervalSeconds: t.IntervalSeconds, Enabled: t.Enabled, CreatedAt: t.CreatedAt,
+		LastAttemptAt: t.LastAttemptAt, LastSuccessAt: t.LastSuccessAt, LastError: t.LastError,
+	}
+	_ = json.Unmarshal([]byte(t.Config), &v.Config)
+	return v
+}
+
+type backupTargetRequest struct {
+	Name            string             `json:"name"`
+	Kind            string             `json:"kind"`
+	Config          backupTargetConfig `json:"config"`
+	Secret          string             `json:"secret"`
+	Keep            *int               `json:"keep"`
+	IntervalSeconds int64              `json:"intervalSeconds"`
+	Enabled         *bool              `json:"enabled"`
+}
+
+// apply validates req and saves it into t; an empty secret keeps the one t
+// has. It returns a message for the first invalid field.
+func (req *backupTargetRequest) apply(t *store.BackupTarget) string {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 100 {
+		return "name is required (at most 100 characters)"
+	}
+	if !slices.Contains(backupTargetKinds, req.Kind) {
+		return "kind must be one of " + strings.Join(backupTargetKinds, ", ")
+	}
+	interval := time.Duration(req.IntervalSeconds) * time.Second
+	if req.IntervalSeconds == 0 {
+		interval = defaultBackupInterval
+	}
+	if interval < minBackupInterval {
+		return fmt.Sprintf("intervalSeconds must be at least %d", int64(minBackupInterval/time.Second))
+	}
+	keep := defaultBackupKeep
+	if req.Keep != nil {
+		keep = *req.Keep
+	}
+	if keep < 0 || keep > maxBackupKeep {
+		return fmt.Sprintf("keep must be between 0 (keep all) and %d", maxBackupKeep)
+	}
+	secret := t.Secret
+	if req.Kind != t.Kind {
+		secret = "" // a password doesn't carry over to another kind
+	}
+	if s := strings.TrimSpace(req.Secret); s != "" {
+		secret = s
+	}
+	if _, err := backupBackend(req.Kind, req.Config, secret); err != nil {
+		return strings.TrimPrefix(err.Error(), "storage: ")
+	}
+	config, _ := json.Marshal(req.Config)
+	t.Name, t.Kind, t.Config, t.Secret = req.Name, req.Kind, string(config), secret
+	t.Keep, t.IntervalSeconds = keep, int64(interval/time.Second)
+	if req.Enabled != nil {
+		t.Enabled = *req.Enabled
+	}
+	return ""
+}
+
+func backupTargetID(r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	return id, err == nil && id > 0
+}
+
+// handleListBackupTargets handles GET /api/admin/backups/targets.
+func (s *Server) handleListBackupTargets(w http.ResponseWriter, r *http.Request) {
+	targets, err := s.store.ListBackupTargets()
+	if err != nil {
+		slog.Error("failed to list backup targets", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to list backup targets")
+		return
+	}
+	out := make([]backupTargetView, 0, len(targets))
+	for _, t := range targets {
+		out = append(out, newBackupTargetView(t))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"targets": out, "kinds": backupTargetKinds})
+}
+
+// handleCreateBackupTarget handles POST /api/admin/backups/targets.
+func (s *Server) handleCreateBackupTarget(w http.ResponseWriter, r *http.Request) {
+	var req backupTargetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	t := store.BackupTarget{Enabled: true}
+	if msg := req.apply(&t); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+	t, err := s.store.CreateBackupTarget(t)
+	if err != nil {
+		slog.Error("failed to create backup target", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to create backup target")
+		return
+	}
+	writeJSON(w, http.StatusCreated, newBackupTargetView(t))
+}
+
+// handleUpdateBackupTarget handles PUT /api/admin/backups/targets/{id}. An
+// empty secret keeps the stored one.
+func (s *Server) handleUpdateBackupTarget(w http.ResponseWriter, r *http.Request) {
+	id, ok := backupTargetID(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var req backupTargetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	t, ok, err := s.store.GetBackupTarget(id)
+	if err != nil {
+		slog.Error("failed to load backup target", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to update backup target")
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if msg := req.apply(&t); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+	if _, err := s.store.UpdateBackupTarget(t); err != nil {
+		slog.Error("failed to update backup target", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to update backup target")
+		return
+	}
+	writeJSON(w, http.StatusOK, newBackupTargetView(t))
+}
+
+// handleDeleteBackupTarget handles DELETE /api/admin/backups/targets/{id}.
+// Backups already uploaded stay on the target.
+func (s *Server) handleDeleteBackupTarget(w http.ResponseWriter, r *http.Request) {
+	id, ok := backupTargetID(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	ok, err := s.store.DeleteBackupTarget(id)
+	if err != nil {
+		slog.Error("failed to delete backup target", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to delete backup target")
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+// loadBackupTarget answers 400 or 404 itself when there's no target.
+func (s *Server) loadBackupTarget(w http.ResponseWriter, r *http.Request) (store.BackupTarget, bool) {
+	id, ok := backupTargetID(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return store.BackupTarget{}, false
+	}
+	t, ok, err := s.store.GetBackupTarget(id)
+	if err != nil {
+		slog.Error("failed to load backup target", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to load backup target")
+		return store.BackupTarget{}, false
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "not found")
+		return store.BackupTarget{}, false
+	}
+	return t, true
+}
+
+// handleRunBackupTarget handles POST /api/admin/backups/targets/{id}/run:
+// back up to the target now, enabled or not.
+func (s *Server) handleRunBackupTarget(w http.ResponseWriter, r *http.Request) {
+	t, ok := s.loadBackupTarget(w, r)
+	if !ok {
+		return
+	}
+	if !s.remoteBackup.TryLock() {
+		writeError(w, http.StatusConflict, "backup already running")
+		return
+	}
+	s.remoteBackup.Unlock()
+	key, err := s.backUpTo(r.Context(), t)
+	if t, ok, _ = s.store.GetBackupTarget(t.ID); !ok {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]any{"error": err.Error(), "target": newBackupTargetView(t)})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "key": key, "target": newBackupTargetView(t)})
+}
+
+// remoteBackupView is a backup stored on a target.
+type remoteBackupView struct {
+	Key     string `json:"key"`
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"modTime,omitempty"`
+}
+
+// handleListRemoteBackups handles GET /api/admin/backups/targets/{id}/backups:
+// the backups on the target, newest first.
+func (s *Server) handleListRemoteBackups(w http.ResponseWriter, r *http.Request) {
+	t, ok := s.loadBackupTarget(w, r)
+	if !ok {
+		return
+	}
+	objs, err := listRemoteBackups(r.Context(), t)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	out := make([]remoteBackupView, 0, len(objs))
+	for i := len(objs) - 1; i >= 0; i-- {
+		v := remoteBackupView{Key: objs[i].Key, Size: objs[i].Size}
+		if !objs[i].ModTime.IsZero() {
+			v.ModTime = objs[i].ModTime.Unix()
+		}
+		out = append(out, v)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"backups": out})
+}
+
+// runRemoteBackups backs up to every enabled target whose interval elapsed
+// until ctx is done. A failed target is tried again after
+// remoteBackupRetryAfter, or its interval when that's shorter.
+func (s *Server) runRemoteBackups(ctx context.Context) {
+	t := time.NewTicker(remoteBackupTick)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		s.backUpDueTargets(ctx, time.Now())
+	}
+}
+
+func (s *Server) backUpDueTargets(ctx context.Context, now time.Time) {
+	targets, err := s.store.ListBackupTargets()
+	if err != nil {
+		slog.Warn("remote backup: list targets failed", "error", err)
+		return
+	}
+	for _, t := range targets {
+		if ctx.Err() != nil {
+			return
+		}
+		if !t.Enabled || !backupDue(t, now) {
+			continue
+		}
+		if _, err := s.backUpTo(ctx, t); err != nil && ctx.Err() == nil {
+			slog.Warn("remote backup failed", "target", t.Name, "error", err)
+		}
+	}
+}
+
+// backupDue reports whether t should be backed up to at now.
+func backupDue(t store.BackupTarget, now time.Time) bool {
+	if t.LastAttemptAt == 0 {
+		return true
+	}
+	wait := time.Duration(t.IntervalSeconds) * time.Second
+	if t.LastError != "" {
+		wait = min(wait, remoteBackupRetryAfter)
+	}
+	return !now.Before(time.Unix(t.LastAttemptAt, 0).Add(wait))
+}
+
+// backUpTo uploads a backup to t, retrying failed uploads, prunes the
+// backups beyond t.Keep and records how it went. It returns the uploaded
+// key. Runs wait for each other.
+func (s *Server) backUpTo(ctx context.Context, t store.BackupTarget) (string, error) {
+	s.remoteBackup.Lock()
+	defer s.remoteBackup.Unlock()
+	now := time.Now()
+	key, err := s.uploadBackup(ctx, t, now)
+	if err == nil {
+		if perr := pruneRemoteBackups(ctx, t); perr != nil {
+			slog.Warn("remote backup: prune failed", "target", t.Name, "error", perr)
+		}
+		slog.Info("remote backup uploaded", "target", t.Name, "key", key)
+	}
+	msg := ""
+	if err != nil {
+		msg = truncateDetail(err.Error())
+	}
+	if rerr := s.store.RecordBackupRun(t.ID, now.Unix(), msg); rerr != nil {
+		slog.Warn("remote backup: record run failed", "target", t.Name, "error", rerr)
+	}
+	return key, err
+}
+
+// uploadBackup uploads the full backup as of now to t.
+func (s *Server) uploadBackup(ctx context.Context, t store.BackupTarget, now time.Time) (string, error) {
+	b, err := targetBackend(t)
+	if err != nil {
+		return "", err
+	}
+	data, err := s.store.ExportJSON()
+	if err != nil {
+		return "", fmt.Errorf("export: %w", err)
+	}
+	key := remoteBackupPrefix + now.UTC().Format("20060102-150405") + ".json"
+	delay := remoteBackupRetryDelay
+	for attempt := 1; ; attempt++ {
+		actx, cancel := context.WithTimeout(ctx, remoteBackupTimeout)
+		err = b.Put(actx, key, data, "application/json")
+		cancel()
+		if err == nil || attempt == remoteBackupAttempts || ctx.Err() != nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+	if err != nil {
+		return "", fmt.Errorf("upload: %w", err)
+	}
+	return key, nil
+}
+
+// listRemoteBackups lists the backups on t, oldest first; their keys sort
+// by time.
+func listRemoteBackups(ctx context.Context, t store.BackupTarget) ([]storage.Object, error) {
+	b, err := targetBackend(t)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, remoteBackupTimeout)
+	defer cancel()
+	objs, err := b.List(ctx, remoteBackupPrefix)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(objs, func(a, b storage.Object) int { return strings.Compare(a.Key, b.Key) })
+	return objs, nil
+}
+
+// pruneRemoteBackups deletes the oldest backups on t beyond t.Keep; 0 keeps
+// them all.
+func pruneRemoteBackups(ctx context.Context, t store.BackupTarget) error {
+	if t.Keep <= 0 {
+		return nil
+	}
+	objs, err := listRemoteBackups(ctx, t)
+	if err != nil || len(objs) <= t.Keep {
+		return err
+	}
+	b, err := targetBackend(t)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, o := range objs[:len(objs)-t.Keep] {
+		if err := b.Delete(ctx, o.Key); err != nil {
+			errs = append(errs, fmt.Errorf("delete %s: %w", o.Key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,294 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/morezhou/hearth/internal/store"
+)
+
+// kvReplicationTokenHash holds the SHA-256 of the token replicas pull the
+// configuration with; the token itself is only shown when it is generated.
+const kvReplicationTokenHash = "replication.tokenHash"
+
+// instanceLocalSettings belong to the instance rather than the dashboard: its
+// own tokens and the settings describing its host. A replica keeps its values
+// of them, or their absence, whatever the primary has.
+var instanceLocalSettings = append([]string{kvReplicationTokenHash, kvIngestTokenHash, kvWeatherAlertWebhookID}, hostSettings...)
+
+const (
+	defaultReplicaInterval = 15 * time.Minute
+	// maxReplicaBundle bounds the configuration pulled from the primary.
+	maxReplicaBundle = 16 << 20
+)
+
+var replicaClient = &http.Client{Timeout: 60 * time.Second}
+
+// replicaStatus is how pulling from the primary went.
+type replicaStatus struct {
+	Primary         string `json:"primary"`
+	IntervalSeconds int64  `json:"intervalSeconds"`
+	// LastAttemptAt is the latest pull, LastSyncAt the latest successful
+	// one and LastChangeAt the latest that changed the configuration.
+	LastAttemptAt int64 `json:"lastAttemptAt,omitempty"`
+	LastSyncAt    int64 `json:"lastSyncAt,omitempty"`
+	LastChangeAt  int64 `json:"lastChangeAt,omitempty"`
+	// ExportedAt is when the primary made the bundle last applied.
+	ExportedAt int64  `json:"exportedAt,omitempty"`
+	LastError  string `json:"lastError,omitempty"`
+}
+
+// replicaState serializes pulls and remembers how the latest went.
+type replicaState struct {
+	running sync.Mutex
+
+	mu     sync.Mutex
+	status replicaStatus
+	sum    string // digest of the bundle last applied
+}
+
+// replicationAuthorized checks the request's bearer token against the stored
+// hash. The export is disabled until a token was generated.
+func (s *Server) replicationAuthorized(r *http.Request) bool {
+	want := s.getStringSetting(kvReplicationTokenHash, "")
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if want == "" || !ok || got == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hashIngestToken(got)), []byte(want)) == 1
+}
+
+// handleReplicationExport handles GET /api/replication/export: the whole
+// configuration with users' own settings, for replicas holding the
+// replication token. Secrets are included in plain text.
+func (s *Server) handleReplicationExport(w http.ResponseWriter, r *http.Request) {
+	if !s.replicationAuthorized(r) {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	p, err := s.store.ExportAll()
+	if err == nil {
+		p.Users, err = s.exportUserSettings()
+	}
+	if err != nil {
+		slog.Error("failed to export for replication", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed")
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, p)
+}
+
+// handleRotateReplicationToken handles POST /api/admin/replication/token:
+// generates a new token, invalidating the previous one, and returns it once.
+func (s *Server) handleRotateReplicationToken(w http.ResponseWriter, r *http.Request) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to generate token")
+		return
+	}
+	token := hex.EncodeToString(b)
+	if err := s.store.SetKV(kvReplicationTokenHash, hashIngestToken(token)); err != nil {
+		slog.Error("failed to save replication token", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to save token")
+		return
+	}
+	slog.Info("replication token rotated")
+	writeJSON(w, http.StatusOK, map[string]any{"token": token})
+}
+
+// handleDeleteReplicationToken handles DELETE /api/admin/replication/token:
+// replicas can't pull anymore.
+func (s *Server) handleDeleteReplicationToken(w http.ResponseWriter, r *http.Request) {
+	if err := s.store.DeleteKV(kvReplicationTokenHash); err != nil {
+		slog.Error("failed to delete replication token", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to delete token")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+// handleGetReplication handles GET /api/admin/replication: whether replicas
+// can pull from this instance, and how pulling from the primary goes when
+// this is a replica.
+func (s *Server) handleGetReplication(w http.ResponseWriter, r *http.Request) {
+	resp := map[string]any{
+		"tokenSet": s.getStringSetting(kvReplicationTokenHash, "") != "",
+		"replica":  nil,
+	}
+	if s.cfg.ReplicaOf != "" {
+		resp["replica"] = s.replicaStatus()
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleReplicaSync handles POST /api/admin/replication/sync: a replica pulls
+// from the primary now.
+func (s *Server) handleReplicaSync(w http.ResponseWriter, r *http.Request) {
+	if s.cfg.ReplicaOf == "" {
+		writeError(w, http.StatusBadRequest, "not a replica; set HEARTH_REPLICA_OF")
+		return
+	}
+	if !s.replica.running.TryLock() {
+		writeError(w, http.StatusConflict, "sync already running")
+		return
+	}
+	s.replica.running.Unlock()
+	if err := s.replicate(r.Context()); err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]any{"error": err.Error(), "replica": s.replicaStatus()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "replica": s.replicaStatus()})
+}
+
+func (s *Server) replicaStatus() replicaStatus {
+	s.replica.mu.Lock()
+	defer s.replica.mu.Unlock()
+	st := s.replica.status
+	st.Primary = s.cfg.ReplicaOf
+	st.IntervalSeconds = int64(s.cfg.ReplicaInterval / time.Second)
+	return st
+}
+
+// runReplication pulls the configuration from the primary at startup and
+// every ReplicaInterval, when this instance is a replica.
+func (s *Server) runReplication(ctx context.Context) {
+	if s.cfg.ReplicaOf == "" {
+		return
+	}
+	interval := s.cfg.ReplicaInterval
+	if interval <= 0 {
+		interval = defaultReplicaInterval
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if err := s.replicate(ctx); err != nil && ctx.Err() == nil {
+			slog.Warn("replication failed", "primary", s.cfg.ReplicaOf, "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// replicate pulls the primary's configuration and makes it this instance's,
+// unless it didn't change since the last pull. Credentials, sessions and
+// caches stay this instance's own.
+func (s *Server) replicate(ctx context.Context) error {
+	s.replica.running.Lock()
+	defer s.replica.running.Unlock()
+	now := time.Now().Unix()
+	p, sum, err := s.pullReplicaBundle(ctx)
+	if err == nil && sum != s.replicaSum() {
+		err = s.applyReplicaBundle(p)
+	}
+
+	s.replica.mu.Lock()
+	defer s.replica.mu.Unlock()
+	s.replica.status.LastAttemptAt = now
+	if err != nil {
+		s.replica.status.LastError = err.Error()
+		return err
+	}
+	s.replica.status.LastError = ""
+	s.replica.status.LastSyncAt = now
+	if sum != s.replica.sum {
+		s.replica.sum = sum
+		s.replica.status.LastChangeAt = now
+		s.replica.status.ExportedAt = p.Exported
+		slog.Info("replicated configuration", "primary", s.cfg.ReplicaOf, "groups", len(p.Groups), "apps", len(p.Apps))
+	}
+	return nil
+}
+
+func (s *Server) replicaSum() string {
+	s.replica.mu.Lock()
+	defer s.replica.mu.Unlock()
+	return s.replica.sum
+}
+
+// pullReplicaBundle fetches the primary's configuration and its digest,
+// which ignores when it was exported.
+func (s *Server) pullReplicaBundle(ctx context.Context) (store.Export, string, error) {
+	url := strings.TrimRight(s.cfg.ReplicaOf, "/") + "/api/replication/export"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return store.Export{}, "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.cfg.ReplicaToken)
+	resp, err := replicaClient.Do(req)
+	if err != nil {
+		return store.Export{}, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return store.Export{}, "", fmt.Errorf("primary returned %s", resp.Status)
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxReplicaBundle+1))
+	if err != nil {
+		return store.Export{}, "", err
+	}
+	if len(b) > maxReplicaBundle {
+		return store.Export{}, "", errors.New("configuration from the primary is too large")
+	}
+	var p store.Export
+	if err := json.Unmarshal(b, &p); err != nil {
+		return store.Export{}, "", fmt.Errorf("parse configuration from the primary: %w", err)
+	}
+	if p.Version == 0 {
+		return store.Export{}, "", errors.New("primary sent no configuration")
+	}
+	digest := p
+	digest.Exported = 0
+	canon, _ := json.Marshal(digest)
+	sum := sha256.Sum256(canon)
+	return p, hex.EncodeToString(sum[:]), nil
+}
+
+// applyReplicaBundle replaces the configuration with p. The replica keeps
+// its instanceLocalSettings: its ingest token stays valid, and its
+// replication token lets it be a primary in turn.
+func (s *Server) applyReplicaBundle(p store.Export) error {
+	if p.Settings == nil {
+		p.Settings = map[string]string{}
+	}
+	for _, k := range instanceLocalSettings {
+		delete(p.Settings, k)
+		if v, ok, err := s.store.GetKV(k); err != nil {
+			return err
+		} else if ok {
+			p.Settings[k] = v
+		}
+	}
+	if err := s.store.ReplaceAll(p); err != nil {
+		return err
+	}
+	skipped, err := s.importUserSettings(p.Users)
+	if err != nil {
+		return err
+	}
+	if len(skipped) > 0 {
+		slog.Debug("replicated settings of unknown users skipped", "users", skipped)
+	}
+	if err := s.ensureDefaultSystemTools(); err != nil {
+		return err
+	}
+	s.loadHolidayDataset()
+	s.respCache.purge()
+	return nil
+}
@@ -0,0 +1,181 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// TTLs of the widget response cache. They stay below the refresh intervals
+// of the widget caches behind the endpoints, so the micro-cache only spares
+// a LAN full of open tabs from serializing the same JSON over and over.
+const (
+	respTTLWeather   = time.Minute
+	respTTLMarkets   = 30 * time.Second
+	respTTLHolidays  = 10 * time.Minute
+	respTTLCountries = time.Hour
+	respTTLQuote     = 5 * time.Minute
+	// respCacheMaxEntries bounds the cache; when full, it starts over.
+	respCacheMaxEntries = 512
+)
+
+// responseCache holds recent successful responses of public widget
+// endpoints, keyed by path, query and locale.
+type responseCache struct {
+	mu      sync.Mutex
+	items   map[string]cachedResponse
+	pending map[string]chan struct{} // keys being computed
+}
+
+type cachedResponse struct {
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+func (c *responseCache) get(key string) (cachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if !ok || time.Now().After(e.expires) {
+		return cachedResponse{}, false
+	}
+	return e, true
+}
+
+// claim returns a channel to wait on when another request is computing key,
+// or nil when the caller should compute it and call release afterwards.
+func (c *responseCache) claim(key string) <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ch, ok := c.pending[key]; ok {
+		return ch
+	}
+	if c.pending == nil {
+		c.pending = map[string]chan struct{}{}
+	}
+	c.pending[key] = make(chan struct{})
+	return nil
+}
+
+// release stores e (unless it is nil) and wakes the requests waiting on key.
+func (c *responseCache) release(key string, e *cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e != nil {
+		if c.items == nil || len(c.items) >= respCacheMaxEntries {
+			c.items = map[string]cachedResponse{}
+		}
+		c.items[key] = *e
+	}
+	if ch, ok := c.pending[key]; ok {
+		close(ch)
+		delete(c.pending, key)
+	}
+}
+
+// purge drops every cached response.
+func (c *responseCache) purge() {
+	c.mu.Lock()
+	c.items = nil
+	c.mu.Unlock()
+}
+
+// cachedFor serves GET requests from the response cache for ttl. Only 200
+// responses are kept; identical requests arriving while one is computed wait
+// for it instead of repeating the work.
+func (s *Server) cachedFor(ttl time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				next.ServeHTTP(w, r)
+				return
+			}
+			// Signed-in users may have their own units and language.
+			userID, _ := userIDFromContext(r)
+			key := r.URL.Path + "?" + r.URL.RawQuery + "#" + localeFromRequest(r) + "#" + userID
+			for {
+				if e, ok := s.respCache.get(key); ok {
+					writeCachedResponse(w, e, "HIT")
+					return
+				}
+				wait := s.respCache.claim(key)
+				if wait == nil {
+					break
+				}
+				select {
+				case <-wait:
+				case <-r.Context().Done():
+					return
+				}
+			}
+
+			rec := &bufferedResponse{header: http.Header{}, status: http.StatusOK}
+			var stored *cachedResponse
+			defer func() { s.respCache.release(key, stored) }()
+			next.ServeHTTP(rec, r)
+			if rec.status != http.StatusOK {
+				copyHeader(w.Header(), rec.header)
+				w.WriteHeader(rec.status)
+				_, _ = w.Write(rec.body.Bytes())
+				return
+			}
+			stored = &cachedResponse{header: rec.header, body: rec.body.Bytes(), expires: time.Now().Add(ttl)}
+			writeCachedResponse(w, *stored, "MISS")
+		})
+	}
+}
+
+// purgeResponsesOnWrite empties the response cache after every successful
+// request that may change data: settings, events and quotes feed the cached
+// widget responses.
+func (s *Server) purgeResponsesOnWrite(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+		if ww.Status() < http.StatusBadRequest {
+			s.respCache.purge()
+		}
+	})
+}
+
+func writeCachedResponse(w http.ResponseWriter, e cachedResponse, state string) {
+	copyHeader(w.Header(), e.header)
+	w.Header().Set("X-Cache", state)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(e.body)
+}
+
+func copyHeader(dst, src http.Header) {
+	for k, v := range src {
+		dst[k] = append([]string(nil), v...)
+	}
+}
+
+// bufferedResponse collects a handler's response so it can be cached.
+type bufferedResponse struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header { return b.header }
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if !b.wroteHeader {
+		b.status, b.wroteHeader = status, true
+	}
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	b.wroteHeader = true
+	return b.body.Write(p)
+}
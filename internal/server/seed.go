@@ -1,7 +1,11 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
+
+	"github.com/morezhou/hearth/internal/icon"
+	"github.com/morezhou/hearth/internal/store"
 )
 
 func (s *Server) ensureDefaultSystemTools() error {
@@ -22,28 +26,14 @@ func (s *Server) ensureDefaultSystemTools() error {
 		return nil
 	}
 
-	gs, err := s.store.ListGroups()
+	gid, err := s.systemGroupID()
 	if err != nil {
 		return err
 	}
-	var gid string
-	for _, g := range gs {
-		if g.Kind == "system" || g.Name == "系统组件" || g.Name == "System Tools" || g.Name == "System Widgets" {
-			gid = g.ID
-			break
-		}
-	}
-	if gid == "" {
-		g, err := s.store.CreateGroup("系统组件", "system")
-		if err != nil {
-			return err
-		}
-		gid = g.ID
-	}
 
 	weatherDescBytes, _ := json.Marshal(map[string]any{"city": defaultWeatherCity})
 	weatherDesc := string(weatherDescBytes)
-	if _, err := s.store.CreateApp(&gid, "Weather", &weatherDesc, "widget:weather", nil, nil); err != nil {
+	if err := s.seedWidget(gid, "Weather", "weather", weatherDesc); err != nil {
 		return err
 	}
 
@@ -56,16 +46,138 @@ func (s *Server) ensureDefaultSystemTools() error {
 		},
 	})
 	clocksDesc := string(clocksDescBytes)
-	if _, err := s.store.CreateApp(&gid, "World Clock", &clocksDesc, "widget:timezones", nil, nil); err != nil {
+	if err := s.seedWidget(gid, "World Clock", "timezones", clocksDesc); err != nil {
 		return err
 	}
 
 	metricsDescBytes, _ := json.Marshal(map[string]any{"showCpu": true, "showMem": true, "showDisk": true, "showNet": true, "refreshSec": 1})
 	metricsDesc := string(metricsDescBytes)
-	if _, err := s.store.CreateApp(&gid, "System Status", &metricsDesc, "widget:metrics", nil, nil); err != nil {
+	if err := s.seedWidget(gid, "System Status", "metrics", metricsDesc); err != nil {
 		return err
 	}
 
 	// Mark seeded so we don't recreate widgets if a user later deletes them.
 	return s.store.SetKV("seed.system_widgets.v1", "1")
 }
+
+// systemGroupID returns the group widgets live in, creating it if needed.
+func (s *Server) systemGroupID() (string, error) {
+	gs, err := s.store.ListGroups()
+	if err != nil {
+		return "", err
+	}
+	for _, g := range gs {
+		if g.Kind == "system" || g.Name == "系统组件" || g.Name == "System Tools" || g.Name == "System Widgets" {
+			return g.ID, nil
+		}
+	}
+	g, err := s.store.CreateGroup("系统组件", "system")
+	if err != nil {
+		return "", err
+	}
+	return g.ID, nil
+}
+
+func (s *Server) seedWidget(gid, name, kind, desc string) error {
+	app, err := s.store.CreateApp(store.AppItem{GroupID: &gid, Name: name, Description: &desc, URL: "widget:" + kind})
+	if err != nil {
+		return err
+	}
+	return s.store.PutWidgetConfig(app.ID, kind, []byte(desc))
+}
+
+// demoApp is an app of the demo data; its icon is an emoji tile, so seeding
+// works offline.
+type demoApp struct {
+	name, url, emoji, description, shortcut string
+}
+
+// demoGroups is the sample dashboard of HEARTH_DEMO and "hearth seed -demo".
+var demoGroups = []struct {
+	name string
+	apps []demoApp
+}{
+	{"Media", []demoApp{
+		{"Jellyfin", "http://jellyfin.lan:8096", "🎬", "Movies and shows for **everyone** at home.", "j"},
+		{"Navidrome", "http://music.lan:4533", "🎵", "Music library and playlists.", ""},
+		{"Sonarr", "http://sonarr.lan:8989", "📺", "TV series downloads.", ""},
+		{"Radarr", "http://radarr.lan:7878", "🎞️", "Movie downloads.", ""},
+	}},
+	{"Home", []demoApp{
+		{"Home Assistant", "http://homeassistant.lan:8123", "🏠", "Lights, heating and scenes.\n\n- Dashboards\n- Automations", "h"},
+		{"Frigate", "http://frigate.lan:5000", "📷", "Camera recordings and events.", ""},
+		{"Node-RED", "http://nodered.lan:1880", "🔀", "Flows for the `zigbee` devices.", ""},
+	}},
+	{"Network", []demoApp{
+		{"Router", "http://192.168.1.1", "📡", "Gateway and Wi-Fi settings.", ""},
+		{"Pi-hole", "http://pihole.lan/admin", "🛡️", "DNS and ad blocking.", ""},
+		{"Uptime Kuma", "http://status.lan:3001", "📈", "Service checks and alerts.", ""},
+	}},
+	{"Productivity", []demoApp{
+		{"Nextcloud", "https://cloud.example.com", "☁️", "Files, calendars and contacts.", "n"},
+		{"Paperless", "http://paperless.lan:8000", "📄", "Scanned documents, searchable.", ""},
+		{"Vaultwarden", "https://vault.example.com", "🔐", "Passwords. See the [docs](https://github.com/dani-garcia/vaultwarden/wiki).", ""},
+		{"Gitea", "http://git.lan:3000", "🍵", "Code and dotfiles.", ""},
+	}},
+}
+
+// SeedDemo adds sample groups, apps and widgets for screenshots and trials.
+// It runs once per database and reports whether it added anything.
+func (s *Server) SeedDemo(ctx context.Context) (bool, error) {
+	if v, ok, err := s.store.GetKV("seed.demo.v1"); err != nil {
+		return false, err
+	} else if ok && v == "1" {
+		return false, nil
+	}
+
+	for _, dg := range demoGroups {
+		g, err := s.store.CreateGroup(dg.name, "app")
+		if err != nil {
+			return false, err
+		}
+		for _, a := range dg.apps {
+			tile, err := icon.RenderTile(iconSourceEmoji, a.emoji, "", a.name)
+			if err != nil {
+				return false, err
+			}
+			if err := s.iconStore.Put(ctx, tile.Key, tile.Data, "image/svg+xml"); err != nil {
+				return false, err
+			}
+			source := iconSourceEmoji
+			app := store.AppItem{GroupID: &g.ID, Name: a.name, URL: a.url, Description: &a.description, IconPath: &tile.Key, IconSource: &source}
+			if a.shortcut != "" {
+				app.Shortcut = &a.shortcut
+			}
+			if _, err := s.store.CreateApp(app); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	gid, err := s.systemGroupID()
+	if err != nil {
+		return false, err
+	}
+	for _, w := range []struct {
+		name, kind string
+		config     map[string]any
+	}{
+		{"Search", "search", map[string]any{}},
+		{"Markets", "markets", map[string]any{"symbols": []string{"BTC", "ETH", "AAPL", "MSFT"}}},
+		{"Holidays", "holidays", map[string]any{"countries": []string{"US", "GB"}}},
+		{"Quote", "quote", map[string]any{"source": "bundled"}},
+	} {
+		desc, _ := json.Marshal(w.config)
+		if err := s.seedWidget(gid, w.name, w.kind, string(desc)); err != nil {
+			return false, err
+		}
+	}
+	if _, ok, err := s.store.GetKV(kvSiteTitle); err != nil {
+		return false, err
+	} else if !ok {
+		if err := s.store.SetKV(kvSiteTitle, "Hearth Demo"); err != nil {
+			return false, err
+		}
+	}
+	return true, s.store.SetKV("seed.demo.v1", "1")
+}
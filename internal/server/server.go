@@ -1,13 +1,17 @@
 package server
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"net/netip"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -15,10 +19,19 @@ import (
 	"github.com/go-chi/cors"
 	_ "modernc.org/sqlite"
 
+	"github.com/morezhou/hearth/internal/agent"
+	"github.com/morezhou/hearth/internal/assetproxy"
 	"github.com/morezhou/hearth/internal/auth"
 	"github.com/morezhou/hearth/internal/background"
+	"github.com/morezhou/hearth/internal/i18n"
 	"github.com/morezhou/hearth/internal/icon"
+	"github.com/morezhou/hearth/internal/lucide"
+	"github.com/morezhou/hearth/internal/metrics"
+	"github.com/morezhou/hearth/internal/netguard"
+	"github.com/morezhou/hearth/internal/plugins"
+	"github.com/morezhou/hearth/internal/storage"
 	"github.com/morezhou/hearth/internal/store"
+	"github.com/morezhou/hearth/internal/widgets"
 )
 
 // Version is set at build time via ldflags.
@@ -27,12 +40,57 @@ var Version = "dev"
 type Server struct {
 	cfg          Config
 	router       chi.Router
-	store        *store.Store
-	auth         *auth.Service
+	store        store.Repository
+	auth         authenticator
 	iconResolver *icon.Resolver
+	iconPack     *icon.Pack // nil when disabled
+	lucide       *lucide.Set
 	bgSvc        *background.Service
+	iconStore    storage.Backend // cached app and market icons
+	assetProxy   *assetproxy.Proxy
+	bgStore      storage.Backend  // cached background images
+	outbound     *netguard.Policy // guards fetches of user-supplied URLs
+	hostMetrics  *metrics.Collector
+	hostSnapshot hostSnapshot
+	docker       *metrics.DockerClient // nil when disabled
+	smart        *metrics.SMARTReader  // nil when disabled
+	agents       *agent.Client
+	plugins      *plugins.Registry // nil when disabled
+	runners      map[string]sshRunner
+	limiters     map[string]*rateLimiter
+	proxies      []netip.Prefix // peers trusted to name the client
+	work         *workers
+	dbMaint      dbMaintenance
+	remoteBackup sync.Mutex // serializes uploads to backup targets
+
+	webhookClient *http.Client
+	webhookWake   chan struct{} // nudges the dispatcher after an event
+	appStatus     appStatus
+
+	alertsSeen     alertDispatchState
+	agentReadings  agentReadings
+	upstreamHealth upstreamHealth
+	respCache      responseCache
+	undo           undoJournal
+	replica        replicaState
 }
 
+// authenticator is the part of auth.Service the handlers use.
+type authenticator interface {
+	Login(username, password string) (string, error)
+	Logout(token string) error
+	Validate(token string) (string, error)
+	ChangePassword(userID, oldPassword, newPassword string) error
+	VerifyPassword(userID, password string) error
+	PasswordChangeRequired(userID string) (bool, error)
+	Username(userID string) (string, error)
+	UserID(username string) (string, error)
+	EnsureDefaultAdmin() error
+	SessionMaxAge() time.Duration
+	PurgeExpiredSessions() (int64, error)
+}
+
+// New opens the SQLite database in cfg and builds the server on it.
 func New(cfg Config) (*Server, error) {
 	if cfg.Addr == "" {
 		return nil, errors.New("addr is required")
@@ -50,10 +108,21 @@ func New(cfg Config) (*Server, error) {
 		return nil, err
 	}
 
-	db, err := sql.Open("sqlite", cfg.DatabaseDSN)
+	st, authSvc, err := Open(cfg)
 	if err != nil {
 		return nil, err
 	}
+	return newServer(cfg, st, authSvc)
+}
+
+// Open opens, migrates and unlocks the SQLite database in cfg, and the
+// authentication service on it. The admin CLI uses it to work on the
+// database without a running server.
+func Open(cfg Config) (*store.Store, *auth.Service, error) {
+	db, err := sql.Open("sqlite", cfg.DatabaseDSN)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	// Configure connection pool for SQLite.
 	// SQLite doesn't benefit from multiple connections for writes (due to locking),
@@ -72,24 +141,125 @@ func New(cfg Config) (*Server, error) {
 
 	st := store.New(db)
 	if err := st.Migrate(); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	if err := st.EnableEncryption(cfg.DBKey, sensitiveSettings); err != nil {
+		return nil, nil, err
+	}
+	authCfg := auth.Config{DB: db, SessionTTL: cfg.SessionTTL, SessionMaxAge: cfg.SessionMaxAge, TokenKey: st.SubKey("sessions"), InitialPassword: cfg.InitialPassword}
+	if cfg.DataDir != "" {
+		// The generated password is kept in a directory of its own that only
+		// the server's user can read and no asset storage is rooted at.
+		dir := filepath.Join(cfg.DataDir, "secrets")
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, nil, err
+		}
+		authCfg.InitialPasswordFile = filepath.Join(dir, "initial-password")
+		// Earlier versions wrote it to the root of DataDir.
+		if err := os.Rename(filepath.Join(cfg.DataDir, "initial-password"), authCfg.InitialPasswordFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("failed to move the initial password file", "error", err)
+		}
+	}
+	authSvc, err := auth.New(authCfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return st, authSvc, nil
+}
 
-	authSvc, err := auth.New(auth.Config{DB: db, SessionTTL: cfg.SessionTTL})
+// newServer builds the server on a migrated repository. Tests pass an
+// in-memory store and a fake authenticator.
+func newServer(cfg Config, st store.Repository, authSvc authenticator) (*Server, error) {
+	if err := st.PruneWidgetCaches(); err != nil {
+		slog.Warn("failed to prune widget caches", "error", err)
+	}
+	widgets.SetCacheStore(widgetCacheStore{st: st})
+	if err := i18n.LoadDir(filepath.Join(cfg.DataDir, "locales")); err != nil {
+		slog.Warn("failed to load locale catalogs", "error", err)
+	}
+
+	iconStore, bgStore, err := newAssetStorage(cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	iconResolver := icon.New(filepath.Join(cfg.DataDir, "icons"))
-	bgSvc, err := background.New(background.Config{CacheDir: filepath.Join(cfg.DataDir, "cache")})
+	outbound, err := netguard.ParseAllowList(cfg.OutboundAllow)
 	if err != nil {
 		return nil, err
 	}
+	proxies, err := parseCIDRs(strings.Split(cfg.TrustedProxies, ","))
+	if err != nil {
+		return nil, fmt.Errorf("HEARTH_TRUSTED_PROXIES: %w", err)
+	}
+	iconResolver := icon.New(iconStore)
+	iconResolver.MaxIconSize = cfg.IconMaxSize
+	iconResolver.Client = outbound.Client(15*time.Second, false)
+	iconResolver.InsecureClient = outbound.Client(15*time.Second, true)
+	bgSvc, err := background.New(background.Config{
+		Storage:      bgStore,
+		Client:       outbound.Client(15*time.Second, false),
+		APODKey:      cfg.NASAAPIKey,
+		MaxDimension: cfg.BackgroundMaxSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	assetProxy := assetproxy.New(iconStore, outbound.Client(10*time.Second, false), cfg.AssetCacheSize)
+	assetProxy.Register(marketIconSource(cfg.MarketIconBaseURL))
 
-	s := &Server{cfg: cfg, store: st, auth: authSvc, iconResolver: iconResolver, bgSvc: bgSvc}
+	s := &Server{cfg: cfg, store: st, auth: authSvc, iconResolver: iconResolver, bgSvc: bgSvc, iconStore: iconStore, assetProxy: assetProxy, bgStore: bgStore, outbound: &outbound, proxies: proxies, hostMetrics: metrics.NewCollector(), agents: agent.NewClient(), work: newWorkers()}
+	if cfg.GeoNames {
+		s.goWork(func(ctx context.Context) {
+			if err := widgets.EnableGeoNames(ctx, filepath.Join(cfg.DataDir, "geonames")); err != nil {
+				slog.Warn("offline geocoding unavailable", "error", err)
+			}
+		})
+	}
+	s.webhookClient = &http.Client{Timeout: 15 * time.Second}
+	s.webhookWake = make(chan struct{}, 1)
+	s.lucide = lucide.New(filepath.Join(cfg.DataDir, "lucide"))
+	if cfg.DockerHost != "" && cfg.DockerHost != "off" {
+		if s.docker, err = metrics.NewDockerClient(cfg.DockerHost); err != nil {
+			slog.Warn("docker metrics disabled", "error", err)
+		}
+	}
+	if cfg.SMART {
+		s.smart = metrics.NewSMARTReader(cfg.SmartctlPath)
+	}
+	if src, ok := icon.PackSources[cfg.IconPack]; ok {
+		if cfg.IconPackBaseURL != "" {
+			src.BaseURL = strings.TrimRight(cfg.IconPackBaseURL, "/")
+		}
+		s.iconPack = icon.NewPack(src, filepath.Join(cfg.DataDir, "iconpacks", src.Name))
+	} else if cfg.IconPack != "" && cfg.IconPack != "off" {
+		slog.Warn("unknown icon pack", "pack", cfg.IconPack)
+	}
+	if cfg.PluginsDir != "" && cfg.PluginsDir != "off" {
+		if s.plugins, err = plugins.Load(cfg.PluginsDir); err != nil {
+			slog.Warn("widget plugins disabled", "error", err)
+		}
+	}
+	if cfg.ActionRunners != "" {
+		if s.runners, err = loadActionRunners(cfg.ActionRunners); err != nil {
+			slog.Warn("ssh action runners disabled", "error", err)
+		}
+	}
 	if err := s.ensureDefaultSystemTools(); err != nil {
 		return nil, err
 	}
+	if cfg.Demo {
+		if added, err := s.SeedDemo(context.Background()); err != nil {
+			slog.Warn("failed to seed demo data", "error", err)
+		} else if added {
+			slog.Info("seeded demo data")
+		}
+	}
+	s.loadHolidayDataset()
+	s.limiters = map[string]*rateLimiter{}
+	for group, l := range parseRateLimits(cfg.RateLimits) {
+		s.limiters[group] = newRateLimiter(l)
+	}
 	s.router = s.buildRouter()
 	return s, nil
 }
@@ -100,9 +270,10 @@ func (s *Server) buildRouter() chi.Router {
 	r := chi.NewRouter()
 
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
+	r.Use(s.realIP)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Timeout(30 * time.Second))
+	r.Use(s.purgeResponsesOnWrite)
 
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"},
@@ -112,22 +283,15 @@ func (s *Server) buildRouter() chi.Router {
 		MaxAge:           300,
 	}))
 
-	// Serve cached icons (local file cache).
-	iconsDir := http.Dir(filepath.Join(s.cfg.DataDir, "icons"))
-	r.Handle("/assets/icons/*", http.StripPrefix("/assets/icons/", withNoCache(http.FileServer(iconsDir))))
+	// Serve cached icons from asset storage, and icon pack icons from DataDir.
+	r.Get("/assets/icons/pack/{file}", s.handleGetPackIcon)
+	r.Handle("/assets/icons/*", http.StripPrefix("/assets/icons/", withNoCache(storedAssetHandler(s.iconStore))))
 
-	r.Get("/api/health", func(w http.ResponseWriter, r *http.Request) {
-		dbOK := s.store.Ping() == nil
-		status := http.StatusOK
-		if !dbOK {
-			status = http.StatusServiceUnavailable
-		}
-		writeJSON(w, status, map[string]any{
-			"ok":       dbOK,
-			"version":  Version,
-			"database": dbOK,
-		})
-	})
+	// Health: liveness and readiness for orchestrators, and a detailed view
+	// with upstream reachability.
+	r.Get("/api/health", s.handleHealth)
+	r.Get("/api/health/live", s.handleLiveness)
+	r.Get("/api/health/ready", s.handleReadiness)
 
 	r.Group(func(r chi.Router) {
 		r.Use(s.optionalUser)
@@ -136,12 +300,23 @@ func (s *Server) buildRouter() chi.Router {
 	// Auth endpoints are public
 	r.Post("/api/auth/login", s.handleLogin)
 	r.Post("/api/auth/logout", s.handleLogout)
-	// Password change requires admin
-	r.With(s.requireAdmin).Post("/api/auth/password", s.handleChangePassword)
+	// Password change requires a session; a generated password allows
+	// nothing else.
+	r.With(s.requireSession).Post("/api/auth/password", s.handleChangePassword)
 
 	// Settings: GET is public; PUT requires admin.
-	r.Get("/api/settings", s.handleGetSettings)
+	r.With(s.optionalUser).Get("/api/settings", s.handleGetSettings)
 	r.With(s.requireAdmin).Put("/api/settings", s.handlePutSettings)
+	r.Get("/api/settings/schema", s.handleGetSettingsSchema)
+	r.With(s.requireAdmin).Get("/api/settings/user", s.handleGetUserSettings)
+	r.With(s.requireAdmin).Put("/api/settings/user", s.handlePutUserSettings)
+	r.With(s.requireAdmin).Delete("/api/settings/user", s.handleDeleteUserSettings)
+	r.With(s.requireAdmin).Get("/api/settings/history", s.handleListSettingsHistory)
+	r.With(s.requireAdmin).Post("/api/settings/history/{id}/revert", s.handleRevertSettingChange)
+	r.Get("/api/i18n", s.handleListLocales)
+	r.Get("/api/i18n/{locale}", s.handleGetLocaleBundle)
+	r.Get("/api/customization", s.handleGetCustomization)
+	r.With(s.requireAdmin).Put("/api/customization", s.handlePutCustomization)
 
 	// Groups/Apps: list is public; mutations require admin.
 	r.Get("/api/groups", s.handleListGroups)
@@ -150,45 +325,156 @@ func (s *Server) buildRouter() chi.Router {
 	r.With(s.requireAdmin).Delete("/api/groups/{id}", s.handleDeleteGroup)
 	r.With(s.requireAdmin).Post("/api/groups/reorder", s.handleReorderGroups)
 
-	r.Get("/api/apps", s.handleListApps)
+	r.With(s.optionalUser).Get("/api/apps", s.handleListApps)
+	r.With(s.optionalUser).Get("/api/apps/recent", s.handleListRecentApps)
 	r.With(s.requireAdmin).Post("/api/apps", s.handleCreateApp)
 	r.With(s.requireAdmin).Put("/api/apps/{id}", s.handleUpdateApp)
 	r.With(s.requireAdmin).Delete("/api/apps/{id}", s.handleDeleteApp)
 	r.With(s.requireAdmin).Post("/api/apps/reorder", s.handleReorderApps)
+	r.With(s.requireAdmin).Post("/api/apps/move", s.handleMoveApp)
+	r.With(s.requireAdmin).Put("/api/apps/{id}/archive", s.handleArchiveApp)
+	r.With(s.requireAdmin).Post("/api/apps/{id}/wake", s.handleWakeApp)
+	r.With(s.requireAdmin).Post("/api/apps/{id}/preview", s.handleFetchAppPreview)
+	r.With(s.requireAdmin).Delete("/api/apps/{id}/preview", s.handleDeleteAppPreview)
+	r.With(s.requireAdmin).Post("/api/apps/{id}/actions/{actionId}", s.handleRunAppAction)
+
+	// Shares: read-only views of a group or the dashboard behind a token
+	// link; managing them requires admin.
+	r.With(s.rateLimited(rateGroupWidgets)).Get("/api/share/{token}", s.handleGetShare)
+	r.With(s.requireAdmin).Get("/api/admin/shares", s.handleListShares)
+	r.With(s.requireAdmin).Post("/api/admin/shares", s.handleCreateShare)
+	r.With(s.requireAdmin).Delete("/api/admin/shares/{id}", s.handleDeleteShare)
+	r.With(s.requireAdmin).Get("/api/admin/actions/audit", s.handleListActionAudit)
+	r.With(s.requireAdmin).Get("/api/admin/actions/runners", s.handleListActionRunners)
 
 	// Icon resolving requires admin (it performs server-side fetching and caching).
 	r.With(s.requireAdmin).Post("/api/icon/resolve", s.handleResolveIcon)
+	r.With(s.requireAdmin).Post("/api/icons/upload", s.handleUploadIcon)
 
 	// Lucide icon search (public, cached on server).
 	r.Get("/api/icons/lucide/search", s.handleSearchLucideIcons)
 	r.Get("/api/icons/lucide/all", s.handleListAllLucideIcons)
+	r.Get("/api/icons/lucide/{name}.svg", s.handleGetLucideSVG)
+	r.With(s.requireAdmin).Post("/api/admin/icons/lucide/refresh", s.handleRefreshLucideIcons)
+
+	// Icon pack search is public; syncing the pack to disk requires admin.
+	r.Get("/api/icons/pack/search", s.handleSearchPackIcons)
+	r.With(s.requireAdmin).Get("/api/admin/icons/pack", s.handleGetIconPackStatus)
+	r.With(s.requireAdmin).Post("/api/admin/icons/pack/sync", s.handleSyncIconPack)
+
+	// Storage maintenance requires admin.
+	r.With(s.requireAdmin).Get("/api/admin/storage", s.handleGetStorage)
+	r.With(s.requireAdmin).Post("/api/admin/icons/gc", s.handleIconGC)
+	r.With(s.requireAdmin).Get("/api/admin/db/maintenance", s.handleGetDBMaintenance)
+	r.With(s.requireAdmin).Post("/api/admin/db/maintenance", s.handleDBMaintenance)
 
 	// Background is public.
 	r.Get("/api/background", s.handleGetBackground)
 	r.Get("/api/background/image", s.handleGetBackgroundImage)
 	r.With(s.requireAdmin).Post("/api/background/refresh", s.handleRefreshBackground)
+	r.Get("/api/background/history", s.handleBackgroundHistory)
+	r.Get("/api/background/history/{name}", s.handleGetBackgroundHistoryImage)
+	r.With(s.requireAdmin).Post("/api/background/previous", s.handlePreviousBackground)
+	r.With(s.requireAdmin).Post("/api/background/next", s.handleNextBackground)
+	r.Get("/api/background/uploads/{name}", s.handleGetBackgroundUpload)
+	r.With(s.requireAdmin).Get("/api/background/uploads", s.handleListBackgroundUploads)
+	r.With(s.requireAdmin).Post("/api/background/upload", s.handleUploadBackground)
+	r.With(s.requireAdmin).Delete("/api/background/uploads/{name}", s.handleDeleteBackgroundUpload)
+
+	// Widgets are public; responses follow the negotiated locale, and the
+	// units and language of a signed-in user. Requests are rate limited per
+	// IP, lookups more tightly.
+	r.Group(func(r chi.Router) {
+		r.Use(s.optionalUser)
+		r.Use(s.withLocale)
+		r.Use(s.rateLimited(rateGroupWidgets))
+		lookup := s.rateLimited(rateGroupLookup)
+		r.With(s.cachedFor(respTTLWeather), withDeadline(deadlineWeather)).Get("/api/widgets/weather", s.handleGetWeather)
+		r.With(lookup, withDeadline(deadlineGeocode)).Get("/api/widgets/geocode", s.handleSearchCity)
+		r.With(lookup, withDeadline(deadlineGeocode)).Get("/api/widgets/timezone", s.handleGetCityTimezone)
+		r.Get("/api/widgets/timezones", s.handleGetTimezones)
+		r.Get("/api/widgets/clocks", s.handleGetClocks)
+		r.With(s.cachedFor(respTTLMarkets), withDeadline(deadlineMarkets)).Get("/api/widgets/markets", s.handleGetMarkets)
+		r.With(lookup, withDeadline(deadlineMarkets)).Get("/api/widgets/markets/search", s.handleSearchMarkets)
+		r.Get("/api/widgets/markets/icon", s.handleGetMarketIcon)
+		r.Head("/api/widgets/markets/icon", s.handleGetMarketIcon)
+		// Holdings are private; the portfolio is only valued for the admin.
+		r.With(s.requireAdmin, withDeadline(deadlineMarkets)).Get("/api/widgets/portfolio", s.handleGetPortfolio)
+		r.With(s.cachedFor(respTTLHolidays), withDeadline(deadlineHolidays)).Get("/api/widgets/holidays", s.handleGetHolidays)
+		r.With(s.cachedFor(respTTLHolidays), withDeadline(deadlineHolidays)).Get("/api/widgets/holidays/ics", s.handleGetHolidaysICS)
+		r.With(s.cachedFor(respTTLCountries), withDeadline(deadlineHolidays)).Get("/api/widgets/holidays/countries", s.handleListHolidayCountries)
+		r.Get("/api/widgets/embeds", s.handleGetEmbeds)
+		r.Get("/api/widgets/search-config", s.handleGetSearchConfig)
+		r.With(s.cachedFor(respTTLQuote), withDeadline(deadlineQuote)).Get("/api/widgets/quote", s.handleGetQuote)
+		r.Get("/api/widgets/registry", s.handleGetWidgetRegistry)
+		r.Get("/api/widgets/plugins", s.handleListPlugins)
+		r.Get("/api/widgets/{appId}/plugin", s.handleRunPlugin)
+		r.Get("/api/widgets/{appId}/config", s.handleGetWidgetConfig)
+		r.With(s.requireAdmin).Put("/api/widgets/{appId}/config", s.handlePutWidgetConfig)
+	})
+
+	r.With(s.requireAdmin).Get("/api/portfolio/holdings", s.handleListHoldings)
+	r.With(s.requireAdmin).Put("/api/portfolio/holdings", s.handlePutHoldings)
+	r.With(s.requireAdmin).Get("/api/holidays/events", s.handleListCustomEvents)
+	r.With(s.requireAdmin).Put("/api/holidays/events", s.handlePutCustomEvents)
+	r.With(s.requireAdmin).Get("/api/admin/quotes", s.handleListQuotes)
+	r.With(s.requireAdmin).Post("/api/admin/quotes", s.handleAddQuote)
+	r.With(s.requireAdmin).Delete("/api/admin/quotes/{id}", s.handleDeleteQuote)
+
+	// Host metrics are public (visitor dashboard), rate limited per IP.
+	r.Group(func(r chi.Router) {
+		r.Use(s.rateLimited(rateGroupMetrics))
+		r.With(s.optionalUser).Get("/api/metrics/host", s.handleGetHostMetrics)
+		r.Get("/api/metrics/host/history", s.handleGetHostMetricsHistory)
+		r.Get("/api/metrics/docker", s.handleGetDockerMetrics)
+		r.With(s.optionalUser).Get("/api/metrics/smart", s.handleGetSMARTMetrics)
+		r.With(s.optionalUser).Get("/api/metrics/hosts", s.handleListMetricHosts)
+	})
+	r.With(s.requireAdmin).Put("/api/admin/metrics/hosts/{name}", s.handlePutMetricHost)
+	r.With(s.requireAdmin).Delete("/api/admin/metrics/hosts/{name}", s.handleDeleteMetricHost)
 
-	// Widgets are public.
-	r.Get("/api/widgets/weather", s.handleGetWeather)
-	r.Get("/api/widgets/geocode", s.handleSearchCity)
-	r.Get("/api/widgets/timezone", s.handleGetCityTimezone)
-	r.Get("/api/widgets/timezones", s.handleGetTimezones)
-	r.Get("/api/widgets/markets", s.handleGetMarkets)
-	r.Get("/api/widgets/markets/search", s.handleSearchMarkets)
-	r.Get("/api/widgets/markets/icon", s.handleGetMarketIcon)
-	r.Head("/api/widgets/markets/icon", s.handleGetMarketIcon)
-	r.Get("/api/widgets/holidays", s.handleGetHolidays)
-	r.Get("/api/widgets/holidays/countries", s.handleListHolidayCountries)
-
-	// Host metrics are public (visitor dashboard).
-	r.Get("/api/metrics/host", s.handleGetHostMetrics)
+	// Scripts push custom widget values with the ingest token; widgets read
+	// them publicly.
+	r.Post("/api/ingest/{key}", s.handleIngest)
+	r.Get("/api/ingest/{key}", s.handleGetIngest)
 
 	// Import/export requires admin.
 	r.With(s.requireAdmin).Get("/api/export", s.handleExport)
 	r.With(s.requireAdmin).Post("/api/import", s.handleImport)
 
+	// Replicas pull the configuration with the replication token.
+	r.Get("/api/replication/export", s.handleReplicationExport)
+	r.With(s.requireAdmin).Get("/api/admin/replication", s.handleGetReplication)
+	r.With(s.requireAdmin).Post("/api/admin/replication/token", s.handleRotateReplicationToken)
+	r.With(s.requireAdmin).Delete("/api/admin/replication/token", s.handleDeleteReplicationToken)
+	r.With(s.requireAdmin).Post("/api/admin/replication/sync", s.handleReplicaSync)
+
 	// Admin maintenance.
 	r.With(s.requireAdmin).Post("/api/admin/reset", s.handleAdminReset)
+	r.With(s.requireAdmin).Get("/api/admin/undo", s.handleListUndo)
+	r.With(s.requireAdmin).Post("/api/admin/undo", s.handleUndo)
+	r.With(s.requireAdmin).Get("/api/admin/ingest", s.handleListIngest)
+	r.With(s.requireAdmin).Post("/api/admin/ingest/token", s.handleRotateIngestToken)
+	r.With(s.requireAdmin).Delete("/api/admin/ingest/{key}", s.handleDeleteIngest)
+	r.With(s.requireAdmin).Get("/api/admin/webhooks", s.handleListWebhooks)
+	r.With(s.requireAdmin).Post("/api/admin/webhooks", s.handleCreateWebhook)
+	r.With(s.requireAdmin).Post("/api/admin/webhooks/test", s.handleTestWebhooks)
+	r.With(s.requireAdmin).Put("/api/admin/webhooks/{id}", s.handleUpdateWebhook)
+	r.With(s.requireAdmin).Delete("/api/admin/webhooks/{id}", s.handleDeleteWebhook)
+	r.With(s.requireAdmin).Get("/api/admin/webhooks/{id}/deliveries", s.handleListWebhookDeliveries)
+	r.With(s.requireAdmin).Get("/api/admin/backups/targets", s.handleListBackupTargets)
+	r.With(s.requireAdmin).Post("/api/admin/backups/targets", s.handleCreateBackupTarget)
+	r.With(s.requireAdmin).Put("/api/admin/backups/targets/{id}", s.handleUpdateBackupTarget)
+	r.With(s.requireAdmin).Delete("/api/admin/backups/targets/{id}", s.handleDeleteBackupTarget)
+	r.With(s.requireAdmin).Post("/api/admin/backups/targets/{id}/run", s.handleRunBackupTarget)
+	r.With(s.requireAdmin).Get("/api/admin/backups/targets/{id}/backups", s.handleListRemoteBackups)
+	r.With(s.requireAdmin).Get("/api/admin/holidays/dataset", s.handleGetHolidayDataset)
+	r.With(s.requireAdmin).Put("/api/admin/holidays/dataset", s.handlePutHolidayDataset)
+	r.With(s.requireAdmin).Delete("/api/admin/holidays/dataset", s.handleDeleteHolidayDataset)
+	r.With(s.requireAdmin).Post("/api/admin/holidays/dataset/refresh", s.handleRefreshHolidayDataset)
+
+	// Browsers can add /search?q=%s as a custom search engine.
+	r.Get("/search", s.handleSearch)
 
 	// Serve built frontend (if present).
 	if h, ok := tryFrontendHandler(filepath.Join("web", "dist")); ok {
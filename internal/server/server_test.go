@@ -2,11 +2,39 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"golang.org/x/net/webdav"
+
+	"github.com/morezhou/hearth/internal/agent"
+	"github.com/morezhou/hearth/internal/metrics"
+	"github.com/morezhou/hearth/internal/plugins"
+	"github.com/morezhou/hearth/internal/store"
+	"github.com/morezhou/hearth/internal/store/memstore"
+	"github.com/morezhou/hearth/internal/testsupport"
+	"github.com/morezhou/hearth/internal/widgets"
 )
 
 func TestHealth(t *testing.T) {
@@ -19,6 +47,95 @@ func TestHealth(t *testing.T) {
 	}
 }
 
+func TestHealthProbes(t *testing.T) {
+	up := testsupport.NewUpstream(t)
+	up.UseForWidgets(t)
+	s := newTestServer(t)
+
+	for _, p := range []string{"/api/health/live", "/api/health/ready"} {
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, p, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", p, w.Code, w.Body.String())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/health?detail=true", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var body struct {
+		OK       bool `json:"ok"`
+		Degraded bool `json:"degraded"`
+		Checks   struct {
+			Upstreams map[string]struct {
+				OK bool `json:"ok"`
+			} `json:"upstreams"`
+		} `json:"checks"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !body.OK || body.Degraded || !body.Checks.Upstreams["weather"].OK {
+		t.Fatalf("unexpected health: %s", w.Body.String())
+	}
+
+	// Upstream results are cached: a second detailed check doesn't probe again.
+	hits := up.Hits("/open-meteo")
+	s.Router().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/health?detail=true", nil))
+	if got := up.Hits("/open-meteo"); got != hits {
+		t.Fatalf("expected cached upstream check, got %d probes", got)
+	}
+
+	// A database that went away makes the instance unready, not dead.
+	if err := s.store.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/health/ready", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("ready: expected 503, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/health/live", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("live: expected 200, got %d", w.Code)
+	}
+}
+
+func TestShutdownWaitsForWork(t *testing.T) {
+	s := newTestServer(t)
+	finished := make(chan struct{})
+	s.goWork(func(ctx context.Context) {
+		<-ctx.Done()
+		time.Sleep(50 * time.Millisecond) // e.g. finishing a write
+		close(finished)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	select {
+	case <-finished:
+	default:
+		t.Fatal("Shutdown returned before the task finished")
+	}
+	if err := s.store.Ping(); err == nil {
+		t.Fatal("expected the database to be closed")
+	}
+
+	// Nothing new starts once shutting down.
+	ran := false
+	s.goWork(func(context.Context) { ran = true })
+	s.work.wg.Wait()
+	if ran {
+		t.Fatal("task started after Shutdown")
+	}
+}
+
 func TestSettingsAuth(t *testing.T) {
 	s := newTestServer(t)
 
@@ -93,36 +210,3140 @@ func TestBackupAuth(t *testing.T) {
 	}
 }
 
-func newTestServer(t *testing.T) *Server {
-	t.Helper()
-	dataDir := t.TempDir()
-	cfg := Config{
-		Addr:        ":0",
-		DataDir:     dataDir,
-		DatabaseDSN: filepath.Join(dataDir, "test.db"),
-		SessionTTL:  "1h",
+func TestScopedExport(t *testing.T) {
+	s, st := newMemTestServer(t)
+	cookie := loginAsAdmin(t, s)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		return w
 	}
-	s, err := New(cfg)
+	export := func(query string) store.Export {
+		t.Helper()
+		w := do(http.MethodGet, "/api/export"+query, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("export %s: expected 200, got %d: %s", query, w.Code, w.Body.String())
+		}
+		var p store.Export
+		if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	g, _ := st.CreateGroup("Media", GroupKindApp)
+	_, _ = st.CreateApp(store.AppItem{GroupID: &g.ID, Name: "Plex", URL: "http://plex.lan"})
+	_, _ = st.CreateApp(store.AppItem{Name: "Clock", URL: "widget:clock"})
+	_ = st.ReplaceHoldings([]store.Holding{{Symbol: "MSFT", Quantity: 1}})
+	_ = st.SetKV(kvSiteTitle, "Cabin")
+	_ = st.SetKV(kvWeatherAPIKey, "secret")
+	_ = st.SetKV(kvMetricsInterfaces, `["eth0"]`)
+	_ = st.SetKV(kvIngestTokenHash, "hash")
+	_ = st.SetUserKV("admin-id", kvLanguage, "en")
+
+	if w := do(http.MethodGet, "/api/export?scope=layout", ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown scope: expected 400, got %d", w.Code)
+	}
+
+	p := export("?scope=settings")
+	if p.Settings[kvSiteTitle] != "Cabin" || len(p.Settings) != 1 || len(p.Groups) != 0 || len(p.Apps) != 0 {
+		t.Fatalf("settings export should hold only shared, non-secret settings: %+v", p)
+	}
+	if !slices.Equal(p.Scopes, []string{exportSettings}) {
+		t.Errorf("scopes = %v", p.Scopes)
+	}
+
+	p = export("?scope=users,apps&scope=groups")
+	if !slices.Equal(p.Scopes, []string{exportGroups, exportApps, exportUsers}) {
+		t.Errorf("scopes = %v", p.Scopes)
+	}
+	if len(p.Groups) != 1 || len(p.Apps) != 1 || p.Apps[0].Name != "Plex" || len(p.Holdings) != 0 || len(p.Settings) != 0 {
+		t.Fatalf("unexpected layout export: %+v", p)
+	}
+	if len(p.Users) != 1 || p.Users[0].Username != "admin" || p.Users[0].Settings[kvLanguage] != "en" {
+		t.Fatalf("unexpected users: %+v", p.Users)
+	}
+
+	widgets := export("?scope=widgets")
+	if len(widgets.Groups) != 1 || widgets.Groups[0].Kind != GroupKindSystem || len(widgets.Holdings) != 1 {
+		t.Fatalf("unexpected widgets export: %+v", widgets)
+	}
+	for _, a := range widgets.Apps {
+		if !strings.HasPrefix(a.URL, "widget:") {
+			t.Errorf("widgets export holds app %s", a.Name)
+		}
+	}
+
+	// Widgets of another instance's system group move to this one's.
+	system := widgets.Groups[0].ID
+	widgets.Groups[0].ID = "other-system"
+	for i := range widgets.Apps {
+		widgets.Apps[i].ID += "-copy"
+		if widgets.Apps[i].GroupID != nil {
+			widgets.Apps[i].GroupID = &widgets.Groups[0].ID
+		}
+	}
+	b, _ := json.Marshal(widgets)
+	if resp := do(http.MethodPost, "/api/import", string(b)); resp.Code != http.StatusOK {
+		t.Fatalf("import widgets: %d %s", resp.Code, resp.Body.String())
+	}
+	groups, _ := st.ListGroups()
+	for _, g := range groups {
+		if g.Kind == GroupKindSystem && g.ID != system {
+			t.Errorf("a second system group was imported: %+v", g)
+		}
+	}
+	list, _ := st.ListApps()
+	for _, a := range list {
+		if strings.HasSuffix(a.ID, "-copy") && a.GroupID != nil && *a.GroupID != system {
+			t.Errorf("widget %s is in group %s", a.Name, *a.GroupID)
+		}
+	}
+
+	// Apps whose group isn't there end up ungrouped, and settings of unknown
+	// users are skipped.
+	apps := export("?scope=apps")
+	elsewhere := "elsewhere"
+	apps.Apps[0].ID, apps.Apps[0].GroupID = "imported", &elsewhere
+	apps.Users = []store.UserExport{{Username: "admin", Settings: map[string]string{kvLanguage: "de"}}, {Username: "guest", Settings: map[string]string{kvLanguage: "fr"}}}
+	b, _ = json.Marshal(apps)
+	resp := do(http.MethodPost, "/api/import", string(b))
+	if resp.Code != http.StatusOK || !strings.Contains(resp.Body.String(), `"skippedUsers":["guest"]`) {
+		t.Fatalf("import: %d %s", resp.Code, resp.Body.String())
+	}
+	list, _ = st.ListApps()
+	for _, a := range list {
+		if a.ID == "imported" && a.GroupID != nil {
+			t.Errorf("imported app kept a missing group: %v", *a.GroupID)
+		}
+	}
+	if o, _ := st.ListUserKV("admin-id"); o[kvLanguage] != "de" {
+		t.Errorf("user language = %q, want de", o[kvLanguage])
+	}
+	if v, _, _ := st.GetKV(kvWeatherAPIKey); v != "secret" {
+		t.Error("a partial import must leave other settings alone")
+	}
+}
+
+func TestMarketIcon(t *testing.T) {
+	pngBytes := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 64)...)
+	var hits int
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		switch r.URL.Path {
+		case "/crypto_icons/BTC.png":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write(pngBytes)
+		case "/ticker_icons/EVIL.png":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte("<svg onload=alert(1)></svg>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer upstream.Close()
+
+	st := memstore.New()
+	if err := st.Migrate(); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	s, err := newServer(Config{Addr: ":0", DataDir: t.TempDir(), MarketIconBaseURL: upstream.URL, OutboundAllow: "127.0.0.1"}, st, &fakeAuth{sessions: map[string]string{}})
 	if err != nil {
-		t.Fatalf("New: %v", err)
+		t.Fatalf("newServer: %v", err)
+	}
+	get := func(method, symbol string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, httptest.NewRequest(method, "/api/widgets/markets/icon?symbol="+symbol, nil))
+		return w
+	}
+
+	w := get(http.MethodGet, "btc")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/png" || !bytes.Equal(w.Body.Bytes(), pngBytes) {
+		t.Fatalf("BTC: %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	before := hits
+	if w := get(http.MethodHead, "BTC"); w.Code != http.StatusOK || w.Body.Len() != 0 || hits != before {
+		t.Fatalf("cached HEAD: %d (%d upstream hits)", w.Code, hits-before)
+	}
+	if w := get(http.MethodGet, "EVIL"); w.Code != http.StatusNotFound {
+		t.Fatalf("a non-PNG icon should be rejected, got %d", w.Code)
+	}
+	if _, err := s.iconStore.Stat(context.Background(), "markets/EVIL.png"); err == nil {
+		t.Fatal("a rejected icon was cached")
 	}
-	return s
 }
 
-func loginAsAdmin(t *testing.T, s *Server) *http.Cookie {
-	t.Helper()
-	body := bytes.NewBufferString(`{"username":"admin","password":"admin"}`)
-	req := httptest.NewRequest(http.MethodPost, "/api/auth/login", body)
+func TestRemoteBackups(t *testing.T) {
+	old := remoteBackupRetryDelay
+	remoteBackupRetryDelay = 0
+	t.Cleanup(func() { remoteBackupRetryDelay = old })
+
+	dav := &webdav.Handler{FileSystem: webdav.NewMemFS(), LockSystem: webdav.NewMemLS()}
+	var mu sync.Mutex
+	failPuts := 0
+	nas := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if u, p, ok := r.BasicAuth(); !ok || u != "hearth" || p != "pw" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		fail := r.Method == http.MethodPut && failPuts > 0
+		if fail {
+			failPuts--
+		}
+		mu.Unlock()
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		dav.ServeHTTP(w, r)
+	}))
+	defer nas.Close()
+	ctx := context.Background()
+	_ = dav.FileSystem.Mkdir(ctx, "/dav", 0o755)
+	for _, name := range []string{"hearth-backup-20200101-000000.json", "hearth-backup-20200102-000000.json", "notes.txt"} {
+		f, _ := dav.FileSystem.OpenFile(ctx, "/dav/"+name, os.O_CREATE|os.O_WRONLY, 0o644)
+		_, _ = f.Write([]byte("{}"))
+		f.Close()
+	}
+
+	s, _ := newMemTestServer(t)
+	cookie := loginAsAdmin(t, s)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(http.MethodPost, "/api/admin/backups/targets", `{"name":"nas","kind":"webdav","config":{}}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("a webdav target without a url: %d %s", w.Code, w.Body.String())
+	}
+	w := do(http.MethodPost, "/api/admin/backups/targets", fmt.Sprintf(`{"name":"nas","kind":"webdav","config":{"url":%q,"username":"hearth"},"secret":"pw","keep":2}`, nas.URL+"/dav"))
+	if w.Code != http.StatusCreated || strings.Contains(w.Body.String(), `"pw"`) {
+		t.Fatalf("create: %d %s", w.Code, w.Body.String())
+	}
+	var target backupTargetView
+	_ = json.Unmarshal(w.Body.Bytes(), &target)
+	if !target.HasSecret || target.IntervalSeconds != 86400 || !target.Enabled {
+		t.Fatalf("unexpected target: %+v", target)
+	}
+	path := fmt.Sprintf("/api/admin/backups/targets/%d", target.ID)
+
+	// Two failed uploads are retried; pruning keeps the two newest backups
+	// and leaves other files alone.
+	mu.Lock()
+	failPuts = 2
+	mu.Unlock()
+	if w := do(http.MethodPost, path+"/run", ""); w.Code != http.StatusOK {
+		t.Fatalf("run: %d %s", w.Code, w.Body.String())
+	}
+	w = do(http.MethodGet, path+"/backups", "")
+	var listed struct {
+		Backups []remoteBackupView `json:"backups"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &listed)
+	if len(listed.Backups) != 2 || listed.Backups[1].Key != "hearth-backup-20200102-000000.json" {
+		t.Fatalf("unexpected remote backups: %s", w.Body.String())
+	}
+	if _, err := dav.FileSystem.Stat(ctx, "/dav/notes.txt"); err != nil {
+		t.Errorf("pruning removed an unrelated file: %v", err)
+	}
+
+	// An empty secret keeps the stored password.
+	if w := do(http.MethodPut, path, fmt.Sprintf(`{"name":"nas","kind":"webdav","config":{"url":%q,"username":"hearth"},"keep":2}`, nas.URL+"/dav")); w.Code != http.StatusOK {
+		t.Fatalf("update: %d %s", w.Code, w.Body.String())
+	}
+	mu.Lock()
+	failPuts = remoteBackupAttempts
+	mu.Unlock()
+	w = do(http.MethodPost, path+"/run", "")
+	if w.Code != http.StatusBadGateway || !strings.Contains(w.Body.String(), "status=503") {
+		t.Fatalf("failing run: %d %s", w.Code, w.Body.String())
+	}
+	bt, _, _ := s.store.GetBackupTarget(target.ID)
+	if bt.Secret != "pw" || bt.LastError == "" || bt.LastSuccessAt == 0 {
+		t.Fatalf("unexpected target after a failed run: %+v", bt)
+	}
+
+	now := time.Unix(bt.LastAttemptAt, 0)
+	if backupDue(bt, now.Add(30*time.Minute)) || !backupDue(bt, now.Add(remoteBackupRetryAfter)) {
+		t.Error("a failed target should be retried after remoteBackupRetryAfter")
+	}
+	bt.LastError = ""
+	if backupDue(bt, now.Add(remoteBackupRetryAfter)) || !backupDue(bt, now.Add(24*time.Hour)) {
+		t.Error("a target should be due after its interval")
+	}
+
+	if w := do(http.MethodDelete, path, ""); w.Code != http.StatusOK {
+		t.Fatalf("delete: %d %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodPost, path+"/run", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("run of a deleted target: %d", w.Code)
+	}
+}
+
+func TestReplication(t *testing.T) {
+	primary, pst := newMemTestServer(t)
+	cookie := loginAsAdmin(t, primary)
+	srv := httptest.NewServer(primary.Router())
+	defer srv.Close()
+
+	if resp, err := http.Get(srv.URL + "/api/replication/export"); err != nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("export without a token: %v %v", resp, err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/replication/token", nil)
+	req.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	primary.Router().ServeHTTP(w, req)
+	var tok struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &tok); err != nil || tok.Token == "" {
+		t.Fatalf("rotate token: %d %s", w.Code, w.Body.String())
+	}
+
+	g, _ := pst.CreateGroup("Media", GroupKindApp)
+	_, _ = pst.CreateApp(store.AppItem{GroupID: &g.ID, Name: "Plex", URL: "http://plex.lan"})
+	_ = pst.SetKV(kvSiteTitle, "Main")
+	_ = pst.SetUserKV("admin-id", kvLanguage, "en")
+
+	replica, rst := newMemTestServer(t)
+	replica.cfg.ReplicaOf, replica.cfg.ReplicaToken = srv.URL, "wrong"
+	if err := replica.replicate(context.Background()); err == nil || replica.replicaStatus().LastError == "" {
+		t.Fatalf("a wrong token should fail: %v", err)
+	}
+	_ = rst.SetKV(kvReplicationTokenHash, "own")
+	_ = rst.SetKV(kvIngestTokenHash, hashIngestToken("replica-ingest"))
+	_ = pst.SetKV(kvIngestTokenHash, hashIngestToken("primary-ingest"))
+	_ = pst.SetKV(kvMetricsDiskPaths, `[{"path":"/mnt/primary"}]`)
+
+	replica.cfg.ReplicaToken = tok.Token
+	if err := replica.replicate(context.Background()); err != nil {
+		t.Fatalf("replicate: %v", err)
+	}
+	if v, _, _ := rst.GetKV(kvSiteTitle); v != "Main" {
+		t.Errorf("site title = %q, want Main", v)
+	}
+	if v, _, _ := rst.GetKV(kvReplicationTokenHash); v != "own" {
+		t.Errorf("the replica's own token was replaced: %q", v)
+	}
+	if v, _, _ := rst.GetKV(kvIngestTokenHash); v != hashIngestToken("replica-ingest") {
+		t.Errorf("the replica's ingest token was replaced: %q", v)
+	}
+	if v, ok, _ := rst.GetKV(kvMetricsDiskPaths); ok {
+		t.Errorf("the primary's disk paths were copied: %q", v)
+	}
+	if o, _ := rst.ListUserKV("admin-id"); o[kvLanguage] != "en" {
+		t.Errorf("user settings not replicated: %v", o)
+	}
+	apps, _ := rst.ListApps()
+	found := false
+	for _, a := range apps {
+		found = found || a.Name == "Plex"
+	}
+	if !found {
+		t.Error("apps not replicated")
+	}
+	st := replica.replicaStatus()
+	if st.LastError != "" || st.LastChangeAt == 0 || st.LastSyncAt == 0 {
+		t.Errorf("unexpected status: %+v", st)
+	}
+
+	// Unchanged configurations aren't applied again.
+	_ = rst.SetKV(kvSiteTitle, "Local")
+	if err := replica.replicate(context.Background()); err != nil {
+		t.Fatalf("replicate again: %v", err)
+	}
+	if v, _, _ := rst.GetKV(kvSiteTitle); v != "Local" {
+		t.Errorf("an unchanged configuration was applied again")
+	}
+	_ = pst.SetKV(kvSiteTitle, "Main 2")
+	if err := replica.replicate(context.Background()); err != nil {
+		t.Fatalf("replicate change: %v", err)
+	}
+	if v, _, _ := rst.GetKV(kvSiteTitle); v != "Main 2" {
+		t.Errorf("site title = %q, want Main 2", v)
+	}
+}
+
+func TestDBMaintenance(t *testing.T) {
+	s := newTestServer(t)
+
 	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/admin/db/maintenance", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", w.Code)
+	}
+
+	cookie := loginAsAdmin(t, s)
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/db/maintenance", nil)
+	req.AddCookie(cookie)
+	w = httptest.NewRecorder()
 	s.Router().ServeHTTP(w, req)
 	if w.Code != http.StatusOK {
-		t.Fatalf("login expected 200, got %d", w.Code)
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
 	}
-	for _, c := range w.Result().Cookies() {
-		if c.Name == "hearth_session" {
-			return c
+	var rep store.MaintenanceReport
+	if err := json.Unmarshal(w.Body.Bytes(), &rep); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !rep.IntegrityOK || !rep.Vacuumed {
+		t.Fatalf("unexpected report: %s", w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/admin/db/maintenance", nil)
+	req.AddCookie(cookie)
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, req)
+	if !strings.Contains(w.Body.String(), `"integrityOk":true`) {
+		t.Fatalf("expected the last report, got %s", w.Body.String())
+	}
+}
+
+func TestHostMetricsPrivacy(t *testing.T) {
+	s := newTestServer(t)
+
+	// guest: hostname hidden by default
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/metrics/host", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &m); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if _, ok := m["hostname"]; ok {
+		t.Fatalf("expected hostname to be hidden from guests, got %v", m["hostname"])
+	}
+
+	// guest: cpu model hidden once enabled
+	if err := s.store.SetKV(kvMetricsHideCPUModel, "true"); err != nil {
+		t.Fatalf("SetKV: %v", err)
+	}
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/metrics/host", nil))
+	m = map[string]any{}
+	_ = json.Unmarshal(w.Body.Bytes(), &m)
+	if m["cpuModel"] != "" {
+		t.Fatalf("expected empty cpuModel, got %v", m["cpuModel"])
+	}
+}
+
+func TestHostMetricsDeviceSettings(t *testing.T) {
+	s := newTestServer(t)
+	cookie := loginAsAdmin(t, s)
+
+	opts := s.metricsOptions()
+	if len(opts.Interfaces) != 0 || len(opts.ExcludeMounts) != len(metrics.DefaultExcludedMounts) {
+		t.Fatalf("unexpected default options: %+v", opts)
+	}
+
+	body := `{"metrics":{"hideHostname":true,"interfaces":["eth*"," ",""],"excludeMounts":["tmpfs","/mnt/backup"]}}`
+	req := httptest.NewRequest(http.MethodPut, "/api/settings", bytes.NewBufferString(body))
+	req.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	opts = s.metricsOptions()
+	if len(opts.Interfaces) != 1 || opts.Interfaces[0] != "eth*" {
+		t.Fatalf("unexpected interfaces: %v", opts.Interfaces)
+	}
+	if len(opts.ExcludeMounts) != 2 || opts.ExcludeMounts[1] != "/mnt/backup" {
+		t.Fatalf("unexpected excludes: %v", opts.ExcludeMounts)
+	}
+
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/metrics/host", nil))
+	var m metrics.HostMetrics
+	if err := json.Unmarshal(w.Body.Bytes(), &m); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	for _, ni := range m.Interfaces {
+		if !strings.HasPrefix(ni.Name, "eth") {
+			t.Fatalf("interface %q not filtered", ni.Name)
 		}
 	}
-	t.Fatalf("missing session cookie")
-	return nil
+	for _, mt := range m.Mounts {
+		if mt.FSType == "tmpfs" || strings.HasPrefix(mt.Path, "/mnt/backup") {
+			t.Fatalf("mount %+v not excluded", mt)
+		}
+	}
+}
+
+func TestHostMetricsDiskPaths(t *testing.T) {
+	s := newTestServer(t)
+	cookie := loginAsAdmin(t, s)
+	dir := t.TempDir()
+	s.cfg.MetricsDiskPaths = metrics.ParseDiskPaths("/")
+
+	if opts := s.metricsOptions(); len(opts.DiskPaths) != 1 || opts.DiskPaths[0].Path != "/" {
+		t.Fatalf("expected the env disk paths, got %+v", opts.DiskPaths)
+	}
+
+	body := `{"metrics":{"diskPaths":[{"path":" ` + dir + `/ ","label":"Data"},{"path":"","label":"empty"}]}}`
+	req := httptest.NewRequest(http.MethodPut, "/api/settings", bytes.NewBufferString(body))
+	req.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var m metrics.HostMetrics
+	if code := getJSON(t, s, "/api/metrics/host", &m); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if len(m.Mounts) != 1 || m.Mounts[0].Path != dir || m.Mounts[0].Label != "Data" {
+		t.Fatalf("expected only the configured path, got %+v", m.Mounts)
+	}
+	if m.DiskTotal == 0 || m.DiskTotal != m.Mounts[0].Total {
+		t.Fatalf("expected the disk totals of the first path, got %d", m.DiskTotal)
+	}
+}
+
+func TestHostMetricsSnapshotShared(t *testing.T) {
+	s := newTestServer(t)
+	s.cfg.MetricsCollectInterval = time.Minute
+
+	var first, second metrics.HostMetrics
+	if code := getJSON(t, s, "/api/metrics/host", &first); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if code := getJSON(t, s, "/api/metrics/host", &second); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if first.CollectedAt == 0 || second.CollectedAt != first.CollectedAt {
+		t.Fatalf("expected the cached snapshot, got collectedAt %d then %d", first.CollectedAt, second.CollectedAt)
+	}
+
+	// A stale snapshot is replaced on demand.
+	s.hostSnapshot.mu.Lock()
+	s.hostSnapshot.at = time.Now().Add(-time.Hour)
+	s.hostSnapshot.mu.Unlock()
+	if code := getJSON(t, s, "/api/metrics/host", &second); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if second.CollectedAt == first.CollectedAt {
+		t.Fatalf("expected a fresh collection")
+	}
+}
+
+func TestHostMetricsHistory(t *testing.T) {
+	s := newTestServer(t)
+
+	if err := s.sampleMetrics(context.Background()); err != nil {
+		t.Fatalf("sampleMetrics: %v", err)
+	}
+	old := time.Now().Add(-2 * time.Hour).Unix()
+	if err := s.store.AddMetricsSample(store.MetricsSample{At: old, CPU: 99}); err != nil {
+		t.Fatalf("AddMetricsSample: %v", err)
+	}
+
+	var res metricsHistoryResponse
+	if code := getJSON(t, s, "/api/metrics/host/history", &res); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if res.Range != "1h" || res.Step != 30 || len(res.Points) != 1 {
+		t.Fatalf("unexpected 1h history: %+v", res)
+	}
+	if code := getJSON(t, s, "/api/metrics/host/history?range=24h", &res); code != http.StatusOK || len(res.Points) != 2 || res.Points[0].CPU != 99 {
+		t.Fatalf("unexpected 24h history (%d): %+v", code, res)
+	}
+	if code := getJSON(t, s, "/api/metrics/host/history?range=1y", &res); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown range, got %d", code)
+	}
+}
+
+func TestDockerMetrics(t *testing.T) {
+	s := newTestServer(t)
+
+	var out map[string]any
+	if code := getJSON(t, s, "/api/metrics/docker", &out); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 while disabled, got %d", code)
+	}
+
+	var names []string
+	docker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/containers/json" {
+			_, _ = w.Write([]byte(`[{"Id":"1","Names":["/plex"]},{"Id":"2","Names":["/sonarr"]}]`))
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer docker.Close()
+	var err error
+	if s.docker, err = metrics.NewDockerClient("tcp://" + strings.TrimPrefix(docker.URL, "http://")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.store.SetKV(kvMetricsContainers, `["plex"]`); err != nil {
+		t.Fatalf("SetKV: %v", err)
+	}
+	var res struct {
+		Containers []metrics.Container `json:"containers"`
+	}
+	if code := getJSON(t, s, "/api/metrics/docker", &res); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	for _, c := range res.Containers {
+		names = append(names, c.Name)
+	}
+	if len(names) != 1 || names[0] != "plex" {
+		t.Fatalf("expected only plex, got %v", names)
+	}
+}
+
+func TestRemoteMetricHosts(t *testing.T) {
+	s := newTestServer(t)
+	cookie := loginAsAdmin(t, s)
+	remote := httptest.NewServer(agent.Handler(agent.Config{Token: "tok"}, metrics.NewCollector()))
+	defer remote.Close()
+
+	put := func(name, body string) int {
+		req := httptest.NewRequest(http.MethodPut, "/api/admin/metrics/hosts/"+name, bytes.NewBufferString(body))
+		req.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := put("NAS!", `{"url":"`+remote.URL+`","token":"tok"}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a bad name, got %d", code)
+	}
+	if code := put("nas", `{"url":"ftp://nas","token":"tok"}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a bad url, got %d", code)
+	}
+	if code := put("nas", `{"url":"`+remote.URL+`","token":"tok"}`); code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", code)
+	}
+
+	var m metrics.HostMetrics
+	if code := getJSON(t, s, "/api/metrics/host?host=nas", &m); code != http.StatusOK || m.CollectedAt == 0 {
+		t.Fatalf("expected remote metrics, got %d %+v", code, m)
+	}
+	if code := getJSON(t, s, "/api/metrics/host?host=vps", &m); code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown host, got %d", code)
+	}
+
+	var hosts []metricHostView
+	if code := getJSON(t, s, "/api/metrics/hosts", &hosts); code != http.StatusOK || len(hosts) != 1 || !hosts[0].Online || hosts[0].URL != "" {
+		t.Fatalf("unexpected guest host list (%d): %+v", code, hosts)
+	}
+
+	// A wrong token (kept when updating without one) makes the host unreachable.
+	if code := put("nas", `{"url":"`+remote.URL+`/"}`); code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", code)
+	}
+	if code := getJSON(t, s, "/api/metrics/host?host=nas", &m); code != http.StatusOK {
+		t.Fatalf("expected the kept token to work, got %d", code)
+	}
+	if code := put("nas", `{"url":"`+remote.URL+`","token":"wrong"}`); code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", code)
+	}
+	if code := getJSON(t, s, "/api/metrics/host?host=nas", &m); code != http.StatusBadGateway {
+		t.Fatalf("expected 502 with a wrong token, got %d", code)
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/admin/metrics/hosts/nas", nil)
+	req.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", w.Code)
+	}
+}
+
+func TestLocaleNegotiation(t *testing.T) {
+	s := newTestServer(t)
+	h := s.withLocale(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(localeFromRequest(r)))
+	}))
+	get := func(target, acceptLang string) string {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if acceptLang != "" {
+			req.Header.Set("Accept-Language", acceptLang)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	if got := get("/", ""); got != defaultLocale {
+		t.Fatalf("default: got %q", got)
+	}
+	if got := get("/", "fr-FR, en-US;q=0.8, zh;q=0.5"); got != "fr" {
+		t.Fatalf("header: got %q", got)
+	}
+	if got := get("/", "sv-SE, en-US;q=0.8, zh;q=0.5"); got != "en" {
+		t.Fatalf("header without a catalog for the first choice: got %q", got)
+	}
+	if err := s.store.SetKV(kvLanguage, "zh"); err != nil {
+		t.Fatalf("SetKV: %v", err)
+	}
+	if got := get("/", "en-US"); got != "zh" {
+		t.Fatalf("setting should beat header: got %q", got)
+	}
+	if got := get("/?lang=en-GB", "zh-CN"); got != "en" {
+		t.Fatalf("query should win: got %q", got)
+	}
+}
+
+func TestI18n(t *testing.T) {
+	s := newTestServer(t)
+	cookie := loginAsAdmin(t, s)
+
+	var locales []localeInfo
+	if code := getJSON(t, s, "/api/i18n", &locales); code != http.StatusOK || len(locales) < 2 {
+		t.Fatalf("unexpected locales: %d %+v", code, locales)
+	}
+	var bundle struct {
+		Locale   string            `json:"locale"`
+		Messages map[string]string `json:"messages"`
+	}
+	if code := getJSON(t, s, "/api/i18n/de-AT", &bundle); code != http.StatusOK || bundle.Locale != "de" || bundle.Messages["widget.weather"] != "Wetter" {
+		t.Fatalf("unexpected bundle: %d %+v", code, bundle)
+	}
+	if code := getJSON(t, s, "/api/i18n/sv", &bundle); code != http.StatusNotFound {
+		t.Fatalf("expected 404 without a catalog, got %d", code)
+	}
+	if code := getJSON(t, s, "/api/i18n/not%20a%20tag", &bundle); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a bad tag, got %d", code)
+	}
+
+	put := func(body string) int {
+		req := httptest.NewRequest(http.MethodPut, "/api/settings", bytes.NewBufferString(body))
+		req.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := put(`{"language":"klingon!"}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a bad language, got %d", code)
+	}
+	if code := put(`{"language":"de_at"}`); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	var st Settings
+	getJSON(t, s, "/api/settings", &st)
+	if st.Language != "de-AT" {
+		t.Fatalf("language = %q", st.Language)
+	}
+	if f := st.Formatting; f == nil || f.Decimal != "," || f.Group != "." || f.Hour12 || f.FirstDayOfWeek != 1 || !f.CurrencyAfter {
+		t.Fatalf("unexpected formatting hints: %+v", f)
+	}
+	var reg []WidgetTypeInfo
+	if code := getJSON(t, s, "/api/widgets/registry", &reg); code != http.StatusOK || reg[0].Name != "Wetter" {
+		t.Fatalf("expected German widget names, got %d %+v", code, reg)
+	}
+}
+
+func TestResolveIconBlocksInternalURLs(t *testing.T) {
+	s := newTestServer(t)
+	cookie := loginAsAdmin(t, s)
+	for target, want := range map[string]int{
+		"http://127.0.0.1:8787/":         http.StatusForbidden,
+		"http://169.254.169.254/latest/": http.StatusForbidden,
+		"http://192.168.1.2/":            http.StatusForbidden,
+		"file:///etc/passwd":             http.StatusBadRequest,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/icon/resolve", strings.NewReader(`{"url":"`+target+`"}`))
+		req.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		if w.Code != want {
+			t.Errorf("%s: expected %d, got %d %s", target, want, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestIconUpload(t *testing.T) {
+	s := newTestServer(t)
+	cookie := loginAsAdmin(t, s)
+	upload := func(data []byte, format string, auth bool) (int, uploadIconResponse) {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, _ := mw.CreateFormFile("file", "icon.jpg")
+		_, _ = fw.Write(data)
+		if format != "" {
+			_ = mw.WriteField("format", format)
+		}
+		_ = mw.Close()
+		req := httptest.NewRequest(http.MethodPost, "/api/icons/upload", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		if auth {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		var res uploadIconResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &res)
+		return w.Code, res
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	img.Set(3, 3, color.RGBA{R: 200, A: 255})
+	var jpg bytes.Buffer
+	if err := jpeg.Encode(&jpg, img, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if code, _ := upload(jpg.Bytes(), "", false); code != http.StatusUnauthorized {
+		t.Fatalf("guest upload: expected 401, got %d", code)
+	}
+	code, first := upload(jpg.Bytes(), "", true)
+	if code != http.StatusOK || !strings.HasSuffix(first.IconPath, ".jpg") || first.IconSource != "upload" || first.Existing {
+		t.Fatalf("unexpected upload: %d %+v", code, first)
+	}
+	if _, err := s.iconStore.Stat(t.Context(), first.IconPath); err != nil {
+		t.Fatalf("uploaded icon not stored: %v", err)
+	}
+	// Same content is deduplicated.
+	if code, again := upload(jpg.Bytes(), "", true); code != http.StatusOK || again.IconPath != first.IconPath || !again.Existing {
+		t.Fatalf("expected the existing icon, got %d %+v", code, again)
+	}
+	if code, conv := upload(jpg.Bytes(), "png", true); code != http.StatusOK || !strings.HasSuffix(conv.IconPath, ".png") {
+		t.Fatalf("expected a PNG re-encode, got %d %+v", code, conv)
+	}
+
+	// SVGs are stored without scripts and served under a restrictive CSP.
+	code, svg := upload([]byte(`<svg xmlns="http://www.w3.org/2000/svg" onload="alert(1)"><script>alert(2)</script><circle r="4"/></svg>`), "", true)
+	if code != http.StatusOK || !strings.HasSuffix(svg.IconPath, ".svg") {
+		t.Fatalf("unexpected SVG upload: %d %+v", code, svg)
+	}
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, svg.IconURL, nil))
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "alert") || !strings.Contains(w.Body.String(), "<circle") || w.Header().Get("Content-Security-Policy") == "" {
+		t.Fatalf("expected a sanitized SVG, got %d %q csp=%q", w.Code, w.Body.String(), w.Header().Get("Content-Security-Policy"))
+	}
+
+	if code, _ := upload([]byte("not an image"), "", true); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a non-image, got %d", code)
+	}
+	if code, _ := upload(jpg.Bytes(), "webp", true); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unsupported format, got %d", code)
+	}
+	if code, _ := upload(bytes.Repeat([]byte{0x89, 'P', 'N', 'G'}, 1<<20), "", true); code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413 for an oversized file, got %d", code)
+	}
+}
+
+func TestIconPackApps(t *testing.T) {
+	var pngData bytes.Buffer
+	_ = png.Encode(&pngData, image.NewGray(image.Rect(0, 0, 4, 4)))
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/tree.json":
+			_, _ = w.Write([]byte(`{"png":["plex.png","jellyfin.png"]}`))
+		case "/png/plex.png":
+			_, _ = w.Write(pngData.Bytes())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer up.Close()
+
+	dataDir := t.TempDir()
+	s, err := New(Config{Addr: ":0", DataDir: dataDir, DatabaseDSN: filepath.Join(dataDir, "test.db"), SessionTTL: "1h", InitialPassword: "admin", IconPack: "dashboard-icons", IconPackBaseURL: up.URL})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	cookie := loginAsAdmin(t, s)
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodGet, "/api/icons/pack/search?q=PLEX", "")
+	var found struct {
+		Source  string             `json:"source"`
+		Results []packSearchResult `json:"results"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &found); err != nil || len(found.Results) == 0 || found.Results[0].IconPath != "pack/plex.png" {
+		t.Fatalf("unexpected search: %d %s", w.Code, w.Body.String())
+	}
+
+	if w := do(http.MethodPost, "/api/apps", `{"name":"Plex","url":"http://plex.lan","iconSource":"pack","iconPath":"plex"}`); w.Code != http.StatusCreated || !strings.Contains(w.Body.String(), `"iconPath":"pack/plex.png"`) {
+		t.Fatalf("expected the slug to be stored as an icon path, got %d %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodPost, "/api/apps", `{"name":"Nope","url":"http://nope.lan","iconSource":"pack","iconPath":"nope"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown pack icon, got %d", w.Code)
+	}
+
+	if w := do(http.MethodGet, "/assets/icons/pack/plex.png", ""); w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("expected the pack icon, got %d", w.Code)
+	}
+	if w := do(http.MethodGet, "/assets/icons/pack/jellyfin.png", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 when the upstream has no icon, got %d", w.Code)
+	}
+}
+
+func TestIconGC(t *testing.T) {
+	s := newTestServer(t)
+	cookie := loginAsAdmin(t, s)
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	old := time.Now().Add(-2 * iconGCGrace)
+	for _, key := range []string{"used.png", "orphan.png", "fresh.png", "markets/AAPL.png"} {
+		if err := s.iconStore.Put(t.Context(), key, []byte("icon"), "image/png"); err != nil {
+			t.Fatal(err)
+		}
+		if key != "fresh.png" {
+			if err := os.Chtimes(filepath.Join(s.cfg.DataDir, "icons", key), old, old); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if w := do(http.MethodPost, "/api/apps", `{"name":"Used","url":"http://used.lan","iconSource":"upload","iconPath":"used.png"}`); w.Code != http.StatusCreated {
+		t.Fatalf("create app: %d %s", w.Code, w.Body.String())
+	}
+
+	var rep iconGCReport
+	w := do(http.MethodPost, "/api/admin/icons/gc?dryRun=true", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &rep); err != nil || w.Code != http.StatusOK || len(rep.Removed) != 1 || rep.Removed[0] != "orphan.png" {
+		t.Fatalf("unexpected dry run: %d %s", w.Code, w.Body.String())
+	}
+	if _, err := s.iconStore.Stat(t.Context(), "orphan.png"); err != nil {
+		t.Fatalf("dry run removed the orphan: %v", err)
+	}
+	if w := do(http.MethodPost, "/api/admin/icons/gc", ""); w.Code != http.StatusOK {
+		t.Fatalf("gc: %d", w.Code)
+	}
+	if _, err := s.iconStore.Stat(t.Context(), "orphan.png"); err == nil {
+		t.Fatalf("expected the orphan to be removed")
+	}
+	for _, key := range []string{"used.png", "fresh.png", "markets/AAPL.png"} {
+		if _, err := s.iconStore.Stat(t.Context(), key); err != nil {
+			t.Fatalf("expected %s to be kept: %v", key, err)
+		}
+	}
+
+	var stats struct {
+		Areas      map[string]storageUsage `json:"areas"`
+		TotalBytes int64                   `json:"totalBytes"`
+	}
+	w = do(http.MethodGet, "/api/admin/storage", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("storage: %d %s", w.Code, w.Body.String())
+	}
+	if stats.Areas["icons"].Files != 3 || stats.Areas["icons"].Bytes != 12 || stats.Areas["database"].Bytes == 0 || stats.Areas["backups"].Files != 0 {
+		t.Fatalf("unexpected storage stats: %+v", stats)
+	}
+}
+
+func TestLucideSVG(t *testing.T) {
+	cdn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<svg xmlns="http://www.w3.org/2000/svg"><path d="M3 10v11"/></svg>`))
+	}))
+	defer cdn.Close()
+	s := newTestServer(t)
+	s.lucide.CDN = cdn.URL + "/lucide-static"
+
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/icons/lucide/house.svg", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/svg+xml" || !strings.Contains(w.Body.String(), "<path") {
+		t.Fatalf("expected the icon, got %d %q", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/icons/lucide/no-such-icon.svg", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown icon, got %d", w.Code)
+	}
+}
+
+func TestTileIconApps(t *testing.T) {
+	s := newTestServer(t)
+	cookie := loginAsAdmin(t, s)
+	create := func(body string) (int, map[string]any) {
+		req := httptest.NewRequest(http.MethodPost, "/api/apps", strings.NewReader(body))
+		req.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		var app map[string]any
+		_ = json.Unmarshal(w.Body.Bytes(), &app)
+		return w.Code, app
+	}
+
+	code, app := create(`{"name":"Home Assistant","url":"http://ha.lan","iconSource":"text"}`)
+	path, _ := app["iconPath"].(string)
+	if code != http.StatusCreated || !strings.HasPrefix(path, "tiles/") {
+		t.Fatalf("text tile: %d %v", code, app)
+	}
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/assets/icons/"+path, nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), ">HA</text>") {
+		t.Fatalf("expected the rendered initials, got %d %q", w.Code, w.Body.String())
+	}
+	// Saving again with the stored path keeps the tile.
+	if code, again := create(`{"name":"Home Assistant","url":"http://ha.lan","iconSource":"text","iconPath":"` + path + `"}`); code != http.StatusCreated || again["iconPath"] != path {
+		t.Fatalf("expected the tile to be kept, got %d %v", code, again)
+	}
+	if code, app := create(`{"name":"Pizza","url":"http://pizza.lan","iconSource":"emoji","iconPath":"🍕","iconColor":"#fde68a"}`); code != http.StatusCreated || !strings.HasPrefix(app["iconPath"].(string), "tiles/") {
+		t.Fatalf("emoji tile: %d %v", code, app)
+	}
+	if code, _ := create(`{"name":"Bad","url":"http://bad.lan","iconSource":"emoji","iconPath":"nope"}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a non-emoji, got %d", code)
+	}
+}
+
+func TestStoredAssetsStayInNamespace(t *testing.T) {
+	s := newTestServer(t)
+	if err := os.WriteFile(filepath.Join(s.cfg.DataDir, "secrets", "initial-password"), []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.iconStore.Put(context.Background(), "a.png", []byte("png"), "image/png"); err != nil {
+		t.Fatal(err)
+	}
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+	if w := get("/assets/icons/a.png"); w.Code != http.StatusOK || w.Body.String() != "png" {
+		t.Fatalf("expected the stored icon, got %d %q", w.Code, w.Body.String())
+	}
+	for _, path := range []string{"/assets/icons/..%2ftest.db", "/assets/icons/..%2finitial-password", "/assets/icons/..%2fsecrets%2finitial-password", "/assets/icons/x%2f..%2f..%2ftest.db", "/assets/icons/%2e%2e/test.db", "/assets/icons/..%2fcache%2fx"} {
+		if w := get(path); w.Code != http.StatusNotFound || strings.Contains(w.Body.String(), "SQLite") || strings.Contains(w.Body.String(), "secret") {
+			t.Errorf("%s: expected 404, got %d %.20q", path, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestAppLinks(t *testing.T) {
+	s := newTestServer(t)
+	cookie := loginAsAdmin(t, s)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPost, "/api/apps", `{"name":"NAS","url":"https://nas.example.com","internalUrl":" http://192.168.1.10:5000 ","openMode":"IFRAME"}`)
+	var app store.AppItem
+	_ = json.Unmarshal(w.Body.Bytes(), &app)
+	if w.Code != http.StatusCreated || app.OpenMode != OpenModeIframe || app.InternalURL == nil || *app.InternalURL != "http://192.168.1.10:5000" {
+		t.Fatalf("unexpected create: %d %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodPost, "/api/apps", `{"name":"Bad","url":"https://bad.example.com","openMode":"popup"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown open mode, got %d", w.Code)
+	}
+	if w := do(http.MethodPost, "/api/apps", `{"name":"Bad","url":"https://bad.example.com","internalUrl":"ftp://nas"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a non-http internal url, got %d", w.Code)
+	}
+
+	href := func(remote string, forwardedFor ...string) string {
+		req := httptest.NewRequest(http.MethodGet, "/api/apps", nil)
+		req.RemoteAddr = remote
+		for _, ip := range forwardedFor {
+			req.Header.Add("X-Forwarded-For", ip)
+			req.Header.Set("X-Real-IP", ip)
+		}
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		var apps []appView
+		_ = json.Unmarshal(w.Body.Bytes(), &apps)
+		for _, a := range apps {
+			if a.ID == app.ID {
+				return a.Href
+			}
+		}
+		t.Fatalf("app missing from list: %s", w.Body.String())
+		return ""
+	}
+	if got := href("192.168.1.20:5555"); got != "http://192.168.1.10:5000" {
+		t.Fatalf("expected the internal url on the LAN, got %q", got)
+	}
+	if got := href("203.0.113.9:5555"); got != "https://nas.example.com" {
+		t.Fatalf("expected the public url from outside, got %q", got)
+	}
+	if got := href("203.0.113.9:5555", "192.168.1.10"); got != "https://nas.example.com" {
+		t.Fatalf("expected a spoofed X-Forwarded-For to get the public url, got %q", got)
+	}
+	s.proxies, _ = parseCIDRs([]string{"172.16.0.0/12"})
+	if got := href("172.17.0.1:5555", "203.0.113.9"); got != "https://nas.example.com" {
+		t.Fatalf("expected the public url for an outside client behind the proxy, got %q", got)
+	}
+	if got := href("172.17.0.1:5555", "192.168.1.20"); got != "http://192.168.1.10:5000" {
+		t.Fatalf("expected the internal url for a LAN client behind the proxy, got %q", got)
+	}
+	s.proxies = nil
+
+	// Configured internal networks replace the private ranges.
+	if w := do(http.MethodPut, "/api/settings", `{"network":{"internalCidrs":["10.8.0.0/16","bogus"]}}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid cidr, got %d", w.Code)
+	}
+	if w := do(http.MethodPut, "/api/settings", `{"network":{"internalCidrs":[" 203.0.113.0/24 ","2001:db8::1"]}}`); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := href("203.0.113.9:5555"); got != "http://192.168.1.10:5000" {
+		t.Fatalf("expected the internal url from a configured network, got %q", got)
+	}
+	if got := href("192.168.1.20:5555"); got != "https://nas.example.com" {
+		t.Fatalf("expected the public url outside the configured networks, got %q", got)
+	}
+	if got := s.internalCIDRs(); strings.Join(got, ",") != "203.0.113.0/24,2001:db8::1/128" {
+		t.Fatalf("unexpected stored cidrs %v", got)
+	}
+
+	// Clearing the internal URL and mode falls back to the defaults.
+	if w := do(http.MethodPut, "/api/apps/"+app.ID, `{"name":"NAS","url":"https://nas.example.com","internalUrl":""}`); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	got, _, _ := s.store.AppByID(app.ID)
+	if got.InternalURL != nil || got.OpenMode != store.DefaultOpenMode {
+		t.Fatalf("unexpected app after update: %+v", got)
+	}
+}
+
+func TestWakeOnLAN(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer conn.Close()
+	old := wakeAddr
+	wakeAddr = conn.LocalAddr().String()
+	t.Cleanup(func() { wakeAddr = old })
+
+	s := newTestServer(t)
+	cookie := loginAsAdmin(t, s)
+	do := func(method, path, body string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(http.MethodPost, "/api/apps", `{"name":"NAS","url":"http://nas.lan","wakeMac":"not-a-mac"}`, cookie); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid mac, got %d", w.Code)
+	}
+	w := do(http.MethodPost, "/api/apps", `{"name":"NAS","url":"http://nas.lan","wakeMac":"AA-BB-CC-DD-EE-0F"}`, cookie)
+	var app store.AppItem
+	_ = json.Unmarshal(w.Body.Bytes(), &app)
+	if w.Code != http.StatusCreated || app.WakeMAC == nil || *app.WakeMAC != "aa:bb:cc:dd:ee:0f" {
+		t.Fatalf("unexpected create: %d %s", w.Code, w.Body.String())
+	}
+
+	if w := do(http.MethodPost, "/api/apps/"+app.ID+"/wake", "", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a session, got %d", w.Code)
+	}
+	if w := do(http.MethodPost, "/api/apps/"+app.ID+"/wake", "", cookie); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 256)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("read packet: %v", err)
+	}
+	mac, _ := net.ParseMAC("aa:bb:cc:dd:ee:0f")
+	if !bytes.Equal(buf[:n], magicPacket(mac)) || n != 102 {
+		t.Fatalf("unexpected magic packet % x", buf[:n])
+	}
+
+	plain := do(http.MethodPost, "/api/apps", `{"name":"Wiki","url":"http://wiki.lan"}`, cookie)
+	_ = json.Unmarshal(plain.Body.Bytes(), &app)
+	if w := do(http.MethodPost, "/api/apps/"+app.ID+"/wake", "", cookie); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an app without a mac, got %d", w.Code)
+	}
+	if w := do(http.MethodPost, "/api/apps/missing/wake", "", cookie); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestAppActions(t *testing.T) {
+	var hookBody string
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		hookBody = r.Method + " " + string(b)
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer hook.Close()
+	var restarted string
+	dockerd := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		restarted = r.URL.Path
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer dockerd.Close()
+	fakeSSH := filepath.Join(t.TempDir(), "ssh")
+	if err := os.WriteFile(fakeSSH, []byte("#!/bin/sh\necho \"$@\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	old := sshCommand
+	sshCommand = fakeSSH
+	t.Cleanup(func() { sshCommand = old })
+
+	s := newTestServer(t)
+	s.runners = map[string]sshRunner{"nas-uptime": {Host: "nas.lan", User: "admin", Command: "uptime"}}
+	s.docker, _ = metrics.NewDockerClient(dockerd.URL)
+	cookie := loginAsAdmin(t, s)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(http.MethodPost, "/api/apps", `{"name":"NAS","url":"http://nas.lan","actions":[{"label":"Reboot","kind":"ssh","runner":"rm-rf"}]}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown runner, got %d", w.Code)
+	}
+	if w := do(http.MethodPost, "/api/apps", `{"name":"NAS","url":"http://nas.lan","actions":[{"label":"Ping","kind":"webhook","url":"file:///etc/passwd"}]}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a non-http webhook, got %d", w.Code)
+	}
+	w := do(http.MethodPost, "/api/apps", `{"name":"NAS","url":"http://nas.lan","actions":[
+		{"id":"hook","label":"Notify","kind":"webhook","url":"`+hook.URL+`/ok","body":"{\"a\":1}"},
+		{"id":"fail","label":"Broken","kind":"webhook","url":"`+hook.URL+`/fail"},
+		{"id":"restart","label":"Restart","kind":"docker_restart","container":"jellyfin"},
+		{"id":"uptime","label":"Uptime","kind":"ssh","runner":"nas-uptime"}]}`)
+	var app store.AppItem
+	_ = json.Unmarshal(w.Body.Bytes(), &app)
+	if w.Code != http.StatusCreated || len(app.Actions) != 4 || app.Actions[0].Method != http.MethodPost {
+		t.Fatalf("unexpected create: %d %s", w.Code, w.Body.String())
+	}
+
+	if w := do(http.MethodPost, "/api/apps/"+app.ID+"/actions/hook", ""); w.Code != http.StatusOK || hookBody != `POST {"a":1}` {
+		t.Fatalf("webhook action: %d %s, upstream got %q", w.Code, w.Body.String(), hookBody)
+	}
+	if w := do(http.MethodPost, "/api/apps/"+app.ID+"/actions/fail", ""); w.Code != http.StatusBadGateway {
+		t.Fatalf("expected 502 for a failing webhook, got %d", w.Code)
+	}
+	if w := do(http.MethodPost, "/api/apps/"+app.ID+"/actions/restart", ""); w.Code != http.StatusOK || restarted != "/containers/jellyfin/restart" {
+		t.Fatalf("docker action: %d %s, docker got %q", w.Code, w.Body.String(), restarted)
+	}
+	w = do(http.MethodPost, "/api/apps/"+app.ID+"/actions/uptime", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "admin@nas.lan uptime") {
+		t.Fatalf("ssh action: %d %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodPost, "/api/apps/"+app.ID+"/actions/nope", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown action, got %d", w.Code)
+	}
+
+	var audit struct {
+		Entries []store.ActionAudit `json:"entries"`
+	}
+	w = do(http.MethodGet, "/api/admin/actions/audit?appId="+app.ID, "")
+	_ = json.Unmarshal(w.Body.Bytes(), &audit)
+	if len(audit.Entries) != 4 || audit.Entries[0].ActionID != "uptime" || audit.Entries[2].OK || audit.Entries[2].Detail != "status 500" {
+		t.Fatalf("unexpected audit trail: %s", w.Body.String())
+	}
+
+	// Visitors don't see the actions or their targets.
+	var apps []appView
+	getJSON(t, s, "/api/apps", &apps)
+	for _, a := range apps {
+		if a.ID == app.ID && len(a.Actions) != 0 {
+			t.Fatalf("expected actions to be hidden from visitors, got %+v", a.Actions)
+		}
+	}
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	s := newTestServer(t)
+	cookie := loginAsAdmin(t, s)
+
+	type hit struct {
+		event, signature string
+		body             []byte
+	}
+	var hits []hit
+	fail := true
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		hits = append(hits, hit{r.Header.Get("X-Hearth-Event"), r.Header.Get("X-Hearth-Signature"), b})
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer receiver.Close()
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/webhooks", bytes.NewBufferString(body))
+		req.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		return w
+	}
+	if w := post(`{"url":"` + receiver.URL + `","events":["app.exploded"]}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown event, got %d", w.Code)
+	}
+	w := post(`{"url":"` + receiver.URL + `","events":["import.completed","auth.login_new_ip"]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created webhookView
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil || created.Secret == "" {
+		t.Fatalf("expected a generated secret, got %s", w.Body.String())
+	}
+
+	s.emitEvent(eventBackgroundRefreshed, map[string]any{}) // not subscribed
+	s.emitEvent(eventImportCompleted, map[string]any{"bytes": 1})
+	now := time.Now()
+	s.deliverDueWebhooks(context.Background(), now)
+	if len(hits) != 1 || hits[0].event != eventImportCompleted {
+		t.Fatalf("expected one import.completed delivery, got %+v", hits)
+	}
+
+	// The failed delivery is retried after the backoff, not before.
+	fail = false
+	s.deliverDueWebhooks(context.Background(), now)
+	if len(hits) != 1 {
+		t.Fatalf("expected the retry to wait, got %d hits", len(hits))
+	}
+	s.deliverDueWebhooks(context.Background(), now.Add(webhookBackoff(1)))
+	if len(hits) != 2 {
+		t.Fatalf("expected a retry, got %d hits", len(hits))
+	}
+	if got, want := hits[1].signature, signWebhook(created.Secret, hits[1].body); got != want {
+		t.Fatalf("signature = %q, want %q", got, want)
+	}
+	var env webhookEnvelope
+	if err := json.Unmarshal(hits[1].body, &env); err != nil || env.Event != eventImportCompleted || env.ID == "" {
+		t.Fatalf("unexpected envelope: %s", hits[1].body)
+	}
+
+	var deliveries struct {
+		Deliveries []store.WebhookDelivery `json:"deliveries"`
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/webhooks/"+strconv.FormatInt(created.ID, 10)+"/deliveries", nil)
+	req.AddCookie(cookie)
+	rec := httptest.NewRecorder()
+	s.Router().ServeHTTP(rec, req)
+	if err := json.Unmarshal(rec.Body.Bytes(), &deliveries); err != nil || len(deliveries.Deliveries) != 1 {
+		t.Fatalf("unexpected deliveries: %s", rec.Body.String())
+	}
+	if d := deliveries.Deliveries[0]; d.Attempts != 2 || d.DeliveredAt == 0 || d.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected delivery: %+v", d)
+	}
+
+	// Logins: the test client's address was already seen by loginAsAdmin.
+	loginAsAdmin(t, s)
+	if due, _ := s.store.DueWebhookDeliveries(time.Now().Unix(), 10); len(due) != 0 {
+		t.Fatalf("expected no event for a known address, got %+v", due)
+	}
+	req = httptest.NewRequest(http.MethodPost, "/api/auth/login", bytes.NewBufferString(`{"username":"admin","password":"admin"}`))
+	req.RemoteAddr = "198.51.100.7:4242"
+	s.Router().ServeHTTP(httptest.NewRecorder(), req)
+	if due, _ := s.store.DueWebhookDeliveries(time.Now().Unix(), 10); len(due) != 1 || due[0].Event != eventLoginNewIP {
+		t.Fatalf("expected a login event, got %+v", due)
+	}
+}
+
+func TestAppStatusObserve(t *testing.T) {
+	var a appStatus
+	steps := []struct {
+		ok   bool
+		want string
+	}{
+		{true, ""},            // first sight: up
+		{false, ""},           // one failure is tolerated
+		{false, eventAppDown}, // two in a row
+		{false, ""},
+		{true, eventAppUp},
+		{true, ""},
+	}
+	for i, st := range steps {
+		if got := a.observe("app", st.ok); got != st.want {
+			t.Fatalf("step %d: event %q, want %q", i, got, st.want)
+		}
+	}
+}
+
+func TestIngest(t *testing.T) {
+	s := newTestServer(t)
+	cookie := loginAsAdmin(t, s)
+
+	push := func(key, token, body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/ingest/"+key, bytes.NewBufferString(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := push("backup", "anything", `{}`); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 before a token exists, got %d", code)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/ingest/token", nil)
+	req.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, req)
+	var tok struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &tok); err != nil || tok.Token == "" {
+		t.Fatalf("expected a token, got %d %s", w.Code, w.Body.String())
+	}
+
+	if code := push("backup", "wrong", `{}`); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a wrong token, got %d", code)
+	}
+	if code := push("Bad_Key", tok.Token, `{}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a bad key, got %d", code)
+	}
+	if code := push("backup", tok.Token, `{"status":`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid json, got %d", code)
+	}
+	if code := push("backup", tok.Token, `{"status":"ok","progress":42}`); code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", code)
+	}
+
+	var v struct {
+		Key       string         `json:"key"`
+		Value     map[string]any `json:"value"`
+		UpdatedAt int64          `json:"updatedAt"`
+	}
+	if code := getJSON(t, s, "/api/ingest/backup", &v); code != http.StatusOK || v.Value["status"] != "ok" || v.UpdatedAt == 0 {
+		t.Fatalf("unexpected value: %d %+v", code, v)
+	}
+	if code := getJSON(t, s, "/api/ingest/printer", nil); code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown key, got %d", code)
+	}
+}
+
+func TestEmbeds(t *testing.T) {
+	s := newTestServer(t)
+	cookie := loginAsAdmin(t, s)
+
+	put := func(body string) int {
+		req := httptest.NewRequest(http.MethodPut, "/api/settings", bytes.NewBufferString(body))
+		req.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for _, body := range []string{
+		`{"embeds":{"allowlist":["*.lan"],"frames":[{"id":"x","url":"https://evil.example/"}]}}`,
+		`{"embeds":{"allowlist":["*.lan"],"frames":[{"id":"x","url":"javascript:alert(1)"}]}}`,
+		`{"embeds":{"allowlist":["*.lan"],"frames":[{"id":"x","url":"http://u:p@grafana.lan/"}]}}`,
+		`{"embeds":{"allowlist":["*.lan"],"frames":[{"id":"x","url":"http://grafana.lan/","sandbox":["allow-top-navigation"]}]}}`,
+		`{"embeds":{"allowlist":["*.lan"],"frames":[{"id":"x","url":"http://a.lan/"},{"id":"X","url":"http://b.lan/"}]}}`,
+		`{"siteTitle":"Changed","embeds":{"allowlist":["[" ]}}`,
+	} {
+		if code := put(body); code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %s, got %d", body, code)
+		}
+	}
+	if got := s.getStringSetting(kvSiteTitle, ""); got == "Changed" {
+		t.Fatalf("rejected settings were partially saved")
+	}
+
+	body := `{"embeds":{"allowlist":["*.lan"],"frames":[{"id":"grafana","name":"Grafana","url":"http://grafana.lan:3000/d/abc","height":50},{"id":"ha","url":"https://ha.lan/","sandbox":["allow-scripts","allow-same-origin"]}]}}`
+	if code := put(body); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	var got struct {
+		Frames []Embed `json:"frames"`
+	}
+	if code := getJSON(t, s, "/api/widgets/embeds", &got); code != http.StatusOK || len(got.Frames) != 2 {
+		t.Fatalf("unexpected embeds: %d %+v", code, got)
+	}
+	if f := got.Frames[0]; f.Height != 100 || strings.Join(f.Sandbox, " ") != "allow-forms allow-popups allow-scripts" {
+		t.Fatalf("expected defaults to be filled in, got %+v", f)
+	}
+
+	// Narrowing the allowlist drops frames on other hosts.
+	if code := put(`{"embeds":{"allowlist":["grafana.lan"]}}`); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if code := getJSON(t, s, "/api/widgets/embeds", &got); code != http.StatusOK || len(got.Frames) != 1 || got.Frames[0].ID != "grafana" {
+		t.Fatalf("expected only the allowed frame, got %+v", got)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	s := newTestServer(t)
+	cookie := loginAsAdmin(t, s)
+
+	search := func(target string) string {
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != http.StatusFound {
+			t.Fatalf("%s: expected 302, got %d", target, w.Code)
+		}
+		return w.Header().Get("Location")
+	}
+	if got := search("/search?q=hello+world"); got != "https://www.google.com/search?q=hello+world" {
+		t.Fatalf("unexpected default redirect %q", got)
+	}
+	if got := search("/search?q=" + url.QueryEscape("!gh hearth")); got != "https://github.com/search?q=hearth" {
+		t.Fatalf("unexpected bang redirect %q", got)
+	}
+	if got := search("/search?q=" + url.QueryEscape("rust !ddg")); got != "https://duckduckgo.com/?q=rust" {
+		t.Fatalf("unexpected trailing bang redirect %q", got)
+	}
+	if got := search("/search?q=" + url.QueryEscape("!nope a&b")); got != "https://www.google.com/search?q=%21nope+a%26b" {
+		t.Fatalf("unknown bangs should be searched as text, got %q", got)
+	}
+	if got := search("/search?q="); got != "/" {
+		t.Fatalf("expected an empty query to go home, got %q", got)
+	}
+
+	put := func(body string) int {
+		req := httptest.NewRequest(http.MethodPut, "/api/settings", bytes.NewBufferString(body))
+		req.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := put(`{"search":{"engines":[{"id":"x","url":"javascript:{q}"}]}}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a bad engine url, got %d", code)
+	}
+	if code := put(`{"search":{"engines":[{"id":"searx","name":"SearXNG","url":"https://searx.lan/search?q={q}"}],"bangs":{"!Hn":"https://hn.algolia.com/?q={q}"},"history":true}}`); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	var cfg SearchSettings
+	if code := getJSON(t, s, "/api/widgets/search-config", &cfg); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if cfg.DefaultEngine != "searx" || len(cfg.Engines) != 1 || cfg.Bangs["hn"] == "" || cfg.History == nil || !*cfg.History {
+		t.Fatalf("unexpected search config: %+v", cfg)
+	}
+	if got := search("/search?q=" + url.QueryEscape("!hn go")); got != "https://hn.algolia.com/?q=go" {
+		t.Fatalf("unexpected custom bang redirect %q", got)
+	}
+}
+
+func TestQuotes(t *testing.T) {
+	up := testsupport.NewUpstream(t)
+	up.UseForWidgets(t)
+	s := newTestServer(t)
+	cookie := loginAsAdmin(t, s)
+
+	var q widgets.Quote
+	if code := getJSON(t, s, "/api/widgets/quote?lang=en", &q); code != http.StatusOK || q.Source != widgets.QuoteSourceBundled || q.Text == "" {
+		t.Fatalf("unexpected bundled quote: %d %+v", code, q)
+	}
+	if code := getJSON(t, s, "/api/widgets/quote?source=custom&lang=en", &q); code != http.StatusOK || q.Source != widgets.QuoteSourceBundled {
+		t.Fatalf("expected bundled quotes without custom ones, got %d %+v", code, q)
+	}
+
+	for i := 0; i < 2; i++ {
+		if code := getJSON(t, s, "/api/widgets/quote?source=quotable", &q); code != http.StatusOK || q.Author != "Edsger W. Dijkstra" {
+			t.Fatalf("unexpected quotable quote: %d %+v", code, q)
+		}
+	}
+	if n := up.Hits("/quotable/random"); n != 1 {
+		t.Fatalf("expected the daily quote to be cached, got %d fetches", n)
+	}
+
+	add := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/quotes", bytes.NewBufferString(body))
+		req.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		return w
+	}
+	if w := add(`{"text":"  "}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an empty quote, got %d", w.Code)
+	}
+	w := add(`{"text":"Keep the lights on.","author":"Ops"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", w.Code)
+	}
+	var created store.CustomQuote
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+
+	if code := getJSON(t, s, "/api/widgets/quote?source=custom", &q); code != http.StatusOK || q.Source != widgets.QuoteSourceCustom || q.Text != "Keep the lights on." {
+		t.Fatalf("unexpected custom quote: %d %+v", code, q)
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/admin/quotes/"+strconv.FormatInt(created.ID, 10), nil)
+	req.AddCookie(cookie)
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if list, _ := s.store.ListCustomQuotes(); len(list) != 0 {
+		t.Fatalf("expected the quote to be deleted, got %+v", list)
+	}
+}
+
+func TestWidgetConfig(t *testing.T) {
+	s := newTestServer(t)
+	cookie := loginAsAdmin(t, s)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		return w
+	}
+	g, err := s.store.CreateGroup("Widgets", GroupKindSystem)
+	if err != nil {
+		t.Fatalf("CreateGroup failed: %v", err)
+	}
+
+	w := do(http.MethodPost, "/api/apps", `{"groupId":"`+g.ID+`","name":"Status","url":"widget:metrics","description":"{\"refreshSec\":3}"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid refreshSec, got %d", w.Code)
+	}
+	w = do(http.MethodPost, "/api/apps", `{"groupId":"`+g.ID+`","name":"Status","url":"widget:metrics","description":"{\"showCpu\":true,\"refreshSec\":5}"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var app store.AppItem
+	_ = json.Unmarshal(w.Body.Bytes(), &app)
+
+	var got widgetConfigResponse
+	if code := getJSON(t, s, "/api/widgets/"+app.ID+"/config", &got); code != http.StatusOK || got.Kind != "metrics" || string(got.Config) != `{"refreshSec":5,"showCpu":true}` || len(got.Schema) == 0 {
+		t.Fatalf("unexpected config: %d %+v", code, got)
+	}
+
+	for _, body := range []string{`[]`, `{"nope":1}`, `{"showCpu":"yes"}`, `{"host":1}`} {
+		if w := do(http.MethodPut, "/api/widgets/"+app.ID+"/config", body); w.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %s, got %d", body, w.Code)
+		}
+	}
+	if w := do(http.MethodPut, "/api/widgets/"+app.ID+"/config", `{"showMem":true,"refreshSec":10}`); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	item, _, _ := s.store.AppByID(app.ID)
+	if item.Description == nil || *item.Description != `{"refreshSec":10,"showMem":true}` {
+		t.Fatalf("expected the config to be mirrored into the description, got %v", item.Description)
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/api/widgets/"+app.ID+"/config", bytes.NewBufferString(`{}`))
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a session, got %d", w.Code)
+	}
+
+	plain, err := s.store.CreateApp(store.AppItem{Name: "App", URL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("CreateApp failed: %v", err)
+	}
+	if code := getJSON(t, s, "/api/widgets/"+plain.ID+"/config", &got); code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a regular app, got %d", code)
+	}
+	if w := do(http.MethodPut, "/api/widgets/"+plain.ID+"/config", `{}`); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a regular app, got %d", w.Code)
+	}
+}
+
+func TestWidgetRegistry(t *testing.T) {
+	s := newTestServer(t)
+
+	var types []WidgetTypeInfo
+	if code := getJSON(t, s, "/api/widgets/registry?lang=en", &types); code != http.StatusOK || len(types) != len(widgetTypes) {
+		t.Fatalf("unexpected registry: %d %+v", code, types)
+	}
+	for _, wt := range types {
+		raw, _ := json.Marshal(wt.DefaultConfig)
+		if _, err := validateWidgetConfig(wt.ID, raw); err != nil {
+			t.Fatalf("default config of %s does not match its schema: %v", wt.ID, err)
+		}
+	}
+	if types[0].ID != "weather" || types[0].Name != "Weather" {
+		t.Fatalf("unexpected first entry: %+v", types[0])
+	}
+	if c := types[0].Credentials; len(c) != 1 || c[0].Required || c[0].Configured {
+		t.Fatalf("expected an optional, unset weather key, got %+v", c)
+	}
+
+	_ = s.store.SetKV(kvWeatherProvider, widgets.WeatherProviderOpenWeatherMap)
+	_ = s.store.SetKV(kvWeatherAPIKey, "k")
+	if code := getJSON(t, s, "/api/widgets/registry?lang=zh", &types); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if types[0].Name != "天气" {
+		t.Fatalf("expected a localized name, got %q", types[0].Name)
+	}
+	if c := types[0].Credentials; !c[0].Required || !c[0].Configured {
+		t.Fatalf("expected a required, configured weather key, got %+v", c)
+	}
+}
+
+func TestPluginWidget(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs /bin/sh")
+	}
+	s := newTestServer(t)
+	cookie := loginAsAdmin(t, s)
+
+	dir := filepath.Join(t.TempDir(), "uptime")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	_ = os.WriteFile(filepath.Join(dir, "plugin.json"), []byte(`{"id":"uptime","name":"Uptime","command":"run.sh","options":[{"name":"target"}]}`), 0o644)
+	_ = os.WriteFile(filepath.Join(dir, "run.sh"), []byte("#!/bin/sh\necho '{\"title\":\"Uptime\",\"value\":\"99.9\",\"unit\":\"%\"}'\n"), 0o755)
+	var err error
+	if s.plugins, err = plugins.Load(filepath.Dir(dir)); err != nil {
+		t.Fatal(err)
+	}
+
+	var list []pluginInfo
+	if code := getJSON(t, s, "/api/widgets/plugins", &list); code != http.StatusOK || len(list) != 1 || list[0].ID != "uptime" || len(list[0].Options) != 1 {
+		t.Fatalf("unexpected plugins: %d %+v", code, list)
+	}
+
+	g, _ := s.store.CreateGroup("Widgets", GroupKindSystem)
+	req := httptest.NewRequest(http.MethodPost, "/api/apps", bytes.NewBufferString(`{"groupId":"`+g.ID+`","name":"Uptime","url":"widget:plugin","description":"{\"plugin\":\"uptime\",\"options\":{\"target\":\"nas\"}}"}`))
+	req.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var app store.AppItem
+	_ = json.Unmarshal(w.Body.Bytes(), &app)
+
+	var out plugins.Output
+	if code := getJSON(t, s, "/api/widgets/"+app.ID+"/plugin", &out); code != http.StatusOK || out.Value != "99.9" || out.Unit != "%" {
+		t.Fatalf("unexpected output: %d %+v", code, out)
+	}
+
+	if err := s.store.PutWidgetConfig(app.ID, "plugin", []byte(`{"plugin":"gone"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if code := getJSON(t, s, "/api/widgets/"+app.ID+"/plugin", &out); code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a missing plugin, got %d", code)
+	}
+}
+
+func TestWithDeadline(t *testing.T) {
+	// A handler waiting on a slow upstream gives up at the route's deadline
+	// and reports a gateway timeout.
+	h := withDeadline(50 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+		err := r.Context().Err()
+		writeError(w, upstreamStatus(r.Context(), err, http.StatusBadRequest), "upstream failed")
+	}))
+	start := time.Now()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("expected 504, got %d", w.Code)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("deadline not applied, took %v", d)
+	}
+
+	if got := upstreamStatus(context.Background(), errors.New("city not found"), http.StatusBadRequest); got != http.StatusBadRequest {
+		t.Fatalf("expected 400 for other errors, got %d", got)
+	}
+}
+
+func TestResponseCache(t *testing.T) {
+	s, _ := newMemTestServer(t)
+	var calls int
+	h := s.purgeResponsesOnWrite(s.cachedFor(time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Query().Get("fail") != "" {
+			writeError(w, http.StatusBadGateway, "upstream failed")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]int{"calls": calls})
+	})))
+	do := func(method, target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		return w
+	}
+
+	if w := do(http.MethodGet, "/w?a=1"); w.Header().Get("X-Cache") != "MISS" {
+		t.Fatalf("first request: X-Cache = %q", w.Header().Get("X-Cache"))
+	}
+	w := do(http.MethodGet, "/w?a=1")
+	if w.Header().Get("X-Cache") != "HIT" || calls != 1 {
+		t.Fatalf("second request: X-Cache = %q, calls = %d", w.Header().Get("X-Cache"), calls)
+	}
+	if !strings.Contains(w.Body.String(), `"calls":1`) {
+		t.Fatalf("unexpected cached body %s", w.Body.String())
+	}
+	if do(http.MethodGet, "/w?a=2"); calls != 2 {
+		t.Fatalf("expected a different query to miss, calls = %d", calls)
+	}
+
+	// Errors are never cached.
+	do(http.MethodGet, "/w?fail=1")
+	if w := do(http.MethodGet, "/w?fail=1"); w.Code != http.StatusBadGateway || calls != 4 {
+		t.Fatalf("expected errors to pass through uncached, code %d calls %d", w.Code, calls)
+	}
+
+	// A successful write empties the cache.
+	do(http.MethodPost, "/w")
+	if w := do(http.MethodGet, "/w?a=1"); w.Header().Get("X-Cache") != "MISS" {
+		t.Fatalf("expected a miss after a write, got %q", w.Header().Get("X-Cache"))
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	limits := parseRateLimits("lookup=10/s, metrics=off, bogus=1/m, widgets=x")
+	if l := limits[rateGroupLookup]; l.Burst != 10 || l.Per != time.Second {
+		t.Fatalf("lookup = %+v", l)
+	}
+	if _, ok := limits[rateGroupMetrics]; ok {
+		t.Fatalf("expected metrics to be unlimited")
+	}
+	if limits[rateGroupWidgets] != defaultRateLimits[rateGroupWidgets] {
+		t.Fatalf("expected an invalid entry to keep the default, got %+v", limits[rateGroupWidgets])
+	}
+	if len(parseRateLimits("off")) != 0 {
+		t.Fatalf("expected off to disable all limits")
+	}
+
+	now := time.Unix(0, 0)
+	l := newRateLimiter(rateLimit{Burst: 2, Per: time.Minute})
+	l.now = func() time.Time { return now }
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.allow("a"); !ok {
+			t.Fatalf("request %d denied", i)
+		}
+	}
+	if ok, wait := l.allow("a"); ok || wait != 30*time.Second {
+		t.Fatalf("expected a 30s wait, got %v %s", ok, wait)
+	}
+	if ok, _ := l.allow("b"); !ok {
+		t.Fatalf("expected other clients to have their own bucket")
+	}
+	now = now.Add(30 * time.Second)
+	if ok, _ := l.allow("a"); !ok {
+		t.Fatalf("expected a token after 30s")
+	}
+
+	s := newTestServer(t)
+	s.limiters[rateGroupMetrics] = newRateLimiter(rateLimit{Burst: 2, Per: time.Minute})
+	s.router = s.buildRouter()
+	get := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/metrics/hosts", nil)
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		return w
+	}
+	for i := 0; i < 2; i++ {
+		if w := get("192.0.2.1"); w.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i, w.Code)
+		}
+	}
+	w := get("192.0.2.1")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "30" {
+		t.Fatalf("expected 429 with Retry-After, got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	if w := get("192.0.2.2"); w.Code != http.StatusOK {
+		t.Fatalf("expected another IP to be allowed, got %d", w.Code)
+	}
+
+	// Forwarding headers only count from trusted proxies.
+	forwarded := func(peer, xff string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/metrics/hosts", nil)
+		req.RemoteAddr = peer + ":1234"
+		req.Header.Set("X-Forwarded-For", xff)
+		req.Header.Set("X-Real-IP", xff)
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		return w
+	}
+	for i := 0; i < 3; i++ {
+		if w := forwarded("192.0.2.1", fmt.Sprintf("203.0.113.%d", i+1)); w.Code != http.StatusTooManyRequests {
+			t.Fatalf("spoofed X-Forwarded-For %d: expected 429, got %d", i, w.Code)
+		}
+	}
+	s.proxies, _ = parseCIDRs([]string{"10.0.0.0/8"})
+	for i := 0; i < 3; i++ {
+		if w := forwarded("10.0.0.2", fmt.Sprintf("203.0.113.%d, 10.0.0.3", i+1)); w.Code != http.StatusOK {
+			t.Fatalf("client %d behind a trusted proxy: expected 200, got %d", i, w.Code)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		forwarded("10.0.0.2", "198.51.100.1, 203.0.113.9")
+	}
+	if w := forwarded("10.0.0.2", "198.51.100.2, 203.0.113.9"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected the address the trusted proxy saw to be limited, got %d", w.Code)
+	}
+}
+
+func TestThemeSettings(t *testing.T) {
+	s := newTestServer(t)
+	cookie := loginAsAdmin(t, s)
+
+	var st Settings
+	if code := getJSON(t, s, "/api/settings", &st); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if st.Theme == nil || st.Theme.Mode != "dark" || st.Theme.Accent != defaultThemeAccent || *st.Theme.FontScale != 1 || *st.Theme.TileOpacity != defaultThemeTileOpacity {
+		t.Fatalf("unexpected default theme: %+v", st.Theme)
+	}
+
+	put := func(body string) int {
+		req := httptest.NewRequest(http.MethodPut, "/api/settings", bytes.NewBufferString(body))
+		req.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		return w.Code
+	}
+	for _, body := range []string{
+		`{"theme":{"accent":"red"}}`,
+		`{"theme":{"mode":"sepia"}}`,
+		`{"theme":{"tileOpacity":1.5}}`,
+		`{"theme":{"fontScale":3}}`,
+		`{"theme":{"customCss":"</style><script>alert(1)</script>"}}`,
+	} {
+		if code := put(body); code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", body, code)
+		}
+	}
+	if code := put(`{"theme":{"accent":"#FF8800","mode":"auto","tileOpacity":0,"customCss":"body { letter-spacing: 1px }"}}`); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	// Omitted fields keep their stored values.
+	if code := put(`{"theme":{"fontScale":1.25}}`); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if code := getJSON(t, s, "/api/settings", &st); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if th := st.Theme; th.Accent != "#ff8800" || th.Mode != "auto" || *th.TileOpacity != 0 || *th.FontScale != 1.25 || *th.CustomCSS != "body { letter-spacing: 1px }" {
+		t.Fatalf("unexpected theme: %+v", th)
+	}
+}
+
+func TestCustomization(t *testing.T) {
+	s := newTestServer(t)
+	cookie := loginAsAdmin(t, s)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/customization", bytes.NewBufferString(body))
+		req.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		return w
+	}
+	if w := put(`{"js":"alert(1)"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 while custom js is disabled, got %d", w.Code)
+	}
+	if w := put(`{"css":"</style><script>alert(1)</script>"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a closing style tag, got %d", w.Code)
+	}
+	if w := put(`{"css":"` + strings.Repeat("a", maxCustomCSS+1) + `"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for oversized css, got %d", w.Code)
+	}
+	w := put(`{"css":"@import url(https://evil.example/x.css);\nbody { background: url('//evil.example/bg.png'); width: expression(alert(1)) }\n.tile-bg { background: url(/assets/icons/a.png) }"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var c Customization
+	if code := getJSON(t, s, "/api/customization", &c); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if strings.Contains(c.CSS, "evil.example") || strings.Contains(c.CSS, "expression") || !strings.Contains(c.CSS, "url(/assets/icons/a.png)") || c.JSEnabled || c.JS != "" {
+		t.Fatalf("unexpected customization: %+v", c)
+	}
+
+	if w := put(`{"css":"body { background: url(https://cdn.example/bg.png) }","allowRemoteUrls":true}`); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	getJSON(t, s, "/api/customization", &c)
+	if !strings.Contains(c.CSS, "https://cdn.example/bg.png") || c.AllowRemoteURLs == nil || !*c.AllowRemoteURLs {
+		t.Fatalf("expected remote urls to be kept, got %+v", c)
+	}
+
+	s.cfg.CustomJS = true
+	if w := put(`{"js":"console.log('hi')"}`); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	getJSON(t, s, "/api/customization", &c)
+	if !c.JSEnabled || c.JS != "console.log('hi')" {
+		t.Fatalf("expected the script, got %+v", c)
+	}
+	s.cfg.CustomJS = false
+	getJSON(t, s, "/api/customization", &c)
+	if c.JS != "" {
+		t.Fatalf("expected stored js to be hidden once disabled, got %q", c.JS)
+	}
+}
+
+func TestCustomCSSPaths(t *testing.T) {
+	// The theme's customCss and /api/customization edit the same stylesheet
+	// under the same rules.
+	s := newTestServer(t)
+	cookie := loginAsAdmin(t, s)
+	put := func(path, body string) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPut, path, bytes.NewBufferString(body))
+		req.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("PUT %s: expected 200, got %d: %s", path, w.Code, w.Body.String())
+		}
+	}
+	check := func(want string) {
+		t.Helper()
+		var st Settings
+		var c Customization
+		getJSON(t, s, "/api/settings", &st)
+		getJSON(t, s, "/api/customization", &c)
+		if *st.Theme.CustomCSS != want || c.CSS != want {
+			t.Fatalf("expected %q on both paths, got theme %q and customization %q", want, *st.Theme.CustomCSS, c.CSS)
+		}
+	}
+	const evil = `@import url(https://evil.example/x.css);\nbody { background: url(https://evil.example/bg.png) }`
+	const stripped = "/* removed */\nbody { background: none }"
+
+	put("/api/settings", `{"theme":{"customCss":"`+evil+`"}}`)
+	check(stripped)
+	put("/api/customization", `{"css":"`+evil+`"}`)
+	check(stripped)
+
+	// Allowing remote URLs applies to the theme's field as well.
+	put("/api/customization", `{"css":"","allowRemoteUrls":true}`)
+	put("/api/settings", `{"theme":{"customCss":"body { background: url(https://cdn.example/bg.png) }"}}`)
+	check("body { background: url(https://cdn.example/bg.png) }")
+}
+
+func TestMemStoreHandlers(t *testing.T) {
+	s, st := newMemTestServer(t)
+	cookie := loginAsAdmin(t, s)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPost, "/api/groups", `{"name":"Media"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var g store.Group
+	_ = json.Unmarshal(w.Body.Bytes(), &g)
+
+	var ids []string
+	for _, name := range []string{"Jellyfin", "Sonarr"} {
+		w := do(http.MethodPost, "/api/apps", `{"groupId":"`+g.ID+`","name":"`+name+`","url":"https://`+strings.ToLower(name)+`.lan"}`)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+		}
+		var a store.AppItem
+		_ = json.Unmarshal(w.Body.Bytes(), &a)
+		ids = append(ids, a.ID)
+	}
+	if w := do(http.MethodPost, "/api/apps/reorder", `{"groupId":"`+g.ID+`","ids":["`+ids[1]+`","`+ids[0]+`"]}`); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var apps []store.AppItem
+	if code := getJSON(t, s, "/api/apps", &apps); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	var order []string
+	for _, a := range apps {
+		if a.GroupID != nil && *a.GroupID == g.ID {
+			order = append(order, a.Name)
+		}
+	}
+	if strings.Join(order, ",") != "Sonarr,Jellyfin" {
+		t.Fatalf("unexpected order: %v", order)
+	}
+
+	if w := do(http.MethodDelete, "/api/apps/"+ids[0], ""); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if _, ok, _ := st.AppByID(ids[0]); ok {
+		t.Fatalf("expected the app to be deleted")
+	}
+
+	w = do(http.MethodGet, "/api/export", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Sonarr") {
+		t.Fatalf("unexpected export: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	dataDir := t.TempDir()
+	cfg := Config{
+		Addr:            ":0",
+		DataDir:         dataDir,
+		DatabaseDSN:     filepath.Join(dataDir, "test.db"),
+		SessionTTL:      "1h",
+		InitialPassword: "admin",
+	}
+	s, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return s
+}
+
+// newMemTestServer builds a server on the in-memory store, with a fake
+// authenticator accepting admin/admin.
+func newMemTestServer(t *testing.T) (*Server, *memstore.Store) {
+	t.Helper()
+	st := memstore.New()
+	if err := st.Migrate(); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	s, err := newServer(Config{Addr: ":0", DataDir: t.TempDir()}, st, &fakeAuth{sessions: map[string]string{}})
+	if err != nil {
+		t.Fatalf("newServer: %v", err)
+	}
+	return s, st
+}
+
+// fakeAuth is an authenticator with a single admin/admin account.
+type fakeAuth struct {
+	mu       sync.Mutex
+	sessions map[string]string
+	n        int
+}
+
+func (a *fakeAuth) Login(username, password string) (string, error) {
+	if username != "admin" || password != "admin" {
+		return "", errors.New("invalid credentials")
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.n++
+	token := "token-" + strconv.Itoa(a.n)
+	a.sessions[token] = "admin-id"
+	return token, nil
+}
+
+func (a *fakeAuth) Logout(token string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.sessions, token)
+	return nil
+}
+
+func (a *fakeAuth) Validate(token string) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if id, ok := a.sessions[token]; ok {
+		return id, nil
+	}
+	return "", errors.New("invalid session")
+}
+
+func (a *fakeAuth) ChangePassword(userID, oldPassword, newPassword string) error {
+	return errors.New("not supported")
+}
+
+func (a *fakeAuth) PasswordChangeRequired(userID string) (bool, error) { return false, nil }
+
+func (a *fakeAuth) Username(userID string) (string, error) {
+	if userID != "admin-id" {
+		return "", errors.New("user not found")
+	}
+	return "admin", nil
+}
+
+func (a *fakeAuth) UserID(username string) (string, error) {
+	if username != "admin" {
+		return "", errors.New("user not found")
+	}
+	return "admin-id", nil
+}
+
+func (a *fakeAuth) EnsureDefaultAdmin() error { return nil }
+
+func (a *fakeAuth) SessionMaxAge() time.Duration { return 0 }
+
+func (a *fakeAuth) PurgeExpiredSessions() (int64, error) { return 0, nil }
+
+func (a *fakeAuth) VerifyPassword(userID, password string) error {
+	if userID != "admin-id" || password != "admin" {
+		return errors.New("incorrect password")
+	}
+	return nil
+}
+
+func loginAsAdmin(t *testing.T, s *Server) *http.Cookie {
+	t.Helper()
+	body := bytes.NewBufferString(`{"username":"admin","password":"admin"}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/login", body)
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("login expected 200, got %d", w.Code)
+	}
+	for _, c := range w.Result().Cookies() {
+		if c.Name == "hearth_session" {
+			return c
+		}
+	}
+	t.Fatalf("missing session cookie")
+	return nil
+}
+
+func TestGeneratedPasswordMustBeChanged(t *testing.T) {
+	dataDir := t.TempDir()
+	s, err := New(Config{Addr: ":0", DataDir: dataDir, DatabaseDSN: filepath.Join(dataDir, "test.db"), SessionTTL: "1h"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	pwFile := filepath.Join(dataDir, "secrets", "initial-password")
+	b, err := os.ReadFile(pwFile)
+	if err != nil {
+		t.Fatalf("read initial password: %v", err)
+	}
+	if fi, err := os.Stat(filepath.Dir(pwFile)); err != nil || fi.Mode().Perm() != 0o700 {
+		t.Fatalf("expected a private secrets directory, got %v %v", fi, err)
+	}
+	password := strings.TrimSpace(string(b))
+
+	do := func(method, path, body string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		return w
+	}
+	if w := do(http.MethodPost, "/api/auth/login", `{"username":"admin","password":"admin"}`, nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("login with admin/admin: expected 401, got %d", w.Code)
+	}
+	w := do(http.MethodPost, "/api/auth/login", `{"username":"admin","password":"`+password+`"}`, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("login: expected 200, got %d", w.Code)
+	}
+	var cookie *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == "hearth_session" {
+			cookie = c
+		}
+	}
+	if cookie == nil {
+		t.Fatal("missing session cookie")
+	}
+
+	if w := do(http.MethodPut, "/api/settings", `{"siteTitle":"x"}`, cookie); w.Code != http.StatusForbidden {
+		t.Fatalf("settings before the change: expected 403, got %d", w.Code)
+	}
+	if w := do(http.MethodGet, "/api/auth/me", "", cookie); !strings.Contains(w.Body.String(), `"mustChangePassword":true`) || strings.Contains(w.Body.String(), `"admin":true`) {
+		t.Fatalf("me before the change: %s", w.Body.String())
+	}
+	if w := do(http.MethodPost, "/api/auth/password", `{"oldPassword":"`+password+`","newPassword":"s3cret-pass"}`, cookie); w.Code != http.StatusOK {
+		t.Fatalf("change password: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodGet, "/api/auth/me", "", cookie); strings.Contains(w.Body.String(), "mustChangePassword") || !strings.Contains(w.Body.String(), `"admin":true`) {
+		t.Fatalf("me after the change: %s", w.Body.String())
+	}
+	if w := do(http.MethodPut, "/api/settings", `{"siteTitle":"x"}`, cookie); w.Code != http.StatusOK {
+		t.Fatalf("settings after the change: expected 200, got %d", w.Code)
+	}
+	if _, err := os.Stat(pwFile); !os.IsNotExist(err) {
+		t.Fatalf("initial password file should be removed, stat err = %v", err)
+	}
+}
+
+func TestShares(t *testing.T) {
+	s := newTestServer(t)
+	cookie := loginAsAdmin(t, s)
+	do := func(method, path, body string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPost, "/api/groups", `{"name":"Media"}`, cookie)
+	var group store.Group
+	_ = json.Unmarshal(w.Body.Bytes(), &group)
+	do(http.MethodPost, "/api/groups", `{"name":"Private"}`, cookie)
+	if w := do(http.MethodPost, "/api/apps", `{"name":"Jellyfin","url":"https://media.example.com","internalUrl":"http://10.0.0.5:8096","groupId":"`+group.ID+`","wakeMac":"aa:bb:cc:dd:ee:ff"}`, cookie); w.Code != http.StatusCreated {
+		t.Fatalf("create app: %d %s", w.Code, w.Body.String())
+	}
+
+	if w := do(http.MethodPost, "/api/admin/shares", `{"name":"Guests"}`, nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a session, got %d", w.Code)
+	}
+	if w := do(http.MethodPost, "/api/admin/shares", `{"name":"Guests","groupId":"missing"}`, cookie); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown group, got %d", w.Code)
+	}
+	w = do(http.MethodPost, "/api/admin/shares", `{"name":"Guests","groupId":"`+group.ID+`"}`, cookie)
+	var created struct {
+		Share store.Share `json:"share"`
+		Token string      `json:"token"`
+		URL   string      `json:"url"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+	if w.Code != http.StatusOK || created.Token == "" || created.URL != "/share/"+created.Token {
+		t.Fatalf("unexpected create: %d %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(do(http.MethodGet, "/api/admin/shares", "", cookie).Body.String(), created.Token) {
+		t.Fatalf("share list must not expose the token")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/share/"+created.Token, nil)
+	req.Header.Set("X-Forwarded-For", "10.0.0.9")
+	req.Header.Set("X-Real-IP", "10.0.0.9")
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	body := w.Body.String()
+	for _, leak := range []string{"10.0.0.5", "aa:bb:cc", "Private"} {
+		if strings.Contains(body, leak) {
+			t.Fatalf("share page leaks %q: %s", leak, body)
+		}
+	}
+	var page struct {
+		Groups []struct {
+			Name string `json:"name"`
+			Apps []struct {
+				Href string `json:"href"`
+			} `json:"apps"`
+		} `json:"groups"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &page)
+	if len(page.Groups) != 1 || page.Groups[0].Name != "Media" || len(page.Groups[0].Apps) != 1 || page.Groups[0].Apps[0].Href != "https://media.example.com" {
+		t.Fatalf("unexpected share page: %s", body)
+	}
+	if w := do(http.MethodGet, "/api/share/nope", "", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown token, got %d", w.Code)
+	}
+
+	if w := do(http.MethodDelete, "/api/admin/shares/"+created.Share.ID, "", cookie); w.Code != http.StatusOK {
+		t.Fatalf("delete share: %d", w.Code)
+	}
+	if w := do(http.MethodGet, "/api/share/"+created.Token, "", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 after revoking, got %d", w.Code)
+	}
+}
+
+func TestAppShortcuts(t *testing.T) {
+	for raw, want := range map[string]string{"p": "p", "G P": "g p", "g+p": "g p", "gp": "g p", "g-2": "g 2"} {
+		if got, err := normalizeShortcut(raw); err != nil || got != want {
+			t.Fatalf("normalizeShortcut(%q) = %q, %v; want %q", raw, got, err, want)
+		}
+	}
+	for _, raw := range []string{"g p x y", "ctrl+p", "?", "é"} {
+		if _, err := normalizeShortcut(raw); err == nil {
+			t.Fatalf("normalizeShortcut(%q) should fail", raw)
+		}
+	}
+
+	s := newTestServer(t)
+	cookie := loginAsAdmin(t, s)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPost, "/api/apps", `{"name":"Plex","url":"http://plex.lan","shortcut":"G+P"}`)
+	var plex store.AppItem
+	_ = json.Unmarshal(w.Body.Bytes(), &plex)
+	if w.Code != http.StatusCreated || plex.Shortcut == nil || *plex.Shortcut != "g p" {
+		t.Fatalf("unexpected create: %d %s", w.Code, w.Body.String())
+	}
+	for _, sc := range []string{"g p", "g", "g p r"} {
+		if w := do(http.MethodPost, "/api/apps", `{"name":"Other","url":"http://other.lan","shortcut":"`+sc+`"}`); w.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for clashing shortcut %q, got %d", sc, w.Code)
+		}
+	}
+	if w := do(http.MethodPost, "/api/apps", `{"name":"Grafana","url":"http://grafana.lan","shortcut":"g r"}`); w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	// Saving an app keeps its own shortcut.
+	if w := do(http.MethodPut, "/api/apps/"+plex.ID, `{"name":"Plex","url":"http://plex.lan","shortcut":"g p"}`); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var apps []store.AppItem
+	getJSON(t, s, "/api/apps", &apps)
+	found := false
+	for _, a := range apps {
+		if a.ID == plex.ID && a.Shortcut != nil && *a.Shortcut == "g p" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("shortcut missing from the app list: %+v", apps)
+	}
+}
+
+func TestRecentApps(t *testing.T) {
+	s := newTestServer(t)
+	cookie := loginAsAdmin(t, s)
+	body := `{"name":"Paperless","url":"https://docs.example.com","actions":[{"label":"Ping","kind":"webhook","url":"https://hooks.example.com/x?token=secret"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/apps", strings.NewReader(body))
+	req.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create app: %d %s", w.Code, w.Body.String())
+	}
+
+	var recent []appView
+	getJSON(t, s, "/api/apps/recent?sort=updated&limit=5", &recent)
+	if len(recent) == 0 || recent[0].Name != "Paperless" || recent[0].Href != "https://docs.example.com" || recent[0].UpdatedAt == 0 {
+		t.Fatalf("unexpected recent apps: %+v", recent)
+	}
+	for _, a := range recent {
+		if strings.HasPrefix(a.URL, "widget:") {
+			t.Fatalf("widgets must not be listed: %+v", a)
+		}
+		if len(a.Actions) != 0 {
+			t.Fatalf("visitors must not see actions: %+v", a)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/apps/recent?sort=name", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown sort, got %d", w.Code)
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	for src, want := range map[string]string{
+		"Plain & simple":                       "<p>Plain &amp; simple</p>",
+		"**Media** server\nfor *movies*":       "<p><strong>Media</strong> server<br>for <em>movies</em></p>",
+		"Run `docker ps`\n\n- one\n- two":      "<p>Run <code>docker ps</code></p><ul><li>one</li><li>two</li></ul>",
+		"[Docs](https://docs.example.com/a?b)": `<p><a href="https://docs.example.com/a?b" target="_blank" rel="noopener noreferrer nofollow">Docs</a></p>`,
+		"[x](javascript:alert(1))":             "<p>x)</p>",
+		"[x](/\\evil.com)":                     "<p>x</p>",
+		"<script>alert(1)</script>":            "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>",
+		`[a"b](https://e.com/"onmouseover=x)`:  `<p><a href="https://e.com/%22onmouseover=x" target="_blank" rel="noopener noreferrer nofollow">a&#34;b</a></p>`,
+		"snake_case_name stays":                "<p>snake_case_name stays</p>",
+		"\\*not italic\\*":                     "<p>*not italic*</p>",
+	} {
+		if got := renderMarkdown(src); got != want {
+			t.Errorf("renderMarkdown(%q)\n got %s\nwant %s", src, got, want)
+		}
+	}
+}
+
+func TestAppPreview(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><head><title>Paperless</title><meta name="description" content="Documents"></head></html>`))
+	}))
+	defer up.Close()
+
+	dataDir := t.TempDir()
+	s, err := New(Config{Addr: ":0", DataDir: dataDir, DatabaseDSN: filepath.Join(dataDir, "test.db"), SessionTTL: "1h", InitialPassword: "admin", OutboundAllow: "127.0.0.1"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	cookie := loginAsAdmin(t, s)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPost, "/api/apps", `{"name":"Paperless","url":"`+up.URL+`","description":"  **Scans**  "}`)
+	var app store.AppItem
+	_ = json.Unmarshal(w.Body.Bytes(), &app)
+	if w.Code != http.StatusCreated || app.Description == nil || *app.Description != "**Scans**" {
+		t.Fatalf("unexpected create: %d %s", w.Code, w.Body.String())
+	}
+	long := strings.Repeat("a", maxDescriptionLen+1)
+	if w := do(http.MethodPost, "/api/apps", `{"name":"Long","url":"http://long.lan","description":"`+long+`"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a long description, got %d", w.Code)
+	}
+
+	if w := do(http.MethodPost, "/api/apps/"+app.ID+"/preview", ""); w.Code != http.StatusOK {
+		t.Fatalf("fetch preview: %d %s", w.Code, w.Body.String())
+	}
+	// Editing the app keeps the preview.
+	if w := do(http.MethodPut, "/api/apps/"+app.ID, `{"name":"Paperless-ngx","url":"`+up.URL+`","description":"**Scans**"}`); w.Code != http.StatusOK {
+		t.Fatalf("update: %d %s", w.Code, w.Body.String())
+	}
+	var apps []appView
+	getJSON(t, s, "/api/apps", &apps)
+	var got *appView
+	for i := range apps {
+		if apps[i].ID == app.ID {
+			got = &apps[i]
+		}
+	}
+	if got == nil || got.Preview == nil || got.Preview.Title != "Paperless" || got.Preview.Description != "Documents" {
+		t.Fatalf("preview missing: %+v", got)
+	}
+	if got.DescriptionHTML != "<p><strong>Scans</strong></p>" {
+		t.Fatalf("unexpected description html %q", got.DescriptionHTML)
+	}
+
+	if w := do(http.MethodDelete, "/api/apps/"+app.ID+"/preview", ""); w.Code != http.StatusOK {
+		t.Fatalf("clear preview: %d", w.Code)
+	}
+	if a, _, _ := s.store.AppByID(app.ID); a.Preview != nil {
+		t.Fatalf("preview not cleared: %+v", a.Preview)
+	}
+}
+
+func TestArchiveApp(t *testing.T) {
+	s := newTestServer(t)
+	cookie := loginAsAdmin(t, s)
+	do := func(method, path, body string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		return w
+	}
+	listed := func(path string, cookie *http.Cookie, id string) bool {
+		var apps []appView
+		if err := json.Unmarshal(do(http.MethodGet, path, "", cookie).Body.Bytes(), &apps); err != nil {
+			t.Fatalf("decode %s: %v", path, err)
+		}
+		for _, a := range apps {
+			if a.ID == id {
+				return true
+			}
+		}
+		return false
+	}
+
+	w := do(http.MethodPost, "/api/apps", `{"name":"Pool heater","url":"http://pool.lan","description":"Summer only"}`, cookie)
+	var app store.AppItem
+	_ = json.Unmarshal(w.Body.Bytes(), &app)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create app: %d %s", w.Code, w.Body.String())
+	}
+
+	if w := do(http.MethodPut, "/api/apps/"+app.ID+"/archive", `{"archived":true}`, nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a session, got %d", w.Code)
+	}
+	if w := do(http.MethodPut, "/api/apps/missing/archive", `{"archived":true}`, cookie); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown app, got %d", w.Code)
+	}
+	if w := do(http.MethodPut, "/api/apps/"+app.ID+"/archive", `{"archived":true}`, cookie); w.Code != http.StatusOK {
+		t.Fatalf("archive: %d %s", w.Code, w.Body.String())
+	}
+	if listed("/api/apps", cookie, app.ID) || listed("/api/apps/recent", nil, app.ID) {
+		t.Fatalf("archived app still on the dashboard")
+	}
+	if listed("/api/apps?includeArchived=1", nil, app.ID) {
+		t.Fatalf("visitors must not list archived apps")
+	}
+	if !listed("/api/apps?includeArchived=1", cookie, app.ID) {
+		t.Fatalf("admin should list archived apps on request")
+	}
+
+	// Editing keeps the app archived, config included.
+	if w := do(http.MethodPut, "/api/apps/"+app.ID, `{"name":"Pool heater","url":"http://pool.lan","description":"Summer only!"}`, cookie); w.Code != http.StatusOK {
+		t.Fatalf("update: %d %s", w.Code, w.Body.String())
+	}
+	if a, _, _ := s.store.AppByID(app.ID); !a.Archived || a.Description == nil || *a.Description != "Summer only!" {
+		t.Fatalf("unexpected app after edit: %+v", a)
+	}
+
+	if w := do(http.MethodPut, "/api/apps/"+app.ID+"/archive", `{"archived":false}`, cookie); w.Code != http.StatusOK {
+		t.Fatalf("restore: %d", w.Code)
+	}
+	if !listed("/api/apps", nil, app.ID) {
+		t.Fatalf("restored app missing from the dashboard")
+	}
+}
+
+func TestMoveApp(t *testing.T) {
+	s, _ := newMemTestServer(t)
+	cookie := loginAsAdmin(t, s)
+	do := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		return w
+	}
+	var from, to store.Group
+	_ = json.Unmarshal(do("/api/groups", `{"name":"From"}`).Body.Bytes(), &from)
+	_ = json.Unmarshal(do("/api/groups", `{"name":"To"}`).Body.Bytes(), &to)
+	ids := map[string]string{}
+	for _, c := range []struct{ name, group string }{{"a", from.ID}, {"b", from.ID}, {"x", to.ID}, {"y", to.ID}} {
+		var a store.AppItem
+		_ = json.Unmarshal(do("/api/apps", `{"name":"`+c.name+`","url":"http://`+c.name+`.lan","groupId":"`+c.group+`"}`).Body.Bytes(), &a)
+		ids[c.name] = a.ID
+	}
+
+	if w := do("/api/apps/move", `{"appId":"`+ids["a"]+`","targetGroupId":"`+to.ID+`","targetIndex":1}`); w.Code != http.StatusOK {
+		t.Fatalf("move: %d %s", w.Code, w.Body.String())
+	}
+	var apps []appView
+	getJSON(t, s, "/api/apps", &apps)
+	got := map[string]string{}
+	for _, a := range apps {
+		if a.GroupID != nil {
+			got[*a.GroupID] += fmt.Sprintf("%s%d,", a.Name, a.SortOrder)
+		}
+	}
+	if got[from.ID] != "b1," || got[to.ID] != "x1,a2,y3," {
+		t.Fatalf("unexpected order after move: %v", got)
+	}
+
+	if w := do("/api/apps/move", `{"appId":"missing","targetGroupId":null,"targetIndex":0}`); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown app, got %d", w.Code)
+	}
+	var system store.Group
+	_ = json.Unmarshal(do("/api/groups", `{"name":"Widgets","kind":"system"}`).Body.Bytes(), &system)
+	if w := do("/api/apps/move", `{"appId":"`+ids["b"]+`","targetGroupId":"`+system.ID+`","targetIndex":0}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 moving an app into the system group, got %d", w.Code)
+	}
+}
+
+func TestDeleteGroupApps(t *testing.T) {
+	s, st := newMemTestServer(t)
+	cookie := loginAsAdmin(t, s)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		return w
+	}
+	group := func(name string) string {
+		var g store.Group
+		_ = json.Unmarshal(do(http.MethodPost, "/api/groups", `{"name":"`+name+`"}`).Body.Bytes(), &g)
+		for _, app := range []string{"a", "b"} {
+			do(http.MethodPost, "/api/apps", `{"name":"`+name+`-`+app+`","url":"http://`+app+`.lan","groupId":"`+g.ID+`"}`)
+		}
+		return g.ID
+	}
+	affected := func(w *httptest.ResponseRecorder) int {
+		var out struct {
+			Affected int `json:"affected"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &out)
+		return out.Affected
+	}
+	groupOf := func(name string) (string, bool) {
+		apps, _ := st.ListApps()
+		for _, a := range apps {
+			if a.Name == name {
+				if a.GroupID == nil {
+					return "", true
+				}
+				return *a.GroupID, true
+			}
+		}
+		return "", false
+	}
+
+	keep := group("keep")
+	moved := group("moved")
+	if w := do(http.MethodDelete, "/api/groups/"+moved+"?apps=move&target="+keep, ""); w.Code != http.StatusOK || affected(w) != 2 {
+		t.Fatalf("move: %d %s", w.Code, w.Body.String())
+	}
+	if g, ok := groupOf("moved-b"); !ok || g != keep {
+		t.Fatalf("moved app in group %q (found %v)", g, ok)
+	}
+	apps, _ := st.ListApps()
+	var order []string
+	for _, a := range apps {
+		if a.GroupID != nil && *a.GroupID == keep {
+			order = append(order, a.Name)
+		}
+	}
+	if strings.Join(order, ",") != "keep-a,keep-b,moved-a,moved-b" {
+		t.Fatalf("moved apps should go last in order: %v", order)
+	}
+
+	ungrouped := group("ungrouped")
+	if w := do(http.MethodDelete, "/api/groups/"+ungrouped+"?apps=ungroup", ""); w.Code != http.StatusOK || affected(w) != 2 {
+		t.Fatalf("ungroup: %d %s", w.Code, w.Body.String())
+	}
+	if g, ok := groupOf("ungrouped-a"); !ok || g != "" {
+		t.Fatalf("ungrouped app in group %q (found %v)", g, ok)
+	}
+
+	deleted := group("deleted")
+	for _, bad := range []string{"?apps=move", "?apps=move&target=" + deleted, "?apps=move&target=missing", "?apps=archive"} {
+		if w := do(http.MethodDelete, "/api/groups/"+deleted+bad, ""); w.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %s, got %d", bad, w.Code)
+		}
+	}
+	if w := do(http.MethodDelete, "/api/groups/"+deleted, ""); w.Code != http.StatusOK || affected(w) != 2 {
+		t.Fatalf("delete: %d %s", w.Code, w.Body.String())
+	}
+	if _, ok := groupOf("deleted-a"); ok {
+		t.Fatalf("apps of the deleted group should be gone")
+	}
+	if w := do(http.MethodDelete, "/api/groups/"+deleted, ""); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a deleted group, got %d", w.Code)
+	}
+}
+
+func TestUndo(t *testing.T) {
+	s := newTestServer(t)
+	s.cfg.UndoWindow = time.Minute
+	cookie := loginAsAdmin(t, s)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		return w
+	}
+	names := func() string {
+		apps, _ := s.store.ListApps()
+		var out []string
+		for _, a := range apps {
+			if !strings.HasPrefix(a.URL, "widget:") {
+				out = append(out, a.Name)
+			}
+		}
+		slices.Sort(out)
+		return strings.Join(out, ",")
+	}
+
+	if w := do(http.MethodPost, "/api/admin/undo", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 with an empty journal, got %d", w.Code)
+	}
+
+	var g store.Group
+	_ = json.Unmarshal(do(http.MethodPost, "/api/groups", `{"name":"media"}`).Body.Bytes(), &g)
+	var app store.AppItem
+	_ = json.Unmarshal(do(http.MethodPost, "/api/apps", `{"name":"jellyfin","url":"http://jf.lan","groupId":"`+g.ID+`"}`).Body.Bytes(), &app)
+	do(http.MethodPost, "/api/apps", `{"name":"plex","url":"http://plex.lan","groupId":"`+g.ID+`"}`)
+
+	do(http.MethodDelete, "/api/apps/"+app.ID, "")
+	do(http.MethodDelete, "/api/groups/"+g.ID, "")
+	if got := names(); got != "" {
+		t.Fatalf("apps left after deleting the group: %q", got)
+	}
+	var list struct {
+		Entries []undoEntry `json:"entries"`
+	}
+	_ = json.Unmarshal(do(http.MethodGet, "/api/admin/undo", "").Body.Bytes(), &list)
+	if len(list.Entries) != 2 || list.Entries[0].Op != undoDeleteGroup || list.Entries[1].Op != undoDeleteApp {
+		t.Fatalf("unexpected journal: %+v", list.Entries)
+	}
+
+	// The latest operation goes first; the group comes back with its apps.
+	if w := do(http.MethodPost, "/api/admin/undo", ""); w.Code != http.StatusOK {
+		t.Fatalf("undo group: %d %s", w.Code, w.Body.String())
+	}
+	if got := names(); got != "plex" {
+		t.Fatalf("after undoing the group delete: %q", got)
+	}
+	if w := do(http.MethodPost, "/api/admin/undo", `{"id":"`+list.Entries[1].ID+`"}`); w.Code != http.StatusOK {
+		t.Fatalf("undo app: %d %s", w.Code, w.Body.String())
+	}
+	if got, _, _ := s.store.AppByID(app.ID); got.GroupID == nil || *got.GroupID != g.ID {
+		t.Fatalf("restored app lost its group: %+v", got)
+	}
+
+	// Undoing an import drops what it added.
+	imported := `{"version":2,"groups":[],"apps":[{"id":"x1","name":"imported","url":"http://x.lan"}]}`
+	if w := do(http.MethodPost, "/api/import", imported); w.Code != http.StatusOK {
+		t.Fatalf("import: %d %s", w.Code, w.Body.String())
+	}
+	if got := names(); got != "imported,jellyfin,plex" {
+		t.Fatalf("after import: %q", got)
+	}
+	if w := do(http.MethodPost, "/api/admin/undo", ""); w.Code != http.StatusOK {
+		t.Fatalf("undo import: %d %s", w.Code, w.Body.String())
+	}
+	if got := names(); got != "jellyfin,plex" {
+		t.Fatalf("after undoing the import: %q", got)
+	}
+
+	// A reset signs everyone out; the configuration comes back.
+	do(http.MethodPost, "/api/admin/reset", `{"password":"admin"}`)
+	cookie = loginAsAdmin(t, s)
+	if got := names(); got != "" {
+		t.Fatalf("apps left after reset: %q", got)
+	}
+	if w := do(http.MethodPost, "/api/admin/undo", ""); w.Code != http.StatusOK {
+		t.Fatalf("undo reset: %d %s", w.Code, w.Body.String())
+	}
+	if got := names(); got != "jellyfin,plex" {
+		t.Fatalf("after undoing the reset: %q", got)
+	}
+
+	s.cfg.UndoWindow = 0
+	do(http.MethodDelete, "/api/apps/"+app.ID, "")
+	if w := do(http.MethodPost, "/api/admin/undo", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("expected nothing to undo when disabled, got %d", w.Code)
+	}
+}
+
+func TestAdminResetScopes(t *testing.T) {
+	s, st := newMemTestServer(t)
+	cookie := loginAsAdmin(t, s)
+	do := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/reset", strings.NewReader(body))
+		req.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		return w
+	}
+	count := func() (apps, widgets int) {
+		list, _ := st.ListApps()
+		for _, a := range list {
+			if strings.HasPrefix(a.URL, "widget:") {
+				widgets++
+			} else {
+				apps++
+			}
+		}
+		return apps, widgets
+	}
+
+	g, _ := st.CreateGroup("Media", "app")
+	if _, err := st.CreateApp(store.AppItem{GroupID: &g.ID, Name: "jellyfin", URL: "http://jf.lan"}); err != nil {
+		t.Fatal(err)
+	}
+	_ = st.SetKV(kvSiteTitle, "Cabin")
+	_, widgetsBefore := count()
+	if widgetsBefore == 0 {
+		t.Fatal("expected the default widgets")
+	}
+
+	for body, code := range map[string]int{
+		``:                                   http.StatusBadRequest,
+		`{"scope":"full"}`:                   http.StatusBadRequest,
+		`{"scope":"full","password":"nope"}`: http.StatusForbidden,
+		`{"scope":"users"}`:                  http.StatusBadRequest,
+	} {
+		if w := do(body); w.Code != code {
+			t.Fatalf("%s: expected %d, got %d", body, code, w.Code)
+		}
+	}
+	if apps, _ := count(); apps != 1 {
+		t.Fatal("rejected resets must not clear anything")
+	}
+
+	if w := do(`{"scope":"apps"}`); w.Code != http.StatusOK {
+		t.Fatalf("apps: %d %s", w.Code, w.Body.String())
+	}
+	if apps, widgets := count(); apps != 0 || widgets != widgetsBefore {
+		t.Fatalf("apps reset left %d apps and %d widgets", apps, widgets)
+	}
+	if _, ok, _ := st.GroupKindByID(g.ID); ok {
+		t.Fatal("app groups should be removed")
+	}
+	if v, _, _ := st.GetKV(kvSiteTitle); v != "Cabin" {
+		t.Fatalf("apps reset touched the settings: %q", v)
+	}
+
+	if w := do(`{"scope":"settings"}`); w.Code != http.StatusOK {
+		t.Fatalf("settings: %d %s", w.Code, w.Body.String())
+	}
+	if _, ok, _ := st.GetKV(kvSiteTitle); ok {
+		t.Fatal("settings reset should clear the site title")
+	}
+
+	if w := do(`{"scope":"widgets"}`); w.Code != http.StatusOK {
+		t.Fatalf("widgets: %d %s", w.Code, w.Body.String())
+	}
+	if _, widgets := count(); widgets != 0 {
+		t.Fatalf("widgets reset left %d widgets", widgets)
+	}
+
+	// A full reset with the password still works for the signed-in admin.
+	if w := do(`{"scope":"full","password":"admin"}`); w.Code != http.StatusOK {
+		t.Fatalf("full: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestSeedDemo(t *testing.T) {
+	s, st := newMemTestServer(t)
+	before, _ := st.ListApps()
+
+	added, err := s.SeedDemo(context.Background())
+	if err != nil || !added {
+		t.Fatalf("SeedDemo: added=%v err=%v", added, err)
+	}
+	apps, _ := st.ListApps()
+	var demoApps, widgets int
+	for _, a := range apps {
+		if strings.HasPrefix(a.URL, "widget:") {
+			widgets++
+			continue
+		}
+		demoApps++
+		if a.IconPath == nil || !strings.HasPrefix(*a.IconPath, tileIconPrefix) {
+			t.Fatalf("%s has no tile icon: %v", a.Name, a.IconPath)
+		}
+		if _, err := s.iconStore.Stat(context.Background(), *a.IconPath); err != nil {
+			t.Fatalf("tile of %s not stored: %v", a.Name, err)
+		}
+	}
+	if demoApps == 0 || widgets <= len(before) {
+		t.Fatalf("expected demo apps and widgets, got %d apps and %d widgets", demoApps, widgets)
+	}
+	if v, _, _ := st.GetKV(kvSiteTitle); v != "Hearth Demo" {
+		t.Fatalf("site title = %q", v)
+	}
+
+	// The marker keeps a restart from adding the data twice.
+	if added, err := s.SeedDemo(context.Background()); err != nil || added {
+		t.Fatalf("second SeedDemo: added=%v err=%v", added, err)
+	}
+	if again, _ := st.ListApps(); len(again) != len(apps) {
+		t.Fatalf("second run changed the apps: %d -> %d", len(apps), len(again))
+	}
+}
+
+func TestUserSettings(t *testing.T) {
+	s, st := newMemTestServer(t)
+	cookie := loginAsAdmin(t, s)
+	do := func(method, path, body string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		return w
+	}
+	get := func(cookie *http.Cookie) Settings {
+		t.Helper()
+		w := do(http.MethodGet, "/api/settings", "", cookie)
+		var got Settings
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatalf("decode settings: %v", err)
+		}
+		return got
+	}
+
+	if w := do(http.MethodPut, "/api/settings/user", `{"language":"en"}`, nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("put without a session: expected 401, got %d", w.Code)
+	}
+	for _, body := range []string{`{"language":"??"}`, `{"theme":{"mode":"neon"}}`, `{"theme":{"customCss":"a{}"}}`} {
+		if w := do(http.MethodPut, "/api/settings/user", body, cookie); w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", body, w.Code)
+		}
+	}
+	w := do(http.MethodPut, "/api/settings/user", `{"language":"en","units":{"system":"imperial"},"theme":{"mode":"light"},"pinnedWidgets":["w1"," w1 ","w2"]}`, cookie)
+	if w.Code != http.StatusOK {
+		t.Fatalf("put user settings: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	mine, shared := get(cookie), get(nil)
+	if mine.Language != "en" || mine.Units.System != "imperial" || mine.Theme.Mode != "light" || !slices.Equal(mine.PinnedWidgets, []string{"w1", "w2"}) {
+		t.Fatalf("user settings not applied: %+v %+v %+v %v", mine.Language, mine.Units, mine.Theme, mine.PinnedWidgets)
+	}
+	if mine.Theme.Accent != defaultThemeAccent {
+		t.Errorf("accent should follow the shared theme, got %q", mine.Theme.Accent)
+	}
+	if shared.Language != "zh" || shared.Units.System != "metric" || shared.Theme.Mode != "dark" || len(shared.PinnedWidgets) != 0 {
+		t.Fatalf("visitors should see the shared settings: %+v %+v %+v", shared.Language, shared.Units, shared.Theme)
+	}
+
+	// Saving the resolved settings keeps overridden keys per user.
+	mine.SiteTitle, mine.Language = "Cabin", "de"
+	b, _ := json.Marshal(mine)
+	if w := do(http.MethodPut, "/api/settings", string(b), cookie); w.Code != http.StatusOK {
+		t.Fatalf("put settings: expected 200, got %d", w.Code)
+	}
+	if v, _, _ := st.GetKV(kvLanguage); v == "de" {
+		t.Error("the user's language was saved as the shared one")
+	}
+	if v, _, _ := st.GetKV(kvSiteTitle); v != "Cabin" {
+		t.Errorf("shared site title = %q, want Cabin", v)
+	}
+	if got := get(cookie); got.Language != "de" {
+		t.Errorf("user language = %q, want de", got.Language)
+	}
+
+	if w := do(http.MethodDelete, "/api/settings/user", "", cookie); w.Code != http.StatusOK {
+		t.Fatalf("delete user settings: expected 200, got %d", w.Code)
+	}
+	if w := do(http.MethodGet, "/api/settings/user", "", cookie); strings.TrimSpace(w.Body.String()) != "{}" {
+		t.Errorf("overrides after reset: %s", w.Body.String())
+	}
+	if got := get(cookie); got.Language != "zh" || got.Theme.Mode != "dark" {
+		t.Errorf("after reset: language %q, mode %q", got.Language, got.Theme.Mode)
+	}
+}
+
+func TestSettingsHistory(t *testing.T) {
+	s, st := newMemTestServer(t)
+	cookie := loginAsAdmin(t, s)
+	do := func(method, path, body string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		return w
+	}
+	history := func(query string) []store.SettingChange {
+		t.Helper()
+		w := do(http.MethodGet, "/api/settings/history"+query, "", cookie)
+		if w.Code != http.StatusOK {
+			t.Fatalf("history: expected 200, got %d", w.Code)
+		}
+		var resp struct {
+			Changes []store.SettingChange `json:"changes"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp.Changes
+	}
+
+	for _, body := range []string{
+		`{"background":{"provider":"bing_daily"},"weather":{"apiKey":"secret"}}`,
+		`{"background":{"provider":"picsum"},"weather":{"apiKey":"secret"}}`,
+	} {
+		if w := do(http.MethodPut, "/api/settings", body, cookie); w.Code != http.StatusOK {
+			t.Fatalf("put settings: expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+	if w := do(http.MethodGet, "/api/settings/history", "", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("history without a session: expected 401, got %d", w.Code)
+	}
+
+	changes := history("?key=" + kvBackgroundProvider)
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 provider changes, got %+v", changes)
+	}
+	latest := changes[0]
+	if derefString(latest.OldValue) != "bing_daily" || derefString(latest.NewValue) != "picsum" || latest.Actor != "admin" {
+		t.Fatalf("unexpected latest change: %+v", latest)
+	}
+	secret := history("?key=" + kvWeatherAPIKey)
+	if len(secret) != 1 || !secret[0].Redacted || secret[0].NewValue != nil {
+		t.Fatalf("api key change should be recorded without its value: %+v", secret)
+	}
+
+	w := do(http.MethodPost, fmt.Sprintf("/api/settings/history/%d/revert", latest.ID), "", cookie)
+	if w.Code != http.StatusOK {
+		t.Fatalf("revert: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if v, _, _ := st.GetKV(kvBackgroundProvider); v != "bing_daily" {
+		t.Errorf("provider after revert = %q, want bing_daily", v)
+	}
+	if c := history("?limit=1"); len(c) != 1 || c[0].RevertOf != latest.ID || derefString(c[0].NewValue) != "bing_daily" {
+		t.Errorf("the revert should be recorded: %+v", c)
+	}
+
+	// Reverting the first change removes the provider again.
+	first := changes[1]
+	if w := do(http.MethodPost, fmt.Sprintf("/api/settings/history/%d/revert", first.ID), "", cookie); w.Code != http.StatusOK {
+		t.Fatalf("revert first: expected 200, got %d", w.Code)
+	}
+	if _, ok, _ := st.GetKV(kvBackgroundProvider); ok {
+		t.Error("provider should be unset after reverting its first change")
+	}
+
+	if w := do(http.MethodPost, fmt.Sprintf("/api/settings/history/%d/revert", secret[0].ID), "", cookie); w.Code != http.StatusBadRequest {
+		t.Errorf("reverting a redacted change: expected 400, got %d", w.Code)
+	}
+	if w := do(http.MethodPost, "/api/settings/history/9999/revert", "", cookie); w.Code != http.StatusNotFound {
+		t.Errorf("unknown change: expected 404, got %d", w.Code)
+	}
+}
+
+func TestSettingsSchemaValidation(t *testing.T) {
+	s, st := newMemTestServer(t)
+	cookie := loginAsAdmin(t, s)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPut, "/api/settings", `{"siteTitle":"Cabin","weather":{"provider":"darksky","forecastDays":40},"time":{"enabled":true,"timezone":"Mars/Olympus"},"units":{"system":"metric","bytes":"nibbles"},"theme":{"tileOpacity":2},"titleSortOrder":1.5}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+	var resp struct {
+		Fields map[string]string `json:"fields"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"weather.provider", "weather.forecastDays", "time.timezone", "units.bytes", "theme.tileOpacity", "titleSortOrder"} {
+		if resp.Fields[f] == "" {
+			t.Errorf("no error for %s: %v", f, resp.Fields)
+		}
+	}
+	if len(resp.Fields) != 6 {
+		t.Errorf("unexpected errors: %v", resp.Fields)
+	}
+	if _, ok, _ := st.GetKV(kvSiteTitle); ok {
+		t.Error("nothing should be saved when a field is invalid")
+	}
+
+	if w := do(http.MethodPut, "/api/settings", `{"siteTitle":"Cabin","markets":{"stockProvider":"Yahoo","cryptoQuote":"usdc"}}`); w.Code != http.StatusOK {
+		t.Fatalf("valid settings: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if v, _, _ := st.GetKV(kvMarketsCryptoQuote); v != "USDC" {
+		t.Errorf("crypto quote stored as %q", v)
+	}
+
+	// Invalid stored values read as the default.
+	_ = st.SetKV(kvWeatherProvider, "darksky")
+	_ = st.SetKV(kvThemeFontScale, "9")
+	set := s.settings()
+	if got := set.String(kvWeatherProvider); got != "open-meteo" {
+		t.Errorf("weather provider = %q", got)
+	}
+	if got := set.Float(kvThemeFontScale); got != defaultThemeFontScale {
+		t.Errorf("font scale = %v", got)
+	}
+	if err := set.Set(kvUnitsSystem, "furlongs"); err == nil {
+		t.Error("Set should reject values outside the schema")
+	}
+
+	w = do(http.MethodGet, "/api/settings/schema", "")
+	var schema []struct {
+		Field   string   `json:"field"`
+		Type    string   `json:"type"`
+		Default any      `json:"default"`
+		Values  []string `json:"values"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &schema); err != nil {
+		t.Fatal(err)
+	}
+	i := slices.IndexFunc(schema, func(d struct {
+		Field   string   `json:"field"`
+		Type    string   `json:"type"`
+		Default any      `json:"default"`
+		Values  []string `json:"values"`
+	}) bool {
+		return d.Field == "weather.provider"
+	})
+	if i < 0 || schema[i].Type != "enum" || schema[i].Default != "open-meteo" || len(schema[i].Values) != 3 {
+		t.Errorf("weather.provider in schema: %+v", schema)
+	}
 }
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/morezhou/hearth/internal/store"
+)
+
+// maxSettingsHistoryList bounds one page of GET /api/settings/history.
+const maxSettingsHistoryList = 500
+
+// settingsRecorder is the settings store as written by the admin: every
+// change of a value is added to the settings history.
+type settingsRecorder struct {
+	store.KVRepository
+	history  store.SettingsHistoryRepository
+	actor    string
+	revertOf int64
+}
+
+// recordSettings returns the settings store for writes made by the request's
+// user.
+func (s *Server) recordSettings(r *http.Request) settingsRecorder {
+	rec := settingsRecorder{KVRepository: s.store, history: s.store}
+	if userID, ok := userIDFromContext(r); ok {
+		if name, err := s.auth.Username(userID); err == nil {
+			rec.actor = name
+		} else {
+			rec.actor = userID
+		}
+	}
+	return rec
+}
+
+func (rec settingsRecorder) SetKV(key, value string) error { return rec.write(key, &value) }
+
+func (rec settingsRecorder) DeleteKV(key string) error { return rec.write(key, nil) }
+
+// write stores value, removing key when it is nil, and records the change.
+// An empty value counts as unset, so rewriting it is not a change.
+func (rec settingsRecorder) write(key string, value *string) error {
+	var old *string
+	if v, ok, err := rec.KVRepository.GetKV(key); err != nil {
+		return err
+	} else if ok {
+		old = &v
+	}
+	var err error
+	if value == nil {
+		err = rec.KVRepository.DeleteKV(key)
+	} else {
+		err = rec.KVRepository.SetKV(key, *value)
+	}
+	if err != nil {
+		return err
+	}
+	if derefString(old) == derefString(value) {
+		return nil
+	}
+	c := store.SettingChange{Key: key, Actor: rec.actor, RevertOf: rec.revertOf}
+	if slices.Contains(sensitiveSettings, key) {
+		c.Redacted = true
+	} else {
+		c.OldValue, c.NewValue = old, value
+	}
+	if _, err := rec.history.RecordSettingChange(c); err != nil {
+		slog.Warn("failed to record settings change", "key", key, "error", err)
+	}
+	return nil
+}
+
+func derefString(p *string) string {
+	if p == nil {
+		return ""
+	}
+	return *p
+}
+
+// handleListSettingsHistory handles GET /api/settings/history: the latest
+// settings changes, newest first, of one key with ?key=.
+func (s *Server) handleListSettingsHistory(w http.ResponseWriter, r *http.Request) {
+	limit := 100
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = min(n, maxSettingsHistoryList)
+	}
+	list, err := s.store.ListSettingChanges(r.URL.Query().Get("key"), limit)
+	if err != nil {
+		slog.Error("failed to list settings history", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to list settings history")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"changes": list})
+}
+
+// handleRevertSettingChange handles POST /api/settings/history/{id}/revert:
+// the setting goes back to the value it had before the change, which is
+// itself recorded. Changes of secrets keep no values and can't be reverted.
+func (s *Server) handleRevertSettingChange(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	c, ok, err := s.store.GetSettingChange(id)
+	if err != nil {
+		slog.Error("failed to load settings change", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to load settings change")
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	switch {
+	case c.Redacted:
+		writeError(w, http.StatusBadRequest, "the values of this setting are not kept")
+		return
+	case c.Key == kvCustomJS && derefString(c.OldValue) != "" && !s.cfg.CustomJS:
+		writeError(w, http.StatusBadRequest, "custom js is disabled; start the server with HEARTH_CUSTOM_JS=true")
+		return
+	}
+
+	rec := s.recordSettings(r)
+	rec.revertOf = c.ID
+	if c.OldValue == nil {
+		err = rec.DeleteKV(c.Key)
+	} else {
+		err = rec.SetKV(c.Key, *c.OldValue)
+	}
+	if err != nil {
+		slog.Error("failed to revert settings change", "error", err, "id", c.ID)
+		writeError(w, http.StatusInternalServerError, "failed to revert settings change")
+		return
+	}
+	slog.Info("settings change reverted", "key", c.Key, "id", c.ID)
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "reverted": c})
+}
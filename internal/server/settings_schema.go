@@ -0,0 +1,370 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/morezhou/hearth/internal/background"
+	"github.com/morezhou/hearth/internal/i18n"
+	"github.com/morezhou/hearth/internal/store"
+	"github.com/morezhou/hearth/internal/widgets"
+)
+
+// settingKind is the type of a declared setting's value.
+type settingKind string
+
+const (
+	kindString   settingKind = "string"
+	kindEnum     settingKind = "enum" // a string from Values
+	kindBool     settingKind = "bool"
+	kindInt      settingKind = "int"
+	kindFloat    settingKind = "float"
+	kindDuration settingKind = "duration" // a Go duration string, e.g. "30m"
+)
+
+// settingDef declares a scalar setting: the kv key it is stored under, the
+// field of Settings it appears as, its type, default and valid values. List
+// and object settings (timezones, embeds, search, ...) have their own
+// validators.
+type settingDef struct {
+	Key     string      `json:"key"`
+	Field   string      `json:"field"` // dotted JSON path, e.g. "weather.provider"
+	Kind    settingKind `json:"type"`
+	Default any         `json:"default"`
+	Values  []string    `json:"values,omitempty"`
+	Min     *float64    `json:"min,omitempty"`
+	Max     *float64    `json:"max,omitempty"`
+	// check validates a string value beyond its kind and returns it in
+	// canonical form.
+	check func(string) (string, error)
+}
+
+func bound(v float64) *float64 { return &v }
+
+// maxSiteTitle bounds the site title, in characters.
+const maxSiteTitle = 200
+
+// settingsSchema declares the scalar settings. Values outside it are
+// rejected by PUT /api/settings and read back as the default.
+var settingsSchema = []settingDef{
+	{Key: kvSiteTitle, Field: "siteTitle", Kind: kindString, Default: "My Home", check: func(v string) (string, error) {
+		if utf8.RuneCountInString(v) > maxSiteTitle {
+			return "", fmt.Errorf("must be at most %d characters", maxSiteTitle)
+		}
+		return v, nil
+	}},
+	{Key: kvLanguage, Field: "language", Kind: kindString, Default: "zh", check: func(v string) (string, error) {
+		if lang, ok := i18n.Normalize(v); ok {
+			return lang, nil
+		}
+		return "", fmt.Errorf("invalid language %q", v)
+	}},
+
+	{Key: kvBackgroundProvider, Field: "background.provider", Kind: kindEnum, Default: "default", Values: []string{
+		"default", string(background.ProviderBing), string(background.ProviderBingDaily), string(background.ProviderBingRandom),
+		string(background.ProviderUnsplash), string(background.ProviderPicsum), string(background.ProviderAPOD),
+		string(background.ProviderWikimedia), string(background.ProviderChromecast), string(background.ProviderCustom),
+	}},
+	{Key: kvBackgroundUnsplashQuery, Field: "background.unsplashQuery", Kind: kindString, Default: ""},
+	{Key: kvBackgroundInterval, Field: "background.interval", Kind: kindDuration, Default: "0"},
+	{Key: kvBackgroundBingMarket, Field: "background.bingMarket", Kind: kindEnum, Default: background.DefaultBingMarket, Values: background.BingMarkets},
+
+	{Key: kvWeatherCity, Field: "weather.city", Kind: kindString, Default: defaultWeatherCity},
+	{Key: kvWeatherProvider, Field: "weather.provider", Kind: kindEnum, Default: widgets.WeatherProviderOpenMeteo, Values: []string{
+		widgets.WeatherProviderOpenMeteo, widgets.WeatherProviderOpenWeatherMap, widgets.WeatherProviderMetNo,
+	}},
+	{Key: kvWeatherAPIKey, Field: "weather.apiKey", Kind: kindString, Default: ""},
+	{Key: kvWeatherForecastDays, Field: "weather.forecastDays", Kind: kindInt, Default: widgets.DefaultForecastDays, Min: bound(0), Max: bound(widgets.MaxForecastDays)},
+	// A PUT with 0 leaves the stored value of these alone.
+	{Key: kvWeatherFreshMinutes, Field: "weather.cacheFreshMinutes", Kind: kindInt, Default: int(widgets.DefaultWeatherOptions().FreshTTL / time.Minute), Min: bound(0)},
+	{Key: kvWeatherMaxStaleMinutes, Field: "weather.cacheMaxStaleMinutes", Kind: kindInt, Default: int(widgets.DefaultWeatherOptions().MaxStale / time.Minute), Min: bound(0)},
+	{Key: kvWeatherAlerts, Field: "weather.alerts", Kind: kindBool, Default: true},
+	{Key: kvWeatherAlertWebhook, Field: "weather.alertWebhook", Kind: kindString, Default: "", check: func(v string) (string, error) {
+		u, err := url.Parse(v)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "", fmt.Errorf("must be an http(s) URL")
+		}
+		return v, nil
+	}},
+
+	{Key: kvTimeEnabled, Field: "time.enabled", Kind: kindBool, Default: true},
+	{Key: kvTimeTimezone, Field: "time.timezone", Kind: kindString, Default: "Asia/Shanghai", check: func(v string) (string, error) {
+		if _, err := time.LoadLocation(v); err != nil {
+			return "", fmt.Errorf("unknown timezone %q", v)
+		}
+		return v, nil
+	}},
+	{Key: kvTimeShowSeconds, Field: "time.showSeconds", Kind: kindBool, Default: true},
+
+	{Key: kvMetricsHideCPUModel, Field: "metrics.hideCpuModel", Kind: kindBool, Default: false},
+	{Key: kvMetricsHideHostname, Field: "metrics.hideHostname", Kind: kindBool, Default: true},
+	{Key: kvMetricsBucketDisk, Field: "metrics.bucketDisk", Kind: kindBool, Default: false},
+
+	{Key: kvUnitsSystem, Field: "units.system", Kind: kindEnum, Default: widgets.UnitsMetric, Values: []string{widgets.UnitsMetric, widgets.UnitsImperial}},
+	{Key: kvUnitsBytes, Field: "units.bytes", Kind: kindEnum, Default: "binary", Values: []string{"binary", "decimal"}},
+
+	{Key: kvMarketsStockProvider, Field: "markets.stockProvider", Kind: kindEnum, Default: widgets.StockProviderStooq, Values: []string{
+		widgets.StockProviderStooq, widgets.StockProviderFinnhub, widgets.StockProviderTwelveData, widgets.StockProviderYahoo,
+	}},
+	{Key: kvMarketsFinnhubKey, Field: "markets.finnhubKey", Kind: kindString, Default: ""},
+	{Key: kvMarketsTwelveDataKey, Field: "markets.twelveDataKey", Kind: kindString, Default: ""},
+	{Key: kvMarketsCryptoQuote, Field: "markets.cryptoQuote", Kind: kindString, Default: widgets.DefaultCryptoQuote, check: func(v string) (string, error) {
+		if q, ok := widgets.NormalizeCryptoQuote(v); ok {
+			return q, nil
+		}
+		return "", fmt.Errorf("unsupported quote asset %q", v)
+	}},
+
+	{Key: kvSearchHistory, Field: "search.history", Kind: kindBool, Default: false},
+
+	{Key: kvThemeAccent, Field: "theme.accent", Kind: kindString, Default: defaultThemeAccent, check: func(v string) (string, error) {
+		if v = strings.ToLower(v); !themeAccentRe.MatchString(v) {
+			return "", fmt.Errorf("must be a #rrggbb color")
+		}
+		return v, nil
+	}},
+	{Key: kvThemeMode, Field: "theme.mode", Kind: kindEnum, Default: defaultThemeMode, Values: []string{"light", "dark", "auto"}},
+	{Key: kvThemeTileOpacity, Field: "theme.tileOpacity", Kind: kindFloat, Default: defaultThemeTileOpacity, Min: bound(0), Max: bound(1)},
+	{Key: kvThemeFontScale, Field: "theme.fontScale", Kind: kindFloat, Default: defaultThemeFontScale, Min: bound(minThemeFontScale), Max: bound(maxThemeFontScale)},
+
+	{Key: kvTitleSortOrder, Field: "titleSortOrder", Kind: kindInt, Default: 0},
+}
+
+// settingsByKey indexes settingsSchema.
+var settingsByKey = func() map[string]*settingDef {
+	m := make(map[string]*settingDef, len(settingsSchema))
+	for i := range settingsSchema {
+		m[settingsSchema[i].Key] = &settingsSchema[i]
+	}
+	return m
+}()
+
+// clean validates a stored or submitted value and returns it in canonical
+// form.
+func (d *settingDef) clean(v string) (string, error) {
+	v = strings.TrimSpace(v)
+	switch d.Kind {
+	case kindEnum:
+		for _, allowed := range d.Values {
+			if strings.EqualFold(v, allowed) {
+				return allowed, nil
+			}
+		}
+		return "", fmt.Errorf("must be one of %s", strings.Join(d.Values, ", "))
+	case kindBool:
+		if v != "true" && v != "false" {
+			return "", fmt.Errorf("must be true or false")
+		}
+	case kindInt:
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return "", fmt.Errorf("must be a whole number")
+		}
+		if err := d.inRange(float64(n)); err != nil {
+			return "", err
+		}
+		v = strconv.Itoa(n)
+	case kindFloat:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return "", fmt.Errorf("must be a number")
+		}
+		if err := d.inRange(f); err != nil {
+			return "", err
+		}
+		v = strconv.FormatFloat(f, 'f', -1, 64)
+	case kindDuration:
+		dur, err := time.ParseDuration(v)
+		if err != nil || dur < 0 {
+			return "", fmt.Errorf("must be a duration such as 30m or 0")
+		}
+	}
+	if d.check != nil {
+		return d.check(v)
+	}
+	return v, nil
+}
+
+func (d *settingDef) inRange(f float64) error {
+	switch {
+	case d.Min != nil && d.Max != nil && (f < *d.Min || f > *d.Max):
+		return fmt.Errorf("must be between %g and %g", *d.Min, *d.Max)
+	case d.Min != nil && f < *d.Min:
+		return fmt.Errorf("must be at least %g", *d.Min)
+	case d.Max != nil && f > *d.Max:
+		return fmt.Errorf("must be at most %g", *d.Max)
+	}
+	return nil
+}
+
+// fromJSON converts a submitted JSON value to the string clean takes.
+func (d *settingDef) fromJSON(v any) (string, error) {
+	switch d.Kind {
+	case kindBool:
+		if b, ok := v.(bool); ok {
+			return boolString(b), nil
+		}
+		return "", fmt.Errorf("must be true or false")
+	case kindInt, kindFloat:
+		if f, ok := v.(float64); ok {
+			if d.Kind == kindInt && f != float64(int64(f)) {
+				return "", fmt.Errorf("must be a whole number")
+			}
+			return strconv.FormatFloat(f, 'f', -1, 64), nil
+		}
+		return "", fmt.Errorf("must be a number")
+	default:
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+		return "", fmt.Errorf("must be a string")
+	}
+}
+
+// fieldErrors maps the dotted JSON path of invalid request fields to what is
+// wrong with them.
+type fieldErrors map[string]string
+
+// under reports whether there is an error for field or one of its children.
+func (e fieldErrors) under(field string) bool {
+	for f := range e {
+		if f == field || strings.HasPrefix(f, field+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// validateSettingsJSON checks the declared settings present in a PUT
+// /api/settings body. Omitted, null and empty string fields are skipped:
+// they keep or reset to the default like before.
+func validateSettingsJSON(body []byte) fieldErrors {
+	var doc map[string]any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return fieldErrors{"": "invalid json"}
+	}
+	errs := fieldErrors{}
+	for i := range settingsSchema {
+		d := &settingsSchema[i]
+		v, ok := lookupField(doc, d.Field)
+		if !ok || v == nil || v == "" {
+			continue
+		}
+		raw, err := d.fromJSON(v)
+		if err == nil {
+			_, err = d.clean(raw)
+		}
+		if err != nil {
+			errs[d.Field] = err.Error()
+		}
+	}
+	return errs
+}
+
+// lookupField returns the value at a dotted path of a JSON object.
+func lookupField(doc map[string]any, field string) (any, bool) {
+	var cur any = doc
+	for _, part := range strings.Split(field, ".") {
+		obj, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = obj[part]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// writeFieldErrors writes a 400 response listing the invalid fields.
+func writeFieldErrors(w http.ResponseWriter, errs fieldErrors) {
+	writeJSON(w, http.StatusBadRequest, apiFieldErrors{Error: "invalid settings", Fields: errs})
+}
+
+// settingsService reads and writes the declared settings as their types.
+// Missing and invalid stored values read as the default.
+type settingsService struct {
+	kv store.KVRepository
+}
+
+func (s *Server) settings() settingsService {
+	return settingsService{kv: s.store}
+}
+
+func mustSettingDef(key string) *settingDef {
+	d, ok := settingsByKey[key]
+	if !ok {
+		panic("undeclared setting " + key)
+	}
+	return d
+}
+
+// raw returns the canonical stored value of key, or its default.
+func (ss settingsService) raw(key string) string {
+	d := mustSettingDef(key)
+	if v, ok, err := ss.kv.GetKV(key); err == nil && ok && v != "" {
+		if v, err := d.clean(v); err == nil {
+			return v
+		}
+	}
+	return fmt.Sprint(d.Default)
+}
+
+func (ss settingsService) String(key string) string { return ss.raw(key) }
+
+func (ss settingsService) Bool(key string) bool { return ss.raw(key) == "true" }
+
+func (ss settingsService) Int(key string) int {
+	n, _ := strconv.Atoi(ss.raw(key))
+	return n
+}
+
+func (ss settingsService) Float(key string) float64 {
+	f, _ := strconv.ParseFloat(ss.raw(key), 64)
+	return f
+}
+
+func (ss settingsService) Duration(key string) time.Duration {
+	d, _ := time.ParseDuration(ss.raw(key))
+	return d
+}
+
+// Set validates v, a string, bool, int or float64, against the declaration
+// of key and stores it. An empty string removes the value, so it reads as
+// the default.
+func (ss settingsService) Set(key string, v any) error {
+	d := mustSettingDef(key)
+	var raw string
+	switch v := v.(type) {
+	case string:
+		raw = v
+	case bool:
+		raw = boolString(v)
+	case int:
+		raw = strconv.Itoa(v)
+	case float64:
+		raw = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Errorf("%s: unsupported value %T", d.Field, v)
+	}
+	if strings.TrimSpace(raw) == "" {
+		return ss.kv.DeleteKV(key)
+	}
+	clean, err := d.clean(raw)
+	if err != nil {
+		return fmt.Errorf("%s: %w", d.Field, err)
+	}
+	return ss.kv.SetKV(key, clean)
+}
+
+// handleGetSettingsSchema handles GET /api/settings/schema: the declared
+// settings with their types, defaults and valid values.
+func (s *Server) handleGetSettingsSchema(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, settingsSchema)
+}
@@ -0,0 +1,191 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/morezhou/hearth/internal/store"
+)
+
+// maxShareName bounds the label of a share.
+const maxShareName = 100
+
+type createShareRequest struct {
+	Name    string  `json:"name"`
+	GroupID *string `json:"groupId"`
+}
+
+// sharedApp is an app as shown on a share page: the resolved link only, so
+// internal addresses, Wake-on-LAN targets and actions stay private.
+type sharedApp struct {
+	ID              string  `json:"id"`
+	Name            string  `json:"name"`
+	Description     *string `json:"description"`
+	DescriptionHTML string  `json:"descriptionHtml,omitempty"`
+	Href            string  `json:"href"`
+	OpenMode        string  `json:"openMode"`
+	IconPath        *string `json:"iconPath"`
+}
+
+// sharedGroup is a group of a share page. Apps without a group are listed
+// under a group with an empty id when the whole dashboard is shared.
+type sharedGroup struct {
+	ID   string      `json:"id"`
+	Name string      `json:"name"`
+	Apps []sharedApp `json:"apps"`
+}
+
+// handleListShares handles GET /api/admin/shares.
+func (s *Server) handleListShares(w http.ResponseWriter, r *http.Request) {
+	list, err := s.store.ListShares()
+	if err != nil {
+		slog.Error("failed to list shares", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to list shares")
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// handleCreateShare handles POST /api/admin/shares: publishes one group, or
+// the whole dashboard when groupId is omitted, and returns the link's token
+// once.
+func (s *Server) handleCreateShare(w http.ResponseWriter, r *http.Request) {
+	var req createShareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > maxShareName {
+		writeError(w, http.StatusBadRequest, "name required")
+		return
+	}
+	if req.GroupID != nil && *req.GroupID == "" {
+		req.GroupID = nil
+	}
+	if req.GroupID != nil {
+		groups, err := s.store.ListGroups()
+		if err != nil {
+			slog.Error("failed to list groups", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to list groups")
+			return
+		}
+		found := false
+		for _, g := range groups {
+			if g.ID == *req.GroupID && g.Kind != GroupKindSystem {
+				found = true
+			}
+		}
+		if !found {
+			writeError(w, http.StatusBadRequest, "group not found")
+			return
+		}
+	}
+
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to generate token")
+		return
+	}
+	token := hex.EncodeToString(b)
+	sh, err := s.store.CreateShare(store.Share{Name: req.Name, GroupID: req.GroupID, TokenHash: hashIngestToken(token)})
+	if err != nil {
+		slog.Error("failed to create share", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to create share")
+		return
+	}
+	slog.Info("share created", "id", sh.ID)
+	writeJSON(w, http.StatusOK, map[string]any{"share": sh, "token": token, "url": "/share/" + token})
+}
+
+// handleDeleteShare handles DELETE /api/admin/shares/{id}; the link stops
+// working right away.
+func (s *Server) handleDeleteShare(w http.ResponseWriter, r *http.Request) {
+	ok, err := s.store.DeleteShare(chi.URLParam(r, "id"))
+	if err != nil {
+		slog.Error("failed to delete share", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to delete share")
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+// handleGetShare handles GET /api/share/{token}, the public read-only view
+// of a share. Widgets, archived apps and system groups are never included.
+func (s *Server) handleGetShare(w http.ResponseWriter, r *http.Request) {
+	sh, ok, err := s.store.ShareByTokenHash(hashIngestToken(chi.URLParam(r, "token")))
+	if err != nil {
+		slog.Error("failed to load share", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to load share")
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	groups, err := s.store.ListGroups()
+	if err != nil {
+		slog.Error("failed to list groups", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to list groups")
+		return
+	}
+	apps, err := s.store.ListApps()
+	if err != nil {
+		slog.Error("failed to list apps", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to list apps")
+		return
+	}
+
+	internal := s.internalClient(r)
+	byGroup := map[string][]sharedApp{}
+	for _, a := range apps {
+		if strings.HasPrefix(a.URL, "widget:") || a.Archived {
+			continue
+		}
+		gid := ""
+		if a.GroupID != nil {
+			gid = *a.GroupID
+		}
+		byGroup[gid] = append(byGroup[gid], sharedApp{
+			ID: a.ID, Name: a.Name, Description: a.Description, DescriptionHTML: descriptionHTML(a),
+			Href: appHref(a, internal), OpenMode: a.OpenMode, IconPath: a.IconPath,
+		})
+	}
+
+	out := []sharedGroup{}
+	if sh.GroupID == nil && len(byGroup[""]) > 0 {
+		out = append(out, sharedGroup{Apps: byGroup[""]})
+	}
+	for _, g := range groups {
+		if g.Kind == GroupKindSystem || (sh.GroupID != nil && g.ID != *sh.GroupID) {
+			continue
+		}
+		list := byGroup[g.ID]
+		if list == nil {
+			list = []sharedApp{}
+		}
+		out = append(out, sharedGroup{ID: g.ID, Name: g.Name, Apps: list})
+	}
+	if sh.GroupID != nil && len(out) == 0 {
+		// The group went away after the link was made.
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Robots-Tag", "noindex")
+	writeJSON(w, http.StatusOK, map[string]any{
+		"name":      sh.Name,
+		"siteTitle": s.getStringSetting(kvSiteTitle, "My Home"),
+		"groups":    out,
+	})
+}
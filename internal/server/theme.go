@@ -0,0 +1,89 @@
+package server
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ThemeSettings is the dashboard's appearance. It lives in the settings so it
+// is the same on every device.
+type ThemeSettings struct {
+	Accent string `json:"accent"` // #rrggbb
+	Mode   string `json:"mode"`   // light|dark|auto (follows the OS)
+	// TileOpacity is the opacity of tile backgrounds, 0 (clear) to 1.
+	TileOpacity *float64 `json:"tileOpacity,omitempty"`
+	// FontScale multiplies the base font size, 0.75 to 1.5.
+	FontScale *float64 `json:"fontScale,omitempty"`
+	// CustomCSS is the customization stylesheet (see Customization), shown
+	// here so it can be edited with the rest of the appearance.
+	CustomCSS *string `json:"customCss,omitempty"`
+}
+
+const (
+	defaultThemeAccent      = "#38bdf8"
+	defaultThemeMode        = "dark"
+	defaultThemeTileOpacity = 0.4
+	defaultThemeFontScale   = 1.0
+
+	minThemeFontScale = 0.75
+	maxThemeFontScale = 1.5
+)
+
+var themeAccentRe = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+
+// themeSettings reads the stored theme, falling back to the defaults for
+// missing or invalid values.
+func (s *Server) themeSettings() ThemeSettings {
+	set := s.settings()
+	st := ThemeSettings{
+		Accent: set.String(kvThemeAccent),
+		Mode:   set.String(kvThemeMode),
+	}
+	opacity := set.Float(kvThemeTileOpacity)
+	scale := set.Float(kvThemeFontScale)
+	css := s.getStringSetting(kvCustomCSS, "")
+	st.TileOpacity, st.FontScale, st.CustomCSS = &opacity, &scale, &css
+	return st
+}
+
+// cleanThemeSettings validates st. Empty or omitted fields are taken from cur.
+func cleanThemeSettings(st, cur ThemeSettings) (ThemeSettings, error) {
+	out := cur
+	if v := strings.ToLower(strings.TrimSpace(st.Accent)); v != "" {
+		if !themeAccentRe.MatchString(v) {
+			return ThemeSettings{}, fmt.Errorf("accent must be a #rrggbb color")
+		}
+		out.Accent = v
+	}
+	if v := strings.TrimSpace(st.Mode); v != "" {
+		if v != "light" && v != "dark" && v != "auto" {
+			return ThemeSettings{}, fmt.Errorf("mode must be light, dark or auto")
+		}
+		out.Mode = v
+	}
+	if st.TileOpacity != nil {
+		if *st.TileOpacity < 0 || *st.TileOpacity > 1 {
+			return ThemeSettings{}, fmt.Errorf("tileOpacity must be between 0 and 1")
+		}
+		out.TileOpacity = st.TileOpacity
+	}
+	if st.FontScale != nil {
+		if *st.FontScale < minThemeFontScale || *st.FontScale > maxThemeFontScale {
+			return ThemeSettings{}, fmt.Errorf("fontScale must be between %g and %g", minThemeFontScale, maxThemeFontScale)
+		}
+		out.FontScale = st.FontScale
+	}
+	if st.CustomCSS != nil {
+		css := strings.TrimSpace(*st.CustomCSS)
+		if len(css) > maxCustomCSS {
+			return ThemeSettings{}, fmt.Errorf("customCss must be at most %d bytes", maxCustomCSS)
+		}
+		// The snippet ends up in a <style> element; don't let it close it.
+		if strings.Contains(strings.ToLower(css), "</style") {
+			return ThemeSettings{}, fmt.Errorf("customCss must not contain </style>")
+		}
+		out.CustomCSS = &css
+	}
+	return out, nil
+}
@@ -0,0 +1,197 @@
+package server
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/morezhou/hearth/internal/store"
+)
+
+// Operations recorded in the undo journal.
+const (
+	undoDeleteApp   = "delete_app"
+	undoDeleteGroup = "delete_group"
+	undoImport      = "import"
+	undoReset       = "reset"
+)
+
+// maxUndoEntries bounds the journal; the oldest entries go first. Full
+// snapshots are kept in memory, so this stays small.
+const maxUndoEntries = 20
+
+const defaultUndoWindow = 10 * time.Minute
+
+// undoEntry is a destructive operation that can still be reverted. The
+// snapshot is the data the operation removed or overwrote: it is merged
+// back, or replaces the whole configuration for imports and resets.
+type undoEntry struct {
+	ID        string `json:"id"`
+	Op        string `json:"op"`
+	Summary   string `json:"summary"`
+	CreatedAt int64  `json:"createdAt"`
+	ExpiresAt int64  `json:"expiresAt"`
+
+	snapshot store.Export
+	replace  bool
+}
+
+// undoJournal keeps the recent destructive operations in memory; it does
+// not survive a restart.
+type undoJournal struct {
+	mu      sync.Mutex
+	entries []undoEntry // oldest first
+}
+
+// pruneLocked drops expired entries.
+func (j *undoJournal) pruneLocked(now time.Time) {
+	kept := j.entries[:0]
+	for _, e := range j.entries {
+		if e.ExpiresAt > now.Unix() {
+			kept = append(kept, e)
+		}
+	}
+	j.entries = kept
+}
+
+func (j *undoJournal) add(e undoEntry) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.pruneLocked(time.Now())
+	if len(j.entries) >= maxUndoEntries {
+		j.entries = j.entries[len(j.entries)-maxUndoEntries+1:]
+	}
+	j.entries = append(j.entries, e)
+}
+
+// list returns the live entries, newest first.
+func (j *undoJournal) list() []undoEntry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.pruneLocked(time.Now())
+	out := make([]undoEntry, 0, len(j.entries))
+	for i := len(j.entries) - 1; i >= 0; i-- {
+		out = append(out, j.entries[i])
+	}
+	return out
+}
+
+// take removes and returns the entry with id, or the newest one when id is
+// empty.
+func (j *undoJournal) take(id string) (undoEntry, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.pruneLocked(time.Now())
+	for i := len(j.entries) - 1; i >= 0; i-- {
+		if id == "" || j.entries[i].ID == id {
+			e := j.entries[i]
+			j.entries = append(j.entries[:i], j.entries[i+1:]...)
+			return e, true
+		}
+	}
+	return undoEntry{}, false
+}
+
+// put returns an entry whose undo failed to the journal.
+func (j *undoJournal) put(e undoEntry) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.entries = append(j.entries, e)
+	slices.SortStableFunc(j.entries, func(a, b undoEntry) int { return cmp.Compare(a.CreatedAt, b.CreatedAt) })
+}
+
+// recordUndo adds a destructive operation to the journal. It is a no-op when
+// HEARTH_UNDO_WINDOW is 0.
+func (s *Server) recordUndo(op, summary string, snapshot store.Export, replace bool) {
+	if s.cfg.UndoWindow <= 0 {
+		return
+	}
+	now := time.Now()
+	s.undo.add(undoEntry{
+		ID:        uuid.NewString(),
+		Op:        op,
+		Summary:   summary,
+		CreatedAt: now.Unix(),
+		ExpiresAt: now.Add(s.cfg.UndoWindow).Unix(),
+		snapshot:  snapshot,
+		replace:   replace,
+	})
+}
+
+// groupSnapshot is the part of the configuration deleting group id removes
+// or moves: the group and its apps, widget configurations included.
+func (s *Server) groupSnapshot(id string) (store.Export, error) {
+	groups, err := s.store.ListGroups()
+	if err != nil {
+		return store.Export{}, err
+	}
+	apps, err := s.store.ListApps()
+	if err != nil {
+		return store.Export{}, err
+	}
+	snap := store.Export{}
+	for _, g := range groups {
+		if g.ID == id {
+			snap.Groups = append(snap.Groups, g)
+		}
+	}
+	for _, a := range apps {
+		if a.GroupID != nil && *a.GroupID == id {
+			snap.Apps = append(snap.Apps, a)
+		}
+	}
+	return snap, nil
+}
+
+// handleListUndo handles GET /api/admin/undo: the operations that can still
+// be undone, newest first.
+func (s *Server) handleListUndo(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"entries": s.undo.list()})
+}
+
+// handleUndo handles POST /api/admin/undo: reverts the operation with the
+// given id, or the latest one when the body is empty. Undoing a reset brings
+// the configuration back but not the credentials, sessions, webhooks, shares
+// or caches it cleared.
+func (s *Server) handleUndo(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4<<10)).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	e, ok := s.undo.take(req.ID)
+	if !ok {
+		writeError(w, http.StatusNotFound, "nothing to undo")
+		return
+	}
+	var err error
+	if e.replace {
+		err = s.store.ReplaceAll(e.snapshot)
+	} else {
+		err = s.store.ImportAll(e.snapshot)
+	}
+	if err != nil {
+		s.undo.put(e)
+		slog.Error("failed to undo", "error", err, "op", e.Op, "id", e.ID)
+		writeError(w, http.StatusInternalServerError, "failed to undo")
+		return
+	}
+	if e.replace {
+		if err := s.ensureDefaultSystemTools(); err != nil {
+			slog.Error("failed to restore system tools", "error", err)
+		}
+		s.loadHolidayDataset()
+	}
+	slog.Info("operation undone", "op", e.Op, "id", e.ID)
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "undone": e})
+}
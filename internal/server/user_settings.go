@@ -0,0 +1,242 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/morezhou/hearth/internal/i18n"
+	"github.com/morezhou/hearth/internal/store"
+	"github.com/morezhou/hearth/internal/widgets"
+)
+
+// userSettingKeys are the settings a signed-in user can override for
+// themselves. The others, and every setting for visitors, are shared.
+var userSettingKeys = []string{
+	kvLanguage,
+	kvUnitsSystem, kvUnitsBytes,
+	kvThemeAccent, kvThemeMode, kvThemeTileOpacity, kvThemeFontScale,
+	kvPinnedWidgets,
+}
+
+// maxPinnedWidgets bounds the pinned widget list.
+const maxPinnedWidgets = 50
+
+// UserSettings are a user's own values of the per-user settings. Omitted
+// fields follow the shared settings.
+type UserSettings struct {
+	Language      *string        `json:"language,omitempty"`
+	Units         *UnitsSettings `json:"units,omitempty"`
+	Theme         *ThemeSettings `json:"theme,omitempty"`
+	PinnedWidgets []string       `json:"pinnedWidgets,omitempty"`
+}
+
+// userOverrides returns the settings the signed-in user has overridden, or
+// nil for visitors.
+func (s *Server) userOverrides(r *http.Request) map[string]string {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		return nil
+	}
+	o, err := s.store.ListUserKV(userID)
+	if err != nil {
+		slog.Warn("failed to load user settings", "error", err)
+		return nil
+	}
+	return o
+}
+
+// userStringSetting returns the signed-in user's own value of a per-user
+// setting, or else the shared one. Widgets and metrics read units and language
+// through it, so they follow what GET /api/settings shows the user.
+func (s *Server) userStringSetting(r *http.Request, key, def string) string {
+	if v := s.userOverrides(r)[key]; v != "" {
+		return v
+	}
+	return s.getStringSetting(key, def)
+}
+
+// applyUserSettings replaces the shared values in st with the user's own.
+// Invalid stored values are skipped.
+func applyUserSettings(st *Settings, o map[string]string) {
+	if v, ok := o[kvLanguage]; ok {
+		if lang, ok := i18n.Normalize(v); ok {
+			st.Language = lang
+			format := i18n.FormatFor(lang)
+			st.Formatting = &format
+		}
+	}
+	if v, ok := o[kvUnitsSystem]; ok {
+		st.Units.System = widgets.NormalizeUnits(v)
+	}
+	if v, ok := o[kvUnitsBytes]; ok {
+		st.Units.Bytes = normalizeByteUnits(v)
+	}
+	if theme, err := cleanThemeSettings(userTheme(o), *st.Theme); err == nil {
+		st.Theme = &theme
+	}
+	if v, ok := o[kvPinnedWidgets]; ok {
+		var ids []string
+		if json.Unmarshal([]byte(v), &ids) == nil {
+			st.PinnedWidgets = ids
+		}
+	}
+}
+
+// userTheme is the part of the theme the user has overridden.
+func userTheme(o map[string]string) ThemeSettings {
+	t := ThemeSettings{Accent: o[kvThemeAccent], Mode: o[kvThemeMode]}
+	if f, err := strconv.ParseFloat(o[kvThemeTileOpacity], 64); err == nil {
+		t.TileOpacity = &f
+	}
+	if f, err := strconv.ParseFloat(o[kvThemeFontScale], 64); err == nil {
+		t.FontScale = &f
+	}
+	return t
+}
+
+// userSettingsFrom lists the overrides in o.
+func userSettingsFrom(o map[string]string) UserSettings {
+	var us UserSettings
+	if v, ok := o[kvLanguage]; ok {
+		us.Language = &v
+	}
+	if sys, bytes := o[kvUnitsSystem], o[kvUnitsBytes]; sys != "" || bytes != "" {
+		us.Units = &UnitsSettings{System: sys, Bytes: bytes}
+	}
+	if t := userTheme(o); t.Accent != "" || t.Mode != "" || t.TileOpacity != nil || t.FontScale != nil {
+		us.Theme = &t
+	}
+	if v, ok := o[kvPinnedWidgets]; ok {
+		_ = json.Unmarshal([]byte(v), &us.PinnedWidgets)
+	}
+	return us
+}
+
+// settingWriter returns how handlePutSettings saves a setting: the values of
+// keys the user has overridden go to their own settings, the others to kv.
+// What GET /api/settings returned to them round-trips without changing
+// everyone else's.
+func (s *Server) settingWriter(r *http.Request, kv store.KVRepository) func(key, value string) error {
+	userID, _ := userIDFromContext(r)
+	o := s.userOverrides(r)
+	return func(key, value string) error {
+		if _, ok := o[key]; ok {
+			return s.store.SetUserKV(userID, key, value)
+		}
+		return kv.SetKV(key, value)
+	}
+}
+
+// cleanPinnedWidgets trims and dedupes a pinned widget list.
+func cleanPinnedWidgets(ids []string) ([]string, error) {
+	out := []string{}
+	for _, id := range trimmedList(ids) {
+		if !slices.Contains(out, id) {
+			out = append(out, id)
+		}
+	}
+	if len(out) > maxPinnedWidgets {
+		return nil, fmt.Errorf("at most %d pinned widgets", maxPinnedWidgets)
+	}
+	return out, nil
+}
+
+// handleGetUserSettings handles GET /api/settings/user: the settings the
+// signed-in user has overridden.
+func (s *Server) handleGetUserSettings(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, userSettingsFrom(s.userOverrides(r)))
+}
+
+// handlePutUserSettings handles PUT /api/settings/user. Given fields become
+// the user's own values; an empty language drops that override.
+func (s *Server) handlePutUserSettings(w http.ResponseWriter, r *http.Request) {
+	var req UserSettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	set := map[string]string{}
+	var unset []string
+	if req.Language != nil {
+		if *req.Language == "" {
+			unset = append(unset, kvLanguage)
+		} else if lang, ok := i18n.Normalize(*req.Language); ok {
+			set[kvLanguage] = lang
+		} else {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid language %q", *req.Language))
+			return
+		}
+	}
+	if req.Units != nil {
+		set[kvUnitsSystem] = widgets.NormalizeUnits(req.Units.System)
+		set[kvUnitsBytes] = normalizeByteUnits(req.Units.Bytes)
+	}
+	if req.Theme != nil {
+		if req.Theme.CustomCSS != nil {
+			writeError(w, http.StatusBadRequest, "customCss is shared by all users")
+			return
+		}
+		// Validated on its own, so only the given fields are stored.
+		clean, err := cleanThemeSettings(*req.Theme, ThemeSettings{})
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if clean.Accent != "" {
+			set[kvThemeAccent] = clean.Accent
+		}
+		if clean.Mode != "" {
+			set[kvThemeMode] = clean.Mode
+		}
+		if clean.TileOpacity != nil {
+			set[kvThemeTileOpacity] = strconv.FormatFloat(*clean.TileOpacity, 'f', -1, 64)
+		}
+		if clean.FontScale != nil {
+			set[kvThemeFontScale] = strconv.FormatFloat(*clean.FontScale, 'f', -1, 64)
+		}
+	}
+	if req.PinnedWidgets != nil {
+		ids, err := cleanPinnedWidgets(req.PinnedWidgets)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		b, _ := json.Marshal(ids)
+		set[kvPinnedWidgets] = string(b)
+	}
+
+	userID, _ := userIDFromContext(r)
+	for k, v := range set {
+		if err := s.store.SetUserKV(userID, k, v); err != nil {
+			slog.Error("failed to save user settings", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to save user settings")
+			return
+		}
+	}
+	for _, k := range unset {
+		if err := s.store.DeleteUserKV(userID, k); err != nil {
+			slog.Error("failed to save user settings", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to save user settings")
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, userSettingsFrom(s.userOverrides(r)))
+}
+
+// handleDeleteUserSettings handles DELETE /api/settings/user: the user goes
+// back to the shared settings.
+func (s *Server) handleDeleteUserSettings(w http.ResponseWriter, r *http.Request) {
+	userID, _ := userIDFromContext(r)
+	for _, k := range userSettingKeys {
+		if err := s.store.DeleteUserKV(userID, k); err != nil {
+			slog.Error("failed to reset user settings", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to reset user settings")
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
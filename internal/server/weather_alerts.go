@@ -0,0 +1,134 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/morezhou/hearth/internal/store"
+	"github.com/morezhou/hearth/internal/widgets"
+)
+
+// alertDispatchState remembers which alerts were already sent to the
+// webhooks so each alert is dispatched once per process.
+type alertDispatchState struct {
+	mu   sync.Mutex
+	seen map[string]int64 // alert id -> expires (unix seconds)
+}
+
+// weatherAlertsFor returns active alerts for a resolved location, or an empty
+// list when alerts are disabled or unavailable. Upstream failures never fail
+// the weather response.
+func (s *Server) weatherAlertsFor(ctx context.Context, countryCode, cityLabel string) []widgets.WeatherAlert {
+	if countryCode == "" || s.getStringSetting(kvWeatherAlerts, "true") != "true" {
+		return []widgets.WeatherAlert{}
+	}
+
+	// cityLabel looks like "City, Admin, Country"; match on city and admin area.
+	parts := strings.Split(cityLabel, ",")
+	hints := make([]string, 0, 2)
+	for i, p := range parts {
+		if i >= 2 || (i > 0 && i == len(parts)-1) {
+			break
+		}
+		hints = append(hints, strings.TrimSpace(p))
+	}
+
+	alerts, err := widgets.FetchWeatherAlerts(ctx, countryCode, hints...)
+	if err != nil {
+		slog.Warn("failed to fetch weather alerts", "country", countryCode, "error", err)
+		return []widgets.WeatherAlert{}
+	}
+	s.dispatchWeatherAlerts(cityLabel, alerts)
+	return alerts
+}
+
+// kvWeatherAlertWebhookID is the webhook delivering to the alert webhook
+// setting. The row is local to the instance, like the webhooks themselves.
+const kvWeatherAlertWebhookID = "weather.alertWebhookId"
+
+// dispatchWeatherAlerts sends newly seen alerts as weather.alert events to
+// the subscribed webhooks, through the delivery outbox. The alert webhook
+// setting is one of them.
+func (s *Server) dispatchWeatherAlerts(city string, alerts []widgets.WeatherAlert) {
+	s.syncAlertWebhook()
+	if len(alerts) == 0 || !s.subscribedToAny(eventWeatherAlert) {
+		return
+	}
+
+	now := time.Now().Unix()
+	fresh := make([]widgets.WeatherAlert, 0, len(alerts))
+	s.alertsSeen.mu.Lock()
+	if s.alertsSeen.seen == nil {
+		s.alertsSeen.seen = map[string]int64{}
+	}
+	for id, exp := range s.alertsSeen.seen {
+		if exp > 0 && exp < now {
+			delete(s.alertsSeen.seen, id)
+		}
+	}
+	for _, a := range alerts {
+		if a.ID == "" {
+			continue
+		}
+		if _, ok := s.alertsSeen.seen[a.ID]; ok {
+			continue
+		}
+		s.alertsSeen.seen[a.ID] = a.Expires
+		fresh = append(fresh, a)
+	}
+	s.alertsSeen.mu.Unlock()
+	if len(fresh) == 0 {
+		return
+	}
+	s.emitEvent(eventWeatherAlert, map[string]any{"city": city, "alerts": fresh})
+}
+
+// syncAlertWebhook keeps a webhook subscribed to weather.alert for the alert
+// webhook setting, so its deliveries are queued, retried and logged like any
+// other webhook's. The row follows the setting's URL and goes when the
+// setting is cleared.
+func (s *Server) syncAlertWebhook() {
+	target := s.getStringSetting(kvWeatherAlertWebhook, "")
+	var hook store.Webhook
+	var ok bool
+	id, _, err := s.store.GetKV(kvWeatherAlertWebhookID)
+	if err != nil {
+		return
+	}
+	if n, err := strconv.ParseInt(id, 10, 64); err == nil {
+		if hook, ok, err = s.store.GetWebhook(n); err != nil {
+			return
+		}
+		// A restored setting may name a row that is now someone else's.
+		ok = ok && slices.Equal(hook.Events, []string{eventWeatherAlert})
+	}
+	switch {
+	case target == "":
+		if ok {
+			if _, err := s.store.DeleteWebhook(hook.ID); err != nil {
+				slog.Warn("failed to remove the alert webhook", "error", err)
+				return
+			}
+		}
+		if id != "" {
+			_ = s.store.SetKV(kvWeatherAlertWebhookID, "")
+		}
+	case !ok:
+		hook, err := s.store.CreateWebhook(store.Webhook{URL: target, Events: []string{eventWeatherAlert}, Enabled: true})
+		if err != nil {
+			slog.Warn("failed to add the alert webhook", "error", err)
+			return
+		}
+		_ = s.store.SetKV(kvWeatherAlertWebhookID, strconv.FormatInt(hook.ID, 10))
+	case hook.URL != target:
+		hook.URL = target
+		if _, err := s.store.UpdateWebhook(hook); err != nil {
+			slog.Warn("failed to update the alert webhook", "error", err)
+		}
+	}
+}
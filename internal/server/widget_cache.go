@@ -0,0 +1,64 @@
+package server
+
+import (
+	"github.com/morezhou/hearth/internal/store"
+	"github.com/morezhou/hearth/internal/widgets"
+)
+
+// widgetCacheStore adapts the store's widget_cache, symbol_map and
+// geocode_cache tables to widgets.CacheStore.
+type widgetCacheStore struct {
+	st store.WidgetCacheRepository
+}
+
+var _ widgets.CacheStore = widgetCacheStore{}
+
+func (c widgetCacheStore) GetWidgetCache(key string) ([]byte, int64, bool, error) {
+	e, ok, err := c.st.GetWidgetCache(key)
+	if err != nil || !ok {
+		return nil, 0, false, err
+	}
+	return e.Payload, e.FetchedAt, true, nil
+}
+
+func (c widgetCacheStore) SetWidgetCache(key string, payload []byte, fetchedAt, expiresAt int64) error {
+	return c.st.SetWidgetCache(store.WidgetCacheEntry{CacheKey: key, Payload: payload, FetchedAt: fetchedAt, ExpiresAt: expiresAt})
+}
+
+func (c widgetCacheStore) GetSymbolMapping(provider, symbol string) (string, string, int64, bool, error) {
+	m, ok, err := c.st.GetSymbolMapping(provider, symbol)
+	if err != nil || !ok {
+		return "", "", 0, false, err
+	}
+	return m.ID, m.Name, m.FetchedAt, true, nil
+}
+
+func (c widgetCacheStore) SetSymbolMapping(provider, symbol, id, name string, fetchedAt, expiresAt int64) error {
+	return c.st.SetSymbolMapping(store.SymbolMapping{Provider: provider, Symbol: symbol, ID: id, Name: name, FetchedAt: fetchedAt, ExpiresAt: expiresAt})
+}
+
+func (c widgetCacheStore) GetGeocode(query, language string) (widgets.GeoPoint, bool, error) {
+	e, ok, err := c.st.GetGeocodeCache(query, language)
+	if err != nil || !ok {
+		return widgets.GeoPoint{}, false, err
+	}
+	return widgets.GeoPoint{Lat: e.Lat, Lon: e.Lon, DisplayName: e.DisplayName, Timezone: e.Timezone, CountryCode: e.CountryCode}, true, nil
+}
+
+func (c widgetCacheStore) SetGeocode(query, language string, pt widgets.GeoPoint, fetchedAt, expiresAt int64) error {
+	return c.st.SetGeocodeCache(store.GeocodeCacheEntry{
+		Query:       query,
+		Language:    language,
+		Lat:         pt.Lat,
+		Lon:         pt.Lon,
+		DisplayName: pt.DisplayName,
+		Timezone:    pt.Timezone,
+		CountryCode: pt.CountryCode,
+		FetchedAt:   fetchedAt,
+		ExpiresAt:   expiresAt,
+	})
+}
+
+func (c widgetCacheStore) ClearWidgetCaches() error {
+	return c.st.ClearWidgetCaches()
+}
@@ -0,0 +1,398 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/morezhou/hearth/internal/i18n"
+	"github.com/morezhou/hearth/internal/widgets"
+)
+
+// Field types of a widget config schema.
+const (
+	fieldString  = "string"
+	fieldBool    = "bool"
+	fieldInt     = "int"
+	fieldStrings = "strings" // list of strings
+	fieldObjects = "objects" // list of objects described by Fields
+	fieldMap     = "map"     // object of string values, e.g. plugin options
+)
+
+// WidgetField describes one key of a widget's config object.
+type WidgetField struct {
+	Name      string        `json:"name"`
+	Type      string        `json:"type"`
+	Enum      []any         `json:"enum,omitempty"`
+	MaxLength int           `json:"maxLength,omitempty"` // runes, for strings and list items
+	MaxItems  int           `json:"maxItems,omitempty"`
+	Fields    []WidgetField `json:"fields,omitempty"`
+}
+
+// widgetType describes a kind of widget: its names, the config keys it
+// accepts and the config new widgets start with. All keys are optional;
+// widgets fall back to their defaults for missing ones.
+// The localized name is the i18n message "widget.<ID>".
+type widgetType struct {
+	ID       string
+	Schema   []WidgetField
+	Defaults map[string]any
+}
+
+// widgetTypes is the registry of widget kinds, in the order the "add widget"
+// dialog lists them.
+var widgetTypes = []widgetType{
+	{
+		ID: "weather",
+		Schema: []WidgetField{
+			{Name: "city", Type: fieldString, MaxLength: 200},
+		},
+		Defaults: map[string]any{"city": defaultWeatherCity},
+	},
+	{
+		ID: "timezones",
+		Schema: []WidgetField{
+			{Name: "clocks", Type: fieldObjects, MaxItems: 4, Fields: []WidgetField{
+				{Name: "city", Type: fieldString, MaxLength: 200},
+				{Name: "timezone", Type: fieldString, MaxLength: 64},
+			}},
+		},
+		Defaults: map[string]any{},
+	},
+	{
+		ID: "metrics",
+		Schema: []WidgetField{
+			{Name: "showCpu", Type: fieldBool},
+			{Name: "showMem", Type: fieldBool},
+			{Name: "showDisk", Type: fieldBool},
+			{Name: "showNet", Type: fieldBool},
+			{Name: "showCores", Type: fieldBool},
+			{Name: "showDocker", Type: fieldBool},
+			{Name: "host", Type: fieldString, MaxLength: 64},
+			{Name: "refreshSec", Type: fieldInt, Enum: []any{1, 5, 10}},
+		},
+		Defaults: map[string]any{"showCpu": true, "showMem": true, "showDisk": true, "showNet": true, "showCores": false, "showDocker": false, "refreshSec": 1},
+	},
+	{
+		ID: "markets",
+		Schema: []WidgetField{
+			{Name: "symbols", Type: fieldStrings, MaxItems: 4, MaxLength: 32},
+		},
+		Defaults: map[string]any{"symbols": []string{"BTC", "ETH", "AAPL", "MSFT"}},
+	},
+	{
+		ID: "holidays",
+		Schema: []WidgetField{
+			{Name: "countries", Type: fieldStrings, MaxItems: 10, MaxLength: 8},
+		},
+		Defaults: map[string]any{"countries": []string{"CN", "US"}},
+	},
+	{
+		ID: "custom",
+		Schema: []WidgetField{
+			{Name: "key", Type: fieldString, MaxLength: 64},
+			{Name: "title", Type: fieldString, MaxLength: 100},
+			{Name: "field", Type: fieldString, MaxLength: 100},
+		},
+		Defaults: map[string]any{"key": ""},
+	},
+	{
+		ID: "iframe",
+		Schema: []WidgetField{
+			{Name: "embed", Type: fieldString, MaxLength: 64},
+		},
+		Defaults: map[string]any{"embed": ""},
+	},
+	{
+		ID: "search",
+		Schema: []WidgetField{
+			{Name: "engine", Type: fieldString, MaxLength: 32},
+		},
+		Defaults: map[string]any{},
+	},
+	{
+		ID: "quote",
+		Schema: []WidgetField{
+			{Name: "source", Type: fieldString, Enum: []any{"bundled", "quotable", "custom"}},
+		},
+		Defaults: map[string]any{"source": "bundled"},
+	},
+	{
+		ID: "plugin",
+		Schema: []WidgetField{
+			{Name: "plugin", Type: fieldString, MaxLength: 32},
+			{Name: "options", Type: fieldMap, MaxItems: 20, MaxLength: 500},
+		},
+		Defaults: map[string]any{"plugin": ""},
+	},
+}
+
+// widgetSchema returns the config schema of kind.
+func widgetSchema(kind string) ([]WidgetField, bool) {
+	for _, t := range widgetTypes {
+		if t.ID == kind {
+			return t.Schema, true
+		}
+	}
+	return nil, false
+}
+
+// widgetKindFromURL returns the widget kind of an item URL such as
+// "widget:holidays?countries=CN", or "" for regular apps.
+func widgetKindFromURL(u string) string {
+	kind, ok := strings.CutPrefix(u, "widget:")
+	if !ok {
+		return ""
+	}
+	kind, _, _ = strings.Cut(kind, "?")
+	return kind
+}
+
+// validateWidgetConfig checks raw against the schema of kind and returns it
+// re-encoded. An empty or null config is stored as {}.
+func validateWidgetConfig(kind string, raw []byte) ([]byte, error) {
+	fields, ok := widgetSchema(kind)
+	if !ok {
+		return nil, fmt.Errorf("unknown widget kind %q", kind)
+	}
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return []byte("{}"), nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, errors.New("config must be valid json")
+	}
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return nil, errors.New("config must be a json object")
+	}
+	if err := checkWidgetObject(fields, obj, ""); err != nil {
+		return nil, err
+	}
+	return json.Marshal(obj)
+}
+
+func checkWidgetObject(fields []WidgetField, obj map[string]any, prefix string) error {
+	for key, val := range obj {
+		i := slices.IndexFunc(fields, func(f WidgetField) bool { return f.Name == key })
+		if i < 0 {
+			return fmt.Errorf("unknown field %q", prefix+key)
+		}
+		if err := checkWidgetField(fields[i], val, prefix+key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkWidgetField(f WidgetField, val any, name string) error {
+	switch f.Type {
+	case fieldString:
+		s, ok := val.(string)
+		if !ok {
+			return fmt.Errorf("%s must be a string", name)
+		}
+		if f.MaxLength > 0 && utf8.RuneCountInString(s) > f.MaxLength {
+			return fmt.Errorf("%s is longer than %d characters", name, f.MaxLength)
+		}
+		if len(f.Enum) > 0 && !slices.Contains(f.Enum, any(s)) {
+			return fmt.Errorf("%s must be one of %v", name, f.Enum)
+		}
+	case fieldBool:
+		if _, ok := val.(bool); !ok {
+			return fmt.Errorf("%s must be a boolean", name)
+		}
+	case fieldInt:
+		n, ok := val.(json.Number)
+		if !ok {
+			return fmt.Errorf("%s must be an integer", name)
+		}
+		i, err := n.Int64()
+		if err != nil {
+			return fmt.Errorf("%s must be an integer", name)
+		}
+		if len(f.Enum) > 0 && !slices.Contains(f.Enum, any(int(i))) {
+			return fmt.Errorf("%s must be one of %v", name, f.Enum)
+		}
+	case fieldMap:
+		obj, ok := val.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s must be an object", name)
+		}
+		if f.MaxItems > 0 && len(obj) > f.MaxItems {
+			return fmt.Errorf("%s has more than %d keys", name, f.MaxItems)
+		}
+		for k, v := range obj {
+			if err := checkWidgetField(WidgetField{Type: fieldString, MaxLength: f.MaxLength}, v, name+"."+k); err != nil {
+				return err
+			}
+		}
+	case fieldStrings, fieldObjects:
+		list, ok := val.([]any)
+		if !ok {
+			return fmt.Errorf("%s must be a list", name)
+		}
+		if f.MaxItems > 0 && len(list) > f.MaxItems {
+			return fmt.Errorf("%s has more than %d items", name, f.MaxItems)
+		}
+		for i, item := range list {
+			itemName := fmt.Sprintf("%s[%d]", name, i)
+			if f.Type == fieldStrings {
+				if err := checkWidgetField(WidgetField{Type: fieldString, MaxLength: f.MaxLength}, item, itemName); err != nil {
+					return err
+				}
+				continue
+			}
+			obj, ok := item.(map[string]any)
+			if !ok {
+				return fmt.Errorf("%s must be an object", itemName)
+			}
+			if err := checkWidgetObject(f.Fields, obj, itemName+"."); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// widgetConfigResponse is the body of GET/PUT /api/widgets/{appId}/config.
+type widgetConfigResponse struct {
+	AppID     string          `json:"appId"`
+	Kind      string          `json:"kind"`
+	Config    json.RawMessage `json:"config"`
+	Schema    []WidgetField   `json:"schema"`
+	UpdatedAt int64           `json:"updatedAt"`
+}
+
+// handleGetWidgetConfig handles GET /api/widgets/{appId}/config.
+func (s *Server) handleGetWidgetConfig(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "appId")
+	c, ok, err := s.store.GetWidgetConfig(id)
+	if err != nil {
+		slog.Error("failed to get widget config", "error", err, "id", id)
+		writeError(w, http.StatusInternalServerError, "failed to get widget config")
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "widget not found")
+		return
+	}
+	schema, _ := widgetSchema(c.Kind)
+	writeJSON(w, http.StatusOK, widgetConfigResponse{
+		AppID:     c.AppID,
+		Kind:      c.Kind,
+		Config:    c.Config,
+		Schema:    schema,
+		UpdatedAt: c.UpdatedAt,
+	})
+}
+
+// handlePutWidgetConfig handles PUT /api/widgets/{appId}/config. The body is
+// the config object itself.
+func (s *Server) handlePutWidgetConfig(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "appId")
+	app, ok, err := s.store.AppByID(id)
+	if err != nil {
+		slog.Error("failed to get app", "error", err, "id", id)
+		writeError(w, http.StatusInternalServerError, "failed to get widget")
+		return
+	}
+	kind := widgetKindFromURL(app.URL)
+	if !ok || kind == "" {
+		writeError(w, http.StatusNotFound, "widget not found")
+		return
+	}
+	var raw json.RawMessage
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&raw); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	config, err := validateWidgetConfig(kind, raw)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := s.store.PutWidgetConfig(id, kind, config); err != nil {
+		slog.Error("failed to save widget config", "error", err, "id", id)
+		writeError(w, http.StatusInternalServerError, "failed to save widget config")
+		return
+	}
+	c, _, err := s.store.GetWidgetConfig(id)
+	if err != nil {
+		slog.Error("failed to get widget config", "error", err, "id", id)
+		writeError(w, http.StatusInternalServerError, "failed to get widget config")
+		return
+	}
+	schema, _ := widgetSchema(kind)
+	writeJSON(w, http.StatusOK, widgetConfigResponse{AppID: id, Kind: kind, Config: c.Config, Schema: schema, UpdatedAt: c.UpdatedAt})
+}
+
+// WidgetCredential is an upstream credential a widget type depends on.
+// Setting names the settings field (or admin API) where it is configured.
+type WidgetCredential struct {
+	ID         string `json:"id"`
+	Setting    string `json:"setting"`
+	Required   bool   `json:"required"` // needed with the current provider selection
+	Configured bool   `json:"configured"`
+}
+
+// WidgetTypeInfo is an entry of GET /api/widgets/registry.
+type WidgetTypeInfo struct {
+	ID            string             `json:"id"`
+	Name          string             `json:"name"`
+	Schema        []WidgetField      `json:"schema"`
+	DefaultConfig map[string]any     `json:"defaultConfig"`
+	Credentials   []WidgetCredential `json:"credentials"`
+}
+
+// widgetCredentials reports the credentials kind depends on, given the
+// providers currently selected in the settings.
+func (s *Server) widgetCredentials(kind string) []WidgetCredential {
+	configured := func(key string) bool { return s.getStringSetting(key, "") != "" }
+	switch kind {
+	case "weather":
+		provider := normalizeWeatherProvider(s.getStringSetting(kvWeatherProvider, widgets.WeatherProviderOpenMeteo))
+		return []WidgetCredential{
+			{ID: "openweathermap", Setting: "weather.apiKey", Required: provider == widgets.WeatherProviderOpenWeatherMap, Configured: configured(kvWeatherAPIKey)},
+		}
+	case "markets":
+		provider := normalizeStockProvider(s.getStringSetting(kvMarketsStockProvider, widgets.StockProviderStooq))
+		return []WidgetCredential{
+			{ID: "finnhub", Setting: "markets.finnhubKey", Required: provider == widgets.StockProviderFinnhub, Configured: configured(kvMarketsFinnhubKey)},
+			{ID: "twelvedata", Setting: "markets.twelveDataKey", Required: provider == widgets.StockProviderTwelveData, Configured: configured(kvMarketsTwelveDataKey)},
+		}
+	case "custom":
+		return []WidgetCredential{
+			{ID: "ingest", Setting: "POST /api/admin/ingest/token", Required: true, Configured: configured(kvIngestTokenHash)},
+		}
+	}
+	return []WidgetCredential{}
+}
+
+// handleGetWidgetRegistry handles GET /api/widgets/registry, which describes
+// every widget type so clients don't have to hardcode them.
+func (s *Server) handleGetWidgetRegistry(w http.ResponseWriter, r *http.Request) {
+	loc := localeFromRequest(r)
+	out := make([]WidgetTypeInfo, 0, len(widgetTypes))
+	for _, t := range widgetTypes {
+		out = append(out, WidgetTypeInfo{
+			ID:            t.ID,
+			Name:          i18n.T(loc, "widget."+t.ID),
+			Schema:        t.Schema,
+			DefaultConfig: t.Defaults,
+			Credentials:   s.widgetCredentials(t.ID),
+		})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
@@ -0,0 +1,1323 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/morezhou/hearth/internal/background"
+	"github.com/morezhou/hearth/internal/testsupport"
+	"github.com/morezhou/hearth/internal/widgets"
+)
+
+func getJSON(t *testing.T, s *Server, target string, out any) int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, req)
+	if out != nil && w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			t.Fatalf("decode %s: %v (body=%s)", target, err, w.Body.String())
+		}
+	}
+	return w.Code
+}
+
+func TestWeatherWidgetOffline(t *testing.T) {
+	up := testsupport.NewUpstream(t)
+	up.UseForWidgets(t)
+	s := newTestServer(t)
+
+	var wx widgets.Weather
+	if code := getJSON(t, s, "/api/widgets/weather?city=Berlin&lang=en", &wx); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if wx.Temperature != 14.6 || len(wx.Daily) != 7 {
+		t.Fatalf("unexpected weather payload: %+v", wx)
+	}
+	if len(wx.Alerts) != 1 || wx.Alerts[0].Severity != "moderate" {
+		t.Fatalf("expected one moderate alert, got %+v", wx.Alerts)
+	}
+	if wx.Hourly != nil {
+		t.Fatalf("hourly series should only be sent with ?detail=hourly")
+	}
+
+	// The city resolution is persisted: another spelling of the same city does
+	// not geocode again.
+	if _, ok, err := s.store.GetGeocodeCache("berlin", "en"); err != nil || !ok {
+		t.Fatalf("expected a persisted geocode, ok=%v err=%v", ok, err)
+	}
+	geocodes := up.Hits("/nominatim/search") + up.Hits("/geocoding/v1/search")
+	if code := getJSON(t, s, "/api/widgets/weather?city=+BERLIN&lang=en", &wx); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if after := up.Hits("/nominatim/search") + up.Hits("/geocoding/v1/search"); after != geocodes {
+		t.Fatalf("expected a cached geocode, upstream hits went %d -> %d", geocodes, after)
+	}
+
+	// Repeated lat/lon requests (no geocoding) are served from the widget cache.
+	_ = getJSON(t, s, "/api/widgets/weather?lat=52.52&lon=13.41", &wx)
+	before := up.Hits("/open-meteo/v1/forecast")
+	_ = getJSON(t, s, "/api/widgets/weather?lat=52.52&lon=13.41", &wx)
+	if after := up.Hits("/open-meteo/v1/forecast"); after != before {
+		t.Fatalf("expected cached forecast, upstream hits went %d -> %d", before, after)
+	}
+}
+
+func TestWeatherAlertDispatch(t *testing.T) {
+	up := testsupport.NewUpstream(t)
+	up.UseForWidgets(t)
+
+	// The receiver fails its first request, so that delivery has to be
+	// retried by the outbox.
+	var mu sync.Mutex
+	var posts int
+	var received []string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Event string `json:"event"`
+			Data  struct {
+				City   string                 `json:"city"`
+				Alerts []widgets.WeatherAlert `json:"alerts"`
+			} `json:"data"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		defer mu.Unlock()
+		if posts++; posts == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		for _, a := range body.Data.Alerts {
+			if strings.HasPrefix(body.Data.City, "Berlin") {
+				received = append(received, body.Event+" "+a.ID)
+			}
+		}
+	}))
+	defer receiver.Close()
+
+	s, st := newMemTestServer(t)
+	_ = st.SetKV(kvWeatherAlertWebhook, receiver.URL)
+
+	var wx widgets.Weather
+	for i := 0; i < 2; i++ {
+		if code := getJSON(t, s, fmt.Sprintf("/api/widgets/weather?city=Berlin&lang=en&n=%d", i), &wx); code != http.StatusOK || len(wx.Alerts) != 1 {
+			t.Fatalf("expected 200 with an alert, got %d %+v", code, wx.Alerts)
+		}
+	}
+	seen := wx.Alerts[0]
+	s.dispatchWeatherAlerts("Berlin", []widgets.WeatherAlert{seen, {ID: "new-alert", Event: "Wind"}})
+
+	// The setting is delivered as a webhook of its own.
+	hooks, _ := st.ListWebhooks()
+	if len(hooks) != 1 || hooks[0].URL != receiver.URL || !slices.Equal(hooks[0].Events, []string{eventWeatherAlert}) {
+		t.Fatalf("expected a webhook for the alert setting, got %+v", hooks)
+	}
+	due, _ := st.DueWebhookDeliveries(time.Now().Unix(), 10)
+	if len(due) != 2 || due[0].Event != eventWeatherAlert || !strings.Contains(string(due[1].Payload), "new-alert") || strings.Contains(string(due[1].Payload), seen.ID) {
+		t.Fatalf("expected one queued delivery per batch of new alerts, got %+v", due)
+	}
+	now := time.Now()
+	s.deliverDueWebhooks(context.Background(), now)
+	s.deliverDueWebhooks(context.Background(), now.Add(time.Hour))
+	s.deliverDueWebhooks(context.Background(), now.Add(2*time.Hour))
+
+	mu.Lock()
+	got := append([]string(nil), received...)
+	mu.Unlock()
+	slices.Sort(got)
+	want := []string{"weather.alert " + seen.ID, "weather.alert new-alert"}
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Fatalf("expected each alert delivered once, got %v", got)
+	}
+	log, _ := st.ListWebhookDeliveries(hooks[0].ID, 10)
+	if len(log) != 2 {
+		t.Fatalf("expected both deliveries in the log, got %+v", log)
+	}
+
+	// Changing the setting moves the webhook; clearing it removes it.
+	_ = st.SetKV(kvWeatherAlertWebhook, receiver.URL+"/moved")
+	s.syncAlertWebhook()
+	if hooks, _ := st.ListWebhooks(); len(hooks) != 1 || hooks[0].URL != receiver.URL+"/moved" {
+		t.Fatalf("expected the webhook to follow the setting, got %+v", hooks)
+	}
+	_ = st.SetKV(kvWeatherAlertWebhook, "")
+	s.syncAlertWebhook()
+	if hooks, _ := st.ListWebhooks(); len(hooks) != 0 {
+		t.Fatalf("expected the webhook to go with the setting, got %+v", hooks)
+	}
+}
+
+func TestWidgetsFollowUserSettings(t *testing.T) {
+	up := testsupport.NewUpstream(t)
+	up.UseForWidgets(t)
+	s, st := newMemTestServer(t)
+	fa := s.auth.(*fakeAuth)
+	fa.sessions["token-alice"], fa.sessions["token-bob"] = "alice-id", "bob-id"
+	for k, v := range map[string]string{kvUnitsSystem: widgets.UnitsImperial, kvUnitsBytes: "decimal", kvLanguage: "en"} {
+		if err := st.SetUserKV("alice-id", k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := st.SetUserKV("bob-id", kvLanguage, "de"); err != nil {
+		t.Fatal(err)
+	}
+	get := func(token, target string, out any) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if token != "" {
+			req.AddCookie(&http.Cookie{Name: "hearth_session", Value: token})
+		}
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", target, w.Code)
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			t.Fatalf("decode %s: %v", target, err)
+		}
+		return w
+	}
+
+	// Both ask for the same cached widget; each gets their own units and
+	// language.
+	var alice, bob, visitor widgets.Weather
+	wa := get("token-alice", "/api/widgets/weather?city=Berlin", &alice)
+	wb := get("token-bob", "/api/widgets/weather?city=Berlin", &bob)
+	get("", "/api/widgets/weather?city=Berlin", &visitor)
+	if alice.TempUnit != "°F" || bob.TempUnit != "°C" || alice.Temp == bob.Temp {
+		t.Fatalf("expected °F for alice and °C for bob, got %v%s / %v%s", alice.Temp, alice.TempUnit, bob.Temp, bob.TempUnit)
+	}
+	if lang := wa.Header().Get("Content-Language"); lang != "en" {
+		t.Errorf("alice's language = %q, want en", lang)
+	}
+	if lang := wb.Header().Get("Content-Language"); lang != "de" {
+		t.Errorf("bob's language = %q, want de", lang)
+	}
+	if visitor.Units != widgets.UnitsMetric {
+		t.Errorf("visitors should get the shared units, got %q", visitor.Units)
+	}
+
+	var am, bm hostMetricsResponse
+	get("token-alice", "/api/metrics/host", &am)
+	get("token-bob", "/api/metrics/host", &bm)
+	if am.Format.ByteBase != 1000 || am.Format.Units != widgets.UnitsImperial || bm.Format.ByteBase != 1024 || bm.Format.Units != widgets.UnitsMetric {
+		t.Fatalf("unexpected metrics formats: %+v / %+v", am.Format, bm.Format)
+	}
+}
+
+func TestWeatherWidgetRateLimited(t *testing.T) {
+	up := testsupport.NewUpstream(t)
+	up.UseForWidgets(t)
+	up.Override("/open-meteo/v1/forecast", testsupport.Status(http.StatusTooManyRequests))
+	s := newTestServer(t)
+
+	if code := getJSON(t, s, "/api/widgets/weather?lat=52.52&lon=13.41", nil); code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", code)
+	}
+}
+
+func TestWeatherOptionsFromSettings(t *testing.T) {
+	up := testsupport.NewUpstream(t)
+	up.UseForWidgets(t)
+	s := newTestServer(t)
+	cookie := loginAsAdmin(t, s)
+
+	var payload Settings
+	payload.Weather.ForecastDays = 3
+	payload.Weather.CacheFreshMinutes = 60
+	payload.Weather.CacheMaxStaleMinutes = 30 // shorter than fresh: raised on read
+	b, _ := json.Marshal(payload)
+	req := httptest.NewRequest(http.MethodPut, "/api/settings", bytes.NewReader(b))
+	req.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	var st Settings
+	if code := getJSON(t, s, "/api/settings", &st); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if st.Weather.ForecastDays != 3 || st.Weather.CacheFreshMinutes != 60 || st.Weather.CacheMaxStaleMinutes != 60 {
+		t.Fatalf("unexpected weather settings: %+v", st.Weather)
+	}
+
+	var wx widgets.Weather
+	if code := getJSON(t, s, "/api/widgets/weather?lat=52.52&lon=13.41", &wx); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if got := up.LastQuery("/open-meteo/v1/forecast").Get("forecast_days"); got != "3" {
+		t.Fatalf("expected forecast_days=3 upstream, got %q", got)
+	}
+}
+
+func TestWeatherProviderFailover(t *testing.T) {
+	up := testsupport.NewUpstream(t)
+	up.UseForWidgets(t)
+	s := newTestServer(t)
+	if err := s.store.SetKV(kvWeatherProvider, widgets.WeatherProviderOpenWeatherMap); err != nil {
+		t.Fatalf("set provider: %v", err)
+	}
+	if err := s.store.SetKV(kvWeatherAPIKey, "test-key"); err != nil {
+		t.Fatalf("set api key: %v", err)
+	}
+
+	var wx widgets.Weather
+	if code := getJSON(t, s, "/api/widgets/weather?lat=52.52&lon=13.41", &wx); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if wx.Provider != widgets.WeatherProviderOpenWeatherMap || wx.Temperature != 13.9 || wx.WeatherCode != 3 {
+		t.Fatalf("unexpected openweathermap payload: %+v", wx)
+	}
+	if len(wx.Daily) != 2 || wx.Daily[1].PrecipProbPct != 90 {
+		t.Fatalf("unexpected openweathermap daily: %+v", wx.Daily)
+	}
+
+	// Rate limited by the preferred provider: Open-Meteo answers instead.
+	widgets.ResetCaches()
+	s.respCache.purge()
+	up.Override("/openweathermap/data/2.5/weather", testsupport.Status(http.StatusTooManyRequests))
+	if code := getJSON(t, s, "/api/widgets/weather?lat=52.52&lon=13.41", &wx); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if wx.Provider != widgets.WeatherProviderOpenMeteo || wx.FailoverFrom != widgets.WeatherProviderOpenWeatherMap || wx.Temperature != 14.6 {
+		t.Fatalf("expected open-meteo failover, got provider=%q failoverFrom=%q", wx.Provider, wx.FailoverFrom)
+	}
+
+	if err := s.store.SetKV(kvWeatherProvider, widgets.WeatherProviderMetNo); err != nil {
+		t.Fatalf("set provider: %v", err)
+	}
+	s.respCache.purge()
+	if code := getJSON(t, s, "/api/widgets/weather?lat=52.52&lon=13.41", &wx); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if wx.Provider != widgets.WeatherProviderMetNo || wx.Temperature != 13.4 || wx.WindSpeed != 18 {
+		t.Fatalf("unexpected metno payload: %+v", wx)
+	}
+}
+
+func TestMarketsWidgetOffline(t *testing.T) {
+	up := testsupport.NewUpstream(t)
+	up.UseForWidgets(t)
+	s := newTestServer(t)
+
+	var res widgets.MarketsResponse
+	if code := getJSON(t, s, "/api/widgets/markets?symbols=BTC,ETH,AAPL,MSFT", &res); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	bySymbol := map[string]widgets.MarketQuote{}
+	for _, it := range res.Items {
+		bySymbol[it.Symbol] = it
+	}
+	if q := bySymbol["BTC"]; q.PriceUSD != 67234 || len(q.Series) != 3 {
+		t.Fatalf("unexpected BTC quote: %+v", q)
+	}
+	if q := bySymbol["AAPL"]; q.PriceUSD != 231.78 || q.Name != "APPLE" {
+		t.Fatalf("unexpected AAPL quote: %+v", q)
+	}
+	if q := bySymbol["AAPL"]; q.Session == nil || q.Session.Exchange != "NYSE" || q.Session.LastTradeTime == 0 {
+		t.Fatalf("expected NYSE session metadata, got %+v", q.Session)
+	}
+	if bySymbol["BTC"].Session != nil {
+		t.Fatalf("crypto trades around the clock and has no session")
+	}
+
+	// Binance blocked: crypto falls back to CoinGecko.
+	widgets.ResetCaches()
+	s.respCache.purge()
+	up.Override("/binance/api/v3/ticker/24hr", testsupport.Status(http.StatusForbidden))
+	if code := getJSON(t, s, "/api/widgets/markets?symbols=BTC,ETH,AAPL,MSFT", &res); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if res.Items[0].Symbol != "BTC" || res.Items[0].Name != "Bitcoin" || len(res.Items[0].Series) != 6 {
+		t.Fatalf("expected CoinGecko fallback, got %+v", res.Items[0])
+	}
+}
+
+func TestMarketsCachePersisted(t *testing.T) {
+	up := testsupport.NewUpstream(t)
+	up.UseForWidgets(t)
+	s := newTestServer(t)
+
+	// Binance blocked so crypto resolves through CoinGecko's symbol search.
+	up.Override("/binance/api/v3/ticker/24hr", testsupport.Status(http.StatusForbidden))
+	var res widgets.MarketsResponse
+	if code := getJSON(t, s, "/api/widgets/markets?symbols=BTC,ETH,AAPL,MSFT", &res); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if m, ok, err := s.store.GetSymbolMapping("coingecko", "BTC"); err != nil || !ok || m.ID != "bitcoin" {
+		t.Fatalf("expected persisted BTC mapping, got %+v ok=%v err=%v", m, ok, err)
+	}
+
+	// Simulate a restart: drop only the in-memory caches, then cut every upstream.
+	widgets.SetCacheStore(nil)
+	widgets.ResetCaches()
+	s.respCache.purge()
+	widgets.SetCacheStore(widgetCacheStore{st: s.store})
+	paths := []string{"/coingecko/api/v3/search", "/coingecko/api/v3/coins/markets", "/stooq/q/l/", "/stooq/q/d/l/"}
+	before := map[string]int{}
+	for _, p := range paths {
+		up.Override(p, testsupport.Status(http.StatusBadGateway))
+		before[p] = up.Hits(p)
+	}
+
+	var again widgets.MarketsResponse
+	if code := getJSON(t, s, "/api/widgets/markets?symbols=BTC,ETH,AAPL,MSFT", &again); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if again.FetchedAt != res.FetchedAt || len(again.Items) != 4 || again.Items[0].Name != "Bitcoin" {
+		t.Fatalf("expected quotes from the persisted cache, got %+v", again)
+	}
+	for _, p := range paths {
+		if up.Hits(p) != before[p] {
+			t.Fatalf("expected no upstream calls to %s after restart", p)
+		}
+	}
+}
+
+func TestMarketsWidgetRange(t *testing.T) {
+	up := testsupport.NewUpstream(t)
+	up.UseForWidgets(t)
+	s := newTestServer(t)
+
+	var res widgets.MarketsResponse
+	if code := getJSON(t, s, "/api/widgets/markets?symbols=BTC,ETH,AAPL,MSFT&range=1Y", &res); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if res.Range != widgets.MarketRange1Y {
+		t.Fatalf("expected range echoed, got %q", res.Range)
+	}
+	q := up.LastQuery("/binance/api/v3/klines")
+	if q.Get("interval") != "1w" || q.Get("limit") != "52" {
+		t.Fatalf("unexpected klines query: %v", q)
+	}
+	for _, it := range res.Items {
+		if it.Symbol == "AAPL" && (len(it.Series) != 4 || it.RangeChangePct == 0) {
+			t.Fatalf("expected full daily history for AAPL, got %+v", it)
+		}
+	}
+
+	// Ranges are cached independently.
+	before := up.Hits("/binance/api/v3/klines")
+	if code := getJSON(t, s, "/api/widgets/markets?symbols=BTC,ETH,AAPL,MSFT&range=7d", &res); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if up.Hits("/binance/api/v3/klines") == before {
+		t.Fatalf("expected a fresh fetch for a different range")
+	}
+
+	if code := getJSON(t, s, "/api/widgets/markets?symbols=BTC&range=5y", nil); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown range, got %d", code)
+	}
+	if code := getJSON(t, s, "/api/widgets/markets?symbols=BTC&refresh=soon", nil); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid refresh, got %d", code)
+	}
+}
+
+func TestMarketsStockProviderRouting(t *testing.T) {
+	up := testsupport.NewUpstream(t)
+	up.UseForWidgets(t)
+	s := newTestServer(t)
+	cookie := loginAsAdmin(t, s)
+
+	var payload Settings
+	payload.Markets = &MarketsSettings{
+		StockProvider: widgets.StockProviderTwelveData,
+		TwelveDataKey: "td-key",
+		Routes:        map[string]string{"*.hk": widgets.StockProviderYahoo, "MSFT": widgets.StockProviderFinnhub},
+	}
+	b, _ := json.Marshal(payload)
+	req := httptest.NewRequest(http.MethodPut, "/api/settings", bytes.NewReader(b))
+	req.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	var st Settings
+	if code := getJSON(t, s, "/api/settings", &st); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if st.Markets == nil || st.Markets.TwelveDataKey != "" || st.Markets.Routes["*.HK"] != widgets.StockProviderYahoo {
+		t.Fatalf("unexpected public markets settings: %+v", st.Markets)
+	}
+
+	var res widgets.MarketsResponse
+	if code := getJSON(t, s, "/api/widgets/markets?symbols=AAPL,0700.HK,MSFT,BTC", &res); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	bySymbol := map[string]widgets.MarketQuote{}
+	for _, it := range res.Items {
+		bySymbol[it.Symbol] = it
+	}
+	if q := bySymbol["AAPL"]; q.Source != widgets.StockProviderTwelveData || q.PriceUSD != 231.9 || len(q.Series) != 3 || q.Series[2] != 231.9 {
+		t.Fatalf("expected AAPL from Twelve Data, got %+v", q)
+	}
+	if q := bySymbol["0700.HK"]; q.Source != widgets.StockProviderYahoo || q.PriceUSD != 512.5 || q.Name != "Tencent Holdings Limited" {
+		t.Fatalf("expected 0700.HK from Yahoo, got %+v", q)
+	}
+	// Finnhub is routed but has no key configured: Stooq serves it.
+	if q := bySymbol["MSFT"]; q.Source != widgets.StockProviderStooq {
+		t.Fatalf("expected MSFT from Stooq, got %+v", q)
+	}
+	if got := up.LastQuery("/twelvedata/quote").Get("apikey"); got != "td-key" {
+		t.Fatalf("expected API key upstream, got %q", got)
+	}
+
+	// Indices and FX pairs default to Stooq regardless of the stock provider.
+	if code := getJSON(t, s, "/api/widgets/markets?symbols=%5EGSPC,EURUSD,AAPL,BTC", &res); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if q := res.Items[0]; q.Kind != widgets.MarketKindIndex || q.Source != widgets.StockProviderStooq || q.Name != "S&P 500" {
+		t.Fatalf("unexpected index quote: %+v", q)
+	}
+	if q := res.Items[1]; q.Kind != widgets.MarketKindFX || q.Source != widgets.StockProviderStooq {
+		t.Fatalf("unexpected fx quote: %+v", q)
+	}
+	if !up.Requested("/stooq/q/l/", "s", "eurusd") {
+		t.Fatalf("expected Stooq FX code upstream")
+	}
+
+	// A failing provider fails over to Stooq.
+	widgets.ResetCaches()
+	s.respCache.purge()
+	up.Override("/twelvedata/quote", testsupport.Status(http.StatusTooManyRequests))
+	if code := getJSON(t, s, "/api/widgets/markets?symbols=AAPL,0700.HK,MSFT,BTC", &res); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if q := res.Items[0]; q.Symbol != "AAPL" || q.Source != widgets.StockProviderStooq || q.PriceUSD != 231.78 {
+		t.Fatalf("expected Stooq failover for AAPL, got %+v", q)
+	}
+}
+
+func TestMarketsWidgetCurrency(t *testing.T) {
+	up := testsupport.NewUpstream(t)
+	up.UseForWidgets(t)
+	s := newTestServer(t)
+
+	var res widgets.MarketsResponse
+	if code := getJSON(t, s, "/api/widgets/markets?symbols=BTC,ETH,AAPL,MSFT&currency=eur", &res); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if res.Currency != "EUR" {
+		t.Fatalf("expected EUR response, got %q", res.Currency)
+	}
+	for _, it := range res.Items {
+		if it.Symbol == "AAPL" && (it.Currency != "EUR" || it.PriceUSD != 231.78 || math.Abs(it.Price-208.602) > 1e-9) {
+			t.Fatalf("unexpected converted AAPL quote: %+v", it)
+		}
+	}
+
+	// Default stays USD and never calls the FX upstream.
+	before := up.Hits("/frankfurter/latest")
+	if code := getJSON(t, s, "/api/widgets/markets?symbols=BTC,ETH,AAPL,MSFT", &res); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if res.Currency != "USD" || res.Items[0].Price != res.Items[0].PriceUSD {
+		t.Fatalf("expected USD prices, got %+v", res.Items[0])
+	}
+	if up.Hits("/frankfurter/latest") != before {
+		t.Fatalf("USD request should not fetch FX rates")
+	}
+
+	// FX outage: prices fall back to USD instead of failing.
+	widgets.ResetCaches()
+	s.respCache.purge()
+	up.Override("/frankfurter/latest", testsupport.Status(http.StatusServiceUnavailable))
+	if code := getJSON(t, s, "/api/widgets/markets?symbols=BTC,ETH,AAPL,MSFT&currency=JPY", &res); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if res.Currency != "USD" {
+		t.Fatalf("expected USD fallback, got %q", res.Currency)
+	}
+
+	if code := getJSON(t, s, "/api/widgets/markets?symbols=BTC&currency=XYZ", nil); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unsupported currency, got %d", code)
+	}
+}
+
+func TestMarketsBinanceBatched(t *testing.T) {
+	up := testsupport.NewUpstream(t)
+	up.UseForWidgets(t)
+	s := newTestServer(t)
+
+	var res widgets.MarketsResponse
+	if code := getJSON(t, s, "/api/widgets/markets?symbols=BTC,ETH,SOL,DOGE", &res); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if got := up.Hits("/binance/api/v3/ticker/24hr"); got != 1 {
+		t.Fatalf("expected one batched ticker request, got %d", got)
+	}
+	if got := up.LastQuery("/binance/api/v3/ticker/24hr").Get("symbols"); got != `["BTCUSDT","ETHUSDT","SOLUSDT","DOGEUSDT"]` {
+		t.Fatalf("unexpected batch %s", got)
+	}
+	if got := up.Hits("/binance/api/v3/klines"); got != 4 {
+		t.Fatalf("expected klines per symbol, got %d", got)
+	}
+	for _, it := range res.Items {
+		if it.Source != "binance" || it.PriceUSD != 67234 || len(it.Series) != 3 {
+			t.Fatalf("unexpected quote %+v", it)
+		}
+	}
+}
+
+func TestMarketsCryptoQuoteAssets(t *testing.T) {
+	up := testsupport.NewUpstream(t)
+	up.UseForWidgets(t)
+	s := newTestServer(t)
+
+	// XYZ has no USDT market; it is priced through BTC.
+	up.Override("/binance/api/v3/ticker/24hr", func(w http.ResponseWriter, r *http.Request) {
+		if batch := r.URL.Query().Get("symbols"); batch != "" {
+			if strings.Contains(batch, "XYZ") {
+				http.Error(w, `{"code":-1121,"msg":"Invalid symbol."}`, http.StatusBadRequest)
+				return
+			}
+			var pairs []string
+			_ = json.Unmarshal([]byte(batch), &pairs)
+			items := make([]string, len(pairs))
+			for i, p := range pairs {
+				items[i] = `{"symbol":"` + p + `","lastPrice":"67234.00000000","priceChangePercent":"1.841"}`
+			}
+			_, _ = w.Write([]byte("[" + strings.Join(items, ",") + "]"))
+			return
+		}
+		switch r.URL.Query().Get("symbol") {
+		case "XYZUSDT", "USDTXYZ":
+			http.Error(w, `{"code":-1121,"msg":"Invalid symbol."}`, http.StatusBadRequest)
+		case "XYZBTC":
+			_, _ = w.Write([]byte(`{"lastPrice":"0.00001000","priceChangePercent":"10"}`))
+		default:
+			_, _ = w.Write([]byte(`{"lastPrice":"67234.00000000","priceChangePercent":"1.841"}`))
+		}
+	})
+
+	var res widgets.MarketsResponse
+	if code := getJSON(t, s, "/api/widgets/markets?symbols=CRYPTO:XYZ,ETH/BTC,AAPL,MSFT&currency=EUR", &res); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	xyz, eth := res.Items[0], res.Items[1]
+	if xyz.Symbol != "XYZ" || xyz.QuoteAsset != "USDT" || math.Abs(xyz.PriceUSD-0.67234) > 1e-9 || len(xyz.Series) != 3 {
+		t.Fatalf("expected XYZ via BTC cross rate, got %+v", xyz)
+	}
+	if xyz.Currency != "EUR" || math.Abs(xyz.Price-0.67234*0.9) > 1e-9 {
+		t.Fatalf("expected USDT-quoted XYZ converted to EUR, got %+v", xyz)
+	}
+	if eth.Kind != widgets.MarketKindCrypto || eth.QuoteAsset != "BTC" || eth.Currency != "BTC" || eth.Price != eth.PriceUSD {
+		t.Fatalf("expected ETH priced in BTC, got %+v", eth)
+	}
+
+	// The configured default quote asset applies to bare symbols.
+	if err := s.store.SetKV(kvMarketsCryptoQuote, "EUR"); err != nil {
+		t.Fatalf("set crypto quote: %v", err)
+	}
+	if code := getJSON(t, s, "/api/widgets/markets?symbols=BTC,ETH,AAPL,MSFT", &res); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if res.Items[0].QuoteAsset != "EUR" || res.Items[0].Currency != "EUR" || up.LastQuery("/binance/api/v3/ticker/24hr").Get("symbols") != `["BTCEUR","ETHEUR"]` {
+		t.Fatalf("expected EUR-quoted crypto, got %+v", res.Items[0])
+	}
+
+	if code := getJSON(t, s, "/api/widgets/markets?symbols=BTC&quote=DOGE", nil); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unsupported quote asset, got %d", code)
+	}
+}
+
+func TestPortfolioWidget(t *testing.T) {
+	up := testsupport.NewUpstream(t)
+	up.UseForWidgets(t)
+	s := newTestServer(t)
+
+	if code := getJSON(t, s, "/api/widgets/portfolio", nil); code != http.StatusUnauthorized {
+		t.Fatalf("guests must not see holdings, got %d", code)
+	}
+
+	cookie := loginAsAdmin(t, s)
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(http.MethodPut, "/api/portfolio/holdings", `[{"symbol":"aapl","quantity":-1}]`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for negative quantity, got %d", w.Code)
+	}
+	if w := do(http.MethodPut, "/api/portfolio/holdings", `[{"symbol":"aapl","quantity":10,"costBasis":200},{"symbol":"BTC","quantity":0.5,"costBasis":60000}]`); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w := do(http.MethodGet, "/api/widgets/portfolio", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var res widgets.PortfolioResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if res.Currency != "USD" || len(res.Positions) != 2 || res.Positions[0].Symbol != "AAPL" {
+		t.Fatalf("unexpected portfolio: %+v", res)
+	}
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-6 }
+	aapl := res.Positions[0]
+	if !near(aapl.Value, 2317.8) || !near(aapl.Gain, 317.8) || !near(aapl.DayChange, 13.8) {
+		t.Fatalf("unexpected AAPL position: %+v", aapl)
+	}
+	if !near(res.TotalValue, 2317.8+0.5*67234) || !near(res.TotalCost, 2000+30000) {
+		t.Fatalf("unexpected totals: value=%v cost=%v", res.TotalValue, res.TotalCost)
+	}
+
+	w = do(http.MethodGet, "/api/widgets/portfolio?currency=EUR", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if res.Currency != "EUR" || !near(res.Positions[0].Value, 2317.8*0.9) || !near(res.Positions[0].CostBasis, 180) {
+		t.Fatalf("unexpected EUR portfolio: %+v", res.Positions[0])
+	}
+}
+
+func TestHolidaysWidgetOffline(t *testing.T) {
+	up := testsupport.NewUpstream(t)
+	up.UseForWidgets(t)
+	s := newTestServer(t)
+
+	var res widgets.HolidaysResponse
+	if code := getJSON(t, s, "/api/widgets/holidays?countries=DE,CN&lang=zh", &res); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if len(res.Items) == 0 {
+		t.Fatalf("expected upcoming holidays")
+	}
+	for _, it := range res.Items {
+		if it.Country == "CN" && it.DisplayName != it.LocalName {
+			t.Fatalf("zh locale should show local CN names, got %+v", it)
+		}
+		if it.Country == "DE" && it.DisplayName != it.Name {
+			t.Fatalf("zh locale should show English DE names, got %+v", it)
+		}
+		if strings.HasSuffix(it.Date, "-09-28") {
+			t.Fatalf("make-up workday listed as a holiday: %+v", it)
+		}
+	}
+	// The fixture's 09-28 make-up workday is always within the coming year.
+	if len(res.Calendars) != 2 || res.Calendars[1].Country != "CN" || len(res.Calendars[1].MakeupWorkdays) == 0 {
+		t.Fatalf("expected CN make-up workdays, got %+v", res.Calendars)
+	}
+	if m := res.Calendars[1].MakeupWorkdays[0]; !strings.HasSuffix(m.Date, "-09-28") || m.DisplayName != "国庆节" {
+		t.Fatalf("unexpected make-up workday: %+v", m)
+	}
+
+	var countries struct {
+		Results []widgets.HolidayCountry `json:"results"`
+	}
+	if code := getJSON(t, s, "/api/widgets/holidays/countries?q=ger", &countries); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if len(countries.Results) != 1 || countries.Results[0].Code != "DE" {
+		t.Fatalf("unexpected countries: %+v", countries.Results)
+	}
+}
+
+func TestHolidaysCustomEvents(t *testing.T) {
+	up := testsupport.NewUpstream(t)
+	up.UseForWidgets(t)
+	s := newTestServer(t)
+
+	cookie := loginAsAdmin(t, s)
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(http.MethodPut, "/api/holidays/events", `[{"name":"Bad","month":2,"day":30}]`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for Feb 30, got %d", w.Code)
+	}
+	now := time.Now().UTC()
+	body := fmt.Sprintf(`[{"name":"Wedding","kind":"anniversary","month":%d,"day":%d,"year":%d},{"name":"Mid-Autumn","month":8,"day":15,"lunar":true}]`,
+		int(now.Month()), now.Day(), now.Year()-5)
+	if w := do(http.MethodPut, "/api/holidays/events", body); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Events alone are enough; no countries needed.
+	var res widgets.HolidaysResponse
+	if code := getJSON(t, s, "/api/widgets/holidays", &res); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if len(res.Items) != 2 {
+		t.Fatalf("expected both events, got %+v", res.Items)
+	}
+	if it := res.Items[0]; it.Name != "Wedding" || it.Kind != widgets.EventKindAnniversary || it.DaysUntil != 0 || it.Years != 5 || it.DisplayName != "Wedding" {
+		t.Fatalf("unexpected anniversary: %+v", it)
+	}
+	if it := res.Items[1]; it.Kind != widgets.EventKindCustom || it.Country != "" {
+		t.Fatalf("unexpected lunar event: %+v", it)
+	}
+
+	// Merged with public holidays.
+	if code := getJSON(t, s, "/api/widgets/holidays?countries=DE", &res); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if res.Items[0].Name != "Wedding" {
+		t.Fatalf("expected today's anniversary first, got %+v", res.Items[0])
+	}
+}
+
+func TestBackgroundBingOffline(t *testing.T) {
+	up := testsupport.NewUpstream(t)
+	s := newTestServer(t)
+	bg, err := background.New(background.Config{Storage: s.bgStore, BaseURLs: up.BackgroundURLs()})
+	if err != nil {
+		t.Fatalf("background.New: %v", err)
+	}
+	s.bgSvc = bg
+	if err := s.store.SetKV(kvBackgroundProvider, "bing_daily"); err != nil {
+		t.Fatalf("SetKV: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/api/background/image", nil)
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", w.Code)
+		}
+		if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "image/jpeg") {
+			t.Fatalf("expected jpeg, got %q", ct)
+		}
+	}
+	if hits := up.Hits("/bing/th"); hits != 1 {
+		t.Fatalf("second request should be served from cache, image fetched %d times", hits)
+	}
+}
+
+func TestBackgroundAttribution(t *testing.T) {
+	up := testsupport.NewUpstream(t)
+	s := newTestServer(t)
+	bg, err := background.New(background.Config{Storage: s.bgStore, BaseURLs: up.BackgroundURLs(), APODKey: "test-key"})
+	if err != nil {
+		t.Fatalf("background.New: %v", err)
+	}
+	s.bgSvc = bg
+
+	cases := []struct {
+		provider string
+		want     background.Meta
+		image    string
+	}{
+		{"apod", background.Meta{Title: "Spiral Galaxy NGC 1234", Credit: "John Doe", Link: "https://apod.nasa.gov/apod/ap261016.html"}, "/images/apod.jpg"},
+		{"wikimedia", background.Meta{Title: "A mountain lake at dawn", Credit: "Jane Roe, CC BY-SA 4.0", Link: "https://commons.wikimedia.org/wiki/File:Mountain_lake_at_dawn.jpg"},
+			"/images/thumb/a/ab/Mountain_lake_at_dawn.jpg/1920px-Mountain_lake_at_dawn.jpg"},
+		{"chromecast", background.Meta{Credit: "Alex Poe"}, "/images/chromecast-1.jpg"},
+	}
+	for _, tc := range cases {
+		if err := s.store.SetKV(kvBackgroundProvider, tc.provider); err != nil {
+			t.Fatalf("SetKV: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/api/background/image", nil)
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "image/jpeg") {
+			t.Fatalf("%s: expected a jpeg, got %d %q", tc.provider, w.Code, w.Header().Get("Content-Type"))
+		}
+		if up.Hits(tc.image) != 1 {
+			t.Fatalf("%s: expected %s to be fetched", tc.provider, tc.image)
+		}
+		var info backgroundInfo
+		if code := getJSON(t, s, "/api/background", &info); code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", tc.provider, code)
+		}
+		if info.Meta != tc.want {
+			t.Fatalf("%s: unexpected attribution %+v", tc.provider, info.Meta)
+		}
+	}
+	if q := up.LastQuery("/apod/planetary/apod"); q.Get("api_key") != "test-key" {
+		t.Fatalf("expected the configured NASA key, got %v", q)
+	}
+}
+
+func TestBackgroundVariants(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		_ = png.Encode(&buf, image.NewGray(image.Rect(0, 0, 3000, 1500)))
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write(buf.Bytes())
+	}))
+	t.Cleanup(up.Close)
+
+	s := newTestServer(t)
+	bg, err := background.New(background.Config{Storage: s.bgStore, BaseURLs: background.BaseURLs{Picsum: up.URL}, MaxDimension: 1000})
+	if err != nil {
+		t.Fatalf("background.New: %v", err)
+	}
+	s.bgSvc = bg
+	size := func(target string) (int, int) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", target, w.Code)
+		}
+		cfg, _, err := image.DecodeConfig(w.Body)
+		if err != nil {
+			t.Fatalf("%s: %v", target, err)
+		}
+		return cfg.Width, cfg.Height
+	}
+
+	// The bundled default has variants too.
+	if w, _ := size("/api/background/image?variant=thumb"); w != 32 {
+		t.Fatalf("expected a 32px placeholder of the default, got %d", w)
+	}
+
+	if err := s.store.SetKV(kvBackgroundProvider, "picsum"); err != nil {
+		t.Fatalf("SetKV: %v", err)
+	}
+	if w, h := size("/api/background/image"); w != 1000 || h != 500 {
+		t.Fatalf("expected the image scaled down to 1000x500, got %dx%d", w, h)
+	}
+	if w, _ := size("/api/background/image?variant=blur"); w != 640 {
+		t.Fatalf("expected a 640px blurred variant, got %d", w)
+	}
+	if w, h := size("/api/background/image?variant=thumb"); w != 32 || h != 16 {
+		t.Fatalf("expected a 32x16 placeholder, got %dx%d", w, h)
+	}
+	if objs, _ := s.bgStore.List(context.Background(), "variants/"); len(objs) != 2 {
+		t.Fatalf("expected the variants to be stored, got %d", len(objs))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/background/image?variant=huge", nil)
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown variant, got %d", w.Code)
+	}
+}
+
+func TestBackgroundBingMarket(t *testing.T) {
+	up := testsupport.NewUpstream(t)
+	s := newTestServer(t)
+	bg, err := background.New(background.Config{Storage: s.bgStore, BaseURLs: up.BackgroundURLs()})
+	if err != nil {
+		t.Fatalf("background.New: %v", err)
+	}
+	s.bgSvc = bg
+	cookie := loginAsAdmin(t, s)
+	req := httptest.NewRequest(http.MethodPut, "/api/settings", strings.NewReader(`{"background":{"provider":"bing_daily","bingMarket":"zh-cn"}}`))
+	req.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("put settings: %d %s", w.Code, w.Body.String())
+	}
+	var st Settings
+	if code := getJSON(t, s, "/api/settings", &st); code != http.StatusOK || st.Background.BingMarket != "zh-CN" {
+		t.Fatalf("expected the normalized market, got %q (%d)", st.Background.BingMarket, code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/background/image", nil)
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("image: %d", w.Code)
+	}
+	if mkt := up.LastQuery("/bing/HPImageArchive.aspx").Get("mkt"); mkt != "zh-CN" {
+		t.Fatalf("expected mkt=zh-CN, got %q", mkt)
+	}
+	var info backgroundInfo
+	if code := getJSON(t, s, "/api/background", &info); code != http.StatusOK {
+		t.Fatalf("background: %d", code)
+	}
+	if info.Title != "Fixture landscape" || info.Credit != "Hearth tests" {
+		t.Fatalf("unexpected attribution %+v", info.Meta)
+	}
+	if entry, ok, _ := s.store.GetBackgroundCache("bg:bing_daily:zh-CN"); !ok || entry.FilePath == "" {
+		t.Fatalf("expected the image cached per market")
+	}
+}
+
+func TestBackgroundPrefetch(t *testing.T) {
+	up := testsupport.NewUpstream(t)
+	s := newTestServer(t)
+	bg, err := background.New(background.Config{Storage: s.bgStore, BaseURLs: up.BackgroundURLs()})
+	if err != nil {
+		t.Fatalf("background.New: %v", err)
+	}
+	s.bgSvc = bg
+	ctx := context.Background()
+
+	// The bundled default needs no fetching.
+	if fetched, err := s.prefetchDueBackground(ctx); err != nil || fetched {
+		t.Fatalf("default provider: fetched=%v err=%v", fetched, err)
+	}
+
+	if err := s.store.SetKV(kvBackgroundProvider, "bing_daily"); err != nil {
+		t.Fatalf("SetKV: %v", err)
+	}
+	if fetched, err := s.prefetchDueBackground(ctx); err != nil || !fetched {
+		t.Fatalf("expected a prefetch at startup: fetched=%v err=%v", fetched, err)
+	}
+	if fetched, err := s.prefetchDueBackground(ctx); err != nil || fetched {
+		t.Fatalf("a fresh background must not be fetched again: fetched=%v err=%v", fetched, err)
+	}
+
+	// Visitors get the prefetched image without an upstream request.
+	req := httptest.NewRequest(http.MethodGet, "/api/background/image", nil)
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, req)
+	if w.Code != http.StatusOK || up.Hits("/bing/th") != 1 {
+		t.Fatalf("expected the warm cache to be served, got %d after %d fetches", w.Code, up.Hits("/bing/th"))
+	}
+}
+
+func TestBackgroundFresh(t *testing.T) {
+	now := time.Now()
+	for _, tc := range []struct {
+		provider string
+		age      time.Duration
+		interval time.Duration
+		lead     time.Duration
+		want     bool
+	}{
+		{"picsum", 5 * time.Hour, 0, time.Minute, true},
+		{"picsum", 30 * time.Minute, time.Hour, time.Minute, true},
+		{"picsum", 59*time.Minute + 30*time.Second, time.Hour, time.Minute, false},
+		{"bing_daily", 2 * time.Hour, time.Hour, 0, true},
+		{"bing_daily", 25 * time.Hour, 0, 0, false},
+	} {
+		if got := backgroundFresh(tc.provider, now.Add(-tc.age), tc.interval, tc.lead); got != tc.want {
+			t.Fatalf("%+v: got %v", tc, got)
+		}
+	}
+}
+
+func TestBackgroundUploads(t *testing.T) {
+	s := newTestServer(t)
+	cookie := loginAsAdmin(t, s)
+	do := func(req *http.Request) *httptest.ResponseRecorder {
+		req.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		return w
+	}
+	images := make([][]byte, 2)
+	for i := range images {
+		var buf bytes.Buffer
+		img := image.NewGray(image.Rect(0, 0, 8, 8))
+		img.Pix[0] = uint8(i)
+		_ = png.Encode(&buf, img)
+		images[i] = buf.Bytes()
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, data := range images {
+		fw, _ := mw.CreateFormFile("file", "photo.png")
+		_, _ = fw.Write(data)
+	}
+	_ = mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/api/background/upload", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := do(req)
+	var res struct {
+		Uploads []struct {
+			Name string `json:"name"`
+			URL  string `json:"url"`
+		} `json:"uploads"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil || w.Code != http.StatusOK || len(res.Uploads) != 2 {
+		t.Fatalf("upload: %d %s", w.Code, w.Body.String())
+	}
+
+	if err := s.store.SetKV(kvBackgroundProvider, "custom"); err != nil {
+		t.Fatalf("SetKV: %v", err)
+	}
+	current := func() []byte {
+		t.Helper()
+		w := do(httptest.NewRequest(http.MethodGet, "/api/background/image", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("image: %d", w.Code)
+		}
+		return w.Body.Bytes()
+	}
+	first := current()
+	if !bytes.Equal(first, current()) {
+		t.Fatalf("the background should not change without a refresh")
+	}
+	if w := do(httptest.NewRequest(http.MethodPost, "/api/background/refresh", nil)); w.Code != http.StatusOK {
+		t.Fatalf("refresh: %d", w.Code)
+	}
+	second := current()
+	if bytes.Equal(first, second) {
+		t.Fatalf("refresh should rotate to the next upload")
+	}
+
+	for _, u := range res.Uploads {
+		if w := do(httptest.NewRequest(http.MethodDelete, "/api/background/uploads/"+u.Name, nil)); w.Code != http.StatusOK {
+			t.Fatalf("delete: %d", w.Code)
+		}
+	}
+	if w := do(httptest.NewRequest(http.MethodDelete, "/api/background/uploads/"+res.Uploads[0].Name, nil)); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a deleted upload, got %d", w.Code)
+	}
+	// Without uploads the bundled default is shown.
+	if w := do(httptest.NewRequest(http.MethodGet, "/api/background/image", nil)); w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/jpeg" {
+		t.Fatalf("expected the default background, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+}
+
+func TestBackgroundHistory(t *testing.T) {
+	var served atomic.Int32
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := served.Add(1)
+		var buf bytes.Buffer
+		img := image.NewGray(image.Rect(0, 0, 8, 8))
+		img.Pix[0] = uint8(n)
+		_ = png.Encode(&buf, img)
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write(buf.Bytes())
+	}))
+	t.Cleanup(up.Close)
+
+	s := newTestServer(t)
+	s.cfg.BackgroundHistory = 2
+	bg, err := background.New(background.Config{Storage: s.bgStore, BaseURLs: background.BaseURLs{Picsum: up.URL}})
+	if err != nil {
+		t.Fatalf("background.New: %v", err)
+	}
+	s.bgSvc = bg
+	if err := s.store.SetKV(kvBackgroundProvider, "picsum"); err != nil {
+		t.Fatalf("SetKV: %v", err)
+	}
+	cookie := loginAsAdmin(t, s)
+	do := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		return w
+	}
+	type item struct {
+		Name    string `json:"name"`
+		URL     string `json:"url"`
+		Current bool   `json:"current"`
+	}
+	history := func() []item {
+		t.Helper()
+		var res struct {
+			Items []item `json:"items"`
+		}
+		w := do(http.MethodGet, "/api/background/history")
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("history: %d %s", w.Code, w.Body.String())
+		}
+		return res.Items
+	}
+	current := func() []byte {
+		t.Helper()
+		w := do(http.MethodGet, "/api/background/image")
+		if w.Code != http.StatusOK {
+			t.Fatalf("image: %d", w.Code)
+		}
+		return w.Body.Bytes()
+	}
+
+	first := current()
+	for i := 0; i < 2; i++ {
+		if w := do(http.MethodPost, "/api/background/refresh"); w.Code != http.StatusOK {
+			t.Fatalf("refresh: %d %s", w.Code, w.Body.String())
+		}
+	}
+	items := history()
+	if len(items) != 2 || !items[0].Current {
+		t.Fatalf("expected the two newest backgrounds, newest current: %+v", items)
+	}
+	// The oldest image fell out of the history and was deleted.
+	if objs, _ := s.bgStore.List(context.Background(), "history/"); len(objs) != 2 {
+		t.Fatalf("expected 2 stored images, got %d", len(objs))
+	}
+	newest := current()
+	if bytes.Equal(first, newest) {
+		t.Fatalf("refresh should change the background")
+	}
+
+	if w := do(http.MethodPost, "/api/background/previous"); w.Code != http.StatusOK {
+		t.Fatalf("previous: %d", w.Code)
+	}
+	if items := history(); !items[1].Current {
+		t.Fatalf("expected the older image to be current: %+v", items)
+	}
+	if w := do(http.MethodGet, items[1].URL); w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), current()) {
+		t.Fatalf("history image should match the current background")
+	}
+	if w := do(http.MethodPost, "/api/background/previous"); w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 at the oldest image, got %d", w.Code)
+	}
+
+	// Next steps forward again without fetching, then fetches once at the front.
+	fetched := served.Load()
+	if w := do(http.MethodPost, "/api/background/next"); w.Code != http.StatusOK || !bytes.Equal(current(), newest) {
+		t.Fatalf("next should return to the newest image")
+	}
+	if served.Load() != fetched {
+		t.Fatalf("stepping through the history must not fetch")
+	}
+	if w := do(http.MethodPost, "/api/background/next"); w.Code != http.StatusOK || served.Load() != fetched+1 {
+		t.Fatalf("next at the newest image should fetch a new one")
+	}
+}
+
+func TestHolidayDatasetAdmin(t *testing.T) {
+	up := testsupport.NewUpstream(t)
+	up.UseForWidgets(t)
+	s := newTestServer(t)
+	t.Cleanup(func() { widgets.SetHolidayDataset(nil) })
+
+	cookie := loginAsAdmin(t, s)
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, req)
+		return w
+	}
+	summary := func(w *httptest.ResponseRecorder) widgets.HolidayDatasetInfo {
+		t.Helper()
+		var info widgets.HolidayDatasetInfo
+		if err := json.Unmarshal(w.Body.Bytes(), &info); err != nil {
+			t.Fatalf("decode: %v: %s", err, w.Body.String())
+		}
+		return info
+	}
+
+	if info := summary(do(http.MethodGet, "/api/admin/holidays/dataset", "")); info.Source != "bundled" || info.Countries["CN"] == 0 {
+		t.Fatalf("expected the bundled dataset, got %+v", info)
+	}
+
+	// Refresh DE from the live source into an override.
+	y := time.Now().Year()
+	w := do(http.MethodPost, "/api/admin/holidays/dataset/refresh", fmt.Sprintf(`{"countries":["de"],"years":[%d,%d]}`, y, y+1))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var refreshed struct {
+		Dataset widgets.HolidayDatasetInfo `json:"dataset"`
+		Warning string                     `json:"warning"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &refreshed); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if refreshed.Dataset.Source != "override" || refreshed.Dataset.Countries["DE"] != 2 || len(refreshed.Dataset.Countries) != 1 || refreshed.Warning != "" {
+		t.Fatalf("unexpected refresh result: %+v", refreshed)
+	}
+	if _, ok, _ := s.store.GetKV(kvHolidayDataset); !ok {
+		t.Fatalf("expected the override to be persisted")
+	}
+
+	// With Nager.Date down, the widget serves the override.
+	for _, year := range []int{y, y + 1} {
+		up.Override(fmt.Sprintf("/nager/api/v3/PublicHolidays/%d/DE", year), testsupport.Status(http.StatusBadGateway))
+	}
+	widgets.ResetCaches()
+	s.respCache.purge()
+	var res widgets.HolidaysResponse
+	if code := getJSON(t, s, "/api/widgets/holidays?countries=DE", &res); code != http.StatusOK || len(res.Items) == 0 || res.Items[0].Country != "DE" {
+		t.Fatalf("expected offline DE holidays, got %d %+v", code, res.Items)
+	}
+
+	// Nothing fetched: the override is left alone.
+	up.Override(fmt.Sprintf("/nager/api/v3/PublicHolidays/%d/FR", y), testsupport.Status(http.StatusBadGateway))
+	if w := do(http.MethodPost, "/api/admin/holidays/dataset/refresh", fmt.Sprintf(`{"countries":["FR"],"years":[%d]}`, y)); w.Code != http.StatusBadGateway {
+		t.Fatalf("expected 502, got %d", w.Code)
+	}
+
+	if w := do(http.MethodPut, "/api/admin/holidays/dataset", `{"holidays":{"DE":{"2026":[{"date":"2026-13-01"}]}}}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid date, got %d", w.Code)
+	}
+	w = do(http.MethodPut, "/api/admin/holidays/dataset", `{"generatedAt":"manual","holidays":{"AT":{"2026":[{"date":"2026-10-26","localName":"Nationalfeiertag","name":"National Day"}]}}}`)
+	if info := summary(w); w.Code != http.StatusOK || info.GeneratedAt != "manual" || info.Countries["AT"] != 1 {
+		t.Fatalf("unexpected upload result: %d %+v", w.Code, info)
+	}
+
+	if info := summary(do(http.MethodDelete, "/api/admin/holidays/dataset", "")); info.Source != "bundled" {
+		t.Fatalf("expected the bundled dataset after delete, got %+v", info)
+	}
+	if _, ok, _ := s.store.GetKV(kvHolidayDataset); ok {
+		t.Fatalf("expected the override to be deleted")
+	}
+}
+
+func TestHolidaysICSFeed(t *testing.T) {
+	up := testsupport.NewUpstream(t)
+	up.UseForWidgets(t)
+	s := newTestServer(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/widgets/holidays/ics?countries=DE&lang=en", nil)
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/calendar") {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	body := w.Body.String()
+	if !strings.HasPrefix(body, "BEGIN:VCALENDAR\r\n") || !strings.Contains(body, "BEGIN:VEVENT\r\n") || !strings.HasSuffix(body, "END:VCALENDAR\r\n") {
+		t.Fatalf("unexpected feed:\n%s", body)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/widgets/holidays/ics", nil)
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without countries or events, got %d", w.Code)
+	}
+}
+
+func TestClocksWidget(t *testing.T) {
+	s := newTestServer(t)
+
+	var res widgets.ClocksResponse
+	if code := getJSON(t, s, "/api/widgets/clocks", &res); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if res.Viewer != "Asia/Shanghai" || len(res.Items) != 2 || res.Items[1].Timezone != "America/New_York" {
+		t.Fatalf("expected the saved zones relative to the clock timezone, got %+v", res)
+	}
+
+	if code := getJSON(t, s, "/api/widgets/clocks?timezones=UTC,Asia/Tokyo&viewer=UTC", &res); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if len(res.Items) != 2 || res.Items[1].OffsetDiffSec != 9*3600 || res.Items[0].NextTransition != nil {
+		t.Fatalf("unexpected clocks: %+v", res.Items)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/widgets/clocks?timezones=Mars/Base", nil)
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown zone, got %d", w.Code)
+	}
+}
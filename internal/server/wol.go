@@ -0,0 +1,89 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// wakeAddr is where magic packets are sent: the discard port on the local
+// broadcast address.
+var wakeAddr = "255.255.255.255:9"
+
+// normalizeMAC parses a 48-bit MAC address in any of the usual notations and
+// returns it as lowercase colon-separated hex.
+func normalizeMAC(v string) (string, error) {
+	v = strings.TrimSpace(v)
+	if len(v) == 12 && !strings.ContainsAny(v, ":-.") {
+		// Bare hex, as printed on many device labels.
+		var b strings.Builder
+		for i := 0; i < 12; i += 2 {
+			if i > 0 {
+				b.WriteByte(':')
+			}
+			b.WriteString(v[i : i+2])
+		}
+		v = b.String()
+	}
+	mac, err := net.ParseMAC(v)
+	if err != nil || len(mac) != 6 {
+		return "", fmt.Errorf("invalid mac address %q", v)
+	}
+	return mac.String(), nil
+}
+
+// magicPacket is six 0xff bytes followed by the MAC repeated 16 times.
+func magicPacket(mac net.HardwareAddr) []byte {
+	p := bytes.Repeat([]byte{0xff}, 6)
+	for i := 0; i < 16; i++ {
+		p = append(p, mac...)
+	}
+	return p
+}
+
+// sendMagicPacket broadcasts a Wake-on-LAN packet for mac.
+func sendMagicPacket(mac net.HardwareAddr) error {
+	conn, err := net.Dial("udp", wakeAddr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write(magicPacket(mac))
+	return err
+}
+
+// handleWakeApp handles POST /api/apps/{id}/wake.
+func (s *Server) handleWakeApp(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	app, ok, err := s.store.AppByID(id)
+	if err != nil {
+		slog.Error("failed to load app", "error", err, "id", id)
+		writeError(w, http.StatusInternalServerError, "failed to load app")
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "app not found")
+		return
+	}
+	if app.WakeMAC == nil || *app.WakeMAC == "" {
+		writeError(w, http.StatusBadRequest, "app has no mac address")
+		return
+	}
+	mac, err := net.ParseMAC(*app.WakeMAC)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "app has an invalid mac address")
+		return
+	}
+	if err := sendMagicPacket(mac); err != nil {
+		slog.Warn("wake-on-lan failed", "error", err, "id", id, "mac", *app.WakeMAC)
+		writeError(w, http.StatusBadGateway, "failed to send magic packet")
+		return
+	}
+	slog.Info("wake-on-lan sent", "id", id, "mac", *app.WakeMAC)
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"sync"
+)
+
+// workers tracks the goroutines that outlive the request or call starting
+// them: the periodic jobs and one-off tasks such as an icon pack sync. They
+// share a context that Shutdown cancels, and Shutdown waits for them to
+// return before the database is closed.
+type workers struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	stopped bool
+}
+
+func newWorkers() *workers {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &workers{ctx: ctx, cancel: cancel}
+}
+
+// goWork runs fn in a tracked goroutine with the server's work context.
+// After Shutdown started, fn is not run at all.
+func (s *Server) goWork(fn func(ctx context.Context)) {
+	s.work.mu.Lock()
+	defer s.work.mu.Unlock()
+	if s.work.stopped {
+		return
+	}
+	s.work.wg.Add(1)
+	go func() {
+		defer s.work.wg.Done()
+		fn(s.work.ctx)
+	}()
+}
+
+// Shutdown cancels the background jobs and tasks, waits for them to return
+// and closes the database. Call it after the HTTP server stopped serving, so
+// no request uses the store anymore. If ctx ends first, the database is left
+// open for the goroutines still running and ctx's error is returned.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.work.mu.Lock()
+	s.work.stopped = true
+	s.work.mu.Unlock()
+	s.work.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.work.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return s.store.Close()
+}
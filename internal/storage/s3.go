@@ -0,0 +1,305 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Config configures an S3-compatible backend (AWS S3, MinIO, R2, ...).
+type S3Config struct {
+	Endpoint  string // e.g. https://s3.eu-west-1.amazonaws.com; derived from Region when empty
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	Prefix    string // optional key prefix inside the bucket
+	PathStyle bool   // use endpoint/bucket/key instead of bucket.endpoint/key
+	Client    *http.Client
+}
+
+// S3 stores objects in an S3-compatible bucket using SigV4-signed requests.
+type S3 struct {
+	cfg      S3Config
+	endpoint *url.URL
+	client   *http.Client
+}
+
+func NewS3(cfg S3Config) (*S3, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("storage: s3 bucket required")
+	}
+	if cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, errors.New("storage: s3 credentials required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://s3." + cfg.Region + ".amazonaws.com"
+	}
+	u, err := url.Parse(strings.TrimRight(cfg.Endpoint, "/"))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("storage: invalid s3 endpoint %q", cfg.Endpoint)
+	}
+	cfg.Prefix = strings.Trim(cfg.Prefix, "/")
+	c := cfg.Client
+	if c == nil {
+		c = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &S3{cfg: cfg, endpoint: u, client: c}, nil
+}
+
+func (s *S3) objectURL(key string) (*url.URL, error) {
+	k, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if s.cfg.Prefix != "" {
+		k = s.cfg.Prefix + "/" + k
+	}
+	u := *s.endpoint
+	p := "/" + k
+	if s.cfg.PathStyle {
+		p = "/" + s.cfg.Bucket + p
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+	}
+	u.Path = strings.TrimRight(u.Path, "/") + p
+	u.RawPath = uriEncodePath(u.Path)
+	return &u, nil
+}
+
+func (s *S3) do(ctx context.Context, method, key string, body []byte, contentType string) (*http.Response, error) {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body == nil {
+		req.Body = http.NoBody
+		req.ContentLength = 0
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("User-Agent", "Hearth/0.1")
+	s.sign(req, body, time.Now())
+	return s.client.Do(req)
+}
+
+func (s *S3) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	if contentType == "" {
+		contentType = contentTypeFor(key)
+	}
+	resp, err := s.do(ctx, http.MethodPut, key, data, contentType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkS3Status(resp)
+}
+
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, Info, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil, "")
+	if err != nil {
+		return nil, Info{}, err
+	}
+	if err := checkS3Status(resp); err != nil {
+		resp.Body.Close()
+		return nil, Info{}, err
+	}
+	return resp.Body, infoFromHeaders(resp, key), nil
+}
+
+func (s *S3) Stat(ctx context.Context, key string) (Info, error) {
+	resp, err := s.do(ctx, http.MethodHead, key, nil, "")
+	if err != nil {
+		return Info{}, err
+	}
+	defer resp.Body.Close()
+	if err := checkS3Status(resp); err != nil {
+		return Info{}, err
+	}
+	return infoFromHeaders(resp, key), nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return checkS3Status(resp)
+}
+
+func checkS3Status(resp *http.Response) error {
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("s3: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func infoFromHeaders(resp *http.Response, key string) Info {
+	info := Info{ContentType: resp.Header.Get("Content-Type")}
+	if info.ContentType == "" {
+		info.ContentType = contentTypeFor(key)
+	}
+	if n, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64); err == nil {
+		info.Size = n
+	}
+	if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.ModTime = t
+	}
+	return info
+}
+
+// sign adds AWS Signature Version 4 headers to req.
+func (s *S3) sign(req *http.Request, body []byte, now time.Time) {
+	sum := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(sum[:])
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := "host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + amzDate + "\n"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.cfg.Region + "/s3/aws4_request"
+	crHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(crHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretKey), date)
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.cfg.AccessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(data))
+	return m.Sum(nil)
+}
+
+// uriEncodePath percent-encodes a path per SigV4 rules, keeping '/' separators.
+func uriEncodePath(p string) string {
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+// listResult is the ListObjectsV2 response.
+type listResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+func (s *S3) List(ctx context.Context, prefix string) ([]Object, error) {
+	full := prefix
+	if s.cfg.Prefix != "" {
+		full = s.cfg.Prefix + "/" + prefix
+	}
+	var out []Object
+	token := ""
+	for {
+		u := *s.endpoint
+		if s.cfg.PathStyle {
+			u.Path = strings.TrimRight(u.Path, "/") + "/" + s.cfg.Bucket + "/"
+		} else {
+			u.Host = s.cfg.Bucket + "." + u.Host
+			u.Path = strings.TrimRight(u.Path, "/") + "/"
+		}
+		u.RawPath = uriEncodePath(u.Path)
+		// SigV4 wants the canonical query sorted by key and percent-encoded.
+		q := "list-type=2&prefix=" + uriEncodeQuery(full)
+		if token != "" {
+			q = "continuation-token=" + uriEncodeQuery(token) + "&" + q
+		}
+		u.RawQuery = q
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), http.NoBody)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("User-Agent", "Hearth/0.1")
+		s.sign(req, nil, time.Now())
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		var res listResult
+		err = checkS3Status(resp)
+		if err == nil {
+			err = xml.NewDecoder(io.LimitReader(resp.Body, 32<<20)).Decode(&res)
+		}
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range res.Contents {
+			key := c.Key
+			if s.cfg.Prefix != "" {
+				key = strings.TrimPrefix(key, s.cfg.Prefix+"/")
+			}
+			out = append(out, Object{Key: key, Size: c.Size, ModTime: c.LastModified})
+		}
+		if !res.IsTruncated || res.NextContinuationToken == "" {
+			return out, nil
+		}
+		token = res.NextContinuationToken
+	}
+}
+
+// uriEncodeQuery percent-encodes a query value per SigV4 rules.
+func uriEncodeQuery(v string) string {
+	return strings.ReplaceAll(uriEncodePath(v), "/", "%2F")
+}
@@ -0,0 +1,288 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SFTPCommand is the sftp client the SFTP backend runs.
+var SFTPCommand = "sftp"
+
+// SFTPConfig configures an SFTP backend. Authentication is by key only, as
+// the client runs in batch mode.
+type SFTPConfig struct {
+	Host string
+	Port int
+	User string
+	Path string // directory objects are stored under; relative paths start at the user's home
+	// PrivateKey is the key to log in with; empty uses the client's own
+	// configuration.
+	PrivateKey string
+	// HostKey is the server's known_hosts line, e.g. "nas.lan ssh-ed25519
+	// AAAA..."; empty checks against the client's known_hosts.
+	HostKey string
+}
+
+// SFTP stores objects as files on an SFTP server by running the OpenSSH sftp
+// client, so no SSH implementation is linked in.
+type SFTP struct {
+	cfg SFTPConfig
+}
+
+func NewSFTP(cfg SFTPConfig) (*SFTP, error) {
+	if cfg.Host == "" || strings.ContainsAny(cfg.Host, " @/") || strings.HasPrefix(cfg.Host, "-") {
+		return nil, fmt.Errorf("storage: invalid sftp host %q", cfg.Host)
+	}
+	if strings.ContainsAny(cfg.User, " @") || strings.HasPrefix(cfg.User, "-") {
+		return nil, fmt.Errorf("storage: invalid sftp user %q", cfg.User)
+	}
+	if strings.ContainsAny(cfg.Path, "\"\n") {
+		return nil, fmt.Errorf("storage: invalid sftp path %q", cfg.Path)
+	}
+	if cfg.Port < 0 || cfg.Port > 65535 {
+		return nil, fmt.Errorf("storage: invalid sftp port %d", cfg.Port)
+	}
+	return &SFTP{cfg: cfg}, nil
+}
+
+// remote is the quoted remote path of the relative path p.
+func (s *SFTP) remote(p string) string {
+	base := strings.TrimRight(s.cfg.Path, "/")
+	if base == "" {
+		base = "."
+	}
+	return strconv.Quote(path.Join(base, p))
+}
+
+// run runs the batch commands in a temporary directory holding the key and
+// host key files, returning the client's output.
+func (s *SFTP) run(ctx context.Context, dir string, batch []string) (string, error) {
+	args := []string{"-b", "-", "-o", "BatchMode=yes", "-o", "ConnectTimeout=10"}
+	if s.cfg.Port > 0 {
+		args = append(args, "-P", strconv.Itoa(s.cfg.Port))
+	}
+	if s.cfg.PrivateKey != "" {
+		key := filepath.Join(dir, "id")
+		if err := os.WriteFile(key, []byte(strings.TrimSpace(s.cfg.PrivateKey)+"\n"), 0o600); err != nil {
+			return "", err
+		}
+		args = append(args, "-o", "IdentitiesOnly=yes", "-i", key)
+	}
+	if s.cfg.HostKey != "" {
+		known := filepath.Join(dir, "known_hosts")
+		if err := os.WriteFile(known, []byte(strings.TrimSpace(s.cfg.HostKey)+"\n"), 0o600); err != nil {
+			return "", err
+		}
+		args = append(args, "-o", "UserKnownHostsFile="+known, "-o", "StrictHostKeyChecking=yes")
+	}
+	target := s.cfg.Host
+	if s.cfg.User != "" {
+		target = s.cfg.User + "@" + s.cfg.Host
+	}
+	args = append(args, "--", target)
+
+	cmd := exec.CommandContext(ctx, SFTPCommand, args...)
+	cmd.Stdin = strings.NewReader(strings.Join(batch, "\n") + "\n")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		lower := strings.ToLower(msg)
+		if strings.Contains(lower, "not found") || strings.Contains(lower, "no such file") {
+			return "", ErrNotFound
+		}
+		if msg == "" {
+			return "", fmt.Errorf("sftp: %w", err)
+		}
+		return "", fmt.Errorf("sftp: %w: %s", err, truncate(msg, 512))
+	}
+	return stdout.String(), nil
+}
+
+// session runs batch with a temporary directory for it and its files, then
+// the after hooks while the directory still exists.
+func (s *SFTP) session(ctx context.Context, batch func(dir string) ([]string, error), after ...func(dir string) error) (string, error) {
+	dir, err := os.MkdirTemp("", "hearth-sftp-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+	cmds, err := batch(dir)
+	if err != nil {
+		return "", err
+	}
+	out, err := s.run(ctx, dir, cmds)
+	if err != nil {
+		return "", err
+	}
+	for _, fn := range after {
+		if err := fn(dir); err != nil {
+			return "", err
+		}
+	}
+	return out, nil
+}
+
+func (s *SFTP) Put(ctx context.Context, key string, data []byte, _ string) error {
+	k, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	_, err = s.session(ctx, func(dir string) ([]string, error) {
+		local := filepath.Join(dir, "object")
+		if err := os.WriteFile(local, data, 0o600); err != nil {
+			return nil, err
+		}
+		// A leading - lets existing directories fail without ending the batch.
+		var cmds []string
+		cur := ""
+		for _, part := range strings.Split(path.Dir(k), "/") {
+			if part == "." {
+				break
+			}
+			cur = path.Join(cur, part)
+			cmds = append(cmds, "-mkdir "+s.remote(cur))
+		}
+		if s.cfg.Path != "" {
+			cmds = append([]string{"-mkdir " + s.remote("")}, cmds...)
+		}
+		return append(cmds, "put "+strconv.Quote(local)+" "+s.remote(k)), nil
+	})
+	return err
+}
+
+func (s *SFTP) Get(ctx context.Context, key string) (io.ReadCloser, Info, error) {
+	k, err := cleanKey(key)
+	if err != nil {
+		return nil, Info{}, err
+	}
+	var data []byte
+	_, err = s.session(ctx, func(dir string) ([]string, error) {
+		return []string{"get " + s.remote(k) + " " + strconv.Quote(filepath.Join(dir, "object"))}, nil
+	}, func(dir string) (err error) {
+		data, err = os.ReadFile(filepath.Join(dir, "object"))
+		return err
+	})
+	if err != nil {
+		return nil, Info{}, err
+	}
+	info := Info{Size: int64(len(data)), ContentType: contentTypeFor(k)}
+	return io.NopCloser(bytes.NewReader(data)), info, nil
+}
+
+func (s *SFTP) Stat(ctx context.Context, key string) (Info, error) {
+	k, err := cleanKey(key)
+	if err != nil {
+		return Info{}, err
+	}
+	entries, err := s.ls(ctx, s.remote(k))
+	if err != nil {
+		return Info{}, err
+	}
+	for _, e := range entries {
+		if !e.dir && e.name == path.Base(k) {
+			return Info{Size: e.size, ContentType: contentTypeFor(k)}, nil
+		}
+	}
+	return Info{}, ErrNotFound
+}
+
+func (s *SFTP) Delete(ctx context.Context, key string) error {
+	k, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	_, err = s.session(ctx, func(string) ([]string, error) {
+		return []string{"rm " + s.remote(k)}, nil
+	})
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// List walks the directories below the directory of prefix.
+func (s *SFTP) List(ctx context.Context, prefix string) ([]Object, error) {
+	dir := prefix
+	if !strings.HasSuffix(dir, "/") {
+		dir = path.Dir(dir) + "/"
+	}
+	if dir == "./" || dir == "/" {
+		dir = ""
+	}
+	out := []Object{}
+	todo := []string{dir}
+	for len(todo) > 0 {
+		cur := todo[0]
+		todo = todo[1:]
+		entries, err := s.ls(ctx, s.remote(cur))
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			key := cur + e.name
+			if e.dir {
+				key += "/"
+				if strings.HasPrefix(key, prefix) || strings.HasPrefix(prefix, key) {
+					todo = append(todo, key)
+				}
+			} else if strings.HasPrefix(key, prefix) {
+				out = append(out, Object{Key: key, Size: e.size})
+			}
+		}
+	}
+	return out, nil
+}
+
+type sftpEntry struct {
+	name string
+	size int64
+	dir  bool
+}
+
+// ls lists the remote path p, a directory or a file, from the numeric long
+// listing: mode, links, uid, gid, size, three date fields and the name.
+func (s *SFTP) ls(ctx context.Context, p string) ([]sftpEntry, error) {
+	out, err := s.session(ctx, func(string) ([]string, error) {
+		return []string{"ls -ln " + p}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var entries []sftpEntry
+	for _, line := range strings.Split(out, "\n") {
+		f := strings.Fields(line)
+		if len(f) < 9 || strings.HasPrefix(line, "sftp>") || (f[0][0] != '-' && f[0][0] != 'd') {
+			continue
+		}
+		size, err := strconv.ParseInt(f[4], 10, 64)
+		if err != nil {
+			continue
+		}
+		name := path.Base(strings.Join(f[8:], " "))
+		if name == "." || name == ".." {
+			continue
+		}
+		entries = append(entries, sftpEntry{name: name, size: size, dir: f[0][0] == 'd'})
+	}
+	return entries, nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "…"
+}
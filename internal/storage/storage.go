@@ -0,0 +1,155 @@
+// Package storage abstracts where cached assets (icons, backgrounds) live so
+// stateless deployments can keep them in object storage instead of local disk.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when a key does not exist.
+	ErrNotFound = errors.New("storage: not found")
+	// ErrInvalidKey is returned for keys that are empty or would leave their
+	// namespace.
+	ErrInvalidKey = errors.New("storage: invalid key")
+)
+
+// Info describes a stored object.
+type Info struct {
+	Size        int64
+	ModTime     time.Time
+	ContentType string
+}
+
+// Object is a listed object.
+type Object struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// Backend stores opaque blobs under slash-separated keys.
+type Backend interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, Info, error)
+	Stat(ctx context.Context, key string) (Info, error)
+	Delete(ctx context.Context, key string) error
+	// List returns every object whose key starts with prefix.
+	List(ctx context.Context, prefix string) ([]Object, error)
+}
+
+// Sub returns a Backend that namespaces every key under prefix.
+func Sub(b Backend, prefix string) Backend {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return b
+	}
+	return &subBackend{b: b, prefix: prefix + "/"}
+}
+
+type subBackend struct {
+	b      Backend
+	prefix string
+}
+
+// key confines a caller's key to the namespace: cleaning it first keeps
+// "../" segments from climbing out of the prefix once it is prepended.
+func (s *subBackend) key(key string) (string, error) {
+	k, err := subKey(key)
+	if err != nil {
+		return "", err
+	}
+	return s.prefix + k, nil
+}
+
+func (s *subBackend) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	k, err := s.key(key)
+	if err != nil {
+		return err
+	}
+	return s.b.Put(ctx, k, data, contentType)
+}
+
+func (s *subBackend) Get(ctx context.Context, key string) (io.ReadCloser, Info, error) {
+	k, err := s.key(key)
+	if err != nil {
+		return nil, Info{}, err
+	}
+	return s.b.Get(ctx, k)
+}
+
+func (s *subBackend) Stat(ctx context.Context, key string) (Info, error) {
+	k, err := s.key(key)
+	if err != nil {
+		return Info{}, err
+	}
+	return s.b.Stat(ctx, k)
+}
+
+func (s *subBackend) Delete(ctx context.Context, key string) error {
+	k, err := s.key(key)
+	if err != nil {
+		return err
+	}
+	return s.b.Delete(ctx, k)
+}
+
+func (s *subBackend) List(ctx context.Context, prefix string) ([]Object, error) {
+	if prefix != "" {
+		p, err := subKey(prefix)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasSuffix(prefix, "/") {
+			p += "/"
+		}
+		prefix = p
+	}
+	list, err := s.b.List(ctx, s.prefix+prefix)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		list[i].Key = strings.TrimPrefix(list[i].Key, s.prefix)
+	}
+	return list, nil
+}
+
+// subKey cleans a key relative to a namespace, rejecting absolute keys and
+// ones that still point above it.
+func subKey(key string) (string, error) {
+	if key == "" || strings.Contains(key, "\\") || path.IsAbs(key) {
+		return "", ErrInvalidKey
+	}
+	k := path.Clean(key)
+	if k == "." || k == ".." || strings.HasPrefix(k, "../") {
+		return "", ErrInvalidKey
+	}
+	return k, nil
+}
+
+// cleanKey validates a key and normalizes it to a relative slash path.
+func cleanKey(key string) (string, error) {
+	key = strings.TrimSpace(key)
+	if key == "" || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	c := path.Clean("/" + key)
+	if c == "/" {
+		return "", ErrInvalidKey
+	}
+	return strings.TrimPrefix(c, "/"), nil
+}
+
+func contentTypeFor(key string) string {
+	if ct := mime.TypeByExtension(path.Ext(key)); ct != "" {
+		return ct
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,249 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/webdav"
+)
+
+func roundTrip(t *testing.T, b Backend) {
+	t.Helper()
+	ctx := context.Background()
+
+	if _, err := b.Stat(ctx, "missing.png"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Stat missing: expected ErrNotFound, got %v", err)
+	}
+	if err := b.Put(ctx, "markets/BTC.png", []byte("png-bytes"), "image/png"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	info, err := b.Stat(ctx, "markets/BTC.png")
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if info.Size != int64(len("png-bytes")) {
+		t.Fatalf("Stat size: got %d", info.Size)
+	}
+	rc, _, err := b.Get(ctx, "markets/BTC.png")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(data) != "png-bytes" {
+		t.Fatalf("Get: got %q", data)
+	}
+	list, err := b.List(ctx, "markets/")
+	if err != nil || len(list) != 1 || list[0].Key != "markets/BTC.png" || list[0].Size != int64(len("png-bytes")) {
+		t.Fatalf("List: got %+v (%v)", list, err)
+	}
+	if list, err := b.List(ctx, "backgrounds/"); err != nil || len(list) != 0 {
+		t.Fatalf("List of an empty prefix: got %+v (%v)", list, err)
+	}
+	if err := b.Delete(ctx, "markets/BTC.png"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, _, err := b.Get(ctx, "markets/BTC.png"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after delete: expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestLocalBackend(t *testing.T) {
+	l, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+	roundTrip(t, Sub(l, "icons"))
+
+	if err := l.Put(context.Background(), "../escape.txt", []byte("x"), ""); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if _, err := l.Stat(context.Background(), "escape.txt"); err != nil {
+		t.Fatalf("traversal key should be confined to root: %v", err)
+	}
+}
+
+func TestSubConfinesKeys(t *testing.T) {
+	ctx := context.Background()
+	l, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+	if err := l.Put(ctx, "hearth.db", []byte("SQLite format 3"), ""); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	icons := Sub(l, "icons")
+	if err := icons.Put(ctx, "sub/../a.png", []byte("png"), "image/png"); err != nil {
+		t.Fatalf("a key that stays in the namespace should work: %v", err)
+	}
+	if _, err := icons.Stat(ctx, "a.png"); err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	for _, key := range []string{"../hearth.db", "a/../../hearth.db", "/hearth.db", "..", "."} {
+		if _, _, err := icons.Get(ctx, key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Get(%q): expected ErrInvalidKey, got %v", key, err)
+		}
+		if err := icons.Put(ctx, key, []byte("x"), ""); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Put(%q): expected ErrInvalidKey, got %v", key, err)
+		}
+		if err := icons.Delete(ctx, key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Delete(%q): expected ErrInvalidKey, got %v", key, err)
+		}
+	}
+	if _, err := icons.List(ctx, "../"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("List(../): expected ErrInvalidKey, got %v", err)
+	}
+	if _, err := l.Stat(ctx, "hearth.db"); err != nil {
+		t.Fatalf("the file outside the namespace should be untouched: %v", err)
+	}
+}
+
+func TestS3Backend(t *testing.T) {
+	var mu sync.Mutex
+	objects := map[string][]byte{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AK/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			b, _ := io.ReadAll(r.Body)
+			objects[r.URL.Path] = b
+		case http.MethodGet, http.MethodHead:
+			if r.URL.Query().Get("list-type") == "2" {
+				prefix := strings.TrimSuffix(r.URL.Path, "/") + "/" + r.URL.Query().Get("prefix")
+				var xml strings.Builder
+				xml.WriteString("<ListBucketResult>")
+				for k, b := range objects {
+					if strings.HasPrefix(k, prefix) {
+						fmt.Fprintf(&xml, "<Contents><Key>%s</Key><Size>%d</Size><LastModified>2006-01-02T15:04:05.000Z</LastModified></Contents>", strings.TrimPrefix(k, "/hearth/"), len(b))
+					}
+				}
+				xml.WriteString("<IsTruncated>false</IsTruncated></ListBucketResult>")
+				_, _ = w.Write([]byte(xml.String()))
+				return
+			}
+			b, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "image/png")
+			w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+			w.Header().Set("Content-Length", strconv.Itoa(len(b)))
+			if r.Method == http.MethodGet {
+				_, _ = w.Write(b)
+			}
+		case http.MethodDelete:
+			delete(objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	s3, err := NewS3(S3Config{Endpoint: srv.URL, Bucket: "hearth", AccessKey: "AK", SecretKey: "SK", Prefix: "prod", PathStyle: true})
+	if err != nil {
+		t.Fatalf("NewS3: %v", err)
+	}
+	roundTrip(t, Sub(s3, "icons"))
+
+	if err := s3.Put(context.Background(), "icons/a.png", []byte("x"), ""); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if _, ok := objects["/hearth/prod/icons/a.png"]; !ok {
+		t.Fatalf("expected path-style key with prefix, got %v", objects)
+	}
+}
+
+func TestWebDAVBackend(t *testing.T) {
+	dav := &webdav.Handler{FileSystem: webdav.NewMemFS(), LockSystem: webdav.NewMemLS()}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if u, p, ok := r.BasicAuth(); !ok || u != "hearth" || p != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		dav.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	if err := dav.FileSystem.Mkdir(context.Background(), "/dav", 0o755); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+	d, err := NewWebDAV(WebDAVConfig{URL: srv.URL + "/dav/", Username: "hearth", Password: "secret"})
+	if err != nil {
+		t.Fatalf("NewWebDAV: %v", err)
+	}
+	roundTrip(t, Sub(d, "backups/prod"))
+
+	if err := d.Put(context.Background(), "a/b/c.json", []byte("{}"), ""); err != nil {
+		t.Fatalf("Put nested: %v", err)
+	}
+	if list, err := d.List(context.Background(), "a/"); err != nil || len(list) != 1 || list[0].Key != "a/b/c.json" {
+		t.Fatalf("List nested: got %+v (%v)", list, err)
+	}
+	if err := d.Delete(context.Background(), "missing.json"); err != nil {
+		t.Fatalf("Delete missing: %v", err)
+	}
+}
+
+// fakeSFTP runs sftp batches against a local directory.
+const fakeSFTP = `#!/bin/sh
+echo "$@" > "$FAKE_SFTP_ROOT/../args"
+cd "$FAKE_SFTP_ROOT" || exit 1
+while read -r cmd x y; do
+  x=$(printf %s "$x" | tr -d '"'); y=$(printf %s "$y" | tr -d '"')
+  echo "sftp> $cmd $x $y"
+  case "$cmd" in
+  -mkdir) mkdir "$x" 2>/dev/null ;;
+  put) cp "$x" "$y" || exit 1 ;;
+  get) [ -f "$x" ] || { echo "File \"$x\" not found." >&2; exit 1; }; cp "$x" "$y" ;;
+  rm) rm "$x" 2>/dev/null || { echo "Couldn't delete file: No such file or directory" >&2; exit 1; } ;;
+  ls) [ -e "$y" ] || { echo "Can't ls: \"$y\" not found" >&2; exit 1; }; ls -ln "$y" ;;
+  esac
+done
+`
+
+func TestSFTPBackend(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "home")
+	if err := os.Mkdir(root, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	bin := filepath.Join(dir, "sftp")
+	if err := os.WriteFile(bin, []byte(fakeSFTP), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	old := SFTPCommand
+	SFTPCommand = bin
+	t.Cleanup(func() { SFTPCommand = old })
+	t.Setenv("FAKE_SFTP_ROOT", root)
+
+	s, err := NewSFTP(SFTPConfig{Host: "nas.lan", Port: 2222, User: "hearth", PrivateKey: "KEY", HostKey: "nas.lan ssh-ed25519 AAAA"})
+	if err != nil {
+		t.Fatalf("NewSFTP: %v", err)
+	}
+	roundTrip(t, Sub(s, "backups"))
+
+	args, _ := os.ReadFile(filepath.Join(dir, "args"))
+	for _, want := range []string{"-P 2222", "BatchMode=yes", "StrictHostKeyChecking=yes", "-- hearth@nas.lan"} {
+		if !strings.Contains(string(args), want) {
+			t.Fatalf("sftp args %q lack %q", args, want)
+		}
+	}
+	if _, err := NewSFTP(SFTPConfig{Host: "-oProxyCommand=x"}); err == nil {
+		t.Fatal("expected an option-like host to be rejected")
+	}
+}
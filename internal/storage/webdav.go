@@ -0,0 +1,280 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WebDAVConfig configures a WebDAV backend (Nextcloud, ownCloud, a NAS, ...).
+type WebDAVConfig struct {
+	URL      string // collection objects are stored under, e.g. https://nas.lan/dav/hearth
+	Username string
+	Password string
+	Client   *http.Client
+}
+
+// WebDAV stores objects as files below a WebDAV collection, creating
+// collections for the directories of keys as needed.
+type WebDAV struct {
+	cfg    WebDAVConfig
+	base   *url.URL
+	client *http.Client
+}
+
+func NewWebDAV(cfg WebDAVConfig) (*WebDAV, error) {
+	u, err := url.Parse(strings.TrimRight(cfg.URL, "/"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("storage: invalid webdav url %q", cfg.URL)
+	}
+	c := cfg.Client
+	if c == nil {
+		c = &http.Client{Timeout: 60 * time.Second}
+	}
+	return &WebDAV{cfg: cfg, base: u, client: c}, nil
+}
+
+// url returns the URL of the relative path p, a key or a directory.
+func (d *WebDAV) url(p string) string {
+	u := *d.base
+	u.Path = strings.TrimRight(u.Path, "/") + "/" + p
+	u.RawPath = ""
+	return u.String()
+}
+
+func (d *WebDAV) do(ctx context.Context, method, p string, body []byte, header map[string]string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, d.url(p), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body == nil {
+		req.Body = http.NoBody
+		req.ContentLength = 0
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	if d.cfg.Username != "" || d.cfg.Password != "" {
+		req.SetBasicAuth(d.cfg.Username, d.cfg.Password)
+	}
+	req.Header.Set("User-Agent", "Hearth/0.1")
+	return d.client.Do(req)
+}
+
+func (d *WebDAV) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	k, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	if contentType == "" {
+		contentType = contentTypeFor(k)
+	}
+	if err := d.mkdirs(ctx, path.Dir(k)); err != nil {
+		return err
+	}
+	resp, err := d.do(ctx, http.MethodPut, k, data, map[string]string{"Content-Type": contentType})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkWebDAVStatus(resp)
+}
+
+// mkdirs creates the collections of dir, outermost first. Existing ones
+// answer 405.
+func (d *WebDAV) mkdirs(ctx context.Context, dir string) error {
+	if dir == "." || dir == "" {
+		return nil
+	}
+	cur := ""
+	for _, part := range strings.Split(dir, "/") {
+		cur += part + "/"
+		resp, err := d.do(ctx, "MKCOL", cur, nil, nil)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusMethodNotAllowed {
+			if err := checkWebDAVStatus(resp); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (d *WebDAV) Get(ctx context.Context, key string) (io.ReadCloser, Info, error) {
+	k, err := cleanKey(key)
+	if err != nil {
+		return nil, Info{}, err
+	}
+	resp, err := d.do(ctx, http.MethodGet, k, nil, nil)
+	if err != nil {
+		return nil, Info{}, err
+	}
+	if err := checkWebDAVStatus(resp); err != nil {
+		resp.Body.Close()
+		return nil, Info{}, err
+	}
+	return resp.Body, infoFromHeaders(resp, k), nil
+}
+
+func (d *WebDAV) Stat(ctx context.Context, key string) (Info, error) {
+	k, err := cleanKey(key)
+	if err != nil {
+		return Info{}, err
+	}
+	resp, err := d.do(ctx, http.MethodHead, k, nil, nil)
+	if err != nil {
+		return Info{}, err
+	}
+	defer resp.Body.Close()
+	if err := checkWebDAVStatus(resp); err != nil {
+		return Info{}, err
+	}
+	return infoFromHeaders(resp, k), nil
+}
+
+func (d *WebDAV) Delete(ctx context.Context, key string) error {
+	k, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	resp, err := d.do(ctx, http.MethodDelete, k, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return checkWebDAVStatus(resp)
+}
+
+// multistatus is the PROPFIND response.
+type multistatus struct {
+	Responses []struct {
+		Href     string `xml:"href"`
+		Propstat []struct {
+			Prop struct {
+				ContentLength string `xml:"getcontentlength"`
+				LastModified  string `xml:"getlastmodified"`
+				ResourceType  struct {
+					Collection *struct{} `xml:"collection"`
+				} `xml:"resourcetype"`
+			} `xml:"prop"`
+			Status string `xml:"status"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<propfind xmlns="DAV:"><prop><resourcetype/><getcontentlength/><getlastmodified/></prop></propfind>`
+
+// List walks the collections below the directory of prefix one level at a
+// time, since many servers refuse Depth: infinity.
+func (d *WebDAV) List(ctx context.Context, prefix string) ([]Object, error) {
+	dir := prefix
+	if !strings.HasSuffix(dir, "/") {
+		dir = path.Dir(dir) + "/"
+	}
+	if dir == "./" || dir == "/" {
+		dir = ""
+	}
+	out := []Object{}
+	todo := []string{dir}
+	for len(todo) > 0 {
+		cur := todo[0]
+		todo = todo[1:]
+		entries, err := d.propfind(ctx, cur)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if e.dir {
+				if strings.HasPrefix(e.Key, prefix) || strings.HasPrefix(prefix, e.Key) {
+					todo = append(todo, e.Key)
+				}
+			} else if strings.HasPrefix(e.Key, prefix) {
+				out = append(out, e.Object)
+			}
+		}
+	}
+	return out, nil
+}
+
+type davEntry struct {
+	Object
+	dir bool
+}
+
+// propfind lists the members of the collection dir; directory keys end in /.
+func (d *WebDAV) propfind(ctx context.Context, dir string) ([]davEntry, error) {
+	resp, err := d.do(ctx, "PROPFIND", dir, []byte(propfindBody), map[string]string{"Depth": "1", "Content-Type": "application/xml"})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := checkWebDAVStatus(resp); err != nil {
+		return nil, err
+	}
+	var ms multistatus
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 32<<20)).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("webdav: parse propfind: %w", err)
+	}
+	basePath := strings.TrimRight(d.base.Path, "/") + "/"
+	var out []davEntry
+	for _, r := range ms.Responses {
+		u, err := url.Parse(r.Href)
+		if err != nil {
+			continue
+		}
+		key, ok := strings.CutPrefix(u.Path, basePath)
+		if !ok || key == dir || key == strings.TrimSuffix(dir, "/") {
+			continue // the collection itself
+		}
+		var e davEntry
+		for _, ps := range r.Propstat {
+			if !strings.Contains(ps.Status, " 200 ") {
+				continue
+			}
+			p := ps.Prop
+			e.dir = e.dir || p.ResourceType.Collection != nil
+			if n, err := strconv.ParseInt(p.ContentLength, 10, 64); err == nil {
+				e.Size = n
+			}
+			if t, err := http.ParseTime(p.LastModified); err == nil {
+				e.ModTime = t
+			}
+		}
+		if e.dir && !strings.HasSuffix(key, "/") {
+			key += "/"
+		}
+		e.Key = key
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+func checkWebDAVStatus(resp *http.Response) error {
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("webdav: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
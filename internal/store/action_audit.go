@@ -0,0 +1,60 @@
+package store
+
+import "time"
+
+// maxActionAudit is how many audit entries are kept.
+const maxActionAudit = 1000
+
+// ActionAudit records one run of an app quick action.
+type ActionAudit struct {
+	ID        int64  `json:"id"`
+	AppID     string `json:"appId"`
+	ActionID  string `json:"actionId"`
+	Label     string `json:"label"`
+	Kind      string `json:"kind"`
+	OK        bool   `json:"ok"`
+	Detail    string `json:"detail,omitempty"`
+	RemoteIP  string `json:"remoteIp,omitempty"`
+	CreatedAt int64  `json:"createdAt"`
+}
+
+// RecordActionAudit appends e to the audit trail, dropping the oldest
+// entries beyond maxActionAudit, and returns it with its id.
+func (s *Store) RecordActionAudit(e ActionAudit) (ActionAudit, error) {
+	e.CreatedAt = time.Now().Unix()
+	res, err := s.db.Exec(`INSERT INTO action_audit (app_id, action_id, label, kind, ok, detail, remote_ip, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.AppID, e.ActionID, e.Label, e.Kind, boolInt(e.OK), e.Detail, e.RemoteIP, e.CreatedAt)
+	if err != nil {
+		return ActionAudit{}, err
+	}
+	if e.ID, err = res.LastInsertId(); err != nil {
+		return ActionAudit{}, err
+	}
+	_, err = s.db.Exec(`DELETE FROM action_audit WHERE id <= ?`, e.ID-maxActionAudit)
+	return e, err
+}
+
+// ListActionAudit returns the newest audit entries first, of one app when
+// appID is set.
+func (s *Store) ListActionAudit(appID string, limit int) ([]ActionAudit, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	rows, err := s.db.Query(`SELECT id, app_id, action_id, label, kind, ok, detail, remote_ip, created_at FROM action_audit
+		WHERE ? = '' OR app_id = ? ORDER BY id DESC LIMIT ?`, appID, appID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []ActionAudit{}
+	for rows.Next() {
+		var e ActionAudit
+		var ok int
+		if err := rows.Scan(&e.ID, &e.AppID, &e.ActionID, &e.Label, &e.Kind, &ok, &e.Detail, &e.RemoteIP, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.OK = ok != 0
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
@@ -2,14 +2,72 @@ package store
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// appColumns are the apps columns scanned by scanApp, in order.
+const appColumns = `id, group_id, name, description, url, internal_url, open_mode, wake_mac, shortcut, actions, preview, archived, icon_path, icon_source, sort_order, created_at, updated_at`
+
+func scanApp(row interface{ Scan(...any) error }) (AppItem, error) {
+	var a AppItem
+	var actions string
+	var preview sql.NullString
+	if err := row.Scan(&a.ID, &a.GroupID, &a.Name, &a.Description, &a.URL, &a.InternalURL, &a.OpenMode, &a.WakeMAC, &a.Shortcut, &actions, &preview, &a.Archived, &a.IconPath, &a.IconSource, &a.SortOrder, &a.CreatedAt, &a.UpdatedAt); err != nil {
+		return AppItem{}, err
+	}
+	a.Actions = decodeActions(actions)
+	a.Preview = decodePreview(preview)
+	return a, nil
+}
+
+// encodeActions is the stored form of an app's actions.
+func encodeActions(list []AppAction) string {
+	if len(list) == 0 {
+		return "[]"
+	}
+	b, err := json.Marshal(list)
+	if err != nil {
+		return "[]"
+	}
+	return string(b)
+}
+
+func decodeActions(raw string) []AppAction {
+	out := []AppAction{}
+	_ = json.Unmarshal([]byte(raw), &out)
+	return out
+}
+
+// encodePreview is the stored form of an app's link preview; nil is NULL.
+func encodePreview(p *LinkPreview) any {
+	if p == nil {
+		return nil
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil
+	}
+	return string(b)
+}
+
+func decodePreview(raw sql.NullString) *LinkPreview {
+	if !raw.Valid || raw.String == "" {
+		return nil
+	}
+	var p LinkPreview
+	if err := json.Unmarshal([]byte(raw.String), &p); err != nil {
+		return nil
+	}
+	return &p
+}
+
 func (s *Store) ListApps() ([]AppItem, error) {
-	rows, err := s.db.Query(`SELECT id, group_id, name, description, url, icon_path, icon_source, sort_order, created_at FROM apps ORDER BY group_id ASC, sort_order ASC, created_at ASC`)
+	rows, err := s.db.Query(`SELECT ` + appColumns + ` FROM apps ORDER BY group_id ASC, sort_order ASC, created_at ASC`)
 	if err != nil {
 		return nil, err
 	}
@@ -17,8 +75,8 @@ func (s *Store) ListApps() ([]AppItem, error) {
 
 	out := make([]AppItem, 0)
 	for rows.Next() {
-		var a AppItem
-		if err := rows.Scan(&a.ID, &a.GroupID, &a.Name, &a.Description, &a.URL, &a.IconPath, &a.IconSource, &a.SortOrder, &a.CreatedAt); err != nil {
+		a, err := scanApp(rows)
+		if err != nil {
 			return nil, err
 		}
 		out = append(out, a)
@@ -26,24 +84,63 @@ func (s *Store) ListApps() ([]AppItem, error) {
 	return out, rows.Err()
 }
 
-func (s *Store) CreateApp(groupID *string, name string, description *string, url string, iconPath, iconSource *string) (AppItem, error) {
-	now := time.Now().Unix()
-	id := uuid.NewString()
+// ListRecentApps returns up to limit apps, widgets and archived apps aside,
+// newest first by
+// creation time, or by last update when byUpdate is set.
+func (s *Store) ListRecentApps(byUpdate bool, limit int) ([]AppItem, error) {
+	order := `created_at DESC`
+	if byUpdate {
+		order = `updated_at DESC, created_at DESC`
+	}
+	rows, err := s.db.Query(`SELECT `+appColumns+` FROM apps WHERE url NOT LIKE 'widget:%' AND archived = 0 ORDER BY `+order+`, id LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	var nextOrder int
-	_ = s.db.QueryRow(`SELECT COALESCE(MAX(sort_order), 0) + 1 FROM apps WHERE group_id IS ?`, groupID).Scan(&nextOrder)
+	out := make([]AppItem, 0)
+	for rows.Next() {
+		a, err := scanApp(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// CreateApp inserts a at the end of its group. ID, SortOrder, CreatedAt and
+// UpdatedAt are assigned here; an empty OpenMode stores the default.
+func (s *Store) CreateApp(a AppItem) (AppItem, error) {
+	a.ID = uuid.NewString()
+	a.CreatedAt = time.Now().Unix()
+	a.UpdatedAt = a.CreatedAt
+	if a.OpenMode == "" {
+		a.OpenMode = DefaultOpenMode
+	}
+
+	_ = s.db.QueryRow(`SELECT COALESCE(MAX(sort_order), 0) + 1 FROM apps WHERE group_id IS ?`, a.GroupID).Scan(&a.SortOrder)
 
-	_, err := s.db.Exec(`INSERT INTO apps (id, group_id, name, description, url, icon_path, icon_source, sort_order, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		id, groupID, name, description, url, iconPath, iconSource, nextOrder, now,
+	_, err := s.db.Exec(`INSERT INTO apps (`+appColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		a.ID, a.GroupID, a.Name, a.Description, a.URL, a.InternalURL, a.OpenMode, a.WakeMAC, a.Shortcut, encodeActions(a.Actions), encodePreview(a.Preview), a.Archived, a.IconPath, a.IconSource, a.SortOrder, a.CreatedAt, a.UpdatedAt,
 	)
 	if err != nil {
 		return AppItem{}, err
 	}
-	return AppItem{ID: id, GroupID: groupID, Name: name, Description: description, URL: url, IconPath: iconPath, IconSource: iconSource, SortOrder: nextOrder, CreatedAt: now}, nil
+	if a.Actions == nil {
+		a.Actions = []AppAction{}
+	}
+	return a, nil
 }
 
-func (s *Store) UpdateApp(id string, groupID *string, name string, description *string, url string, iconPath, iconSource *string) error {
-	res, err := s.db.Exec(`UPDATE apps SET group_id = ?, name = ?, description = ?, url = ?, icon_path = ?, icon_source = ? WHERE id = ?`, groupID, name, description, url, iconPath, iconSource, id)
+// UpdateApp saves every field of a except its sort order and creation time,
+// and stamps the update time.
+func (s *Store) UpdateApp(a AppItem) error {
+	if a.OpenMode == "" {
+		a.OpenMode = DefaultOpenMode
+	}
+	res, err := s.db.Exec(`UPDATE apps SET group_id = ?, name = ?, description = ?, url = ?, internal_url = ?, open_mode = ?, wake_mac = ?, shortcut = ?, actions = ?, icon_path = ?, icon_source = ?, updated_at = ? WHERE id = ?`,
+		a.GroupID, a.Name, a.Description, a.URL, a.InternalURL, a.OpenMode, a.WakeMAC, a.Shortcut, encodeActions(a.Actions), a.IconPath, a.IconSource, time.Now().Unix(), a.ID)
 	if err != nil {
 		return err
 	}
@@ -54,9 +151,33 @@ func (s *Store) UpdateApp(id string, groupID *string, name string, description *
 	return nil
 }
 
+// SetAppPreview stores the link preview of app id, or clears it when p is
+// nil. It reports whether the app exists; the update time is left alone.
+func (s *Store) SetAppPreview(id string, p *LinkPreview) (bool, error) {
+	res, err := s.db.Exec(`UPDATE apps SET preview = ? WHERE id = ?`, encodePreview(p), id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// SetAppArchived archives or restores app id. It reports whether the app
+// exists; the update time is left alone.
+func (s *Store) SetAppArchived(id string, archived bool) (bool, error) {
+	res, err := s.db.Exec(`UPDATE apps SET archived = ? WHERE id = ?`, archived, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
 func (s *Store) DeleteApp(id string) error {
-	_, err := s.db.Exec(`DELETE FROM apps WHERE id = ?`, id)
-	return err
+	if _, err := s.db.Exec(`DELETE FROM apps WHERE id = ?`, id); err != nil {
+		return err
+	}
+	return s.DeleteWidgetConfig(id)
 }
 
 func (s *Store) ReorderApps(groupID *string, ids []string) error {
@@ -80,20 +201,133 @@ func (s *Store) ReorderApps(groupID *string, ids []string) error {
 	return tx.Commit()
 }
 
-func (s *Store) MoveGroupAppsToUngrouped(groupID string) error {
-	_, err := s.db.Exec(`UPDATE apps SET group_id = NULL WHERE group_id = ?`, groupID)
-	return err
+// MoveApp puts app id at position index of group groupID (nil being
+// ungrouped) and renumbers the groups it leaves and joins, in one
+// transaction. index counts unarchived apps only and is clamped to the
+// group. It reports whether the app exists.
+func (s *Store) MoveApp(id string, groupID *string, index int) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var from sql.NullString
+	if err := tx.QueryRow(`SELECT group_id FROM apps WHERE id = ?`, id).Scan(&from); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	ids, archived, err := groupAppIDs(tx, groupID, id)
+	index = max(index, 0)
+	if err != nil {
+		return false, err
+	}
+	pos := len(ids)
+	for i, visible := 0, 0; i < len(ids); i++ {
+		if archived[i] {
+			continue
+		}
+		if visible == index {
+			pos = i
+			break
+		}
+		visible++
+	}
+	if _, err := tx.Exec(`UPDATE apps SET group_id = ? WHERE id = ?`, groupID, id); err != nil {
+		return false, err
+	}
+	if err := renumberApps(tx, slices.Insert(ids, pos, id)); err != nil {
+		return false, err
+	}
+	if from.Valid != (groupID != nil) || (groupID != nil && from.String != *groupID) {
+		var fromID *string
+		if from.Valid {
+			fromID = &from.String
+		}
+		rest, _, err := groupAppIDs(tx, fromID, id)
+		if err != nil {
+			return false, err
+		}
+		if err := renumberApps(tx, rest); err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit()
+}
+
+// groupAppIDs returns the apps of a group in order, except the app except,
+// along with whether each is archived.
+func groupAppIDs(tx *sql.Tx, groupID *string, except string) ([]string, []bool, error) {
+	rows, err := tx.Query(`SELECT id, archived FROM apps WHERE group_id IS ? AND id != ? ORDER BY sort_order ASC, created_at ASC`, groupID, except)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	var ids []string
+	var archived []bool
+	for rows.Next() {
+		var id string
+		var a bool
+		if err := rows.Scan(&id, &a); err != nil {
+			return nil, nil, err
+		}
+		ids, archived = append(ids, id), append(archived, a)
+	}
+	return ids, archived, rows.Err()
 }
 
-func (s *Store) DeleteAppsByGroupID(groupID string) error {
-	_, err := s.db.Exec(`DELETE FROM apps WHERE group_id = ?`, groupID)
-	return err
+func renumberApps(tx *sql.Tx, ids []string) error {
+	for i, id := range ids {
+		if _, err := tx.Exec(`UPDATE apps SET sort_order = ? WHERE id = ?`, i+1, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MoveGroupApps moves the apps of group from, in order, to the end of group
+// to (nil being ungrouped) and returns how many were moved.
+func (s *Store) MoveGroupApps(from string, to *string) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var offset int
+	if err := tx.QueryRow(`SELECT COALESCE(MAX(sort_order), 0) FROM apps WHERE group_id IS ?`, to).Scan(&offset); err != nil {
+		return 0, err
+	}
+	res, err := tx.Exec(`UPDATE apps SET group_id = ?, sort_order = sort_order + ? WHERE group_id = ?`, to, offset, from)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(n), tx.Commit()
+}
+
+// DeleteAppsByGroupID deletes the apps of a group and returns how many
+// there were.
+func (s *Store) DeleteAppsByGroupID(groupID string) (int, error) {
+	res, err := s.db.Exec(`DELETE FROM apps WHERE group_id = ?`, groupID)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	_, err = s.db.Exec(`DELETE FROM widget_configs WHERE app_id NOT IN (SELECT id FROM apps)`)
+	return int(n), err
 }
 
 func (s *Store) AppByID(id string) (AppItem, bool, error) {
-	var a AppItem
-	err := s.db.QueryRow(`SELECT id, group_id, name, description, url, icon_path, icon_source, sort_order, created_at FROM apps WHERE id = ?`, id).
-		Scan(&a.ID, &a.GroupID, &a.Name, &a.Description, &a.URL, &a.IconPath, &a.IconSource, &a.SortOrder, &a.CreatedAt)
+	a, err := scanApp(s.db.QueryRow(`SELECT `+appColumns+` FROM apps WHERE id = ?`, id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return AppItem{}, false, nil
@@ -0,0 +1,130 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// BackgroundHistoryEntry is one image fetched for a background cache key.
+type BackgroundHistoryEntry struct {
+	ID        int64
+	CacheKey  string
+	FilePath  string
+	SourceURL string
+	// Attribution reported by the provider, if any.
+	Title     string
+	Credit    string
+	Link      string
+	FetchedAt int64
+}
+
+const backgroundHistoryColumns = `id, cache_key, file_path, source_url, title, credit, link, fetched_at`
+
+func scanBackgroundHistory(sc interface{ Scan(...any) error }) (BackgroundHistoryEntry, error) {
+	var e BackgroundHistoryEntry
+	err := sc.Scan(&e.ID, &e.CacheKey, &e.FilePath, &e.SourceURL, &e.Title, &e.Credit, &e.Link, &e.FetchedAt)
+	return e, err
+}
+
+// ListBackgroundHistory returns the images kept for cacheKey, newest first.
+func (s *Store) ListBackgroundHistory(cacheKey string) ([]BackgroundHistoryEntry, error) {
+	rows, err := s.db.Query(`SELECT `+backgroundHistoryColumns+` FROM background_history
+		WHERE cache_key = ? ORDER BY fetched_at DESC, id DESC`, cacheKey)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []BackgroundHistoryEntry
+	for rows.Next() {
+		e, err := scanBackgroundHistory(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// GetBackgroundHistory returns the history entry of filePath for cacheKey.
+func (s *Store) GetBackgroundHistory(cacheKey, filePath string) (BackgroundHistoryEntry, bool, error) {
+	e, err := scanBackgroundHistory(s.db.QueryRow(`SELECT `+backgroundHistoryColumns+` FROM background_history
+		WHERE cache_key = ? AND file_path = ?`, cacheKey, filePath))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return BackgroundHistoryEntry{}, false, nil
+		}
+		return BackgroundHistoryEntry{}, false, err
+	}
+	return e, true, nil
+}
+
+// AddBackgroundHistory records a fetched image (moving it to the front if it
+// is already there) and keeps only the newest keep entries of its cache key.
+// It returns the file paths of dropped entries that nothing references
+// anymore, so the caller can delete the files.
+func (s *Store) AddBackgroundHistory(e BackgroundHistoryEntry, keep int) ([]string, error) {
+	if keep < 1 {
+		keep = 1
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now().Unix()
+	if _, err := tx.Exec(`DELETE FROM background_history WHERE cache_key = ? AND file_path = ?`, e.CacheKey, e.FilePath); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`INSERT INTO background_history (cache_key, file_path, source_url, title, credit, link, fetched_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		e.CacheKey, e.FilePath, e.SourceURL, e.Title, e.Credit, e.Link, now); err != nil {
+		return nil, err
+	}
+	cacheKey := e.CacheKey
+
+	rows, err := tx.Query(`SELECT id, file_path FROM background_history WHERE cache_key = ?
+		ORDER BY fetched_at DESC, id DESC LIMIT -1 OFFSET ?`, cacheKey, keep)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int64
+	var paths []string
+	for rows.Next() {
+		var id int64
+		var p string
+		if err := rows.Scan(&id, &p); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+		paths = append(paths, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		if _, err := tx.Exec(`DELETE FROM background_history WHERE id = ?`, id); err != nil {
+			return nil, err
+		}
+	}
+
+	var removed []string
+	for _, p := range paths {
+		var n int
+		if err := tx.QueryRow(`SELECT
+			(SELECT COUNT(*) FROM background_history WHERE file_path = ?) +
+			(SELECT COUNT(*) FROM background_cache WHERE file_path = ?)`, p, p).Scan(&n); err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			removed = append(removed, p)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
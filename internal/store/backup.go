@@ -12,6 +12,20 @@ type Export struct {
 	Settings map[string]string `json:"settings"`
 	Groups   []Group           `json:"groups"`
 	Apps     []AppItem         `json:"apps"`
+	Holdings []Holding         `json:"holdings,omitempty"`
+	Events   []CustomEvent     `json:"events,omitempty"`
+	Quotes   []CustomQuote     `json:"quotes,omitempty"`
+	// Scopes lists what a partial export holds; empty for full backups.
+	Scopes []string `json:"scopes,omitempty"`
+	// Users are users' own settings, by username. Only partial exports
+	// carry them, and the store doesn't import them.
+	Users []UserExport `json:"users,omitempty"`
+}
+
+// UserExport is one user's own settings in an export.
+type UserExport struct {
+	Username string            `json:"username"`
+	Settings map[string]string `json:"settings"`
 }
 
 func (s *Store) ExportAll() (Export, error) {
@@ -26,6 +40,14 @@ func (s *Store) ExportAll() (Export, error) {
 			_ = rows.Close()
 			return Export{}, err
 		}
+		if isCryptoKey(k) {
+			continue
+		}
+		// Backups carry secrets in plain text so they restore anywhere.
+		if v, err = s.open(v); err != nil {
+			_ = rows.Close()
+			return Export{}, err
+		}
 		settings[k] = v
 	}
 	_ = rows.Close()
@@ -38,6 +60,18 @@ func (s *Store) ExportAll() (Export, error) {
 	if err != nil {
 		return Export{}, err
 	}
+	holdings, err := s.ListHoldings()
+	if err != nil {
+		return Export{}, err
+	}
+	events, err := s.ListCustomEvents()
+	if err != nil {
+		return Export{}, err
+	}
+	quotes, err := s.ListCustomQuotes()
+	if err != nil {
+		return Export{}, err
+	}
 
 	return Export{
 		Version:  2,
@@ -45,18 +79,56 @@ func (s *Store) ExportAll() (Export, error) {
 		Settings: settings,
 		Groups:   groups,
 		Apps:     apps,
+		Holdings: holdings,
+		Events:   events,
+		Quotes:   quotes,
 	}, nil
 }
 
+// ImportAll merges payload into the database, upserting by primary key.
 func (s *Store) ImportAll(payload Export) error {
+	return s.importPayload(payload, false)
+}
+
+// ReplaceAll makes payload the whole configuration: settings, groups, apps,
+// holdings, events and quotes missing from it are removed. Credentials and
+// caches are left alone.
+func (s *Store) ReplaceAll(payload Export) error {
+	return s.importPayload(payload, true)
+}
+
+func (s *Store) importPayload(payload Export, replace bool) error {
 	tx, err := s.db.Begin()
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
+	if replace {
+		for _, stmt := range []string{
+			`DELETE FROM widget_configs`,
+			`DELETE FROM apps`,
+			`DELETE FROM groups`,
+			`DELETE FROM kv WHERE key NOT LIKE 'crypto.%'`,
+			`DELETE FROM holdings`,
+			`DELETE FROM custom_events`,
+			`DELETE FROM custom_quotes`,
+		} {
+			if _, err := tx.Exec(stmt); err != nil {
+				return err
+			}
+		}
+	}
+
 	// Settings
 	for k, v := range payload.Settings {
+		if isCryptoKey(k) {
+			continue
+		}
+		v, err := s.sealKV(k, v)
+		if err != nil {
+			return err
+		}
 		if _, err := tx.Exec(`INSERT INTO kv (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value=excluded.value`, k, v); err != nil {
 			return err
 		}
@@ -77,9 +149,52 @@ func (s *Store) ImportAll(payload Export) error {
 
 	// Apps
 	for _, a := range payload.Apps {
-		_, err := tx.Exec(`INSERT INTO apps (id, group_id, name, description, url, icon_path, icon_source, sort_order, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-			ON CONFLICT(id) DO UPDATE SET group_id=excluded.group_id, name=excluded.name, description=excluded.description, url=excluded.url, icon_path=excluded.icon_path, icon_source=excluded.icon_source, sort_order=excluded.sort_order`,
-			a.ID, a.GroupID, a.Name, a.Description, a.URL, a.IconPath, a.IconSource, a.SortOrder, a.CreatedAt)
+		openMode := a.OpenMode
+		if openMode == "" {
+			openMode = DefaultOpenMode
+		}
+		updatedAt := a.UpdatedAt
+		if updatedAt == 0 {
+			updatedAt = a.CreatedAt
+		}
+		_, err := tx.Exec(`INSERT INTO apps (`+appColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(id) DO UPDATE SET group_id=excluded.group_id, name=excluded.name, description=excluded.description, url=excluded.url, internal_url=excluded.internal_url, open_mode=excluded.open_mode, wake_mac=excluded.wake_mac, shortcut=excluded.shortcut, actions=excluded.actions, preview=excluded.preview, archived=excluded.archived, icon_path=excluded.icon_path, icon_source=excluded.icon_source, sort_order=excluded.sort_order, updated_at=excluded.updated_at`,
+			a.ID, a.GroupID, a.Name, a.Description, a.URL, a.InternalURL, openMode, a.WakeMAC, a.Shortcut, encodeActions(a.Actions), encodePreview(a.Preview), a.Archived, a.IconPath, a.IconSource, a.SortOrder, a.CreatedAt, updatedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Holdings
+	for _, h := range payload.Holdings {
+		_, err := tx.Exec(`INSERT INTO holdings (symbol, quantity, cost_basis, sort_order, updated_at) VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT(symbol) DO UPDATE SET quantity=excluded.quantity, cost_basis=excluded.cost_basis, sort_order=excluded.sort_order, updated_at=excluded.updated_at`,
+			h.Symbol, h.Quantity, h.CostBasis, h.SortOrder, h.UpdatedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Custom events
+	for _, e := range payload.Events {
+		_, err := tx.Exec(`INSERT INTO custom_events (id, name, kind, month, day, year, lunar, sort_order, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(id) DO UPDATE SET name=excluded.name, kind=excluded.kind, month=excluded.month, day=excluded.day, year=excluded.year, lunar=excluded.lunar, sort_order=excluded.sort_order, updated_at=excluded.updated_at`,
+			e.ID, e.Name, e.Kind, e.Month, e.Day, e.Year, boolInt(e.Lunar), e.SortOrder, e.UpdatedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Imported descriptions replace the widget configurations.
+	if err := migrateWidgetConfigs(tx, true); err != nil {
+		return err
+	}
+
+	// Custom quotes
+	for _, q := range payload.Quotes {
+		_, err := tx.Exec(`INSERT INTO custom_quotes (id, text, author, created_at) VALUES (?, ?, ?, ?)
+			ON CONFLICT(id) DO UPDATE SET text=excluded.text, author=excluded.author, created_at=excluded.created_at`,
+			q.ID, q.Text, q.Author, q.CreatedAt)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,131 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// BackupTarget is a remote destination scheduled backups are uploaded to.
+// Config holds the kind's settings as JSON; Secret, the password or private
+// key, is sealed at rest.
+type BackupTarget struct {
+	ID              int64
+	Name            string
+	Kind            string // s3, webdav or sftp
+	Config          string
+	Secret          string
+	Keep            int // backups kept on the target; older ones are pruned
+	IntervalSeconds int64
+	Enabled         bool
+	CreatedAt       int64
+	// LastAttemptAt is the latest run, LastSuccessAt the latest that
+	// uploaded a backup and LastError why the latest run failed.
+	LastAttemptAt int64
+	LastSuccessAt int64
+	LastError     string
+}
+
+const backupTargetColumns = `id, name, kind, config, secret, keep, interval_seconds, enabled, created_at, last_attempt_at, last_success_at, last_error`
+
+func (s *Store) scanBackupTarget(row interface{ Scan(...any) error }) (BackupTarget, error) {
+	var t BackupTarget
+	var enabled int
+	if err := row.Scan(&t.ID, &t.Name, &t.Kind, &t.Config, &t.Secret, &t.Keep, &t.IntervalSeconds, &enabled,
+		&t.CreatedAt, &t.LastAttemptAt, &t.LastSuccessAt, &t.LastError); err != nil {
+		return BackupTarget{}, err
+	}
+	secret, err := s.open(t.Secret)
+	if err != nil {
+		return BackupTarget{}, err
+	}
+	t.Secret = secret
+	t.Enabled = enabled != 0
+	return t, nil
+}
+
+// ListBackupTargets returns all backup targets in the order they were
+// created.
+func (s *Store) ListBackupTargets() ([]BackupTarget, error) {
+	rows, err := s.db.Query(`SELECT ` + backupTargetColumns + ` FROM backup_targets ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []BackupTarget{}
+	for rows.Next() {
+		t, err := s.scanBackupTarget(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+// GetBackupTarget returns the backup target with the given id.
+func (s *Store) GetBackupTarget(id int64) (BackupTarget, bool, error) {
+	t, err := s.scanBackupTarget(s.db.QueryRow(`SELECT `+backupTargetColumns+` FROM backup_targets WHERE id = ?`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return BackupTarget{}, false, nil
+		}
+		return BackupTarget{}, false, err
+	}
+	return t, true, nil
+}
+
+// CreateBackupTarget adds a backup target and returns it with its id.
+func (s *Store) CreateBackupTarget(t BackupTarget) (BackupTarget, error) {
+	t.CreatedAt = time.Now().Unix()
+	t.LastAttemptAt, t.LastSuccessAt, t.LastError = 0, 0, ""
+	secret, err := s.seal(t.Secret)
+	if err != nil {
+		return BackupTarget{}, err
+	}
+	res, err := s.db.Exec(`INSERT INTO backup_targets (name, kind, config, secret, keep, interval_seconds, enabled, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		t.Name, t.Kind, t.Config, secret, t.Keep, t.IntervalSeconds, boolInt(t.Enabled), t.CreatedAt)
+	if err != nil {
+		return BackupTarget{}, err
+	}
+	t.ID, err = res.LastInsertId()
+	return t, err
+}
+
+// UpdateBackupTarget saves the settings of an existing backup target, leaving
+// how its runs went alone. It reports whether the target exists.
+func (s *Store) UpdateBackupTarget(t BackupTarget) (bool, error) {
+	secret, err := s.seal(t.Secret)
+	if err != nil {
+		return false, err
+	}
+	res, err := s.db.Exec(`UPDATE backup_targets SET name = ?, kind = ?, config = ?, secret = ?, keep = ?, interval_seconds = ?, enabled = ? WHERE id = ?`,
+		t.Name, t.Kind, t.Config, secret, t.Keep, t.IntervalSeconds, boolInt(t.Enabled), t.ID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// DeleteBackupTarget removes a backup target; the backups on it stay. It
+// reports whether the target existed.
+func (s *Store) DeleteBackupTarget(id int64) (bool, error) {
+	res, err := s.db.Exec(`DELETE FROM backup_targets WHERE id = ?`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// RecordBackupRun records a run of the backup target at at; an empty errMsg
+// means it succeeded.
+func (s *Store) RecordBackupRun(id int64, at int64, errMsg string) error {
+	if errMsg == "" {
+		_, err := s.db.Exec(`UPDATE backup_targets SET last_attempt_at = ?, last_success_at = ?, last_error = '' WHERE id = ?`, at, at, id)
+		return err
+	}
+	_, err := s.db.Exec(`UPDATE backup_targets SET last_attempt_at = ?, last_error = ? WHERE id = ?`, at, errMsg, id)
+	return err
+}
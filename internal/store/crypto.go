@@ -0,0 +1,237 @@
+package store
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Secrets at rest. With a database key, webhook secrets, agent tokens, backup
+// target credentials and the settings named sensitive are sealed with AES-GCM
+// before they are written, so a copy of the database file doesn't give them
+// away. Everything else, including the schema, stays readable.
+const (
+	encPrefix = "enc:v1:"
+	// kvCryptoPrefix namespaces the key material kept in kv; it survives
+	// resets and is never exported.
+	kvCryptoPrefix = "crypto."
+	kvCryptoSalt   = kvCryptoPrefix + "salt"
+	kvCryptoCheck  = kvCryptoPrefix + "check"
+	cryptoCheck    = "hearth"
+)
+
+var (
+	// ErrWrongKey means the configured key isn't the one the database was
+	// encrypted with.
+	ErrWrongKey = errors.New("database key does not match the encrypted database")
+	// ErrKeyRequired means the database holds encrypted values but no key
+	// was configured.
+	ErrKeyRequired = errors.New("database is encrypted; a database key is required")
+)
+
+// sealer holds the key derived from the database passphrase.
+type sealer struct {
+	master    []byte
+	aead      cipher.AEAD
+	sensitive map[string]bool // kv keys sealed at rest
+}
+
+// EnableEncryption derives the database key from passphrase and encrypts
+// the sensitive values still stored in plain text: webhook secrets, agent
+// tokens, backup target credentials and the kv entries listed in
+// sensitiveKV. An empty passphrase only verifies that the database isn't
+// encrypted. Call it after Migrate and before the store is used.
+func (s *Store) EnableEncryption(passphrase string, sensitiveKV []string) error {
+	check, hasCheck, err := s.getRawKV(kvCryptoCheck)
+	if err != nil {
+		return err
+	}
+	if passphrase == "" {
+		if hasCheck {
+			return ErrKeyRequired
+		}
+		return nil
+	}
+
+	salt, ok, err := s.GetKV(kvCryptoSalt)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return err
+		}
+		salt = hex.EncodeToString(b)
+		if err := s.SetKV(kvCryptoSalt, salt); err != nil {
+			return err
+		}
+	}
+	master, err := scrypt.Key([]byte(passphrase), []byte(salt), 1<<15, 8, 1, 32)
+	if err != nil {
+		return err
+	}
+	block, err := aes.NewCipher(master)
+	if err != nil {
+		return err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	sl := &sealer{master: master, aead: aead, sensitive: map[string]bool{}}
+	for _, k := range sensitiveKV {
+		sl.sensitive[k] = true
+	}
+
+	if hasCheck {
+		if v, err := sl.open(check); err != nil || v != cryptoCheck {
+			return ErrWrongKey
+		}
+	}
+	s.sealer = sl
+	if !hasCheck {
+		sealed, err := sl.seal(cryptoCheck)
+		if err != nil {
+			return err
+		}
+		if err := s.SetKV(kvCryptoCheck, sealed); err != nil {
+			return err
+		}
+	}
+	// Overwrite the plain text left behind by the rewrite below.
+	_, _ = s.db.Exec(`PRAGMA secure_delete = ON`)
+	return s.sealPlaintext()
+}
+
+// SubKey derives a key for purpose from the database key, or returns nil
+// without encryption. The auth service hashes session tokens with one.
+func (s *Store) SubKey(purpose string) []byte {
+	if s.sealer == nil {
+		return nil
+	}
+	mac := hmac.New(sha256.New, s.sealer.master)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// sealPlaintext encrypts sensitive values written before encryption was
+// enabled.
+func (s *Store) sealPlaintext() error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	reseal := func(update, query string, args ...any) error {
+		rows, err := tx.Query(query, args...)
+		if err != nil {
+			return err
+		}
+		type row struct{ id, v string }
+		var todo []row
+		for rows.Next() {
+			var r row
+			if err := rows.Scan(&r.id, &r.v); err != nil {
+				rows.Close()
+				return err
+			}
+			if r.v != "" && !strings.HasPrefix(r.v, encPrefix) {
+				todo = append(todo, r)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		for _, r := range todo {
+			sealed, err := s.sealer.seal(r.v)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.Exec(update, sealed, r.id); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := reseal(`UPDATE webhooks SET secret = ? WHERE id = ?`, `SELECT id, secret FROM webhooks`); err != nil {
+		return err
+	}
+	if err := reseal(`UPDATE metric_hosts SET token = ? WHERE name = ?`, `SELECT name, token FROM metric_hosts`); err != nil {
+		return err
+	}
+	if err := reseal(`UPDATE backup_targets SET secret = ? WHERE id = ?`, `SELECT id, secret FROM backup_targets`); err != nil {
+		return err
+	}
+	for k := range s.sealer.sensitive {
+		if err := reseal(`UPDATE kv SET value = ? WHERE key = ?`, `SELECT key, value FROM kv WHERE key = ?`, k); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// seal encrypts v when encryption is enabled; empty values stay empty.
+func (s *Store) seal(v string) (string, error) {
+	if s.sealer == nil || v == "" {
+		return v, nil
+	}
+	return s.sealer.seal(v)
+}
+
+// open decrypts a value written by seal; plain text passes through.
+func (s *Store) open(v string) (string, error) {
+	if !strings.HasPrefix(v, encPrefix) {
+		return v, nil
+	}
+	if s.sealer == nil {
+		return "", ErrKeyRequired
+	}
+	return s.sealer.open(v)
+}
+
+// sealKV seals v if key is a sensitive kv entry.
+func (s *Store) sealKV(key, v string) (string, error) {
+	if s.sealer == nil || !s.sealer.sensitive[key] {
+		return v, nil
+	}
+	return s.seal(v)
+}
+
+func (sl *sealer) seal(v string) (string, error) {
+	nonce := make([]byte, sl.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	out := sl.aead.Seal(nonce, nonce, []byte(v), nil)
+	return encPrefix + base64.RawStdEncoding.EncodeToString(out), nil
+}
+
+func (sl *sealer) open(v string) (string, error) {
+	b, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(v, encPrefix))
+	if err != nil {
+		return "", fmt.Errorf("decode sealed value: %w", err)
+	}
+	n := sl.aead.NonceSize()
+	if len(b) < n {
+		return "", errors.New("sealed value too short")
+	}
+	plain, err := sl.aead.Open(nil, b[:n], b[n:], nil)
+	if err != nil {
+		return "", ErrWrongKey
+	}
+	return string(plain), nil
+}
+
+// isCryptoKey reports whether a kv key holds key material.
+func isCryptoKey(key string) bool { return strings.HasPrefix(key, kvCryptoPrefix) }
@@ -0,0 +1,55 @@
+package store
+
+import "time"
+
+// ListCustomEvents returns the personal dates ordered as they were saved.
+func (s *Store) ListCustomEvents() ([]CustomEvent, error) {
+	rows, err := s.db.Query(`SELECT id, name, kind, month, day, year, lunar, sort_order, updated_at FROM custom_events ORDER BY sort_order ASC, id ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []CustomEvent{}
+	for rows.Next() {
+		var e CustomEvent
+		var lunar int
+		if err := rows.Scan(&e.ID, &e.Name, &e.Kind, &e.Month, &e.Day, &e.Year, &lunar, &e.SortOrder, &e.UpdatedAt); err != nil {
+			return nil, err
+		}
+		e.Lunar = lunar != 0
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// ReplaceCustomEvents atomically replaces all personal dates. The slice order
+// becomes the sort order.
+func (s *Store) ReplaceCustomEvents(list []CustomEvent) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM custom_events`); err != nil {
+		return err
+	}
+	now := time.Now().Unix()
+	for i, e := range list {
+		if _, err := tx.Exec(
+			`INSERT INTO custom_events (name, kind, month, day, year, lunar, sort_order, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			e.Name, e.Kind, e.Month, e.Day, e.Year, boolInt(e.Lunar), i, now,
+		); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
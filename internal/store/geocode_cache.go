@@ -0,0 +1,42 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// GeocodeCacheEntry is a persisted city resolution, keyed by the normalized
+// query and result language.
+type GeocodeCacheEntry struct {
+	Query       string
+	Language    string
+	Lat         float64
+	Lon         float64
+	DisplayName string
+	Timezone    string
+	CountryCode string
+	FetchedAt   int64
+	ExpiresAt   int64
+}
+
+func (s *Store) GetGeocodeCache(query, language string) (GeocodeCacheEntry, bool, error) {
+	var e GeocodeCacheEntry
+	err := s.db.QueryRow(`SELECT query, language, lat, lon, display_name, timezone, country_code, fetched_at, expires_at FROM geocode_cache WHERE query = ? AND language = ? AND expires_at > ?`, query, language, time.Now().Unix()).
+		Scan(&e.Query, &e.Language, &e.Lat, &e.Lon, &e.DisplayName, &e.Timezone, &e.CountryCode, &e.FetchedAt, &e.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return GeocodeCacheEntry{}, false, nil
+		}
+		return GeocodeCacheEntry{}, false, err
+	}
+	return e, true, nil
+}
+
+func (s *Store) SetGeocodeCache(e GeocodeCacheEntry) error {
+	_, err := s.db.Exec(`INSERT INTO geocode_cache (query, language, lat, lon, display_name, timezone, country_code, fetched_at, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(query, language) DO UPDATE SET lat=excluded.lat, lon=excluded.lon, display_name=excluded.display_name, timezone=excluded.timezone, country_code=excluded.country_code, fetched_at=excluded.fetched_at, expires_at=excluded.expires_at`,
+		e.Query, e.Language, e.Lat, e.Lon, e.DisplayName, e.Timezone, e.CountryCode, e.FetchedAt, e.ExpiresAt,
+	)
+	return err
+}
@@ -86,6 +86,9 @@ func (s *Store) UpdateGroup(id, name string) error {
 }
 
 func (s *Store) DeleteGroup(id string) error {
+	if _, err := s.db.Exec(`DELETE FROM shares WHERE group_id = ?`, id); err != nil {
+		return err
+	}
 	_, err := s.db.Exec(`DELETE FROM groups WHERE id = ?`, id)
 	return err
 }
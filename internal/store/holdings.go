@@ -0,0 +1,46 @@
+package store
+
+import "time"
+
+// ListHoldings returns the portfolio positions ordered as they were saved.
+func (s *Store) ListHoldings() ([]Holding, error) {
+	rows, err := s.db.Query(`SELECT symbol, quantity, cost_basis, sort_order, updated_at FROM holdings ORDER BY sort_order ASC, symbol ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Holding{}
+	for rows.Next() {
+		var h Holding
+		if err := rows.Scan(&h.Symbol, &h.Quantity, &h.CostBasis, &h.SortOrder, &h.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, h)
+	}
+	return out, rows.Err()
+}
+
+// ReplaceHoldings atomically replaces all portfolio positions. The slice order
+// becomes the sort order.
+func (s *Store) ReplaceHoldings(list []Holding) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM holdings`); err != nil {
+		return err
+	}
+	now := time.Now().Unix()
+	for i, h := range list {
+		if _, err := tx.Exec(
+			`INSERT INTO holdings (symbol, quantity, cost_basis, sort_order, updated_at) VALUES (?, ?, ?, ?, ?)`,
+			h.Symbol, h.Quantity, h.CostBasis, i, now,
+		); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -40,3 +40,22 @@ func (s *Store) DeleteIconCache(cacheKey string) error {
 	_, err := s.db.Exec(`DELETE FROM icon_cache WHERE cache_key = ?`, cacheKey)
 	return err
 }
+
+// ReferencedIconPaths returns the icon paths used by apps or the icon cache.
+func (s *Store) ReferencedIconPaths() (map[string]bool, error) {
+	rows, err := s.db.Query(`SELECT icon_path FROM apps WHERE icon_path IS NOT NULL AND icon_path != ''
+		UNION SELECT icon_path FROM icon_cache WHERE icon_path != ''`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]bool{}
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		out[p] = true
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,71 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// IngestValue is the latest JSON payload pushed to a custom widget key.
+type IngestValue struct {
+	Key       string
+	Payload   []byte
+	UpdatedAt int64
+}
+
+// PutIngestValue stores payload as the latest value of key.
+func (s *Store) PutIngestValue(key string, payload []byte) error {
+	_, err := s.db.Exec(`INSERT INTO ingest_values (key, payload, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET payload=excluded.payload, updated_at=excluded.updated_at`,
+		key, payload, time.Now().Unix())
+	return err
+}
+
+// GetIngestValue returns the latest value of key.
+func (s *Store) GetIngestValue(key string) (IngestValue, bool, error) {
+	var v IngestValue
+	err := s.db.QueryRow(`SELECT key, payload, updated_at FROM ingest_values WHERE key = ?`, key).
+		Scan(&v.Key, &v.Payload, &v.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return IngestValue{}, false, nil
+		}
+		return IngestValue{}, false, err
+	}
+	return v, true, nil
+}
+
+// ListIngestValues returns every key's latest value ordered by key.
+func (s *Store) ListIngestValues() ([]IngestValue, error) {
+	rows, err := s.db.Query(`SELECT key, payload, updated_at FROM ingest_values ORDER BY key`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []IngestValue{}
+	for rows.Next() {
+		var v IngestValue
+		if err := rows.Scan(&v.Key, &v.Payload, &v.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, rows.Err()
+}
+
+// CountIngestValues returns how many keys hold a value.
+func (s *Store) CountIngestValues() (int, error) {
+	var n int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM ingest_values`).Scan(&n)
+	return n, err
+}
+
+// DeleteIngestValue removes key. It reports whether the key existed.
+func (s *Store) DeleteIngestValue(key string) (bool, error) {
+	res, err := s.db.Exec(`DELETE FROM ingest_values WHERE key = ?`, key)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
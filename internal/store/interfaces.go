@@ -1,5 +1,7 @@
 package store
 
+import "context"
+
 // GroupRepository defines the interface for group operations.
 type GroupRepository interface {
 	ListGroups() ([]Group, error)
@@ -14,28 +16,191 @@ type GroupRepository interface {
 // AppRepository defines the interface for app operations.
 type AppRepository interface {
 	ListApps() ([]AppItem, error)
-	CreateApp(groupID *string, name string, description *string, url string, iconPath, iconSource *string) (AppItem, error)
-	UpdateApp(id string, groupID *string, name string, description *string, url string, iconPath, iconSource *string) error
+	ListRecentApps(byUpdate bool, limit int) ([]AppItem, error)
+	CreateApp(a AppItem) (AppItem, error)
+	UpdateApp(a AppItem) error
+	SetAppPreview(id string, p *LinkPreview) (bool, error)
+	SetAppArchived(id string, archived bool) (bool, error)
 	DeleteApp(id string) error
 	ReorderApps(groupID *string, ids []string) error
-	MoveGroupAppsToUngrouped(groupID string) error
-	DeleteAppsByGroupID(groupID string) error
+	MoveApp(id string, groupID *string, index int) (bool, error)
+	MoveGroupApps(from string, to *string) (int, error)
+	DeleteAppsByGroupID(groupID string) (int, error)
 	AppByID(id string) (AppItem, bool, error)
+	GetWidgetConfig(appID string) (WidgetConfig, bool, error)
+	PutWidgetConfig(appID, kind string, config []byte) error
+	DeleteWidgetConfig(appID string) error
 }
 
 // KVRepository defines the interface for key-value operations.
 type KVRepository interface {
 	GetKV(key string) (string, bool, error)
 	SetKV(key, value string) error
+	DeleteKV(key string) error
+	// User settings override the entry of the same key for one user.
+	ListUserKV(userID string) (map[string]string, error)
+	ListAllUserKV() (map[string]map[string]string, error)
+	SetUserKV(userID, key, value string) error
+	DeleteUserKV(userID, key string) error
+}
+
+// BackgroundRepository defines the interface for the background image cache
+// and its history.
+type BackgroundRepository interface {
+	GetBackgroundCache(cacheKey string) (BackgroundCacheEntry, bool, error)
+	SetBackgroundCache(cacheKey, filePath string) error
+	DeleteBackgroundCache(cacheKey string) error
+	ListBackgroundHistory(cacheKey string) ([]BackgroundHistoryEntry, error)
+	GetBackgroundHistory(cacheKey, filePath string) (BackgroundHistoryEntry, bool, error)
+	AddBackgroundHistory(e BackgroundHistoryEntry, keep int) ([]string, error)
+}
+
+// IconCacheRepository defines the interface for resolved app icons.
+type IconCacheRepository interface {
+	GetIconCache(cacheKey string) (IconCacheEntry, bool, error)
+	SetIconCache(cacheKey, iconPath, iconSource string) error
+	DeleteIconCache(cacheKey string) error
+	ReferencedIconPaths() (map[string]bool, error)
+}
+
+// WidgetCacheRepository defines the interface for persisted widget
+// responses, symbol mappings and geocoding results.
+type WidgetCacheRepository interface {
+	GetWidgetCache(cacheKey string) (WidgetCacheEntry, bool, error)
+	SetWidgetCache(e WidgetCacheEntry) error
+	GetSymbolMapping(provider, symbol string) (SymbolMapping, bool, error)
+	SetSymbolMapping(m SymbolMapping) error
+	GetGeocodeCache(query, language string) (GeocodeCacheEntry, bool, error)
+	SetGeocodeCache(e GeocodeCacheEntry) error
+	PruneWidgetCaches() error
+	ClearWidgetCaches() error
+}
+
+// WebhookRepository defines the interface for webhooks and their delivery
+// queue.
+type WebhookRepository interface {
+	ListWebhooks() ([]Webhook, error)
+	GetWebhook(id int64) (Webhook, bool, error)
+	CreateWebhook(w Webhook) (Webhook, error)
+	UpdateWebhook(w Webhook) (bool, error)
+	DeleteWebhook(id int64) (bool, error)
+	EnqueueWebhookDelivery(webhookID int64, event string, payload []byte) (int64, error)
+	DueWebhookDeliveries(now int64, limit int) ([]WebhookDelivery, error)
+	ListWebhookDeliveries(webhookID int64, limit int) ([]WebhookDelivery, error)
+	RecordWebhookAttempt(id int64, statusCode int, errMsg string, nextAttemptAt int64) error
+	PruneWebhookDeliveries(before int64) (int64, error)
+}
+
+// BackupTargetRepository defines the interface for remote backup targets.
+type BackupTargetRepository interface {
+	ListBackupTargets() ([]BackupTarget, error)
+	GetBackupTarget(id int64) (BackupTarget, bool, error)
+	CreateBackupTarget(t BackupTarget) (BackupTarget, error)
+	UpdateBackupTarget(t BackupTarget) (bool, error)
+	DeleteBackupTarget(id int64) (bool, error)
+	RecordBackupRun(id int64, at int64, errMsg string) error
+}
+
+// ActionAuditRepository defines the interface for the audit trail of app
+// quick actions.
+type ActionAuditRepository interface {
+	RecordActionAudit(e ActionAudit) (ActionAudit, error)
+	ListActionAudit(appID string, limit int) ([]ActionAudit, error)
+}
+
+// SettingsHistoryRepository defines the interface for the history of
+// settings changes.
+type SettingsHistoryRepository interface {
+	RecordSettingChange(c SettingChange) (SettingChange, error)
+	ListSettingChanges(key string, limit int) ([]SettingChange, error)
+	GetSettingChange(id int64) (SettingChange, bool, error)
+}
+
+// ShareRepository defines the interface for read-only share links.
+type ShareRepository interface {
+	ListShares() ([]Share, error)
+	CreateShare(sh Share) (Share, error)
+	DeleteShare(id string) (bool, error)
+	ShareByTokenHash(hash string) (Share, bool, error)
+}
+
+// LoginRepository defines the interface for the addresses admins logged in
+// from.
+type LoginRepository interface {
+	RecordLoginIP(ip string) (known bool, err error)
+}
+
+// MetricsRepository defines the interface for metrics agents and the host
+// metrics history.
+type MetricsRepository interface {
+	ListMetricHosts() ([]MetricHost, error)
+	GetMetricHost(name string) (MetricHost, bool, error)
+	PutMetricHost(h MetricHost) error
+	DeleteMetricHost(name string) (bool, error)
+	AddMetricsSample(m MetricsSample) error
+	PruneMetricsSamples(before int64) (int64, error)
+	MetricsHistory(since, step int64) ([]MetricsSample, error)
+}
+
+// IngestRepository defines the interface for values pushed to custom
+// widgets.
+type IngestRepository interface {
+	PutIngestValue(key string, payload []byte) error
+	GetIngestValue(key string) (IngestValue, bool, error)
+	ListIngestValues() ([]IngestValue, error)
+	CountIngestValues() (int, error)
+	DeleteIngestValue(key string) (bool, error)
+}
+
+// PersonalRepository defines the interface for the admin's own data:
+// portfolio holdings, personal dates and quotes.
+type PersonalRepository interface {
+	ListHoldings() ([]Holding, error)
+	ReplaceHoldings(list []Holding) error
+	ListCustomEvents() ([]CustomEvent, error)
+	ReplaceCustomEvents(list []CustomEvent) error
+	ListCustomQuotes() ([]CustomQuote, error)
+	AddCustomQuote(text, author string) (CustomQuote, error)
+	DeleteCustomQuote(id int64) (bool, error)
+}
+
+// AdminRepository defines the interface for whole-database operations:
+// backups, resets and maintenance.
+type AdminRepository interface {
+	ExportAll() (Export, error)
+	ImportAll(payload Export) error
+	ReplaceAll(payload Export) error
+	ExportJSON() ([]byte, error)
+	ImportJSON(b []byte) error
+	ResetAll() error
+	ResetScope(scope string) error
+	Maintain(ctx context.Context, vacuum bool) (MaintenanceReport, error)
+	FreeRatio(ctx context.Context) (float64, error)
 }
 
-// Repository combines all repository interfaces.
+// Repository combines all repository interfaces. The server depends on it
+// rather than on *Store, so handlers can run against the in-memory
+// implementation in package memstore.
 type Repository interface {
 	GroupRepository
 	AppRepository
 	KVRepository
+	BackgroundRepository
+	IconCacheRepository
+	WidgetCacheRepository
+	WebhookRepository
+	BackupTargetRepository
+	ActionAuditRepository
+	SettingsHistoryRepository
+	ShareRepository
+	LoginRepository
+	MetricsRepository
+	IngestRepository
+	PersonalRepository
+	AdminRepository
 	Ping() error
 	Migrate() error
+	Close() error
 }
 
 // Ensure Store implements Repository.
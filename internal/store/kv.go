@@ -6,6 +6,18 @@ import (
 )
 
 func (s *Store) GetKV(key string) (string, bool, error) {
+	v, ok, err := s.getRawKV(key)
+	if err != nil || !ok {
+		return "", ok, err
+	}
+	if v, err = s.open(v); err != nil {
+		return "", false, err
+	}
+	return v, true, nil
+}
+
+// getRawKV returns a kv value as stored, without decrypting it.
+func (s *Store) getRawKV(key string) (string, bool, error) {
 	var v string
 	err := s.db.QueryRow(`SELECT value FROM kv WHERE key = ?`, key).Scan(&v)
 	if err != nil {
@@ -18,6 +30,66 @@ func (s *Store) GetKV(key string) (string, bool, error) {
 }
 
 func (s *Store) SetKV(key, value string) error {
-	_, err := s.db.Exec(`INSERT INTO kv (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value=excluded.value`, key, value)
+	value, err := s.sealKV(key, value)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO kv (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value=excluded.value`, key, value)
+	return err
+}
+
+func (s *Store) DeleteKV(key string) error {
+	_, err := s.db.Exec(`DELETE FROM kv WHERE key = ?`, key)
+	return err
+}
+
+// User settings override kv entries of the same key for one user.
+
+// ListUserKV returns the settings userID has overridden.
+func (s *Store) ListUserKV(userID string) (map[string]string, error) {
+	rows, err := s.db.Query(`SELECT key, value FROM user_kv WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]string{}
+	for rows.Next() {
+		var k, v string
+		if err := rows.Scan(&k, &v); err != nil {
+			return nil, err
+		}
+		out[k] = v
+	}
+	return out, rows.Err()
+}
+
+// ListAllUserKV returns the settings of every user who has any, by user ID.
+func (s *Store) ListAllUserKV() (map[string]map[string]string, error) {
+	rows, err := s.db.Query(`SELECT user_id, key, value FROM user_kv`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]map[string]string{}
+	for rows.Next() {
+		var id, k, v string
+		if err := rows.Scan(&id, &k, &v); err != nil {
+			return nil, err
+		}
+		if out[id] == nil {
+			out[id] = map[string]string{}
+		}
+		out[id][k] = v
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) SetUserKV(userID, key, value string) error {
+	_, err := s.db.Exec(`INSERT INTO user_kv (user_id, key, value) VALUES (?, ?, ?) ON CONFLICT(user_id, key) DO UPDATE SET value=excluded.value`, userID, key, value)
+	return err
+}
+
+func (s *Store) DeleteUserKV(userID, key string) error {
+	_, err := s.db.Exec(`DELETE FROM user_kv WHERE user_id = ? AND key = ?`, userID, key)
 	return err
 }
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// MaintenanceReport is the outcome of one database maintenance run.
+type MaintenanceReport struct {
+	// Checkpoint is the WAL checkpoint result. Busy means readers kept the
+	// WAL from being fully reset; Frames and Checkpointed are -1 outside WAL
+	// mode.
+	Checkpoint struct {
+		Busy         bool `json:"busy"`
+		Frames       int  `json:"frames"`
+		Checkpointed int  `json:"checkpointed"`
+	} `json:"checkpoint"`
+	Vacuumed    bool     `json:"vacuumed"`
+	SizeBefore  int64    `json:"sizeBefore"` // bytes, pages * page size
+	SizeAfter   int64    `json:"sizeAfter"`
+	FreeBefore  int64    `json:"freeBefore"` // bytes on the freelist
+	IntegrityOK bool     `json:"integrityOk"`
+	Integrity   []string `json:"integrity"` // "ok", or the problems found
+	DurationMs  int64    `json:"durationMs"`
+}
+
+// Maintain checkpoints and truncates the WAL, optionally vacuums, and runs
+// an integrity check. Vacuuming rewrites the whole file and blocks writers
+// while it runs.
+func (s *Store) Maintain(ctx context.Context, vacuum bool) (MaintenanceReport, error) {
+	start := time.Now()
+	var rep MaintenanceReport
+	var err error
+	if rep.SizeBefore, rep.FreeBefore, err = s.dbSize(ctx); err != nil {
+		return rep, err
+	}
+
+	var busy int
+	if err := s.db.QueryRowContext(ctx, `PRAGMA wal_checkpoint(TRUNCATE)`).Scan(&busy, &rep.Checkpoint.Frames, &rep.Checkpoint.Checkpointed); err != nil {
+		return rep, err
+	}
+	rep.Checkpoint.Busy = busy != 0
+
+	if vacuum {
+		if _, err := s.db.ExecContext(ctx, `VACUUM`); err != nil {
+			return rep, err
+		}
+		rep.Vacuumed = true
+		// VACUUM goes through the WAL as well.
+		_, _ = s.db.ExecContext(ctx, `PRAGMA wal_checkpoint(TRUNCATE)`)
+	}
+
+	// Stop after 20 problems; a damaged file can report thousands.
+	rows, err := s.db.QueryContext(ctx, `PRAGMA integrity_check(20)`)
+	if err != nil {
+		return rep, err
+	}
+	defer rows.Close()
+	rep.Integrity = []string{}
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return rep, err
+		}
+		rep.Integrity = append(rep.Integrity, line)
+	}
+	if err := rows.Err(); err != nil {
+		return rep, err
+	}
+	rep.IntegrityOK = len(rep.Integrity) == 1 && rep.Integrity[0] == "ok"
+
+	if rep.SizeAfter, _, err = s.dbSize(ctx); err != nil {
+		return rep, err
+	}
+	rep.DurationMs = time.Since(start).Milliseconds()
+	return rep, nil
+}
+
+// dbSize returns the size of the database and of its free pages in bytes.
+func (s *Store) dbSize(ctx context.Context) (size, free int64, err error) {
+	var pageSize, pages, freePages int64
+	if err := s.db.QueryRowContext(ctx, `PRAGMA page_size`).Scan(&pageSize); err != nil {
+		return 0, 0, err
+	}
+	if err := s.db.QueryRowContext(ctx, `PRAGMA page_count`).Scan(&pages); err != nil {
+		return 0, 0, err
+	}
+	if err := s.db.QueryRowContext(ctx, `PRAGMA freelist_count`).Scan(&freePages); err != nil {
+		return 0, 0, err
+	}
+	return pages * pageSize, freePages * pageSize, nil
+}
+
+// FreeRatio returns the share of the database's pages that are unused, which
+// VACUUM would give back.
+func (s *Store) FreeRatio(ctx context.Context) (float64, error) {
+	size, free, err := s.dbSize(ctx)
+	if err != nil || size == 0 {
+		return 0, err
+	}
+	return float64(free) / float64(size), nil
+}
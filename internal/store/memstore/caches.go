@@ -0,0 +1,247 @@
+package memstore
+
+import (
+	"sort"
+	"time"
+
+	"github.com/morezhou/hearth/internal/store"
+)
+
+// Background images.
+
+func (s *Store) GetBackgroundCache(cacheKey string) (store.BackgroundCacheEntry, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.bgCache[cacheKey]
+	return e, ok, nil
+}
+
+func (s *Store) SetBackgroundCache(cacheKey, filePath string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bgCache[cacheKey] = store.BackgroundCacheEntry{CacheKey: cacheKey, FilePath: filePath, FetchedAt: time.Now().Unix()}
+	return nil
+}
+
+func (s *Store) DeleteBackgroundCache(cacheKey string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.bgCache, cacheKey)
+	return nil
+}
+
+// historyOf returns the history of cacheKey, newest first.
+func (s *Store) historyOf(cacheKey string) []store.BackgroundHistoryEntry {
+	var out []store.BackgroundHistoryEntry
+	for _, e := range s.bgHistory {
+		if e.CacheKey == cacheKey {
+			out = append(out, e)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].FetchedAt != out[j].FetchedAt {
+			return out[i].FetchedAt > out[j].FetchedAt
+		}
+		return out[i].ID > out[j].ID
+	})
+	return out
+}
+
+func (s *Store) ListBackgroundHistory(cacheKey string) ([]store.BackgroundHistoryEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.historyOf(cacheKey), nil
+}
+
+func (s *Store) GetBackgroundHistory(cacheKey, filePath string) (store.BackgroundHistoryEntry, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.bgHistory {
+		if e.CacheKey == cacheKey && e.FilePath == filePath {
+			return e, true, nil
+		}
+	}
+	return store.BackgroundHistoryEntry{}, false, nil
+}
+
+// AddBackgroundHistory records e, keeps the newest keep entries of its cache
+// key and returns the dropped file paths nothing references anymore.
+func (s *Store) AddBackgroundHistory(e store.BackgroundHistoryEntry, keep int) ([]string, error) {
+	if keep < 1 {
+		keep = 1
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeHistory(func(h store.BackgroundHistoryEntry) bool {
+		return h.CacheKey == e.CacheKey && h.FilePath == e.FilePath
+	})
+	e.ID, e.FetchedAt = s.id(), time.Now().Unix()
+	s.bgHistory = append(s.bgHistory, e)
+
+	list := s.historyOf(e.CacheKey)
+	if len(list) <= keep {
+		return nil, nil
+	}
+	drop := map[int64]bool{}
+	for _, h := range list[keep:] {
+		drop[h.ID] = true
+	}
+	s.removeHistory(func(h store.BackgroundHistoryEntry) bool { return drop[h.ID] })
+
+	var removed []string
+	for _, h := range list[keep:] {
+		if !s.backgroundReferenced(h.FilePath) {
+			removed = append(removed, h.FilePath)
+		}
+	}
+	return removed, nil
+}
+
+func (s *Store) removeHistory(match func(store.BackgroundHistoryEntry) bool) {
+	kept := s.bgHistory[:0]
+	for _, h := range s.bgHistory {
+		if !match(h) {
+			kept = append(kept, h)
+		}
+	}
+	s.bgHistory = kept
+}
+
+func (s *Store) backgroundReferenced(path string) bool {
+	for _, h := range s.bgHistory {
+		if h.FilePath == path {
+			return true
+		}
+	}
+	for _, c := range s.bgCache {
+		if c.FilePath == path {
+			return true
+		}
+	}
+	return false
+}
+
+// Icons.
+
+func (s *Store) GetIconCache(cacheKey string) (store.IconCacheEntry, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.iconCache[cacheKey]
+	return e, ok, nil
+}
+
+func (s *Store) SetIconCache(cacheKey, iconPath, iconSource string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.iconCache[cacheKey] = store.IconCacheEntry{CacheKey: cacheKey, IconPath: iconPath, IconSource: iconSource, UpdatedAt: time.Now().Unix()}
+	return nil
+}
+
+func (s *Store) DeleteIconCache(cacheKey string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.iconCache, cacheKey)
+	return nil
+}
+
+func (s *Store) ReferencedIconPaths() (map[string]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := map[string]bool{}
+	for _, a := range s.apps {
+		if a.IconPath != nil && *a.IconPath != "" {
+			out[*a.IconPath] = true
+		}
+	}
+	for _, e := range s.iconCache {
+		if e.IconPath != "" {
+			out[e.IconPath] = true
+		}
+	}
+	return out, nil
+}
+
+// Widget caches. Expired entries read as missing.
+
+func (s *Store) GetWidgetCache(cacheKey string) (store.WidgetCacheEntry, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.widgetCache[cacheKey]
+	if !ok || e.ExpiresAt <= time.Now().Unix() {
+		return store.WidgetCacheEntry{}, false, nil
+	}
+	return e, true, nil
+}
+
+func (s *Store) SetWidgetCache(e store.WidgetCacheEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.Payload = append([]byte(nil), e.Payload...)
+	s.widgetCache[e.CacheKey] = e
+	return nil
+}
+
+func (s *Store) GetSymbolMapping(provider, symbol string) (store.SymbolMapping, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.symbols[[2]string{provider, symbol}]
+	if !ok || m.ExpiresAt <= time.Now().Unix() {
+		return store.SymbolMapping{}, false, nil
+	}
+	return m, true, nil
+}
+
+func (s *Store) SetSymbolMapping(m store.SymbolMapping) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.symbols[[2]string{m.Provider, m.Symbol}] = m
+	return nil
+}
+
+func (s *Store) GetGeocodeCache(query, language string) (store.GeocodeCacheEntry, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.geocodes[[2]string{query, language}]
+	if !ok || e.ExpiresAt <= time.Now().Unix() {
+		return store.GeocodeCacheEntry{}, false, nil
+	}
+	return e, true, nil
+}
+
+func (s *Store) SetGeocodeCache(e store.GeocodeCacheEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.geocodes[[2]string{e.Query, e.Language}] = e
+	return nil
+}
+
+func (s *Store) PruneWidgetCaches() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().Unix()
+	for k, e := range s.widgetCache {
+		if e.ExpiresAt <= now {
+			delete(s.widgetCache, k)
+		}
+	}
+	for k, m := range s.symbols {
+		if m.ExpiresAt <= now {
+			delete(s.symbols, k)
+		}
+	}
+	for k, e := range s.geocodes {
+		if e.ExpiresAt <= now {
+			delete(s.geocodes, k)
+		}
+	}
+	return nil
+}
+
+func (s *Store) ClearWidgetCaches() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.widgetCache = map[string]store.WidgetCacheEntry{}
+	s.symbols = map[[2]string]store.SymbolMapping{}
+	s.geocodes = map[[2]string]store.GeocodeCacheEntry{}
+	return nil
+}
@@ -0,0 +1,827 @@
+// Package memstore is an in-memory implementation of store.Repository. It
+// mirrors the SQLite store's semantics (ordering, upserts, cascades) closely
+// enough for handler tests, without a database. Nothing is encrypted and
+// ResetAll doesn't touch credentials, which live in the auth service.
+package memstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/morezhou/hearth/internal/store"
+)
+
+var errNotFound = errors.New("not found")
+
+// Store holds every table in maps and slices guarded by one mutex.
+type Store struct {
+	mu sync.Mutex
+
+	kv            map[string]string
+	userKV        map[string]map[string]string // user ID -> key -> value
+	groups        []store.Group
+	apps          []store.AppItem
+	widgetConfigs map[string]store.WidgetConfig
+
+	bgCache   map[string]store.BackgroundCacheEntry
+	bgHistory []store.BackgroundHistoryEntry
+	iconCache map[string]store.IconCacheEntry
+
+	widgetCache map[string]store.WidgetCacheEntry
+	symbols     map[[2]string]store.SymbolMapping
+	geocodes    map[[2]string]store.GeocodeCacheEntry
+
+	webhooks      []store.Webhook
+	deliveries    []store.WebhookDelivery
+	backupTargets []store.BackupTarget
+	audit         []store.ActionAudit
+	shares        []store.Share
+	loginIPs      map[string]int64
+
+	settingChanges []store.SettingChange
+
+	metricHosts map[string]store.MetricHost
+	samples     map[int64]store.MetricsSample
+	ingest      map[string]store.IngestValue
+
+	holdings []store.Holding
+	events   []store.CustomEvent
+	quotes   []store.CustomQuote
+
+	nextID int64 // shared autoincrement for the integer keyed tables
+}
+
+var _ store.Repository = (*Store)(nil)
+
+// New returns an empty store. Call Migrate to create the system group like
+// the SQLite store does.
+func New() *Store {
+	s := &Store{}
+	s.reset()
+	return s
+}
+
+func (s *Store) reset() {
+	s.kv = map[string]string{}
+	s.userKV = map[string]map[string]string{}
+	s.groups = nil
+	s.apps = nil
+	s.widgetConfigs = map[string]store.WidgetConfig{}
+	s.bgCache = map[string]store.BackgroundCacheEntry{}
+	s.bgHistory = nil
+	s.iconCache = map[string]store.IconCacheEntry{}
+	s.widgetCache = map[string]store.WidgetCacheEntry{}
+	s.symbols = map[[2]string]store.SymbolMapping{}
+	s.geocodes = map[[2]string]store.GeocodeCacheEntry{}
+	s.webhooks = nil
+	s.deliveries = nil
+	s.backupTargets = nil
+	s.audit = nil
+	s.settingChanges = nil
+	s.shares = nil
+	s.loginIPs = map[string]int64{}
+	s.metricHosts = map[string]store.MetricHost{}
+	s.samples = map[int64]store.MetricsSample{}
+	s.ingest = map[string]store.IngestValue{}
+	s.holdings = nil
+	s.events = nil
+	s.quotes = nil
+}
+
+func (s *Store) id() int64 {
+	s.nextID++
+	return s.nextID
+}
+
+func (s *Store) Ping() error  { return nil }
+func (s *Store) Close() error { return nil }
+
+// Migrate ensures there is exactly one system group and that widget items
+// live in it and have a configuration, like store.Store.Migrate.
+func (s *Store) Migrate() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	systemID := ""
+	for _, g := range s.sortedGroups() {
+		if g.Kind == "system" {
+			systemID = g.ID
+			break
+		}
+	}
+	if systemID == "" {
+		g := s.createGroup("系统组件", "system")
+		systemID = g.ID
+	}
+	for i := range s.groups {
+		if s.groups[i].Kind == "system" && s.groups[i].ID != systemID {
+			s.groups[i].Kind = "app"
+		}
+	}
+	for i := range s.apps {
+		a := &s.apps[i]
+		if strings.HasPrefix(a.URL, "widget:") && (a.GroupID == nil || s.groupKind(*a.GroupID) != "system") {
+			id := systemID
+			a.GroupID = &id
+		}
+	}
+	s.migrateWidgetConfigs(false)
+	return nil
+}
+
+// migrateWidgetConfigs derives configurations from the descriptions of
+// widget items and drops those of items that are gone or no longer widgets.
+func (s *Store) migrateWidgetConfigs(overwrite bool) {
+	now := time.Now().Unix()
+	widgets := map[string]bool{}
+	for _, a := range s.apps {
+		if !strings.HasPrefix(a.URL, "widget:") {
+			continue
+		}
+		widgets[a.ID] = true
+		if _, ok := s.widgetConfigs[a.ID]; ok && !overwrite {
+			continue
+		}
+		kind := strings.TrimPrefix(a.URL, "widget:")
+		if i := strings.IndexByte(kind, '?'); i >= 0 {
+			kind = kind[:i]
+		}
+		config := []byte("{}")
+		var obj map[string]any
+		if a.Description != nil && json.Unmarshal([]byte(*a.Description), &obj) == nil && obj != nil {
+			config = []byte(*a.Description)
+		}
+		s.widgetConfigs[a.ID] = store.WidgetConfig{AppID: a.ID, Kind: kind, Config: config, UpdatedAt: now}
+	}
+	for id := range s.widgetConfigs {
+		if !widgets[id] {
+			delete(s.widgetConfigs, id)
+		}
+	}
+}
+
+// Key-value settings.
+
+func (s *Store) GetKV(key string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.kv[key]
+	return v, ok, nil
+}
+
+func (s *Store) SetKV(key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.kv[key] = value
+	return nil
+}
+
+func (s *Store) DeleteKV(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.kv, key)
+	return nil
+}
+
+func (s *Store) ListUserKV(userID string) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.userKV[userID]), nil
+}
+
+func (s *Store) ListAllUserKV() (map[string]map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := map[string]map[string]string{}
+	for id, kv := range s.userKV {
+		if len(kv) > 0 {
+			out[id] = maps.Clone(kv)
+		}
+	}
+	return out, nil
+}
+
+func (s *Store) SetUserKV(userID, key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.userKV[userID] == nil {
+		s.userKV[userID] = map[string]string{}
+	}
+	s.userKV[userID][key] = value
+	return nil
+}
+
+func (s *Store) DeleteUserKV(userID, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.userKV[userID], key)
+	return nil
+}
+
+// Groups.
+
+// sortedGroups returns the groups by sort order, then creation.
+func (s *Store) sortedGroups() []store.Group {
+	out := append([]store.Group{}, s.groups...)
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].SortOrder != out[j].SortOrder {
+			return out[i].SortOrder < out[j].SortOrder
+		}
+		return out[i].CreatedAt < out[j].CreatedAt
+	})
+	return out
+}
+
+func (s *Store) groupIndex(id string) int {
+	for i, g := range s.groups {
+		if g.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Store) groupKind(id string) string {
+	if i := s.groupIndex(id); i >= 0 {
+		return s.groups[i].Kind
+	}
+	return ""
+}
+
+func (s *Store) createGroup(name, kind string) store.Group {
+	if kind == "" {
+		kind = "app"
+	}
+	next := 1
+	for _, g := range s.groups {
+		if g.SortOrder >= next {
+			next = g.SortOrder + 1
+		}
+	}
+	g := store.Group{ID: uuid.NewString(), Name: name, Kind: kind, SortOrder: next, CreatedAt: time.Now().Unix()}
+	s.groups = append(s.groups, g)
+	return g
+}
+
+func (s *Store) ListGroups() ([]store.Group, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sortedGroups(), nil
+}
+
+func (s *Store) CreateGroup(name string, kind string) (store.Group, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.createGroup(name, kind), nil
+}
+
+func (s *Store) UpdateGroup(id, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.groupIndex(id)
+	if i < 0 {
+		return errNotFound
+	}
+	s.groups[i].Name = name
+	return nil
+}
+
+// DeleteGroup removes a group; its apps become ungrouped, as with the
+// ON DELETE SET NULL foreign key.
+func (s *Store) DeleteGroup(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.groupIndex(id)
+	if i < 0 {
+		return nil
+	}
+	s.groups = append(s.groups[:i], s.groups[i+1:]...)
+	for j := range s.apps {
+		if s.apps[j].GroupID != nil && *s.apps[j].GroupID == id {
+			s.apps[j].GroupID = nil
+		}
+	}
+	kept := s.shares[:0]
+	for _, sh := range s.shares {
+		if sh.GroupID == nil || *sh.GroupID != id {
+			kept = append(kept, sh)
+		}
+	}
+	s.shares = kept
+	return nil
+}
+
+func (s *Store) ReorderGroups(ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for n, id := range ids {
+		if i := s.groupIndex(id); i >= 0 {
+			s.groups[i].SortOrder = n + 1
+		}
+	}
+	return nil
+}
+
+func (s *Store) HasSystemGroup() (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, g := range s.groups {
+		if g.Kind == "system" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *Store) GroupKindByID(id string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.groupIndex(id); i >= 0 {
+		return s.groups[i].Kind, true, nil
+	}
+	return "", false, nil
+}
+
+// Apps.
+
+func sameGroup(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+func cloneStr(p *string) *string {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
+func cloneApp(a store.AppItem) store.AppItem {
+	a.GroupID = cloneStr(a.GroupID)
+	a.Description = cloneStr(a.Description)
+	a.InternalURL = cloneStr(a.InternalURL)
+	a.WakeMAC = cloneStr(a.WakeMAC)
+	a.Shortcut = cloneStr(a.Shortcut)
+	a.Actions = append([]store.AppAction{}, a.Actions...)
+	if a.Preview != nil {
+		p := *a.Preview
+		a.Preview = &p
+	}
+	a.IconPath = cloneStr(a.IconPath)
+	a.IconSource = cloneStr(a.IconSource)
+	return a
+}
+
+func (s *Store) appIndex(id string) int {
+	for i, a := range s.apps {
+		if a.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// ListApps returns the apps by group (ungrouped first), sort order and
+// creation.
+func (s *Store) ListApps() ([]store.AppItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]store.AppItem, 0, len(s.apps))
+	for _, a := range s.apps {
+		out = append(out, cloneApp(a))
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		gi, gj := out[i].GroupID, out[j].GroupID
+		if !sameGroup(gi, gj) {
+			if gi == nil || gj == nil {
+				return gi == nil
+			}
+			return *gi < *gj
+		}
+		if out[i].SortOrder != out[j].SortOrder {
+			return out[i].SortOrder < out[j].SortOrder
+		}
+		return out[i].CreatedAt < out[j].CreatedAt
+	})
+	return out, nil
+}
+
+func (s *Store) ListRecentApps(byUpdate bool, limit int) ([]store.AppItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]store.AppItem, 0, len(s.apps))
+	for _, a := range s.apps {
+		if !strings.HasPrefix(a.URL, "widget:") && !a.Archived {
+			out = append(out, cloneApp(a))
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if byUpdate && out[i].UpdatedAt != out[j].UpdatedAt {
+			return out[i].UpdatedAt > out[j].UpdatedAt
+		}
+		if out[i].CreatedAt != out[j].CreatedAt {
+			return out[i].CreatedAt > out[j].CreatedAt
+		}
+		return out[i].ID < out[j].ID
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (s *Store) CreateApp(a store.AppItem) (store.AppItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	next := 1
+	for _, b := range s.apps {
+		if sameGroup(b.GroupID, a.GroupID) && b.SortOrder >= next {
+			next = b.SortOrder + 1
+		}
+	}
+	if a.OpenMode == "" {
+		a.OpenMode = store.DefaultOpenMode
+	}
+	a.ID, a.SortOrder, a.CreatedAt = uuid.NewString(), next, time.Now().Unix()
+	a.UpdatedAt = a.CreatedAt
+	s.apps = append(s.apps, cloneApp(a))
+	return cloneApp(a), nil
+}
+
+func (s *Store) UpdateApp(a store.AppItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.appIndex(a.ID)
+	if i < 0 {
+		return errNotFound
+	}
+	if a.OpenMode == "" {
+		a.OpenMode = store.DefaultOpenMode
+	}
+	a.SortOrder, a.CreatedAt, a.UpdatedAt = s.apps[i].SortOrder, s.apps[i].CreatedAt, time.Now().Unix()
+	a.Preview, a.Archived = s.apps[i].Preview, s.apps[i].Archived
+	s.apps[i] = cloneApp(a)
+	return nil
+}
+
+func (s *Store) SetAppPreview(id string, p *store.LinkPreview) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.appIndex(id)
+	if i < 0 {
+		return false, nil
+	}
+	if p != nil {
+		v := *p
+		p = &v
+	}
+	s.apps[i].Preview = p
+	return true, nil
+}
+
+func (s *Store) SetAppArchived(id string, archived bool) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.appIndex(id)
+	if i < 0 {
+		return false, nil
+	}
+	s.apps[i].Archived = archived
+	return true, nil
+}
+
+func (s *Store) DeleteApp(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.appIndex(id); i >= 0 {
+		s.apps = append(s.apps[:i], s.apps[i+1:]...)
+	}
+	delete(s.widgetConfigs, id)
+	return nil
+}
+
+func (s *Store) ReorderApps(groupID *string, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for n, id := range ids {
+		if i := s.appIndex(id); i >= 0 && sameGroup(s.apps[i].GroupID, groupID) {
+			s.apps[i].SortOrder = n + 1
+		}
+	}
+	return nil
+}
+
+func (s *Store) MoveApp(id string, groupID *string, index int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.appIndex(id)
+	if i < 0 {
+		return false, nil
+	}
+	from := s.apps[i].GroupID
+	target := s.groupOrder(groupID, id)
+	pos, visible := len(target), 0
+	for n, j := range target {
+		if s.apps[j].Archived {
+			continue
+		}
+		if visible == max(index, 0) {
+			pos = n
+			break
+		}
+		visible++
+	}
+	s.apps[i].GroupID = cloneStr(groupID)
+	for n, j := range slices.Insert(target, pos, i) {
+		s.apps[j].SortOrder = n + 1
+	}
+	if !sameGroup(from, groupID) {
+		for n, j := range s.groupOrder(from, id) {
+			s.apps[j].SortOrder = n + 1
+		}
+	}
+	return true, nil
+}
+
+// groupOrder returns the indexes of the apps of a group in order, except
+// the app except.
+func (s *Store) groupOrder(groupID *string, except string) []int {
+	var out []int
+	for j, a := range s.apps {
+		if a.ID != except && sameGroup(a.GroupID, groupID) {
+			out = append(out, j)
+		}
+	}
+	sort.SliceStable(out, func(x, y int) bool {
+		a, b := s.apps[out[x]], s.apps[out[y]]
+		if a.SortOrder != b.SortOrder {
+			return a.SortOrder < b.SortOrder
+		}
+		return a.CreatedAt < b.CreatedAt
+	})
+	return out
+}
+
+func (s *Store) MoveGroupApps(from string, to *string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	offset := 0
+	for _, a := range s.apps {
+		if sameGroup(a.GroupID, to) && a.SortOrder > offset {
+			offset = a.SortOrder
+		}
+	}
+	n := 0
+	for i := range s.apps {
+		if s.apps[i].GroupID != nil && *s.apps[i].GroupID == from {
+			s.apps[i].GroupID = cloneStr(to)
+			s.apps[i].SortOrder += offset
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *Store) DeleteAppsByGroupID(groupID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.apps[:0]
+	n := 0
+	for _, a := range s.apps {
+		if a.GroupID != nil && *a.GroupID == groupID {
+			delete(s.widgetConfigs, a.ID)
+			n++
+			continue
+		}
+		kept = append(kept, a)
+	}
+	s.apps = kept
+	return n, nil
+}
+
+func (s *Store) AppByID(id string) (store.AppItem, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.appIndex(id); i >= 0 {
+		return cloneApp(s.apps[i]), true, nil
+	}
+	return store.AppItem{}, false, nil
+}
+
+// Widget configurations.
+
+func (s *Store) GetWidgetConfig(appID string) (store.WidgetConfig, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.widgetConfigs[appID]
+	c.Config = append([]byte(nil), c.Config...)
+	return c, ok, nil
+}
+
+// PutWidgetConfig stores the configuration and mirrors it into the item's
+// description.
+func (s *Store) PutWidgetConfig(appID, kind string, config []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.appIndex(appID)
+	if i < 0 {
+		return errNotFound
+	}
+	desc := string(config)
+	s.apps[i].Description = &desc
+	s.widgetConfigs[appID] = store.WidgetConfig{AppID: appID, Kind: kind, Config: append([]byte(nil), config...), UpdatedAt: time.Now().Unix()}
+	return nil
+}
+
+func (s *Store) DeleteWidgetConfig(appID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.widgetConfigs, appID)
+	return nil
+}
+
+// Backups, resets and maintenance.
+
+func (s *Store) ExportAll() (store.Export, error) {
+	settings := map[string]string{}
+	s.mu.Lock()
+	for k, v := range s.kv {
+		if !strings.HasPrefix(k, "crypto.") {
+			settings[k] = v
+		}
+	}
+	s.mu.Unlock()
+	groups, _ := s.ListGroups()
+	apps, _ := s.ListApps()
+	holdings, _ := s.ListHoldings()
+	events, _ := s.ListCustomEvents()
+	quotes, _ := s.ListCustomQuotes()
+	return store.Export{
+		Version:  2,
+		Exported: time.Now().Unix(),
+		Settings: settings,
+		Groups:   groups,
+		Apps:     apps,
+		Holdings: holdings,
+		Events:   events,
+		Quotes:   quotes,
+	}, nil
+}
+
+// ImportAll upserts the payload by primary key, like the SQLite import.
+func (s *Store) ImportAll(p store.Export) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.importPayload(p)
+	return nil
+}
+
+// ReplaceAll clears the configuration and imports p, like the SQLite
+// replace.
+func (s *Store) ReplaceAll(p store.Export) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k := range s.kv {
+		if !strings.HasPrefix(k, "crypto.") {
+			delete(s.kv, k)
+		}
+	}
+	s.groups, s.apps, s.holdings, s.events, s.quotes = nil, nil, nil, nil, nil
+	s.widgetConfigs = map[string]store.WidgetConfig{}
+	s.importPayload(p)
+	return nil
+}
+
+func (s *Store) importPayload(p store.Export) {
+	for k, v := range p.Settings {
+		if !strings.HasPrefix(k, "crypto.") {
+			s.kv[k] = v
+		}
+	}
+	for _, g := range p.Groups {
+		if g.Kind == "" {
+			g.Kind = "app"
+		}
+		if i := s.groupIndex(g.ID); i >= 0 {
+			s.groups[i].Name, s.groups[i].Kind, s.groups[i].SortOrder = g.Name, g.Kind, g.SortOrder
+		} else {
+			s.groups = append(s.groups, g)
+		}
+	}
+	for _, a := range p.Apps {
+		if a.OpenMode == "" {
+			a.OpenMode = store.DefaultOpenMode
+		}
+		if i := s.appIndex(a.ID); i >= 0 {
+			a.CreatedAt = s.apps[i].CreatedAt
+			s.apps[i] = cloneApp(a)
+		} else {
+			s.apps = append(s.apps, cloneApp(a))
+		}
+	}
+	for _, h := range p.Holdings {
+		s.holdings = upsert(s.holdings, h, func(x store.Holding) bool { return x.Symbol == h.Symbol })
+	}
+	for _, e := range p.Events {
+		s.events = upsert(s.events, e, func(x store.CustomEvent) bool { return x.ID == e.ID })
+		s.nextID = max(s.nextID, e.ID)
+	}
+	s.migrateWidgetConfigs(true)
+	for _, q := range p.Quotes {
+		s.quotes = upsert(s.quotes, q, func(x store.CustomQuote) bool { return x.ID == q.ID })
+		s.nextID = max(s.nextID, q.ID)
+	}
+}
+
+func upsert[T any](list []T, v T, match func(T) bool) []T {
+	for i := range list {
+		if match(list[i]) {
+			list[i] = v
+			return list
+		}
+	}
+	return append(list, v)
+}
+
+func (s *Store) ExportJSON() ([]byte, error) {
+	p, err := s.ExportAll()
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(p, "", "  ")
+}
+
+func (s *Store) ImportJSON(b []byte) error {
+	var p store.Export
+	if err := json.Unmarshal(b, &p); err != nil {
+		return err
+	}
+	return s.ImportAll(p)
+}
+
+// ResetAll clears all data.
+func (s *Store) ResetAll() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reset()
+	return nil
+}
+
+// ResetScope clears the data of one reset scope.
+func (s *Store) ResetScope(scope string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch scope {
+	case store.ResetApps:
+		s.apps = slices.DeleteFunc(s.apps, func(a store.AppItem) bool { return !strings.HasPrefix(a.URL, "widget:") })
+		s.groups = slices.DeleteFunc(s.groups, func(g store.Group) bool { return g.Kind != "system" })
+	case store.ResetWidgets:
+		s.apps = slices.DeleteFunc(s.apps, func(a store.AppItem) bool { return strings.HasPrefix(a.URL, "widget:") })
+		s.widgetConfigs = map[string]store.WidgetConfig{}
+	case store.ResetCaches:
+		s.iconCache = map[string]store.IconCacheEntry{}
+		s.bgCache = map[string]store.BackgroundCacheEntry{}
+		s.bgHistory = nil
+		s.widgetCache = map[string]store.WidgetCacheEntry{}
+		s.symbols = map[[2]string]store.SymbolMapping{}
+		s.geocodes = map[[2]string]store.GeocodeCacheEntry{}
+	case store.ResetSettings:
+		for k := range s.kv {
+			if strings.HasPrefix(k, "settings.") || strings.HasPrefix(k, "customization.") {
+				delete(s.kv, k)
+			}
+		}
+		s.userKV = map[string]map[string]string{}
+	default:
+		return fmt.Errorf("unknown reset scope %q", scope)
+	}
+	return nil
+}
+
+// Maintain reports a clean database; there is nothing to checkpoint or
+// vacuum.
+func (s *Store) Maintain(ctx context.Context, vacuum bool) (store.MaintenanceReport, error) {
+	var rep store.MaintenanceReport
+	if err := ctx.Err(); err != nil {
+		return rep, err
+	}
+	rep.Checkpoint.Frames, rep.Checkpoint.Checkpointed = -1, -1
+	rep.Vacuumed = vacuum
+	rep.IntegrityOK = true
+	rep.Integrity = []string{"ok"}
+	return rep, nil
+}
+
+func (s *Store) FreeRatio(ctx context.Context) (float64, error) { return 0, ctx.Err() }
@@ -0,0 +1,600 @@
+package memstore
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/morezhou/hearth/internal/store"
+)
+
+// Webhooks.
+
+func cloneWebhook(w store.Webhook) store.Webhook {
+	w.Events = append([]string{}, w.Events...)
+	return w
+}
+
+func (s *Store) webhookIndex(id int64) int {
+	for i, w := range s.webhooks {
+		if w.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Store) ListWebhooks() ([]store.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []store.Webhook{}
+	for _, w := range s.webhooks {
+		out = append(out, cloneWebhook(w))
+	}
+	return out, nil
+}
+
+func (s *Store) GetWebhook(id int64) (store.Webhook, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.webhookIndex(id); i >= 0 {
+		return cloneWebhook(s.webhooks[i]), true, nil
+	}
+	return store.Webhook{}, false, nil
+}
+
+func (s *Store) CreateWebhook(w store.Webhook) (store.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w = cloneWebhook(w)
+	w.ID, w.CreatedAt = s.id(), time.Now().Unix()
+	s.webhooks = append(s.webhooks, w)
+	return cloneWebhook(w), nil
+}
+
+func (s *Store) UpdateWebhook(w store.Webhook) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.webhookIndex(w.ID)
+	if i < 0 {
+		return false, nil
+	}
+	old := &s.webhooks[i]
+	old.URL, old.Secret, old.Events, old.Enabled = w.URL, w.Secret, append([]string{}, w.Events...), w.Enabled
+	return true, nil
+}
+
+func (s *Store) DeleteWebhook(id int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeDeliveries(func(d store.WebhookDelivery) bool { return d.WebhookID == id })
+	i := s.webhookIndex(id)
+	if i < 0 {
+		return false, nil
+	}
+	s.webhooks = append(s.webhooks[:i], s.webhooks[i+1:]...)
+	return true, nil
+}
+
+func (s *Store) removeDeliveries(match func(store.WebhookDelivery) bool) int64 {
+	var n int64
+	kept := s.deliveries[:0]
+	for _, d := range s.deliveries {
+		if match(d) {
+			n++
+			continue
+		}
+		kept = append(kept, d)
+	}
+	s.deliveries = kept
+	return n
+}
+
+func (s *Store) EnqueueWebhookDelivery(webhookID int64, event string, payload []byte) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().Unix()
+	d := store.WebhookDelivery{ID: s.id(), WebhookID: webhookID, Event: event, Payload: append([]byte(nil), payload...), NextAttemptAt: now, CreatedAt: now}
+	s.deliveries = append(s.deliveries, d)
+	return d.ID, nil
+}
+
+// limited returns the first limit deliveries; a negative limit means all,
+// as in SQLite.
+func limited(list []store.WebhookDelivery, limit int) []store.WebhookDelivery {
+	if limit >= 0 && len(list) > limit {
+		list = list[:limit]
+	}
+	return list
+}
+
+func (s *Store) DueWebhookDeliveries(now int64, limit int) ([]store.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []store.WebhookDelivery{}
+	for _, d := range s.deliveries {
+		if d.NextAttemptAt > 0 && d.NextAttemptAt <= now {
+			out = append(out, d)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].NextAttemptAt != out[j].NextAttemptAt {
+			return out[i].NextAttemptAt < out[j].NextAttemptAt
+		}
+		return out[i].ID < out[j].ID
+	})
+	return limited(out, limit), nil
+}
+
+func (s *Store) ListWebhookDeliveries(webhookID int64, limit int) ([]store.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []store.WebhookDelivery{}
+	for i := len(s.deliveries) - 1; i >= 0; i-- {
+		if s.deliveries[i].WebhookID == webhookID {
+			out = append(out, s.deliveries[i])
+		}
+	}
+	return limited(out, limit), nil
+}
+
+func (s *Store) RecordWebhookAttempt(id int64, statusCode int, errMsg string, nextAttemptAt int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.deliveries {
+		d := &s.deliveries[i]
+		if d.ID != id {
+			continue
+		}
+		d.Attempts++
+		d.StatusCode, d.LastError, d.NextAttemptAt, d.DeliveredAt = statusCode, errMsg, nextAttemptAt, 0
+		if errMsg == "" {
+			d.DeliveredAt = time.Now().Unix()
+		}
+	}
+	return nil
+}
+
+func (s *Store) PruneWebhookDeliveries(before int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.removeDeliveries(func(d store.WebhookDelivery) bool {
+		return d.NextAttemptAt == 0 && d.CreatedAt < before
+	}), nil
+}
+
+// Action audit.
+
+func (s *Store) RecordActionAudit(e store.ActionAudit) (store.ActionAudit, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.ID, e.CreatedAt = s.id(), time.Now().Unix()
+	s.audit = append(s.audit, e)
+	if len(s.audit) > maxActionAudit {
+		s.audit = append([]store.ActionAudit(nil), s.audit[len(s.audit)-maxActionAudit:]...)
+	}
+	return e, nil
+}
+
+func (s *Store) ListActionAudit(appID string, limit int) ([]store.ActionAudit, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if limit <= 0 {
+		limit = 100
+	}
+	out := []store.ActionAudit{}
+	for i := len(s.audit) - 1; i >= 0 && len(out) < limit; i-- {
+		if appID == "" || s.audit[i].AppID == appID {
+			out = append(out, s.audit[i])
+		}
+	}
+	return out, nil
+}
+
+// Backup targets.
+
+func (s *Store) backupTargetIndex(id int64) int {
+	for i, t := range s.backupTargets {
+		if t.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Store) ListBackupTargets() ([]store.BackupTarget, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]store.BackupTarget{}, s.backupTargets...), nil
+}
+
+func (s *Store) GetBackupTarget(id int64) (store.BackupTarget, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.backupTargetIndex(id); i >= 0 {
+		return s.backupTargets[i], true, nil
+	}
+	return store.BackupTarget{}, false, nil
+}
+
+func (s *Store) CreateBackupTarget(t store.BackupTarget) (store.BackupTarget, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t.ID, t.CreatedAt = s.id(), time.Now().Unix()
+	t.LastAttemptAt, t.LastSuccessAt, t.LastError = 0, 0, ""
+	s.backupTargets = append(s.backupTargets, t)
+	return t, nil
+}
+
+func (s *Store) UpdateBackupTarget(t store.BackupTarget) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.backupTargetIndex(t.ID)
+	if i < 0 {
+		return false, nil
+	}
+	old := &s.backupTargets[i]
+	old.Name, old.Kind, old.Config, old.Secret = t.Name, t.Kind, t.Config, t.Secret
+	old.Keep, old.IntervalSeconds, old.Enabled = t.Keep, t.IntervalSeconds, t.Enabled
+	return true, nil
+}
+
+func (s *Store) DeleteBackupTarget(id int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.backupTargetIndex(id)
+	if i < 0 {
+		return false, nil
+	}
+	s.backupTargets = append(s.backupTargets[:i], s.backupTargets[i+1:]...)
+	return true, nil
+}
+
+func (s *Store) RecordBackupRun(id int64, at int64, errMsg string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.backupTargetIndex(id); i >= 0 {
+		t := &s.backupTargets[i]
+		t.LastAttemptAt, t.LastError = at, errMsg
+		if errMsg == "" {
+			t.LastSuccessAt = at
+		}
+	}
+	return nil
+}
+
+// Settings history.
+
+func (s *Store) RecordSettingChange(c store.SettingChange) (store.SettingChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c.ID, c.ChangedAt = s.id(), time.Now().Unix()
+	s.settingChanges = append(s.settingChanges, c)
+	if len(s.settingChanges) > maxSettingChanges {
+		s.settingChanges = append([]store.SettingChange(nil), s.settingChanges[len(s.settingChanges)-maxSettingChanges:]...)
+	}
+	return c, nil
+}
+
+func (s *Store) ListSettingChanges(key string, limit int) ([]store.SettingChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if limit <= 0 {
+		limit = 100
+	}
+	out := []store.SettingChange{}
+	for i := len(s.settingChanges) - 1; i >= 0 && len(out) < limit; i-- {
+		if key == "" || s.settingChanges[i].Key == key {
+			out = append(out, s.settingChanges[i])
+		}
+	}
+	return out, nil
+}
+
+func (s *Store) GetSettingChange(id int64) (store.SettingChange, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.settingChanges {
+		if c.ID == id {
+			return c, true, nil
+		}
+	}
+	return store.SettingChange{}, false, nil
+}
+
+// Shares.
+
+func (s *Store) ListShares() ([]store.Share, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []store.Share{}
+	for _, sh := range s.shares {
+		sh.GroupID = cloneStr(sh.GroupID)
+		out = append(out, sh)
+	}
+	return out, nil
+}
+
+func (s *Store) CreateShare(sh store.Share) (store.Share, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.shares {
+		if e.TokenHash == sh.TokenHash {
+			return store.Share{}, errors.New("UNIQUE constraint failed: shares.token_hash")
+		}
+	}
+	sh.ID, sh.CreatedAt = uuid.NewString(), time.Now().Unix()
+	sh.GroupID = cloneStr(sh.GroupID)
+	s.shares = append(s.shares, sh)
+	return sh, nil
+}
+
+func (s *Store) DeleteShare(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, sh := range s.shares {
+		if sh.ID == id {
+			s.shares = append(s.shares[:i], s.shares[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *Store) ShareByTokenHash(hash string) (store.Share, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sh := range s.shares {
+		if sh.TokenHash == hash {
+			sh.GroupID = cloneStr(sh.GroupID)
+			return sh, true, nil
+		}
+	}
+	return store.Share{}, false, nil
+}
+
+// maxActionAudit matches the SQLite store's retention.
+const maxActionAudit = 1000
+
+// maxSettingChanges matches the SQLite store's retention.
+const maxSettingChanges = 500
+
+func (s *Store) RecordLoginIP(ip string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, known := s.loginIPs[ip]
+	s.loginIPs[ip] = time.Now().Unix()
+	return known, nil
+}
+
+// Metrics.
+
+func (s *Store) ListMetricHosts() ([]store.MetricHost, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []store.MetricHost{}
+	for _, h := range s.metricHosts {
+		out = append(out, h)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+func (s *Store) GetMetricHost(name string) (store.MetricHost, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h, ok := s.metricHosts[name]
+	return h, ok, nil
+}
+
+func (s *Store) PutMetricHost(h store.MetricHost) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if old, ok := s.metricHosts[h.Name]; ok {
+		h.CreatedAt = old.CreatedAt
+	} else {
+		h.CreatedAt = time.Now().Unix()
+	}
+	s.metricHosts[h.Name] = h
+	return nil
+}
+
+func (s *Store) DeleteMetricHost(name string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.metricHosts[name]
+	delete(s.metricHosts, name)
+	return ok, nil
+}
+
+func (s *Store) AddMetricsSample(m store.MetricsSample) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.samples[m.At] = m
+	return nil
+}
+
+func (s *Store) PruneMetricsSamples(before int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for at := range s.samples {
+		if at < before {
+			delete(s.samples, at)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// MetricsHistory averages the samples since since over buckets of step
+// seconds, oldest first.
+func (s *Store) MetricsHistory(since, step int64) ([]store.MetricsSample, error) {
+	if step < 1 {
+		step = 1
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	type bucket struct {
+		sum store.MetricsSample
+		n   float64
+	}
+	buckets := map[int64]*bucket{}
+	for at, m := range s.samples {
+		if at < since {
+			continue
+		}
+		k := (at / step) * step
+		b := buckets[k]
+		if b == nil {
+			b = &bucket{}
+			buckets[k] = b
+		}
+		b.sum.CPU += m.CPU
+		b.sum.Mem += m.Mem
+		b.sum.Disk += m.Disk
+		b.sum.NetSend += m.NetSend
+		b.sum.NetRecv += m.NetRecv
+		b.sum.DiskRead += m.DiskRead
+		b.sum.DiskWrite += m.DiskWrite
+		b.n++
+	}
+	out := []store.MetricsSample{}
+	for k, b := range buckets {
+		out = append(out, store.MetricsSample{
+			At:        k,
+			CPU:       b.sum.CPU / b.n,
+			Mem:       b.sum.Mem / b.n,
+			Disk:      b.sum.Disk / b.n,
+			NetSend:   b.sum.NetSend / b.n,
+			NetRecv:   b.sum.NetRecv / b.n,
+			DiskRead:  b.sum.DiskRead / b.n,
+			DiskWrite: b.sum.DiskWrite / b.n,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].At < out[j].At })
+	return out, nil
+}
+
+// Ingested values.
+
+func (s *Store) PutIngestValue(key string, payload []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ingest[key] = store.IngestValue{Key: key, Payload: append([]byte(nil), payload...), UpdatedAt: time.Now().Unix()}
+	return nil
+}
+
+func (s *Store) GetIngestValue(key string) (store.IngestValue, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.ingest[key]
+	return v, ok, nil
+}
+
+func (s *Store) ListIngestValues() ([]store.IngestValue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []store.IngestValue{}
+	for _, v := range s.ingest {
+		out = append(out, v)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out, nil
+}
+
+func (s *Store) CountIngestValues() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.ingest), nil
+}
+
+func (s *Store) DeleteIngestValue(key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.ingest[key]
+	delete(s.ingest, key)
+	return ok, nil
+}
+
+// Holdings, personal dates and quotes.
+
+func (s *Store) ListHoldings() ([]store.Holding, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := append([]store.Holding{}, s.holdings...)
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].SortOrder != out[j].SortOrder {
+			return out[i].SortOrder < out[j].SortOrder
+		}
+		return out[i].Symbol < out[j].Symbol
+	})
+	return out, nil
+}
+
+func (s *Store) ReplaceHoldings(list []store.Holding) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().Unix()
+	s.holdings = make([]store.Holding, 0, len(list))
+	for i, h := range list {
+		h.SortOrder, h.UpdatedAt = i, now
+		s.holdings = append(s.holdings, h)
+	}
+	return nil
+}
+
+func (s *Store) ListCustomEvents() ([]store.CustomEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := append([]store.CustomEvent{}, s.events...)
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].SortOrder != out[j].SortOrder {
+			return out[i].SortOrder < out[j].SortOrder
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out, nil
+}
+
+func (s *Store) ReplaceCustomEvents(list []store.CustomEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().Unix()
+	s.events = make([]store.CustomEvent, 0, len(list))
+	for i, e := range list {
+		e.ID, e.SortOrder, e.UpdatedAt = s.id(), i, now
+		s.events = append(s.events, e)
+	}
+	return nil
+}
+
+func (s *Store) ListCustomQuotes() ([]store.CustomQuote, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := append([]store.CustomQuote{}, s.quotes...)
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+func (s *Store) AddCustomQuote(text, author string) (store.CustomQuote, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q := store.CustomQuote{ID: s.id(), Text: text, Author: author, CreatedAt: time.Now().Unix()}
+	s.quotes = append(s.quotes, q)
+	return q, nil
+}
+
+func (s *Store) DeleteCustomQuote(id int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, q := range s.quotes {
+		if q.ID == id {
+			s.quotes = append(s.quotes[:i], s.quotes[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
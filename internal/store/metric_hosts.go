@@ -0,0 +1,75 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// MetricHost is a remote machine running `hearth --agent`.
+type MetricHost struct {
+	Name      string
+	URL       string
+	Token     string
+	CreatedAt int64
+}
+
+// ListMetricHosts returns the registered agents ordered by name.
+func (s *Store) ListMetricHosts() ([]MetricHost, error) {
+	rows, err := s.db.Query(`SELECT name, url, token, created_at FROM metric_hosts ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []MetricHost{}
+	for rows.Next() {
+		var h MetricHost
+		if err := rows.Scan(&h.Name, &h.URL, &h.Token, &h.CreatedAt); err != nil {
+			return nil, err
+		}
+		if h.Token, err = s.open(h.Token); err != nil {
+			return nil, err
+		}
+		out = append(out, h)
+	}
+	return out, rows.Err()
+}
+
+// GetMetricHost returns the agent registered as name.
+func (s *Store) GetMetricHost(name string) (MetricHost, bool, error) {
+	var h MetricHost
+	err := s.db.QueryRow(`SELECT name, url, token, created_at FROM metric_hosts WHERE name = ?`, name).
+		Scan(&h.Name, &h.URL, &h.Token, &h.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return MetricHost{}, false, nil
+		}
+		return MetricHost{}, false, err
+	}
+	if h.Token, err = s.open(h.Token); err != nil {
+		return MetricHost{}, false, err
+	}
+	return h, true, nil
+}
+
+// PutMetricHost registers an agent or updates its URL and token.
+func (s *Store) PutMetricHost(h MetricHost) error {
+	token, err := s.seal(h.Token)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO metric_hosts (name, url, token, created_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET url=excluded.url, token=excluded.token`,
+		h.Name, h.URL, token, time.Now().Unix())
+	return err
+}
+
+// DeleteMetricHost removes an agent. It reports whether one was registered.
+func (s *Store) DeleteMetricHost(name string) (bool, error) {
+	res, err := s.db.Exec(`DELETE FROM metric_hosts WHERE name = ?`, name)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
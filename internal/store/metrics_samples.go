@@ -0,0 +1,56 @@
+package store
+
+// MetricsSample is one host metrics reading kept for the history charts.
+// Percentages are 0..100, rates bytes per second.
+type MetricsSample struct {
+	At        int64   `json:"t"` // unix seconds
+	CPU       float64 `json:"cpu"`
+	Mem       float64 `json:"mem"`
+	Disk      float64 `json:"disk"`
+	NetSend   float64 `json:"netSend"`
+	NetRecv   float64 `json:"netRecv"`
+	DiskRead  float64 `json:"diskRead"`
+	DiskWrite float64 `json:"diskWrite"`
+}
+
+// AddMetricsSample records a sample, replacing one taken the same second.
+func (s *Store) AddMetricsSample(m MetricsSample) error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO metrics_samples (at, cpu, mem, disk, net_send, net_recv, disk_read, disk_write)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		m.At, m.CPU, m.Mem, m.Disk, m.NetSend, m.NetRecv, m.DiskRead, m.DiskWrite)
+	return err
+}
+
+// PruneMetricsSamples deletes samples taken before the unix time before.
+func (s *Store) PruneMetricsSamples(before int64) (int64, error) {
+	res, err := s.db.Exec(`DELETE FROM metrics_samples WHERE at < ?`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// MetricsHistory returns the samples taken since the unix time since,
+// averaged over buckets of step seconds and oldest first. Each bucket is
+// stamped with its start.
+func (s *Store) MetricsHistory(since, step int64) ([]MetricsSample, error) {
+	if step < 1 {
+		step = 1
+	}
+	rows, err := s.db.Query(`SELECT (at / ?) * ? AS bucket, AVG(cpu), AVG(mem), AVG(disk),
+			AVG(net_send), AVG(net_recv), AVG(disk_read), AVG(disk_write)
+		FROM metrics_samples WHERE at >= ? GROUP BY bucket ORDER BY bucket`, step, step, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []MetricsSample{}
+	for rows.Next() {
+		var m MetricsSample
+		if err := rows.Scan(&m.At, &m.CPU, &m.Mem, &m.Disk, &m.NetSend, &m.NetRecv, &m.DiskRead, &m.DiskWrite); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
@@ -13,9 +13,84 @@ type AppItem struct {
 	GroupID     *string `json:"groupId"`
 	Name        string  `json:"name"`
 	Description *string `json:"description"`
+	// URL is the public address; InternalURL, when set, is used instead for
+	// clients on the local network.
 	URL         string  `json:"url"`
-	IconPath    *string `json:"iconPath"`
-	IconSource  *string `json:"iconSource"`
-	SortOrder   int     `json:"sortOrder"`
-	CreatedAt   int64   `json:"createdAt"`
+	InternalURL *string `json:"internalUrl"`
+	// OpenMode is how the tile opens its link: new_tab, same_tab or iframe.
+	OpenMode string `json:"openMode"`
+	// WakeMAC is the MAC address woken by Wake-on-LAN from the tile.
+	WakeMAC *string `json:"wakeMac"`
+	// Shortcut is the key sequence opening the app, unique on the
+	// dashboard, e.g. "g p".
+	Shortcut *string     `json:"shortcut"`
+	Actions  []AppAction `json:"actions"`
+	// Preview is the fetched title and description of the app's page, if
+	// the admin asked for one. UpdateApp leaves it alone.
+	Preview *LinkPreview `json:"preview"`
+	// Archived apps are hidden from the dashboard but keep their config.
+	// UpdateApp leaves it alone.
+	Archived   bool    `json:"archived"`
+	IconPath   *string `json:"iconPath"`
+	IconSource *string `json:"iconSource"`
+	SortOrder  int     `json:"sortOrder"`
+	CreatedAt  int64   `json:"createdAt"`
+	UpdatedAt  int64   `json:"updatedAt"`
+}
+
+// LinkPreview is what an app's page says about itself.
+type LinkPreview struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	FetchedAt   int64  `json:"fetchedAt"`
+}
+
+// AppAction is a quick action run from an app tile. Kind selects which of
+// the target fields apply: URL, Method and Body for a webhook, Container for
+// a Docker restart, Runner (configured on the server) for an SSH command.
+type AppAction struct {
+	ID        string `json:"id"`
+	Label     string `json:"label"`
+	Kind      string `json:"kind"` // webhook|docker_restart|ssh
+	URL       string `json:"url,omitempty"`
+	Method    string `json:"method,omitempty"`
+	Body      string `json:"body,omitempty"`
+	Container string `json:"container,omitempty"`
+	Runner    string `json:"runner,omitempty"`
+}
+
+// DefaultOpenMode is the open mode of apps that don't set one.
+const DefaultOpenMode = "new_tab"
+
+// Holding is a portfolio position for a market symbol. CostBasis is the
+// average cost per unit in USD.
+type Holding struct {
+	Symbol    string  `json:"symbol"`
+	Quantity  float64 `json:"quantity"`
+	CostBasis float64 `json:"costBasis"`
+	SortOrder int     `json:"sortOrder"`
+	UpdatedAt int64   `json:"updatedAt"`
+}
+
+// CustomEvent is a personal recurring date (birthday, anniversary...) shown in
+// the holidays widget. Year is the first occurrence, 0 when unknown; Lunar
+// dates are on the Chinese calendar.
+type CustomEvent struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	Month     int    `json:"month"`
+	Day       int    `json:"day"`
+	Year      int    `json:"year,omitempty"`
+	Lunar     bool   `json:"lunar"`
+	SortOrder int    `json:"sortOrder"`
+	UpdatedAt int64  `json:"updatedAt"`
+}
+
+// CustomQuote is a quote added by the admin for the quote widget.
+type CustomQuote struct {
+	ID        int64  `json:"id"`
+	Text      string `json:"text"`
+	Author    string `json:"author,omitempty"`
+	CreatedAt int64  `json:"createdAt"`
 }
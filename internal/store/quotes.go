@@ -0,0 +1,43 @@
+package store
+
+import "time"
+
+// ListCustomQuotes returns the admin's quotes in the order they were added.
+func (s *Store) ListCustomQuotes() ([]CustomQuote, error) {
+	rows, err := s.db.Query(`SELECT id, text, author, created_at FROM custom_quotes ORDER BY id ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []CustomQuote{}
+	for rows.Next() {
+		var q CustomQuote
+		if err := rows.Scan(&q.ID, &q.Text, &q.Author, &q.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, q)
+	}
+	return out, rows.Err()
+}
+
+// AddCustomQuote stores a new quote and returns it with its ID.
+func (s *Store) AddCustomQuote(text, author string) (CustomQuote, error) {
+	q := CustomQuote{Text: text, Author: author, CreatedAt: time.Now().Unix()}
+	res, err := s.db.Exec(`INSERT INTO custom_quotes (text, author, created_at) VALUES (?, ?, ?)`, q.Text, q.Author, q.CreatedAt)
+	if err != nil {
+		return CustomQuote{}, err
+	}
+	q.ID, err = res.LastInsertId()
+	return q, err
+}
+
+// DeleteCustomQuote removes a quote. It reports whether the quote existed.
+func (s *Store) DeleteCustomQuote(id int64) (bool, error) {
+	res, err := s.db.Exec(`DELETE FROM custom_quotes WHERE id = ?`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
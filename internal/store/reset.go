@@ -1,14 +1,9 @@
 package store
 
-import (
-	"time"
+import "fmt"
 
-	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
-)
-
-// ResetAll clears all persisted configuration and cached data.
-// It also resets authentication back to the default admin/admin.
+// ResetAll clears all persisted configuration and cached data, users and
+// sessions included; the auth service then creates the admin again.
 func (s *Store) ResetAll() error {
 	tx, err := s.db.Begin()
 	if err != nil {
@@ -19,12 +14,31 @@ func (s *Store) ResetAll() error {
 	// Order matters for FKs.
 	stmts := []string{
 		`DELETE FROM sessions;`,
+		`DELETE FROM user_kv;`,
 		`DELETE FROM users;`,
+		`DELETE FROM widget_configs;`,
 		`DELETE FROM apps;`,
 		`DELETE FROM groups;`,
-		`DELETE FROM kv;`,
+		`DELETE FROM kv WHERE key NOT LIKE 'crypto.%';`,
+		`DELETE FROM holdings;`,
+		`DELETE FROM custom_events;`,
+		`DELETE FROM custom_quotes;`,
 		`DELETE FROM icon_cache;`,
 		`DELETE FROM background_cache;`,
+		`DELETE FROM background_history;`,
+		`DELETE FROM metrics_samples;`,
+		`DELETE FROM metric_hosts;`,
+		`DELETE FROM webhook_deliveries;`,
+		`DELETE FROM action_audit;`,
+		`DELETE FROM settings_history;`,
+		`DELETE FROM shares;`,
+		`DELETE FROM webhooks;`,
+		`DELETE FROM backup_targets;`,
+		`DELETE FROM login_ips;`,
+		`DELETE FROM ingest_values;`,
+		`DELETE FROM widget_cache;`,
+		`DELETE FROM symbol_map;`,
+		`DELETE FROM geocode_cache;`,
 	}
 	for _, stmt := range stmts {
 		if _, err := tx.Exec(stmt); err != nil {
@@ -32,17 +46,56 @@ func (s *Store) ResetAll() error {
 		}
 	}
 
-	hash, err := bcrypt.GenerateFromPassword([]byte("admin"), bcrypt.DefaultCost)
+	return tx.Commit()
+}
+
+// Scopes of ResetScope, each clearing one part of the data and leaving the
+// rest, users and sessions included, alone.
+const (
+	ResetApps     = "apps"     // apps and app groups; widgets stay
+	ResetWidgets  = "widgets"  // widgets and their configurations
+	ResetCaches   = "caches"   // icon, background, widget, symbol and geocode caches
+	ResetSettings = "settings" // settings, users' own settings and page customization
+)
+
+var resetScopeStmts = map[string][]string{
+	ResetApps: {
+		`DELETE FROM apps WHERE url NOT LIKE 'widget:%';`,
+		`DELETE FROM groups WHERE kind <> 'system';`,
+	},
+	ResetWidgets: {
+		`DELETE FROM widget_configs;`,
+		`DELETE FROM apps WHERE url LIKE 'widget:%';`,
+	},
+	ResetCaches: {
+		`DELETE FROM icon_cache;`,
+		`DELETE FROM background_cache;`,
+		`DELETE FROM background_history;`,
+		`DELETE FROM widget_cache;`,
+		`DELETE FROM symbol_map;`,
+		`DELETE FROM geocode_cache;`,
+	},
+	ResetSettings: {
+		`DELETE FROM kv WHERE key LIKE 'settings.%' OR key LIKE 'customization.%';`,
+		`DELETE FROM user_kv;`,
+	},
+}
+
+// ResetScope clears the data of one reset scope.
+func (s *Store) ResetScope(scope string) error {
+	stmts, ok := resetScopeStmts[scope]
+	if !ok {
+		return fmt.Errorf("unknown reset scope %q", scope)
+	}
+	tx, err := s.db.Begin()
 	if err != nil {
 		return err
 	}
-	now := time.Now().Unix()
-	if _, err := tx.Exec(
-		`INSERT INTO users (id, username, password_hash, created_at) VALUES (?, ?, ?, ?)`,
-		uuid.NewString(), "admin", string(hash), now,
-	); err != nil {
-		return err
+	defer tx.Rollback()
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
 	}
-
 	return tx.Commit()
 }
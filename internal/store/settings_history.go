@@ -0,0 +1,94 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// maxSettingChanges is how many settings changes are kept.
+const maxSettingChanges = 500
+
+// SettingChange records one change of a setting. A nil value means the key
+// was not set, or was removed.
+type SettingChange struct {
+	ID        int64   `json:"id"`
+	Key       string  `json:"key"`
+	OldValue  *string `json:"oldValue"`
+	NewValue  *string `json:"newValue"`
+	Actor     string  `json:"actor"`
+	ChangedAt int64   `json:"changedAt"`
+	// Redacted changes of secrets keep no values and can't be reverted.
+	Redacted bool `json:"redacted,omitempty"`
+	// RevertOf is the change this one reverted.
+	RevertOf int64 `json:"revertOf,omitempty"`
+}
+
+// RecordSettingChange appends c to the settings history, dropping the oldest
+// entries beyond maxSettingChanges, and returns it with its id.
+func (s *Store) RecordSettingChange(c SettingChange) (SettingChange, error) {
+	c.ChangedAt = time.Now().Unix()
+	res, err := s.db.Exec(`INSERT INTO settings_history (key, old_value, new_value, actor, changed_at, redacted, revert_of) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		c.Key, c.OldValue, c.NewValue, c.Actor, c.ChangedAt, boolInt(c.Redacted), c.RevertOf)
+	if err != nil {
+		return SettingChange{}, err
+	}
+	if c.ID, err = res.LastInsertId(); err != nil {
+		return SettingChange{}, err
+	}
+	_, err = s.db.Exec(`DELETE FROM settings_history WHERE id <= ?`, c.ID-maxSettingChanges)
+	return c, err
+}
+
+const settingChangeColumns = `id, key, old_value, new_value, actor, changed_at, redacted, revert_of`
+
+func scanSettingChange(row interface{ Scan(...any) error }) (SettingChange, error) {
+	var c SettingChange
+	var oldV, newV sql.NullString
+	var redacted int
+	if err := row.Scan(&c.ID, &c.Key, &oldV, &newV, &c.Actor, &c.ChangedAt, &redacted, &c.RevertOf); err != nil {
+		return SettingChange{}, err
+	}
+	if oldV.Valid {
+		c.OldValue = &oldV.String
+	}
+	if newV.Valid {
+		c.NewValue = &newV.String
+	}
+	c.Redacted = redacted != 0
+	return c, nil
+}
+
+// ListSettingChanges returns the newest changes first, of one key when key
+// is set.
+func (s *Store) ListSettingChanges(key string, limit int) ([]SettingChange, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	rows, err := s.db.Query(`SELECT `+settingChangeColumns+` FROM settings_history
+		WHERE ? = '' OR key = ? ORDER BY id DESC LIMIT ?`, key, key, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []SettingChange{}
+	for rows.Next() {
+		c, err := scanSettingChange(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) GetSettingChange(id int64) (SettingChange, bool, error) {
+	c, err := scanSettingChange(s.db.QueryRow(`SELECT `+settingChangeColumns+` FROM settings_history WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return SettingChange{}, false, nil
+	}
+	if err != nil {
+		return SettingChange{}, false, err
+	}
+	return c, true, nil
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Share publishes one app group, or every app group when GroupID is nil, at
+// a read-only link. Only the SHA-256 of the link's token is stored.
+type Share struct {
+	ID        string  `json:"id"`
+	Name      string  `json:"name"`
+	GroupID   *string `json:"groupId"`
+	TokenHash string  `json:"-"`
+	CreatedAt int64   `json:"createdAt"`
+}
+
+const shareColumns = `id, name, group_id, token_hash, created_at`
+
+func scanShare(row interface{ Scan(...any) error }) (Share, error) {
+	var sh Share
+	err := row.Scan(&sh.ID, &sh.Name, &sh.GroupID, &sh.TokenHash, &sh.CreatedAt)
+	return sh, err
+}
+
+// ListShares returns all shares in the order they were created.
+func (s *Store) ListShares() ([]Share, error) {
+	rows, err := s.db.Query(`SELECT ` + shareColumns + ` FROM shares ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Share{}
+	for rows.Next() {
+		sh, err := scanShare(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, sh)
+	}
+	return out, rows.Err()
+}
+
+// CreateShare adds sh and returns it with its id.
+func (s *Store) CreateShare(sh Share) (Share, error) {
+	sh.ID = uuid.NewString()
+	sh.CreatedAt = time.Now().Unix()
+	_, err := s.db.Exec(`INSERT INTO shares (`+shareColumns+`) VALUES (?, ?, ?, ?, ?)`,
+		sh.ID, sh.Name, sh.GroupID, sh.TokenHash, sh.CreatedAt)
+	if err != nil {
+		return Share{}, err
+	}
+	return sh, nil
+}
+
+// DeleteShare revokes a share. It reports whether the share existed.
+func (s *Store) DeleteShare(id string) (bool, error) {
+	res, err := s.db.Exec(`DELETE FROM shares WHERE id = ?`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ShareByTokenHash returns the share whose token hashes to hash.
+func (s *Store) ShareByTokenHash(hash string) (Share, bool, error) {
+	sh, err := scanShare(s.db.QueryRow(`SELECT `+shareColumns+` FROM shares WHERE token_hash = ?`, hash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Share{}, false, nil
+		}
+		return Share{}, false, err
+	}
+	return sh, true, nil
+}
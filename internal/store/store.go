@@ -10,7 +10,8 @@ import (
 )
 
 type Store struct {
-	db *sql.DB
+	db     *sql.DB
+	sealer *sealer // nil unless EnableEncryption was called with a key
 }
 
 func New(db *sql.DB) *Store {
@@ -22,12 +23,23 @@ func (s *Store) Ping() error {
 	return s.db.Ping()
 }
 
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
 func (s *Store) Migrate() error {
 	stmts := []string{
 		`CREATE TABLE IF NOT EXISTS kv (
 			key TEXT PRIMARY KEY,
 			value TEXT NOT NULL
 		);`,
+		`CREATE TABLE IF NOT EXISTS user_kv (
+			user_id TEXT NOT NULL,
+			key TEXT NOT NULL,
+			value TEXT NOT NULL,
+			PRIMARY KEY (user_id, key)
+		);`,
 		`CREATE TABLE IF NOT EXISTS icon_cache (
 			cache_key TEXT PRIMARY KEY,
 			icon_path TEXT NOT NULL,
@@ -39,6 +51,61 @@ func (s *Store) Migrate() error {
 			file_path TEXT NOT NULL,
 			fetched_at INTEGER NOT NULL
 		);`,
+		`CREATE TABLE IF NOT EXISTS background_history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			cache_key TEXT NOT NULL,
+			file_path TEXT NOT NULL,
+			source_url TEXT NOT NULL DEFAULT '',
+			fetched_at INTEGER NOT NULL
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_background_history_key ON background_history(cache_key, fetched_at);`,
+		`CREATE TABLE IF NOT EXISTS metrics_samples (
+			at INTEGER PRIMARY KEY,
+			cpu REAL NOT NULL,
+			mem REAL NOT NULL,
+			disk REAL NOT NULL,
+			net_send REAL NOT NULL,
+			net_recv REAL NOT NULL,
+			disk_read REAL NOT NULL,
+			disk_write REAL NOT NULL
+		);`,
+		`CREATE TABLE IF NOT EXISTS webhooks (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			url TEXT NOT NULL,
+			secret TEXT NOT NULL,
+			events TEXT NOT NULL DEFAULT '',
+			enabled INTEGER NOT NULL DEFAULT 1,
+			created_at INTEGER NOT NULL
+		);`,
+		`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			webhook_id INTEGER NOT NULL,
+			event TEXT NOT NULL,
+			payload BLOB NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			next_attempt_at INTEGER NOT NULL,
+			last_error TEXT NOT NULL DEFAULT '',
+			status_code INTEGER NOT NULL DEFAULT 0,
+			created_at INTEGER NOT NULL,
+			delivered_at INTEGER NOT NULL DEFAULT 0
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at);`,
+		`CREATE TABLE IF NOT EXISTS login_ips (
+			ip TEXT PRIMARY KEY,
+			first_seen INTEGER NOT NULL,
+			last_seen INTEGER NOT NULL
+		);`,
+		`CREATE TABLE IF NOT EXISTS ingest_values (
+			key TEXT PRIMARY KEY,
+			payload BLOB NOT NULL,
+			updated_at INTEGER NOT NULL
+		);`,
+		`CREATE TABLE IF NOT EXISTS metric_hosts (
+			name TEXT PRIMARY KEY,
+			url TEXT NOT NULL,
+			token TEXT NOT NULL,
+			created_at INTEGER NOT NULL
+		);`,
 		`CREATE TABLE IF NOT EXISTS groups (
 			id TEXT PRIMARY KEY,
 			name TEXT NOT NULL,
@@ -62,7 +129,8 @@ func (s *Store) Migrate() error {
 			id TEXT PRIMARY KEY,
 			username TEXT NOT NULL UNIQUE,
 			password_hash TEXT NOT NULL,
-			created_at INTEGER NOT NULL
+			created_at INTEGER NOT NULL,
+			must_change_password INTEGER NOT NULL DEFAULT 0
 		);`,
 		`CREATE TABLE IF NOT EXISTS sessions (
 			token TEXT PRIMARY KEY,
@@ -72,6 +140,107 @@ func (s *Store) Migrate() error {
 			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
 		);`,
 		`CREATE INDEX IF NOT EXISTS idx_sessions_expires ON sessions(expires_at);`,
+		`CREATE TABLE IF NOT EXISTS widget_cache (
+			cache_key TEXT PRIMARY KEY,
+			payload BLOB NOT NULL,
+			fetched_at INTEGER NOT NULL,
+			expires_at INTEGER NOT NULL
+		);`,
+		`CREATE TABLE IF NOT EXISTS symbol_map (
+			provider TEXT NOT NULL,
+			symbol TEXT NOT NULL,
+			provider_id TEXT NOT NULL,
+			name TEXT NOT NULL DEFAULT '',
+			fetched_at INTEGER NOT NULL,
+			expires_at INTEGER NOT NULL,
+			PRIMARY KEY (provider, symbol)
+		);`,
+		`CREATE TABLE IF NOT EXISTS geocode_cache (
+			query TEXT NOT NULL,
+			language TEXT NOT NULL,
+			lat REAL NOT NULL,
+			lon REAL NOT NULL,
+			display_name TEXT NOT NULL DEFAULT '',
+			timezone TEXT NOT NULL DEFAULT '',
+			country_code TEXT NOT NULL DEFAULT '',
+			fetched_at INTEGER NOT NULL,
+			expires_at INTEGER NOT NULL,
+			PRIMARY KEY (query, language)
+		);`,
+		`CREATE TABLE IF NOT EXISTS holdings (
+			symbol TEXT PRIMARY KEY,
+			quantity REAL NOT NULL,
+			cost_basis REAL NOT NULL DEFAULT 0,
+			sort_order INTEGER NOT NULL DEFAULT 0,
+			updated_at INTEGER NOT NULL
+		);`,
+		`CREATE TABLE IF NOT EXISTS custom_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			kind TEXT NOT NULL DEFAULT 'custom',
+			month INTEGER NOT NULL,
+			day INTEGER NOT NULL,
+			year INTEGER NOT NULL DEFAULT 0,
+			lunar INTEGER NOT NULL DEFAULT 0,
+			sort_order INTEGER NOT NULL DEFAULT 0,
+			updated_at INTEGER NOT NULL
+		);`,
+		`CREATE TABLE IF NOT EXISTS widget_configs (
+			app_id TEXT PRIMARY KEY,
+			kind TEXT NOT NULL,
+			config TEXT NOT NULL,
+			updated_at INTEGER NOT NULL
+		);`,
+		`CREATE TABLE IF NOT EXISTS action_audit (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			app_id TEXT NOT NULL,
+			action_id TEXT NOT NULL,
+			label TEXT NOT NULL DEFAULT '',
+			kind TEXT NOT NULL,
+			ok INTEGER NOT NULL,
+			detail TEXT NOT NULL DEFAULT '',
+			remote_ip TEXT NOT NULL DEFAULT '',
+			created_at INTEGER NOT NULL
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_action_audit_app ON action_audit(app_id, id);`,
+		`CREATE TABLE IF NOT EXISTS settings_history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			key TEXT NOT NULL,
+			old_value TEXT,
+			new_value TEXT,
+			actor TEXT NOT NULL DEFAULT '',
+			changed_at INTEGER NOT NULL,
+			redacted INTEGER NOT NULL DEFAULT 0,
+			revert_of INTEGER NOT NULL DEFAULT 0
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_settings_history_key ON settings_history(key, id);`,
+		`CREATE TABLE IF NOT EXISTS backup_targets (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			kind TEXT NOT NULL,
+			config TEXT NOT NULL DEFAULT '{}',
+			secret TEXT NOT NULL DEFAULT '',
+			keep INTEGER NOT NULL DEFAULT 0,
+			interval_seconds INTEGER NOT NULL,
+			enabled INTEGER NOT NULL DEFAULT 1,
+			created_at INTEGER NOT NULL,
+			last_attempt_at INTEGER NOT NULL DEFAULT 0,
+			last_success_at INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT NOT NULL DEFAULT ''
+		);`,
+		`CREATE TABLE IF NOT EXISTS shares (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL DEFAULT '',
+			group_id TEXT,
+			token_hash TEXT NOT NULL UNIQUE,
+			created_at INTEGER NOT NULL
+		);`,
+		`CREATE TABLE IF NOT EXISTS custom_quotes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			text TEXT NOT NULL,
+			author TEXT NOT NULL DEFAULT '',
+			created_at INTEGER NOT NULL
+		);`,
 	}
 
 	for _, stmt := range stmts {
@@ -87,6 +256,22 @@ func (s *Store) Migrate() error {
 			return err
 		}
 	}
+	for _, col := range []string{"internal_url TEXT", "wake_mac TEXT", "shortcut TEXT", "preview TEXT", "archived INTEGER NOT NULL DEFAULT 0", "actions TEXT NOT NULL DEFAULT '[]'", "open_mode TEXT NOT NULL DEFAULT 'new_tab'", "updated_at INTEGER NOT NULL DEFAULT 0"} {
+		if _, err := s.db.Exec(`ALTER TABLE apps ADD COLUMN ` + col); err != nil {
+			// Ignore if column already exists.
+			errLower := strings.ToLower(err.Error())
+			if !strings.Contains(errLower, "duplicate") && !strings.Contains(errLower, "already exists") {
+				return err
+			}
+		}
+	}
+	// Apps from before updated_at count as updated when they were created.
+	if _, err := s.db.Exec(`UPDATE apps SET updated_at = created_at WHERE updated_at = 0`); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(`CREATE INDEX IF NOT EXISTS idx_apps_updated ON apps(updated_at)`); err != nil {
+		return err
+	}
 	if _, err := s.db.Exec(`ALTER TABLE groups ADD COLUMN kind TEXT NOT NULL DEFAULT 'app'`); err != nil {
 		// Ignore if column already exists.
 		errLower := strings.ToLower(err.Error())
@@ -94,6 +279,22 @@ func (s *Store) Migrate() error {
 			return err
 		}
 	}
+	if _, err := s.db.Exec(`ALTER TABLE users ADD COLUMN must_change_password INTEGER NOT NULL DEFAULT 0`); err != nil {
+		// Ignore if column already exists.
+		errLower := strings.ToLower(err.Error())
+		if !strings.Contains(errLower, "duplicate") && !strings.Contains(errLower, "already exists") {
+			return err
+		}
+	}
+	for _, col := range []string{"title", "credit", "link"} {
+		if _, err := s.db.Exec(`ALTER TABLE background_history ADD COLUMN ` + col + ` TEXT NOT NULL DEFAULT ''`); err != nil {
+			// Ignore if column already exists.
+			errLower := strings.ToLower(err.Error())
+			if !strings.Contains(errLower, "duplicate") && !strings.Contains(errLower, "already exists") {
+				return err
+			}
+		}
+	}
 	// Migrate legacy default system group names.
 	_, _ = s.db.Exec(`UPDATE groups SET kind = 'system' WHERE name IN ('系统组件', 'System Tools', 'System Widgets')`)
 
@@ -138,5 +339,7 @@ func (s *Store) Migrate() error {
 			systemID,
 		)
 	}
-	return nil
+
+	// Widget settings used to live only in the item description.
+	return migrateWidgetConfigs(s.db, false)
 }
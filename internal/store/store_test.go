@@ -1,8 +1,14 @@
 package store
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	_ "modernc.org/sqlite"
 )
@@ -141,7 +147,7 @@ func TestAppsCRUD(t *testing.T) {
 
 	// Create an app
 	groupID := g.ID
-	created, err := s.CreateApp(&groupID, "Test App", nil, "https://example.com", nil, nil)
+	created, err := s.CreateApp(AppItem{GroupID: &groupID, Name: "Test App", URL: "https://example.com"})
 	if err != nil {
 		t.Fatalf("CreateApp failed: %v", err)
 	}
@@ -162,7 +168,7 @@ func TestAppsCRUD(t *testing.T) {
 	}
 
 	// Update app
-	err = s.UpdateApp(created.ID, &groupID, "Updated App", nil, "https://example.com", nil, nil)
+	err = s.UpdateApp(AppItem{ID: created.ID, GroupID: &groupID, Name: "Updated App", URL: "https://example.com"})
 	if err != nil {
 		t.Fatalf("UpdateApp failed: %v", err)
 	}
@@ -187,6 +193,120 @@ func TestAppsCRUD(t *testing.T) {
 	}
 }
 
+func TestListRecentApps(t *testing.T) {
+	s := newTestStore(t)
+
+	var ids []string
+	for _, name := range []string{"Old", "Middle", "New"} {
+		a, err := s.CreateApp(AppItem{Name: name, URL: "https://" + name + ".example.com"})
+		if err != nil {
+			t.Fatalf("CreateApp failed: %v", err)
+		}
+		ids = append(ids, a.ID)
+	}
+	if _, err := s.CreateApp(AppItem{Name: "Clock", URL: "widget:clock"}); err != nil {
+		t.Fatalf("CreateApp failed: %v", err)
+	}
+	// Spread the creation times; Old was edited last.
+	for i, id := range ids {
+		if _, err := s.db.Exec(`UPDATE apps SET created_at = ?, updated_at = ? WHERE id = ?`, 1000+i, 1000+i, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.db.Exec(`UPDATE apps SET updated_at = 5000 WHERE id = ?`, ids[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	names := func(list []AppItem) string {
+		var out []string
+		for _, a := range list {
+			out = append(out, a.Name)
+		}
+		return strings.Join(out, ",")
+	}
+	byCreation, err := s.ListRecentApps(false, 10)
+	if err != nil {
+		t.Fatalf("ListRecentApps failed: %v", err)
+	}
+	if got := names(byCreation); got != "New,Middle,Old" {
+		t.Errorf("by creation: got %s", got)
+	}
+	byUpdate, _ := s.ListRecentApps(true, 2)
+	if got := names(byUpdate); got != "Old,New" {
+		t.Errorf("by update: got %s", got)
+	}
+
+	a, _, _ := s.AppByID(ids[1])
+	if err := s.UpdateApp(a); err != nil {
+		t.Fatalf("UpdateApp failed: %v", err)
+	}
+	a, _, _ = s.AppByID(ids[1])
+	if a.UpdatedAt <= 5000 || a.CreatedAt != 1001 {
+		t.Errorf("UpdateApp should stamp updated_at only: %+v", a)
+	}
+}
+
+func TestMoveApp(t *testing.T) {
+	s := newTestStore(t)
+	g1, _ := s.CreateGroup("One", "app")
+	g2, _ := s.CreateGroup("Two", "app")
+	create := func(name string, g *string) string {
+		a, err := s.CreateApp(AppItem{GroupID: g, Name: name, URL: "https://" + name + ".example.com"})
+		if err != nil {
+			t.Fatalf("CreateApp failed: %v", err)
+		}
+		return a.ID
+	}
+	a := create("a", &g1.ID)
+	create("b", &g1.ID)
+	create("c", &g1.ID)
+	x := create("x", &g2.ID)
+	y := create("y", &g2.ID)
+	if _, err := s.SetAppArchived(x, true); err != nil {
+		t.Fatal(err)
+	}
+	order := func(g string) string {
+		apps, _ := s.ListApps()
+		var out []string
+		for _, app := range apps {
+			if app.GroupID != nil && *app.GroupID == g {
+				out = append(out, fmt.Sprintf("%s%d", app.Name, app.SortOrder))
+			}
+		}
+		return strings.Join(out, ",")
+	}
+
+	// Index 1 counts visible apps: after y, the archived x staying first.
+	if ok, err := s.MoveApp(a, &g2.ID, 1); err != nil || !ok {
+		t.Fatalf("MoveApp failed: %v %v", ok, err)
+	}
+	if got := order(g1.ID); got != "b1,c2" {
+		t.Errorf("source group: got %s", got)
+	}
+	if got := order(g2.ID); got != "x1,y2,a3" {
+		t.Errorf("target group: got %s", got)
+	}
+
+	// Within a group, out-of-range indexes clamp; the first visible place
+	// is still behind the archived x.
+	if _, err := s.MoveApp(y, &g2.ID, -5); err != nil {
+		t.Fatal(err)
+	}
+	if got := order(g2.ID); got != "x1,y2,a3" {
+		t.Errorf("after moving to the front: got %s", got)
+	}
+	if _, err := s.MoveApp(y, &g2.ID, 99); err != nil {
+		t.Fatal(err)
+	}
+	if got := order(g2.ID); got != "x1,a2,y3" {
+		t.Errorf("after moving to the end: got %s", got)
+	}
+
+	if ok, err := s.MoveApp("missing", nil, 0); err != nil || ok {
+		t.Errorf("expected a missing app to report false, got %v %v", ok, err)
+	}
+}
+
 func TestKVOperations(t *testing.T) {
 	s := newTestStore(t)
 
@@ -225,3 +345,597 @@ func TestKVOperations(t *testing.T) {
 		t.Errorf("expected 'updated_value', got '%s'", val)
 	}
 }
+
+func TestUserKV(t *testing.T) {
+	s := newTestStore(t)
+
+	if err := s.SetUserKV("u1", "settings.language", "en"); err != nil {
+		t.Fatalf("SetUserKV failed: %v", err)
+	}
+	_ = s.SetUserKV("u1", "settings.language", "de")
+	_ = s.SetUserKV("u1", "settings.theme.mode", "light")
+	_ = s.SetUserKV("u2", "settings.language", "fr")
+
+	got, err := s.ListUserKV("u1")
+	if err != nil {
+		t.Fatalf("ListUserKV failed: %v", err)
+	}
+	if len(got) != 2 || got["settings.language"] != "de" || got["settings.theme.mode"] != "light" {
+		t.Errorf("u1 settings = %v", got)
+	}
+
+	all, err := s.ListAllUserKV()
+	if err != nil {
+		t.Fatalf("ListAllUserKV failed: %v", err)
+	}
+	if len(all) != 2 || all["u2"]["settings.language"] != "fr" || len(all["u1"]) != 2 {
+		t.Errorf("all user settings = %v", all)
+	}
+
+	if err := s.DeleteUserKV("u1", "settings.theme.mode"); err != nil {
+		t.Fatalf("DeleteUserKV failed: %v", err)
+	}
+	if got, _ := s.ListUserKV("u1"); len(got) != 1 {
+		t.Errorf("u1 settings after delete = %v", got)
+	}
+
+	if err := s.ResetScope(ResetSettings); err != nil {
+		t.Fatalf("ResetScope failed: %v", err)
+	}
+	if got, _ := s.ListUserKV("u2"); len(got) != 0 {
+		t.Errorf("u2 settings after reset = %v", got)
+	}
+}
+
+func TestSettingsHistory(t *testing.T) {
+	s := newTestStore(t)
+
+	old, next := "bing", "picsum"
+	first, err := s.RecordSettingChange(SettingChange{Key: "settings.background.provider", NewValue: &old, Actor: "admin"})
+	if err != nil {
+		t.Fatalf("RecordSettingChange failed: %v", err)
+	}
+	_, _ = s.RecordSettingChange(SettingChange{Key: "settings.background.provider", OldValue: &old, NewValue: &next, Actor: "admin"})
+	_, _ = s.RecordSettingChange(SettingChange{Key: "settings.weather.apiKey", Redacted: true})
+
+	got, err := s.ListSettingChanges("settings.background.provider", 0)
+	if err != nil {
+		t.Fatalf("ListSettingChanges failed: %v", err)
+	}
+	if len(got) != 2 || *got[0].NewValue != "picsum" || *got[0].OldValue != "bing" || got[1].OldValue != nil {
+		t.Fatalf("unexpected changes: %+v", got)
+	}
+	if all, _ := s.ListSettingChanges("", 0); len(all) != 3 || !all[0].Redacted {
+		t.Errorf("unexpected history: %+v", all)
+	}
+
+	c, ok, err := s.GetSettingChange(first.ID)
+	if err != nil || !ok || c.Actor != "admin" || *c.NewValue != "bing" {
+		t.Errorf("GetSettingChange = %+v, %v, %v", c, ok, err)
+	}
+	if _, ok, _ := s.GetSettingChange(first.ID + 100); ok {
+		t.Error("unknown change found")
+	}
+}
+
+func TestHoldingsReplaceAndExport(t *testing.T) {
+	s := newTestStore(t)
+
+	list := []Holding{{Symbol: "MSFT", Quantity: 3, CostBasis: 310}, {Symbol: "BTC", Quantity: 0.25}}
+	if err := s.ReplaceHoldings(list); err != nil {
+		t.Fatalf("ReplaceHoldings failed: %v", err)
+	}
+	got, err := s.ListHoldings()
+	if err != nil {
+		t.Fatalf("ListHoldings failed: %v", err)
+	}
+	if len(got) != 2 || got[0].Symbol != "MSFT" || got[1].Symbol != "BTC" || got[0].CostBasis != 310 {
+		t.Fatalf("unexpected holdings: %+v", got)
+	}
+
+	exp, err := s.ExportAll()
+	if err != nil {
+		t.Fatalf("ExportAll failed: %v", err)
+	}
+	if err := s.ReplaceHoldings(nil); err != nil {
+		t.Fatalf("ReplaceHoldings failed: %v", err)
+	}
+	if got, _ := s.ListHoldings(); len(got) != 0 {
+		t.Fatalf("expected empty portfolio, got %+v", got)
+	}
+	if err := s.ImportAll(exp); err != nil {
+		t.Fatalf("ImportAll failed: %v", err)
+	}
+	if got, _ := s.ListHoldings(); len(got) != 2 {
+		t.Fatalf("expected holdings restored from backup, got %+v", got)
+	}
+}
+
+func TestReplaceAll(t *testing.T) {
+	s := newTestStore(t)
+	g, _ := s.CreateGroup("Media", "app")
+	if _, err := s.CreateApp(AppItem{GroupID: &g.ID, Name: "jellyfin", URL: "http://jf.lan"}); err != nil {
+		t.Fatalf("CreateApp failed: %v", err)
+	}
+	if err := s.SetKV("siteTitle", "Home"); err != nil {
+		t.Fatal(err)
+	}
+	exp, err := s.ExportAll()
+	if err != nil {
+		t.Fatalf("ExportAll failed: %v", err)
+	}
+
+	if _, err := s.CreateApp(AppItem{Name: "extra", URL: "http://x.lan"}); err != nil {
+		t.Fatalf("CreateApp failed: %v", err)
+	}
+	if _, err := s.CreateGroup("Extra", "app"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetKV("other", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ReplaceAll(exp); err != nil {
+		t.Fatalf("ReplaceAll failed: %v", err)
+	}
+	apps, _ := s.ListApps()
+	if len(apps) != 1 || apps[0].Name != "jellyfin" {
+		t.Fatalf("unexpected apps after replace: %+v", apps)
+	}
+	groups, _ := s.ListGroups()
+	for _, gr := range groups {
+		if gr.Name == "Extra" {
+			t.Fatalf("group missing from the payload should be removed: %+v", groups)
+		}
+	}
+	if _, ok, _ := s.GetKV("other"); ok {
+		t.Fatal("settings missing from the payload should be removed")
+	}
+	if v, _, _ := s.GetKV("siteTitle"); v != "Home" {
+		t.Fatalf("siteTitle = %q", v)
+	}
+}
+
+func TestWidgetCacheExpiry(t *testing.T) {
+	s := newTestStore(t)
+	now := time.Now().Unix()
+
+	if err := s.SetWidgetCache(WidgetCacheEntry{CacheKey: "markets:BTC", Payload: []byte(`{}`), FetchedAt: now, ExpiresAt: now + 60}); err != nil {
+		t.Fatalf("SetWidgetCache failed: %v", err)
+	}
+	if err := s.SetWidgetCache(WidgetCacheEntry{CacheKey: "markets:old", Payload: []byte(`{}`), FetchedAt: now - 120, ExpiresAt: now - 60}); err != nil {
+		t.Fatalf("SetWidgetCache failed: %v", err)
+	}
+	if e, ok, err := s.GetWidgetCache("markets:BTC"); err != nil || !ok || string(e.Payload) != `{}` || e.FetchedAt != now {
+		t.Fatalf("unexpected cache entry: %+v ok=%v err=%v", e, ok, err)
+	}
+	if _, ok, _ := s.GetWidgetCache("markets:old"); ok {
+		t.Fatalf("expired entry must not be returned")
+	}
+
+	if err := s.SetSymbolMapping(SymbolMapping{Provider: "coingecko", Symbol: "BTC", ID: "bitcoin", Name: "Bitcoin", FetchedAt: now, ExpiresAt: now + 60}); err != nil {
+		t.Fatalf("SetSymbolMapping failed: %v", err)
+	}
+	if m, ok, err := s.GetSymbolMapping("coingecko", "BTC"); err != nil || !ok || m.ID != "bitcoin" {
+		t.Fatalf("unexpected mapping: %+v ok=%v err=%v", m, ok, err)
+	}
+
+	if err := s.PruneWidgetCaches(); err != nil {
+		t.Fatalf("PruneWidgetCaches failed: %v", err)
+	}
+	var n int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM widget_cache`).Scan(&n); err != nil || n != 1 {
+		t.Fatalf("expected 1 row after prune, got %d (%v)", n, err)
+	}
+}
+
+func TestBackgroundHistory(t *testing.T) {
+	s := newTestStore(t)
+
+	for _, p := range []string{"history/a.jpg", "history/b.jpg", "history/c.jpg"} {
+		if removed, err := s.AddBackgroundHistory(BackgroundHistoryEntry{CacheKey: "bg:picsum", FilePath: p, SourceURL: "https://example.com/" + p}, 2); err != nil {
+			t.Fatalf("AddBackgroundHistory failed: %v", err)
+		} else if p != "history/c.jpg" && len(removed) != 0 {
+			t.Fatalf("nothing should be dropped yet, got %v", removed)
+		} else if p == "history/c.jpg" && (len(removed) != 1 || removed[0] != "history/a.jpg") {
+			t.Fatalf("expected a.jpg to be dropped, got %v", removed)
+		}
+	}
+	list, err := s.ListBackgroundHistory("bg:picsum")
+	if err != nil || len(list) != 2 || list[0].FilePath != "history/c.jpg" || list[1].FilePath != "history/b.jpg" {
+		t.Fatalf("unexpected history: %+v (%v)", list, err)
+	}
+
+	// Re-adding moves an entry to the front instead of duplicating it.
+	if _, err := s.AddBackgroundHistory(BackgroundHistoryEntry{CacheKey: "bg:picsum", FilePath: "history/b.jpg", Title: "B"}, 2); err != nil {
+		t.Fatalf("AddBackgroundHistory failed: %v", err)
+	}
+	if list, _ = s.ListBackgroundHistory("bg:picsum"); len(list) != 2 || list[0].FilePath != "history/b.jpg" {
+		t.Fatalf("expected b.jpg first, got %+v", list)
+	}
+	if e, ok, err := s.GetBackgroundHistory("bg:picsum", "history/b.jpg"); err != nil || !ok || e.Title != "B" {
+		t.Fatalf("unexpected entry: %+v ok=%v err=%v", e, ok, err)
+	}
+
+	// Files still shown by another provider are not reported for deletion.
+	if err := s.SetBackgroundCache("bg:bing_daily", "history/c.jpg"); err != nil {
+		t.Fatalf("SetBackgroundCache failed: %v", err)
+	}
+	removed, err := s.AddBackgroundHistory(BackgroundHistoryEntry{CacheKey: "bg:picsum", FilePath: "history/d.jpg"}, 1)
+	if err != nil || len(removed) != 1 || removed[0] != "history/b.jpg" {
+		t.Fatalf("expected only b.jpg to be dropped, got %v (%v)", removed, err)
+	}
+}
+
+func TestGeocodeCache(t *testing.T) {
+	s := newTestStore(t)
+	now := time.Now().Unix()
+
+	if err := s.SetGeocodeCache(GeocodeCacheEntry{Query: "berlin", Language: "en", Lat: 52.52, Lon: 13.41, DisplayName: "Berlin, Germany", Timezone: "Europe/Berlin", CountryCode: "DE", FetchedAt: now, ExpiresAt: now + 60}); err != nil {
+		t.Fatalf("SetGeocodeCache failed: %v", err)
+	}
+	if err := s.SetGeocodeCache(GeocodeCacheEntry{Query: "paris", Language: "en", FetchedAt: now - 120, ExpiresAt: now - 60}); err != nil {
+		t.Fatalf("SetGeocodeCache failed: %v", err)
+	}
+	if e, ok, err := s.GetGeocodeCache("berlin", "en"); err != nil || !ok || e.Lat != 52.52 || e.Timezone != "Europe/Berlin" || e.CountryCode != "DE" {
+		t.Fatalf("unexpected geocode entry: %+v ok=%v err=%v", e, ok, err)
+	}
+	if _, ok, _ := s.GetGeocodeCache("berlin", "zh"); ok {
+		t.Fatalf("entries are per language")
+	}
+	if _, ok, _ := s.GetGeocodeCache("paris", "en"); ok {
+		t.Fatalf("expired entry must not be returned")
+	}
+
+	if err := s.PruneWidgetCaches(); err != nil {
+		t.Fatalf("PruneWidgetCaches failed: %v", err)
+	}
+	var n int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM geocode_cache`).Scan(&n); err != nil || n != 1 {
+		t.Fatalf("expected 1 row after prune, got %d (%v)", n, err)
+	}
+	if err := s.ClearWidgetCaches(); err != nil {
+		t.Fatalf("ClearWidgetCaches failed: %v", err)
+	}
+	if _, ok, _ := s.GetGeocodeCache("berlin", "en"); ok {
+		t.Fatalf("expected the cache to be cleared")
+	}
+}
+
+func TestCustomEventsReplaceAndExport(t *testing.T) {
+	s := newTestStore(t)
+
+	list := []CustomEvent{{Name: "Mom", Kind: "birthday", Month: 3, Day: 8, Year: 1960, Lunar: true}, {Name: "Wedding", Kind: "anniversary", Month: 6, Day: 1}}
+	if err := s.ReplaceCustomEvents(list); err != nil {
+		t.Fatalf("ReplaceCustomEvents failed: %v", err)
+	}
+	got, err := s.ListCustomEvents()
+	if err != nil {
+		t.Fatalf("ListCustomEvents failed: %v", err)
+	}
+	if len(got) != 2 || got[0].Name != "Mom" || !got[0].Lunar || got[1].Lunar || got[1].SortOrder != 1 {
+		t.Fatalf("unexpected events: %+v", got)
+	}
+
+	exp, err := s.ExportAll()
+	if err != nil {
+		t.Fatalf("ExportAll failed: %v", err)
+	}
+	if err := s.ReplaceCustomEvents(nil); err != nil {
+		t.Fatalf("ReplaceCustomEvents failed: %v", err)
+	}
+	if err := s.ImportAll(exp); err != nil {
+		t.Fatalf("ImportAll failed: %v", err)
+	}
+	if got, _ := s.ListCustomEvents(); len(got) != 2 || got[0].Year != 1960 {
+		t.Fatalf("expected events restored from backup, got %+v", got)
+	}
+}
+
+func TestMetricsHistory(t *testing.T) {
+	s := newTestStore(t)
+
+	for i, cpu := range []float64{10, 30, 50, 70} {
+		if err := s.AddMetricsSample(MetricsSample{At: 1000 + int64(i)*30, CPU: cpu, NetRecv: 100 * float64(i)}); err != nil {
+			t.Fatalf("AddMetricsSample failed: %v", err)
+		}
+	}
+
+	got, err := s.MetricsHistory(0, 60)
+	if err != nil {
+		t.Fatalf("MetricsHistory failed: %v", err)
+	}
+	// Buckets start at 960 (1000), 1020 (1030, 1060) and 1080 (1090).
+	if len(got) != 3 || got[0].At != 960 || got[1].CPU != 40 || got[1].NetRecv != 150 || got[2].CPU != 70 {
+		t.Fatalf("unexpected history: %+v", got)
+	}
+
+	if n, err := s.PruneMetricsSamples(1060); err != nil || n != 2 {
+		t.Fatalf("expected 2 pruned, got %d (%v)", n, err)
+	}
+	if got, _ = s.MetricsHistory(0, 1); len(got) != 2 || got[0].At != 1060 {
+		t.Fatalf("unexpected history after prune: %+v", got)
+	}
+}
+
+func TestMetricHosts(t *testing.T) {
+	s := newTestStore(t)
+
+	if err := s.PutMetricHost(MetricHost{Name: "vps", URL: "https://vps.example.com:8788", Token: "a"}); err != nil {
+		t.Fatalf("PutMetricHost failed: %v", err)
+	}
+	if err := s.PutMetricHost(MetricHost{Name: "nas", URL: "http://192.168.1.10:8788", Token: "b"}); err != nil {
+		t.Fatalf("PutMetricHost failed: %v", err)
+	}
+	if err := s.PutMetricHost(MetricHost{Name: "nas", URL: "http://192.168.1.11:8788", Token: "c"}); err != nil {
+		t.Fatalf("PutMetricHost failed: %v", err)
+	}
+	list, err := s.ListMetricHosts()
+	if err != nil || len(list) != 2 || list[0].Name != "nas" || list[0].URL != "http://192.168.1.11:8788" || list[0].Token != "c" {
+		t.Fatalf("unexpected hosts: %+v (%v)", list, err)
+	}
+	if ok, err := s.DeleteMetricHost("nas"); err != nil || !ok {
+		t.Fatalf("DeleteMetricHost: ok=%v err=%v", ok, err)
+	}
+	if _, ok, _ := s.GetMetricHost("nas"); ok {
+		t.Fatal("expected nas to be gone")
+	}
+	if ok, _ := s.DeleteMetricHost("nas"); ok {
+		t.Fatal("expected deleting a missing host to report false")
+	}
+}
+
+func TestWebhooks(t *testing.T) {
+	s := newTestStore(t)
+
+	w, err := s.CreateWebhook(Webhook{URL: "https://hooks.example.com/a", Secret: "s", Events: []string{"app.down", "app.up"}, Enabled: true})
+	if err != nil || w.ID == 0 {
+		t.Fatalf("CreateWebhook: %+v %v", w, err)
+	}
+	if !w.Subscribed("app.down") || w.Subscribed("import.completed") {
+		t.Fatalf("unexpected subscriptions: %v", w.Events)
+	}
+	w.Events, w.Enabled = nil, false
+	if ok, err := s.UpdateWebhook(w); err != nil || !ok {
+		t.Fatalf("UpdateWebhook: ok=%v err=%v", ok, err)
+	}
+	got, ok, err := s.GetWebhook(w.ID)
+	if err != nil || !ok || len(got.Events) != 0 || got.Enabled || got.Subscribed("app.down") {
+		t.Fatalf("unexpected webhook: %+v (%v)", got, err)
+	}
+
+	id, err := s.EnqueueWebhookDelivery(w.ID, "app.down", []byte(`{}`))
+	if err != nil {
+		t.Fatalf("EnqueueWebhookDelivery: %v", err)
+	}
+	now := time.Now().Unix()
+	due, err := s.DueWebhookDeliveries(now, 10)
+	if err != nil || len(due) != 1 || due[0].ID != id || string(due[0].Payload) != `{}` {
+		t.Fatalf("unexpected due deliveries: %+v (%v)", due, err)
+	}
+	if err := s.RecordWebhookAttempt(id, 500, "status 500", now+60); err != nil {
+		t.Fatalf("RecordWebhookAttempt: %v", err)
+	}
+	if due, _ := s.DueWebhookDeliveries(now, 10); len(due) != 0 {
+		t.Fatalf("expected the retry to wait, got %+v", due)
+	}
+	if due, _ := s.DueWebhookDeliveries(now+60, 10); len(due) != 1 || due[0].Attempts != 1 || due[0].LastError != "status 500" {
+		t.Fatalf("expected the retry to be due, got %+v", due)
+	}
+	if err := s.RecordWebhookAttempt(id, 204, "", 0); err != nil {
+		t.Fatalf("RecordWebhookAttempt: %v", err)
+	}
+	list, err := s.ListWebhookDeliveries(w.ID, 10)
+	if err != nil || len(list) != 1 || list[0].DeliveredAt == 0 || list[0].Attempts != 2 {
+		t.Fatalf("unexpected deliveries: %+v (%v)", list, err)
+	}
+	if n, err := s.PruneWebhookDeliveries(now + 1); err != nil || n != 1 {
+		t.Fatalf("PruneWebhookDeliveries: n=%d err=%v", n, err)
+	}
+
+	if ok, err := s.DeleteWebhook(w.ID); err != nil || !ok {
+		t.Fatalf("DeleteWebhook: ok=%v err=%v", ok, err)
+	}
+	if list, _ := s.ListWebhooks(); len(list) != 0 {
+		t.Fatalf("expected no webhooks, got %+v", list)
+	}
+}
+
+func TestBackupTargets(t *testing.T) {
+	s := newTestStore(t)
+
+	bt, err := s.CreateBackupTarget(BackupTarget{Name: "nas", Kind: "webdav", Config: `{"url":"https://nas.lan/dav"}`, Secret: "pw", Keep: 7, IntervalSeconds: 86400, Enabled: true})
+	if err != nil || bt.ID == 0 || bt.CreatedAt == 0 {
+		t.Fatalf("CreateBackupTarget: %+v %v", bt, err)
+	}
+	if err := s.RecordBackupRun(bt.ID, 100, "status 507"); err != nil {
+		t.Fatalf("RecordBackupRun: %v", err)
+	}
+	if err := s.RecordBackupRun(bt.ID, 200, ""); err != nil {
+		t.Fatalf("RecordBackupRun: %v", err)
+	}
+	if err := s.RecordBackupRun(bt.ID, 300, "timeout"); err != nil {
+		t.Fatalf("RecordBackupRun: %v", err)
+	}
+	bt.Keep, bt.Enabled = 3, false
+	if ok, err := s.UpdateBackupTarget(bt); err != nil || !ok {
+		t.Fatalf("UpdateBackupTarget: ok=%v err=%v", ok, err)
+	}
+	got, ok, err := s.GetBackupTarget(bt.ID)
+	if err != nil || !ok || got.Keep != 3 || got.Enabled || got.Secret != "pw" {
+		t.Fatalf("unexpected target: %+v (%v)", got, err)
+	}
+	if got.LastAttemptAt != 300 || got.LastSuccessAt != 200 || got.LastError != "timeout" {
+		t.Fatalf("runs not recorded: %+v", got)
+	}
+
+	if ok, err := s.DeleteBackupTarget(bt.ID); err != nil || !ok {
+		t.Fatalf("DeleteBackupTarget: ok=%v err=%v", ok, err)
+	}
+	if list, _ := s.ListBackupTargets(); len(list) != 0 {
+		t.Fatalf("expected no backup targets, got %+v", list)
+	}
+}
+
+func TestRecordLoginIP(t *testing.T) {
+	s := newTestStore(t)
+	if known, err := s.RecordLoginIP("192.0.2.1"); err != nil || known {
+		t.Fatalf("first login: known=%v err=%v", known, err)
+	}
+	if known, err := s.RecordLoginIP("192.0.2.1"); err != nil || !known {
+		t.Fatalf("second login: known=%v err=%v", known, err)
+	}
+}
+
+func TestMigrateWidgetConfigs(t *testing.T) {
+	s := newTestStore(t)
+	g, err := s.CreateGroup("Widgets", "system")
+	if err != nil {
+		t.Fatalf("CreateGroup failed: %v", err)
+	}
+	desc, broken := `{"countries":["CN"]}`, `not json`
+	holidays, _ := s.CreateApp(AppItem{GroupID: &g.ID, Name: "Holidays", Description: &desc, URL: "widget:holidays?countries=CN"})
+	weather, _ := s.CreateApp(AppItem{GroupID: &g.ID, Name: "Weather", Description: &broken, URL: "widget:weather"})
+	app, _ := s.CreateApp(AppItem{Name: "App", Description: &desc, URL: "https://example.com"})
+
+	// Items created before the table existed are picked up on the next start.
+	if err := s.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	c, ok, err := s.GetWidgetConfig(holidays.ID)
+	if err != nil || !ok || c.Kind != "holidays" || string(c.Config) != desc {
+		t.Fatalf("unexpected holidays config: %+v %v %v", c, ok, err)
+	}
+	if c, ok, _ := s.GetWidgetConfig(weather.ID); !ok || c.Kind != "weather" || string(c.Config) != "{}" {
+		t.Fatalf("expected an invalid description to become {}, got %+v", c)
+	}
+	if _, ok, _ := s.GetWidgetConfig(app.ID); ok {
+		t.Fatalf("expected no config for a plain app")
+	}
+
+	if err := s.PutWidgetConfig(weather.ID, "weather", []byte(`{"city":"Berlin"}`)); err != nil {
+		t.Fatalf("PutWidgetConfig failed: %v", err)
+	}
+	if a, _, _ := s.AppByID(weather.ID); a.Description == nil || *a.Description != `{"city":"Berlin"}` {
+		t.Fatalf("expected the config to be mirrored into the description, got %v", a.Description)
+	}
+	// Existing configs survive a restart.
+	if err := s.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if c, _, _ := s.GetWidgetConfig(weather.ID); string(c.Config) != `{"city":"Berlin"}` {
+		t.Fatalf("config was overwritten: %s", c.Config)
+	}
+
+	if err := s.DeleteApp(weather.ID); err != nil {
+		t.Fatalf("DeleteApp failed: %v", err)
+	}
+	if _, ok, _ := s.GetWidgetConfig(weather.ID); ok {
+		t.Fatalf("expected the config to be deleted with its item")
+	}
+}
+
+func TestMaintain(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "m.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec(`PRAGMA journal_mode = WAL`); err != nil {
+		t.Fatalf("wal: %v", err)
+	}
+	s := New(db)
+	if err := s.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	for i := range 500 {
+		if err := s.AddMetricsSample(MetricsSample{At: int64(i), CPU: 1}); err != nil {
+			t.Fatalf("AddMetricsSample: %v", err)
+		}
+	}
+	if _, err := s.PruneMetricsSamples(1000); err != nil {
+		t.Fatalf("PruneMetricsSamples: %v", err)
+	}
+
+	rep, err := s.Maintain(context.Background(), true)
+	if err != nil {
+		t.Fatalf("Maintain: %v", err)
+	}
+	if !rep.IntegrityOK || !rep.Vacuumed {
+		t.Fatalf("unexpected report: %+v", rep)
+	}
+	if rep.FreeBefore == 0 || rep.SizeAfter >= rep.SizeBefore {
+		t.Fatalf("expected vacuum to reclaim free pages: %+v", rep)
+	}
+	if ratio, err := s.FreeRatio(context.Background()); err != nil || ratio != 0 {
+		t.Fatalf("FreeRatio after vacuum = %v, %v", ratio, err)
+	}
+}
+
+func TestEncryption(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "enc.db")
+	open := func() *Store {
+		db, err := sql.Open("sqlite", path)
+		if err != nil {
+			t.Fatalf("open: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+		s := New(db)
+		if err := s.Migrate(); err != nil {
+			t.Fatalf("Migrate failed: %v", err)
+		}
+		return s
+	}
+
+	s := open()
+	// Written before encryption was enabled.
+	if err := s.SetKV("settings.apiKey", "plain-key"); err != nil {
+		t.Fatalf("SetKV: %v", err)
+	}
+	if err := s.EnableEncryption("passphrase", []string{"settings.apiKey"}); err != nil {
+		t.Fatalf("EnableEncryption: %v", err)
+	}
+	if _, err := s.CreateWebhook(Webhook{URL: "http://x", Secret: "hook-secret", Enabled: true}); err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	if _, err := s.CreateBackupTarget(BackupTarget{Name: "nas", Kind: "sftp", Config: "{}", Secret: "private-key", IntervalSeconds: 3600}); err != nil {
+		t.Fatalf("CreateBackupTarget: %v", err)
+	}
+	for q, plain := range map[string]string{
+		`SELECT value FROM kv WHERE key = 'settings.apiKey'`: "plain-key",
+		`SELECT secret FROM webhooks`:                        "hook-secret",
+		`SELECT secret FROM backup_targets`:                  "private-key",
+	} {
+		var raw string
+		if err := s.db.QueryRow(q).Scan(&raw); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+		if raw == plain || !strings.HasPrefix(raw, encPrefix) {
+			t.Fatalf("%s: stored %q, want it sealed", q, raw)
+		}
+	}
+	if v, _, _ := s.GetKV("settings.apiKey"); v != "plain-key" {
+		t.Fatalf("GetKV = %q", v)
+	}
+	exp, err := s.ExportAll()
+	if err != nil {
+		t.Fatalf("ExportAll: %v", err)
+	}
+	if exp.Settings["settings.apiKey"] != "plain-key" || exp.Settings[kvCryptoCheck] != "" {
+		t.Fatalf("unexpected export settings: %v", exp.Settings)
+	}
+
+	if err := open().EnableEncryption("", nil); !errors.Is(err, ErrKeyRequired) {
+		t.Fatalf("without key: got %v", err)
+	}
+	if err := open().EnableEncryption("wrong", nil); !errors.Is(err, ErrWrongKey) {
+		t.Fatalf("wrong key: got %v", err)
+	}
+	s2 := open()
+	if err := s2.EnableEncryption("passphrase", []string{"settings.apiKey"}); err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	hooks, err := s2.ListWebhooks()
+	if err != nil || len(hooks) != 1 || hooks[0].Secret != "hook-secret" {
+		t.Fatalf("ListWebhooks = %+v, %v", hooks, err)
+	}
+}
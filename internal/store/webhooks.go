@@ -0,0 +1,242 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Webhook is an outbound endpoint notified of dashboard events. Events lists
+// the event names it subscribes to; empty means all of them.
+type Webhook struct {
+	ID        int64
+	URL       string
+	Secret    string
+	Events    []string
+	Enabled   bool
+	CreatedAt int64
+}
+
+// Subscribed reports whether the webhook wants event.
+func (w Webhook) Subscribed(event string) bool {
+	if !w.Enabled {
+		return false
+	}
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is one event queued for one webhook. NextAttemptAt is 0
+// once the delivery succeeded or was given up.
+type WebhookDelivery struct {
+	ID            int64  `json:"id"`
+	WebhookID     int64  `json:"webhookId"`
+	Event         string `json:"event"`
+	Payload       []byte `json:"-"`
+	Attempts      int    `json:"attempts"`
+	NextAttemptAt int64  `json:"nextAttemptAt"`
+	LastError     string `json:"lastError,omitempty"`
+	StatusCode    int    `json:"statusCode,omitempty"`
+	CreatedAt     int64  `json:"createdAt"`
+	DeliveredAt   int64  `json:"deliveredAt,omitempty"`
+}
+
+const webhookColumns = `id, url, secret, events, enabled, created_at`
+
+func (s *Store) scanWebhook(row interface{ Scan(...any) error }) (Webhook, error) {
+	var w Webhook
+	var events string
+	var enabled int
+	if err := row.Scan(&w.ID, &w.URL, &w.Secret, &events, &enabled, &w.CreatedAt); err != nil {
+		return Webhook{}, err
+	}
+	secret, err := s.open(w.Secret)
+	if err != nil {
+		return Webhook{}, err
+	}
+	w.Secret = secret
+	w.Enabled = enabled != 0
+	w.Events = []string{}
+	if events != "" {
+		w.Events = strings.Split(events, ",")
+	}
+	return w, nil
+}
+
+// ListWebhooks returns all webhooks in the order they were created.
+func (s *Store) ListWebhooks() ([]Webhook, error) {
+	rows, err := s.db.Query(`SELECT ` + webhookColumns + ` FROM webhooks ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Webhook{}
+	for rows.Next() {
+		w, err := s.scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, w)
+	}
+	return out, rows.Err()
+}
+
+// GetWebhook returns the webhook with the given id.
+func (s *Store) GetWebhook(id int64) (Webhook, bool, error) {
+	w, err := s.scanWebhook(s.db.QueryRow(`SELECT `+webhookColumns+` FROM webhooks WHERE id = ?`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Webhook{}, false, nil
+		}
+		return Webhook{}, false, err
+	}
+	return w, true, nil
+}
+
+// CreateWebhook adds a webhook and returns it with its id.
+func (s *Store) CreateWebhook(w Webhook) (Webhook, error) {
+	w.CreatedAt = time.Now().Unix()
+	secret, err := s.seal(w.Secret)
+	if err != nil {
+		return Webhook{}, err
+	}
+	res, err := s.db.Exec(`INSERT INTO webhooks (url, secret, events, enabled, created_at) VALUES (?, ?, ?, ?, ?)`,
+		w.URL, secret, strings.Join(w.Events, ","), boolInt(w.Enabled), w.CreatedAt)
+	if err != nil {
+		return Webhook{}, err
+	}
+	w.ID, err = res.LastInsertId()
+	return w, err
+}
+
+// UpdateWebhook saves the URL, secret, events and enabled flag of an existing
+// webhook. It reports whether the webhook exists.
+func (s *Store) UpdateWebhook(w Webhook) (bool, error) {
+	secret, err := s.seal(w.Secret)
+	if err != nil {
+		return false, err
+	}
+	res, err := s.db.Exec(`UPDATE webhooks SET url = ?, secret = ?, events = ?, enabled = ? WHERE id = ?`,
+		w.URL, secret, strings.Join(w.Events, ","), boolInt(w.Enabled), w.ID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// DeleteWebhook removes a webhook and its queued deliveries. It reports
+// whether the webhook existed.
+func (s *Store) DeleteWebhook(id int64) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM webhook_deliveries WHERE webhook_id = ?`, id); err != nil {
+		return false, err
+	}
+	res, err := tx.Exec(`DELETE FROM webhooks WHERE id = ?`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, tx.Commit()
+}
+
+// EnqueueWebhookDelivery queues an event for a webhook, due immediately.
+func (s *Store) EnqueueWebhookDelivery(webhookID int64, event string, payload []byte) (int64, error) {
+	now := time.Now().Unix()
+	res, err := s.db.Exec(`INSERT INTO webhook_deliveries (webhook_id, event, payload, next_attempt_at, created_at) VALUES (?, ?, ?, ?, ?)`,
+		webhookID, event, payload, now, now)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+const deliveryColumns = `id, webhook_id, event, payload, attempts, next_attempt_at, last_error, status_code, created_at, delivered_at`
+
+func scanDeliveries(rows *sql.Rows) ([]WebhookDelivery, error) {
+	defer rows.Close()
+	out := []WebhookDelivery{}
+	for rows.Next() {
+		var d WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.Event, &d.Payload, &d.Attempts, &d.NextAttemptAt, &d.LastError, &d.StatusCode, &d.CreatedAt, &d.DeliveredAt); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// DueWebhookDeliveries returns up to limit pending deliveries due at now,
+// oldest first.
+func (s *Store) DueWebhookDeliveries(now int64, limit int) ([]WebhookDelivery, error) {
+	rows, err := s.db.Query(`SELECT `+deliveryColumns+` FROM webhook_deliveries
+		WHERE next_attempt_at > 0 AND next_attempt_at <= ? ORDER BY next_attempt_at, id LIMIT ?`, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanDeliveries(rows)
+}
+
+// ListWebhookDeliveries returns the latest deliveries of a webhook, newest
+// first.
+func (s *Store) ListWebhookDeliveries(webhookID int64, limit int) ([]WebhookDelivery, error) {
+	rows, err := s.db.Query(`SELECT `+deliveryColumns+` FROM webhook_deliveries
+		WHERE webhook_id = ? ORDER BY id DESC LIMIT ?`, webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanDeliveries(rows)
+}
+
+// RecordWebhookAttempt stores the outcome of a delivery attempt. A zero
+// nextAttemptAt ends the delivery: delivered when errMsg is empty, given up
+// otherwise.
+func (s *Store) RecordWebhookAttempt(id int64, statusCode int, errMsg string, nextAttemptAt int64) error {
+	deliveredAt := int64(0)
+	if errMsg == "" {
+		deliveredAt = time.Now().Unix()
+	}
+	_, err := s.db.Exec(`UPDATE webhook_deliveries SET attempts = attempts + 1, status_code = ?, last_error = ?, next_attempt_at = ?, delivered_at = ? WHERE id = ?`,
+		statusCode, errMsg, nextAttemptAt, deliveredAt, id)
+	return err
+}
+
+// PruneWebhookDeliveries removes finished deliveries created before the given
+// unix time.
+func (s *Store) PruneWebhookDeliveries(before int64) (int64, error) {
+	res, err := s.db.Exec(`DELETE FROM webhook_deliveries WHERE next_attempt_at = 0 AND created_at < ?`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// RecordLoginIP remembers a successful login from ip and reports whether the
+// address had logged in before.
+func (s *Store) RecordLoginIP(ip string) (known bool, err error) {
+	now := time.Now().Unix()
+	res, err := s.db.Exec(`UPDATE login_ips SET last_seen = ? WHERE ip = ?`, now, ip)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return n > 0, err
+	}
+	_, err = s.db.Exec(`INSERT INTO login_ips (ip, first_seen, last_seen) VALUES (?, ?, ?) ON CONFLICT(ip) DO UPDATE SET last_seen = excluded.last_seen`, ip, now, now)
+	return false, err
+}
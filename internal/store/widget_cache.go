@@ -0,0 +1,89 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// WidgetCacheEntry is a persisted upstream response of a widget fetcher.
+type WidgetCacheEntry struct {
+	CacheKey  string
+	Payload   []byte
+	FetchedAt int64
+	ExpiresAt int64
+}
+
+func (s *Store) GetWidgetCache(cacheKey string) (WidgetCacheEntry, bool, error) {
+	var e WidgetCacheEntry
+	err := s.db.QueryRow(`SELECT cache_key, payload, fetched_at, expires_at FROM widget_cache WHERE cache_key = ? AND expires_at > ?`, cacheKey, time.Now().Unix()).
+		Scan(&e.CacheKey, &e.Payload, &e.FetchedAt, &e.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return WidgetCacheEntry{}, false, nil
+		}
+		return WidgetCacheEntry{}, false, err
+	}
+	return e, true, nil
+}
+
+func (s *Store) SetWidgetCache(e WidgetCacheEntry) error {
+	_, err := s.db.Exec(`INSERT INTO widget_cache (cache_key, payload, fetched_at, expires_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(cache_key) DO UPDATE SET payload=excluded.payload, fetched_at=excluded.fetched_at, expires_at=excluded.expires_at`,
+		e.CacheKey, e.Payload, e.FetchedAt, e.ExpiresAt,
+	)
+	return err
+}
+
+// SymbolMapping resolves a ticker to a provider-specific id (e.g. CoinGecko coin id).
+type SymbolMapping struct {
+	Provider  string
+	Symbol    string
+	ID        string
+	Name      string
+	FetchedAt int64
+	ExpiresAt int64
+}
+
+func (s *Store) GetSymbolMapping(provider, symbol string) (SymbolMapping, bool, error) {
+	var m SymbolMapping
+	err := s.db.QueryRow(`SELECT provider, symbol, provider_id, name, fetched_at, expires_at FROM symbol_map WHERE provider = ? AND symbol = ? AND expires_at > ?`, provider, symbol, time.Now().Unix()).
+		Scan(&m.Provider, &m.Symbol, &m.ID, &m.Name, &m.FetchedAt, &m.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return SymbolMapping{}, false, nil
+		}
+		return SymbolMapping{}, false, err
+	}
+	return m, true, nil
+}
+
+func (s *Store) SetSymbolMapping(m SymbolMapping) error {
+	_, err := s.db.Exec(`INSERT INTO symbol_map (provider, symbol, provider_id, name, fetched_at, expires_at) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(provider, symbol) DO UPDATE SET provider_id=excluded.provider_id, name=excluded.name, fetched_at=excluded.fetched_at, expires_at=excluded.expires_at`,
+		m.Provider, m.Symbol, m.ID, m.Name, m.FetchedAt, m.ExpiresAt,
+	)
+	return err
+}
+
+// PruneWidgetCaches deletes expired widget cache rows, symbol mappings and
+// geocoding results.
+func (s *Store) PruneWidgetCaches() error {
+	now := time.Now().Unix()
+	for _, table := range []string{"widget_cache", "symbol_map", "geocode_cache"} {
+		if _, err := s.db.Exec(`DELETE FROM `+table+` WHERE expires_at <= ?`, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClearWidgetCaches deletes all persisted widget caches.
+func (s *Store) ClearWidgetCaches() error {
+	for _, table := range []string{"widget_cache", "symbol_map", "geocode_cache"} {
+		if _, err := s.db.Exec(`DELETE FROM ` + table); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// WidgetConfig is the structured configuration of a widget item. The JSON is
+// mirrored into the item's description so older clients keep working.
+type WidgetConfig struct {
+	AppID     string
+	Kind      string
+	Config    []byte
+	UpdatedAt int64
+}
+
+// widgetConfigsFromApps copies the description of every widget item into
+// widget_configs. Descriptions that aren't JSON objects become "{}". The
+// kind is the URL after "widget:", without any query string.
+const widgetConfigsFromApps = `INSERT INTO widget_configs (app_id, kind, config, updated_at)
+	SELECT id,
+		CASE WHEN instr(url, '?') > 0 THEN substr(url, 8, instr(url, '?') - 8) ELSE substr(url, 8) END,
+		CASE WHEN description IS NOT NULL AND json_valid(description) AND json_type(description) = 'object' THEN description ELSE '{}' END,
+		?
+	FROM apps WHERE url LIKE 'widget:%'`
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// migrateWidgetConfigs fills widget_configs for widget items that have none
+// yet (or for all of them when overwrite is set, after an import) and drops
+// rows of items that are gone or no longer widgets.
+func migrateWidgetConfigs(db execer, overwrite bool) error {
+	conflict := ` ON CONFLICT(app_id) DO NOTHING`
+	if overwrite {
+		conflict = ` ON CONFLICT(app_id) DO UPDATE SET kind=excluded.kind, config=excluded.config, updated_at=excluded.updated_at`
+	}
+	if _, err := db.Exec(widgetConfigsFromApps+conflict, time.Now().Unix()); err != nil {
+		return err
+	}
+	_, err := db.Exec(`DELETE FROM widget_configs WHERE app_id NOT IN (SELECT id FROM apps WHERE url LIKE 'widget:%')`)
+	return err
+}
+
+// GetWidgetConfig returns the configuration of a widget item.
+func (s *Store) GetWidgetConfig(appID string) (WidgetConfig, bool, error) {
+	var c WidgetConfig
+	err := s.db.QueryRow(`SELECT app_id, kind, config, updated_at FROM widget_configs WHERE app_id = ?`, appID).
+		Scan(&c.AppID, &c.Kind, &c.Config, &c.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return WidgetConfig{}, false, nil
+		}
+		return WidgetConfig{}, false, err
+	}
+	return c, true, nil
+}
+
+// PutWidgetConfig stores the configuration of a widget item and mirrors it
+// into the item's description.
+func (s *Store) PutWidgetConfig(appID, kind string, config []byte) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`UPDATE apps SET description = ? WHERE id = ?`, string(config), appID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("not found")
+	}
+	if _, err := tx.Exec(`INSERT INTO widget_configs (app_id, kind, config, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(app_id) DO UPDATE SET kind=excluded.kind, config=excluded.config, updated_at=excluded.updated_at`,
+		appID, kind, config, time.Now().Unix()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteWidgetConfig removes the configuration of an item, e.g. when it is
+// no longer a widget.
+func (s *Store) DeleteWidgetConfig(appID string) error {
+	_, err := s.db.Exec(`DELETE FROM widget_configs WHERE app_id = ?`, appID)
+	return err
+}
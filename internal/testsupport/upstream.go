@@ -0,0 +1,274 @@
+// Package testsupport provides deterministic fakes of the third-party APIs the
+// widgets and background service call, so handler tests run offline.
+package testsupport
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/morezhou/hearth/internal/background"
+	"github.com/morezhou/hearth/internal/widgets"
+)
+
+//go:embed fixtures/*
+var fixtures embed.FS
+
+// Upstream is a single httptest server that impersonates every upstream API,
+// each mounted under its own path prefix (/open-meteo, /coingecko, ...).
+type Upstream struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	hits      map[string]int
+	queries   map[string]url.Values
+	history   map[string][]url.Values
+	overrides map[string]http.HandlerFunc
+}
+
+// NewUpstream starts the fake upstream server; it is closed on test cleanup.
+func NewUpstream(t testing.TB) *Upstream {
+	t.Helper()
+	u := &Upstream{hits: map[string]int{}, queries: map[string]url.Values{}, history: map[string][]url.Values{}, overrides: map[string]http.HandlerFunc{}}
+	u.Server = httptest.NewServer(http.HandlerFunc(u.serve))
+	t.Cleanup(u.Close)
+	return u
+}
+
+// UseForWidgets points the widget fetchers at the fake server until the test ends.
+func (u *Upstream) UseForWidgets(t testing.TB) {
+	t.Helper()
+	restore := widgets.SetEndpoints(u.WidgetEndpoints())
+	t.Cleanup(restore)
+}
+
+// WidgetEndpoints returns widget base URLs targeting the fake server.
+func (u *Upstream) WidgetEndpoints() widgets.Endpoints {
+	return widgets.Endpoints{
+		OpenMeteo:          u.URL + "/open-meteo",
+		OpenMeteoGeocoding: u.URL + "/geocoding",
+		OpenWeatherMap:     u.URL + "/openweathermap",
+		MetNo:              u.URL + "/metno",
+		Nominatim:          u.URL + "/nominatim",
+		CoinGecko:          u.URL + "/coingecko",
+		Binance:            u.URL + "/binance",
+		Stooq:              u.URL + "/stooq",
+		Finnhub:            u.URL + "/finnhub",
+		TwelveData:         u.URL + "/twelvedata",
+		Yahoo:              u.URL + "/yahoo",
+		Frankfurter:        u.URL + "/frankfurter",
+		Nager:              u.URL + "/nager",
+		HolidayCN:          u.URL + "/holiday-cn",
+		MeteoAlarm:         u.URL + "/meteoalarm",
+		Quotable:           u.URL + "/quotable",
+	}
+}
+
+// BackgroundURLs returns background provider base URLs targeting the fake server.
+func (u *Upstream) BackgroundURLs() background.BaseURLs {
+	return background.BaseURLs{
+		Bing:       u.URL + "/bing",
+		Unsplash:   u.URL + "/unsplash",
+		Picsum:     u.URL + "/picsum",
+		APOD:       u.URL + "/apod",
+		Wikimedia:  u.URL + "/wikimedia",
+		Chromecast: u.URL + "/chromecast",
+	}
+}
+
+// Override replaces the response for an exact request path, e.g. to simulate
+// rate limiting: u.Override("/open-meteo/v1/forecast", Status(429)).
+func (u *Upstream) Override(p string, h http.HandlerFunc) {
+	u.mu.Lock()
+	u.overrides[p] = h
+	u.mu.Unlock()
+}
+
+// Hits returns how many requests were served for an exact path.
+func (u *Upstream) Hits(p string) int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.hits[p]
+}
+
+// LastQuery returns the query parameters of the latest request for a path.
+func (u *Upstream) LastQuery(p string) url.Values {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.queries[p]
+}
+
+// Requested reports whether any request for a path had query parameter key
+// set to value. Concurrent fetchers make LastQuery order dependent.
+func (u *Upstream) Requested(p, key, value string) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for _, q := range u.history[p] {
+		if q.Get(key) == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Status returns a handler that replies with the given status code.
+func Status(code int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, http.StatusText(code), code)
+	}
+}
+
+func (u *Upstream) serve(w http.ResponseWriter, r *http.Request) {
+	p := r.URL.Path
+	u.mu.Lock()
+	u.hits[p]++
+	u.queries[p] = r.URL.Query()
+	u.history[p] = append(u.history[p], r.URL.Query())
+	override := u.overrides[p]
+	u.mu.Unlock()
+	if override != nil {
+		override(w, r)
+		return
+	}
+
+	switch {
+	case p == "/open-meteo/v1/forecast":
+		serveFixture(w, "openmeteo_forecast.json", "application/json", nil)
+	case p == "/geocoding/v1/search":
+		serveFixture(w, "openmeteo_geocoding.json", "application/json", nil)
+	case p == "/openweathermap/data/2.5/weather":
+		serveFixture(w, "owm_weather.json", "application/json", nil)
+	case p == "/openweathermap/data/2.5/forecast":
+		serveFixture(w, "owm_forecast.json", "application/json", nil)
+	case p == "/metno/weatherapi/locationforecast/2.0/compact":
+		serveFixture(w, "metno_compact.json", "application/json", nil)
+	case p == "/nominatim/search":
+		serveFixture(w, "nominatim_search.json", "application/json", nil)
+	case p == "/coingecko/api/v3/search":
+		serveFixture(w, "coingecko_search.json", "application/json", nil)
+	case p == "/coingecko/api/v3/coins/markets":
+		serveFixture(w, "coingecko_markets.json", "application/json", nil)
+	case p == "/binance/api/v3/ticker/24hr" && r.URL.Query().Has("symbols"):
+		serveBinanceTickers(w, r.URL.Query().Get("symbols"))
+	case p == "/binance/api/v3/ticker/24hr":
+		serveFixture(w, "binance_ticker.json", "application/json", map[string]string{"{{SYMBOL}}": r.URL.Query().Get("symbol")})
+	case p == "/binance/api/v3/klines":
+		serveFixture(w, "binance_klines.json", "application/json", nil)
+	case p == "/stooq/q/l/":
+		serveFixture(w, "stooq_quote.csv", "text/csv", nil)
+	case p == "/stooq/q/d/l/":
+		serveFixture(w, "stooq_daily.csv", "text/csv", nil)
+	case p == "/finnhub/api/v1/quote":
+		serveFixture(w, "finnhub_quote.json", "application/json", nil)
+	case p == "/twelvedata/quote":
+		serveFixture(w, "twelvedata_quote.json", "application/json", map[string]string{"{{SYMBOL}}": r.URL.Query().Get("symbol")})
+	case p == "/twelvedata/time_series":
+		serveFixture(w, "twelvedata_series.json", "application/json", map[string]string{"{{SYMBOL}}": r.URL.Query().Get("symbol")})
+	case strings.HasPrefix(p, "/yahoo/v8/finance/chart/"):
+		serveFixture(w, "yahoo_chart.json", "application/json", map[string]string{"{{SYMBOL}}": path.Base(p)})
+	case p == "/frankfurter/latest":
+		serveFixture(w, "frankfurter_latest.json", "application/json", nil)
+	case p == "/nager/api/v3/AvailableCountries":
+		serveFixture(w, "nager_countries.json", "application/json", nil)
+	case strings.HasPrefix(p, "/nager/api/v3/PublicHolidays/"):
+		// /nager/api/v3/PublicHolidays/{year}/{country}
+		parts := strings.Split(strings.TrimPrefix(p, "/nager/api/v3/PublicHolidays/"), "/")
+		serveFixture(w, "nager_holidays.json", "application/json", map[string]string{"{{YEAR}}": yearOrNow(parts[0])})
+	case strings.HasPrefix(p, "/holiday-cn/"):
+		year := strings.TrimSuffix(path.Base(p), ".json")
+		serveFixture(w, "holiday_cn.json", "application/json", map[string]string{"{{YEAR}}": yearOrNow(year)})
+	case strings.HasPrefix(p, "/meteoalarm/api/v1/warnings/feeds-"):
+		serveFixture(w, "meteoalarm_feed.json", "application/json", nil)
+	case p == "/quotable/random":
+		serveFixture(w, "quotable_random.json", "application/json", nil)
+	case p == "/bing/HPImageArchive.aspx":
+		serveFixture(w, "bing_archive.json", "application/json", nil)
+	case p == "/apod/planetary/apod":
+		serveFixture(w, "apod.json", "application/json", map[string]string{"{{BASE}}": u.URL})
+	case strings.HasPrefix(p, "/wikimedia/feed/v1/wikipedia/en/featured/"):
+		serveFixture(w, "wikimedia_featured.json", "application/json", map[string]string{"{{BASE}}": u.URL})
+	case p == "/chromecast/backgrounds.json":
+		serveFixture(w, "chromecast_backgrounds.json", "application/json", map[string]string{"{{BASE}}": u.URL})
+	case p == "/bing/th", strings.HasPrefix(p, "/unsplash/"), strings.HasPrefix(p, "/picsum/"), strings.HasPrefix(p, "/images/"):
+		w.Header().Set("Content-Type", "image/jpeg")
+		_, _ = w.Write(fixtureJPEG())
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func serveFixture(w http.ResponseWriter, name, contentType string, vars map[string]string) {
+	b, err := fixtures.ReadFile("fixtures/" + name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for k, v := range vars {
+		b = bytes.ReplaceAll(b, []byte(k), []byte(v))
+	}
+	w.Header().Set("Content-Type", contentType)
+	_, _ = w.Write(b)
+}
+
+// serveBinanceTickers answers a batched ticker request (symbols=["A","B"])
+// with one fixture ticker per symbol.
+func serveBinanceTickers(w http.ResponseWriter, raw string) {
+	var symbols []string
+	if err := json.Unmarshal([]byte(raw), &symbols); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	b, err := fixtures.ReadFile("fixtures/binance_ticker.json")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	items := make([][]byte, 0, len(symbols))
+	for _, sym := range symbols {
+		items = append(items, bytes.ReplaceAll(b, []byte("{{SYMBOL}}"), []byte(sym)))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte("["))
+	_, _ = w.Write(bytes.Join(items, []byte(",")))
+	_, _ = w.Write([]byte("]"))
+}
+
+func yearOrNow(s string) string {
+	if y, err := strconv.Atoi(s); err == nil && y > 1900 {
+		return s
+	}
+	return strconv.Itoa(time.Now().Year())
+}
+
+var fixtureJPEGOnce = struct {
+	once sync.Once
+	b    []byte
+}{}
+
+// fixtureJPEG is a tiny valid JPEG used for every fake image response.
+func fixtureJPEG() []byte {
+	fixtureJPEGOnce.once.Do(func() {
+		img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+		for y := 0; y < 8; y++ {
+			for x := 0; x < 8; x++ {
+				img.Set(x, y, color.RGBA{R: 40, G: 90, B: 160, A: 255})
+			}
+		}
+		var buf bytes.Buffer
+		_ = jpeg.Encode(&buf, img, nil)
+		fixtureJPEGOnce.b = buf.Bytes()
+	})
+	return fixtureJPEGOnce.b
+}
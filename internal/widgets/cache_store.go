@@ -0,0 +1,114 @@
+package widgets
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheStore persists widget caches across restarts so a deploy does not
+// trigger a burst of upstream calls. Implementations must be safe for
+// concurrent use; errors are treated as cache misses.
+type CacheStore interface {
+	GetWidgetCache(key string) (payload []byte, fetchedAt int64, ok bool, err error)
+	SetWidgetCache(key string, payload []byte, fetchedAt, expiresAt int64) error
+	GetSymbolMapping(provider, symbol string) (id, name string, fetchedAt int64, ok bool, err error)
+	SetSymbolMapping(provider, symbol, id, name string, fetchedAt, expiresAt int64) error
+	GetGeocode(query, language string) (pt GeoPoint, ok bool, err error)
+	SetGeocode(query, language string, pt GeoPoint, fetchedAt, expiresAt int64) error
+	ClearWidgetCaches() error
+}
+
+var cacheStoreState = struct {
+	mu sync.RWMutex
+	s  CacheStore
+}{}
+
+// SetCacheStore installs the persistent cache backing the market and geocoding
+// caches; nil
+// disables persistence.
+func SetCacheStore(s CacheStore) {
+	cacheStoreState.mu.Lock()
+	cacheStoreState.s = s
+	cacheStoreState.mu.Unlock()
+}
+
+func cacheStore() CacheStore {
+	cacheStoreState.mu.RLock()
+	defer cacheStoreState.mu.RUnlock()
+	return cacheStoreState.s
+}
+
+// marketsMaxStale is how long persisted market responses remain usable as a
+// fallback when every upstream fails.
+const marketsMaxStale = 24 * time.Hour
+
+// loadMarketsCache returns the cached response for key from memory, or from the
+// persistent store (warming memory) after a restart.
+func loadMarketsCache(key string) (MarketsResponse, bool) {
+	marketsCache.mu.Lock()
+	cached, ok := marketsCache.items[key]
+	marketsCache.mu.Unlock()
+	if ok {
+		return cached, true
+	}
+	cs := cacheStore()
+	if cs == nil {
+		return MarketsResponse{}, false
+	}
+	payload, _, ok, err := cs.GetWidgetCache("markets:" + key)
+	if err != nil || !ok {
+		return MarketsResponse{}, false
+	}
+	var res MarketsResponse
+	if err := json.Unmarshal(payload, &res); err != nil {
+		return MarketsResponse{}, false
+	}
+	marketsCache.mu.Lock()
+	marketsCache.items[key] = res
+	marketsCache.mu.Unlock()
+	return res, true
+}
+
+func saveMarketsCache(key string, res MarketsResponse) {
+	marketsCache.mu.Lock()
+	marketsCache.items[key] = res
+	marketsCache.mu.Unlock()
+	if cs := cacheStore(); cs != nil {
+		if payload, err := json.Marshal(res); err == nil {
+			_ = cs.SetWidgetCache("markets:"+key, payload, res.FetchedAt, res.FetchedAt+int64(marketsMaxStale/time.Second))
+		}
+	}
+}
+
+// geocodeCacheTTL is how long a city resolution is reused. Cities rarely move,
+// so this only bounds how long upstream corrections take to show.
+const geocodeCacheTTL = 30 * 24 * time.Hour
+
+// geocodeCacheKey normalizes a city query and language: "  New  York" and
+// "new york" share an entry, as do "zh-CN" and "zh".
+func geocodeCacheKey(city, language string) (string, string) {
+	return strings.ToLower(strings.Join(strings.Fields(city), " ")), normalizeGeoLanguage(language)
+}
+
+func loadGeocodeCache(city, language string) (GeoPoint, bool) {
+	cs := cacheStore()
+	if cs == nil {
+		return GeoPoint{}, false
+	}
+	q, lang := geocodeCacheKey(city, language)
+	pt, ok, err := cs.GetGeocode(q, lang)
+	if err != nil || !ok {
+		return GeoPoint{}, false
+	}
+	return pt, true
+}
+
+func saveGeocodeCache(city, language string, pt GeoPoint) {
+	if cs := cacheStore(); cs != nil {
+		q, lang := geocodeCacheKey(city, language)
+		now := time.Now().Unix()
+		_ = cs.SetGeocode(q, lang, pt, now, now+int64(geocodeCacheTTL/time.Second))
+	}
+}
@@ -0,0 +1,103 @@
+package widgets
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MaxClocks caps the time zones of one world clock request.
+const MaxClocks = 24
+
+// ClocksResponse is the world clock widget payload.
+type ClocksResponse struct {
+	Now    int64   `json:"now"`    // unix seconds the clocks were computed at
+	Viewer string  `json:"viewer"` // time zone the differences are relative to
+	Items  []Clock `json:"items"`
+}
+
+// Clock is the current time in one IANA time zone.
+type Clock struct {
+	Timezone     string `json:"timezone"`
+	Abbreviation string `json:"abbreviation"` // e.g. "CEST"; may be numeric ("+08")
+	Time         string `json:"time"`         // RFC 3339 in the zone
+	Date         string `json:"date"`         // YYYY-MM-DD in the zone
+	OffsetSec    int    `json:"offsetSec"`
+	Offset       string `json:"offset"` // "+02:00"
+	IsDST        bool   `json:"isDst"`
+	// NextTransition is the next offset change; nil for zones without DST.
+	NextTransition *ClockTransition `json:"nextTransition,omitempty"`
+	// DayDiff is the calendar day relative to the viewer: -1 for yesterday,
+	// 1 for tomorrow.
+	DayDiff int `json:"dayDiff"`
+	// OffsetDiffSec is the zone's offset minus the viewer's.
+	OffsetDiffSec int `json:"offsetDiffSec"`
+}
+
+// ClockTransition is a change of UTC offset, e.g. the start or end of DST.
+type ClockTransition struct {
+	At           string `json:"at"` // RFC 3339, UTC
+	InDays       int    `json:"inDays"`
+	OffsetSec    int    `json:"offsetSec"` // offset from At on
+	Abbreviation string `json:"abbreviation"`
+	IsDST        bool   `json:"isDst"`
+	ShiftSec     int    `json:"shiftSec"` // 3600 when clocks go forward an hour
+}
+
+// WorldClocks computes the clocks for the given IANA zones at now, relative to
+// the viewer's zone.
+func WorldClocks(timezones []string, viewer string, now time.Time) (ClocksResponse, error) {
+	viewerLoc, err := time.LoadLocation(strings.TrimSpace(viewer))
+	if err != nil {
+		return ClocksResponse{}, fmt.Errorf("invalid viewer timezone %q", viewer)
+	}
+	if len(timezones) > MaxClocks {
+		return ClocksResponse{}, fmt.Errorf("too many timezones (max %d)", MaxClocks)
+	}
+
+	vt := now.In(viewerLoc)
+	_, viewerOffset := vt.Zone()
+	viewerDay := time.Date(vt.Year(), vt.Month(), vt.Day(), 0, 0, 0, 0, time.UTC)
+
+	out := ClocksResponse{Now: now.Unix(), Viewer: viewerLoc.String(), Items: make([]Clock, 0, len(timezones))}
+	for _, raw := range timezones {
+		name := strings.TrimSpace(raw)
+		if name == "" {
+			continue
+		}
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			return ClocksResponse{}, fmt.Errorf("invalid timezone %q", name)
+		}
+		t := now.In(loc)
+		abbr, offset := t.Zone()
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		c := Clock{
+			Timezone:      loc.String(),
+			Abbreviation:  abbr,
+			Time:          t.Format(time.RFC3339),
+			Date:          t.Format("2006-01-02"),
+			OffsetSec:     offset,
+			Offset:        t.Format("-07:00"),
+			IsDST:         t.IsDST(),
+			DayDiff:       int(day.Sub(viewerDay).Hours() / 24),
+			OffsetDiffSec: offset - viewerOffset,
+		}
+		if _, end := t.ZoneBounds(); !end.IsZero() {
+			next := end.In(loc)
+			nextAbbr, nextOffset := next.Zone()
+			if nextOffset != offset || next.IsDST() != c.IsDST {
+				c.NextTransition = &ClockTransition{
+					At:           end.UTC().Format(time.RFC3339),
+					InDays:       int(end.Sub(now).Hours() / 24),
+					OffsetSec:    nextOffset,
+					Abbreviation: nextAbbr,
+					IsDST:        next.IsDST(),
+					ShiftSec:     nextOffset - offset,
+				}
+			}
+		}
+		out.Items = append(out.Items, c)
+	}
+	return out, nil
+}
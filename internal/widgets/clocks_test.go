@@ -0,0 +1,47 @@
+package widgets
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWorldClocks(t *testing.T) {
+	if _, err := time.LoadLocation("America/New_York"); err != nil {
+		t.Skip("tzdata unavailable")
+	}
+	now := time.Date(2026, time.October, 16, 20, 0, 0, 0, time.UTC)
+	res, err := WorldClocks([]string{"America/New_York", "Europe/London", "Asia/Shanghai", "Australia/Sydney", "Asia/Kolkata"}, "Asia/Shanghai", now)
+	if err != nil {
+		t.Fatalf("WorldClocks: %v", err)
+	}
+	if res.Viewer != "Asia/Shanghai" || res.Now != now.Unix() || len(res.Items) != 5 {
+		t.Fatalf("unexpected response: %+v", res)
+	}
+
+	ny := res.Items[0]
+	if ny.Abbreviation != "EDT" || !ny.IsDST || ny.OffsetSec != -4*3600 || ny.Offset != "-04:00" || ny.DayDiff != -1 || ny.OffsetDiffSec != -12*3600 {
+		t.Fatalf("unexpected New York clock: %+v", ny)
+	}
+	if tr := ny.NextTransition; tr == nil || tr.At != "2026-11-01T06:00:00Z" || tr.ShiftSec != -3600 || tr.IsDST || tr.Abbreviation != "EST" {
+		t.Fatalf("unexpected New York transition: %+v", tr)
+	}
+	if tr := res.Items[1].NextTransition; tr == nil || tr.At != "2026-10-25T01:00:00Z" || tr.InDays != 8 {
+		t.Fatalf("unexpected London transition: %+v", tr)
+	}
+	if sh := res.Items[2]; sh.IsDST || sh.NextTransition != nil || sh.DayDiff != 0 || sh.OffsetDiffSec != 0 {
+		t.Fatalf("unexpected Shanghai clock: %+v", sh)
+	}
+	if syd := res.Items[3]; !syd.IsDST || syd.Date != "2026-10-17" || syd.NextTransition == nil || syd.NextTransition.ShiftSec != -3600 {
+		t.Fatalf("unexpected Sydney clock: %+v", syd)
+	}
+	if in := res.Items[4]; in.Offset != "+05:30" || in.DayDiff != 0 {
+		t.Fatalf("unexpected Kolkata clock: %+v", in)
+	}
+
+	if _, err := WorldClocks([]string{"Mars/Base"}, "UTC", now); err == nil {
+		t.Fatalf("expected an error for an unknown zone")
+	}
+	if _, err := WorldClocks([]string{"UTC"}, "Nowhere", now); err == nil {
+		t.Fatalf("expected an error for an unknown viewer zone")
+	}
+}
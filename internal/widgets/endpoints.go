@@ -0,0 +1,162 @@
+package widgets
+
+import "sync"
+
+// Endpoints holds the upstream base URLs used by the widget fetchers.
+// Tests point them at local fakes (see internal/testsupport).
+type Endpoints struct {
+	OpenMeteo          string // forecast + timezone API
+	OpenMeteoGeocoding string
+	OpenWeatherMap     string
+	MetNo              string
+	Nominatim          string
+	CoinGecko          string
+	Binance            string
+	Stooq              string
+	Finnhub            string
+	TwelveData         string
+	Yahoo              string // Yahoo Finance chart API
+	Frankfurter        string // ECB exchange rates
+	Nager              string
+	HolidayCN          string // holiday-cn raw data
+	MeteoAlarm         string
+	GeoNames           string // GeoNames dump files (offline geocoding)
+	Quotable           string // quote of the day
+}
+
+// DefaultEndpoints returns the production upstream base URLs.
+func DefaultEndpoints() Endpoints {
+	return Endpoints{
+		OpenMeteo:          "https://api.open-meteo.com",
+		OpenMeteoGeocoding: "https://geocoding-api.open-meteo.com",
+		OpenWeatherMap:     "https://api.openweathermap.org",
+		MetNo:              "https://api.met.no",
+		Nominatim:          "https://nominatim.openstreetmap.org",
+		CoinGecko:          "https://api.coingecko.com",
+		Binance:            "https://api.binance.com",
+		Stooq:              "https://stooq.com",
+		Finnhub:            "https://finnhub.io",
+		TwelveData:         "https://api.twelvedata.com",
+		Yahoo:              "https://query1.finance.yahoo.com",
+		Frankfurter:        "https://api.frankfurter.app",
+		Nager:              "https://date.nager.at",
+		HolidayCN:          "https://raw.githubusercontent.com/NateScarlet/holiday-cn/master",
+		MeteoAlarm:         "https://feeds.meteoalarm.org",
+		GeoNames:           "https://download.geonames.org/export/dump",
+		Quotable:           "https://api.quotable.io",
+	}
+}
+
+var endpointsState = struct {
+	mu sync.RWMutex
+	e  Endpoints
+}{e: DefaultEndpoints()}
+
+// SetEndpoints replaces the upstream base URLs and clears all widget caches so
+// no data from the previous upstreams leaks through. Empty fields keep their
+// default. It returns a function restoring the previous endpoints.
+func SetEndpoints(e Endpoints) (restore func()) {
+	def := DefaultEndpoints()
+	fill := func(v *string, d string) {
+		if *v == "" {
+			*v = d
+		}
+	}
+	fill(&e.OpenMeteo, def.OpenMeteo)
+	fill(&e.OpenMeteoGeocoding, def.OpenMeteoGeocoding)
+	fill(&e.OpenWeatherMap, def.OpenWeatherMap)
+	fill(&e.MetNo, def.MetNo)
+	fill(&e.Nominatim, def.Nominatim)
+	fill(&e.CoinGecko, def.CoinGecko)
+	fill(&e.Binance, def.Binance)
+	fill(&e.Stooq, def.Stooq)
+	fill(&e.Finnhub, def.Finnhub)
+	fill(&e.TwelveData, def.TwelveData)
+	fill(&e.Yahoo, def.Yahoo)
+	fill(&e.Frankfurter, def.Frankfurter)
+	fill(&e.Nager, def.Nager)
+	fill(&e.HolidayCN, def.HolidayCN)
+	fill(&e.MeteoAlarm, def.MeteoAlarm)
+	fill(&e.GeoNames, def.GeoNames)
+	fill(&e.Quotable, def.Quotable)
+
+	endpointsState.mu.Lock()
+	prev := endpointsState.e
+	endpointsState.e = e
+	endpointsState.mu.Unlock()
+	ResetCaches()
+	return func() {
+		endpointsState.mu.Lock()
+		endpointsState.e = prev
+		endpointsState.mu.Unlock()
+		ResetCaches()
+	}
+}
+
+func endpoints() Endpoints {
+	endpointsState.mu.RLock()
+	defer endpointsState.mu.RUnlock()
+	return endpointsState.e
+}
+
+// ResetCaches drops every widget cache, including the persisted ones.
+func ResetCaches() {
+	if cs := cacheStore(); cs != nil {
+		_ = cs.ClearWidgetCaches()
+	}
+
+	weatherCache.mu.Lock()
+	clear(weatherCache.items)
+	weatherCache.mu.Unlock()
+
+	providerWeatherCache.mu.Lock()
+	clear(providerWeatherCache.items)
+	providerWeatherCache.mu.Unlock()
+
+	weatherAlertsCache.mu.Lock()
+	clear(weatherAlertsCache.items)
+	weatherAlertsCache.mu.Unlock()
+
+	marketsCache.mu.Lock()
+	clear(marketsCache.items)
+	marketsCache.mu.Unlock()
+
+	coinGeckoSymbolCache.mu.Lock()
+	clear(coinGeckoSymbolCache.items)
+	coinGeckoSymbolCache.mu.Unlock()
+
+	fxCache.mu.Lock()
+	fxCache.rates = nil
+	fxCache.fetchedAt = 0
+	fxCache.mu.Unlock()
+
+	holidaysCache.mu.Lock()
+	clear(holidaysCache.items)
+	holidaysCache.mu.Unlock()
+
+	holidayCountriesCache.mu.Lock()
+	holidayCountriesCache.fetchedAt = 0
+	holidayCountriesCache.items = nil
+	holidayCountriesCache.mu.Unlock()
+
+	chinaOffDaysCache.mu.Lock()
+	clear(chinaOffDaysCache.items)
+	chinaOffDaysCache.mu.Unlock()
+
+	quotableCache.mu.Lock()
+	quotableCache.date, quotableCache.quote = "", Quote{}
+	quotableCache.mu.Unlock()
+}
+
+// CriticalUpstreams returns the base URLs of the upstreams the default
+// widgets can't do without, keyed by a short name, for the health check.
+func CriticalUpstreams() map[string]string {
+	e := endpoints()
+	return map[string]string{
+		"weather":   e.OpenMeteo,
+		"geocoding": e.OpenMeteoGeocoding,
+		"holidays":  e.Nager,
+		"markets":   e.Stooq,
+		"crypto":    e.Binance,
+	}
+}
@@ -0,0 +1,179 @@
+package widgets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Display currencies supported by ConvertMarkets. Quotes are always fetched in
+// USD and converted server-side.
+const CurrencyUSD = "USD"
+
+var supportedCurrencies = []string{"USD", "EUR", "GBP", "CNY", "JPY"}
+
+// SupportedCurrencies returns the currency codes accepted by NormalizeCurrency.
+func SupportedCurrencies() []string {
+	return append([]string(nil), supportedCurrencies...)
+}
+
+// NormalizeCurrency maps input to a supported ISO 4217 code, or "" if unsupported.
+func NormalizeCurrency(code string) string {
+	c := strings.ToUpper(strings.TrimSpace(code))
+	if c == "" {
+		return CurrencyUSD
+	}
+	for _, s := range supportedCurrencies {
+		if c == s {
+			return c
+		}
+	}
+	return ""
+}
+
+var fxCache = struct {
+	mu        sync.Mutex
+	fetchedAt int64
+	rates     map[string]float64 // units of currency per 1 USD
+}{}
+
+// FetchUSDRates returns exchange rates from USD to the supported currencies,
+// using the ECB reference rates published by Frankfurter (no API key). Rates
+// only change once per working day, so they are cached for hours and a stale
+// copy is preferred over failing.
+func FetchUSDRates(ctx context.Context) (map[string]float64, error) {
+	const freshTTL = 6 * time.Hour
+	const maxStale = 7 * 24 * time.Hour
+
+	fxCache.mu.Lock()
+	cached, fetchedAt := fxCache.rates, fxCache.fetchedAt
+	fxCache.mu.Unlock()
+	age := time.Since(time.Unix(fetchedAt, 0))
+	if cached != nil && age >= 0 && age < freshTTL {
+		return cached, nil
+	}
+
+	rates, err := fetchFrankfurterRates(ctx)
+	if err != nil {
+		if cached != nil && age >= 0 && age < maxStale {
+			return cached, nil
+		}
+		return nil, err
+	}
+	fxCache.mu.Lock()
+	fxCache.rates = rates
+	fxCache.fetchedAt = time.Now().Unix()
+	fxCache.mu.Unlock()
+	return rates, nil
+}
+
+func fetchFrankfurterRates(ctx context.Context) (map[string]float64, error) {
+	to := make([]string, 0, len(supportedCurrencies))
+	for _, c := range supportedCurrencies {
+		if c != CurrencyUSD {
+			to = append(to, c)
+		}
+	}
+	q := url.Values{}
+	q.Set("from", CurrencyUSD)
+	q.Set("to", strings.Join(to, ","))
+	endpoint := endpoints().Frankfurter + "/latest?" + q.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Hearth/0.1")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("frankfurter: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var payload struct {
+		Rates map[string]float64 `json:"rates"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&payload); err != nil {
+		return nil, err
+	}
+	rates := map[string]float64{CurrencyUSD: 1}
+	for _, c := range to {
+		r, ok := payload.Rates[c]
+		if !ok || r <= 0 {
+			return nil, fmt.Errorf("frankfurter: missing rate for %s", c)
+		}
+		rates[c] = r
+	}
+	return rates, nil
+}
+
+// ConvertMarkets fills the display price fields of every quote in currency.
+// priceUsd is left untouched so older clients keep working. The sparkline is
+// converted too so it shares the price's scale. Indices, FX pairs and crypto
+// pairs quoted in a non-USD asset are not converted.
+func ConvertMarkets(res MarketsResponse, currency string, rates map[string]float64) (MarketsResponse, error) {
+	currency = NormalizeCurrency(currency)
+	rate, err := usdRate(currency, rates)
+	if err != nil {
+		return res, err
+	}
+	items := make([]MarketQuote, len(res.Items))
+	for i, q := range res.Items {
+		switch q.Kind {
+		case MarketKindIndex:
+			// Points, not money.
+			q.Price, q.Currency = q.PriceUSD, ""
+			items[i] = q
+			continue
+		case MarketKindFX:
+			// A rate, already denominated in the pair's quote currency.
+			_, quote, _ := fxPair(q.Symbol)
+			q.Price, q.Currency = q.PriceUSD, quote
+			items[i] = q
+			continue
+		case MarketKindCrypto:
+			// Priced in BTC, EUR...: keep the pair's own denomination.
+			if q.QuoteAsset != "" && !isUSDQuote(q.QuoteAsset) {
+				q.Price, q.Currency = q.PriceUSD, q.QuoteAsset
+				items[i] = q
+				continue
+			}
+		}
+		q.Currency = currency
+		q.Price = q.PriceUSD * rate
+		if rate != 1 && q.Series != nil {
+			series := make([]float64, len(q.Series))
+			for j, v := range q.Series {
+				series[j] = v * rate
+			}
+			q.Series = series
+		}
+		items[i] = q
+	}
+	res.Items = items
+	res.Currency = currency
+	return res, nil
+}
+
+// usdRate returns how many units of currency one USD buys.
+func usdRate(currency string, rates map[string]float64) (float64, error) {
+	if currency == CurrencyUSD {
+		return 1, nil
+	}
+	r, ok := rates[currency]
+	if !ok || r <= 0 {
+		return 0, fmt.Errorf("fx: no rate for %q", currency)
+	}
+	return r, nil
+}
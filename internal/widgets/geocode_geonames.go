@@ -0,0 +1,355 @@
+package widgets
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// GeoNames dump files kept in the offline geocoding directory.
+const (
+	geoNamesCitiesFile    = "cities15000.txt"
+	geoNamesCountriesFile = "countryInfo.txt"
+	geoNamesAdmin1File    = "admin1CodesASCII.txt"
+)
+
+// geoNamesExonymPopulation is the population above which a city's Latin
+// alternate names (exonyms such as "Peking" or "Munich") are indexed too;
+// indexing them for every city would cost far more memory than it is worth.
+const geoNamesExonymPopulation = 500000
+
+type geoNamesCity struct {
+	name        string
+	nameZh      string
+	lat, lon    float64
+	countryCode string
+	country     string
+	admin1      string
+	timezone    string
+	population  int
+}
+
+type geoNamesIndex struct {
+	cities []geoNamesCity
+	byName map[string][]int32
+	keys   []string // sorted byName keys, for prefix lookups
+}
+
+var geoNames atomic.Pointer[geoNamesIndex]
+
+// GeoNamesReady reports whether the offline city index is loaded.
+func GeoNamesReady() bool { return geoNames.Load() != nil }
+
+// DisableGeoNames drops the offline city index.
+func DisableGeoNames() { geoNames.Store(nil) }
+
+// EnableGeoNames loads the offline city index from dir, downloading the
+// GeoNames cities15000 dump into it first when missing. It blocks for the
+// download, so callers usually run it in a goroutine.
+func EnableGeoNames(ctx context.Context, dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, name := range []string{geoNamesCitiesFile, geoNamesCountriesFile, geoNamesAdmin1File} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			continue
+		}
+		if err := downloadGeoNamesFile(ctx, dir, name); err != nil {
+			return err
+		}
+	}
+	idx, err := loadGeoNames(dir)
+	if err != nil {
+		return err
+	}
+	geoNames.Store(idx)
+	return nil
+}
+
+// downloadGeoNamesFile fetches one dump file into dir. The cities list is
+// published zipped.
+func downloadGeoNamesFile(ctx context.Context, dir, name string) error {
+	remote := name
+	if name == geoNamesCitiesFile {
+		remote = strings.TrimSuffix(name, ".txt") + ".zip"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoints().GeoNames+"/"+remote, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "Hearth/0.1")
+	client := &http.Client{Timeout: 5 * time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("geonames %s: status=%d body=%s", remote, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+	if err != nil {
+		return err
+	}
+	if remote != name {
+		zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+		if err != nil {
+			return fmt.Errorf("geonames %s: %w", remote, err)
+		}
+		body = nil
+		for _, f := range zr.File {
+			if f.Name != name {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return err
+			}
+			body, err = io.ReadAll(io.LimitReader(rc, 256<<20))
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		}
+		if body == nil {
+			return fmt.Errorf("geonames %s: %s missing from archive", remote, name)
+		}
+	}
+	// Write atomically so an interrupted download is retried next time.
+	tmp := filepath.Join(dir, name+".tmp")
+	if err := os.WriteFile(tmp, body, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, name))
+}
+
+// readGeoNamesTSV calls fn with the tab-separated fields of each non-comment line.
+func readGeoNamesTSV(path string, fn func(fields []string)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 4<<20)
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fn(strings.Split(line, "\t"))
+	}
+	return sc.Err()
+}
+
+func loadGeoNames(dir string) (*geoNamesIndex, error) {
+	countries := map[string]string{}
+	if err := readGeoNamesTSV(filepath.Join(dir, geoNamesCountriesFile), func(f []string) {
+		if len(f) > 4 {
+			countries[f[0]] = f[4]
+		}
+	}); err != nil {
+		return nil, err
+	}
+	admin1 := map[string]string{}
+	if err := readGeoNamesTSV(filepath.Join(dir, geoNamesAdmin1File), func(f []string) {
+		if len(f) > 1 {
+			admin1[f[0]] = f[1]
+		}
+	}); err != nil {
+		return nil, err
+	}
+
+	idx := &geoNamesIndex{byName: map[string][]int32{}}
+	add := func(key string, id int32) {
+		key = strings.ToLower(strings.TrimSpace(key))
+		if key == "" {
+			return
+		}
+		list := idx.byName[key]
+		if n := len(list); n > 0 && list[n-1] == id {
+			return
+		}
+		idx.byName[key] = append(list, id)
+	}
+	err := readGeoNamesTSV(filepath.Join(dir, geoNamesCitiesFile), func(f []string) {
+		if len(f) < 18 {
+			return
+		}
+		lat, err1 := strconv.ParseFloat(f[4], 64)
+		lon, err2 := strconv.ParseFloat(f[5], 64)
+		if err1 != nil || err2 != nil {
+			return
+		}
+		pop, _ := strconv.Atoi(f[14])
+		c := geoNamesCity{
+			name:        f[1],
+			lat:         lat,
+			lon:         lon,
+			countryCode: f[8],
+			country:     countries[f[8]],
+			admin1:      admin1[f[8]+"."+f[10]],
+			timezone:    f[17],
+			population:  pop,
+		}
+		id := int32(len(idx.cities))
+		add(c.name, id)
+		add(f[2], id)
+		for _, alt := range strings.Split(f[3], ",") {
+			switch {
+			case containsCJK(alt):
+				if c.nameZh == "" || isMoreSimplified(alt, c.nameZh) {
+					c.nameZh = alt
+				}
+				add(alt, id)
+			case pop >= geoNamesExonymPopulation && len(alt) >= 3 && !strings.ContainsAny(alt, "0123456789") && strings.ToUpper(alt) != alt:
+				add(alt, id)
+			}
+		}
+		idx.cities = append(idx.cities, c)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(idx.cities) == 0 {
+		return nil, errors.New("geonames: no cities")
+	}
+	idx.keys = make([]string, 0, len(idx.byName))
+	for k := range idx.byName {
+		idx.keys = append(idx.keys, k)
+	}
+	sort.Strings(idx.keys)
+	return idx, nil
+}
+
+// search matches the first comma-separated part of query against city names;
+// further parts must match the country code, country or first-level region.
+// Exact names win over prefixes; ties go to the larger city.
+func (idx *geoNamesIndex) search(query string, count int, language string) []GeoPoint {
+	parts := strings.FieldsFunc(query, func(r rune) bool { return r == ',' || r == '，' })
+	if len(parts) == 0 {
+		return nil
+	}
+	q := strings.ToLower(strings.TrimSpace(parts[0]))
+	if q == "" {
+		return nil
+	}
+	var hints []string
+	for _, p := range parts[1:] {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+			hints = append(hints, p)
+		}
+	}
+
+	ids := append([]int32(nil), idx.byName[q]...)
+	exact := len(ids)
+	// Prefixes only for queries long enough to be selective.
+	if n := len([]rune(q)); n >= 3 || (n >= 2 && containsCJK(q)) {
+		for i := sort.SearchStrings(idx.keys, q); i < len(idx.keys) && strings.HasPrefix(idx.keys[i], q) && len(ids) < 500; i++ {
+			if idx.keys[i] != q {
+				ids = append(ids, idx.byName[idx.keys[i]]...)
+			}
+		}
+	}
+
+	type hit struct {
+		id    int32
+		exact bool
+	}
+	seen := map[int32]bool{}
+	hits := make([]hit, 0, len(ids))
+	for i, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		c := idx.cities[id]
+		if !geoNamesHintsMatch(c, hints) {
+			continue
+		}
+		hits = append(hits, hit{id: id, exact: i < exact})
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].exact != hits[j].exact {
+			return hits[i].exact
+		}
+		return idx.cities[hits[i].id].population > idx.cities[hits[j].id].population
+	})
+
+	zh := normalizeGeoLanguage(language) == "zh"
+	out := make([]GeoPoint, 0, min(count, len(hits)))
+	for _, h := range hits {
+		if len(out) >= count {
+			break
+		}
+		c := idx.cities[h.id]
+		name := c.name
+		if zh && c.nameZh != "" {
+			name = c.nameZh
+		}
+		label := []string{name}
+		if c.admin1 != "" && c.admin1 != c.name {
+			label = append(label, c.admin1)
+		}
+		if c.country != "" {
+			label = append(label, c.country)
+		}
+		out = append(out, GeoPoint{
+			Lat:         c.lat,
+			Lon:         c.lon,
+			DisplayName: strings.Join(label, ", "),
+			Timezone:    c.timezone,
+			CountryCode: c.countryCode,
+		})
+	}
+	return out
+}
+
+func geoNamesHintsMatch(c geoNamesCity, hints []string) bool {
+	for _, h := range hints {
+		if h != strings.ToLower(c.countryCode) && h != strings.ToLower(c.country) && h != strings.ToLower(c.admin1) {
+			return false
+		}
+	}
+	return true
+}
+
+// romanize returns the name of the largest city called name, e.g. "Beijing"
+// for "北京", or "" when none is.
+func (idx *geoNamesIndex) romanize(name string) string {
+	best := int32(-1)
+	for _, id := range idx.byName[strings.ToLower(strings.TrimSpace(name))] {
+		if best < 0 || idx.cities[id].population > idx.cities[best].population {
+			best = id
+		}
+	}
+	if best < 0 {
+		return ""
+	}
+	return idx.cities[best].name
+}
+
+// searchCitiesOffline looks query up in the GeoNames index, if loaded.
+func searchCitiesOffline(query string, count int, language string) []GeoPoint {
+	idx := geoNames.Load()
+	if idx == nil {
+		return nil
+	}
+	if count <= 0 {
+		count = 8
+	}
+	return idx.search(query, min(count, 20), language)
+}
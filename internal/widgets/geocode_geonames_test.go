@@ -0,0 +1,97 @@
+package widgets
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func geoNamesLine(id, name, ascii, alts, lat, lon, cc, admin1, pop, tz string) string {
+	return strings.Join([]string{id, name, ascii, alts, lat, lon, "P", "PPL", cc, "", admin1, "", "", "", pop, "", "40", tz, "2024-01-01"}, "\t")
+}
+
+func TestGeoNamesOffline(t *testing.T) {
+	cities := strings.Join([]string{
+		geoNamesLine("2950159", "Berlin", "Berlin", "BER,Berlyn,柏林", "52.52437", "13.41053", "DE", "16", "3426354", "Europe/Berlin"),
+		geoNamesLine("5083330", "Berlin", "Berlin", "", "44.46867", "-71.18508", "US", "NH", "15500", "America/New_York"),
+		geoNamesLine("1816670", "Beijing", "Beijing", "BJS,Peking,北京,北京市", "39.9075", "116.39723", "CN", "22", "18960744", "Asia/Shanghai"),
+		geoNamesLine("4951788", "Springfield", "Springfield", "", "42.10148", "-72.58981", "US", "MA", "155929", "America/New_York"),
+		geoNamesLine("4409896", "Springfield", "Springfield", "", "37.21533", "-93.29824", "US", "MO", "166810", "America/Chicago"),
+	}, "\n") + "\n"
+	var zipped bytes.Buffer
+	zw := zip.NewWriter(&zipped)
+	f, _ := zw.Create("cities15000.txt")
+	_, _ = f.Write([]byte(cities))
+	_ = zw.Close()
+
+	files := map[string]string{
+		"/cities15000.zip":      zipped.String(),
+		"/countryInfo.txt":      "#ISO\tISO3\tISO-Numeric\tfips\tCountry\n#comment\nDE\tDEU\t276\tGM\tGermany\nCN\tCHN\t156\tCH\tChina\nUS\tUSA\t840\tUS\tUnited States\n",
+		"/admin1CodesASCII.txt": "DE.16\tBerlin\tBerlin\t2950157\nCN.22\tBeijing\tBeijing\t2038349\nUS.MA\tMassachusetts\tMassachusetts\t6254926\nUS.MO\tMissouri\tMissouri\t4398678\nUS.NH\tNew Hampshire\tNew Hampshire\t5090174\n",
+	}
+	var downloads atomic.Int32
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := files[r.URL.Path]
+		if !ok {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		downloads.Add(1)
+		_, _ = w.Write([]byte(body))
+	}))
+	defer up.Close()
+	restore := SetEndpoints(Endpoints{GeoNames: up.URL, Nominatim: up.URL, OpenMeteoGeocoding: up.URL, OpenMeteo: up.URL})
+	defer restore()
+	defer DisableGeoNames()
+
+	dir := t.TempDir()
+	if err := EnableGeoNames(context.Background(), dir); err != nil {
+		t.Fatalf("EnableGeoNames: %v", err)
+	}
+	if !GeoNamesReady() || downloads.Load() != 3 {
+		t.Fatalf("expected the index after 3 downloads, got ready=%v downloads=%d", GeoNamesReady(), downloads.Load())
+	}
+	// Already on disk: loaded without downloading again.
+	if err := EnableGeoNames(context.Background(), dir); err != nil || downloads.Load() != 3 {
+		t.Fatalf("expected no new downloads, got %d (err=%v)", downloads.Load(), err)
+	}
+
+	cases := []struct {
+		query, lang, want, tz string
+	}{
+		{"berlin", "en", "Berlin, Germany", "Europe/Berlin"},
+		{"Berlin, US", "en", "Berlin, New Hampshire, United States", "America/New_York"},
+		{"Peking", "en", "Beijing, China", "Asia/Shanghai"},
+		{"北京", "zh", "北京, China", "Asia/Shanghai"},
+		{"Springf", "en", "Springfield, Missouri, United States", "America/Chicago"},
+		{"Springfield, Massachusetts", "en", "Springfield, Massachusetts, United States", "America/New_York"},
+	}
+	for _, tc := range cases {
+		list, err := SearchCities(context.Background(), tc.query, 5, tc.lang)
+		if err != nil || len(list) == 0 {
+			t.Fatalf("SearchCities(%q): %v", tc.query, err)
+		}
+		if list[0].DisplayName != tc.want || list[0].Timezone != tc.tz {
+			t.Errorf("SearchCities(%q) = %+v, want %q (%s)", tc.query, list[0], tc.want, tc.tz)
+		}
+	}
+	if list, _ := SearchCities(context.Background(), "Springfield", 5, "en"); len(list) != 2 {
+		t.Errorf("expected both Springfields, got %+v", list)
+	}
+	// The index romanizes CJK names for the online providers; names outside
+	// it come from the table or are transliterated.
+	for q, want := range map[string]string{"柏林": "Berlin", "北京市": "Beijing", "厦门市": "xiamen", "株洲市": "zhuzhou", "Berlin": "", "亚特兰蒂斯": "yatelandisi"} {
+		if got := romanizeCJK(q); got != want {
+			t.Errorf("romanizeCJK(%q) = %q, want %q", q, got, want)
+		}
+	}
+	// Misses fall through to the online providers, which are down here.
+	if _, err := SearchCities(context.Background(), "Atlantis", 5, "en"); err == nil {
+		t.Errorf("expected a miss for an unknown city")
+	}
+}
@@ -11,7 +11,6 @@ import (
 	"strconv"
 	"strings"
 	"time"
-	"unicode"
 )
 
 // nominatimResult represents a single result from Nominatim API
@@ -89,73 +88,6 @@ func isMoreSimplified(a, b string) bool {
 	return countTraditional(a) < countTraditional(b)
 }
 
-// Common Chinese city names to English/pinyin mapping for Nominatim search
-// Nominatim doesn't support searching in Chinese, so we need to translate first
-var chineseToEnglish = map[string]string{
-	// Direct-controlled municipalities
-	"北京": "beijing", "上海": "shanghai", "天津": "tianjin", "重庆": "chongqing",
-	// Provincial capitals and major cities - Northeast
-	"长春": "changchun", "哈尔滨": "harbin", "沈阳": "shenyang", "大连": "dalian",
-	// North
-	"石家庄": "shijiazhuang", "太原": "taiyuan", "呼和浩特": "hohhot",
-	// East
-	"济南": "jinan", "青岛": "qingdao", "郑州": "zhengzhou", "武汉": "wuhan",
-	"长沙": "changsha", "南京": "nanjing", "杭州": "hangzhou", "合肥": "hefei",
-	"南昌": "nanchang", "福州": "fuzhou", "厦门": "xiamen",
-	// South
-	"广州": "guangzhou", "深圳": "shenzhen", "东莞": "dongguan", "珠海": "zhuhai", "佛山": "foshan",
-	"南宁": "nanning", "海口": "haikou",
-	// Southwest
-	"成都": "chengdu", "贵阳": "guiyang", "昆明": "kunming", "拉萨": "lhasa",
-	// Northwest
-	"西安": "xian", "兰州": "lanzhou", "西宁": "xining", "银川": "yinchuan", "乌鲁木齐": "urumqi",
-	// Other major cities
-	"苏州": "suzhou", "无锡": "wuxi", "常州": "changzhou", "宁波": "ningbo",
-	"温州": "wenzhou", "嘉兴": "jiaxing", "烟台": "yantai", "潍坊": "weifang",
-	"淄博": "zibo", "威海": "weihai", "洛阳": "luoyang", "开封": "kaifeng",
-	"唐山": "tangshan", "秦皇岛": "qinhuangdao", "包头": "baotou",
-	"鞍山": "anshan", "抚顺": "fushun", "吉林": "jilin city", "齐齐哈尔": "qiqihar",
-	"大庆": "daqing", "牡丹江": "mudanjiang", "佳木斯": "jiamusi",
-	"徐州": "xuzhou", "连云港": "lianyungang", "扬州": "yangzhou", "镇江": "zhenjiang",
-	"绍兴": "shaoxing", "台州": "taizhou", "金华": "jinhua", "衢州": "quzhou",
-	"芜湖": "wuhu", "蚌埠": "bengbu", "马鞍山": "maanshan", "安庆": "anqing",
-	"泉州": "quanzhou", "漳州": "zhangzhou", "莆田": "putian", "三明": "sanming",
-	"九江": "jiujiang", "景德镇": "jingdezhen", "赣州": "ganzhou",
-	"汕头": "shantou", "惠州": "huizhou", "中山": "zhongshan", "江门": "jiangmen",
-	"桂林": "guilin", "柳州": "liuzhou", "北海": "beihai",
-	"三亚": "sanya", "绵阳": "mianyang", "宜宾": "yibin", "泸州": "luzhou",
-	"遵义": "zunyi", "曲靖": "qujing", "玉溪": "yuxi", "咸阳": "xianyang",
-	"宝鸡": "baoji", "延安": "yanan", "天水": "tianshui", "白银": "baiyin",
-	// Hong Kong, Macau, Taiwan
-	"香港": "hong kong", "澳门": "macau", "台北": "taipei", "高雄": "kaohsiung",
-	"台中": "taichung", "台南": "tainan", "新北": "new taipei city",
-	// International cities
-	"纽约": "new york", "洛杉矶": "los angeles", "旧金山": "san francisco",
-	"芝加哥": "chicago", "伦敦": "london", "巴黎": "paris", "东京": "tokyo",
-	"首尔": "seoul", "新加坡": "singapore", "悉尼": "sydney", "墨尔本": "melbourne",
-	"温哥华": "vancouver", "多伦多": "toronto", "柏林": "berlin", "莫斯科": "moscow",
-	"迪拜": "dubai", "曼谷": "bangkok", "吉隆坡": "kuala lumpur",
-}
-
-// containsCJKNominatim checks if a string contains CJK characters
-func containsCJKNominatim(s string) bool {
-	for _, r := range s {
-		if unicode.Is(unicode.Han, r) {
-			return true
-		}
-	}
-	return false
-}
-
-// translateChineseQuery translates a Chinese city name to English/pinyin for search
-func translateChineseQuery(query string) string {
-	if eng, ok := chineseToEnglish[query]; ok {
-		return eng
-	}
-	return query
-}
-
-// fetchNominatim queries the Nominatim API with retry for rate limiting
 func fetchNominatim(ctx context.Context, query string, limit int, language string) ([]nominatimResult, error) {
 	params := url.Values{}
 	params.Set("q", query)
@@ -168,7 +100,7 @@ func fetchNominatim(ctx context.Context, query string, limit int, language strin
 		params.Set("accept-language", language)
 	}
 
-	endpoint := "https://nominatim.openstreetmap.org/search?" + params.Encode()
+	endpoint := endpoints().Nominatim + "/search?" + params.Encode()
 
 	// Retry up to 3 times with exponential backoff for rate limiting
 	var lastErr error
@@ -254,16 +186,13 @@ func SearchCitiesNominatim(ctx context.Context, query string, count int, languag
 		acceptLang = "zh-CN,zh"
 	}
 
-	// If query is in Chinese, translate to English for search
-	// Nominatim doesn't support Chinese input well, but returns Chinese output fine
+	// If query is in Chinese, romanize it for search
+	// Nominatim doesn't support Chinese input well, but returns Chinese output fine.
+	// A transliterated name may not be what the place is called, so the
+	// original query is still tried when it finds nothing.
 	searchQuery := q
-	if containsCJKNominatim(q) {
-		if translated := translateChineseQuery(q); translated != q {
-			searchQuery = translated
-		} else {
-			// If we don't have a translation, try the original query anyway
-			// It might be an international city name in Chinese characters
-		}
+	if romanized := romanizeCJK(q); romanized != "" {
+		searchQuery = romanized
 	}
 
 	// Fetch from Nominatim
@@ -311,6 +240,7 @@ func SearchCitiesNominatim(ctx context.Context, query string, count int, languag
 			Lon:         lon,
 			DisplayName: displayName,
 			Timezone:    "", // Will be resolved separately if needed
+			CountryCode: strings.ToUpper(strings.TrimSpace(r.Address.CountryCode)),
 		})
 	}
 
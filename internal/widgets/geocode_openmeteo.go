@@ -20,17 +20,19 @@ type GeoPoint struct {
 	Lon         float64
 	DisplayName string
 	Timezone    string
+	CountryCode string // ISO 3166-1 alpha-2, upper case (may be empty)
 }
 
 type geoResult struct {
-	ID         int     `json:"id"`
-	Name       string  `json:"name"`
-	Latitude   float64 `json:"latitude"`
-	Longitude  float64 `json:"longitude"`
-	Timezone   string  `json:"timezone"`
-	Country    string  `json:"country"`
-	Admin1     string  `json:"admin1"`
-	Population int     `json:"population"`
+	ID          int     `json:"id"`
+	Name        string  `json:"name"`
+	Latitude    float64 `json:"latitude"`
+	Longitude   float64 `json:"longitude"`
+	Timezone    string  `json:"timezone"`
+	Country     string  `json:"country"`
+	CountryCode string  `json:"country_code"`
+	Admin1      string  `json:"admin1"`
+	Population  int     `json:"population"`
 }
 
 type geoPayload struct {
@@ -55,51 +57,6 @@ func containsCJK(s string) bool {
 	return false
 }
 
-// Common Chinese city names to pinyin mapping for better search results
-// Open-Meteo's geocoding API works much better with pinyin for major cities
-var chineseToPinyin = map[string]string{
-	// Direct-controlled municipalities
-	"北京": "beijing", "上海": "shanghai", "天津": "tianjin", "重庆": "chongqing",
-	// Provincial capitals and major cities
-	"长春": "changchun", "哈尔滨": "harbin", "沈阳": "shenyang", "大连": "dalian",
-	"石家庄": "shijiazhuang", "太原": "taiyuan", "呼和浩特": "hohhot",
-	"济南": "jinan", "青岛": "qingdao", "郑州": "zhengzhou", "武汉": "wuhan",
-	"长沙": "changsha", "南京": "nanjing", "杭州": "hangzhou", "合肥": "hefei",
-	"南昌": "nanchang", "福州": "fuzhou", "厦门": "xiamen", "广州": "guangzhou",
-	"深圳": "shenzhen", "东莞": "dongguan", "珠海": "zhuhai", "佛山": "foshan",
-	"南宁": "nanning", "海口": "haikou", "成都": "chengdu", "贵阳": "guiyang",
-	"昆明": "kunming", "拉萨": "lhasa", "西安": "xian", "兰州": "lanzhou",
-	"西宁": "xining", "银川": "yinchuan", "乌鲁木齐": "urumqi",
-	// Other major cities
-	"苏州": "suzhou", "无锡": "wuxi", "常州": "changzhou", "宁波": "ningbo",
-	"温州": "wenzhou", "嘉兴": "jiaxing", "烟台": "yantai", "潍坊": "weifang",
-	"淄博": "zibo", "威海": "weihai", "洛阳": "luoyang", "开封": "kaifeng",
-	"唐山": "tangshan", "秦皇岛": "qinhuangdao", "包头": "baotou",
-	"鞍山": "anshan", "抚顺": "fushun", "吉林": "jilin", "齐齐哈尔": "qiqihar",
-	"大庆": "daqing", "牡丹江": "mudanjiang", "佳木斯": "jiamusi",
-	"徐州": "xuzhou", "连云港": "lianyungang", "扬州": "yangzhou", "镇江": "zhenjiang",
-	"绍兴": "shaoxing", "台州": "taizhou", "金华": "jinhua", "衢州": "quzhou",
-	"芜湖": "wuhu", "蚌埠": "bengbu", "马鞍山": "maanshan", "安庆": "anqing",
-	"泉州": "quanzhou", "漳州": "zhangzhou", "莆田": "putian", "三明": "sanming",
-	"九江": "jiujiang", "景德镇": "jingdezhen", "赣州": "ganzhou",
-	"汕头": "shantou", "惠州": "huizhou", "中山": "zhongshan", "江门": "jiangmen",
-	"桂林": "guilin", "柳州": "liuzhou", "北海": "beihai",
-	"三亚": "sanya", "绵阳": "mianyang", "宜宾": "yibin", "泸州": "luzhou",
-	"遵义": "zunyi", "曲靖": "qujing", "玉溪": "yuxi", "咸阳": "xianyang",
-	"宝鸡": "baoji", "延安": "yanan", "天水": "tianshui", "白银": "baiyin",
-	// Hong Kong, Macau, Taiwan
-	"香港": "hong kong", "澳门": "macau", "台北": "taipei", "高雄": "kaohsiung",
-	"台中": "taichung", "台南": "tainan", "新北": "new taipei",
-}
-
-// getPinyinVariant returns the pinyin version of a Chinese city name if available
-func getPinyinVariant(chinese string) string {
-	if pinyin, ok := chineseToPinyin[chinese]; ok {
-		return pinyin
-	}
-	return ""
-}
-
 func fetchGeo(ctx context.Context, q string, count int, language string) (geoPayload, error) {
 	params := url.Values{}
 	params.Set("name", q)
@@ -107,7 +64,7 @@ func fetchGeo(ctx context.Context, q string, count int, language string) (geoPay
 	params.Set("language", language)
 	params.Set("format", "json")
 
-	endpoint := "https://geocoding-api.open-meteo.com/v1/search?" + params.Encode()
+	endpoint := endpoints().OpenMeteoGeocoding + "/v1/search?" + params.Encode()
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
 		return geoPayload{}, err
@@ -146,7 +103,12 @@ func fetchGeo(ctx context.Context, q string, count int, language string) (geoPay
 
 // SearchCities searches for cities using Nominatim (OpenStreetMap) API as the primary backend.
 // Falls back to Open-Meteo if Nominatim fails, since Open-Meteo includes timezone info.
+// When the offline GeoNames index is enabled it is tried first.
 func SearchCities(ctx context.Context, query string, count int, language string) ([]GeoPoint, error) {
+	if results := searchCitiesOffline(query, count, language); len(results) > 0 {
+		return results, nil
+	}
+
 	// Try Nominatim first - much better for Chinese/international city names
 	results, err := SearchCitiesNominatim(ctx, query, count, language)
 	if err == nil && len(results) > 0 {
@@ -247,7 +209,7 @@ func SearchCitiesOpenMeteo(ctx context.Context, query string, count int, languag
 	// Open-Meteo's search works much better with pinyin for major Chinese cities
 	pinyinQuery := ""
 	if isCJKQuery {
-		pinyinQuery = getPinyinVariant(q)
+		pinyinQuery = romanizeCJK(q)
 	}
 
 	// Always search in English first (better coverage for major cities)
@@ -400,6 +362,7 @@ func SearchCitiesOpenMeteo(ctx context.Context, query string, count int, languag
 			Lon:         r.Longitude,
 			DisplayName: dn,
 			Timezone:    strings.TrimSpace(r.Timezone),
+			CountryCode: strings.ToUpper(strings.TrimSpace(r.CountryCode)),
 		})
 	}
 
@@ -413,19 +376,16 @@ func SearchCitiesOpenMeteo(ctx context.Context, query string, count int, languag
 // GeocodeCity resolves a free-form city name to a single lat/lon via Open-Meteo's geocoding API.
 // This lets the frontend stay city-only (no manual lat/lon).
 func GeocodeCity(ctx context.Context, city string) (GeoPoint, error) {
-	list, err := SearchCities(ctx, city, 1, "en")
-	if err != nil {
-		return GeoPoint{}, err
-	}
-	if len(list) == 0 {
-		return GeoPoint{}, errors.New("city not found")
-	}
-	return list[0], nil
+	return GeocodeCityLocalized(ctx, city, "en")
 }
 
 // GeocodeCityLocalized resolves a free-form city name to a single lat/lon via Open-Meteo's geocoding API,
 // returning a display name localized to the requested language.
+// Resolutions are kept in the persistent cache, when installed, for a month.
 func GeocodeCityLocalized(ctx context.Context, city string, language string) (GeoPoint, error) {
+	if pt, ok := loadGeocodeCache(city, language); ok {
+		return pt, nil
+	}
 	list, err := SearchCities(ctx, city, 1, language)
 	if err != nil {
 		return GeoPoint{}, err
@@ -433,5 +393,6 @@ func GeocodeCityLocalized(ctx context.Context, city string, language string) (Ge
 	if len(list) == 0 {
 		return GeoPoint{}, errors.New("city not found")
 	}
+	saveGeocodeCache(city, language, list[0])
 	return list[0], nil
 }
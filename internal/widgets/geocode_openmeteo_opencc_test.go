@@ -2,13 +2,54 @@ package widgets
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 )
 
+// fakeGeocoders serves the Open-Meteo geocoding search from openMeteo, keyed
+// by "name|language", and the Nominatim search from nominatim, keyed by the
+// query. Unknown queries find nothing; the forecast API answers every
+// timezone lookup with Asia/Shanghai.
+func fakeGeocoders(t *testing.T, openMeteo map[string][]geoResult, nominatim map[string][]nominatimResult) {
+	t.Helper()
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/geocoding/v1/search":
+			_ = json.NewEncoder(w).Encode(geoPayload{Results: openMeteo[q.Get("name")+"|"+q.Get("language")]})
+		case "/nominatim/search":
+			results := nominatim[q.Get("q")]
+			if results == nil {
+				results = []nominatimResult{}
+			}
+			_ = json.NewEncoder(w).Encode(results)
+		case "/open-meteo/v1/forecast":
+			_, _ = w.Write([]byte(`{"timezone":"Asia/Shanghai"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(up.Close)
+	t.Cleanup(SetEndpoints(Endpoints{OpenMeteoGeocoding: up.URL + "/geocoding", Nominatim: up.URL + "/nominatim", OpenMeteo: up.URL + "/open-meteo"}))
+	DisableGeoNames()
+}
+
 func TestSearchCitiesZhUsesSimplifiedCityName(t *testing.T) {
-	// Open-Meteo currently returns Traditional for London under language=zh.
-	// We merge with zh-CN to guarantee Simplified.
+	// Open-Meteo returns Traditional for London under language=zh. We merge
+	// with zh-CN to guarantee Simplified.
+	london := func(name, admin1 string) []geoResult {
+		return []geoResult{{ID: 2643743, Name: name, Latitude: 51.50853, Longitude: -0.12574, Timezone: "Europe/London", Country: "United Kingdom", CountryCode: "GB", Admin1: admin1, Population: 8961989}}
+	}
+	fakeGeocoders(t, map[string][]geoResult{
+		"London|en":    london("London", "England"),
+		"London|zh":    london("倫敦", "英格蘭"),
+		"London|zh-CN": london("伦敦", "英格兰"),
+	}, nil)
+
 	list, err := SearchCities(context.Background(), "London", 1, "zh")
 	if err != nil {
 		t.Fatalf("SearchCities error: %v", err)
@@ -16,62 +57,81 @@ func TestSearchCitiesZhUsesSimplifiedCityName(t *testing.T) {
 	if len(list) == 0 {
 		t.Fatalf("expected results")
 	}
-	if strings.Contains(list[0].DisplayName, "倫敦") {
+	if !strings.HasPrefix(list[0].DisplayName, "伦敦") {
 		t.Fatalf("expected simplified city name, got %q", list[0].DisplayName)
 	}
+	if list[0].Timezone != "Europe/London" {
+		t.Fatalf("expected the timezone from Open-Meteo, got %q", list[0].Timezone)
+	}
 }
 
 func TestSearchCitiesChangchun(t *testing.T) {
-	// Test that searching "长春" returns Changchun in Jilin as a top result
-	// The major city (population ~4.7M) should be prioritized over villages
+	// Searching "长春" should return Changchun in Jilin as the top result: the
+	// major city (population ~4.7M) is prioritized over villages. The Chinese
+	// search only finds the village; the romanized one finds the city.
+	village := geoResult{ID: 1, Name: "Changchun", Latitude: 29.1, Longitude: 112.2, Timezone: "Asia/Shanghai", Country: "China", CountryCode: "CN", Admin1: "Hunan"}
+	city := geoResult{ID: 2038180, Name: "Changchun", Latitude: 43.88, Longitude: 125.32278, Timezone: "Asia/Shanghai", Country: "China", CountryCode: "CN", Admin1: "Jilin", Population: 4714996}
+	zh := func(r geoResult, admin1 string) geoResult {
+		r.Name, r.Admin1, r.Country = "长春", admin1, "中国"
+		return r
+	}
+	both := []geoResult{village, city}
+	bothZh := []geoResult{zh(village, "湖南省"), zh(city, "吉林省")}
+	fakeGeocoders(t, map[string][]geoResult{
+		"长春|en":           {village},
+		"长春|zh":           {zh(village, "湖南省")},
+		"长春|zh-CN":        {zh(village, "湖南省")},
+		"changchun|en":    both,
+		"changchun|zh":    bothZh,
+		"changchun|zh-CN": bothZh,
+	}, nil)
+
 	testCases := []struct {
 		query    string
 		language string
+		want     string
 	}{
-		{"长春", "zh"},
-		{"changchun", "zh"},
-		{"changchun", "en"},
+		{"长春", "zh", "长春, 吉林省, 中国"},
+		{"changchun", "zh", "长春, 吉林省, 中国"},
+		{"changchun", "en", "Changchun, Jilin, China"},
 	}
-
 	for _, tc := range testCases {
 		t.Run(tc.query+"_"+tc.language, func(t *testing.T) {
 			list, err := SearchCities(context.Background(), tc.query, 5, tc.language)
 			if err != nil {
 				t.Fatalf("SearchCities error: %v", err)
 			}
-			if len(list) == 0 {
-				t.Fatalf("expected results")
+			if len(list) != 2 {
+				t.Fatalf("expected the city and the village, got %+v", list)
 			}
-
-			// The first result should be the major city in Jilin
-			first := list[0].DisplayName
-			if tc.language == "zh" {
-				// Should contain 吉林 (Jilin in Chinese)
-				if !strings.Contains(first, "吉林") {
-					t.Errorf("expected first result to be in Jilin (吉林), got %q", first)
-				}
-			} else {
-				// Should contain Jilin
-				if !strings.Contains(first, "Jilin") {
-					t.Errorf("expected first result to be in Jilin, got %q", first)
-				}
+			if list[0].DisplayName != tc.want {
+				t.Errorf("expected %q first, got %q", tc.want, list[0].DisplayName)
 			}
 		})
 	}
 }
 
 func TestSearchCitiesBilingual(t *testing.T) {
-	// Test that English pinyin search works for Chinese cities
+	// English pinyin search finds Chinese cities through Nominatim, whose
+	// results get their timezone from Open-Meteo.
+	place := func(id int, name, state, lat, lon string) []nominatimResult {
+		return []nominatimResult{{PlaceID: id, Lat: lat, Lon: lon, Name: name, Class: "boundary", Type: "administrative", AddressType: "city",
+			Address: nominatimAddr{City: name, State: state, Country: "China", CountryCode: "cn"}}}
+	}
+	fakeGeocoders(t, nil, map[string][]nominatimResult{
+		"beijing":  place(1, "Beijing", "Beijing", "39.9057136", "116.3912972"),
+		"shanghai": place(2, "Shanghai", "Shanghai", "31.2312707", "121.4700152"),
+		"shenzhen": place(3, "Shenzhen", "Guangdong Province", "22.5445741", "114.0545429"),
+	})
+
 	testCases := []struct {
-		query       string
-		expectCity  string // Partial match
-		expectAdmin string // Partial match
+		query string
+		want  string
 	}{
-		{"beijing", "Beijing", ""},
-		{"shanghai", "Shanghai", ""},
-		{"shenzhen", "Shenzhen", "Guangdong"},
+		{"beijing", "Beijing, China"},
+		{"shanghai", "Shanghai, China"},
+		{"shenzhen", "Shenzhen, Guangdong Province, China"},
 	}
-
 	for _, tc := range testCases {
 		t.Run(tc.query, func(t *testing.T) {
 			list, err := SearchCities(context.Background(), tc.query, 3, "en")
@@ -81,14 +141,44 @@ func TestSearchCitiesBilingual(t *testing.T) {
 			if len(list) == 0 {
 				t.Fatalf("expected results for %q", tc.query)
 			}
-
-			first := list[0].DisplayName
-			if !strings.Contains(first, tc.expectCity) {
-				t.Errorf("expected %q in result, got %q", tc.expectCity, first)
-			}
-			if tc.expectAdmin != "" && !strings.Contains(first, tc.expectAdmin) {
-				t.Errorf("expected %q in result, got %q", tc.expectAdmin, first)
+			if list[0].DisplayName != tc.want || list[0].Timezone != "Asia/Shanghai" || list[0].CountryCode != "CN" {
+				t.Errorf("expected %q in Asia/Shanghai, got %+v", tc.want, list[0])
 			}
 		})
 	}
 }
+
+func TestSearchCitiesRomanizedQuery(t *testing.T) {
+	// Nominatim is searched with the conventional name of a Chinese city, and
+	// with the original query when the transliteration of an unlisted one
+	// finds nothing.
+	place := func(id int, name, country, code, lat, lon string) []nominatimResult {
+		return []nominatimResult{{PlaceID: id, Lat: lat, Lon: lon, Name: name, Class: "boundary", Type: "administrative", AddressType: "city",
+			Address: nominatimAddr{City: name, Country: country, CountryCode: code}}}
+	}
+	fakeGeocoders(t, nil, map[string][]nominatimResult{
+		"xiamen":    place(1, "Xiamen", "China", "cn", "24.4801069", "118.0853479"),
+		"shamen":    place(2, "Shamen", "China", "cn", "30.0", "110.0"),
+		"chongqing": place(3, "Chongqing", "China", "cn", "29.5585712", "106.5492822"),
+		"hong kong": place(4, "Hong Kong", "China", "cn", "22.2793278", "114.1628131"),
+		"tokyo":     place(5, "Tokyo", "Japan", "jp", "35.6768601", "139.7638947"),
+		"伊斯坦布尔":     place(6, "Istanbul", "Turkey", "tr", "41.006381", "28.9758715"),
+	})
+
+	for q, want := range map[string]string{
+		"厦门":    "Xiamen, China",
+		"重庆":    "Chongqing, China",
+		"香港":    "Hong Kong, China",
+		"东京":    "Tokyo, Japan",
+		"伊斯坦布尔": "Istanbul, Turkey",
+	} {
+		list, err := SearchCities(context.Background(), q, 3, "en")
+		if err != nil {
+			t.Errorf("SearchCities(%q) error: %v", q, err)
+			continue
+		}
+		if list[0].DisplayName != want {
+			t.Errorf("SearchCities(%q) = %q, want %q", q, list[0].DisplayName, want)
+		}
+	}
+}
@@ -11,6 +11,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/morezhou/hearth/internal/i18n"
 )
 
 type NextHoliday struct {
@@ -27,12 +29,59 @@ type HolidayItem struct {
 	Date      string `json:"date"` // YYYY-MM-DD
 	Name      string `json:"name"`
 	LocalName string `json:"localName"`
-	DaysUntil int    `json:"daysUntil"`
+	// DisplayName is Name or LocalName, whichever suits the request locale.
+	DisplayName string `json:"displayName,omitempty"`
+	DaysUntil   int    `json:"daysUntil"`
+	// WorkingDaysUntil counts the working days left before the date, taking
+	// the country's days off and make-up workdays into account.
+	WorkingDaysUntil int `json:"workingDaysUntil"`
+	// Kind is set for custom events (see EventKindBirthday...); Years counts
+	// the occurrence when the event's first year is known.
+	Kind  string `json:"kind,omitempty"`
+	Years int    `json:"years,omitempty"`
+}
+
+// localeCountries lists countries whose local holiday names are written in
+// the given language. Multilingual countries (BE, CH, ...) are left out since
+// their local names may be in any of them.
+var localeCountries = map[string]map[string]bool{
+	"zh": {"CN": true, "TW": true, "HK": true, "MO": true},
+	"en": {"US": true, "GB": true, "AU": true, "CA": true, "IE": true, "NZ": true},
+	"de": {"DE": true, "AT": true, "LI": true},
+	"fr": {"FR": true, "MC": true},
+	"es": {"ES": true, "MX": true, "AR": true, "CL": true, "CO": true, "PE": true, "UY": true, "VE": true, "EC": true, "CR": true},
+	"ja": {"JP": true},
+	"it": {"IT": true, "SM": true},
+	"pt": {"PT": true, "BR": true},
+	"nl": {"NL": true},
+	"ko": {"KR": true},
+}
+
+// LocalizeHolidays returns a copy of items with DisplayName set for locale:
+// the local name when the country speaks the locale's language, otherwise the
+// English name.
+func LocalizeHolidays(items []HolidayItem, locale string) []HolidayItem {
+	out := make([]HolidayItem, len(items))
+	for i, it := range items {
+		it.DisplayName = holidayDisplayName(it.Country, it.Name, it.LocalName, locale)
+		out[i] = it
+	}
+	return out
+}
+
+func holidayDisplayName(country, name, localName, locale string) string {
+	countries := localeCountries[i18n.Language(locale)]
+	if countries[strings.ToUpper(country)] && strings.TrimSpace(localName) != "" {
+		return localName
+	}
+	return name
 }
 
 type HolidaysResponse struct {
 	FetchedAt int64         `json:"fetchedAt"`
 	Items     []HolidayItem `json:"items"`
+	// Calendars holds one entry per requested country with holiday data.
+	Calendars []WorkCalendar `json:"calendars,omitempty"`
 }
 
 type HolidayCountry struct {
@@ -44,6 +93,9 @@ type nagerHoliday struct {
 	Date      string `json:"date"`
 	LocalName string `json:"localName"`
 	Name      string `json:"name"`
+	// Workday marks a make-up working day (调休) on a weekend; only holiday-cn
+	// reports them.
+	Workday bool `json:"workday,omitempty"`
 }
 
 var holidaysCache = struct {
@@ -120,6 +172,8 @@ type holidayCandidate struct {
 	Name      string
 	LocalName string
 	Day       time.Time
+	Kind      string
+	Years     int
 }
 
 var chinaOffDaysCache = struct {
@@ -156,7 +210,9 @@ func chinaHolidayEnglishName(local string) string {
 	}
 }
 
-func fetchChinaOffDays(ctx context.Context, year int) ([]nagerHoliday, error) {
+// fetchChinaHolidays returns the days off of a year in China together with the
+// make-up workdays, which are flagged Workday.
+func fetchChinaHolidays(ctx context.Context, year int) ([]nagerHoliday, error) {
 	if year <= 0 {
 		return nil, errors.New("invalid year")
 	}
@@ -174,7 +230,7 @@ func fetchChinaOffDays(ctx context.Context, year int) ([]nagerHoliday, error) {
 	}
 	chinaOffDaysCache.mu.Unlock()
 
-	endpoint := fmt.Sprintf("https://raw.githubusercontent.com/NateScarlet/holiday-cn/master/%d.json", year)
+	endpoint := fmt.Sprintf("%s/%d.json", endpoints().HolidayCN, year)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
 		return nil, err
@@ -199,15 +255,12 @@ func fetchChinaOffDays(ctx context.Context, year int) ([]nagerHoliday, error) {
 
 	out := make([]nagerHoliday, 0, len(payload.Days))
 	for _, d := range payload.Days {
-		if !d.IsOffDay {
-			continue
-		}
 		local := strings.TrimSpace(d.Name)
 		date := strings.TrimSpace(d.Date)
 		if local == "" || date == "" {
 			continue
 		}
-		out = append(out, nagerHoliday{Date: date, LocalName: local, Name: chinaHolidayEnglishName(local)})
+		out = append(out, nagerHoliday{Date: date, LocalName: local, Name: chinaHolidayEnglishName(local), Workday: !d.IsOffDay})
 	}
 	sort.Slice(out, func(i, j int) bool { return out[i].Date < out[j].Date })
 
@@ -221,11 +274,38 @@ func fetchChinaOffDays(ctx context.Context, year int) ([]nagerHoliday, error) {
 	return out, nil
 }
 
+// holidayWorkers bounds concurrent holiday list requests.
+const holidayWorkers = 4
+
+// fetchHolidaysFor fetches the holidays of every country and year at most
+// holidayWorkers at a time. Results and errors are indexed country-major:
+// country i, year j is at i*len(years)+j.
+func fetchHolidaysFor(ctx context.Context, countries []string, years []int) ([][]nagerHoliday, []error) {
+	out := make([][]nagerHoliday, len(countries)*len(years))
+	errs := make([]error, len(out))
+	sem := make(chan struct{}, holidayWorkers)
+	var wg sync.WaitGroup
+	for ci, country := range countries {
+		for yi, year := range years {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				i := ci*len(years) + yi
+				out[i], errs[i] = fetchHolidays(ctx, year, country)
+			}()
+		}
+	}
+	wg.Wait()
+	return out, errs
+}
+
 // UpcomingPublicHolidays returns the next N upcoming public holidays
-// across all provided countries, sorted by date.
-func UpcomingPublicHolidays(ctx context.Context, countryCodes []string, now time.Time, limit int) (HolidaysResponse, error) {
+// across all provided countries, merged with the custom events, sorted by date.
+func UpcomingPublicHolidays(ctx context.Context, countryCodes []string, events []CustomEvent, now time.Time, limit int) (HolidaysResponse, error) {
 	cc := normalizeCountryCodes(countryCodes)
-	if len(cc) == 0 {
+	if len(cc) == 0 && len(events) == 0 {
 		return HolidaysResponse{}, errors.New("countries required")
 	}
 	if limit <= 0 {
@@ -240,20 +320,21 @@ func UpcomingPublicHolidays(ctx context.Context, countryCodes []string, now time
 	years := []int{today.Year(), today.Year() + 1}
 
 	cands := make([]holidayCandidate, 0, 64)
+	calendars := map[string]workCalendar{}
 
-	for _, country := range cc {
-		for _, year := range years {
-			var list []nagerHoliday
-			var err error
-			if country == "CN" {
-				list, err = fetchChinaOffDays(ctx, year)
-			} else {
-				list, err = fetchNagerPublicHolidays(ctx, year, country)
-			}
+	lists, errs := fetchHolidaysFor(ctx, cc, years)
+	for ci, country := range cc {
+		var all []nagerHoliday
+		for yi := range years {
+			list, err := lists[ci*len(years)+yi], errs[ci*len(years)+yi]
 			if err != nil {
 				continue
 			}
+			all = append(all, list...)
 			for _, h := range list {
+				if h.Workday {
+					continue
+				}
 				day, err := parseISODateUTC(h.Date)
 				if err != nil {
 					continue
@@ -264,6 +345,14 @@ func UpcomingPublicHolidays(ctx context.Context, countryCodes []string, now time
 				cands = append(cands, holidayCandidate{Country: country, Date: h.Date, Name: h.Name, LocalName: h.LocalName, Day: day})
 			}
 		}
+		if len(all) > 0 {
+			calendars[country] = newWorkCalendar(all)
+		}
+	}
+	for _, e := range events {
+		if c, ok := e.candidate(today); ok {
+			cands = append(cands, c)
+		}
 	}
 
 	if len(cands) == 0 {
@@ -298,11 +387,14 @@ func UpcomingPublicHolidays(ctx context.Context, countryCodes []string, now time
 			days = 0
 		}
 		out.Items = append(out.Items, HolidayItem{
-			Country:   c.Country,
-			Date:      c.Date,
-			Name:      c.Name,
-			LocalName: c.LocalName,
-			DaysUntil: days,
+			Country:          c.Country,
+			Date:             c.Date,
+			Name:             c.Name,
+			LocalName:        c.LocalName,
+			DaysUntil:        days,
+			WorkingDaysUntil: calendars[c.Country].workingDays(today, c.Day),
+			Kind:             c.Kind,
+			Years:            c.Years,
 		})
 		if len(out.Items) >= limit {
 			break
@@ -312,10 +404,16 @@ func UpcomingPublicHolidays(ctx context.Context, countryCodes []string, now time
 	if len(out.Items) == 0 {
 		return HolidaysResponse{}, errors.New("no upcoming holiday")
 	}
+	for _, country := range cc {
+		if cal, ok := calendars[country]; ok {
+			out.Calendars = append(out.Calendars, cal.summary(country, today))
+		}
+	}
 	return out, nil
 }
 
-// ListHolidayCountries returns available country codes (cached).
+// ListHolidayCountries returns available country codes (cached), or the
+// offline dataset's countries when Nager.Date is unreachable.
 func ListHolidayCountries(ctx context.Context) ([]HolidayCountry, error) {
 	const ttl = 7 * 24 * time.Hour
 
@@ -331,7 +429,7 @@ func ListHolidayCountries(ctx context.Context) ([]HolidayCountry, error) {
 	}
 	holidayCountriesCache.mu.Unlock()
 
-	endpoint := "https://date.nager.at/api/v3/AvailableCountries"
+	endpoint := endpoints().Nager + "/api/v3/AvailableCountries"
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
 		return nil, err
@@ -341,12 +439,11 @@ func ListHolidayCountries(ctx context.Context) ([]HolidayCountry, error) {
 	client := &http.Client{Timeout: 12 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, err
+		return offlineHolidayCountries(), nil
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
-		return nil, fmt.Errorf("nagerdate countries: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(body)))
+		return offlineHolidayCountries(), nil
 	}
 
 	var payload []struct {
@@ -354,7 +451,7 @@ func ListHolidayCountries(ctx context.Context) ([]HolidayCountry, error) {
 		Name        string `json:"name"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
-		return nil, err
+		return offlineHolidayCountries(), nil
 	}
 
 	out := make([]HolidayCountry, 0, len(payload))
@@ -400,19 +497,17 @@ func NextPublicHoliday(ctx context.Context, countryCodes []string, now time.Time
 	}
 	var best *candidate
 
-	for _, country := range cc {
-		for _, year := range years {
-			var list []nagerHoliday
-			var err error
-			if country == "CN" {
-				list, err = fetchChinaOffDays(ctx, year)
-			} else {
-				list, err = fetchNagerPublicHolidays(ctx, year, country)
-			}
+	lists, errs := fetchHolidaysFor(ctx, cc, years)
+	for ci, country := range cc {
+		for yi := range years {
+			list, err := lists[ci*len(years)+yi], errs[ci*len(years)+yi]
 			if err != nil {
 				continue
 			}
 			for _, h := range list {
+				if h.Workday {
+					continue
+				}
 				day, err := parseISODateUTC(h.Date)
 				if err != nil {
 					continue
@@ -469,7 +564,7 @@ func fetchNagerPublicHolidays(ctx context.Context, year int, country string) ([]
 	}
 	holidaysCache.mu.Unlock()
 
-	endpoint := fmt.Sprintf("https://date.nager.at/api/v3/PublicHolidays/%d/%s", year, country)
+	endpoint := fmt.Sprintf("%s/api/v3/PublicHolidays/%d/%s", endpoints().Nager, year, country)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,90 @@
+package widgets
+
+import (
+	"strings"
+	"time"
+)
+
+// Custom event kinds.
+const (
+	EventKindBirthday    = "birthday"
+	EventKindAnniversary = "anniversary"
+	EventKindCustom      = "custom"
+)
+
+// NormalizeEventKind maps unknown kinds to EventKindCustom.
+func NormalizeEventKind(kind string) string {
+	switch k := strings.ToLower(strings.TrimSpace(kind)); k {
+	case EventKindBirthday, EventKindAnniversary:
+		return k
+	default:
+		return EventKindCustom
+	}
+}
+
+// CustomEvent is a personal date that recurs every year, such as a birthday
+// or a wedding anniversary, listed alongside the public holidays.
+type CustomEvent struct {
+	Name  string
+	Kind  string
+	Month int
+	Day   int
+	Year  int  // year of the first occurrence; 0 when unknown
+	Lunar bool // Month and Day are on the Chinese lunar calendar
+}
+
+// ValidEventDate reports whether month/day can recur every year. Solar dates
+// allow Feb 29 (observed on Feb 28 in common years); lunar days go up to 30.
+func ValidEventDate(month, day int, lunar bool) bool {
+	if month < 1 || month > 12 || day < 1 {
+		return false
+	}
+	if lunar {
+		return day <= 30
+	}
+	// 2000 is a leap year, so Feb 29 passes.
+	return day <= time.Date(2000, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// nextOccurrence returns the first occurrence on or after today and the year
+// it falls in (the lunar year for lunar events).
+func (e CustomEvent) nextOccurrence(today time.Time) (time.Time, int, bool) {
+	if !ValidEventDate(e.Month, e.Day, e.Lunar) {
+		return time.Time{}, 0, false
+	}
+	// A late lunar month of last year can still fall in January or February.
+	for y := today.Year() - 1; y <= today.Year()+1; y++ {
+		var d time.Time
+		if e.Lunar {
+			var ok bool
+			if d, ok = LunarToSolar(y, e.Month, e.Day, false); !ok {
+				continue
+			}
+		} else {
+			day := min(e.Day, time.Date(y, time.Month(e.Month)+1, 0, 0, 0, 0, 0, time.UTC).Day())
+			d = time.Date(y, time.Month(e.Month), day, 0, 0, 0, 0, time.UTC)
+		}
+		if !d.Before(today) {
+			return d, y, true
+		}
+	}
+	return time.Time{}, 0, false
+}
+
+func (e CustomEvent) candidate(today time.Time) (holidayCandidate, bool) {
+	day, year, ok := e.nextOccurrence(today)
+	if !ok || strings.TrimSpace(e.Name) == "" {
+		return holidayCandidate{}, false
+	}
+	c := holidayCandidate{
+		Date:      day.Format("2006-01-02"),
+		Name:      e.Name,
+		LocalName: e.Name,
+		Day:       day,
+		Kind:      NormalizeEventKind(e.Kind),
+	}
+	if e.Year > 0 && year > e.Year {
+		c.Years = year - e.Year
+	}
+	return c, true
+}
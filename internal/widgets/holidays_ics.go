@@ -0,0 +1,199 @@
+package widgets
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// HolidayEvent is one calendar entry: a public holiday, possibly spanning
+// several consecutive days off, or a custom event.
+type HolidayEvent struct {
+	Country   string
+	Name      string
+	LocalName string
+	Kind      string
+	Years     int
+	Start     time.Time // UTC midnight
+	End       time.Time // exclusive
+}
+
+// CalendarHolidays returns every holiday and custom event in the year starting
+// today. Consecutive days off sharing a name (e.g. a week-long Chinese
+// holiday) are merged into one event.
+func CalendarHolidays(ctx context.Context, countryCodes []string, events []CustomEvent, now time.Time) ([]HolidayEvent, error) {
+	cc := normalizeCountryCodes(countryCodes)
+	if len(cc) == 0 && len(events) == 0 {
+		return nil, errors.New("countries required")
+	}
+
+	nowUTC := now.UTC()
+	today := time.Date(nowUTC.Year(), nowUTC.Month(), nowUTC.Day(), 0, 0, 0, 0, time.UTC)
+	end := today.AddDate(1, 0, 0)
+
+	out := make([]HolidayEvent, 0, 64)
+	var lastErr error
+	var years []int
+	for year := today.Year(); year <= end.Year(); year++ {
+		years = append(years, year)
+	}
+	lists, errs := fetchHolidaysFor(ctx, cc, years)
+	for ci, country := range cc {
+		var days []HolidayEvent
+		fetched := false
+		for yi := range years {
+			list, err := lists[ci*len(years)+yi], errs[ci*len(years)+yi]
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			fetched = true
+			for _, h := range list {
+				day, err := parseISODateUTC(h.Date)
+				if err != nil || h.Workday || day.Before(today) || !day.Before(end) {
+					continue
+				}
+				days = append(days, HolidayEvent{Country: country, Name: h.Name, LocalName: h.LocalName, Start: day, End: day.AddDate(0, 0, 1)})
+			}
+		}
+		if !fetched {
+			return nil, fmt.Errorf("%s: %w", country, lastErr)
+		}
+		out = append(out, mergeHolidayDays(days)...)
+	}
+	for _, e := range events {
+		c, ok := e.candidate(today)
+		if !ok || !c.Day.Before(end) {
+			continue
+		}
+		out = append(out, HolidayEvent{Name: c.Name, LocalName: c.LocalName, Kind: c.Kind, Years: c.Years, Start: c.Day, End: c.Day.AddDate(0, 0, 1)})
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Start.Equal(out[j].Start) {
+			if out[i].Country == out[j].Country {
+				return out[i].Name < out[j].Name
+			}
+			return out[i].Country < out[j].Country
+		}
+		return out[i].Start.Before(out[j].Start)
+	})
+	return out, nil
+}
+
+// mergeHolidayDays joins single days of one country that share a name and
+// follow each other; duplicate entries for the same day are dropped.
+func mergeHolidayDays(days []HolidayEvent) []HolidayEvent {
+	sort.Slice(days, func(i, j int) bool {
+		if days[i].Name == days[j].Name {
+			return days[i].Start.Before(days[j].Start)
+		}
+		return days[i].Name < days[j].Name
+	})
+	out := make([]HolidayEvent, 0, len(days))
+	for _, d := range days {
+		if n := len(out); n > 0 && out[n-1].Name == d.Name && !d.Start.After(out[n-1].End) {
+			if d.End.After(out[n-1].End) {
+				out[n-1].End = d.End
+			}
+			continue
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// RenderHolidaysICS writes events as an iCalendar (RFC 5545) feed of all-day
+// events, named for locale. now is used as the DTSTAMP.
+func RenderHolidaysICS(events []HolidayEvent, locale string, now time.Time) []byte {
+	countries := map[string]bool{}
+	for _, e := range events {
+		if e.Country != "" {
+			countries[e.Country] = true
+		}
+	}
+	stamp := now.UTC().Format("20060102T150405Z")
+
+	var b bytes.Buffer
+	line := func(s string) {
+		b.WriteString(foldICSLine(s))
+		b.WriteString("\r\n")
+	}
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//Hearth//Holidays//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:Hearth")
+	line("REFRESH-INTERVAL;VALUE=DURATION:PT12H")
+	line("X-PUBLISHED-TTL:PT12H")
+	for _, e := range events {
+		name := holidayDisplayName(e.Country, e.Name, e.LocalName, locale)
+		summary := name
+		if e.Country != "" && len(countries) > 1 {
+			summary += " (" + e.Country + ")"
+		}
+		if e.Years > 0 {
+			summary += fmt.Sprintf(" (%d)", e.Years)
+		}
+		uid := sha1.Sum([]byte(e.Country + "|" + e.Kind + "|" + e.Name + "|" + e.Start.Format("2006-01-02")))
+
+		line("BEGIN:VEVENT")
+		line("UID:" + hex.EncodeToString(uid[:10]) + "@hearth")
+		line("DTSTAMP:" + stamp)
+		line("DTSTART;VALUE=DATE:" + e.Start.Format("20060102"))
+		line("DTEND;VALUE=DATE:" + e.End.Format("20060102"))
+		line("SUMMARY:" + escapeICSText(summary))
+		// The name in the other language, if any.
+		alt := e.LocalName
+		if alt == name {
+			alt = e.Name
+		}
+		if alt != "" && alt != name {
+			line("DESCRIPTION:" + escapeICSText(alt))
+		}
+		if e.Kind != "" {
+			line("CATEGORIES:" + escapeICSText(e.Kind))
+		} else {
+			line("CATEGORIES:holiday")
+		}
+		line("TRANSP:TRANSPARENT")
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return b.Bytes()
+}
+
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escapeICSText(s string) string {
+	return icsEscaper.Replace(s)
+}
+
+// foldICSLine splits lines longer than 75 octets without breaking UTF-8
+// sequences; continuation lines start with a space.
+func foldICSLine(s string) string {
+	const limit = 75
+	if len(s) <= limit {
+		return s
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range s {
+		size := utf8.RuneLen(r)
+		if n+size > limit {
+			b.WriteString("\r\n ")
+			n = 1
+		}
+		b.WriteRune(r)
+		n += size
+	}
+	return b.String()
+}
@@ -0,0 +1,74 @@
+package widgets
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHolidaysICS(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unreachable", http.StatusBadGateway)
+	}))
+	defer up.Close()
+	restore := SetEndpoints(Endpoints{Nager: up.URL, HolidayCN: up.URL})
+	defer restore()
+	ResetCaches()
+	defer ResetCaches()
+
+	now := time.Date(2026, time.September, 20, 9, 0, 0, 0, time.UTC)
+	events := []CustomEvent{{Name: "Wedding, party", Kind: EventKindAnniversary, Month: 9, Day: 20, Year: 2020}}
+	list, err := CalendarHolidays(context.Background(), []string{"CN", "US"}, events, now)
+	if err != nil {
+		t.Fatalf("CalendarHolidays: %v", err)
+	}
+	if len(list) < 4 || list[0].Name != "Wedding, party" || list[0].Years != 6 {
+		t.Fatalf("unexpected events: %+v", list)
+	}
+	if e := list[1]; e.Country != "CN" || e.Name != "Mid-Autumn Festival" || e.Start.Format("2006-01-02") != "2026-09-25" || e.End.Format("2006-01-02") != "2026-09-28" {
+		t.Fatalf("expected merged Mid-Autumn days, got %+v", e)
+	}
+	for _, e := range list {
+		if !e.Start.Before(now.AddDate(1, 0, 0)) {
+			t.Fatalf("event beyond one year: %+v", e)
+		}
+	}
+
+	ics := string(RenderHolidaysICS(list, "zh", now))
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"DTSTART;VALUE=DATE:20261001\r\nDTEND;VALUE=DATE:20261008\r\nSUMMARY:国庆节 (CN)\r\nDESCRIPTION:National Day\r\n",
+		"SUMMARY:Wedding\\, party (6)\r\n",
+		"SUMMARY:Thanksgiving Day (US)\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(ics, want) {
+			t.Fatalf("missing %q in\n%s", want, ics)
+		}
+	}
+	for _, l := range strings.Split(ics, "\r\n") {
+		if len(l) > 75 {
+			t.Fatalf("line longer than 75 octets: %q", l)
+		}
+	}
+
+	if _, err := CalendarHolidays(context.Background(), []string{"AT"}, nil, now); err == nil {
+		t.Fatalf("expected an error when a country cannot be loaded")
+	}
+}
+
+func TestFoldICSLine(t *testing.T) {
+	s := "SUMMARY:" + strings.Repeat("节", 40)
+	folded := foldICSLine(s)
+	for _, l := range strings.Split(folded, "\r\n") {
+		if len(l) > 75 {
+			t.Fatalf("bad fold: %q", l)
+		}
+	}
+	if got := strings.ReplaceAll(folded, "\r\n ", ""); got != s {
+		t.Fatalf("unfolded = %q", got)
+	}
+}
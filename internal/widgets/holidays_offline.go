@@ -0,0 +1,243 @@
+package widgets
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:generate go run ../../cmd/gen-holidays -out data/holidays.json
+
+// bundledHolidays is the offline dataset shipped with the binary, used when
+// date.nager.at or holiday-cn cannot be reached.
+//
+//go:embed data/holidays.json
+var bundledHolidays []byte
+
+// DatasetCountries are the countries included in the bundled dataset.
+var DatasetCountries = []string{"CN", "DE", "FR", "GB", "JP", "US"}
+
+// DatasetHoliday is one day off in a HolidayDataset.
+type DatasetHoliday struct {
+	Date      string `json:"date"` // YYYY-MM-DD
+	LocalName string `json:"localName"`
+	Name      string `json:"name"`
+	Workday   bool   `json:"workday,omitempty"` // make-up working day
+}
+
+// HolidayDataset is an offline copy of public holidays, keyed by country code
+// and then by year.
+type HolidayDataset struct {
+	GeneratedAt string                                 `json:"generatedAt"`
+	Countries   []HolidayCountry                       `json:"countries"`
+	Holidays    map[string]map[string][]DatasetHoliday `json:"holidays"`
+}
+
+// HolidayDatasetInfo summarizes a dataset for the admin API.
+type HolidayDatasetInfo struct {
+	Source      string         `json:"source"` // "bundled" or "override"
+	GeneratedAt string         `json:"generatedAt"`
+	Countries   map[string]int `json:"countries"` // code -> number of years
+	Years       []int          `json:"years"`
+}
+
+var holidayDataset = struct {
+	mu       sync.Mutex
+	bundled  *HolidayDataset
+	override *HolidayDataset
+}{}
+
+// ParseHolidayDataset decodes and validates a dataset.
+func ParseHolidayDataset(b []byte) (*HolidayDataset, error) {
+	var ds HolidayDataset
+	if err := json.Unmarshal(b, &ds); err != nil {
+		return nil, err
+	}
+	if len(ds.Holidays) == 0 {
+		return nil, errors.New("holidays dataset: no holidays")
+	}
+	norm := make(map[string]map[string][]DatasetHoliday, len(ds.Holidays))
+	for country, years := range ds.Holidays {
+		cc := normalizeCountryCodes([]string{country})
+		if len(cc) == 0 {
+			return nil, fmt.Errorf("holidays dataset: invalid country %q", country)
+		}
+		norm[cc[0]] = map[string][]DatasetHoliday{}
+		for year, days := range years {
+			y, err := strconv.Atoi(year)
+			if err != nil || y < 1900 || y > 2999 {
+				return nil, fmt.Errorf("holidays dataset: invalid year %q", year)
+			}
+			for _, d := range days {
+				day, err := parseISODateUTC(d.Date)
+				if err != nil || day.Year() != y {
+					return nil, fmt.Errorf("holidays dataset: invalid date %q for %s/%s", d.Date, cc[0], year)
+				}
+			}
+			list := append([]DatasetHoliday(nil), days...)
+			sort.Slice(list, func(i, j int) bool { return list[i].Date < list[j].Date })
+			norm[cc[0]][year] = list
+		}
+	}
+	ds.Holidays = norm
+	return &ds, nil
+}
+
+// SetHolidayDataset replaces the bundled dataset with ds; nil restores it.
+func SetHolidayDataset(ds *HolidayDataset) {
+	holidayDataset.mu.Lock()
+	holidayDataset.override = ds
+	holidayDataset.mu.Unlock()
+}
+
+func activeHolidayDataset() (*HolidayDataset, string) {
+	holidayDataset.mu.Lock()
+	defer holidayDataset.mu.Unlock()
+	if holidayDataset.override != nil {
+		return holidayDataset.override, "override"
+	}
+	if holidayDataset.bundled == nil {
+		ds, err := ParseHolidayDataset(bundledHolidays)
+		if err != nil {
+			// The embedded file is generated and checked by tests.
+			panic(err)
+		}
+		holidayDataset.bundled = ds
+	}
+	return holidayDataset.bundled, "bundled"
+}
+
+// HolidayDatasetSummary describes the dataset currently used as fallback.
+func HolidayDatasetSummary() HolidayDatasetInfo {
+	ds, source := activeHolidayDataset()
+	info := HolidayDatasetInfo{Source: source, GeneratedAt: ds.GeneratedAt, Countries: map[string]int{}}
+	seen := map[int]bool{}
+	for country, years := range ds.Holidays {
+		info.Countries[country] = len(years)
+		for y := range years {
+			if n, err := strconv.Atoi(y); err == nil && !seen[n] {
+				seen[n] = true
+				info.Years = append(info.Years, n)
+			}
+		}
+	}
+	sort.Ints(info.Years)
+	return info
+}
+
+// offlineHolidays returns the dataset entries for one country and year.
+func offlineHolidays(country string, year int) ([]nagerHoliday, bool) {
+	ds, _ := activeHolidayDataset()
+	days, ok := ds.Holidays[country][strconv.Itoa(year)]
+	if !ok {
+		return nil, false
+	}
+	out := make([]nagerHoliday, len(days))
+	for i, d := range days {
+		out[i] = nagerHoliday(d)
+	}
+	return out, true
+}
+
+// offlineHolidayCountries returns the dataset's country list.
+func offlineHolidayCountries() []HolidayCountry {
+	ds, _ := activeHolidayDataset()
+	out := make([]HolidayCountry, 0, len(ds.Countries))
+	for _, c := range ds.Countries {
+		if _, ok := ds.Holidays[strings.ToUpper(c.Code)]; ok {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// fetchHolidays loads one country's holidays for a year from the live source,
+// falling back to the offline dataset when it fails.
+func fetchHolidays(ctx context.Context, year int, country string) ([]nagerHoliday, error) {
+	var list []nagerHoliday
+	var err error
+	if country == "CN" {
+		list, err = fetchChinaHolidays(ctx, year)
+	} else {
+		list, err = fetchNagerPublicHolidays(ctx, year, country)
+	}
+	if err == nil {
+		return list, nil
+	}
+	if offline, ok := offlineHolidays(country, year); ok {
+		return offline, nil
+	}
+	return nil, err
+}
+
+// BuildHolidayDataset fetches the given countries and years from the live
+// sources. Entries that fail keep their current offline data and are reported
+// in the error without aborting the build; the dataset is nil when nothing
+// could be fetched at all.
+func BuildHolidayDataset(ctx context.Context, countries []string, years []int) (*HolidayDataset, error) {
+	cc := normalizeCountryCodes(countries)
+	if len(cc) == 0 || len(years) == 0 {
+		return nil, errors.New("countries and years required")
+	}
+	names := map[string]string{}
+	if list, err := ListHolidayCountries(ctx); err == nil {
+		for _, c := range list {
+			names[c.Code] = c.Name
+		}
+	}
+	ds := &HolidayDataset{
+		GeneratedAt: time.Now().UTC().Format("2006-01-02"),
+		Holidays:    map[string]map[string][]DatasetHoliday{},
+	}
+	var errs []error
+	fetched := 0
+	for _, country := range cc {
+		for _, year := range years {
+			var list []nagerHoliday
+			var err error
+			if country == "CN" {
+				list, err = fetchChinaHolidays(ctx, year)
+			} else {
+				list, err = fetchNagerPublicHolidays(ctx, year, country)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s/%d: %w", country, year, err))
+				var ok bool
+				if list, ok = offlineHolidays(country, year); !ok {
+					continue
+				}
+			} else {
+				fetched++
+			}
+			if len(list) == 0 {
+				continue
+			}
+			if ds.Holidays[country] == nil {
+				ds.Holidays[country] = map[string][]DatasetHoliday{}
+			}
+			days := make([]DatasetHoliday, len(list))
+			for i, h := range list {
+				days[i] = DatasetHoliday(h)
+			}
+			ds.Holidays[country][strconv.Itoa(year)] = days
+		}
+		if _, ok := ds.Holidays[country]; ok {
+			name := names[country]
+			if name == "" {
+				name = country
+			}
+			ds.Countries = append(ds.Countries, HolidayCountry{Code: country, Name: name})
+		}
+	}
+	if fetched == 0 {
+		return nil, errors.Join(errs...)
+	}
+	return ds, errors.Join(errs...)
+}
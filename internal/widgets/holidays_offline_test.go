@@ -0,0 +1,71 @@
+package widgets
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBundledHolidayDataset(t *testing.T) {
+	ds, err := ParseHolidayDataset(bundledHolidays)
+	if err != nil {
+		t.Fatalf("ParseHolidayDataset: %v", err)
+	}
+	for _, c := range DatasetCountries {
+		if len(ds.Holidays[c]["2026"]) == 0 {
+			t.Errorf("bundled dataset has no 2026 holidays for %s", c)
+		}
+	}
+	if len(ds.Countries) != len(DatasetCountries) {
+		t.Errorf("countries = %+v", ds.Countries)
+	}
+
+	if _, err := ParseHolidayDataset([]byte(`{"holidays":{"US":{"2026":[{"date":"2027-01-01","name":"x"}]}}}`)); err == nil {
+		t.Errorf("expected a date outside its year to be rejected")
+	}
+	if _, err := ParseHolidayDataset([]byte(`{"holidays":{}}`)); err == nil {
+		t.Errorf("expected an empty dataset to be rejected")
+	}
+}
+
+func TestHolidaysOfflineFallback(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unreachable", http.StatusBadGateway)
+	}))
+	defer up.Close()
+	restore := SetEndpoints(Endpoints{Nager: up.URL, HolidayCN: up.URL})
+	defer restore()
+	ResetCaches()
+	defer ResetCaches()
+
+	now := time.Date(2026, time.September, 20, 9, 0, 0, 0, time.UTC)
+	res, err := UpcomingPublicHolidays(context.Background(), []string{"US", "CN"}, nil, now, 3)
+	if err != nil {
+		t.Fatalf("UpcomingPublicHolidays: %v", err)
+	}
+	want := []string{"CN 2026-09-25", "CN 2026-10-01", "US 2026-10-12"}
+	for i, it := range res.Items {
+		if got := it.Country + " " + it.Date; i >= len(want) || got != want[i] {
+			t.Fatalf("items = %+v, want %v", res.Items, want)
+		}
+	}
+
+	countries, err := ListHolidayCountries(context.Background())
+	if err != nil || len(countries) != len(DatasetCountries) {
+		t.Fatalf("ListHolidayCountries = %+v, %v", countries, err)
+	}
+
+	SetHolidayDataset(&HolidayDataset{Holidays: map[string]map[string][]DatasetHoliday{
+		"US": {"2026": {{Date: "2026-09-21", LocalName: "Test Day", Name: "Test Day"}}},
+	}})
+	defer SetHolidayDataset(nil)
+	next, err := NextPublicHoliday(context.Background(), []string{"US"}, now)
+	if err != nil || next.Date != "2026-09-21" || next.DaysUntil != 1 {
+		t.Fatalf("NextPublicHoliday = %+v, %v", next, err)
+	}
+	if _, err := NextPublicHoliday(context.Background(), []string{"CN"}, now); err == nil {
+		t.Fatalf("expected no CN holidays outside the override")
+	}
+}
@@ -0,0 +1,193 @@
+package widgets
+
+import "time"
+
+// Working-day hints look this far ahead.
+const (
+	longWeekendHorizon = 365 * 24 * time.Hour
+	bridgeDayHorizon   = 90 * 24 * time.Hour
+	maxBridgeDays      = 3
+	maxMakeupWorkdays  = 3
+)
+
+// WorkCalendar summarizes a country's working days around its coming holidays.
+type WorkCalendar struct {
+	Country string `json:"country"`
+	// NextLongWeekend is the next span of three or more days off that
+	// includes a public holiday.
+	NextLongWeekend *LongWeekend `json:"nextLongWeekend,omitempty"`
+	// BridgeDays are long weekends within 90 days that need one day of leave.
+	BridgeDays []LongWeekend `json:"bridgeDays,omitempty"`
+	// MakeupWorkdays are weekend days that are working days (调休).
+	MakeupWorkdays []MakeupWorkday `json:"makeupWorkdays,omitempty"`
+}
+
+// LongWeekend is a run of consecutive days off, Start to End inclusive.
+type LongWeekend struct {
+	Start       string `json:"start"` // YYYY-MM-DD
+	End         string `json:"end"`
+	Days        int    `json:"days"`
+	Name        string `json:"name"`
+	LocalName   string `json:"localName"`
+	DisplayName string `json:"displayName,omitempty"`
+	// TakeOff lists the working days to take as leave (bridge days).
+	TakeOff []string `json:"takeOff,omitempty"`
+}
+
+// MakeupWorkday is a weekend day worked in exchange for a holiday.
+type MakeupWorkday struct {
+	Date        string `json:"date"` // YYYY-MM-DD
+	Name        string `json:"name"`
+	LocalName   string `json:"localName"`
+	DisplayName string `json:"displayName,omitempty"`
+	DaysUntil   int    `json:"daysUntil"`
+}
+
+// LocalizeWorkCalendars returns a copy of cals with DisplayName set for locale,
+// as LocalizeHolidays does for items.
+func LocalizeWorkCalendars(cals []WorkCalendar, locale string) []WorkCalendar {
+	out := make([]WorkCalendar, len(cals))
+	for i, c := range cals {
+		if c.NextLongWeekend != nil {
+			lw := *c.NextLongWeekend
+			lw.DisplayName = holidayDisplayName(c.Country, lw.Name, lw.LocalName, locale)
+			c.NextLongWeekend = &lw
+		}
+		bridges := make([]LongWeekend, len(c.BridgeDays))
+		for j, b := range c.BridgeDays {
+			b.DisplayName = holidayDisplayName(c.Country, b.Name, b.LocalName, locale)
+			bridges[j] = b
+		}
+		c.BridgeDays = bridges
+		makeups := make([]MakeupWorkday, len(c.MakeupWorkdays))
+		for j, m := range c.MakeupWorkdays {
+			m.DisplayName = holidayDisplayName(c.Country, m.Name, m.LocalName, locale)
+			makeups[j] = m
+		}
+		c.MakeupWorkdays = makeups
+		out[i] = c
+	}
+	return out
+}
+
+// workCalendar knows a country's days off and make-up workdays; other days
+// are working days from Monday to Friday. The zero value only knows weekends.
+type workCalendar struct {
+	off    map[string]nagerHoliday
+	makeup map[string]nagerHoliday
+}
+
+func newWorkCalendar(list []nagerHoliday) workCalendar {
+	c := workCalendar{off: map[string]nagerHoliday{}, makeup: map[string]nagerHoliday{}}
+	for _, h := range list {
+		if h.Workday {
+			c.makeup[h.Date] = h
+		} else if _, dup := c.off[h.Date]; !dup {
+			c.off[h.Date] = h
+		}
+	}
+	return c
+}
+
+func (c workCalendar) isOff(day time.Time) bool {
+	key := day.Format("2006-01-02")
+	if _, ok := c.makeup[key]; ok {
+		return false
+	}
+	if _, ok := c.off[key]; ok {
+		return true
+	}
+	return day.Weekday() == time.Saturday || day.Weekday() == time.Sunday
+}
+
+// workingDays counts the working days in [from, to).
+func (c workCalendar) workingDays(from, to time.Time) int {
+	n := 0
+	for d := from; d.Before(to); d = d.AddDate(0, 0, 1) {
+		if !c.isOff(d) {
+			n++
+		}
+	}
+	return n
+}
+
+// offRun is a run of consecutive days off, start to end inclusive.
+type offRun struct {
+	start, end time.Time
+	holiday    *nagerHoliday // first public holiday in the run
+}
+
+func (r offRun) days() int { return int(r.end.Sub(r.start).Hours()/24) + 1 }
+
+// offRuns splits [from, to) into runs of days off.
+func (c workCalendar) offRuns(from, to time.Time) []offRun {
+	var runs []offRun
+	var cur *offRun
+	for d := from; d.Before(to); d = d.AddDate(0, 0, 1) {
+		if !c.isOff(d) {
+			cur = nil
+			continue
+		}
+		if cur == nil {
+			runs = append(runs, offRun{start: d, end: d})
+			cur = &runs[len(runs)-1]
+		}
+		cur.end = d
+		if h, ok := c.off[d.Format("2006-01-02")]; ok && cur.holiday == nil {
+			cur.holiday = &h
+		}
+	}
+	return runs
+}
+
+func (r offRun) longWeekend(days int, takeOff []string) LongWeekend {
+	return LongWeekend{
+		Start:     r.start.Format("2006-01-02"),
+		End:       r.end.Format("2006-01-02"),
+		Days:      days,
+		Name:      r.holiday.Name,
+		LocalName: r.holiday.LocalName,
+		TakeOff:   takeOff,
+	}
+}
+
+// summary computes the working-day hints from today on.
+func (c workCalendar) summary(country string, today time.Time) WorkCalendar {
+	out := WorkCalendar{Country: country}
+	runs := c.offRuns(today, today.Add(longWeekendHorizon))
+	for i, r := range runs {
+		if r.holiday != nil && r.days() >= 3 && out.NextLongWeekend == nil {
+			lw := r.longWeekend(r.days(), nil)
+			out.NextLongWeekend = &lw
+		}
+		// A single working day between two runs, one of them a holiday.
+		if i+1 >= len(runs) || len(out.BridgeDays) >= maxBridgeDays {
+			continue
+		}
+		next := runs[i+1]
+		bridge := r.end.AddDate(0, 0, 1)
+		if !next.start.Equal(bridge.AddDate(0, 0, 1)) || bridge.Sub(today) >= bridgeDayHorizon {
+			continue
+		}
+		joined := offRun{start: r.start, end: next.end, holiday: r.holiday}
+		if joined.holiday == nil {
+			joined.holiday = next.holiday
+		}
+		if joined.holiday == nil {
+			continue
+		}
+		out.BridgeDays = append(out.BridgeDays, joined.longWeekend(joined.days(), []string{bridge.Format("2006-01-02")}))
+	}
+
+	for d := today; d.Before(today.Add(longWeekendHorizon)) && len(out.MakeupWorkdays) < maxMakeupWorkdays; d = d.AddDate(0, 0, 1) {
+		if h, ok := c.makeup[d.Format("2006-01-02")]; ok {
+			out.MakeupWorkdays = append(out.MakeupWorkdays, MakeupWorkday{
+				Date:      h.Date,
+				Name:      h.Name,
+				LocalName: h.LocalName,
+				DaysUntil: int(d.Sub(today).Hours() / 24),
+			})
+		}
+	}
+	return out
+}
@@ -0,0 +1,58 @@
+package widgets
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHolidayWorkCalendars(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unreachable", http.StatusBadGateway)
+	}))
+	defer up.Close()
+	restore := SetEndpoints(Endpoints{Nager: up.URL, HolidayCN: up.URL})
+	defer restore()
+	ResetCaches()
+	defer ResetCaches()
+
+	// A Sunday that is a make-up workday in China.
+	now := time.Date(2026, time.September, 20, 9, 0, 0, 0, time.UTC)
+	res, err := UpcomingPublicHolidays(context.Background(), []string{"CN", "US"}, nil, now, 3)
+	if err != nil {
+		t.Fatalf("UpcomingPublicHolidays: %v", err)
+	}
+	if it := res.Items[0]; it.Country != "CN" || it.Date != "2026-09-25" || it.WorkingDaysUntil != 5 {
+		t.Fatalf("unexpected CN item: %+v", it)
+	}
+	if it := res.Items[2]; it.Country != "US" || it.Date != "2026-10-12" || it.WorkingDaysUntil != 15 {
+		t.Fatalf("unexpected US item: %+v", it)
+	}
+
+	if len(res.Calendars) != 2 {
+		t.Fatalf("expected two calendars, got %+v", res.Calendars)
+	}
+	cn, us := res.Calendars[0], res.Calendars[1]
+	if lw := cn.NextLongWeekend; lw == nil || lw.Start != "2026-09-25" || lw.End != "2026-09-27" || lw.Days != 3 || lw.LocalName != "中秋节" {
+		t.Fatalf("unexpected CN long weekend: %+v", lw)
+	}
+	if len(cn.MakeupWorkdays) != 2 || cn.MakeupWorkdays[0].Date != "2026-09-20" || cn.MakeupWorkdays[1].DaysUntil != 20 {
+		t.Fatalf("unexpected CN make-up workdays: %+v", cn.MakeupWorkdays)
+	}
+	if lw := us.NextLongWeekend; lw == nil || lw.Start != "2026-10-10" || lw.Days != 3 || lw.Name != "Columbus Day" {
+		t.Fatalf("unexpected US long weekend: %+v", lw)
+	}
+	if len(us.BridgeDays) != 1 || us.BridgeDays[0].Start != "2026-11-26" || us.BridgeDays[0].Days != 4 || us.BridgeDays[0].TakeOff[0] != "2026-11-27" {
+		t.Fatalf("unexpected US bridge days: %+v", us.BridgeDays)
+	}
+	if len(us.MakeupWorkdays) != 0 {
+		t.Fatalf("unexpected US make-up workdays: %+v", us.MakeupWorkdays)
+	}
+
+	loc := LocalizeWorkCalendars(res.Calendars, "zh")
+	if loc[0].NextLongWeekend.DisplayName != "中秋节" || loc[1].BridgeDays[0].DisplayName != "Thanksgiving Day" {
+		t.Fatalf("unexpected localized calendars: %+v", loc)
+	}
+}
@@ -0,0 +1,81 @@
+package widgets
+
+import "time"
+
+// lunarInfo encodes the Chinese lunisolar calendar for lunar years
+// lunarFirstYear.. one entry per year:
+//   - bits 0-3:  leap month (0 = none)
+//   - bits 4-15: month sizes, bit 0x10000>>m set when month m has 30 days
+//   - bit 16:    leap month has 30 days
+var lunarInfo = [...]int{
+	0x0c960, 0x0d954, 0x0d4a0, 0x0da50, 0x07552, 0x056a0, 0x0abb7, 0x025d0, 0x092d0, 0x0cab5, // 2000-2009
+	0x0a950, 0x0b4a0, 0x0baa4, 0x0ad50, 0x055d9, 0x04ba0, 0x0a5b0, 0x15176, 0x052b0, 0x0a930, // 2010-2019
+	0x07954, 0x06aa0, 0x0ad50, 0x05b52, 0x04b60, 0x0a6e6, 0x0a4e0, 0x0d260, 0x0ea65, 0x0d530, // 2020-2029
+	0x05aa0, 0x076a3, 0x096d0, 0x04afb, 0x04ad0, 0x0a4d0, 0x1d0b6, 0x0d250, 0x0d520, 0x0dd45, // 2030-2039
+	0x0b5a0, 0x056d0, 0x055b2, 0x049b0, 0x0a577, 0x0a4b0, 0x0aa50, 0x1b255, 0x06d20, 0x0ada0, // 2040-2049
+}
+
+// lunarFirstYear's first day (正月初一) fell on 2000-02-05.
+const lunarFirstYear = 2000
+
+var lunarEpoch = time.Date(2000, time.February, 5, 0, 0, 0, 0, time.UTC)
+
+func lunarLeapMonth(year int) int {
+	return lunarInfo[year-lunarFirstYear] & 0xf
+}
+
+func lunarLeapDays(year int) int {
+	if lunarLeapMonth(year) == 0 {
+		return 0
+	}
+	if lunarInfo[year-lunarFirstYear]&0x10000 != 0 {
+		return 30
+	}
+	return 29
+}
+
+func lunarMonthDays(year, month int) int {
+	if lunarInfo[year-lunarFirstYear]&(0x10000>>month) != 0 {
+		return 30
+	}
+	return 29
+}
+
+func lunarYearDays(year int) int {
+	days := lunarLeapDays(year)
+	for m := 1; m <= 12; m++ {
+		days += lunarMonthDays(year, m)
+	}
+	return days
+}
+
+// LunarToSolar converts a date of the Chinese calendar to the Gregorian date
+// (UTC midnight). Day 30 of a 29-day month is moved to day 29, the usual
+// convention for birthdays. ok is false outside the supported years or for an
+// invalid month/day.
+func LunarToSolar(year, month, day int, leap bool) (time.Time, bool) {
+	if year < lunarFirstYear || year >= lunarFirstYear+len(lunarInfo) || month < 1 || month > 12 || day < 1 || day > 30 {
+		return time.Time{}, false
+	}
+	if leap && lunarLeapMonth(year) != month {
+		return time.Time{}, false
+	}
+	offset := 0
+	for y := lunarFirstYear; y < year; y++ {
+		offset += lunarYearDays(y)
+	}
+	leapMonth := lunarLeapMonth(year)
+	for m := 1; m < month; m++ {
+		offset += lunarMonthDays(year, m)
+		if m == leapMonth {
+			offset += lunarLeapDays(year)
+		}
+	}
+	size := lunarMonthDays(year, month)
+	if leap {
+		offset += size
+		size = lunarLeapDays(year)
+	}
+	offset += min(day, size) - 1
+	return lunarEpoch.AddDate(0, 0, offset), true
+}
@@ -0,0 +1,60 @@
+package widgets
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLunarToSolar(t *testing.T) {
+	cases := []struct {
+		year, month, day int
+		leap             bool
+		want             string
+	}{
+		{2000, 1, 1, false, "2000-02-05"},
+		{2024, 1, 1, false, "2024-02-10"},
+		{2025, 1, 1, false, "2025-01-29"},
+		{2026, 1, 1, false, "2026-02-17"},
+		{2049, 1, 1, false, "2049-02-02"},
+		{2023, 2, 1, true, "2023-03-22"},   // leap 2nd month
+		{2025, 6, 1, true, "2025-07-25"},   // leap 6th month
+		{2025, 8, 15, false, "2025-10-06"}, // Mid-Autumn after the leap month
+		{2024, 5, 5, false, "2024-06-10"},  // Dragon Boat
+	}
+	for _, c := range cases {
+		d, ok := LunarToSolar(c.year, c.month, c.day, c.leap)
+		if !ok || d.Format("2006-01-02") != c.want {
+			t.Errorf("LunarToSolar(%d, %d, %d, %v) = %s, want %s", c.year, c.month, c.day, c.leap, d.Format("2006-01-02"), c.want)
+		}
+	}
+	if _, ok := LunarToSolar(2024, 2, 1, true); ok {
+		t.Errorf("2024 has no leap 2nd month")
+	}
+	if _, ok := LunarToSolar(1999, 1, 1, false); ok {
+		t.Errorf("years before the table must be rejected")
+	}
+}
+
+func TestCustomEventNextOccurrence(t *testing.T) {
+	today := time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		ev    CustomEvent
+		want  string
+		years int
+	}{
+		{CustomEvent{Name: "Wedding", Month: 10, Day: 16, Year: 2016}, "2026-10-16", 10},
+		{CustomEvent{Name: "Leap day", Month: 2, Day: 29}, "2027-02-28", 0},
+		{CustomEvent{Name: "Mid-Autumn", Month: 8, Day: 15, Lunar: true}, "2027-09-15", 0},
+		// Lunar 12/20 of 2026 falls in January 2027.
+		{CustomEvent{Name: "Grandma", Month: 12, Day: 20, Lunar: true, Year: 1950}, "2027-01-27", 76},
+	}
+	for _, c := range cases {
+		got, ok := c.ev.candidate(today)
+		if !ok || got.Date != c.want || got.Years != c.years {
+			t.Errorf("%s: got %s (years %d), want %s (years %d)", c.ev.Name, got.Date, got.Years, c.want, c.years)
+		}
+	}
+	if _, ok := (CustomEvent{Name: "Bad", Month: 4, Day: 31}).candidate(today); ok {
+		t.Errorf("April 31 must be rejected")
+	}
+}
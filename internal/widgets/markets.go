@@ -9,6 +9,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -17,21 +18,86 @@ import (
 
 type MarketQuote struct {
 	Symbol       string    `json:"symbol"`
-	Kind         string    `json:"kind"` // "stock" | "crypto"
+	Kind         string    `json:"kind"` // "stock" | "crypto" | "index" | "fx"
 	Name         string    `json:"name,omitempty"`
 	PriceUSD     float64   `json:"priceUsd"`
+	Price        float64   `json:"price"`    // in Currency; see ConvertMarkets
+	Currency     string    `json:"currency"` // ISO 4217 code (or crypto quote asset) of Price and Series
 	ChangePct24h float64   `json:"changePct24h"`
 	Series       []float64 `json:"series"`
+	// QuoteAsset is the asset a crypto quote is priced in ("USDT", "BTC",
+	// "EUR"...). Unless it is a USD stablecoin, PriceUSD holds the price in
+	// that asset, as it does for FX rates.
+	QuoteAsset string `json:"quoteAsset,omitempty"`
+	// RangeChangePct is the change from the first to the last Series point.
+	RangeChangePct float64 `json:"rangeChangePct,omitempty"`
+	Source         string  `json:"source,omitempty"` // upstream that produced the quote
+	// Session is the trading session of the symbol's exchange (stocks,
+	// indices and FX only).
+	Session *MarketSession `json:"session,omitempty"`
+}
+
+// Chart ranges accepted by FetchMarkets. The empty range keeps the original
+// widget series: 24 hourly points for crypto, 30 daily closes for stocks.
+const (
+	MarketRange1D = "1d"
+	MarketRange7D = "7d"
+	MarketRange1M = "1m"
+	MarketRange3M = "3m"
+	MarketRange1Y = "1y"
+)
+
+// marketRangeSpec says how each source builds the series for a range.
+type marketRangeSpec struct {
+	binanceInterval string // kline interval
+	binanceLimit    int
+	stockCloses     int // Stooq has no intraday data; daily closes only
+	coinGeckoPoints int // tail of the hourly 7d sparkline (0 = all of it)
+	yahooRange      string
+	yahooInterval   string
+	twelveInterval  string
+	twelvePoints    int
+}
+
+var marketRanges = map[string]marketRangeSpec{
+	"": {binanceInterval: "1h", binanceLimit: 24, stockCloses: 30, coinGeckoPoints: 24,
+		yahooRange: "3mo", yahooInterval: "1d", twelveInterval: "1day", twelvePoints: 30},
+	MarketRange1D: {binanceInterval: "1h", binanceLimit: 24, stockCloses: 2, coinGeckoPoints: 24,
+		yahooRange: "1d", yahooInterval: "5m", twelveInterval: "5min", twelvePoints: 78},
+	MarketRange7D: {binanceInterval: "2h", binanceLimit: 84, stockCloses: 5,
+		yahooRange: "5d", yahooInterval: "30m", twelveInterval: "30min", twelvePoints: 65},
+	MarketRange1M: {binanceInterval: "12h", binanceLimit: 60, stockCloses: 22,
+		yahooRange: "1mo", yahooInterval: "1d", twelveInterval: "1day", twelvePoints: 22},
+	MarketRange3M: {binanceInterval: "1d", binanceLimit: 90, stockCloses: 66,
+		yahooRange: "3mo", yahooInterval: "1d", twelveInterval: "1day", twelvePoints: 66},
+	MarketRange1Y: {binanceInterval: "1w", binanceLimit: 52, stockCloses: 252,
+		yahooRange: "1y", yahooInterval: "1wk", twelveInterval: "1week", twelvePoints: 52},
+}
+
+// maxSeriesPoints caps sparkline length; longer series are thinned evenly.
+const maxSeriesPoints = 90
+
+// NormalizeMarketRange lower-cases r and reports whether it is a known range.
+func NormalizeMarketRange(r string) (string, bool) {
+	r = strings.ToLower(strings.TrimSpace(r))
+	_, ok := marketRanges[r]
+	return r, ok
 }
 
 type MarketsResponse struct {
 	FetchedAt int64         `json:"fetchedAt"`
+	Range     string        `json:"range,omitempty"` // chart range of every Series
+	Currency  string        `json:"currency,omitempty"`
 	Items     []MarketQuote `json:"items"`
+	// IsStale is set when upstreams failed and an older cached response is
+	// served instead; CacheAgeSec is then its age at the time of the request.
+	IsStale     bool  `json:"isStale,omitempty"`
+	CacheAgeSec int64 `json:"cacheAgeSec,omitempty"`
 }
 
 type MarketSymbol struct {
 	Symbol string `json:"symbol"`
-	Kind   string `json:"kind"` // "stock" | "crypto"
+	Kind   string `json:"kind"` // "stock" | "crypto" | "index" | "fx"
 	Name   string `json:"name"`
 }
 
@@ -98,8 +164,23 @@ func normalizeSymbols(in []string) []string {
 	return out
 }
 
-func marketsCacheKey(symbols []string) string {
-	return strings.Join(symbols, "|")
+// dedupeSymbols upper-cases symbols and drops blanks and duplicates.
+func dedupeSymbols(in []string) []string {
+	out := make([]string, 0, len(in))
+	seen := map[string]bool{}
+	for _, raw := range in {
+		s := strings.ToUpper(strings.TrimSpace(raw))
+		if s == "" || seen[s] {
+			continue
+		}
+		seen[s] = true
+		out = append(out, s)
+	}
+	return out
+}
+
+func marketsCacheKey(symbols []string, rng string) string {
+	return rng + "#" + strings.Join(symbols, "|")
 }
 
 var popularCryptoSymbols = map[string]bool{
@@ -160,7 +241,7 @@ func isCryptoSymbol(symUpper string) bool {
 	if popularCryptoSymbols[s] {
 		return true
 	}
-	return false
+	return isCryptoPair(s)
 }
 
 func stripCryptoPrefix(symUpper string) string {
@@ -175,32 +256,84 @@ func stripCryptoPrefix(symUpper string) string {
 }
 
 // FetchMarkets aggregates free data sources:
-// - Crypto: Binance public endpoints (USDT quoted; treated as USD)
+// - Crypto: Binance public endpoints (USDT quoted by default; see MarketOptions.CryptoQuote)
 // - Stocks: Stooq (USD)
 // Results are cached for ~5 minutes.
-func FetchMarkets(ctx context.Context, symbols []string) (MarketsResponse, error) {
-	symbols = normalizeSymbols(symbols)
+// Responses are cached per chart range and stock routing.
+func FetchMarkets(ctx context.Context, symbols []string, opts MarketOptions) (MarketsResponse, error) {
 	// Always 4.
+	return FetchQuotes(ctx, normalizeSymbols(symbols), opts)
+}
+
+// MarketOptions tunes FetchMarkets and FetchQuotes.
+type MarketOptions struct {
+	Range       string        // chart range of each Series (see MarketRange1D...)
+	Stocks      StockRouter   // stock provider per symbol; zero value uses Stooq
+	TTL         time.Duration // how long quotes stay fresh; zero uses DefaultMarketsTTL
+	CryptoQuote string        // default quote asset of crypto symbols; "" is USDT
+}
+
+// Bounds of the per-widget refresh interval.
+const (
+	DefaultMarketsTTL = 5 * time.Minute
+	MinMarketsTTL     = time.Minute
+	MaxMarketsTTL     = time.Hour
+)
 
-	const ttl = 5 * time.Minute
-	key := marketsCacheKey(symbols)
-	marketsCache.mu.Lock()
-	if cached, ok := marketsCache.items[key]; ok {
+// NormalizeMarketsTTL clamps a refresh interval into [MinMarketsTTL, MaxMarketsTTL];
+// zero or negative values select DefaultMarketsTTL.
+func NormalizeMarketsTTL(d time.Duration) time.Duration {
+	if d <= 0 {
+		return DefaultMarketsTTL
+	}
+	return min(max(d, MinMarketsTTL), MaxMarketsTTL)
+}
+
+// FetchQuotes is FetchMarkets without the widget's fixed four-symbol layout:
+// it quotes exactly the given symbols, in order, sharing the same cache.
+// Session metadata is evaluated per call, so it stays current for cached quotes.
+func FetchQuotes(ctx context.Context, symbols []string, opts MarketOptions) (MarketsResponse, error) {
+	res, err := fetchQuotes(ctx, symbols, opts)
+	if err != nil {
+		return res, err
+	}
+	return annotateSessions(res, time.Now()), nil
+}
+
+func fetchQuotes(ctx context.Context, symbols []string, opts MarketOptions) (MarketsResponse, error) {
+	symbols = dedupeSymbols(symbols)
+	rng, ok := NormalizeMarketRange(opts.Range)
+	if !ok {
+		return MarketsResponse{}, fmt.Errorf("unknown chart range %q", rng)
+	}
+	spec := marketRanges[rng]
+
+	cryptoQuote, ok := NormalizeCryptoQuote(opts.CryptoQuote)
+	if !ok {
+		return MarketsResponse{}, fmt.Errorf("unknown crypto quote asset %q", cryptoQuote)
+	}
+
+	ttl := NormalizeMarketsTTL(opts.TTL)
+	key := marketsCacheKey(symbols, rng) + "#" + opts.Stocks.cacheKey() + "#" + cryptoQuote
+	if cached, ok := loadMarketsCache(key); ok {
 		age := time.Since(time.Unix(cached.FetchedAt, 0))
 		if cached.FetchedAt > 0 && age >= 0 && age < ttl {
-			marketsCache.mu.Unlock()
 			return cached, nil
 		}
 	}
-	marketsCache.mu.Unlock()
 
+	// getAnyCached returns the last response regardless of age, flagged stale.
 	getAnyCached := func() (MarketsResponse, bool) {
-		marketsCache.mu.Lock()
-		defer marketsCache.mu.Unlock()
-		c, ok := marketsCache.items[key]
-		return c, ok && c.FetchedAt > 0
+		c, ok := loadMarketsCache(key)
+		if !ok || c.FetchedAt <= 0 {
+			return MarketsResponse{}, false
+		}
+		c.IsStale = true
+		c.CacheAgeSec = max(time.Now().Unix()-c.FetchedAt, 0)
+		return c, true
 	}
 
+	// Indices and FX pairs go through the stock router like stocks do.
 	cryptoSyms := make([]string, 0, len(symbols))
 	stockSyms := make([]string, 0, len(symbols))
 	for _, s := range symbols {
@@ -211,57 +344,92 @@ func FetchMarkets(ctx context.Context, symbols []string) (MarketsResponse, error
 		}
 	}
 
+	// Crypto is fetched in one batch while the stocks, one request each, are
+	// quoted at most stockWorkers at a time.
+	var cryptoItems map[string]MarketQuote
+	var cgItems []MarketQuote
+	var cryptoErr error
+	stockItems := make([]MarketQuote, len(stockSyms))
+	stockErrs := make([]error, len(stockSyms))
+	var wg sync.WaitGroup
+	if len(cryptoSyms) > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			if cryptoItems, err = fetchBinanceCrypto(ctx, cryptoSyms, spec, cryptoQuote); err != nil {
+				// Fallback to CoinGecko (some networks block Binance).
+				cryptoItems = nil
+				cgItems, cryptoErr = fetchCoinGecko(ctx, cryptoSyms, spec, cryptoQuote)
+			}
+		}()
+	}
+	sem := make(chan struct{}, stockWorkers)
+	for i, sym := range stockSyms {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			stockItems[i], stockErrs[i] = opts.Stocks.quoteStock(ctx, sym, rng)
+		}()
+	}
+	wg.Wait()
+
 	itemsBySymbol := map[string]MarketQuote{}
+	failed := 0
 
 	if len(cryptoSyms) > 0 {
-		cryptoItems, err := fetchBinanceCrypto(ctx, cryptoSyms)
-		if err != nil {
-			// Fallback to CoinGecko (some networks block Binance).
-			if cgItems, err2 := fetchCoinGecko(ctx, cryptoSyms); err2 == nil {
-				for _, it := range cgItems {
-					itemsBySymbol[strings.ToUpper(it.Symbol)] = it
-				}
-			} else {
-				// Prefer stale cache over failing the whole widget.
-				if cached, ok := getAnyCached(); ok {
-					return cached, nil
-				}
-				// Otherwise, keep going with stocks and leave crypto rows empty.
-				cryptoItems = nil
+		if cryptoErr != nil {
+			// Prefer stale cache over failing the whole widget.
+			if cached, ok := getAnyCached(); ok {
+				return cached, nil
 			}
+			// Otherwise, keep going with stocks and leave crypto rows empty.
+			failed += len(cryptoSyms)
+		}
+		for _, it := range cgItems {
+			itemsBySymbol[strings.ToUpper(it.Symbol)] = it
 		}
 		for keySym, it := range cryptoItems {
 			itemsBySymbol[strings.ToUpper(keySym)] = it
 		}
 	}
-	for _, s := range stockSyms {
-		it, err := fetchStooqStock(ctx, s)
-		if err != nil {
+	for i, s := range stockSyms {
+		if stockErrs[i] != nil {
 			// Keep widget resilient: represent missing items as 0/empty.
-			itemsBySymbol[strings.ToUpper(s)] = MarketQuote{Symbol: strings.ToUpper(s), Kind: "stock"}
+			itemsBySymbol[strings.ToUpper(s)] = MarketQuote{Symbol: strings.ToUpper(s), Kind: marketKind(s)}
+			failed++
 			continue
 		}
+		it := stockItems[i]
 		itemsBySymbol[strings.ToUpper(it.Symbol)] = it
 	}
 
-	out := MarketsResponse{FetchedAt: time.Now().Unix()}
+	// Nothing could be quoted, or the caller's deadline cut the quotes
+	// short: an older response beats a row of zeros.
+	if failed == len(symbols) || (failed > 0 && ctx.Err() != nil) {
+		if cached, ok := getAnyCached(); ok {
+			return cached, nil
+		}
+	}
+
+	out := MarketsResponse{FetchedAt: time.Now().Unix(), Range: rng}
 	out.Items = make([]MarketQuote, 0, len(symbols))
 	for _, s := range symbols {
 		keySym := strings.ToUpper(s)
 		if it, ok := itemsBySymbol[keySym]; ok {
+			it.RangeChangePct = seriesChangePct(it.Series)
 			out.Items = append(out.Items, it)
 		} else {
-			kind := "stock"
-			if isCryptoSymbol(s) {
-				kind = "crypto"
-			}
-			out.Items = append(out.Items, MarketQuote{Symbol: keySym, Kind: kind})
+			out.Items = append(out.Items, MarketQuote{Symbol: keySym, Kind: marketKind(keySym)})
 		}
 	}
 
-	marketsCache.mu.Lock()
-	marketsCache.items[key] = out
-	marketsCache.mu.Unlock()
+	// Don't let a response cut short by the deadline replace a complete one.
+	if failed == 0 || ctx.Err() == nil {
+		saveMarketsCache(key, out)
+	}
 
 	return out, nil
 }
@@ -301,6 +469,15 @@ func SearchMarketSymbols(ctx context.Context, query string, limit int) ([]Market
 		return results, nil
 	}
 
+	// Indices and FX pairs are recognised by their notation.
+	switch sym := strings.ToUpper(q); marketKind(sym) {
+	case MarketKindIndex:
+		push(MarketSymbol{Symbol: sym, Kind: MarketKindIndex, Name: marketIndices[sym].name})
+	case MarketKindFX:
+		base, quote, _ := fxPair(sym)
+		push(MarketSymbol{Symbol: base + quote, Kind: MarketKindFX, Name: base + "/" + quote})
+	}
+
 	// Stocks: treat the query as a ticker candidate and validate it via Stooq quote.
 	if sym, code := normalizeStockSearchQuery(q); sym != "" {
 		name, _, ok, _ := fetchStooqQuote(ctx, code)
@@ -371,7 +548,7 @@ func coinGeckoSearch(ctx context.Context, query string, limit int) ([]coinGeckoS
 
 	params := url.Values{}
 	params.Set("query", q)
-	endpoint := "https://api.coingecko.com/api/v3/search?" + params.Encode()
+	endpoint := endpoints().CoinGecko + "/api/v3/search?" + params.Encode()
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
 		return nil, err
@@ -413,14 +590,17 @@ func coinGeckoSearch(ctx context.Context, query string, limit int) ([]coinGeckoS
 	return out, nil
 }
 
-func fetchCoinGecko(ctx context.Context, symbolsUpper []string) ([]MarketQuote, error) {
+// fetchCoinGecko quotes crypto in USD; pairs in other quote assets are left
+// out and show up as empty rows.
+func fetchCoinGecko(ctx context.Context, symbolsUpper []string, spec marketRangeSpec, defQuote string) ([]MarketQuote, error) {
 	ids := make([]string, 0, len(symbolsUpper))
 	idToSymbol := map[string]string{}
 	idToName := map[string]string{}
+	idToQuote := map[string]string{}
 
 	for _, symRaw := range symbolsUpper {
-		sym := stripCryptoPrefix(symRaw)
-		if sym == "" {
+		sym, quote := cryptoPair(symRaw, defQuote)
+		if sym == "" || !isUSDQuote(quote) {
 			continue
 		}
 		id, name, err := coinGeckoResolveSymbol(ctx, sym)
@@ -430,6 +610,7 @@ func fetchCoinGecko(ctx context.Context, symbolsUpper []string) ([]MarketQuote,
 		ids = append(ids, id)
 		idToSymbol[id] = strings.ToUpper(symRaw)
 		idToName[id] = name
+		idToQuote[id] = quote
 	}
 	if len(ids) == 0 {
 		return nil, errors.New("coingecko: unable to resolve crypto symbols")
@@ -441,7 +622,7 @@ func fetchCoinGecko(ctx context.Context, symbolsUpper []string) ([]MarketQuote,
 	q.Set("sparkline", "true")
 	q.Set("price_change_percentage", "24h")
 
-	endpoint := "https://api.coingecko.com/api/v3/coins/markets?" + q.Encode()
+	endpoint := endpoints().CoinGecko + "/api/v3/coins/markets?" + q.Encode()
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
 		return nil, err
@@ -485,128 +666,100 @@ func fetchCoinGecko(ctx context.Context, symbolsUpper []string) ([]MarketQuote,
 		if name == "" {
 			name = strings.TrimSpace(idToName[row.ID])
 		}
-		series := downsampleTail(row.Sparkline7.Price, 24)
+		// Only a 7d sparkline is available; longer ranges get all of it.
+		series := row.Sparkline7.Price
+		if spec.coinGeckoPoints > 0 {
+			series = downsampleTail(series, spec.coinGeckoPoints)
+		} else {
+			series = thinSeries(downsampleTail(series, len(series)), maxSeriesPoints)
+		}
 		out = append(out, MarketQuote{
 			Symbol:       symbol,
 			Kind:         "crypto",
 			Name:         name,
 			PriceUSD:     row.Price,
+			QuoteAsset:   idToQuote[row.ID],
 			ChangePct24h: row.ChangePct,
 			Series:       series,
+			Source:       "coingecko",
 		})
 	}
 	return out, nil
 }
 
-func fetchBinanceCrypto(ctx context.Context, symbolsUpper []string) (map[string]MarketQuote, error) {
-	out := map[string]MarketQuote{}
-
-	client := &http.Client{Timeout: 10 * time.Second}
-	anyOK := false
-	var anyErr error
-
+// fetchBinanceCrypto quotes all direct pairs with one batched ticker call,
+// then loads klines (and resolves routed pairs) with at most binanceWorkers
+// requests in flight.
+func fetchBinanceCrypto(ctx context.Context, symbolsUpper []string, spec marketRangeSpec, defQuote string) (map[string]MarketQuote, error) {
+	type job struct {
+		key, base, quote string
+	}
+	jobs := make([]job, 0, len(symbolsUpper))
+	pairs := make([]string, 0, len(symbolsUpper))
 	for _, symRaw := range symbolsUpper {
 		origKey := strings.ToUpper(strings.TrimSpace(symRaw))
-		base := strings.ToUpper(strings.TrimSpace(stripCryptoPrefix(symRaw)))
+		base, quote := cryptoPair(symRaw, defQuote)
 		if base == "" || origKey == "" {
 			continue
 		}
-		pair := base + "USDT"
-
-		// 24h ticker
-		{
-			endpoint := "https://api.binance.com/api/v3/ticker/24hr?" + url.Values{"symbol": []string{pair}}.Encode()
-			req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
-			if err != nil {
-				anyErr = err
-				continue
-			}
-			req.Header.Set("User-Agent", "Hearth/0.1")
-			resp, err := client.Do(req)
-			if err != nil {
-				anyErr = err
-				continue
-			}
-			body, _ := io.ReadAll(io.LimitReader(resp.Body, 256*1024))
-			_ = resp.Body.Close()
-			if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-				anyErr = fmt.Errorf("binance ticker: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(body)))
-				continue
-			}
-
-			var ticker struct {
-				LastPrice         string `json:"lastPrice"`
-				PriceChangePct24h string `json:"priceChangePercent"`
-			}
-			if err := json.Unmarshal(body, &ticker); err != nil {
-				anyErr = err
-				continue
-			}
-
-			price, err := strconv.ParseFloat(strings.TrimSpace(ticker.LastPrice), 64)
-			if err != nil {
-				anyErr = err
-				continue
-			}
-			pct := 0.0
-			if p, err := strconv.ParseFloat(strings.TrimSpace(ticker.PriceChangePct24h), 64); err == nil {
-				pct = p
-			}
+		jobs = append(jobs, job{key: origKey, base: base, quote: quote})
+		if !slices.Contains(pairs, base+quote) {
+			pairs = append(pairs, base+quote)
+		}
+	}
+	if len(jobs) == 0 {
+		return nil, errors.New("binance: no data")
+	}
 
-			// 24h series (hourly closes)
-			series := make([]float64, 0, 24)
-			{
-				q := url.Values{}
-				q.Set("symbol", pair)
-				q.Set("interval", "1h")
-				q.Set("limit", "24")
-				endpoint := "https://api.binance.com/api/v3/klines?" + q.Encode()
-				req2, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
-				if err == nil {
-					req2.Header.Set("User-Agent", "Hearth/0.1")
-					resp2, err := client.Do(req2)
-					if err == nil {
-						body2, _ := io.ReadAll(io.LimitReader(resp2.Body, 1024*1024))
-						_ = resp2.Body.Close()
-						if resp2.StatusCode >= 200 && resp2.StatusCode < 300 {
-							var klines [][]any
-							if err := json.Unmarshal(body2, &klines); err == nil {
-								for _, k := range klines {
-									if len(k) < 5 {
-										continue
-									}
-									closeStr, ok := k[4].(string)
-									if !ok {
-										continue
-									}
-									if f, err := strconv.ParseFloat(strings.TrimSpace(closeStr), 64); err == nil && f > 0 {
-										series = append(series, f)
-									}
-								}
-							}
-						}
-					}
-				}
-			}
+	client := &http.Client{Timeout: 10 * time.Second}
+	// One unlisted pair fails the whole batch; every symbol is then resolved
+	// on its own, through the routing table where needed.
+	tickers, err := binanceTickers(ctx, client, pairs)
+	if err != nil && !errors.Is(err, errBinanceNoPair) {
+		return nil, err
+	}
 
-			name := strings.TrimSpace(cryptoFullNames[base])
-			out[origKey] = MarketQuote{
-				Symbol:       base,
-				Kind:         "crypto",
-				Name:         name,
-				PriceUSD:     price,
-				ChangePct24h: pct,
-				Series:       series,
+	results := make([]binancePair, len(jobs))
+	errs := make([]error, len(jobs))
+	sem := make(chan struct{}, binanceWorkers)
+	var wg sync.WaitGroup
+	for i, j := range jobs {
+		wg.Add(1)
+		go func(i int, j job) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if t, ok := tickers[j.base+j.quote]; ok {
+				t.series = binanceCloses(ctx, client, j.base+j.quote, spec)
+				results[i] = t
+				return
 			}
-			anyOK = true
-		}
+			results[i], errs[i] = binanceQuotePair(ctx, client, j.base, j.quote, spec)
+		}(i, j)
 	}
+	wg.Wait()
 
-	if !anyOK {
-		if anyErr != nil {
-			return nil, anyErr
+	out := map[string]MarketQuote{}
+	var anyErr error
+	for i, j := range jobs {
+		if errs[i] != nil {
+			anyErr = errs[i]
+			continue
+		}
+		p := results[i]
+		out[j.key] = MarketQuote{
+			Symbol:       j.base,
+			Kind:         "crypto",
+			Name:         strings.TrimSpace(cryptoFullNames[j.base]),
+			PriceUSD:     p.price,
+			QuoteAsset:   j.quote,
+			ChangePct24h: p.pct,
+			Series:       p.series,
+			Source:       "binance",
 		}
-		return nil, errors.New("binance: no data")
+	}
+	if len(out) == 0 {
+		return nil, anyErr
 	}
 	return out, nil
 }
@@ -628,9 +781,24 @@ func coinGeckoResolveSymbol(ctx context.Context, symbolUpper string) (id string,
 	}
 	coinGeckoSymbolCache.mu.Unlock()
 
+	// Resolutions survive restarts; the store only returns unexpired rows.
+	if cs := cacheStore(); cs != nil {
+		if id, name, fetched, ok, err := cs.GetSymbolMapping("coingecko", sym); err == nil && ok && id != "" {
+			coinGeckoSymbolCache.mu.Lock()
+			coinGeckoSymbolCache.items[sym] = struct {
+				ID       string
+				Name     string
+				Fetched  int64
+				SymbolUp string
+			}{ID: id, Name: name, Fetched: fetched, SymbolUp: sym}
+			coinGeckoSymbolCache.mu.Unlock()
+			return id, name, nil
+		}
+	}
+
 	q := url.Values{}
 	q.Set("query", sym)
-	endpoint := "https://api.coingecko.com/api/v3/search?" + q.Encode()
+	endpoint := endpoints().CoinGecko + "/api/v3/search?" + q.Encode()
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
 		return "", "", err
@@ -690,24 +858,22 @@ func coinGeckoResolveSymbol(ctx context.Context, symbolUpper string) (id string,
 		SymbolUp: sym,
 	}
 	coinGeckoSymbolCache.mu.Unlock()
+	if cs := cacheStore(); cs != nil {
+		now := time.Now().Unix()
+		_ = cs.SetSymbolMapping("coingecko", sym, pickedID, pickedName, now, now+int64(ttl/time.Second))
+	}
 
 	return pickedID, pickedName, nil
 }
 
-func fetchStooqStock(ctx context.Context, symbolUpper string) (MarketQuote, error) {
+func fetchStooqStock(ctx context.Context, symbolUpper string, spec marketRangeSpec) (MarketQuote, error) {
 	sym := strings.TrimSpace(strings.ToUpper(symbolUpper))
 	if sym == "" {
 		return MarketQuote{}, errors.New("symbol required")
 	}
 
-	code := strings.ToLower(sym)
-	if strings.HasPrefix(code, "STOCK:") {
-		code = strings.TrimSpace(strings.TrimPrefix(code, "stock:"))
-	}
-	if !strings.Contains(code, ".") {
-		// Default to US market.
-		code = code + ".us"
-	}
+	code := stooqCode(sym)
+	kind := marketKind(sym)
 
 	name, lastClose, ok, err := fetchStooqQuote(ctx, code)
 	if err != nil {
@@ -716,14 +882,17 @@ func fetchStooqStock(ctx context.Context, symbolUpper string) (MarketQuote, erro
 	if !ok {
 		return MarketQuote{}, errors.New("stooq: no quote")
 	}
+	if idx, known := marketIndices[sym]; known {
+		name = idx.name
+	}
 
-	closes, err := fetchStooqDailyClosesTail(ctx, code, 90)
+	closes, err := fetchStooqDailyClosesTail(ctx, code, max(90, spec.stockCloses))
 	if err != nil {
 		// Still return quote-only data.
-		return MarketQuote{Symbol: sym, Kind: "stock", Name: name, PriceUSD: lastClose, ChangePct24h: 0, Series: nil}, nil
+		return MarketQuote{Symbol: sym, Kind: kind, Name: name, PriceUSD: lastClose, ChangePct24h: 0, Series: nil}, nil
 	}
 	if len(closes) == 0 {
-		return MarketQuote{Symbol: sym, Kind: "stock", Name: name, PriceUSD: lastClose, ChangePct24h: 0, Series: nil}, nil
+		return MarketQuote{Symbol: sym, Kind: kind, Name: name, PriceUSD: lastClose, ChangePct24h: 0, Series: nil}, nil
 	}
 
 	price := closes[len(closes)-1]
@@ -738,12 +907,12 @@ func fetchStooqStock(ctx context.Context, symbolUpper string) (MarketQuote, erro
 		}
 	}
 
-	series := downsampleTail(closes, 30)
-	return MarketQuote{Symbol: sym, Kind: "stock", Name: name, PriceUSD: price, ChangePct24h: changePct, Series: series}, nil
+	series := thinSeries(downsampleTail(closes, spec.stockCloses), maxSeriesPoints)
+	return MarketQuote{Symbol: sym, Kind: kind, Name: name, PriceUSD: price, ChangePct24h: changePct, Series: series}, nil
 }
 
 func fetchStooqQuote(ctx context.Context, code string) (name string, close float64, ok bool, err error) {
-	endpoint := fmt.Sprintf("https://stooq.com/q/l/?s=%s&f=snc&h&e=csv", url.QueryEscape(code))
+	endpoint := fmt.Sprintf("%s/q/l/?s=%s&f=snc&h&e=csv", endpoints().Stooq, url.QueryEscape(code))
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
 		return "", 0, false, err
@@ -798,7 +967,7 @@ func fetchStooqDailyClosesTail(ctx context.Context, code string, maxKeep int) ([
 	if maxKeep <= 0 {
 		maxKeep = 90
 	}
-	endpoint := fmt.Sprintf("https://stooq.com/q/d/l/?s=%s&i=d", url.QueryEscape(code))
+	endpoint := fmt.Sprintf("%s/q/d/l/?s=%s&i=d", endpoints().Stooq, url.QueryEscape(code))
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
 		return nil, err
@@ -885,3 +1054,24 @@ func downsampleTail(series []float64, maxN int) []float64 {
 	}
 	return out
 }
+
+// thinSeries evenly samples series down to at most maxN points, always
+// keeping the last (current) one.
+func thinSeries(series []float64, maxN int) []float64 {
+	if maxN <= 1 || len(series) <= maxN {
+		return series
+	}
+	out := make([]float64, 0, maxN)
+	step := float64(len(series)-1) / float64(maxN-1)
+	for i := 0; i < maxN-1; i++ {
+		out = append(out, series[int(float64(i)*step)])
+	}
+	return append(out, series[len(series)-1])
+}
+
+func seriesChangePct(series []float64) float64 {
+	if len(series) < 2 || series[0] == 0 {
+		return 0
+	}
+	return (series[len(series)-1] - series[0]) / series[0] * 100
+}
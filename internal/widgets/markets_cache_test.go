@@ -0,0 +1,94 @@
+package widgets
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFetchQuotesRefreshAndStale(t *testing.T) {
+	var hits atomic.Int32
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		http.Error(w, "down", http.StatusBadGateway)
+	}))
+	defer up.Close()
+	restore := SetEndpoints(Endpoints{Binance: up.URL, CoinGecko: up.URL, Stooq: up.URL})
+	defer restore()
+
+	symbols := []string{"BTC", "AAPL"}
+	key := marketsCacheKey(symbols, "") + "#" + StockRouter{}.cacheKey() + "#" + DefaultCryptoQuote
+	fetchedAt := time.Now().Add(-3 * time.Minute).Unix()
+	marketsCache.mu.Lock()
+	marketsCache.items[key] = MarketsResponse{FetchedAt: fetchedAt, Items: []MarketQuote{{Symbol: "BTC", PriceUSD: 1}, {Symbol: "AAPL", PriceUSD: 2}}}
+	marketsCache.mu.Unlock()
+
+	// Within the default 5 minute refresh interval the entry is fresh.
+	res, err := FetchQuotes(context.Background(), symbols, MarketOptions{})
+	if err != nil || res.IsStale || hits.Load() != 0 {
+		t.Fatalf("expected fresh cache hit, got %+v err=%v hits=%d", res, err, hits.Load())
+	}
+
+	// A 1 minute widget refreshes; every upstream fails, so the old quotes come back flagged.
+	res, err = FetchQuotes(context.Background(), symbols, MarketOptions{TTL: time.Minute})
+	if err != nil {
+		t.Fatalf("FetchQuotes: %v", err)
+	}
+	if hits.Load() == 0 {
+		t.Fatalf("expected an upstream refresh")
+	}
+	if !res.IsStale || res.CacheAgeSec < 170 || res.FetchedAt != fetchedAt || res.Items[0].PriceUSD != 1 {
+		t.Fatalf("expected stale cached quotes, got %+v", res)
+	}
+
+	marketsCache.mu.Lock()
+	cached := marketsCache.items[key]
+	marketsCache.mu.Unlock()
+	if cached.IsStale {
+		t.Fatalf("stale flag must not be cached")
+	}
+}
+
+func TestNormalizeMarketsTTL(t *testing.T) {
+	cases := map[time.Duration]time.Duration{
+		0:                DefaultMarketsTTL,
+		10 * time.Second: MinMarketsTTL,
+		15 * time.Minute: 15 * time.Minute,
+		24 * time.Hour:   MaxMarketsTTL,
+	}
+	for in, want := range cases {
+		if got := NormalizeMarketsTTL(in); got != want {
+			t.Errorf("NormalizeMarketsTTL(%v) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestFetchQuotesConcurrentStocks(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+		http.Error(w, "down", http.StatusBadGateway)
+	}))
+	defer up.Close()
+	restore := SetEndpoints(Endpoints{Binance: up.URL, CoinGecko: up.URL, Stooq: up.URL})
+	defer restore()
+
+	symbols := []string{"AAPL", "MSFT", "GOOG", "AMZN", "NVDA", "TSLA", "META", "NFLX"}
+	if _, err := FetchQuotes(context.Background(), symbols, MarketOptions{}); err != nil {
+		t.Fatalf("FetchQuotes: %v", err)
+	}
+	if p := peak.Load(); p < 2 || p > stockWorkers {
+		t.Fatalf("expected between 2 and %d concurrent quotes, got %d", stockWorkers, p)
+	}
+}
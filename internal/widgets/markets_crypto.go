@@ -0,0 +1,279 @@
+package widgets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// DefaultCryptoQuote is the Binance quote asset used when a crypto symbol
+// names none.
+const DefaultCryptoQuote = "USDT"
+
+// cryptoQuoteAssets are the quote assets accepted in "ETH/BTC", "ETH-EUR" or as
+// the configured default. USD stablecoins are reported as USD; the others keep
+// their own denomination, like FX pairs.
+var cryptoQuoteAssets = map[string]bool{
+	"USDT":  true,
+	"USDC":  true,
+	"FDUSD": true,
+	"BTC":   false,
+	"ETH":   false,
+	"BNB":   false,
+	"EUR":   false,
+}
+
+// binanceRoutes are the intermediate assets tried, in order, when Binance has
+// no direct market for a pair: BASE/Q is priced as BASE/VIA x VIA/Q.
+var binanceRoutes = []string{"USDT", "BTC", "ETH", "BNB"}
+
+// binanceWorkers bounds concurrent Binance requests per widget refresh.
+const binanceWorkers = 4
+
+// stockWorkers bounds concurrent stock quote requests per widget refresh;
+// the keyed providers rate limit per minute, so it stays small.
+const stockWorkers = 4
+
+// errBinanceNoPair reports that Binance does not list a trading pair.
+var errBinanceNoPair = errors.New("binance: unknown pair")
+
+// NormalizeCryptoQuote upper-cases a quote asset; "" selects DefaultCryptoQuote.
+func NormalizeCryptoQuote(s string) (string, bool) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "" {
+		return DefaultCryptoQuote, true
+	}
+	_, ok := cryptoQuoteAssets[s]
+	return s, ok
+}
+
+// isUSDQuote reports whether a quote asset is a USD stablecoin.
+func isUSDQuote(asset string) bool {
+	return cryptoQuoteAssets[asset]
+}
+
+// isCryptoPair reports whether sym names an explicit crypto pair such as
+// "ETH/BTC" or "SOL-EUR".
+func isCryptoPair(sym string) bool {
+	s := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(sym)), "CRYPTO:")
+	i := strings.LastIndexAny(s, "/-")
+	if i <= 0 {
+		return false
+	}
+	_, ok := cryptoQuoteAssets[s[i+1:]]
+	return ok
+}
+
+// cryptoPair splits a crypto symbol into base and quote asset. Bare symbols
+// use defQuote; "-USD" picks defQuote when it is a USD stablecoin and USDT
+// otherwise.
+func cryptoPair(sym, defQuote string) (base, quote string) {
+	s := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(sym)), "CRYPTO:")
+	if b, ok := strings.CutSuffix(s, "-USD"); ok {
+		if isUSDQuote(defQuote) {
+			return b, defQuote
+		}
+		return b, DefaultCryptoQuote
+	}
+	if i := strings.LastIndexAny(s, "/-"); i > 0 {
+		if _, ok := cryptoQuoteAssets[s[i+1:]]; ok {
+			return s[:i], s[i+1:]
+		}
+	}
+	return s, defQuote
+}
+
+// binancePair is a price, 24h change and range series for one Binance market.
+type binancePair struct {
+	price  float64
+	pct    float64
+	series []float64
+}
+
+// binanceQuotePair prices base in quote, routing through binanceRoutes when
+// Binance lists no direct market.
+func binanceQuotePair(ctx context.Context, client *http.Client, base, quote string, spec marketRangeSpec) (binancePair, error) {
+	direct, err := binanceMarket(ctx, client, base, quote, spec)
+	if !errors.Is(err, errBinanceNoPair) {
+		return direct, err
+	}
+	for _, via := range binanceRoutes {
+		if via == base || via == quote {
+			continue
+		}
+		leg1, err := binanceMarket(ctx, client, base, via, spec)
+		if errors.Is(err, errBinanceNoPair) {
+			continue
+		}
+		if err != nil {
+			return binancePair{}, err
+		}
+		leg2, err := binanceMarket(ctx, client, via, quote, spec)
+		if errors.Is(err, errBinanceNoPair) {
+			continue
+		}
+		if err != nil {
+			return binancePair{}, err
+		}
+		return crossPair(leg1, leg2), nil
+	}
+	return binancePair{}, fmt.Errorf("%w: %s/%s", errBinanceNoPair, base, quote)
+}
+
+// binanceMarket quotes base in quote from the direct market, or from the
+// inverse one (e.g. USDT/EUR from EURUSDT).
+func binanceMarket(ctx context.Context, client *http.Client, base, quote string, spec marketRangeSpec) (binancePair, error) {
+	p, err := binanceTickerSeries(ctx, client, base+quote, spec)
+	if !errors.Is(err, errBinanceNoPair) {
+		return p, err
+	}
+	inv, err := binanceTickerSeries(ctx, client, quote+base, spec)
+	if err != nil || inv.price <= 0 {
+		return binancePair{}, err
+	}
+	out := binancePair{price: 1 / inv.price, pct: 100/(1+inv.pct/100) - 100, series: make([]float64, 0, len(inv.series))}
+	for _, v := range inv.series {
+		out.series = append(out.series, 1/v)
+	}
+	return out, nil
+}
+
+// crossPair multiplies two legs. Series are combined point by point when both
+// legs cover the same candles, otherwise the first leg is scaled by the
+// second leg's price.
+func crossPair(a, b binancePair) binancePair {
+	out := binancePair{
+		price:  a.price * b.price,
+		pct:    ((1+a.pct/100)*(1+b.pct/100) - 1) * 100,
+		series: make([]float64, len(a.series)),
+	}
+	for i, v := range a.series {
+		if len(b.series) == len(a.series) {
+			out.series[i] = v * b.series[i]
+		} else {
+			out.series[i] = v * b.price
+		}
+	}
+	return out
+}
+
+// binanceTickers fetches the 24h tickers of several pairs in one request.
+// Series are left empty.
+func binanceTickers(ctx context.Context, client *http.Client, pairs []string) (map[string]binancePair, error) {
+	list, err := json.Marshal(pairs)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := endpoints().Binance + "/api/v3/ticker/24hr?" + url.Values{"symbols": []string{string(list)}}.Encode()
+	body, err := binanceGet(ctx, client, endpoint, 1024*1024)
+	if err != nil {
+		return nil, fmt.Errorf("binance ticker: %w", err)
+	}
+	var tickers []struct {
+		Symbol            string `json:"symbol"`
+		LastPrice         string `json:"lastPrice"`
+		PriceChangePct24h string `json:"priceChangePercent"`
+	}
+	if err := json.Unmarshal(body, &tickers); err != nil {
+		return nil, err
+	}
+	out := make(map[string]binancePair, len(tickers))
+	for _, t := range tickers {
+		price, err := strconv.ParseFloat(strings.TrimSpace(t.LastPrice), 64)
+		if err != nil {
+			continue
+		}
+		p := binancePair{price: price}
+		if pct, err := strconv.ParseFloat(strings.TrimSpace(t.PriceChangePct24h), 64); err == nil {
+			p.pct = pct
+		}
+		out[strings.ToUpper(t.Symbol)] = p
+	}
+	return out, nil
+}
+
+// binanceTickerSeries fetches the 24h ticker and kline closes of one pair.
+// A missing series is not an error.
+func binanceTickerSeries(ctx context.Context, client *http.Client, pair string, spec marketRangeSpec) (binancePair, error) {
+	endpoint := endpoints().Binance + "/api/v3/ticker/24hr?" + url.Values{"symbol": []string{pair}}.Encode()
+	body, err := binanceGet(ctx, client, endpoint, 256*1024)
+	if err != nil {
+		return binancePair{}, fmt.Errorf("binance ticker: %w", err)
+	}
+	var ticker struct {
+		LastPrice         string `json:"lastPrice"`
+		PriceChangePct24h string `json:"priceChangePercent"`
+	}
+	if err := json.Unmarshal(body, &ticker); err != nil {
+		return binancePair{}, err
+	}
+	price, err := strconv.ParseFloat(strings.TrimSpace(ticker.LastPrice), 64)
+	if err != nil {
+		return binancePair{}, err
+	}
+	out := binancePair{price: price}
+	if p, err := strconv.ParseFloat(strings.TrimSpace(ticker.PriceChangePct24h), 64); err == nil {
+		out.pct = p
+	}
+	out.series = binanceCloses(ctx, client, pair, spec)
+	return out, nil
+}
+
+// binanceCloses returns the kline closes of a pair for the chart range.
+func binanceCloses(ctx context.Context, client *http.Client, pair string, spec marketRangeSpec) []float64 {
+	q := url.Values{}
+	q.Set("symbol", pair)
+	q.Set("interval", spec.binanceInterval)
+	q.Set("limit", strconv.Itoa(spec.binanceLimit))
+	series := make([]float64, 0, spec.binanceLimit)
+	body, err := binanceGet(ctx, client, endpoints().Binance+"/api/v3/klines?"+q.Encode(), 1024*1024)
+	if err != nil {
+		return series
+	}
+	var klines [][]any
+	if err := json.Unmarshal(body, &klines); err != nil {
+		return series
+	}
+	for _, k := range klines {
+		if len(k) < 5 {
+			continue
+		}
+		closeStr, ok := k[4].(string)
+		if !ok {
+			continue
+		}
+		if f, err := strconv.ParseFloat(strings.TrimSpace(closeStr), 64); err == nil && f > 0 {
+			series = append(series, f)
+		}
+	}
+	return series
+}
+
+// binanceGet performs a GET and maps Binance's "Invalid symbol." error
+// (code -1121) onto errBinanceNoPair.
+func binanceGet(ctx context.Context, client *http.Client, endpoint string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Hearth/0.1")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, limit))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if resp.StatusCode == http.StatusBadRequest && strings.Contains(string(body), "-1121") {
+			return nil, errBinanceNoPair
+		}
+		return nil, fmt.Errorf("status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
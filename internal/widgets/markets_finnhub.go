@@ -0,0 +1,42 @@
+package widgets
+
+import (
+	"context"
+	"errors"
+	"net/url"
+)
+
+// FinnhubProvider uses the Finnhub quote API. The free tier has no price
+// history, so quotes carry no Series.
+type FinnhubProvider struct {
+	APIKey string
+}
+
+func (FinnhubProvider) Name() string { return StockProviderFinnhub }
+
+func (p FinnhubProvider) Quote(ctx context.Context, symbol, rng string) (MarketQuote, error) {
+	q := url.Values{}
+	q.Set("symbol", providerSymbol(symbol))
+	q.Set("token", p.APIKey)
+	var payload struct {
+		Current   float64 `json:"c"`
+		ChangePct float64 `json:"dp"`
+		PrevClose float64 `json:"pc"`
+		Time      int64   `json:"t"` // last trade, unix seconds
+	}
+	if err := fetchJSON(ctx, endpoints().Finnhub+"/api/v1/quote?"+q.Encode(), "finnhub quote", &payload); err != nil {
+		return MarketQuote{}, err
+	}
+	// Unknown symbols come back as an all-zero quote.
+	if payload.Current <= 0 {
+		return MarketQuote{}, errors.New("finnhub: no quote")
+	}
+	return MarketQuote{
+		Symbol:       symbol,
+		Kind:         marketKind(symbol),
+		PriceUSD:     payload.Current,
+		ChangePct24h: payload.ChangePct,
+		Source:       StockProviderFinnhub,
+		Session:      &MarketSession{LastTradeTime: payload.Time},
+	}, nil
+}
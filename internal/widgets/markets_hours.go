@@ -0,0 +1,269 @@
+package widgets
+
+import (
+	"strings"
+	"time"
+)
+
+// Market session states reported in MarketSession.State.
+const (
+	SessionPre    = "pre"    // pre-market (extended hours)
+	SessionOpen   = "open"   // regular trading hours
+	SessionBreak  = "break"  // midday break (Tokyo, Hong Kong, Shanghai)
+	SessionPost   = "post"   // after-hours (extended hours)
+	SessionClosed = "closed" // overnight, weekend or holiday
+)
+
+// MarketSession tells the client whether a quote is live. Times are unix seconds.
+type MarketSession struct {
+	Exchange      string `json:"exchange"`
+	Timezone      string `json:"timezone"`
+	State         string `json:"state"`
+	IsMarketOpen  bool   `json:"isMarketOpen"`
+	LastTradeTime int64  `json:"lastTradeTime,omitempty"`
+	NextOpen      int64  `json:"nextOpen,omitempty"`
+}
+
+// exchangeCalendar is one row of the exchange calendar table. Times are
+// minutes after local midnight; zero pre/post means no extended hours.
+type exchangeCalendar struct {
+	code               string
+	tz                 string
+	open, close        int
+	preOpen, postClose int
+	breakStart         int
+	breakEnd           int
+	holidays           func(year int) map[string]bool // nil: weekends only
+}
+
+func hm(h, m int) int { return h*60 + m }
+
+var exchangeCalendars = map[string]*exchangeCalendar{
+	"US":    {code: "NYSE", tz: "America/New_York", open: hm(9, 30), close: hm(16, 0), preOpen: hm(4, 0), postClose: hm(20, 0), holidays: nyseHolidays},
+	"XETRA": {code: "XETRA", tz: "Europe/Berlin", open: hm(9, 0), close: hm(17, 30)},
+	"LSE":   {code: "LSE", tz: "Europe/London", open: hm(8, 0), close: hm(16, 30)},
+	"TSE":   {code: "TSE", tz: "Asia/Tokyo", open: hm(9, 0), close: hm(15, 30), breakStart: hm(11, 30), breakEnd: hm(12, 30)},
+	"HKEX":  {code: "HKEX", tz: "Asia/Hong_Kong", open: hm(9, 30), close: hm(16, 0), breakStart: hm(12, 0), breakEnd: hm(13, 0)},
+	"SSE":   {code: "SSE", tz: "Asia/Shanghai", open: hm(9, 30), close: hm(15, 0), breakStart: hm(11, 30), breakEnd: hm(13, 0)},
+}
+
+// exchangeBySuffix maps Stooq/Yahoo symbol suffixes to calendars. Symbols
+// without a suffix are US listings.
+var exchangeBySuffix = map[string]string{
+	"":    "US",
+	".US": "US",
+	".DE": "XETRA",
+	".UK": "LSE",
+	".L":  "LSE",
+	".JP": "TSE",
+	".T":  "TSE",
+	".HK": "HKEX",
+	".SS": "SSE",
+	".SZ": "SSE",
+}
+
+// exchangeFor returns the calendar of a stock or index symbol, or nil if unknown.
+func exchangeFor(sym string) *exchangeCalendar {
+	if marketKind(sym) == MarketKindIndex {
+		return exchangeCalendars[marketIndices[sym].exchange]
+	}
+	suffix := ""
+	if i := strings.LastIndexByte(sym, '.'); i >= 0 {
+		suffix = sym[i:]
+	}
+	return exchangeCalendars[exchangeBySuffix[suffix]]
+}
+
+// annotateSessions returns res with session metadata on every stock, index
+// and FX quote. Crypto trades around the clock and gets none. The input is
+// not modified (it may be shared with the cache).
+func annotateSessions(res MarketsResponse, now time.Time) MarketsResponse {
+	items := make([]MarketQuote, len(res.Items))
+	for i, q := range res.Items {
+		var s *MarketSession
+		switch q.Kind {
+		case MarketKindFX:
+			s = fxSession(now)
+		case MarketKindStock, MarketKindIndex:
+			if c := exchangeFor(q.Symbol); c != nil {
+				s = c.session(now)
+			}
+		}
+		if s != nil && q.Session != nil && q.Session.LastTradeTime > 0 {
+			// Providers that report the actual last trade win over the calendar.
+			s.LastTradeTime = q.Session.LastTradeTime
+		}
+		q.Session = s
+		items[i] = q
+	}
+	res.Items = items
+	return res
+}
+
+func (c *exchangeCalendar) tradingDay(d time.Time) bool {
+	if d.Weekday() == time.Saturday || d.Weekday() == time.Sunday {
+		return false
+	}
+	return c.holidays == nil || !c.holidays(d.Year())[d.Format("2006-01-02")]
+}
+
+func (c *exchangeCalendar) at(day time.Time, minutes int) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), minutes/60, minutes%60, 0, 0, day.Location())
+}
+
+// prevTradingDay/nextTradingDay step from d (exclusive). Two weeks covers
+// any holiday cluster.
+func (c *exchangeCalendar) prevTradingDay(d time.Time) time.Time {
+	for i := 1; i <= 14; i++ {
+		if p := d.AddDate(0, 0, -i); c.tradingDay(p) {
+			return p
+		}
+	}
+	return d.AddDate(0, 0, -1)
+}
+
+func (c *exchangeCalendar) nextTradingDay(d time.Time) time.Time {
+	for i := 1; i <= 14; i++ {
+		if n := d.AddDate(0, 0, i); c.tradingDay(n) {
+			return n
+		}
+	}
+	return d.AddDate(0, 0, 1)
+}
+
+// session evaluates the calendar at now. It returns nil when the exchange
+// timezone is unavailable on this system.
+func (c *exchangeCalendar) session(now time.Time) *MarketSession {
+	loc, err := time.LoadLocation(c.tz)
+	if err != nil {
+		return nil
+	}
+	local := now.In(loc)
+	m := local.Hour()*60 + local.Minute()
+	s := &MarketSession{Exchange: c.code, Timezone: c.tz, State: SessionClosed}
+
+	today := c.tradingDay(local)
+	lastClose := c.at(c.prevTradingDay(local), c.close)
+	next := c.at(c.nextTradingDay(local), c.open)
+	if today {
+		if m >= c.close {
+			lastClose = c.at(local, c.close)
+		}
+		if m < c.open {
+			next = c.at(local, c.open)
+		}
+	}
+	s.LastTradeTime = lastClose.Unix()
+	s.NextOpen = next.Unix()
+	if !today {
+		return s
+	}
+
+	switch {
+	case m >= c.open && m < c.close:
+		if c.breakStart > 0 && m >= c.breakStart && m < c.breakEnd {
+			s.State = SessionBreak
+			s.LastTradeTime = c.at(local, c.breakStart).Unix()
+			s.NextOpen = c.at(local, c.breakEnd).Unix()
+			break
+		}
+		s.State = SessionOpen
+		s.IsMarketOpen = true
+		s.LastTradeTime = now.Unix()
+		s.NextOpen = 0
+	case c.preOpen > 0 && m >= c.preOpen && m < c.open:
+		s.State = SessionPre
+	case c.postClose > 0 && m >= c.close && m < c.postClose:
+		s.State = SessionPost
+	}
+	return s
+}
+
+// fxSession models the interbank FX week: Sunday 17:00 to Friday 17:00 New York.
+func fxSession(now time.Time) *MarketSession {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		return nil
+	}
+	local := now.In(loc)
+	s := &MarketSession{Exchange: "FX", Timezone: "America/New_York", State: SessionOpen, IsMarketOpen: true, LastTradeTime: now.Unix()}
+	wd, m := local.Weekday(), local.Hour()*60+local.Minute()
+	closed := wd == time.Saturday || (wd == time.Friday && m >= hm(17, 0)) || (wd == time.Sunday && m < hm(17, 0))
+	if !closed {
+		return s
+	}
+	// Back to Friday 17:00 and forward to Sunday 17:00.
+	fri := local.AddDate(0, 0, -((int(wd) - int(time.Friday) + 7) % 7))
+	sun := local.AddDate(0, 0, (int(time.Sunday)-int(wd)+7)%7)
+	s.State = SessionClosed
+	s.IsMarketOpen = false
+	s.LastTradeTime = time.Date(fri.Year(), fri.Month(), fri.Day(), 17, 0, 0, 0, loc).Unix()
+	s.NextOpen = time.Date(sun.Year(), sun.Month(), sun.Day(), 17, 0, 0, 0, loc).Unix()
+	return s
+}
+
+// nyseHolidays returns the full-day NYSE closures of a year, following the
+// exchange's observance rules (Saturday holidays move to Friday, Sunday ones
+// to Monday).
+func nyseHolidays(year int) map[string]bool {
+	out := map[string]bool{}
+	add := func(d time.Time) { out[d.Format("2006-01-02")] = true }
+	observed := func(m time.Month, day int) {
+		d := time.Date(year, m, day, 0, 0, 0, 0, time.UTC)
+		switch d.Weekday() {
+		case time.Saturday:
+			// No Friday closure for a Saturday New Year's Day.
+			if m != time.January {
+				add(d.AddDate(0, 0, -1))
+			}
+		case time.Sunday:
+			add(d.AddDate(0, 0, 1))
+		default:
+			add(d)
+		}
+	}
+	nth := func(m time.Month, wd time.Weekday, n int) time.Time {
+		d := time.Date(year, m, 1, 0, 0, 0, 0, time.UTC)
+		for d.Weekday() != wd {
+			d = d.AddDate(0, 0, 1)
+		}
+		return d.AddDate(0, 0, 7*(n-1))
+	}
+	last := func(m time.Month, wd time.Weekday) time.Time {
+		d := time.Date(year, m+1, 0, 0, 0, 0, 0, time.UTC)
+		for d.Weekday() != wd {
+			d = d.AddDate(0, 0, -1)
+		}
+		return d
+	}
+
+	observed(time.January, 1)
+	add(nth(time.January, time.Monday, 3))  // Martin Luther King Jr. Day
+	add(nth(time.February, time.Monday, 3)) // Washington's Birthday
+	add(easterSunday(year).AddDate(0, 0, -2))
+	add(last(time.May, time.Monday)) // Memorial Day
+	if year >= 2022 {
+		observed(time.June, 19) // Juneteenth
+	}
+	observed(time.July, 4)
+	add(nth(time.September, time.Monday, 1)) // Labor Day
+	add(nth(time.November, time.Thursday, 4))
+	observed(time.December, 25)
+	return out
+}
+
+// easterSunday computes Western Easter (anonymous Gregorian algorithm).
+func easterSunday(year int) time.Time {
+	a := year % 19
+	b, c := year/100, year%100
+	d, e := b/4, b%4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i, k := c/4, c%4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,73 @@
+package widgets
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNYSEHolidays(t *testing.T) {
+	h := nyseHolidays(2026)
+	for _, d := range []string{"2026-01-01", "2026-01-19", "2026-04-03", "2026-05-25", "2026-06-19", "2026-07-03", "2026-11-26", "2026-12-25"} {
+		if !h[d] {
+			t.Errorf("expected %s to be an NYSE holiday", d)
+		}
+	}
+	if h["2026-07-04"] {
+		t.Errorf("Saturday Independence Day is observed on Friday")
+	}
+	if len(h) != 10 {
+		t.Errorf("expected 10 holidays in 2026, got %d: %v", len(h), h)
+	}
+}
+
+func TestExchangeSession(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("tzdata unavailable")
+	}
+	at := func(s string) time.Time {
+		tm, err := time.ParseInLocation("2006-01-02 15:04", s, ny)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+	us := exchangeFor("AAPL")
+	cases := []struct {
+		now   string
+		state string
+		open  bool
+	}{
+		{"2026-10-14 10:00", SessionOpen, true},
+		{"2026-10-14 07:00", SessionPre, false},
+		{"2026-10-14 17:30", SessionPost, false},
+		{"2026-10-14 22:00", SessionClosed, false},
+		{"2026-10-18 12:00", SessionClosed, false}, // Sunday
+		{"2026-11-26 12:00", SessionClosed, false}, // Thanksgiving
+	}
+	for _, c := range cases {
+		s := us.session(at(c.now))
+		if s == nil || s.State != c.state || s.IsMarketOpen != c.open {
+			t.Errorf("%s: got %+v, want state=%s open=%v", c.now, s, c.state, c.open)
+		}
+	}
+
+	// Sunday: last trade is Friday's close, next open Monday 09:30.
+	s := us.session(at("2026-10-18 12:00"))
+	if s.LastTradeTime != at("2026-10-16 16:00").Unix() || s.NextOpen != at("2026-10-19 09:30").Unix() {
+		t.Errorf("weekend session times: last=%v next=%v", time.Unix(s.LastTradeTime, 0).In(ny), time.Unix(s.NextOpen, 0).In(ny))
+	}
+
+	// Hong Kong lunch break.
+	hk := exchangeFor("0700.HK").session(at("2026-10-14 00:30")) // 12:30 HKT
+	if hk == nil || hk.State != SessionBreak || hk.Exchange != "HKEX" {
+		t.Errorf("expected HKEX lunch break, got %+v", hk)
+	}
+
+	if fx := fxSession(at("2026-10-17 12:00")); fx.IsMarketOpen {
+		t.Errorf("FX should be closed on Saturday")
+	}
+	if fx := fxSession(at("2026-10-18 18:00")); !fx.IsMarketOpen {
+		t.Errorf("FX should reopen Sunday evening New York time")
+	}
+}
@@ -0,0 +1,103 @@
+package widgets
+
+import "strings"
+
+// Market symbol kinds reported in MarketQuote.Kind.
+const (
+	MarketKindStock  = "stock"
+	MarketKindCrypto = "crypto"
+	MarketKindIndex  = "index" // Yahoo-style "^GSPC"; priced in points
+	MarketKindFX     = "fx"    // currency pair "EURUSD" (also "EUR/USD", "EURUSD=X")
+)
+
+// marketIndex describes a well-known index: its display name, Stooq code and
+// the exchange calendar its session follows.
+type marketIndex struct {
+	name     string
+	stooq    string
+	exchange string
+}
+
+var marketIndices = map[string]marketIndex{
+	"^GSPC":  {name: "S&P 500", stooq: "^spx", exchange: "US"},
+	"^SPX":   {name: "S&P 500", stooq: "^spx", exchange: "US"},
+	"^NDX":   {name: "Nasdaq 100", stooq: "^ndx", exchange: "US"},
+	"^IXIC":  {name: "Nasdaq Composite", stooq: "^ndq", exchange: "US"},
+	"^DJI":   {name: "Dow Jones Industrial Average", stooq: "^dji", exchange: "US"},
+	"^N225":  {name: "Nikkei 225", stooq: "^nkx", exchange: "TSE"},
+	"^FTSE":  {name: "FTSE 100", stooq: "^ukx", exchange: "LSE"},
+	"^GDAXI": {name: "DAX", stooq: "^dax", exchange: "XETRA"},
+	"^HSI":   {name: "Hang Seng", stooq: "^hsi", exchange: "HKEX"},
+}
+
+// fxCurrencies are the ISO 4217 codes recognised in currency pairs.
+var fxCurrencies = map[string]bool{
+	"USD": true, "EUR": true, "GBP": true, "JPY": true, "CNY": true, "CNH": true,
+	"HKD": true, "CHF": true, "CAD": true, "AUD": true, "NZD": true, "SEK": true,
+	"NOK": true, "DKK": true, "SGD": true, "KRW": true, "INR": true, "TWD": true,
+	"MXN": true, "BRL": true, "ZAR": true, "TRY": true, "PLN": true, "THB": true,
+}
+
+// marketKind classifies a normalized (upper-case) symbol.
+func marketKind(sym string) string {
+	switch {
+	case isCryptoSymbol(sym):
+		return MarketKindCrypto
+	case strings.HasPrefix(sym, "^"):
+		return MarketKindIndex
+	}
+	if _, _, ok := fxPair(sym); ok {
+		return MarketKindFX
+	}
+	return MarketKindStock
+}
+
+// fxPair splits "EURUSD", "EUR/USD" or "EURUSD=X" into its currencies.
+func fxPair(sym string) (base, quote string, ok bool) {
+	s := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(sym)), "=X")
+	s = strings.ReplaceAll(s, "/", "")
+	if len(s) != 6 {
+		return "", "", false
+	}
+	base, quote = s[:3], s[3:]
+	if !fxCurrencies[base] || !fxCurrencies[quote] || base == quote {
+		return "", "", false
+	}
+	return base, quote, true
+}
+
+// stooqCode maps a symbol to Stooq notation: "aapl.us", "^spx", "eurusd".
+func stooqCode(sym string) string {
+	switch marketKind(sym) {
+	case MarketKindIndex:
+		if idx, ok := marketIndices[sym]; ok {
+			return idx.stooq
+		}
+		return strings.ToLower(sym)
+	case MarketKindFX:
+		base, quote, _ := fxPair(sym)
+		return strings.ToLower(base + quote)
+	}
+	code := strings.ToLower(strings.TrimPrefix(sym, "STOCK:"))
+	if !strings.Contains(code, ".") {
+		// Default to US market.
+		code = code + ".us"
+	}
+	return code
+}
+
+// yahooSymbol maps a symbol to Yahoo notation: "AAPL", "^GSPC", "EURUSD=X".
+func yahooSymbol(sym string) string {
+	if base, quote, ok := fxPair(sym); ok {
+		return base + quote + "=X"
+	}
+	return providerSymbol(sym)
+}
+
+// twelveDataSymbol maps a symbol to Twelve Data notation: "AAPL", "EUR/USD".
+func twelveDataSymbol(sym string) string {
+	if base, quote, ok := fxPair(sym); ok {
+		return base + "/" + quote
+	}
+	return strings.TrimPrefix(providerSymbol(sym), "^")
+}
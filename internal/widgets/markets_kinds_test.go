@@ -0,0 +1,78 @@
+package widgets
+
+import "testing"
+
+func TestMarketKindAndNotation(t *testing.T) {
+	cases := []struct {
+		sym, kind, stooq, yahoo, twelve string
+	}{
+		{"AAPL", MarketKindStock, "aapl.us", "AAPL", "AAPL"},
+		{"SAP.DE", MarketKindStock, "sap.de", "SAP.DE", "SAP.DE"},
+		{"BTC", MarketKindCrypto, "", "", ""},
+		{"^GSPC", MarketKindIndex, "^spx", "^GSPC", "GSPC"},
+		{"^STOXX50E", MarketKindIndex, "^stoxx50e", "^STOXX50E", "STOXX50E"},
+		{"EURUSD", MarketKindFX, "eurusd", "EURUSD=X", "EUR/USD"},
+		{"USD/JPY", MarketKindFX, "usdjpy", "USDJPY=X", "USD/JPY"},
+		{"EURUSD=X", MarketKindFX, "eurusd", "EURUSD=X", "EUR/USD"},
+		{"GOOGLE", MarketKindStock, "google.us", "GOOGLE", "GOOGLE"},
+		{"BRK-B", MarketKindStock, "brk-b.us", "BRK-B", "BRK-B"},
+		{"ETH/BTC", MarketKindCrypto, "", "", ""},
+		{"SOL-EUR", MarketKindCrypto, "", "", ""},
+	}
+	for _, c := range cases {
+		if got := marketKind(c.sym); got != c.kind {
+			t.Errorf("marketKind(%q) = %q, want %q", c.sym, got, c.kind)
+		}
+		if c.kind == MarketKindCrypto {
+			continue
+		}
+		if got := stooqCode(c.sym); got != c.stooq {
+			t.Errorf("stooqCode(%q) = %q, want %q", c.sym, got, c.stooq)
+		}
+		if got := yahooSymbol(c.sym); got != c.yahoo {
+			t.Errorf("yahooSymbol(%q) = %q, want %q", c.sym, got, c.yahoo)
+		}
+		if got := twelveDataSymbol(c.sym); got != c.twelve {
+			t.Errorf("twelveDataSymbol(%q) = %q, want %q", c.sym, got, c.twelve)
+		}
+	}
+}
+
+func TestConvertMarketsSkipsIndicesAndFX(t *testing.T) {
+	res := MarketsResponse{Items: []MarketQuote{
+		{Symbol: "AAPL", Kind: MarketKindStock, PriceUSD: 100},
+		{Symbol: "^GSPC", Kind: MarketKindIndex, PriceUSD: 5800},
+		{Symbol: "EURUSD", Kind: MarketKindFX, PriceUSD: 1.08},
+	}}
+	got, err := ConvertMarkets(res, "EUR", map[string]float64{"EUR": 0.9})
+	if err != nil {
+		t.Fatalf("ConvertMarkets: %v", err)
+	}
+	if got.Items[0].Price != 90 || got.Items[0].Currency != "EUR" {
+		t.Errorf("stock: %+v", got.Items[0])
+	}
+	if got.Items[1].Price != 5800 || got.Items[1].Currency != "" {
+		t.Errorf("index: %+v", got.Items[1])
+	}
+	if got.Items[2].Price != 1.08 || got.Items[2].Currency != "USD" {
+		t.Errorf("fx: %+v", got.Items[2])
+	}
+}
+
+func TestCryptoPair(t *testing.T) {
+	cases := []struct {
+		sym, def, base, quote string
+	}{
+		{"BTC", "USDT", "BTC", "USDT"},
+		{"BTC", "EUR", "BTC", "EUR"},
+		{"CRYPTO:ETH/BTC", "USDT", "ETH", "BTC"},
+		{"SOL-EUR", "USDT", "SOL", "EUR"},
+		{"BTC-USD", "USDC", "BTC", "USDC"},
+		{"BTC-USD", "EUR", "BTC", "USDT"},
+	}
+	for _, c := range cases {
+		if base, quote := cryptoPair(c.sym, c.def); base != c.base || quote != c.quote {
+			t.Errorf("cryptoPair(%q, %q) = %s/%s, want %s/%s", c.sym, c.def, base, quote, c.base, c.quote)
+		}
+	}
+}
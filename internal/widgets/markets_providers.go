@@ -0,0 +1,180 @@
+package widgets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Stock quote provider names as stored in settings.
+const (
+	StockProviderStooq      = "stooq"
+	StockProviderFinnhub    = "finnhub"
+	StockProviderTwelveData = "twelvedata"
+	StockProviderYahoo      = "yahoo"
+)
+
+// StockProvider quotes a single stock symbol (e.g. "AAPL", "SAP.DE") in USD
+// terms, with a Series for the given chart range (see MarketRange1D...).
+type StockProvider interface {
+	Name() string
+	Quote(ctx context.Context, symbol, rng string) (MarketQuote, error)
+}
+
+// StockAPIKeys holds credentials for the keyed stock providers.
+type StockAPIKeys struct {
+	Finnhub    string
+	TwelveData string
+}
+
+// NewStockProvider returns the provider for a settings value. Providers that
+// need an API key fall back to Stooq when none is configured.
+func NewStockProvider(name string, keys StockAPIKeys) StockProvider {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case StockProviderFinnhub:
+		if k := strings.TrimSpace(keys.Finnhub); k != "" {
+			return FinnhubProvider{APIKey: k}
+		}
+	case StockProviderTwelveData:
+		if k := strings.TrimSpace(keys.TwelveData); k != "" {
+			return TwelveDataProvider{APIKey: k}
+		}
+	case StockProviderYahoo:
+		return YahooProvider{}
+	}
+	return StooqProvider{}
+}
+
+// StooqProvider is the keyless default. It only has daily closes, and its
+// coverage outside US exchanges is patchy.
+type StooqProvider struct{}
+
+func (StooqProvider) Name() string { return StockProviderStooq }
+
+func (StooqProvider) Quote(ctx context.Context, symbol, rng string) (MarketQuote, error) {
+	q, err := fetchStooqStock(ctx, symbol, marketRanges[rng])
+	q.Source = StockProviderStooq
+	return q, err
+}
+
+// StockRouter picks a provider per stock, index or FX symbol. Routes are keyed
+// by an exact symbol ("SAP.DE") or a path.Match pattern ("*.HK", "^*"); exact
+// keys win, then the longest pattern. Unrouted stocks use Default; unrouted
+// indices and FX pairs use Stooq, which covers them without a key. The zero
+// value sends everything to Stooq.
+type StockRouter struct {
+	Default StockProvider
+	Routes  map[string]StockProvider
+}
+
+// NewStockRouter builds a router from settings values. Routes naming unknown
+// or unconfigured providers resolve to Stooq.
+func NewStockRouter(def string, keys StockAPIKeys, routes map[string]string) StockRouter {
+	r := StockRouter{Default: NewStockProvider(def, keys)}
+	if len(routes) > 0 {
+		r.Routes = make(map[string]StockProvider, len(routes))
+		for pattern, name := range routes {
+			pattern = strings.ToUpper(strings.TrimSpace(pattern))
+			if pattern == "" {
+				continue
+			}
+			r.Routes[pattern] = NewStockProvider(name, keys)
+		}
+	}
+	return r
+}
+
+// For returns the provider responsible for symbol.
+func (r StockRouter) For(symbol string) StockProvider {
+	sym := strings.ToUpper(strings.TrimSpace(symbol))
+	if p, ok := r.Routes[sym]; ok {
+		return p
+	}
+	var best string
+	for pattern := range r.Routes {
+		if ok, _ := path.Match(pattern, sym); ok && len(pattern) > len(best) {
+			best = pattern
+		}
+	}
+	if best != "" {
+		return r.Routes[best]
+	}
+	if r.Default != nil && marketKind(sym) == MarketKindStock {
+		return r.Default
+	}
+	return StooqProvider{}
+}
+
+// cacheKey identifies the routing so differently routed responses are cached
+// apart.
+func (r StockRouter) cacheKey() string {
+	def := StockProviderStooq
+	if r.Default != nil {
+		def = r.Default.Name()
+	}
+	keys := make([]string, 0, len(r.Routes))
+	for pattern, p := range r.Routes {
+		keys = append(keys, pattern+"="+p.Name())
+	}
+	sort.Strings(keys)
+	return def + ";" + strings.Join(keys, ",")
+}
+
+// quoteStock asks the routed provider and fails over to Stooq when it errors.
+func (r StockRouter) quoteStock(ctx context.Context, symbol, rng string) (MarketQuote, error) {
+	p := r.For(symbol)
+	q, err := p.Quote(ctx, symbol, rng)
+	if err == nil || p.Name() == StockProviderStooq {
+		return q, err
+	}
+	fb, fbErr := StooqProvider{}.Quote(ctx, symbol, rng)
+	if fbErr != nil {
+		return MarketQuote{}, fmt.Errorf("%w (stooq fallback: %v)", err, fbErr)
+	}
+	return fb, nil
+}
+
+// providerSymbol converts Hearth's Stooq-style notation to the ticker most
+// other providers expect: a trailing ".US" is dropped.
+func providerSymbol(symbol string) string {
+	return strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(symbol)), ".US")
+}
+
+// seriesFromCloses turns closes (oldest first) into the range sparkline. The
+// legacy empty range keeps the last stockCloses daily points.
+func seriesFromCloses(closes []float64, rng string) []float64 {
+	keep := len(closes)
+	if rng == "" {
+		keep = marketRanges[rng].stockCloses
+	}
+	return thinSeries(downsampleTail(closes, keep), maxSeriesPoints)
+}
+
+// fetchJSON GETs endpoint and decodes a JSON body into out. label prefixes
+// errors, e.g. "finnhub quote: status=429 body=...".
+func fetchJSON(ctx context.Context, endpoint, label string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	// Yahoo rejects requests without a browser-like agent.
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; Hearth/0.1)")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s: status=%d body=%s", label, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 4<<20)).Decode(out)
+}
@@ -0,0 +1,86 @@
+package widgets
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// TwelveDataProvider uses the Twelve Data quote and time_series APIs, which
+// cover most international exchanges and intraday intervals.
+type TwelveDataProvider struct {
+	APIKey string
+}
+
+func (TwelveDataProvider) Name() string { return StockProviderTwelveData }
+
+// twelveDataStatus is embedded in every response; errors (bad key, quota)
+// arrive with HTTP 200 and status "error".
+type twelveDataStatus struct {
+	Status  string `json:"status"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (s twelveDataStatus) err(label string) error {
+	if s.Status != "error" {
+		return nil
+	}
+	return fmt.Errorf("%s: status=%d body=%s", label, s.Code, s.Message)
+}
+
+func (p TwelveDataProvider) Quote(ctx context.Context, symbol, rng string) (MarketQuote, error) {
+	sym := twelveDataSymbol(symbol)
+	spec := marketRanges[rng]
+
+	q := url.Values{}
+	q.Set("symbol", sym)
+	q.Set("apikey", p.APIKey)
+	var quote struct {
+		twelveDataStatus
+		Name          string `json:"name"`
+		Close         string `json:"close"`
+		PercentChange string `json:"percent_change"`
+	}
+	if err := fetchJSON(ctx, endpoints().TwelveData+"/quote?"+q.Encode(), "twelvedata quote", &quote); err != nil {
+		return MarketQuote{}, err
+	}
+	if err := quote.err("twelvedata quote"); err != nil {
+		return MarketQuote{}, err
+	}
+	price, err := strconv.ParseFloat(strings.TrimSpace(quote.Close), 64)
+	if err != nil || price <= 0 {
+		return MarketQuote{}, fmt.Errorf("twelvedata: no quote for %s", sym)
+	}
+	pct, _ := strconv.ParseFloat(strings.TrimSpace(quote.PercentChange), 64)
+	out := MarketQuote{
+		Symbol:       symbol,
+		Kind:         marketKind(symbol),
+		Name:         strings.TrimSpace(quote.Name),
+		PriceUSD:     price,
+		ChangePct24h: pct,
+		Source:       StockProviderTwelveData,
+	}
+
+	q.Set("interval", spec.twelveInterval)
+	q.Set("outputsize", strconv.Itoa(spec.twelvePoints))
+	var series struct {
+		twelveDataStatus
+		Values []struct {
+			Close string `json:"close"`
+		} `json:"values"` // newest first
+	}
+	// The series is decoration; keep the quote if it fails.
+	if err := fetchJSON(ctx, endpoints().TwelveData+"/time_series?"+q.Encode(), "twelvedata series", &series); err == nil && series.err("") == nil {
+		closes := make([]float64, 0, len(series.Values))
+		for i := len(series.Values) - 1; i >= 0; i-- {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(series.Values[i].Close), 64); err == nil && f > 0 {
+				closes = append(closes, f)
+			}
+		}
+		out.Series = seriesFromCloses(closes, rng)
+	}
+	return out, nil
+}
@@ -0,0 +1,98 @@
+package widgets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// YahooProvider uses the (unofficial, keyless) Yahoo Finance chart API. It
+// covers international exchanges in Yahoo notation, e.g. "0700.HK", "SAP.DE",
+// plus indices ("^GSPC") and FX pairs.
+type YahooProvider struct{}
+
+func (YahooProvider) Name() string { return StockProviderYahoo }
+
+func (YahooProvider) Quote(ctx context.Context, symbol, rng string) (MarketQuote, error) {
+	sym := yahooSymbol(symbol)
+	spec := marketRanges[rng]
+
+	q := url.Values{}
+	q.Set("range", spec.yahooRange)
+	q.Set("interval", spec.yahooInterval)
+	endpoint := endpoints().Yahoo + "/v8/finance/chart/" + url.PathEscape(sym) + "?" + q.Encode()
+	var payload struct {
+		Chart struct {
+			Result []struct {
+				Meta struct {
+					RegularMarketPrice float64 `json:"regularMarketPrice"`
+					RegularMarketTime  int64   `json:"regularMarketTime"`
+					PreviousClose      float64 `json:"previousClose"`
+					ChartPreviousClose float64 `json:"chartPreviousClose"`
+					LongName           string  `json:"longName"`
+					ShortName          string  `json:"shortName"`
+				} `json:"meta"`
+				Indicators struct {
+					Quote []struct {
+						Close []*float64 `json:"close"`
+					} `json:"quote"`
+				} `json:"indicators"`
+			} `json:"result"`
+			Error *struct {
+				Code        string `json:"code"`
+				Description string `json:"description"`
+			} `json:"error"`
+		} `json:"chart"`
+	}
+	if err := fetchJSON(ctx, endpoint, "yahoo chart", &payload); err != nil {
+		return MarketQuote{}, err
+	}
+	if e := payload.Chart.Error; e != nil {
+		return MarketQuote{}, fmt.Errorf("yahoo chart: %s: %s", e.Code, e.Description)
+	}
+	if len(payload.Chart.Result) == 0 {
+		return MarketQuote{}, errors.New("yahoo: no quote")
+	}
+	res := payload.Chart.Result[0]
+
+	closes := []float64{}
+	if len(res.Indicators.Quote) > 0 {
+		for _, c := range res.Indicators.Quote[0].Close {
+			if c != nil && *c > 0 {
+				closes = append(closes, *c)
+			}
+		}
+	}
+	price := res.Meta.RegularMarketPrice
+	if price <= 0 && len(closes) > 0 {
+		price = closes[len(closes)-1]
+	}
+	if price <= 0 {
+		return MarketQuote{}, errors.New("yahoo: no quote")
+	}
+	// previousClose is only reported for intraday charts; for daily charts
+	// the close before the last one is the previous session.
+	prev := res.Meta.PreviousClose
+	if prev <= 0 && spec.yahooInterval == "1d" && len(closes) >= 2 {
+		prev = closes[len(closes)-2]
+	}
+	if prev <= 0 && rng == MarketRange1D {
+		prev = res.Meta.ChartPreviousClose
+	}
+	name := strings.TrimSpace(res.Meta.LongName)
+	if name == "" {
+		name = strings.TrimSpace(res.Meta.ShortName)
+	}
+	return MarketQuote{
+		Symbol:       symbol,
+		Kind:         marketKind(symbol),
+		Name:         name,
+		PriceUSD:     price,
+		ChangePct24h: pct(price-prev, prev),
+		Series:       seriesFromCloses(closes, rng),
+		Source:       StockProviderYahoo,
+		Session:      &MarketSession{LastTradeTime: res.Meta.RegularMarketTime},
+	}, nil
+}
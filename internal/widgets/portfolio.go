@@ -0,0 +1,116 @@
+package widgets
+
+import (
+	"context"
+	"strings"
+)
+
+// Holding is a position to value: Quantity units of Symbol bought at an
+// average CostBasis per unit (USD).
+type Holding struct {
+	Symbol    string
+	Quantity  float64
+	CostBasis float64
+}
+
+// PortfolioPosition values one holding. Money fields are in the response currency.
+type PortfolioPosition struct {
+	Symbol       string  `json:"symbol"`
+	Kind         string  `json:"kind"`
+	Name         string  `json:"name,omitempty"`
+	Quantity     float64 `json:"quantity"`
+	Price        float64 `json:"price"`
+	CostBasis    float64 `json:"costBasis"` // per unit
+	Value        float64 `json:"value"`
+	Cost         float64 `json:"cost"`
+	Gain         float64 `json:"gain"`
+	GainPct      float64 `json:"gainPct"`
+	DayChange    float64 `json:"dayChange"`
+	DayChangePct float64 `json:"dayChangePct"`
+	Stale        bool    `json:"stale,omitempty"` // no quote available; valued at 0
+}
+
+type PortfolioResponse struct {
+	FetchedAt    int64               `json:"fetchedAt"`
+	Currency     string              `json:"currency"`
+	TotalValue   float64             `json:"totalValue"`
+	TotalCost    float64             `json:"totalCost"`
+	TotalGain    float64             `json:"totalGain"`
+	TotalGainPct float64             `json:"totalGainPct"`
+	DayChange    float64             `json:"dayChange"`
+	DayChangePct float64             `json:"dayChangePct"`
+	Positions    []PortfolioPosition `json:"positions"`
+}
+
+// FetchPortfolio quotes every holding with the market fetchers and values the
+// portfolio in currency. rates may be nil for USD.
+func FetchPortfolio(ctx context.Context, holdings []Holding, currency string, rates map[string]float64, stocks StockRouter) (PortfolioResponse, error) {
+	currency = NormalizeCurrency(currency)
+	rate, err := usdRate(currency, rates)
+	if err != nil {
+		return PortfolioResponse{}, err
+	}
+	symbols := make([]string, 0, len(holdings))
+	for _, h := range holdings {
+		symbols = append(symbols, h.Symbol)
+	}
+	quotes := MarketsResponse{}
+	if len(symbols) > 0 {
+		if quotes, err = FetchQuotes(ctx, symbols, MarketOptions{Stocks: stocks}); err != nil {
+			return PortfolioResponse{}, err
+		}
+	}
+	res := BuildPortfolio(quotes, holdings, rate)
+	res.Currency = currency
+	return res, nil
+}
+
+// BuildPortfolio values holdings against USD quotes, scaling money by rate.
+// Daily P/L is derived from each quote's 24h change.
+func BuildPortfolio(quotes MarketsResponse, holdings []Holding, rate float64) PortfolioResponse {
+	bySymbol := make(map[string]MarketQuote, len(quotes.Items))
+	for _, q := range quotes.Items {
+		bySymbol[strings.ToUpper(q.Symbol)] = q
+	}
+
+	res := PortfolioResponse{FetchedAt: quotes.FetchedAt, Positions: make([]PortfolioPosition, 0, len(holdings))}
+	prevTotal := 0.0
+	for _, h := range holdings {
+		sym := strings.ToUpper(strings.TrimSpace(h.Symbol))
+		q, ok := bySymbol[sym]
+		p := PortfolioPosition{
+			Symbol:    sym,
+			Kind:      q.Kind,
+			Name:      q.Name,
+			Quantity:  h.Quantity,
+			Price:     q.PriceUSD * rate,
+			CostBasis: h.CostBasis * rate,
+			Stale:     !ok || q.PriceUSD <= 0,
+		}
+		p.Value = p.Quantity * p.Price
+		p.Cost = p.Quantity * p.CostBasis
+		if !p.Stale {
+			p.Gain = p.Value - p.Cost
+			p.GainPct = pct(p.Gain, p.Cost)
+			prev := p.Value / (1 + q.ChangePct24h/100)
+			p.DayChange = p.Value - prev
+			p.DayChangePct = q.ChangePct24h
+			prevTotal += prev
+			res.TotalGain += p.Gain
+			res.DayChange += p.DayChange
+		}
+		res.TotalValue += p.Value
+		res.TotalCost += p.Cost
+		res.Positions = append(res.Positions, p)
+	}
+	res.TotalGainPct = pct(res.TotalGain, res.TotalCost)
+	res.DayChangePct = pct(res.DayChange, prevTotal)
+	return res
+}
+
+func pct(part, whole float64) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return part / whole * 100
+}
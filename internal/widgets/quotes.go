@@ -0,0 +1,137 @@
+package widgets
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Quote sources for the quote widget.
+const (
+	QuoteSourceBundled  = "bundled"  // quotes shipped with the binary
+	QuoteSourceQuotable = "quotable" // api.quotable.io
+	QuoteSourceCustom   = "custom"   // quotes added by the admin
+)
+
+// bundledQuotesJSON is the offline quote list, in English and Chinese.
+//
+//go:embed data/quotes.json
+var bundledQuotesJSON []byte
+
+// Quote is the quote of the day.
+type Quote struct {
+	Text   string `json:"text"`
+	Author string `json:"author,omitempty"`
+	Source string `json:"source"`
+	Date   string `json:"date"` // YYYY-MM-DD the quote was picked for
+}
+
+// NormalizeQuoteSource maps s to a known source, defaulting to bundled.
+func NormalizeQuoteSource(s string) string {
+	switch s = strings.ToLower(strings.TrimSpace(s)); s {
+	case QuoteSourceQuotable, QuoteSourceCustom:
+		return s
+	default:
+		return QuoteSourceBundled
+	}
+}
+
+var bundledQuotes = struct {
+	once  sync.Once
+	items []struct {
+		Text   string `json:"text"`
+		Author string `json:"author"`
+		Lang   string `json:"lang"`
+	}
+}{}
+
+// BundledQuotes returns the bundled quotes in lang ("zh" or "en").
+func BundledQuotes(lang string) []Quote {
+	bundledQuotes.once.Do(func() {
+		_ = json.Unmarshal(bundledQuotesJSON, &bundledQuotes.items)
+	})
+	if lang != "zh" {
+		lang = "en"
+	}
+	out := []Quote{}
+	for _, q := range bundledQuotes.items {
+		if q.Lang == lang {
+			out = append(out, Quote{Text: q.Text, Author: q.Author, Source: QuoteSourceBundled})
+		}
+	}
+	return out
+}
+
+// DailyQuote picks the quote for day from quotes. The pick only changes when
+// the date does, so every visitor sees the same quote all day.
+func DailyQuote(quotes []Quote, day time.Time) (Quote, bool) {
+	if len(quotes) == 0 {
+		return Quote{}, false
+	}
+	date := day.Format("2006-01-02")
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(date))
+	q := quotes[int(h.Sum32()%uint32(len(quotes)))]
+	q.Date = date
+	return q, true
+}
+
+var quotableCache = struct {
+	mu    sync.Mutex
+	date  string
+	quote Quote
+}{}
+
+// FetchQuotableQuote returns a random quote from quotable.io, fetched once per
+// date and served from memory for the rest of the day.
+func FetchQuotableQuote(ctx context.Context, day time.Time) (Quote, error) {
+	date := day.Format("2006-01-02")
+	quotableCache.mu.Lock()
+	if quotableCache.date == date {
+		q := quotableCache.quote
+		quotableCache.mu.Unlock()
+		return q, nil
+	}
+	quotableCache.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoints().Quotable+"/random", nil)
+	if err != nil {
+		return Quote{}, err
+	}
+	req.Header.Set("User-Agent", "Hearth/0.1")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Quote{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return Quote{}, fmt.Errorf("quotable: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var payload struct {
+		Content string `json:"content"`
+		Author  string `json:"author"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&payload); err != nil {
+		return Quote{}, err
+	}
+	if strings.TrimSpace(payload.Content) == "" {
+		return Quote{}, fmt.Errorf("quotable: empty quote")
+	}
+	q := Quote{Text: strings.TrimSpace(payload.Content), Author: strings.TrimSpace(payload.Author), Source: QuoteSourceQuotable, Date: date}
+
+	quotableCache.mu.Lock()
+	quotableCache.date, quotableCache.quote = date, q
+	quotableCache.mu.Unlock()
+	return q, nil
+}
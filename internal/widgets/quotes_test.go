@@ -0,0 +1,46 @@
+package widgets
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBundledQuotes(t *testing.T) {
+	for _, lang := range []string{"en", "zh"} {
+		qs := BundledQuotes(lang)
+		if len(qs) < 10 {
+			t.Fatalf("%s: expected bundled quotes, got %d", lang, len(qs))
+		}
+		for _, q := range qs {
+			if q.Text == "" || q.Source != QuoteSourceBundled {
+				t.Fatalf("%s: bad quote %+v", lang, q)
+			}
+		}
+	}
+	if len(BundledQuotes("fr")) != len(BundledQuotes("en")) {
+		t.Fatalf("expected unknown languages to get the English quotes")
+	}
+}
+
+func TestDailyQuote(t *testing.T) {
+	qs := BundledQuotes("en")
+	morning := time.Date(2026, 3, 14, 8, 0, 0, 0, time.UTC)
+	a, ok := DailyQuote(qs, morning)
+	if !ok || a.Date != "2026-03-14" {
+		t.Fatalf("unexpected quote %+v", a)
+	}
+	if b, _ := DailyQuote(qs, morning.Add(12*time.Hour)); b != a {
+		t.Fatalf("expected the same quote all day, got %+v and %+v", a, b)
+	}
+	changed := false
+	for d := 1; d <= 7 && !changed; d++ {
+		q, _ := DailyQuote(qs, morning.AddDate(0, 0, d))
+		changed = q.Text != a.Text
+	}
+	if !changed {
+		t.Fatalf("expected the quote to rotate within a week")
+	}
+	if _, ok := DailyQuote(nil, morning); ok {
+		t.Fatalf("expected no quote from an empty list")
+	}
+}
@@ -0,0 +1,99 @@
+package widgets
+
+import (
+	"strings"
+
+	"github.com/gosimple/unidecode"
+)
+
+// cjkPlaceNames romanizes common Chinese city names for the geocoders, which
+// search much better in Latin script. It holds the conventional readings that
+// character-by-character transliteration gets wrong: characters read
+// differently in place names ("重庆" is Chongqing, "厦门" Xiamen), names
+// that are not pinyin ("香港" is Hong Kong) and foreign cities ("东京" is
+// Tokyo).
+var cjkPlaceNames = map[string]string{
+	// Direct-controlled municipalities
+	"北京": "beijing", "上海": "shanghai", "天津": "tianjin", "重庆": "chongqing",
+	// Provincial capitals and major cities
+	"长春": "changchun", "哈尔滨": "harbin", "沈阳": "shenyang", "大连": "dalian",
+	"石家庄": "shijiazhuang", "太原": "taiyuan", "呼和浩特": "hohhot",
+	"济南": "jinan", "青岛": "qingdao", "郑州": "zhengzhou", "武汉": "wuhan",
+	"长沙": "changsha", "南京": "nanjing", "杭州": "hangzhou", "合肥": "hefei",
+	"南昌": "nanchang", "福州": "fuzhou", "厦门": "xiamen", "广州": "guangzhou",
+	"深圳": "shenzhen", "东莞": "dongguan", "珠海": "zhuhai", "佛山": "foshan",
+	"南宁": "nanning", "海口": "haikou", "成都": "chengdu", "贵阳": "guiyang",
+	"昆明": "kunming", "拉萨": "lhasa", "西安": "xian", "兰州": "lanzhou",
+	"西宁": "xining", "银川": "yinchuan", "乌鲁木齐": "urumqi",
+	// Other major cities
+	"苏州": "suzhou", "无锡": "wuxi", "常州": "changzhou", "宁波": "ningbo",
+	"温州": "wenzhou", "嘉兴": "jiaxing", "烟台": "yantai", "潍坊": "weifang",
+	"淄博": "zibo", "威海": "weihai", "洛阳": "luoyang", "开封": "kaifeng",
+	"唐山": "tangshan", "秦皇岛": "qinhuangdao", "包头": "baotou",
+	"鞍山": "anshan", "抚顺": "fushun", "吉林": "jilin", "齐齐哈尔": "qiqihar",
+	"大庆": "daqing", "牡丹江": "mudanjiang", "佳木斯": "jiamusi",
+	"徐州": "xuzhou", "连云港": "lianyungang", "扬州": "yangzhou", "镇江": "zhenjiang",
+	"绍兴": "shaoxing", "台州": "taizhou", "金华": "jinhua", "衢州": "quzhou",
+	"芜湖": "wuhu", "蚌埠": "bengbu", "马鞍山": "maanshan", "安庆": "anqing",
+	"泉州": "quanzhou", "漳州": "zhangzhou", "莆田": "putian", "三明": "sanming",
+	"九江": "jiujiang", "景德镇": "jingdezhen", "赣州": "ganzhou",
+	"汕头": "shantou", "惠州": "huizhou", "中山": "zhongshan", "江门": "jiangmen",
+	"桂林": "guilin", "柳州": "liuzhou", "北海": "beihai",
+	"三亚": "sanya", "绵阳": "mianyang", "宜宾": "yibin", "泸州": "luzhou",
+	"遵义": "zunyi", "曲靖": "qujing", "玉溪": "yuxi", "咸阳": "xianyang",
+	"宝鸡": "baoji", "延安": "yanan", "天水": "tianshui", "白银": "baiyin",
+	// Hong Kong, Macau, Taiwan
+	"香港": "hong kong", "澳门": "macau", "台北": "taipei", "高雄": "kaohsiung",
+	"台中": "taichung", "台南": "tainan", "新北": "new taipei",
+	// International cities
+	"纽约": "new york", "洛杉矶": "los angeles", "旧金山": "san francisco",
+	"芝加哥": "chicago", "伦敦": "london", "巴黎": "paris", "东京": "tokyo",
+	"首尔": "seoul", "新加坡": "singapore", "悉尼": "sydney", "墨尔本": "melbourne",
+	"温哥华": "vancouver", "多伦多": "toronto", "柏林": "berlin", "莫斯科": "moscow",
+	"迪拜": "dubai", "曼谷": "bangkok", "吉隆坡": "kuala lumpur",
+}
+
+// cjkAdminSuffixes are dropped from a name that does not romanize as is:
+// "杭州市" is searched as "杭州".
+var cjkAdminSuffixes = []string{"特别行政区", "自治州", "地区", "市", "省", "县", "区"}
+
+// romanizeCJK returns a Latin-script name for a CJK place name, which the
+// geocoders search much better, or "" when q is not CJK. The GeoNames index,
+// when loaded, and cjkPlaceNames know the conventional names ("乌鲁木齐" is
+// Urumqi); other names are transliterated character by character, which
+// spells Chinese names in toneless pinyin.
+func romanizeCJK(q string) string {
+	q = strings.TrimSpace(q)
+	if !containsCJK(q) {
+		return ""
+	}
+	candidates := []string{q}
+	for _, suffix := range cjkAdminSuffixes {
+		if base := strings.TrimSuffix(q, suffix); base != q && base != "" {
+			candidates = append(candidates, base)
+			break
+		}
+	}
+	idx := geoNames.Load()
+	for _, c := range candidates {
+		if idx != nil {
+			if name := idx.romanize(c); name != "" {
+				return name
+			}
+		}
+		if name, ok := cjkPlaceNames[c]; ok {
+			return name
+		}
+	}
+	return transliterate(candidates[len(candidates)-1])
+}
+
+// transliterate spells s in Latin script, joining the syllables of each word:
+// "杭州" becomes "hangzhou".
+func transliterate(s string) string {
+	words := strings.Fields(s)
+	for i, w := range words {
+		words[i] = strings.Join(strings.Fields(strings.ToLower(unidecode.Unidecode(w))), "")
+	}
+	return strings.Join(words, " ")
+}
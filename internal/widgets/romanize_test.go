@@ -0,0 +1,26 @@
+package widgets
+
+import "testing"
+
+func TestRomanizeCJK(t *testing.T) {
+	DisableGeoNames()
+	// Without the GeoNames index the table supplies the conventional names
+	// and everything else is transliterated.
+	for q, want := range map[string]string{
+		"重庆":      "chongqing",
+		"厦门市":     "xiamen",
+		"蚌埠":      "bengbu",
+		"东京":      "tokyo",
+		"纽约":      "new york",
+		"香港特别行政区": "hong kong",
+		"株洲":      "zhuzhou",
+		"景德镇市":    "jingdezhen",
+		"黔东南自治州":  "qiandongnan",
+		"Berlin":  "",
+		"":        "",
+	} {
+		if got := romanizeCJK(q); got != want {
+			t.Errorf("romanizeCJK(%q) = %q, want %q", q, got, want)
+		}
+	}
+}
@@ -26,7 +26,7 @@ func ResolveTimezone(ctx context.Context, lat, lon string) (string, error) {
 	q.Set("current", "temperature_2m")
 	q.Set("timezone", "auto")
 
-	endpoint := "https://api.open-meteo.com/v1/forecast?" + q.Encode()
+	endpoint := endpoints().OpenMeteo + "/v1/forecast?" + q.Encode()
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
 		return "", err
@@ -0,0 +1,60 @@
+package widgets
+
+import "math"
+
+// Unit systems accepted by ApplyUnits.
+const (
+	UnitsMetric   = "metric"
+	UnitsImperial = "imperial"
+)
+
+// NormalizeUnits maps any input to a supported unit system (metric by default).
+func NormalizeUnits(system string) string {
+	if system == UnitsImperial {
+		return UnitsImperial
+	}
+	return UnitsMetric
+}
+
+// ApplyUnits fills the unit-neutral display fields of wx for the given unit
+// system. The canonical metric fields (temperatureC, windSpeedKph, ...) are
+// left untouched so older clients keep working.
+func ApplyUnits(wx Weather, system string) Weather {
+	system = NormalizeUnits(system)
+	temp := func(c float64) float64 { return c }
+	wind := func(kph float64) float64 { return kph }
+	wx.Units = system
+	wx.TempUnit = "°C"
+	wx.WindUnit = "km/h"
+	if system == UnitsImperial {
+		temp = func(c float64) float64 { return round1(c*9/5 + 32) }
+		wind = func(kph float64) float64 { return round1(kph / 1.609344) }
+		wx.TempUnit = "°F"
+		wx.WindUnit = "mph"
+	}
+
+	wx.Temp = temp(wx.Temperature)
+	wx.Wind = wind(wx.WindSpeed)
+	if wx.Daily != nil {
+		daily := make([]DailyForecast, len(wx.Daily))
+		for i, d := range wx.Daily {
+			d.TempMax = temp(d.TempMaxC)
+			d.TempMin = temp(d.TempMinC)
+			daily[i] = d
+		}
+		wx.Daily = daily
+	}
+	if wx.Hourly != nil {
+		hourly := make([]HourlyForecast, len(wx.Hourly))
+		for i, h := range wx.Hourly {
+			h.Temp = temp(h.TempC)
+			hourly[i] = h
+		}
+		wx.Hourly = hourly
+	}
+	return wx
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
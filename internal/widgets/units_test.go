@@ -0,0 +1,32 @@
+package widgets
+
+import "testing"
+
+func TestApplyUnitsImperial(t *testing.T) {
+	wx := Weather{
+		Temperature: 20,
+		WindSpeed:   16.09344,
+		Daily:       []DailyForecast{{TempMaxC: 30, TempMinC: -10}},
+		Hourly:      []HourlyForecast{{TempC: 0}},
+	}
+	got := ApplyUnits(wx, UnitsImperial)
+	if got.Temp != 68 || got.TempUnit != "°F" {
+		t.Fatalf("temperature: got %v %s", got.Temp, got.TempUnit)
+	}
+	if got.Wind != 10 || got.WindUnit != "mph" {
+		t.Fatalf("wind: got %v %s", got.Wind, got.WindUnit)
+	}
+	if got.Daily[0].TempMax != 86 || got.Daily[0].TempMin != 14 {
+		t.Fatalf("daily: got %+v", got.Daily[0])
+	}
+	if got.Hourly[0].Temp != 32 {
+		t.Fatalf("hourly: got %v", got.Hourly[0].Temp)
+	}
+	if got.Temperature != 20 || wx.Daily[0].TempMax != 0 {
+		t.Fatalf("canonical fields and input must be left untouched")
+	}
+
+	if m := ApplyUnits(wx, "bogus"); m.Units != UnitsMetric || m.Temp != 20 {
+		t.Fatalf("unknown systems should fall back to metric, got %+v", m)
+	}
+}
@@ -0,0 +1,243 @@
+package widgets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WeatherAlert is a severe weather warning affecting a location.
+type WeatherAlert struct {
+	ID       string `json:"id"`
+	Event    string `json:"event"`
+	Severity string `json:"severity"` // minor|moderate|severe|extreme
+	Headline string `json:"headline"`
+	Area     string `json:"area,omitempty"`
+	Onset    int64  `json:"onset"`   // unix seconds, 0 when unknown
+	Expires  int64  `json:"expires"` // unix seconds, 0 when unknown
+	Source   string `json:"source"`
+}
+
+// meteoAlarmFeeds maps ISO country codes to MeteoAlarm feed slugs.
+// MeteoAlarm only covers EUMETNET members; other countries have no alerts.
+var meteoAlarmFeeds = map[string]string{
+	"AT": "austria", "BA": "bosnia-herzegovina", "BE": "belgium", "BG": "bulgaria",
+	"CH": "switzerland", "CY": "cyprus", "CZ": "czechia", "DE": "germany",
+	"DK": "denmark", "EE": "estonia", "ES": "spain", "FI": "finland",
+	"FR": "france", "GB": "united-kingdom", "GR": "greece", "HR": "croatia",
+	"HU": "hungary", "IE": "ireland", "IL": "israel", "IS": "iceland",
+	"IT": "italy", "LT": "lithuania", "LU": "luxembourg", "LV": "latvia",
+	"MD": "moldova", "ME": "montenegro", "MK": "republic-of-north-macedonia", "MT": "malta",
+	"NL": "netherlands", "NO": "norway", "PL": "poland", "PT": "portugal",
+	"RO": "romania", "RS": "serbia", "SE": "sweden", "SI": "slovenia",
+	"SK": "slovakia", "UA": "ukraine",
+}
+
+var weatherAlertsCache = struct {
+	mu    sync.Mutex
+	items map[string]struct {
+		FetchedAt int64
+		List      []WeatherAlert
+	}
+}{
+	items: map[string]struct {
+		FetchedAt int64
+		List      []WeatherAlert
+	}{},
+}
+
+var alertSeverityRank = map[string]int{"minor": 1, "moderate": 2, "severe": 3, "extreme": 4}
+
+// FetchWeatherAlerts returns active MeteoAlarm warnings for a country, narrowed to
+// warnings whose area mentions one of the given area hints (city, region).
+// Countries outside MeteoAlarm coverage return an empty list without error.
+func FetchWeatherAlerts(ctx context.Context, countryCode string, areaHints ...string) ([]WeatherAlert, error) {
+	cc := strings.ToUpper(strings.TrimSpace(countryCode))
+	slug, ok := meteoAlarmFeeds[cc]
+	if !ok {
+		return []WeatherAlert{}, nil
+	}
+
+	all, err := fetchMeteoAlarmFeed(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	hints := make([]string, 0, len(areaHints))
+	for _, h := range areaHints {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if h != "" {
+			hints = append(hints, h)
+		}
+	}
+
+	now := time.Now().Unix()
+	out := make([]WeatherAlert, 0, len(all))
+	for _, a := range all {
+		if a.Expires > 0 && a.Expires < now {
+			continue
+		}
+		if len(hints) > 0 {
+			area := strings.ToLower(a.Area)
+			matched := false
+			for _, h := range hints {
+				if strings.Contains(area, h) {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				continue
+			}
+		}
+		out = append(out, a)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		ri, rj := alertSeverityRank[out[i].Severity], alertSeverityRank[out[j].Severity]
+		if ri != rj {
+			return ri > rj
+		}
+		return out[i].Onset < out[j].Onset
+	})
+	return out, nil
+}
+
+func fetchMeteoAlarmFeed(ctx context.Context, slug string) ([]WeatherAlert, error) {
+	const ttl = 15 * time.Minute
+	const maxStale = 6 * time.Hour
+
+	weatherAlertsCache.mu.Lock()
+	cached, hasCached := weatherAlertsCache.items[slug]
+	weatherAlertsCache.mu.Unlock()
+	if hasCached {
+		age := time.Since(time.Unix(cached.FetchedAt, 0))
+		if cached.FetchedAt > 0 && age >= 0 && age < ttl {
+			out := make([]WeatherAlert, len(cached.List))
+			copy(out, cached.List)
+			return out, nil
+		}
+	}
+	staleOK := func() ([]WeatherAlert, bool) {
+		if !hasCached {
+			return nil, false
+		}
+		age := time.Since(time.Unix(cached.FetchedAt, 0))
+		if cached.FetchedAt <= 0 || age < 0 || age >= maxStale {
+			return nil, false
+		}
+		out := make([]WeatherAlert, len(cached.List))
+		copy(out, cached.List)
+		return out, true
+	}
+
+	endpoint := endpoints().MeteoAlarm + "/api/v1/warnings/feeds-" + slug
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Hearth/0.1")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		if out, ok := staleOK(); ok {
+			return out, nil
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if out, ok := staleOK(); ok {
+			return out, nil
+		}
+		return nil, fmt.Errorf("meteoalarm: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var payload struct {
+		Warnings []struct {
+			UUID  string `json:"uuid"`
+			Alert struct {
+				Identifier string `json:"identifier"`
+				Info       []struct {
+					Language string `json:"language"`
+					Event    string `json:"event"`
+					Severity string `json:"severity"`
+					Headline string `json:"headline"`
+					Onset    string `json:"onset"`
+					Expires  string `json:"expires"`
+					Area     []struct {
+						AreaDesc string `json:"areaDesc"`
+					} `json:"area"`
+				} `json:"info"`
+			} `json:"alert"`
+		} `json:"warnings"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 8<<20)).Decode(&payload); err != nil {
+		return nil, err
+	}
+
+	out := make([]WeatherAlert, 0, len(payload.Warnings))
+	for _, w := range payload.Warnings {
+		if len(w.Alert.Info) == 0 {
+			continue
+		}
+		// Prefer the English info block; feeds usually carry one per language.
+		info := w.Alert.Info[0]
+		for _, in := range w.Alert.Info {
+			if strings.HasPrefix(strings.ToLower(in.Language), "en") {
+				info = in
+				break
+			}
+		}
+		areas := make([]string, 0, len(info.Area))
+		for _, a := range info.Area {
+			if d := strings.TrimSpace(a.AreaDesc); d != "" {
+				areas = append(areas, d)
+			}
+		}
+		id := strings.TrimSpace(w.Alert.Identifier)
+		if id == "" {
+			id = strings.TrimSpace(w.UUID)
+		}
+		out = append(out, WeatherAlert{
+			ID:       id,
+			Event:    strings.TrimSpace(info.Event),
+			Severity: strings.ToLower(strings.TrimSpace(info.Severity)),
+			Headline: strings.TrimSpace(info.Headline),
+			Area:     strings.Join(areas, ", "),
+			Onset:    parseAlertTime(info.Onset),
+			Expires:  parseAlertTime(info.Expires),
+			Source:   "meteoalarm",
+		})
+	}
+
+	weatherAlertsCache.mu.Lock()
+	weatherAlertsCache.items[slug] = struct {
+		FetchedAt int64
+		List      []WeatherAlert
+	}{FetchedAt: time.Now().Unix(), List: out}
+	weatherAlertsCache.mu.Unlock()
+
+	res := make([]WeatherAlert, len(out))
+	copy(res, out)
+	return res, nil
+}
+
+func parseAlertTime(s string) int64 {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return 0
+	}
+	return t.Unix()
+}
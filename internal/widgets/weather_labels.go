@@ -0,0 +1,60 @@
+package widgets
+
+import (
+	"strconv"
+
+	"github.com/morezhou/hearth/internal/i18n"
+)
+
+// weatherCodeKey is the i18n key describing a WMO weather code, or "" for
+// codes without one.
+func weatherCodeKey(code int) string {
+	switch {
+	case code == 0:
+		return "weather.clear"
+	case code == 1 || code == 2:
+		return "weather.partlyCloudy"
+	case code == 3:
+		return "weather.overcast"
+	case code == 45 || code == 48:
+		return "weather.fog"
+	case (code >= 51 && code <= 57) || (code >= 61 && code <= 67) || (code >= 80 && code <= 82):
+		return "weather.rain"
+	case (code >= 71 && code <= 77) || (code >= 85 && code <= 86):
+		return "weather.snow"
+	case code >= 95:
+		return "weather.thunderstorm"
+	}
+	return ""
+}
+
+// WeatherLabel describes a WMO weather code in locale.
+func WeatherLabel(code int, locale string) string {
+	if key := weatherCodeKey(code); key != "" {
+		return i18n.T(locale, key)
+	}
+	return i18n.T(locale, "weather.code", "code", strconv.Itoa(code))
+}
+
+// LocalizeWeather returns a copy of wx with the weather code labels set for
+// locale. Like ApplyUnits it runs per request, after the cache.
+func LocalizeWeather(wx Weather, locale string) Weather {
+	wx.Label = WeatherLabel(wx.WeatherCode, locale)
+	if wx.Daily != nil {
+		daily := make([]DailyForecast, len(wx.Daily))
+		for i, d := range wx.Daily {
+			d.Label = WeatherLabel(d.Code, locale)
+			daily[i] = d
+		}
+		wx.Daily = daily
+	}
+	if wx.Hourly != nil {
+		hourly := make([]HourlyForecast, len(wx.Hourly))
+		for i, h := range wx.Hourly {
+			h.Label = WeatherLabel(h.Code, locale)
+			hourly[i] = h
+		}
+		wx.Hourly = hourly
+	}
+	return wx
+}
@@ -0,0 +1,36 @@
+package widgets
+
+import "testing"
+
+func TestLocalizeWeather(t *testing.T) {
+	wx := Weather{
+		WeatherCode: 61,
+		Daily:       []DailyForecast{{Code: 0}, {Code: 99}},
+		Hourly:      []HourlyForecast{{Code: 3}},
+	}
+	got := LocalizeWeather(wx, "de-AT")
+	if got.Label != "Regen" || got.Daily[0].Label != "Klar" || got.Daily[1].Label != "Gewitter" || got.Hourly[0].Label != "Bedeckt" {
+		t.Fatalf("unexpected labels: %+v", got)
+	}
+	if wx.Daily[0].Label != "" {
+		t.Fatalf("input must be left untouched")
+	}
+	if l := WeatherLabel(4, "zh"); l != "天气码 4" {
+		t.Fatalf("unknown code: got %q", l)
+	}
+	if l := WeatherLabel(45, "sv"); l != "Fog" {
+		t.Fatalf("expected the English fallback, got %q", l)
+	}
+}
+
+func TestHolidayDisplayNameLanguages(t *testing.T) {
+	if got := holidayDisplayName("DE", "Christmas Day", "Erster Weihnachtstag", "de-CH"); got != "Erster Weihnachtstag" {
+		t.Fatalf("got %q", got)
+	}
+	if got := holidayDisplayName("DE", "Christmas Day", "Erster Weihnachtstag", "fr"); got != "Christmas Day" {
+		t.Fatalf("got %q", got)
+	}
+	if got := holidayDisplayName("CN", "New Year's Day", "元旦", "zh-Hant-TW"); got != "元旦" {
+		t.Fatalf("got %q", got)
+	}
+}
@@ -0,0 +1,187 @@
+package widgets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// MetNoProvider uses the Norwegian Meteorological Institute locationforecast
+// API. It needs no key, but its terms require an identifying User-Agent.
+type MetNoProvider struct{}
+
+func (MetNoProvider) Name() string { return WeatherProviderMetNo }
+
+func (p MetNoProvider) Fetch(ctx context.Context, lat, lon, city string, opts WeatherOptions) (Weather, error) {
+	return cachedProviderFetch(WeatherProviderMetNo, lat, lon, city, opts, func() (Weather, error) {
+		return p.fetch(ctx, lat, lon, city, opts.Normalized().ForecastDays)
+	})
+}
+
+func (MetNoProvider) fetch(ctx context.Context, lat, lon, city string, days int) (Weather, error) {
+	q := url.Values{}
+	q.Set("lat", lat)
+	q.Set("lon", lon)
+	endpoint := endpoints().MetNo + "/weatherapi/locationforecast/2.0/compact?" + q.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return Weather{}, err
+	}
+	req.Header.Set("User-Agent", "Hearth/0.1 github.com/cailurus/Hearth")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Weather{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return Weather{}, fmt.Errorf("metno: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	type summary struct {
+		SymbolCode string `json:"symbol_code"`
+	}
+	var payload struct {
+		Properties struct {
+			Timeseries []struct {
+				Time string `json:"time"`
+				Data struct {
+					Instant struct {
+						Details struct {
+							AirTemperature   float64 `json:"air_temperature"`
+							WindSpeed        float64 `json:"wind_speed"` // m/s
+							RelativeHumidity float64 `json:"relative_humidity"`
+						} `json:"details"`
+					} `json:"instant"`
+					Next1Hours *struct {
+						Summary summary `json:"summary"`
+					} `json:"next_1_hours"`
+					Next6Hours *struct {
+						Summary summary `json:"summary"`
+						Details struct {
+							AirTemperatureMax *float64 `json:"air_temperature_max"`
+							AirTemperatureMin *float64 `json:"air_temperature_min"`
+						} `json:"details"`
+					} `json:"next_6_hours"`
+				} `json:"data"`
+			} `json:"timeseries"`
+		} `json:"properties"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 8<<20)).Decode(&payload); err != nil {
+		return Weather{}, err
+	}
+	series := payload.Properties.Timeseries
+	if len(series) == 0 {
+		return Weather{}, fmt.Errorf("metno: empty timeseries")
+	}
+
+	// Timestamps are UTC; days are grouped by UTC date.
+	acc := newDailyAccumulator()
+	hourly := make([]HourlyForecast, 0, hourlyForecastHours)
+	for _, ts := range series {
+		t, err := time.Parse(time.RFC3339, ts.Time)
+		if err != nil {
+			continue
+		}
+		d := ts.Data
+		symbol := ""
+		if d.Next1Hours != nil {
+			symbol = d.Next1Hours.Summary.SymbolCode
+		} else if d.Next6Hours != nil {
+			symbol = d.Next6Hours.Summary.SymbolCode
+		}
+		code := metNoSymbolToWMO(symbol)
+		temp := d.Instant.Details.AirTemperature
+		minC, maxC := temp, temp
+		if d.Next6Hours != nil {
+			if v := d.Next6Hours.Details.AirTemperatureMin; v != nil {
+				minC = *v
+			}
+			if v := d.Next6Hours.Details.AirTemperatureMax; v != nil {
+				maxC = *v
+			}
+		}
+		acc.add(t.UTC().Format("2006-01-02"), code, minC, maxC, 0)
+		if d.Next1Hours != nil && len(hourly) < hourlyForecastHours {
+			hourly = append(hourly, HourlyForecast{
+				Time:        t.UTC().Format("2006-01-02T15:04"),
+				Code:        code,
+				TempC:       temp,
+				HumidityPct: int(d.Instant.Details.RelativeHumidity + 0.5),
+			})
+		}
+	}
+
+	now := series[0].Data
+	currentSymbol := ""
+	if now.Next1Hours != nil {
+		currentSymbol = now.Next1Hours.Summary.SymbolCode
+	}
+	return Weather{
+		City:        city,
+		Temperature: now.Instant.Details.AirTemperature,
+		WeatherCode: metNoSymbolToWMO(currentSymbol),
+		WindSpeed:   round1(now.Instant.Details.WindSpeed * 3.6),
+		Daily:       acc.list(days),
+		Hourly:      hourly,
+	}, nil
+}
+
+// metNoSymbolToWMO maps met.no symbol codes (e.g. "lightrainshowers_day")
+// onto WMO weather codes.
+func metNoSymbolToWMO(symbol string) int {
+	s := symbol
+	if i := strings.IndexByte(s, '_'); i >= 0 {
+		s = s[:i]
+	}
+	if strings.Contains(s, "thunder") {
+		return 95
+	}
+	switch s {
+	case "clearsky":
+		return 0
+	case "fair":
+		return 1
+	case "partlycloudy":
+		return 2
+	case "cloudy":
+		return 3
+	case "fog":
+		return 45
+	case "lightrain":
+		return 61
+	case "rain":
+		return 63
+	case "heavyrain":
+		return 65
+	case "lightrainshowers":
+		return 80
+	case "rainshowers":
+		return 81
+	case "heavyrainshowers":
+		return 82
+	case "lightsleet", "lightsleetshowers":
+		return 66
+	case "sleet", "heavysleet", "sleetshowers", "heavysleetshowers":
+		return 67
+	case "lightsnow":
+		return 71
+	case "snow":
+		return 73
+	case "heavysnow":
+		return 75
+	case "lightsnowshowers", "snowshowers":
+		return 85
+	case "heavysnowshowers":
+		return 86
+	default:
+		return 0
+	}
+}
@@ -25,33 +25,111 @@ func weatherCacheKey(lat, lon string) string {
 }
 
 type Weather struct {
-	City        string          `json:"city"`
-	Temperature float64         `json:"temperatureC"`
-	WeatherCode int             `json:"weatherCode"`
-	WindSpeed   float64         `json:"windSpeedKph"`
-	FetchedAt   int64           `json:"fetchedAt"`
-	Daily       []DailyForecast `json:"daily"`
+	City        string           `json:"city"`
+	Temperature float64          `json:"temperatureC"`
+	WeatherCode int              `json:"weatherCode"`
+	Label       string           `json:"label,omitempty"` // WeatherCode described in the request locale
+	WindSpeed   float64          `json:"windSpeedKph"`
+	FetchedAt   int64            `json:"fetchedAt"`
+	Daily       []DailyForecast  `json:"daily"`
+	Hourly      []HourlyForecast `json:"hourly,omitempty"`
+	Alerts      []WeatherAlert   `json:"alerts"`
+
+	Provider     string `json:"provider,omitempty"`     // provider that produced the data
+	FailoverFrom string `json:"failoverFrom,omitempty"` // preferred provider that failed, if any
+
+	// Display values in the configured unit system; see ApplyUnits.
+	Units    string  `json:"units,omitempty"` // metric|imperial
+	Temp     float64 `json:"temperature"`
+	TempUnit string  `json:"temperatureUnit,omitempty"`
+	Wind     float64 `json:"windSpeed"`
+	WindUnit string  `json:"windSpeedUnit,omitempty"`
 }
 
 type DailyForecast struct {
-	Date     string  `json:"date"`
-	Code     int     `json:"weatherCode"`
-	TempMaxC float64 `json:"tempMaxC"`
-	TempMinC float64 `json:"tempMinC"`
+	Date          string  `json:"date"`
+	Code          int     `json:"weatherCode"`
+	Label         string  `json:"label,omitempty"`
+	TempMaxC      float64 `json:"tempMaxC"`
+	TempMinC      float64 `json:"tempMinC"`
+	PrecipProbPct int     `json:"precipProbPct"`
+	TempMax       float64 `json:"tempMax"`
+	TempMin       float64 `json:"tempMin"`
+}
+
+// HourlyForecast is one hour of the intraday forecast (local time of the location).
+type HourlyForecast struct {
+	Time          string  `json:"time"` // YYYY-MM-DDTHH:MM
+	Code          int     `json:"weatherCode"`
+	Label         string  `json:"label,omitempty"`
+	TempC         float64 `json:"tempC"`
+	PrecipProbPct int     `json:"precipProbPct"`
+	HumidityPct   int     `json:"humidityPct"`
+	Temp          float64 `json:"temp"`
+}
+
+// hourlyForecastHours is how far ahead the hourly series reaches.
+const hourlyForecastHours = 48
+
+// Forecast length limits; Open-Meteo serves at most 16 days.
+const (
+	DefaultForecastDays = 7
+	MaxForecastDays     = 16
+)
+
+// WeatherOptions tunes forecast length and caching. Low-traffic installs can
+// raise the TTLs to call upstream less often. Zero values use the defaults.
+type WeatherOptions struct {
+	ForecastDays int           // upper bound; OpenWeatherMap only covers 5 days
+	FreshTTL     time.Duration // serve from cache without refetching
+	MaxStale     time.Duration // serve from cache when upstream fails
+}
+
+// DefaultWeatherOptions returns the built-in forecast length and cache TTLs.
+func DefaultWeatherOptions() WeatherOptions {
+	return WeatherOptions{
+		ForecastDays: DefaultForecastDays,
+		FreshTTL:     5 * time.Minute,
+		MaxStale:     2 * time.Hour,
+	}
+}
+
+// Normalized fills zero fields with defaults and clamps out-of-range values.
+func (o WeatherOptions) Normalized() WeatherOptions {
+	def := DefaultWeatherOptions()
+	if o.ForecastDays <= 0 {
+		o.ForecastDays = def.ForecastDays
+	}
+	if o.ForecastDays > MaxForecastDays {
+		o.ForecastDays = MaxForecastDays
+	}
+	if o.FreshTTL <= 0 {
+		o.FreshTTL = def.FreshTTL
+	}
+	if o.MaxStale <= 0 {
+		o.MaxStale = def.MaxStale
+	}
+	if o.MaxStale < o.FreshTTL {
+		o.MaxStale = o.FreshTTL
+	}
+	return o
 }
 
 // FetchOpenMeteo uses Open-Meteo current weather (no API key).
-func FetchOpenMeteo(ctx context.Context, lat, lon, city string) (Weather, error) {
+func FetchOpenMeteo(ctx context.Context, lat, lon, city string, opts WeatherOptions) (Weather, error) {
 	if lat == "" || lon == "" {
 		return Weather{}, errors.New("weather lat/lon not configured")
 	}
+	opts = opts.Normalized()
 
 	// Reduce repeated calls (frontend may request the same location multiple times).
 	// If we get rate-limited by Open-Meteo, fall back to a cached value when available.
-	const freshTTL = 5 * time.Minute
-	const maxStale = 2 * time.Hour
+	freshTTL := opts.FreshTTL
+	maxStale := opts.MaxStale
 	key := weatherCacheKey(lat, lon)
 	if key != "," {
+		// Different forecast lengths are different payloads.
+		key = fmt.Sprintf("%s|%d", key, opts.ForecastDays)
 		weatherCache.mu.Lock()
 		if cached, ok := weatherCache.items[key]; ok {
 			age := time.Since(time.Unix(cached.FetchedAt, 0))
@@ -71,11 +149,13 @@ func FetchOpenMeteo(ctx context.Context, lat, lon, city string) (Weather, error)
 	q.Set("latitude", lat)
 	q.Set("longitude", lon)
 	q.Set("current", "temperature_2m,weather_code,wind_speed_10m")
-	q.Set("daily", "weather_code,temperature_2m_max,temperature_2m_min")
-	q.Set("forecast_days", "7")
+	q.Set("daily", "weather_code,temperature_2m_max,temperature_2m_min,precipitation_probability_max")
+	q.Set("hourly", "temperature_2m,weather_code,precipitation_probability,relative_humidity_2m")
+	q.Set("forecast_days", fmt.Sprintf("%d", opts.ForecastDays))
+	q.Set("forecast_hours", fmt.Sprintf("%d", hourlyForecastHours))
 	q.Set("timezone", "auto")
 
-	endpoint := "https://api.open-meteo.com/v1/forecast?" + q.Encode()
+	endpoint := endpoints().OpenMeteo + "/v1/forecast?" + q.Encode()
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
 		return Weather{}, err
@@ -141,17 +221,25 @@ func FetchOpenMeteo(ctx context.Context, lat, lon, city string) (Weather, error)
 			WindSpeed   float64 `json:"wind_speed_10m"`
 		} `json:"current"`
 		Daily struct {
-			Time []string  `json:"time"`
-			Code []int     `json:"weather_code"`
-			MaxC []float64 `json:"temperature_2m_max"`
-			MinC []float64 `json:"temperature_2m_min"`
+			Time       []string  `json:"time"`
+			Code       []int     `json:"weather_code"`
+			MaxC       []float64 `json:"temperature_2m_max"`
+			MinC       []float64 `json:"temperature_2m_min"`
+			PrecipProb []*int    `json:"precipitation_probability_max"`
 		} `json:"daily"`
+		Hourly struct {
+			Time       []string  `json:"time"`
+			Code       []int     `json:"weather_code"`
+			TempC      []float64 `json:"temperature_2m"`
+			PrecipProb []*int    `json:"precipitation_probability"`
+			Humidity   []*int    `json:"relative_humidity_2m"`
+		} `json:"hourly"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
 		return Weather{}, err
 	}
 
-	daily := make([]DailyForecast, 0, 7)
+	daily := make([]DailyForecast, 0, opts.ForecastDays)
 	if len(payload.Daily.Time) > 0 {
 		n := len(payload.Daily.Time)
 		if len(payload.Daily.Code) < n {
@@ -165,10 +253,31 @@ func FetchOpenMeteo(ctx context.Context, lat, lon, city string) (Weather, error)
 		}
 		for i := 0; i < n; i++ {
 			daily = append(daily, DailyForecast{
-				Date:     payload.Daily.Time[i],
-				Code:     payload.Daily.Code[i],
-				TempMaxC: payload.Daily.MaxC[i],
-				TempMinC: payload.Daily.MinC[i],
+				Date:          payload.Daily.Time[i],
+				Code:          payload.Daily.Code[i],
+				TempMaxC:      payload.Daily.MaxC[i],
+				TempMinC:      payload.Daily.MinC[i],
+				PrecipProbPct: intAt(payload.Daily.PrecipProb, i),
+			})
+		}
+	}
+
+	hourly := make([]HourlyForecast, 0, hourlyForecastHours)
+	if len(payload.Hourly.Time) > 0 {
+		n := len(payload.Hourly.Time)
+		if len(payload.Hourly.Code) < n {
+			n = len(payload.Hourly.Code)
+		}
+		if len(payload.Hourly.TempC) < n {
+			n = len(payload.Hourly.TempC)
+		}
+		for i := 0; i < n; i++ {
+			hourly = append(hourly, HourlyForecast{
+				Time:          payload.Hourly.Time[i],
+				Code:          payload.Hourly.Code[i],
+				TempC:         payload.Hourly.TempC[i],
+				PrecipProbPct: intAt(payload.Hourly.PrecipProb, i),
+				HumidityPct:   intAt(payload.Hourly.Humidity, i),
 			})
 		}
 	}
@@ -180,6 +289,8 @@ func FetchOpenMeteo(ctx context.Context, lat, lon, city string) (Weather, error)
 		WindSpeed:   payload.Current.WindSpeed,
 		FetchedAt:   time.Now().Unix(),
 		Daily:       daily,
+		Hourly:      hourly,
+		Provider:    WeatherProviderOpenMeteo,
 	}
 	if key != "," {
 		weatherCache.mu.Lock()
@@ -188,3 +299,11 @@ func FetchOpenMeteo(ctx context.Context, lat, lon, city string) (Weather, error)
 	}
 	return w, nil
 }
+
+// intAt returns the value at i, treating missing entries and nulls as 0.
+func intAt(list []*int, i int) int {
+	if i < 0 || i >= len(list) || list[i] == nil {
+		return 0
+	}
+	return *list[i]
+}
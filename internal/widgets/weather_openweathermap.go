@@ -0,0 +1,167 @@
+package widgets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// OpenWeatherMapProvider uses the free OpenWeatherMap 2.5 current weather and
+// 5 day / 3 hour forecast APIs.
+type OpenWeatherMapProvider struct {
+	APIKey string
+}
+
+func (OpenWeatherMapProvider) Name() string { return WeatherProviderOpenWeatherMap }
+
+func (p OpenWeatherMapProvider) Fetch(ctx context.Context, lat, lon, city string, opts WeatherOptions) (Weather, error) {
+	return cachedProviderFetch(WeatherProviderOpenWeatherMap, lat, lon, city, opts, func() (Weather, error) {
+		return p.fetch(ctx, lat, lon, city, opts.Normalized().ForecastDays)
+	})
+}
+
+type owmCondition struct {
+	ID int `json:"id"`
+}
+
+func (p OpenWeatherMapProvider) get(ctx context.Context, path, lat, lon string, out any) error {
+	q := url.Values{}
+	q.Set("lat", lat)
+	q.Set("lon", lon)
+	q.Set("units", "metric")
+	q.Set("appid", p.APIKey)
+	endpoint := endpoints().OpenWeatherMap + path + "?" + q.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "Hearth/0.1")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("openweathermap: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 4<<20)).Decode(out)
+}
+
+func (p OpenWeatherMapProvider) fetch(ctx context.Context, lat, lon, city string, days int) (Weather, error) {
+	var current struct {
+		Weather []owmCondition `json:"weather"`
+		Main    struct {
+			Temp float64 `json:"temp"`
+		} `json:"main"`
+		Wind struct {
+			Speed float64 `json:"speed"` // m/s
+		} `json:"wind"`
+	}
+	if err := p.get(ctx, "/data/2.5/weather", lat, lon, &current); err != nil {
+		return Weather{}, err
+	}
+
+	var forecast struct {
+		List []struct {
+			Dt      int64          `json:"dt"`
+			Weather []owmCondition `json:"weather"`
+			Main    struct {
+				Temp     float64 `json:"temp"`
+				TempMin  float64 `json:"temp_min"`
+				TempMax  float64 `json:"temp_max"`
+				Humidity int     `json:"humidity"`
+			} `json:"main"`
+			Pop float64 `json:"pop"` // 0..1
+		} `json:"list"`
+		City struct {
+			Timezone int `json:"timezone"` // offset from UTC in seconds
+		} `json:"city"`
+	}
+	if err := p.get(ctx, "/data/2.5/forecast", lat, lon, &forecast); err != nil {
+		return Weather{}, err
+	}
+
+	loc := time.FixedZone("", forecast.City.Timezone)
+	acc := newDailyAccumulator()
+	hourly := make([]HourlyForecast, 0, hourlyForecastHours/3)
+	for _, it := range forecast.List {
+		t := time.Unix(it.Dt, 0).In(loc)
+		code := owmToWMO(firstCondition(it.Weather))
+		pop := int(math.Round(it.Pop * 100))
+		acc.add(t.Format("2006-01-02"), code, it.Main.TempMin, it.Main.TempMax, pop)
+		if len(hourly) < cap(hourly) {
+			hourly = append(hourly, HourlyForecast{
+				Time:          t.Format("2006-01-02T15:04"),
+				Code:          code,
+				TempC:         it.Main.Temp,
+				PrecipProbPct: pop,
+				HumidityPct:   it.Main.Humidity,
+			})
+		}
+	}
+
+	return Weather{
+		City:        city,
+		Temperature: current.Main.Temp,
+		WeatherCode: owmToWMO(firstCondition(current.Weather)),
+		WindSpeed:   round1(current.Wind.Speed * 3.6),
+		Daily:       acc.list(days),
+		Hourly:      hourly,
+	}, nil
+}
+
+func firstCondition(list []owmCondition) int {
+	if len(list) == 0 {
+		return 800
+	}
+	return list[0].ID
+}
+
+// owmToWMO maps OpenWeatherMap condition ids onto WMO weather codes.
+func owmToWMO(id int) int {
+	switch {
+	case id >= 200 && id < 300:
+		return 95
+	case id >= 300 && id < 400:
+		return 53
+	case id == 500:
+		return 61
+	case id == 501:
+		return 63
+	case id >= 502 && id <= 504:
+		return 65
+	case id == 511:
+		return 66
+	case id >= 520 && id < 600:
+		return 81
+	case id == 600:
+		return 71
+	case id == 601:
+		return 73
+	case id == 602:
+		return 75
+	case id >= 611 && id <= 616:
+		return 67
+	case id >= 620 && id < 700:
+		return 85
+	case id >= 700 && id < 800:
+		return 45
+	case id == 801:
+		return 1
+	case id == 802:
+		return 2
+	case id >= 803:
+		return 3
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,164 @@
+package widgets
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Weather provider names as stored in settings.
+const (
+	WeatherProviderOpenMeteo      = "open-meteo"
+	WeatherProviderOpenWeatherMap = "openweathermap"
+	WeatherProviderMetNo          = "metno"
+)
+
+// WeatherProvider fetches current conditions and a daily/hourly forecast for a
+// coordinate. Implementations report WMO weather codes and metric units so the
+// rest of the pipeline (units, alerts, frontend icons) stays provider-agnostic.
+type WeatherProvider interface {
+	Name() string
+	Fetch(ctx context.Context, lat, lon, city string, opts WeatherOptions) (Weather, error)
+}
+
+// OpenMeteoProvider is the keyless default provider.
+type OpenMeteoProvider struct{}
+
+func (OpenMeteoProvider) Name() string { return WeatherProviderOpenMeteo }
+
+func (OpenMeteoProvider) Fetch(ctx context.Context, lat, lon, city string, opts WeatherOptions) (Weather, error) {
+	return FetchOpenMeteo(ctx, lat, lon, city, opts)
+}
+
+// NewWeatherProvider returns the provider for a settings value. Providers that
+// need an API key fall back to Open-Meteo when none is configured.
+func NewWeatherProvider(name, apiKey string) WeatherProvider {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case WeatherProviderOpenWeatherMap:
+		if strings.TrimSpace(apiKey) != "" {
+			return OpenWeatherMapProvider{APIKey: strings.TrimSpace(apiKey)}
+		}
+	case WeatherProviderMetNo:
+		return MetNoProvider{}
+	}
+	return OpenMeteoProvider{}
+}
+
+// FetchWeather fetches from the preferred provider and fails over to
+// Open-Meteo when it errors or is rate limited. Weather.Provider names the
+// provider that actually answered and Weather.FailoverFrom the one that failed.
+func FetchWeather(ctx context.Context, p WeatherProvider, lat, lon, city string, opts WeatherOptions) (Weather, error) {
+	if p == nil || p.Name() == WeatherProviderOpenMeteo {
+		return FetchOpenMeteo(ctx, lat, lon, city, opts)
+	}
+	w, err := p.Fetch(ctx, lat, lon, city, opts)
+	if err == nil {
+		return w, nil
+	}
+	fb, fbErr := FetchOpenMeteo(ctx, lat, lon, city, opts)
+	if fbErr != nil {
+		return Weather{}, err
+	}
+	fb.FailoverFrom = p.Name()
+	return fb, nil
+}
+
+const providerMinFreshTTL = 10 * time.Minute
+
+var providerWeatherCache = struct {
+	mu    sync.Mutex
+	items map[string]Weather
+}{
+	items: map[string]Weather{},
+}
+
+// cachedProviderFetch applies the same fresh/stale policy as FetchOpenMeteo to
+// the other providers. Keyed providers usually have much tighter quotas, so
+// entries stay fresh for at least providerMinFreshTTL.
+func cachedProviderFetch(provider, lat, lon, city string, opts WeatherOptions, fetch func() (Weather, error)) (Weather, error) {
+	opts = opts.Normalized()
+	freshTTL := max(opts.FreshTTL, providerMinFreshTTL)
+	maxStale := max(opts.MaxStale, freshTTL)
+	key := fmt.Sprintf("%s|%s|%d", provider, weatherCacheKey(lat, lon), opts.ForecastDays)
+
+	withCity := func(w Weather) Weather {
+		if strings.TrimSpace(city) != "" {
+			w.City = city
+		}
+		return w
+	}
+
+	providerWeatherCache.mu.Lock()
+	cached, ok := providerWeatherCache.items[key]
+	providerWeatherCache.mu.Unlock()
+	if ok {
+		age := time.Since(time.Unix(cached.FetchedAt, 0))
+		if cached.FetchedAt > 0 && age >= 0 && age < freshTTL {
+			return withCity(cached), nil
+		}
+	}
+
+	w, err := fetch()
+	if err != nil {
+		if ok {
+			age := time.Since(time.Unix(cached.FetchedAt, 0))
+			if cached.FetchedAt > 0 && age >= 0 && age < maxStale {
+				return withCity(cached), nil
+			}
+		}
+		return Weather{}, err
+	}
+	w.Provider = provider
+	w.FetchedAt = time.Now().Unix()
+	providerWeatherCache.mu.Lock()
+	providerWeatherCache.items[key] = w
+	providerWeatherCache.mu.Unlock()
+	return withCity(w), nil
+}
+
+// dailyAccumulator folds sub-daily samples into DailyForecast rows.
+type dailyAccumulator struct {
+	order []string
+	days  map[string]*DailyForecast
+	codes map[string]int // most severe code seen per day
+}
+
+func newDailyAccumulator() *dailyAccumulator {
+	return &dailyAccumulator{days: map[string]*DailyForecast{}, codes: map[string]int{}}
+}
+
+func (a *dailyAccumulator) add(date string, code int, minC, maxC float64, precipPct int) {
+	d, ok := a.days[date]
+	if !ok {
+		d = &DailyForecast{Date: date, TempMinC: minC, TempMaxC: maxC}
+		a.days[date] = d
+		a.order = append(a.order, date)
+	}
+	if minC < d.TempMinC {
+		d.TempMinC = minC
+	}
+	if maxC > d.TempMaxC {
+		d.TempMaxC = maxC
+	}
+	if precipPct > d.PrecipProbPct {
+		d.PrecipProbPct = precipPct
+	}
+	// Higher WMO codes are more significant weather; show the worst of the day.
+	if code > a.codes[date] || !ok {
+		a.codes[date] = code
+		d.Code = code
+	}
+}
+
+func (a *dailyAccumulator) list(max int) []DailyForecast {
+	out := make([]DailyForecast, 0, len(a.order))
+	for _, date := range a.order {
+		out = append(out, *a.days[date])
+		if len(out) >= max {
+			break
+		}
+	}
+	return out
+}
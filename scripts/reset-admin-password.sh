@@ -70,9 +70,9 @@ cd "$PROJECT_ROOT"
 
 # Try using the Go tool if available
 if command -v go &> /dev/null; then
-    go run ./cmd/reset-password/main.go -db "$DB_PATH" -user "$USERNAME" -password "$NEW_PASSWORD"
+    go run ./cmd/hearth admin reset-password -db "$DB_PATH" -user "$USERNAME" -password "$NEW_PASSWORD"
 else
-    echo -e "${RED}Error: Go is not installed. Please install Go or run the reset-password tool manually.${NC}"
+    echo -e "${RED}Error: Go is not installed. Please install Go or run "hearth admin reset-password" manually.${NC}"
     echo ""
     echo "Alternatively, you can reset the password directly with SQLite:"
     echo ""
@@ -1,5 +1,6 @@
 import { Route, Routes } from 'react-router-dom'
 import HomePage from './pages/HomePage'
+import SharePage from './pages/SharePage'
 
 export default function App() {
   return (
@@ -7,6 +8,7 @@ export default function App() {
       <Routes>
         <Route path="/" element={<HomePage />} />
         <Route path="/admin" element={<HomePage initialDialog="login" />} />
+        <Route path="/share/:token" element={<SharePage />} />
       </Routes>
     </div>
   )
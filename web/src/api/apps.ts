@@ -14,6 +14,17 @@ import type {
     ReorderAppsRequest,
     IconResolve,
     IconResolveRequest,
+    LinkPreview,
+    Share,
+    CreateShareResponse,
+    SharedPage,
+    UndoEntry,
+    ReplicationInfo,
+    ReplicaStatus,
+    BackupTarget,
+    BackupTargetKind,
+    BackupTargetRequest,
+    RemoteBackup,
 } from '../types'
 
 export const groupsApi = {
@@ -34,9 +45,12 @@ export const groupsApi = {
         apiPut<void>(`/api/groups/${id}`, data),
 
     /**
-     * 删除分组
+     * 删除分组；apps 决定组内 App 的去向：删除（默认）、移出分组或移到 target 分组
      */
-    delete: (id: string) => apiDelete<void>(`/api/groups/${id}`),
+    delete: (id: string, apps: 'delete' | 'ungroup' | 'move' = 'delete', target?: string) =>
+        apiDelete<{ ok: boolean; affected: number }>(
+            `/api/groups/${id}?${new URLSearchParams(target ? { apps, target } : { apps }).toString()}`,
+        ),
 
     /**
      * 重新排序分组
@@ -48,7 +62,14 @@ export const appsApi = {
     /**
      * 获取所有 Apps
      */
-    list: () => apiGet<AppItem[]>('/api/apps'),
+    list: (includeArchived = false) =>
+        apiGet<AppItem[]>(includeArchived ? '/api/apps?includeArchived=1' : '/api/apps'),
+
+    /**
+     * 最近添加（或最近更新）的 Apps，不含组件
+     */
+    recent: (sort: 'created' | 'updated' = 'created', limit = 10) =>
+        apiGet<AppItem[]>(`/api/apps/recent?sort=${sort}&limit=${limit}`),
 
     /**
      * 创建 App
@@ -70,6 +91,39 @@ export const appsApi = {
      * 重新排序 Apps
      */
     reorder: (data: ReorderAppsRequest) => apiPost<void>('/api/apps/reorder', data),
+
+    /**
+     * 把 App 移到另一个分组的指定位置（一次请求内重排两个分组）
+     */
+    move: (appId: string, targetGroupId: string | null, targetIndex: number) =>
+        apiPost<void>('/api/apps/move', { appId, targetGroupId, targetIndex }),
+
+    /**
+     * 发送网络唤醒（Wake-on-LAN）包
+     */
+    wake: (id: string) => apiPost<void>(`/api/apps/${id}/wake`, {}),
+
+    /**
+     * 归档或恢复 App
+     */
+    setArchived: (id: string, archived: boolean) =>
+        apiPut<void>(`/api/apps/${id}/archive`, { archived }),
+
+    /**
+     * 抓取链接预览（页面标题与描述）并保存
+     */
+    fetchPreview: (id: string) => apiPost<LinkPreview>(`/api/apps/${id}/preview`, {}),
+
+    /**
+     * 清除链接预览
+     */
+    clearPreview: (id: string) => apiDelete<void>(`/api/apps/${id}/preview`),
+
+    /**
+     * 执行快捷操作
+     */
+    runAction: (id: string, actionId: string) =>
+        apiPost<void>(`/api/apps/${id}/actions/${actionId}`, {}),
 }
 
 export const iconApi = {
@@ -79,3 +133,95 @@ export const iconApi = {
     resolve: (data: IconResolveRequest) =>
         apiPost<IconResolve>('/api/icon/resolve', data),
 }
+
+export const sharesApi = {
+    /**
+     * 获取所有分享链接
+     */
+    list: () => apiGet<Share[]>('/api/admin/shares'),
+
+    /**
+     * 创建分享链接，不传 groupId 时分享整个首页
+     */
+    create: (data: { name: string; groupId?: string | null }) =>
+        apiPost<CreateShareResponse>('/api/admin/shares', data),
+
+    /**
+     * 撤销分享链接
+     */
+    delete: (id: string) => apiDelete<void>(`/api/admin/shares/${id}`),
+
+    /**
+     * 获取分享页面数据（公开）
+     */
+    get: (token: string) => apiGet<SharedPage>(`/api/share/${token}`),
+}
+
+export const undoApi = {
+    /**
+     * 最近可撤销的操作（最新在前）
+     */
+    list: () => apiGet<{ entries: UndoEntry[] }>('/api/admin/undo'),
+
+    /**
+     * 撤销指定操作，不传 id 时撤销最近一次
+     */
+    undo: (id?: string) => apiPost<{ ok: boolean; undone: UndoEntry }>('/api/admin/undo', id ? { id } : {}),
+}
+
+export const replicationApi = {
+    /**
+     * 复制状态
+     */
+    get: () => apiGet<ReplicationInfo>('/api/admin/replication'),
+
+    /**
+     * 生成副本拉取用的令牌（旧令牌失效，仅返回一次）
+     */
+    rotateToken: () => apiPost<{ token: string }>('/api/admin/replication/token'),
+
+    /**
+     * 删除令牌，副本无法再拉取
+     */
+    deleteToken: () => apiDelete<void>('/api/admin/replication/token'),
+
+    /**
+     * 副本立即从主实例同步
+     */
+    sync: () => apiPost<{ ok: boolean; replica: ReplicaStatus }>('/api/admin/replication/sync'),
+}
+
+export const backupTargetsApi = {
+    /**
+     * 远程备份目标列表
+     */
+    list: () => apiGet<{ targets: BackupTarget[]; kinds: BackupTargetKind[] }>('/api/admin/backups/targets'),
+
+    /**
+     * 添加备份目标
+     */
+    create: (data: BackupTargetRequest) => apiPost<BackupTarget>('/api/admin/backups/targets', data),
+
+    /**
+     * 更新备份目标（secret 留空则保留原密钥）
+     */
+    update: (id: number, data: BackupTargetRequest) =>
+        apiPut<BackupTarget>(`/api/admin/backups/targets/${id}`, data),
+
+    /**
+     * 删除备份目标，已上传的备份保留在目标上
+     */
+    delete: (id: number) => apiDelete<void>(`/api/admin/backups/targets/${id}`),
+
+    /**
+     * 立即备份到该目标
+     */
+    run: (id: number) =>
+        apiPost<{ ok: boolean; key: string; target: BackupTarget }>(`/api/admin/backups/targets/${id}/run`),
+
+    /**
+     * 目标上的备份，最新的在前
+     */
+    listBackups: (id: number) =>
+        apiGet<{ backups: RemoteBackup[] }>(`/api/admin/backups/targets/${id}/backups`),
+}
@@ -11,7 +11,11 @@ async function parseJsonOrThrow<T>(res: Response): Promise<T> {
     const text = await res.text()
     const data = text ? (JSON.parse(text) as unknown) : undefined
     if (!res.ok) {
-        const msg = (data as ApiError | undefined)?.error || res.statusText
+        const err = data as ApiError | undefined
+        let msg = err?.error || res.statusText
+        if (err?.fields) {
+            msg += ': ' + Object.entries(err.fields).map(([field, m]) => `${field} ${m}`).join('; ')
+        }
         throw new Error(msg)
     }
     return data as T
@@ -51,6 +55,18 @@ export async function apiPut<T>(path: string, body?: unknown): Promise<T> {
     return parseJsonOrThrow<T>(res)
 }
 
+/**
+ * 上传表单（multipart）
+ */
+export async function apiPostForm<T>(path: string, form: FormData): Promise<T> {
+    const res = await fetch(path, {
+        method: 'POST',
+        credentials: 'include',
+        body: form,
+    })
+    return parseJsonOrThrow<T>(res)
+}
+
 /**
  * DELETE 请求
  */
@@ -3,10 +3,10 @@
  */
 
 // HTTP 客户端
-export { apiGet, apiPost, apiPut, apiDelete, apiDownload } from './client'
+export { apiGet, apiPost, apiPut, apiDelete, apiDownload, apiPostForm } from './client'
 
 // 领域 API
 export { authApi } from './auth'
-export { groupsApi, appsApi, iconApi } from './apps'
+export { groupsApi, appsApi, iconApi, sharesApi, undoApi, replicationApi, backupTargetsApi } from './apps'
 export { settingsApi, backgroundApi } from './settings'
 export { widgetsApi } from './widgets'
@@ -2,8 +2,8 @@
  * 设置相关 API
  */
 
-import { apiGet, apiPost, apiPut } from './client'
-import type { Settings, BackgroundInfo } from '../types'
+import { apiDelete, apiGet, apiPost, apiPostForm, apiPut } from './client'
+import type { Settings, SettingDef, SettingChange, UserSettings, Customization, LocaleInfo, LocaleBundle, BackgroundInfo, BackgroundUpload, BackgroundHistoryItem } from '../types'
 
 export const settingsApi = {
     /**
@@ -15,6 +15,62 @@ export const settingsApi = {
      * 更新设置
      */
     update: (data: Settings) => apiPut<void>('/api/settings', data),
+
+    /**
+     * 设置项的类型、默认值与可选值
+     */
+    getSchema: () => apiGet<SettingDef[]>('/api/settings/schema'),
+
+    /**
+     * 设置的修改记录（最新在前），可按 key 过滤
+     */
+    getHistory: (key?: string, limit?: number) => {
+        const params = new URLSearchParams()
+        if (key) params.set('key', key)
+        if (limit) params.set('limit', String(limit))
+        const query = params.toString()
+        return apiGet<{ changes: SettingChange[] }>(`/api/settings/history${query ? `?${query}` : ''}`)
+    },
+
+    /**
+     * 撤销一次修改，把设置改回修改前的值
+     */
+    revertChange: (id: number) => apiPost<{ ok: boolean; reverted: SettingChange }>(`/api/settings/history/${id}/revert`),
+
+    /**
+     * 当前用户覆盖的设置（语言、单位、主题、固定组件）
+     */
+    getUser: () => apiGet<UserSettings>('/api/settings/user'),
+
+    /**
+     * 更新当前用户的设置（返回全部覆盖项）
+     */
+    updateUser: (data: UserSettings) => apiPut<UserSettings>('/api/settings/user', data),
+
+    /**
+     * 清除当前用户的设置，改回共享设置
+     */
+    resetUser: () => apiDelete<void>('/api/settings/user'),
+
+    /**
+     * 有翻译目录的语言
+     */
+    getLocales: () => apiGet<LocaleInfo[]>('/api/i18n'),
+
+    /**
+     * 获取语言的翻译目录（如 de-AT 返回 de）
+     */
+    getLocaleBundle: (locale: string) => apiGet<LocaleBundle>(`/api/i18n/${encodeURIComponent(locale)}`),
+
+    /**
+     * 获取自定义 CSS / JS
+     */
+    getCustomization: () => apiGet<Customization>('/api/customization'),
+
+    /**
+     * 更新自定义 CSS / JS（返回清理后的内容）
+     */
+    updateCustomization: (data: Partial<Customization>) => apiPut<Customization>('/api/customization', data),
 }
 
 export const backgroundApi = {
@@ -31,6 +87,30 @@ export const backgroundApi = {
         return apiPost<void>(`/api/background/refresh${params}`)
     },
 
+    /**
+     * 最近获取的背景（最新在前）
+     */
+    history: (provider?: string) => {
+        const params = provider ? `?provider=${encodeURIComponent(provider)}` : ''
+        return apiGet<{ provider: string; items: BackgroundHistoryItem[] }>(`/api/background/history${params}`)
+    },
+
+    /**
+     * 切换到上一张背景
+     */
+    previous: (provider?: string) => {
+        const params = provider ? `?provider=${encodeURIComponent(provider)}` : ''
+        return apiPost<void>(`/api/background/previous${params}`)
+    },
+
+    /**
+     * 切换到下一张背景，已是最新时获取新图片
+     */
+    next: (provider?: string) => {
+        const params = provider ? `?provider=${encodeURIComponent(provider)}` : ''
+        return apiPost<void>(`/api/background/next${params}`)
+    },
+
     /**
      * 获取背景图片 URL
      */
@@ -38,4 +118,23 @@ export const backgroundApi = {
         const base = '/api/background/image'
         return nonce ? `${base}?v=${nonce}` : base
     },
+
+    /**
+     * 已上传的背景图片（custom 提供方轮换使用）
+     */
+    listUploads: () => apiGet<{ uploads: BackgroundUpload[] }>('/api/background/uploads'),
+
+    /**
+     * 上传背景图片，可多选
+     */
+    upload: (files: File[]) => {
+        const form = new FormData()
+        for (const f of files) form.append('file', f)
+        return apiPostForm<{ uploads: BackgroundUpload[] }>('/api/background/upload', form)
+    },
+
+    /**
+     * 删除已上传的背景图片
+     */
+    deleteUpload: (name: string) => apiDelete<void>(`/api/background/uploads/${encodeURIComponent(name)}`),
 }
@@ -2,10 +2,24 @@
  * Widgets 数据相关 API
  */
 
-import { apiGet } from './client'
+import { apiGet, apiPut } from './client'
 import type {
     Weather,
     HostMetrics,
+    MetricsHistory,
+    MetricsHistoryRange,
+    DockerMetrics,
+    SmartMetrics,
+    IngestValue,
+    Embed,
+    SearchConfig,
+    Quote,
+    QuoteSource,
+    WidgetConfig,
+    WidgetTypeInfo,
+    PluginInfo,
+    PluginOutput,
+    MetricHost,
     MarketsResponse,
     HolidaysResponse,
     HolidayCountry,
@@ -29,6 +43,50 @@ export const widgetsApi = {
      */
     getMetrics: () => apiGet<HostMetrics>('/api/metrics/host'),
 
+    /**
+     * 获取主机指标历史
+     */
+    getMetricsHistory: (range: MetricsHistoryRange = '1h') =>
+        apiGet<MetricsHistory>(`/api/metrics/host/history?range=${range}`),
+
+    /**
+     * 获取 Docker 容器资源占用
+     */
+    getDockerMetrics: () => apiGet<DockerMetrics>('/api/metrics/docker'),
+
+    /**
+     * 获取硬盘 SMART 健康状态（需服务端启用 HEARTH_SMART）
+     */
+    getSmartMetrics: () => apiGet<SmartMetrics>('/api/metrics/smart'),
+
+    /**
+     * 获取自定义推送数据
+     */
+    getIngestValue: (key: string) => apiGet<IngestValue>(`/api/ingest/${encodeURIComponent(key)}`),
+
+    /**
+     * 获取已登记的嵌入网页
+     */
+    getEmbeds: () => apiGet<{ frames: Embed[] }>('/api/widgets/embeds'),
+
+    /**
+     * 获取搜索引擎与快捷方式配置
+     */
+    getSearchConfig: () => apiGet<SearchConfig>('/api/widgets/search-config'),
+
+    /**
+     * 获取每日一句
+     */
+    getQuote: (source: QuoteSource, lang: Language) => {
+        const params = new URLSearchParams({ source, lang })
+        return apiGet<Quote>(`/api/widgets/quote?${params}`)
+    },
+
+    /**
+     * 获取远程主机列表
+     */
+    getMetricHosts: () => apiGet<MetricHost[]>('/api/metrics/hosts'),
+
     /**
      * 获取市场行情
      */
@@ -73,4 +131,29 @@ export const widgetsApi = {
         const params = new URLSearchParams({ q: query })
         return apiGet<MarketSymbolSearchResponse>(`/api/widgets/markets/search?${params}`)
     },
+
+    /**
+     * 获取可添加的组件类型（名称、配置结构、默认配置、所需凭据）
+     */
+    getWidgetRegistry: (lang: Language) => apiGet<WidgetTypeInfo[]>(`/api/widgets/registry?${new URLSearchParams({ lang })}`),
+
+    /**
+     * 获取已安装的插件
+     */
+    listPlugins: () => apiGet<PluginInfo[]>('/api/widgets/plugins'),
+
+    /**
+     * 运行插件组件（按服务端保存的组件配置）
+     */
+    runPlugin: (appId: string, lang: Language) => apiGet<PluginOutput>(`/api/widgets/${appId}/plugin?${new URLSearchParams({ lang })}`),
+
+    /**
+     * 获取组件的结构化配置
+     */
+    getWidgetConfig: (appId: string) => apiGet<WidgetConfig>(`/api/widgets/${appId}/config`),
+
+    /**
+     * 保存组件配置（需管理员），服务端按组件类型校验
+     */
+    putWidgetConfig: (appId: string, config: object) => apiPut<WidgetConfig>(`/api/widgets/${appId}/config`, config),
 }
@@ -77,18 +77,26 @@ export function AppCard({
             ) : null}
 
             <a
-                href={app.url}
-                target="_blank"
+                href={app.href || app.url}
+                target={app.openMode === 'same_tab' ? undefined : '_blank'}
                 rel="noreferrer"
                 draggable={false}
-                className={`group block rounded-2xl border bg-black/40 p-3 transition-all duration-200 ease-out hover:bg-black/30 hover:shadow-lg hover:shadow-black/20 ${isDropTarget ? 'border-white/50 ring-2 ring-white/30 scale-[1.02]' : 'border-white/10'}`}
+                className={`group block rounded-2xl border tile-bg p-3 transition-all duration-200 ease-out hover:bg-black/30 hover:shadow-lg hover:shadow-black/20 ${isDropTarget ? 'border-white/50 ring-2 ring-white/30 scale-[1.02]' : 'border-white/10'}`}
             >
                 <div className="flex items-center gap-3">
                     <AppIcon iconPath={app.iconPath} name={app.name} />
                     <div className="min-w-0">
                         <div className="truncate text-sm font-medium text-white">{app.name}</div>
-                        {app.description ? (
+                        {app.descriptionHtml ? (
+                            <div
+                                className="app-markdown mt-1 line-clamp-2 text-xs text-white/70"
+                                // 服务端已转义并只输出白名单标签
+                                dangerouslySetInnerHTML={{ __html: app.descriptionHtml }}
+                            />
+                        ) : app.description ? (
                             <div className="mt-1 line-clamp-2 text-xs text-white/70">{app.description}</div>
+                        ) : app.preview?.description ? (
+                            <div className="mt-1 line-clamp-2 text-xs text-white/70">{app.preview.description}</div>
                         ) : (
                             <div className="truncate text-xs text-white/60">{app.url}</div>
                         )}
@@ -1,7 +1,7 @@
 import { useEffect, useState } from 'react'
 
-// Lucide CDN URL for SVG icons
-const LUCIDE_CDN_BASE = 'https://unpkg.com/lucide-static@latest/icons'
+// Lucide SVG icons, served by the backend
+const LUCIDE_SVG_BASE = '/api/icons/lucide'
 
 // Cache for loaded SVGs
 const svgCache = new Map<string, string>()
@@ -15,7 +15,7 @@ export interface AppIconProps {
 /**
  * App icon component with error handling fallback
  * Supports:
- * - Lucide icons (iconPath starts with "lucide:") - loaded from CDN
+ * - Lucide icons (iconPath starts with "lucide:") - loaded from the backend
  * - Regular image icons
  * - Fallback to first letter of name
  */
@@ -93,7 +93,7 @@ function LucideIcon({ name, className = 'h-5 w-5' }: LucideIconProps) {
         let mounted = true
         const loadSvg = async () => {
             try {
-                const res = await fetch(`${LUCIDE_CDN_BASE}/${kebabName}.svg`)
+                const res = await fetch(`${LUCIDE_SVG_BASE}/${kebabName}.svg`)
                 if (res.ok && mounted) {
                     const text = await res.text()
                     svgCache.set(kebabName, text)
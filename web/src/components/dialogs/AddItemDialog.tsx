@@ -1,7 +1,7 @@
 import { useState, useCallback, useEffect, useRef, type FormEvent } from 'react'
 import { Modal } from '../ui'
-import { apiPost } from '../../api'
-import type { WidgetKind, IconResolve } from '../../types'
+import { apiPost, widgetsApi } from '../../api'
+import type { IconResolve, WidgetTypeInfo } from '../../types'
 import { Loader2, Image as ImageIcon } from 'lucide-react'
 import { IconPicker, LucideIconDisplay } from '../ui/IconPicker'
 
@@ -23,22 +23,6 @@ interface AddItemData {
     iconSource: string | null
 }
 
-const WIDGET_TYPES: { kind: WidgetKind; labelZh: string; labelEn: string }[] = [
-    { kind: 'weather', labelZh: '天气', labelEn: 'Weather' },
-    { kind: 'timezones', labelZh: '世界时钟', labelEn: 'World Clock' },
-    { kind: 'metrics', labelZh: '系统状态', labelEn: 'System Status' },
-    { kind: 'markets', labelZh: '行情', labelEn: 'Markets' },
-    { kind: 'holidays', labelZh: '未来假日', labelEn: 'Upcoming Holidays' },
-]
-
-const DEFAULT_WIDGET_CONFIG: Record<WidgetKind, object | null> = {
-    weather: { city: 'Shanghai, Shanghai, China' },
-    metrics: { showCpu: true, showMem: true, showDisk: true, showNet: true, refreshSec: 1 },
-    markets: { symbols: ['BTC', 'ETH', 'AAPL', 'MSFT'] },
-    holidays: { countries: ['CN', 'US'] },
-    timezones: null,
-}
-
 // Simple URL validation
 function isValidUrl(str: string): boolean {
     try {
@@ -83,6 +67,9 @@ export function AddItemDialog({
     const [showIconPicker, setShowIconPicker] = useState(false)
     const [selectedLucideIcon, setSelectedLucideIcon] = useState<string | null>(null)
 
+    // Widget types come from the backend registry
+    const [widgetTypes, setWidgetTypes] = useState<WidgetTypeInfo[]>([])
+
     // Track if user has manually edited the name
     const userEditedNameRef = useRef(false)
     const fetchSeqRef = useRef(0)
@@ -105,6 +92,23 @@ export function AddItemDialog({
         }
     }, [open])
 
+    useEffect(() => {
+        if (!open || groupKind !== 'system') return
+        let cancelled = false
+        widgetsApi
+            .getWidgetRegistry(lang)
+            .then((types) => {
+                if (!cancelled) setWidgetTypes(types)
+            })
+            .catch((err) => {
+                if (!cancelled) setError(err instanceof Error ? err.message : t('加载组件列表失败', 'Failed to load widgets'))
+            })
+        return () => {
+            cancelled = true
+        }
+        // eslint-disable-next-line react-hooks/exhaustive-deps
+    }, [open, groupKind, lang])
+
     // Debounced auto-fetch favicon and title when URL changes (only in auto mode)
     useEffect(() => {
         if (!open || groupKind === 'system' || iconMode !== 'auto') return
@@ -159,17 +163,15 @@ export function AddItemDialog({
     }
 
     const handleAddWidget = useCallback(
-        async (kind: WidgetKind) => {
+        async (widget: WidgetTypeInfo) => {
             setError(null)
             setLoading(true)
             try {
-                const widgetName = WIDGET_TYPES.find((w) => w.kind === kind)?.[lang === 'en' ? 'labelEn' : 'labelZh'] || kind
-                const config = DEFAULT_WIDGET_CONFIG[kind]
                 await onSubmit({
                     groupId,
-                    name: widgetName,
-                    description: config ? JSON.stringify(config) : null,
-                    url: `widget:${kind}`,
+                    name: widget.name,
+                    description: JSON.stringify(widget.defaultConfig),
+                    url: `widget:${widget.id}`,
                     iconPath: null,
                     iconSource: null,
                 })
@@ -180,7 +182,7 @@ export function AddItemDialog({
                 setLoading(false)
             }
         },
-        [groupId, onSubmit, onClose, lang, t]
+        [groupId, onSubmit, onClose, t]
     )
 
     const handleAddAppLink = useCallback(
@@ -289,18 +291,23 @@ export function AddItemDialog({
             )}
             {groupKind === 'system' ? (
                 <div className="grid grid-cols-3 gap-2">
-                    {WIDGET_TYPES.map((widget) => (
-                        <button
-                            key={widget.kind}
-                            onClick={() => handleAddWidget(widget.kind)}
-                            disabled={loading}
-                            className="h-10 rounded-lg border border-white/10 bg-black/40 px-2 text-xs hover:bg-black/30 disabled:opacity-50"
-                        >
-                            <div className="flex h-full w-full items-center justify-center text-center leading-tight">
-                                {lang === 'en' ? widget.labelEn : widget.labelZh}
-                            </div>
-                        </button>
-                    ))}
+                    {widgetTypes.map((widget) => {
+                        const missing = widget.credentials.filter((c) => c.required && !c.configured)
+                        return (
+                            <button
+                                key={widget.id}
+                                onClick={() => handleAddWidget(widget)}
+                                disabled={loading}
+                                title={missing.length ? t(`需要先配置：${missing.map((c) => c.setting).join('、')}`, `Needs ${missing.map((c) => c.setting).join(', ')}`) : undefined}
+                                className="h-10 rounded-lg border border-white/10 bg-black/40 px-2 text-xs hover:bg-black/30 disabled:opacity-50"
+                            >
+                                <div className="flex h-full w-full items-center justify-center text-center leading-tight">
+                                    {widget.name}
+                                    {missing.length ? <span className="ml-1 text-amber-300">*</span> : null}
+                                </div>
+                            </button>
+                        )
+                    })}
                 </div>
             ) : (
                 <form onSubmit={handleAddAppLink} className="space-y-3">
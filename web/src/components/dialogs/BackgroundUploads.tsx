@@ -0,0 +1,87 @@
+/**
+ * 自定义背景图片管理（custom 提供方）
+ */
+
+import { useEffect, useState, type ChangeEvent } from 'react'
+import { Spinner } from '../ui/Spinner'
+import { backgroundApi } from '../../api/settings'
+import type { BackgroundUpload } from '../../types'
+
+interface BackgroundUploadsProps {
+    lang: 'zh' | 'en'
+    onChanged: () => void
+}
+
+export function BackgroundUploads({ lang, onChanged }: BackgroundUploadsProps) {
+    const t = (zh: string, en: string) => (lang === 'en' ? en : zh)
+    const [uploads, setUploads] = useState<BackgroundUpload[]>([])
+    const [busy, setBusy] = useState(false)
+    const [err, setErr] = useState<string | null>(null)
+
+    useEffect(() => {
+        backgroundApi
+            .listUploads()
+            .then((res) => setUploads(res.uploads))
+            .catch((e: unknown) => setErr(e instanceof Error ? e.message : String(e)))
+    }, [])
+
+    const onFiles = async (e: ChangeEvent<HTMLInputElement>) => {
+        const files = Array.from(e.target.files ?? [])
+        e.target.value = ''
+        if (files.length === 0) return
+        setBusy(true)
+        setErr(null)
+        try {
+            await backgroundApi.upload(files)
+            setUploads((await backgroundApi.listUploads()).uploads)
+            onChanged()
+        } catch (e: unknown) {
+            setErr(e instanceof Error ? e.message : String(e))
+        } finally {
+            setBusy(false)
+        }
+    }
+
+    const remove = async (name: string) => {
+        setErr(null)
+        try {
+            await backgroundApi.deleteUpload(name)
+            setUploads((prev) => prev.filter((u) => u.name !== name))
+            onChanged()
+        } catch (e: unknown) {
+            setErr(e instanceof Error ? e.message : String(e))
+        }
+    }
+
+    return (
+        <div className="space-y-2 text-sm">
+            <div className="flex items-center gap-2">
+                <label className="inline-flex cursor-pointer items-center rounded-lg bg-white/10 px-3 py-2 hover:bg-white/20">
+                    {t('上传图片', 'Upload photos')}
+                    <input type="file" accept="image/jpeg,image/png,image/gif,image/webp" multiple className="hidden" onChange={onFiles} />
+                </label>
+                {busy ? <Spinner /> : null}
+                {err ? <span className="text-red-400">{err}</span> : null}
+            </div>
+            {uploads.length === 0 ? (
+                <div className="text-white/50">{t('还没有上传图片', 'No photos uploaded yet')}</div>
+            ) : (
+                <div className="grid grid-cols-4 gap-2">
+                    {uploads.map((u) => (
+                        <div key={u.name} className="group relative aspect-video overflow-hidden rounded-md bg-white/5">
+                            <img src={u.url} alt="" className="h-full w-full object-cover" loading="lazy" />
+                            <button
+                                type="button"
+                                onClick={() => remove(u.name)}
+                                title={t('删除', 'Delete')}
+                                className="absolute right-1 top-1 hidden h-5 w-5 items-center justify-center rounded-full bg-black/60 text-xs text-white group-hover:flex"
+                            >
+                                ×
+                            </button>
+                        </div>
+                    ))}
+                </div>
+            )}
+        </div>
+    )
+}
@@ -2,7 +2,7 @@
  * 编辑组件/App 对话框
  */
 
-import { type FormEvent, useState, useCallback } from 'react'
+import { type FormEvent, useState, useCallback, useEffect } from 'react'
 import { Modal } from '../ui/Modal'
 import { Spinner } from '../ui/Spinner'
 import { CityPicker } from '../pickers/CityPicker'
@@ -10,7 +10,8 @@ import { MarketSymbolPicker } from '../pickers/MarketSymbolPicker'
 import { HolidayCountryTags } from '../pickers/HolidayCountryTags'
 import { IconPicker, LucideIconDisplay } from '../ui/IconPicker'
 import { Image as ImageIcon } from 'lucide-react'
-import type { AppItem } from '../../types'
+import type { AppItem, Embed, MetricHost, PluginInfo, QuoteSource, SearchEngine } from '../../types'
+import { widgetsApi } from '../../api/widgets'
 
 const DEFAULT_MARKET_SYMBOLS = ['BTC', 'ETH', 'AAPL', 'MSFT']
 
@@ -36,7 +37,7 @@ interface EditItemDialogProps {
     iconResolving: boolean
     saveItem: (e: FormEvent) => void
     // Widget kind
-    widgetKind: 'weather' | 'timezones' | 'metrics' | 'markets' | 'holidays' | null
+    widgetKind: 'weather' | 'timezones' | 'metrics' | 'markets' | 'holidays' | 'custom' | 'iframe' | 'search' | 'quote' | 'plugin' | null
     // Weather
     wCity: string
     setWCity: (v: string) => void
@@ -53,6 +54,33 @@ interface EditItemDialogProps {
     setMShowDisk: (v: boolean) => void
     mShowNet: boolean
     setMShowNet: (v: boolean) => void
+    mShowCores: boolean
+    setMShowCores: (v: boolean) => void
+    mShowDocker: boolean
+    setMShowDocker: (v: boolean) => void
+    mHost: string
+    setMHost: (v: string) => void
+    // Custom
+    cKey: string
+    setCKey: (v: string) => void
+    cTitle: string
+    setCTitle: (v: string) => void
+    cField: string
+    setCField: (v: string) => void
+    // Embed
+    iEmbed: string
+    setIEmbed: (v: string) => void
+    // Search
+    sEngine: string
+    setSEngine: (v: string) => void
+    // Quote
+    qSource: QuoteSource
+    setQSource: (v: QuoteSource) => void
+    // Plugin
+    pPlugin: string
+    setPPlugin: (v: string) => void
+    pOptions: Record<string, string>
+    setPOptions: (v: Record<string, string>) => void
     // Markets
     mkSymbols: string[]
     setMkSymbols: React.Dispatch<React.SetStateAction<string[]>>
@@ -105,6 +133,28 @@ export function EditItemDialog({
     setMShowDisk,
     mShowNet,
     setMShowNet,
+    mShowCores,
+    setMShowCores,
+    mShowDocker,
+    setMShowDocker,
+    mHost,
+    setMHost,
+    cKey,
+    setCKey,
+    cTitle,
+    setCTitle,
+    cField,
+    setCField,
+    iEmbed,
+    setIEmbed,
+    sEngine,
+    setSEngine,
+    qSource,
+    setQSource,
+    pPlugin,
+    setPPlugin,
+    pOptions,
+    setPOptions,
     mkSymbols,
     setMkSymbols,
     mkQueries,
@@ -120,6 +170,75 @@ export function EditItemDialog({
 }: EditItemDialogProps) {
     const t = (zh: string, en: string) => (lang === 'en' ? en : zh)
 
+    const [metricHosts, setMetricHosts] = useState<MetricHost[]>([])
+    useEffect(() => {
+        if (!open || widgetKind !== 'metrics') return
+        let cancelled = false
+        widgetsApi
+            .getMetricHosts()
+            .then((list) => {
+                if (!cancelled) setMetricHosts(list)
+            })
+            .catch(() => {
+                if (!cancelled) setMetricHosts([])
+            })
+        return () => {
+            cancelled = true
+        }
+    }, [open, widgetKind])
+
+    const [embeds, setEmbeds] = useState<Embed[] | null>(null)
+    useEffect(() => {
+        if (!open || widgetKind !== 'iframe') return
+        let cancelled = false
+        widgetsApi
+            .getEmbeds()
+            .then((res) => {
+                if (!cancelled) setEmbeds(res.frames)
+            })
+            .catch(() => {
+                if (!cancelled) setEmbeds([])
+            })
+        return () => {
+            cancelled = true
+        }
+    }, [open, widgetKind])
+
+    const [plugins, setPlugins] = useState<PluginInfo[] | null>(null)
+    useEffect(() => {
+        if (!open || widgetKind !== 'plugin') return
+        let cancelled = false
+        widgetsApi
+            .listPlugins()
+            .then((list) => {
+                if (!cancelled) setPlugins(list)
+            })
+            .catch(() => {
+                if (!cancelled) setPlugins([])
+            })
+        return () => {
+            cancelled = true
+        }
+    }, [open, widgetKind])
+    const selectedPlugin = plugins?.find((p) => p.id === pPlugin) || null
+
+    const [searchEngines, setSearchEngines] = useState<SearchEngine[]>([])
+    useEffect(() => {
+        if (!open || widgetKind !== 'search') return
+        let cancelled = false
+        widgetsApi
+            .getSearchConfig()
+            .then((res) => {
+                if (!cancelled) setSearchEngines(res.engines)
+            })
+            .catch(() => {
+                if (!cancelled) setSearchEngines([])
+            })
+        return () => {
+            cancelled = true
+        }
+    }, [open, widgetKind])
+
     // Lucide icon picker state
     const [showIconPicker, setShowIconPicker] = useState(false)
 
@@ -209,12 +328,33 @@ export function EditItemDialog({
                                             <option value={10}>{t('10 秒', '10s')}</option>
                                         </select>
                                     </label>
+                                    {metricHosts.length > 0 || mHost ? (
+                                        <label className="block text-sm">
+                                            <div className="mb-1 text-white/70">{t('主机', 'Host')}</div>
+                                            <select
+                                                value={mHost}
+                                                onChange={(e) => setMHost(e.target.value)}
+                                                className="w-full rounded-lg border border-white/10 bg-white/5 px-3 py-2 text-sm text-white outline-none"
+                                            >
+                                                <option value="">{t('本机', 'This server')}</option>
+                                                {metricHosts.map((h) => (
+                                                    <option key={h.name} value={h.name}>
+                                                        {h.name}
+                                                        {h.online ? '' : t('（离线）', ' (offline)')}
+                                                    </option>
+                                                ))}
+                                                {mHost && !metricHosts.some((h) => h.name === mHost) ? <option value={mHost}>{mHost}</option> : null}
+                                            </select>
+                                        </label>
+                                    ) : null}
                                 </div>
                                 <div className="mt-3 flex flex-wrap gap-3 text-sm">
                                     <label className="flex items-center gap-2"><input type="checkbox" checked={mShowCpu} onChange={(e) => setMShowCpu(e.target.checked)} />CPU</label>
                                     <label className="flex items-center gap-2"><input type="checkbox" checked={mShowMem} onChange={(e) => setMShowMem(e.target.checked)} />{t('内存', 'Memory')}</label>
                                     <label className="flex items-center gap-2"><input type="checkbox" checked={mShowDisk} onChange={(e) => setMShowDisk(e.target.checked)} />{t('磁盘', 'Disk')}</label>
                                     <label className="flex items-center gap-2"><input type="checkbox" checked={mShowNet} onChange={(e) => setMShowNet(e.target.checked)} />{t('网络', 'Network')}</label>
+                                    <label className="flex items-center gap-2"><input type="checkbox" checked={mShowCores} onChange={(e) => setMShowCores(e.target.checked)} />{t('每核占用', 'Per-core')}</label>
+                                    <label className="flex items-center gap-2"><input type="checkbox" checked={mShowDocker} onChange={(e) => setMShowDocker(e.target.checked)} />{t('容器', 'Containers')}</label>
                                 </div>
                             </div>
                         ) : widgetKind === 'markets' ? (
@@ -267,6 +407,144 @@ export function EditItemDialog({
                                     onChange={setHCountryCodes}
                                 />
                             </div>
+                        ) : widgetKind === 'custom' ? (
+                            <div className="space-y-3 rounded-xl border border-white/10 bg-black/40 p-3">
+                                <div className="text-sm font-semibold text-white/80">{t('自定义数据', 'Custom Data')}</div>
+                                <div className="grid grid-cols-1 gap-3 sm:grid-cols-2">
+                                    <label className="block text-sm">
+                                        <div className="mb-1 text-white/70">{t('数据键', 'Key')}</div>
+                                        <input
+                                            value={cKey}
+                                            onChange={(e) => setCKey(e.target.value.toLowerCase())}
+                                            placeholder="printer"
+                                            className="w-full rounded-lg border border-white/10 bg-white/5 px-3 py-2 text-sm text-white outline-none"
+                                        />
+                                    </label>
+                                    <label className="block text-sm">
+                                        <div className="mb-1 text-white/70">{t('标题', 'Title')}</div>
+                                        <input
+                                            value={cTitle}
+                                            onChange={(e) => setCTitle(e.target.value)}
+                                            placeholder={cKey || t('自定义数据', 'Custom Data')}
+                                            className="w-full rounded-lg border border-white/10 bg-white/5 px-3 py-2 text-sm text-white outline-none"
+                                        />
+                                    </label>
+                                    <label className="block text-sm sm:col-span-2">
+                                        <div className="mb-1 text-white/70">{t('字段（可选，点号路径）', 'Field (optional, dot path)')}</div>
+                                        <input
+                                            value={cField}
+                                            onChange={(e) => setCField(e.target.value)}
+                                            placeholder="job.progress"
+                                            className="w-full rounded-lg border border-white/10 bg-white/5 px-3 py-2 text-sm text-white outline-none"
+                                        />
+                                    </label>
+                                </div>
+                                <div className="break-all text-xs text-white/50">
+                                    {t('推送数据：', 'Push data: ')}
+                                    <code className="text-white/70">{`curl -X POST -H "Authorization: Bearer <token>" -d '{"progress": 42}' ${window.location.origin}/api/ingest/${cKey || '<key>'}`}</code>
+                                </div>
+                            </div>
+                        ) : widgetKind === 'plugin' ? (
+                            <div className="space-y-3 rounded-xl border border-white/10 bg-black/40 p-3">
+                                <div className="text-sm font-semibold text-white/80">{t('插件', 'Plugin')}</div>
+                                {plugins && plugins.length === 0 ? (
+                                    <div className="text-xs text-white/50">
+                                        {t('没有已安装的插件。将插件放到数据目录的 plugins/ 下并重启。', 'No plugins installed. Put plugins in plugins/ under the data directory and restart.')}
+                                    </div>
+                                ) : (
+                                    <label className="block text-sm">
+                                        <div className="mb-1 text-white/70">{t('插件', 'Plugin')}</div>
+                                        <select
+                                            value={pPlugin}
+                                            onChange={(e) => {
+                                                setPPlugin(e.target.value)
+                                                setPOptions({})
+                                            }}
+                                            className="w-full rounded-lg border border-white/10 bg-white/5 px-3 py-2 text-sm text-white outline-none"
+                                        >
+                                            <option value="">{t('选择插件', 'Choose a plugin')}</option>
+                                            {(plugins || []).map((p) => (
+                                                <option key={p.id} value={p.id}>{p.name}</option>
+                                            ))}
+                                        </select>
+                                    </label>
+                                )}
+                                {selectedPlugin?.description ? <div className="text-xs text-white/50">{selectedPlugin.description}</div> : null}
+                                {(selectedPlugin?.options || []).map((o) => (
+                                    <label key={o.name} className="block text-sm">
+                                        <div className="mb-1 text-white/70">{o.label || o.name}</div>
+                                        <input
+                                            value={pOptions[o.name] ?? ''}
+                                            onChange={(e) => setPOptions({ ...pOptions, [o.name]: e.target.value })}
+                                            placeholder={o.default || ''}
+                                            className="w-full rounded-lg border border-white/10 bg-white/5 px-3 py-2 text-sm text-white outline-none placeholder:text-white/30"
+                                        />
+                                    </label>
+                                ))}
+                            </div>
+                        ) : widgetKind === 'quote' ? (
+                            <div className="space-y-3 rounded-xl border border-white/10 bg-black/40 p-3">
+                                <div className="text-sm font-semibold text-white/80">{t('每日一句', 'Quote of the Day')}</div>
+                                <label className="block text-sm">
+                                    <div className="mb-1 text-white/70">{t('来源', 'Source')}</div>
+                                    <select
+                                        value={qSource}
+                                        onChange={(e) => setQSource(e.target.value === 'quotable' ? 'quotable' : e.target.value === 'custom' ? 'custom' : 'bundled')}
+                                        className="w-full rounded-lg border border-white/10 bg-white/5 px-3 py-2 text-sm text-white outline-none"
+                                    >
+                                        <option value="bundled">{t('内置名言', 'Bundled quotes')}</option>
+                                        <option value="quotable">quotable.io</option>
+                                        <option value="custom">{t('我的名言', 'My quotes')}</option>
+                                    </select>
+                                </label>
+                                <div className="text-xs text-white/50">
+                                    {t('每天更换一次。“我的名言”可通过 POST /api/admin/quotes 添加。', 'Changes once a day. Add your own with POST /api/admin/quotes.')}
+                                </div>
+                            </div>
+                        ) : widgetKind === 'search' ? (
+                            <div className="space-y-3 rounded-xl border border-white/10 bg-black/40 p-3">
+                                <div className="text-sm font-semibold text-white/80">{t('搜索', 'Search')}</div>
+                                <label className="block text-sm">
+                                    <div className="mb-1 text-white/70">{t('搜索引擎', 'Search engine')}</div>
+                                    <select
+                                        value={sEngine}
+                                        onChange={(e) => setSEngine(e.target.value)}
+                                        className="w-full rounded-lg border border-white/10 bg-white/5 px-3 py-2 text-sm text-white outline-none"
+                                    >
+                                        <option value="">{t('可切换（默认引擎）', 'Switchable (default engine)')}</option>
+                                        {searchEngines.map((e) => (
+                                            <option key={e.id} value={e.id}>{e.name}</option>
+                                        ))}
+                                    </select>
+                                </label>
+                                <div className="text-xs text-white/50">
+                                    {t('引擎与 !bang 快捷方式在设置 search 中配置。', 'Engines and !bang shortcuts are configured under the search setting.')}
+                                </div>
+                            </div>
+                        ) : widgetKind === 'iframe' ? (
+                            <div className="space-y-3 rounded-xl border border-white/10 bg-black/40 p-3">
+                                <div className="text-sm font-semibold text-white/80">{t('嵌入网页', 'Embed')}</div>
+                                <label className="block text-sm">
+                                    <div className="mb-1 text-white/70">{t('网页', 'Page')}</div>
+                                    <select
+                                        value={iEmbed}
+                                        onChange={(e) => setIEmbed(e.target.value)}
+                                        className="w-full rounded-lg border border-white/10 bg-white/5 px-3 py-2 text-sm text-white outline-none"
+                                    >
+                                        <option value="">{t('请选择', 'Choose…')}</option>
+                                        {(embeds || []).map((e) => (
+                                            <option key={e.id} value={e.id}>{e.name || e.id}</option>
+                                        ))}
+                                        {iEmbed && embeds && !embeds.some((e) => e.id === iEmbed) ? <option value={iEmbed}>{iEmbed}</option> : null}
+                                    </select>
+                                </label>
+                                <div className="text-xs text-white/50">
+                                    {t(
+                                        '只能嵌入在设置 embeds 中登记、且主机在白名单内的网页。',
+                                        'Only pages registered under the embeds setting, on allowlisted hosts, can be shown.',
+                                    )}
+                                </div>
+                            </div>
                         ) : (
                             <div className="space-y-3 rounded-xl border border-white/10 bg-black/40 p-3">
                                 <div className="text-sm font-semibold text-white/80">{t('世界时钟', 'World Clock')}</div>
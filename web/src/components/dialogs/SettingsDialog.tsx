@@ -2,17 +2,45 @@
  * 系统设置对话框
  */
 
-import { useState, type FormEvent } from 'react'
+import { useEffect, useState, type FormEvent } from 'react'
 import { Modal } from '../ui/Modal'
 import { Spinner } from '../ui/Spinner'
 import { TimezonePicker } from '../pickers/TimezonePicker'
-import { apiPost } from '../../api'
-import type { Settings } from '../../types'
+import { BackgroundUploads } from './BackgroundUploads'
+import { apiPost, settingsApi } from '../../api'
+import type { BackgroundAction, LocaleInfo, Settings, ThemeSettings } from '../../types'
 
-type SettingsTab = 'general' | 'time' | 'background' | 'account'
+type SettingsTab = 'general' | 'time' | 'background' | 'appearance' | 'account'
 
-// Use the same type as HomePage: Pick<Settings, 'siteTitle' | 'background' | 'time' | 'language'>
-type SiteDraft = Pick<Settings, 'siteTitle' | 'background' | 'time' | 'language'>
+// Use the same type as HomePage: Pick<Settings, 'siteTitle' | 'background' | 'time' | 'language' | 'theme'>
+type SiteDraft = Pick<Settings, 'siteTitle' | 'background' | 'time' | 'language' | 'theme'>
+
+// Markets Bing publishes daily images for (mirrors background.BingMarkets).
+const BING_MARKETS = [
+    'en-US',
+    'en-GB',
+    'en-CA',
+    'en-AU',
+    'en-NZ',
+    'en-IN',
+    'zh-CN',
+    'ja-JP',
+    'de-DE',
+    'fr-FR',
+    'fr-CA',
+    'it-IT',
+    'es-ES',
+    'pt-BR',
+]
+
+// Mirrors the server defaults in theme.go.
+const DEFAULT_THEME: ThemeSettings = {
+    accent: '#38bdf8',
+    mode: 'dark',
+    tileOpacity: 0.4,
+    fontScale: 1,
+    customCss: '',
+}
 
 interface SettingsDialogProps {
     open: boolean
@@ -26,9 +54,12 @@ interface SettingsDialogProps {
     // Background
     bgRefreshing: boolean
     bgRefreshErr: string | null
-    refreshBackground: () => void
+    refreshBackground: (action?: BackgroundAction) => void
     // Account
     onLogout: () => void
+    // 打开时显示的标签页，首次登录需改密码时为 account
+    initialTab?: SettingsTab
+    onPasswordChanged?: () => void
 }
 
 export function SettingsDialog({
@@ -44,8 +75,13 @@ export function SettingsDialog({
     bgRefreshErr,
     refreshBackground,
     onLogout,
+    initialTab,
+    onPasswordChanged,
 }: SettingsDialogProps) {
     const [settingsTab, setSettingsTab] = useState<SettingsTab>('general')
+    useEffect(() => {
+        if (open && initialTab) setSettingsTab(initialTab)
+    }, [open, initialTab])
     const t = (zh: string, en: string) => (lang === 'en' ? en : zh)
 
     // Password change state (managed internally)
@@ -56,6 +92,42 @@ export function SettingsDialog({
     const [passwordSuccess, setPasswordSuccess] = useState(false)
     const [changingPassword, setChangingPassword] = useState(false)
 
+    // 有翻译目录的语言；加载失败时只提供中英文
+    const [locales, setLocales] = useState<LocaleInfo[]>([])
+    useEffect(() => {
+        if (!open) return
+        let cancelled = false
+        settingsApi
+            .getLocales()
+            .then((list) => {
+                if (!cancelled && Array.isArray(list)) setLocales(list)
+            })
+            .catch(() => {})
+        return () => {
+            cancelled = true
+        }
+    }, [open])
+    const languageOptions: LocaleInfo[] = locales.length
+        ? [...locales]
+        : [
+            { locale: 'zh', name: t('中文', 'Chinese') },
+            { locale: 'en', name: t('英文', 'English') },
+        ]
+    const currentLanguage = siteDraft?.language || 'zh'
+    if (!languageOptions.some((l) => l.locale === currentLanguage)) {
+        // 没有翻译目录的语言（如 de-AT、sv）也保留为可选项
+        languageOptions.push({ locale: currentLanguage, name: currentLanguage })
+    }
+
+    const theme = siteDraft?.theme ?? DEFAULT_THEME
+    const updateTheme = (patch: Partial<ThemeSettings>, timing: 'now' | 'debounce') =>
+        setSiteDraft((prev) => {
+            if (!prev) return prev
+            const next = { ...prev, theme: { ...(prev.theme ?? DEFAULT_THEME), ...patch } }
+            schedulePersistSiteDraft(next, timing)
+            return next
+        })
+
     const onChangePassword = async (e: FormEvent) => {
         e.preventDefault()
         setPasswordErr(null)
@@ -81,6 +153,7 @@ export function SettingsDialog({
             setOldPassword('')
             setNewPassword('')
             setConfirmPassword('')
+            onPasswordChanged?.()
         } catch (err) {
             setPasswordErr(err instanceof Error ? err.message : (lang === 'en' ? 'Failed to change password' : '修改密码失败'))
         } finally {
@@ -118,6 +191,12 @@ export function SettingsDialog({
                     >
                         {t('背景', 'Background')}
                     </button>
+                    <button
+                        onClick={() => setSettingsTab('appearance')}
+                        className={`mb-1 rounded-lg px-3 py-2 text-left text-sm transition-colors ${settingsTab === 'appearance' ? 'bg-white/15 text-white' : 'text-white/60 hover:bg-white/5 hover:text-white/80'}`}
+                    >
+                        {t('外观', 'Appearance')}
+                    </button>
                     <button
                         onClick={() => setSettingsTab('account')}
                         className={`mb-1 rounded-lg px-3 py-2 text-left text-sm transition-colors ${settingsTab === 'account' ? 'bg-white/15 text-white' : 'text-white/60 hover:bg-white/5 hover:text-white/80'}`}
@@ -152,7 +231,7 @@ export function SettingsDialog({
                             <label className="block text-sm">
                                 <div className="mb-1 text-white/70">{t('语言', 'Language')}</div>
                                 <select
-                                    value={siteDraft?.language || 'zh'}
+                                    value={currentLanguage}
                                     onChange={(e) =>
                                         setSiteDraft((prev) => {
                                             if (!prev) return prev
@@ -163,8 +242,11 @@ export function SettingsDialog({
                                     }
                                     className="w-full rounded-lg border border-white/10 bg-white/5 px-3 py-2 text-sm text-white outline-none"
                                 >
-                                    <option value="zh">{t('中文', 'Chinese')}</option>
-                                    <option value="en">{t('英文', 'English')}</option>
+                                    {languageOptions.map((l) => (
+                                        <option key={l.locale} value={l.locale}>
+                                            {l.name}
+                                        </option>
+                                    ))}
                                 </select>
                             </label>
                         </div>
@@ -273,9 +355,41 @@ export function SettingsDialog({
                                         <option value="bing_random">Bing Random</option>
                                         <option value="bing_daily">Bing Daily</option>
                                         <option value="picsum">Picsum</option>
+                                        <option value="apod">{t('NASA 每日天文图', 'NASA Picture of the Day')}</option>
+                                        <option value="wikimedia">{t('维基共享资源每日图片', 'Wikimedia Picture of the Day')}</option>
+                                        <option value="chromecast">Chromecast</option>
+                                        <option value="custom">{t('我的图片', 'My photos')}</option>
                                     </select>
                                 </label>
 
+                                {siteDraft?.background.provider === 'bing_daily' || siteDraft?.background.provider === 'bing_random' ? (
+                                    <label className="block text-sm">
+                                        <div className="mb-1 text-white/70">{t('Bing 地区', 'Bing region')}</div>
+                                        <select
+                                            value={siteDraft.background.bingMarket || 'en-US'}
+                                            onChange={(e) =>
+                                                setSiteDraft((prev) => {
+                                                    if (!prev) return prev
+                                                    const next = { ...prev, background: { ...prev.background, bingMarket: e.target.value } }
+                                                    schedulePersistSiteDraft(next, 'now')
+                                                    return next
+                                                })
+                                            }
+                                            className="w-full rounded-lg border border-white/10 bg-white/5 px-3 py-2 text-sm text-white outline-none"
+                                        >
+                                            {BING_MARKETS.map((m) => (
+                                                <option key={m} value={m}>
+                                                    {m}
+                                                </option>
+                                            ))}
+                                        </select>
+                                    </label>
+                                ) : null}
+
+                                {siteDraft?.background.provider === 'custom' ? (
+                                    <BackgroundUploads lang={lang} onChanged={() => refreshBackground()} />
+                                ) : null}
+
                                 {siteDraft?.background.provider === 'bing_daily' || siteDraft?.background.provider === 'default' ? null : (
                                     <label className="block text-sm">
                                         <div className="mb-1 text-white/70">{t('更新间隔', 'Refresh interval')}</div>
@@ -305,12 +419,28 @@ export function SettingsDialog({
                             <div className="flex items-center gap-2">
                                 <button
                                     type="button"
-                                    onClick={refreshBackground}
+                                    onClick={() => refreshBackground('previous')}
+                                    disabled={bgRefreshing}
+                                    className="inline-flex items-center rounded-lg bg-white/10 px-3 py-2 text-sm hover:bg-white/20 disabled:opacity-60"
+                                >
+                                    {t('上一张', 'Previous')}
+                                </button>
+                                <button
+                                    type="button"
+                                    onClick={() => refreshBackground()}
                                     disabled={bgRefreshing}
                                     className="inline-flex items-center rounded-lg bg-white/10 px-3 py-2 text-sm hover:bg-white/20 disabled:opacity-60"
                                 >
                                     {t('更新', 'Refresh')}
                                 </button>
+                                <button
+                                    type="button"
+                                    onClick={() => refreshBackground('next')}
+                                    disabled={bgRefreshing}
+                                    className="inline-flex items-center rounded-lg bg-white/10 px-3 py-2 text-sm hover:bg-white/20 disabled:opacity-60"
+                                >
+                                    {t('下一张', 'Next')}
+                                </button>
                                 {bgRefreshing ? <Spinner /> : null}
                                 {bgRefreshErr ? (
                                     <span
@@ -324,6 +454,78 @@ export function SettingsDialog({
                         </div>
                     )}
 
+                    {/* Appearance Tab */}
+                    {settingsTab === 'appearance' && (
+                        <div className="space-y-4">
+                            <label className="block text-sm">
+                                <div className="mb-1 text-white/70">{t('模式', 'Mode')}</div>
+                                <select
+                                    value={theme.mode}
+                                    onChange={(e) => updateTheme({ mode: e.target.value as ThemeSettings['mode'] }, 'now')}
+                                    className="w-full rounded-lg border border-white/10 bg-white/5 px-3 py-2 text-sm text-white outline-none"
+                                >
+                                    <option value="dark">{t('深色', 'Dark')}</option>
+                                    <option value="light">{t('浅色', 'Light')}</option>
+                                    <option value="auto">{t('跟随系统', 'Auto')}</option>
+                                </select>
+                            </label>
+
+                            <label className="flex items-center justify-between text-sm">
+                                <div className="text-white/70">{t('强调色', 'Accent color')}</div>
+                                <input
+                                    type="color"
+                                    value={theme.accent}
+                                    onChange={(e) => updateTheme({ accent: e.target.value }, 'debounce')}
+                                    className="h-8 w-14 cursor-pointer rounded border border-white/10 bg-transparent"
+                                />
+                            </label>
+
+                            <label className="block text-sm">
+                                <div className="mb-1 flex justify-between text-white/70">
+                                    <span>{t('卡片不透明度', 'Tile opacity')}</span>
+                                    <span>{Math.round(theme.tileOpacity * 100)}%</span>
+                                </div>
+                                <input
+                                    type="range"
+                                    min={0}
+                                    max={1}
+                                    step={0.05}
+                                    value={theme.tileOpacity}
+                                    onChange={(e) => updateTheme({ tileOpacity: Number(e.target.value) }, 'debounce')}
+                                    className="w-full"
+                                />
+                            </label>
+
+                            <label className="block text-sm">
+                                <div className="mb-1 flex justify-between text-white/70">
+                                    <span>{t('字号', 'Font size')}</span>
+                                    <span>{Math.round(theme.fontScale * 100)}%</span>
+                                </div>
+                                <input
+                                    type="range"
+                                    min={0.75}
+                                    max={1.5}
+                                    step={0.05}
+                                    value={theme.fontScale}
+                                    onChange={(e) => updateTheme({ fontScale: Number(e.target.value) }, 'debounce')}
+                                    className="w-full"
+                                />
+                            </label>
+
+                            <label className="block text-sm">
+                                <div className="mb-1 text-white/70">{t('自定义 CSS', 'Custom CSS')}</div>
+                                <textarea
+                                    value={theme.customCss}
+                                    onChange={(e) => updateTheme({ customCss: e.target.value }, 'debounce')}
+                                    rows={6}
+                                    spellCheck={false}
+                                    placeholder=".tile-bg { border-radius: 0 }"
+                                    className="w-full rounded-lg border border-white/10 bg-white/5 px-3 py-2 font-mono text-xs text-white outline-none"
+                                />
+                            </label>
+                        </div>
+                    )}
+
                     {/* Account Tab */}
                     {settingsTab === 'account' && (
                         <div className="space-y-6">
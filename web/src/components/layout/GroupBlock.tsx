@@ -3,15 +3,20 @@
  */
 
 import { useState, useRef } from 'react'
-import { Cog, Cpu, Download, HardDrive, MemoryStick, Trash2, Upload } from 'lucide-react'
-import type { AppItem, HolidaysResponse, HostMetrics, MarketsResponse, Weather } from '../../types'
+import { BatteryCharging, BatteryMedium, Box, Cog, Cpu, Download, HardDrive, MemoryStick, PlugZap, Thermometer, Trash2, Upload } from 'lucide-react'
+import type { AppItem, FormattingHints, HolidaysResponse, DockerContainer, Embed, HostMetrics, IngestValue, MarketsResponse, PluginOutput, Quote, SearchConfig, MetricsHistory, Weather } from '../../types'
 import { AppIcon } from '../cards/AppIcon'
 import { WeatherWidget } from '../widgets/WeatherWidget'
 import { MarketsWidget } from '../widgets/MarketsWidget'
 import { HolidaysWidget } from '../widgets/HolidaysWidget'
 import { TimezonesWidget } from '../widgets/TimezonesWidget'
+import { CustomWidget } from '../widgets/CustomWidget'
+import { SearchWidget } from '../widgets/SearchWidget'
+import { QuoteWidget } from '../widgets/QuoteWidget'
+import { PluginWidget } from '../widgets/PluginWidget'
+import { MiniSparkline } from '../widgets/MiniSparkline'
 
-import { safeParseJSON, formatBytesPerSec, formatGiB, shortenCpuModelName, clocksFromCfg } from '../../utils'
+import { safeParseJSON, formatBytes, formatBytesPerSec, formatGiB, formatUptime, shortenCpuModelName, clocksFromCfg } from '../../utils'
 
 interface GroupBlockProps {
     groupId: string | null
@@ -32,10 +37,24 @@ interface GroupBlockProps {
     marketsErrById?: Record<string, string | null>
     holidaysById?: Record<string, HolidaysResponse | null>
     holidaysErrById?: Record<string, string | null>
+    customById?: Record<string, IngestValue | null>
+    customErrById?: Record<string, string | null>
+    embeds?: Embed[] | null
+    searchConfig?: SearchConfig | null
+    quotesById?: Record<string, Quote | null>
+    quotesErrById?: Record<string, string | null>
+    pluginsById?: Record<string, PluginOutput | null>
+    pluginsErrById?: Record<string, string | null>
     metrics: HostMetrics | null
     netRate?: { upBps: number; downBps: number } | null
+    metricsHistory?: MetricsHistory | null
+    dockerContainers?: DockerContainer[] | null
+    /** Metrics of remote agents, by host name */
+    metricsByHost?: Record<string, HostMetrics | null>
     localTimezone: string
     lang: 'zh' | 'en'
+    /** Number formatting for the configured language */
+    formatting?: FormattingHints | null
 }
 
 export function GroupBlock({
@@ -57,10 +76,22 @@ export function GroupBlock({
     marketsErrById,
     holidaysById,
     holidaysErrById,
-    metrics,
-    netRate,
+    customById,
+    customErrById,
+    embeds,
+    searchConfig,
+    quotesById,
+    quotesErrById,
+    pluginsById,
+    pluginsErrById,
+    metrics: localMetrics,
+    netRate: localNetRate,
+    metricsHistory: localMetricsHistory,
+    dockerContainers: localDockerContainers,
+    metricsByHost,
     localTimezone,
     lang,
+    formatting,
 }: GroupBlockProps) {
     const [draggingId, setDraggingId] = useState<string | null>(null)
     const [dropTargetId, setDropTargetId] = useState<string | null>(null)
@@ -126,7 +157,7 @@ export function GroupBlock({
                 }
             >
                 {items.length === 0 ? (
-                    <div className="col-span-full rounded-2xl border border-white/10 bg-black/40 p-3 text-sm text-white/60">{t('暂无内容', 'No items')}</div>
+                    <div className="col-span-full rounded-2xl border border-white/10 tile-bg p-3 text-sm text-white/60">{t('暂无内容', 'No items')}</div>
                 ) : (
                     items.map((a) => {
                         const widget = a.url?.startsWith('widget:') ? a.url.slice('widget:'.length) : null
@@ -136,7 +167,7 @@ export function GroupBlock({
                             const widgetCardClass =
                                 isSystemWidgetsOnly
                                     ? 'col-span-2 sm:col-span-1'  // All widgets: full width on mobile, 1 col on tablet+
-                                    : widget === 'timezones'
+                                    : widget === 'timezones' || widget === 'iframe' || widget === 'search'
                                         ? 'col-span-2 sm:col-span-3 lg:col-span-2'
                                         : widget === 'metrics'
                                             ? 'col-span-2 sm:col-span-1'
@@ -154,7 +185,7 @@ export function GroupBlock({
                             return (
                                 <div
                                     key={a.id}
-                                    className={`group/card relative flex flex-col rounded-2xl border bg-black/40 ${widgetPadClass} transition-all duration-200 ease-out ${widgetCardClass} ${widgetHeightClass} ${isAdmin ? 'cursor-grab active:cursor-grabbing' : ''} ${isDragging ? 'opacity-30 border-dashed border-white/30 bg-white/5' : isDropTarget ? 'border-white/50 ring-2 ring-white/30 scale-[1.02]' : 'hover:bg-black/30 hover:shadow-lg hover:shadow-black/20 border-white/10'}`}
+                                    className={`group/card relative flex flex-col rounded-2xl border tile-bg ${widgetPadClass} transition-all duration-200 ease-out ${widgetCardClass} ${widgetHeightClass} ${isAdmin ? 'cursor-grab active:cursor-grabbing' : ''} ${isDragging ? 'opacity-30 border-dashed border-white/30 bg-white/5' : isDropTarget ? 'border-white/50 ring-2 ring-white/30 scale-[1.02]' : 'hover:bg-black/30 hover:shadow-lg hover:shadow-black/20 border-white/10'}`}
                                     draggable={isAdmin}
                                     onDragStart={(e) => {
                                         if (!isAdmin) return
@@ -231,12 +262,24 @@ export function GroupBlock({
                                         {widget === 'weather'
                                             ? t('天气', 'Weather')
                                             : widget === 'metrics'
-                                                ? t('系统状态', 'System Status')
+                                                ? typeof cfg?.host === 'string' && cfg.host
+                                                    ? `${t('系统状态', 'System Status')} · ${cfg.host}`
+                                                    : t('系统状态', 'System Status')
                                                 : widget === 'markets'
                                                     ? t('行情', 'Markets')
                                                     : widget === 'holidays'
                                                         ? t('未来假日', 'Upcoming Holidays')
-                                                        : t('世界时钟', 'World Clock')}
+                                                        : widget === 'custom'
+                                                            ? String(cfg?.title || cfg?.key || t('自定义数据', 'Custom Data'))
+                                                            : widget === 'iframe'
+                                                                ? embeds?.find((e) => e.id === cfg?.embed)?.name || t('嵌入网页', 'Embed')
+                                                                : widget === 'search'
+                                                                    ? t('搜索', 'Search')
+                                                                    : widget === 'quote'
+                                                                        ? t('每日一句', 'Quote of the Day')
+                                                                        : widget === 'plugin'
+                                                                            ? pluginsById?.[a.id]?.title || t('插件', 'Plugin')
+                                                                            : t('世界时钟', 'World Clock')}
                                     </div>
                                     <div className="min-h-0 flex-1">
                                         {widget === 'weather' ? (
@@ -245,8 +288,16 @@ export function GroupBlock({
                                                 error={(weatherErrById?.[a.id] ?? weatherErr) || null}
                                                 lang={lang}
                                             />
-                                        ) : widget === 'metrics' ? (
-                                            metrics ? (
+                                        ) : widget === 'metrics' ? ((() => {
+                                            // Widgets bound to a remote agent read its metrics; rates come from the agent.
+                                            const hostName = typeof cfg?.host === 'string' ? cfg.host : ''
+                                            const metrics = hostName ? metricsByHost?.[hostName] ?? null : localMetrics
+                                            const netRate = hostName
+                                                ? metrics ? { upBps: metrics.netSendRate ?? 0, downBps: metrics.netRecvRate ?? 0 } : null
+                                                : localNetRate
+                                            const metricsHistory = hostName ? null : localMetricsHistory
+                                            const dockerContainers = hostName ? null : localDockerContainers
+                                            return metrics ? (
                                                 <div className="space-y-2 sm:space-y-3 text-[11px] sm:text-xs text-white/85 overflow-hidden">
                                                     {cfg?.showCpu !== false ? (
                                                         <div className="flex items-center justify-between gap-2">
@@ -257,6 +308,70 @@ export function GroupBlock({
                                                             </span>
                                                         </div>
                                                     ) : null}
+                                                    {cfg?.showCpu !== false && (metricsHistory?.points.length ?? 0) > 1 ? (
+                                                        <div title={t('近 1 小时 CPU', 'CPU, last hour')}>
+                                                            <MiniSparkline series={metricsHistory!.points.map((p) => p.cpu)} />
+                                                        </div>
+                                                    ) : null}
+                                                    {cfg?.showCores === true && (metrics.cpuPerCore?.length ?? 0) > 1 ? (
+                                                        <div className="flex h-2 gap-px overflow-hidden rounded-sm">
+                                                            {metrics.cpuPerCore!.map((p, i) => (
+                                                                <div
+                                                                    key={i}
+                                                                    className="flex-1"
+                                                                    style={{ backgroundColor: `hsla(${Math.round(120 - Math.min(100, p) * 1.2)}, 70%, 50%, ${0.35 + Math.min(100, p) / 160})` }}
+                                                                    title={`#${i}: ${p.toFixed(0)}%`}
+                                                                />
+                                                            ))}
+                                                        </div>
+                                                    ) : null}
+                                                    {cfg?.showCpu !== false && metrics.uptime !== undefined ? (
+                                                        <div className="flex items-center justify-between gap-2 text-white/70" title={t('运行时长', 'Uptime') + ': ' + formatUptime(metrics.uptime, lang)}>
+                                                            <span className="shrink-0">{t('负载', 'Load')}</span>
+                                                            <span className="tabular-nums text-right min-w-0 truncate">
+                                                                {(metrics.load1 ?? 0).toFixed(2)} {(metrics.load5 ?? 0).toFixed(2)} {(metrics.load15 ?? 0).toFixed(2)} · ↑{formatUptime(metrics.uptime, lang)}
+                                                            </span>
+                                                        </div>
+                                                    ) : null}
+                                                    {cfg?.showCpu !== false && (metrics.temperatures?.length ?? 0) > 0 ? (() => {
+                                                        const hottest = metrics.temperatures!.reduce((a, b) => (b.celsius > a.celsius ? b : a))
+                                                        const details = [
+                                                            ...metrics.temperatures!.map((s) => `${s.sensor}: ${s.celsius.toFixed(0)}°C`),
+                                                            ...(metrics.fans ?? []).map((f) => `${f.sensor}: ${f.rpm ? `${f.rpm} RPM` : `${(f.percent ?? 0).toFixed(0)}%`}`),
+                                                        ].join('\n')
+                                                        return (
+                                                            <div className="flex items-center justify-between gap-2" title={details}>
+                                                                <span className="flex items-center gap-1.5 sm:gap-2 shrink-0"><Thermometer className="h-3.5 w-3.5 sm:h-4 sm:w-4 text-white/70" />{t('温度', 'Temp')}</span>
+                                                                <span className={`tabular-nums text-right min-w-0 truncate ${hottest.high && hottest.celsius >= hottest.high ? 'text-red-300' : ''}`}>
+                                                                    {hottest.celsius.toFixed(0)}°C
+                                                                </span>
+                                                            </div>
+                                                        )
+                                                    })() : null}
+                                                    {metrics.ups ? (
+                                                        <div className="flex items-center justify-between gap-2" title={`${metrics.ups.name}: ${metrics.ups.status}`}>
+                                                            <span className="flex items-center gap-1.5 sm:gap-2 shrink-0">
+                                                                {metrics.ups.onBattery ? <BatteryMedium className="h-3.5 w-3.5 sm:h-4 sm:w-4 text-amber-300" /> : <PlugZap className="h-3.5 w-3.5 sm:h-4 sm:w-4 text-white/70" />}
+                                                                UPS
+                                                            </span>
+                                                            <span className={`tabular-nums text-right min-w-0 truncate ${metrics.ups.lowBattery ? 'text-red-300' : metrics.ups.onBattery ? 'text-amber-300' : ''}`}>
+                                                                {metrics.ups.charge.toFixed(0)}%
+                                                                {metrics.ups.onBattery && metrics.ups.runtime ? ` · ${formatUptime(metrics.ups.runtime, lang)}` : ''}
+                                                                {!metrics.ups.onBattery && metrics.ups.load ? ` · ${t('负载', 'Load')} ${metrics.ups.load.toFixed(0)}%` : ''}
+                                                            </span>
+                                                        </div>
+                                                    ) : null}
+                                                    {(metrics.batteries ?? []).map((b) => (
+                                                        <div key={b.name} className="flex items-center justify-between gap-2" title={`${b.name}: ${b.status}`}>
+                                                            <span className="flex items-center gap-1.5 sm:gap-2 shrink-0">
+                                                                {b.status === 'charging' ? <BatteryCharging className="h-3.5 w-3.5 sm:h-4 sm:w-4 text-white/70" /> : <BatteryMedium className="h-3.5 w-3.5 sm:h-4 sm:w-4 text-white/70" />}
+                                                                {t('电池', 'Battery')}
+                                                            </span>
+                                                            <span className={`tabular-nums text-right min-w-0 truncate ${b.status === 'discharging' && b.percent <= 15 ? 'text-red-300' : ''}`}>
+                                                                {b.percent.toFixed(0)}%
+                                                            </span>
+                                                        </div>
+                                                    ))}
                                                     {cfg?.showMem !== false ? (
                                                         <div className="flex items-center justify-between gap-2">
                                                             <span className="flex items-center gap-1.5 sm:gap-2 shrink-0"><MemoryStick className="h-3.5 w-3.5 sm:h-4 sm:w-4 text-white/70" />{t('内存', 'Mem')}</span>
@@ -266,10 +381,29 @@ export function GroupBlock({
                                                         </div>
                                                     ) : null}
                                                     {cfg?.showDisk !== false ? (
-                                                        <div className="flex items-center justify-between gap-2">
-                                                            <span className="flex items-center gap-1.5 sm:gap-2 shrink-0"><HardDrive className="h-3.5 w-3.5 sm:h-4 sm:w-4 text-white/70" />{t('磁盘', 'Disk')}</span>
+                                                        (metrics.mounts?.length ?? 0) > 1 ? (
+                                                            metrics.mounts!.map((m) => (
+                                                                <div key={m.path} className="flex items-center justify-between gap-2" title={`${m.path} (${m.fsType})`}>
+                                                                    <span className="flex items-center gap-1.5 sm:gap-2 min-w-0"><HardDrive className="h-3.5 w-3.5 sm:h-4 sm:w-4 text-white/70 shrink-0" /><span className="truncate">{m.label || m.path}</span></span>
+                                                                    <span className="tabular-nums text-right shrink-0">
+                                                                        {formatGiB(m.used)}/{formatGiB(m.total)} · {m.percent.toFixed(0)}%
+                                                                    </span>
+                                                                </div>
+                                                            ))
+                                                        ) : (
+                                                            <div className="flex items-center justify-between gap-2">
+                                                                <span className="flex items-center gap-1.5 sm:gap-2 shrink-0"><HardDrive className="h-3.5 w-3.5 sm:h-4 sm:w-4 text-white/70" />{t('磁盘', 'Disk')}</span>
+                                                                <span className="tabular-nums text-right min-w-0 truncate">
+                                                                    {formatGiB(metrics.diskUsed)}/{formatGiB(metrics.diskTotal)} · {metrics.diskPercent.toFixed(0)}%
+                                                                </span>
+                                                            </div>
+                                                        )
+                                                    ) : null}
+                                                    {cfg?.showDisk !== false && metrics.diskReadRate !== undefined ? (
+                                                        <div className="flex items-center justify-between gap-2" title={t('读取 / 写入', 'Read / Write')}>
+                                                            <span className="flex items-center gap-1.5 sm:gap-2 shrink-0"><HardDrive className="h-3.5 w-3.5 sm:h-4 sm:w-4 text-white/70" />{t('读写', 'I/O')}</span>
                                                             <span className="tabular-nums text-right min-w-0 truncate">
-                                                                {formatGiB(metrics.diskUsed)}/{formatGiB(metrics.diskTotal)} · {metrics.diskPercent.toFixed(0)}%
+                                                                {formatBytesPerSec(metrics.diskReadRate)} / {formatBytesPerSec(metrics.diskWriteRate ?? 0)}
                                                             </span>
                                                         </div>
                                                     ) : null}
@@ -286,14 +420,62 @@ export function GroupBlock({
                                                             </div>
                                                         </>
                                                     ) : null}
+                                                    {cfg?.showDocker === true && dockerContainers?.length ? (
+                                                        [...dockerContainers]
+                                                            .sort((a, b) => b.cpuPercent - a.cpuPercent)
+                                                            .slice(0, 5)
+                                                            .map((c) => (
+                                                                <div key={c.id} className="flex items-center justify-between gap-2" title={`${c.image} · ${c.status}`}>
+                                                                    <span className="flex items-center gap-1.5 sm:gap-2 min-w-0"><Box className="h-3.5 w-3.5 sm:h-4 sm:w-4 text-white/70 shrink-0" /><span className="truncate">{c.name}</span></span>
+                                                                    <span className="tabular-nums text-right shrink-0">
+                                                                        {c.cpuPercent.toFixed(0)}% · {formatBytes(c.memUsed)}
+                                                                    </span>
+                                                                </div>
+                                                            ))
+                                                    ) : null}
                                                 </div>
                                             ) : (
                                                 <div className="flex h-full items-center justify-center text-sm text-white/60">{t('暂不可用', 'Unavailable')}</div>
                                             )
-                                        ) : widget === 'markets' ? (
-                                            <MarketsWidget data={marketsById?.[a.id] || null} error={marketsErrById?.[a.id] || null} lang={lang} />
+                                        })()) : widget === 'markets' ? (
+                                            <MarketsWidget data={marketsById?.[a.id] || null} error={marketsErrById?.[a.id] || null} lang={lang} formatting={formatting} />
                                         ) : widget === 'holidays' ? (
                                             <HolidaysWidget data={holidaysById?.[a.id] || null} error={holidaysErrById?.[a.id] || null} lang={lang} />
+                                        ) : widget === 'custom' ? (
+                                            <CustomWidget
+                                                data={customById?.[a.id] || null}
+                                                error={customErrById?.[a.id] || null}
+                                                field={typeof cfg?.field === 'string' ? cfg.field : undefined}
+                                                lang={lang}
+                                            />
+                                        ) : widget === 'iframe' ? ((() => {
+                                            // Only frames registered by the admin are shown; the URL and
+                                            // sandbox never come from the item's description.
+                                            const embed = embeds?.find((e) => e.id === cfg?.embed)
+                                            if (!embed) {
+                                                return (
+                                                    <div className="flex h-full items-center justify-center text-sm text-white/60">
+                                                        {embeds === null ? t('加载中…', 'Loading…') : t('未登记的嵌入网页', 'Embed not registered')}
+                                                    </div>
+                                                )
+                                            }
+                                            return (
+                                                <iframe
+                                                    src={embed.url}
+                                                    title={embed.name || embed.id}
+                                                    sandbox={embed.sandbox.join(' ')}
+                                                    referrerPolicy="no-referrer"
+                                                    loading="lazy"
+                                                    className="w-full rounded-lg border-0 bg-white/5"
+                                                    style={{ height: embed.height }}
+                                                />
+                                            )
+                                        })()) : widget === 'search' ? (
+                                            <SearchWidget config={searchConfig || null} engine={typeof cfg?.engine === 'string' ? cfg.engine : undefined} lang={lang} />
+                                        ) : widget === 'quote' ? (
+                                            <QuoteWidget data={quotesById?.[a.id] || null} error={quotesErrById?.[a.id] || null} lang={lang} />
+                                        ) : widget === 'plugin' ? (
+                                            <PluginWidget data={pluginsById?.[a.id] || null} error={pluginsErrById?.[a.id] || null} lang={lang} />
                                         ) : (
                                             <TimezonesWidget localTimezone={localTimezone} clocks={clocksFromCfg(cfg)} />
                                         )}
@@ -386,11 +568,11 @@ export function GroupBlock({
                                 ) : null}
 
                                 <a
-                                    href={a.url}
-                                    target="_blank"
+                                    href={a.href || a.url}
+                                    target={a.openMode === 'same_tab' ? undefined : '_blank'}
                                     rel="noreferrer"
                                     draggable={false}
-                                    className={`group block rounded-2xl border bg-black/40 p-3 transition-all duration-200 ease-out hover:bg-black/30 hover:shadow-lg hover:shadow-black/20 ${isDropTarget ? 'border-white/50 ring-2 ring-white/30 scale-[1.02]' : 'border-white/10'}`}
+                                    className={`group block rounded-2xl border tile-bg p-3 transition-all duration-200 ease-out hover:bg-black/30 hover:shadow-lg hover:shadow-black/20 ${isDropTarget ? 'border-white/50 ring-2 ring-white/30 scale-[1.02]' : 'border-white/10'}`}
                                 >
                                     <div className="flex items-center gap-3">
                                         <AppIcon iconPath={a.iconPath} name={a.name} />
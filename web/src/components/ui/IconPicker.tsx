@@ -6,8 +6,8 @@
 import { useState, useCallback, useEffect, useRef } from 'react'
 import { Search, X, Loader2 } from 'lucide-react'
 
-// Lucide CDN URL for SVG icons
-const LUCIDE_CDN_BASE = 'https://unpkg.com/lucide-static@latest/icons'
+// Lucide SVG icons, served by the backend
+const LUCIDE_SVG_BASE = '/api/icons/lucide'
 
 interface LucideIcon {
     name: string
@@ -227,7 +227,7 @@ export function LucideIconPreview({ name, className = 'h-6 w-6' }: LucideIconPre
 
         const loadSvg = async () => {
             try {
-                const res = await fetch(`${LUCIDE_CDN_BASE}/${name}.svg`)
+                const res = await fetch(`${LUCIDE_SVG_BASE}/${name}.svg`)
                 if (res.ok) {
                     const text = await res.text()
                     svgCache.set(name, text)
@@ -0,0 +1,95 @@
+import type { IngestValue } from '../../types'
+
+interface CustomWidgetProps {
+    data: IngestValue | null
+    error?: string | null
+    /** 可选：用点号路径取 payload 中的字段，如 "printer.progress" */
+    field?: string
+    lang: 'zh' | 'en'
+}
+
+function pick(value: unknown, field: string | undefined): unknown {
+    if (!field) return value
+    let cur: unknown = value
+    for (const part of field.split('.')) {
+        if (cur == null || typeof cur !== 'object') return undefined
+        cur = (cur as Record<string, unknown>)[part]
+    }
+    return cur
+}
+
+function formatScalar(v: unknown): string {
+    if (v == null) return '—'
+    if (typeof v === 'number') return Number.isInteger(v) ? String(v) : v.toFixed(2)
+    if (typeof v === 'boolean') return v ? '✓' : '✗'
+    if (typeof v === 'string') return v
+    return JSON.stringify(v)
+}
+
+function formatAge(updatedAt: number, lang: 'zh' | 'en'): string {
+    const secs = Math.max(0, Math.floor(Date.now() / 1000 - updatedAt))
+    if (secs < 60) return lang === 'en' ? 'just now' : '刚刚'
+    const mins = Math.floor(secs / 60)
+    if (mins < 60) return lang === 'en' ? `${mins}m ago` : `${mins} 分钟前`
+    const hours = Math.floor(mins / 60)
+    if (hours < 48) return lang === 'en' ? `${hours}h ago` : `${hours} 小时前`
+    const days = Math.floor(hours / 24)
+    return lang === 'en' ? `${days}d ago` : `${days} 天前`
+}
+
+/**
+ * 自定义数据组件 - 显示脚本通过 POST /api/ingest/{key} 推送的最新值
+ */
+export function CustomWidget({ data, error, field, lang }: CustomWidgetProps) {
+    if (!data) {
+        const msg = String(error || '').trim()
+        return <div className="flex h-full items-center justify-center text-sm text-white/60">{msg || (lang === 'en' ? 'Waiting for data…' : '等待数据…')}</div>
+    }
+
+    const value = pick(data.value, field)
+    const age = <div className="mt-2 text-[11px] text-white/50">{formatAge(data.updatedAt, lang)}</div>
+
+    if (value == null || typeof value !== 'object') {
+        return (
+            <div className="flex h-full flex-col justify-center">
+                <div className="truncate text-2xl font-semibold tabular-nums text-white/95">{formatScalar(value)}</div>
+                {age}
+            </div>
+        )
+    }
+
+    if (Array.isArray(value)) {
+        return (
+            <div className="flex h-full flex-col gap-1 text-xs text-white/85">
+                {value.slice(0, 6).map((v, i) => (
+                    <div key={i} className="truncate">{formatScalar(v)}</div>
+                ))}
+                {age}
+            </div>
+        )
+    }
+
+    const obj = value as Record<string, unknown>
+    const progress = typeof obj.progress === 'number' && Number.isFinite(obj.progress) ? Math.max(0, Math.min(100, obj.progress)) : null
+    const rows = Object.entries(obj).filter(([k, v]) => k !== 'progress' && (v == null || typeof v !== 'object')).slice(0, 6)
+
+    return (
+        <div className="flex h-full flex-col gap-1.5 text-xs text-white/85">
+            {progress != null ? (
+                <div className="flex items-center gap-2">
+                    <div className="h-1.5 flex-1 overflow-hidden rounded-full bg-white/10">
+                        <div className="h-full rounded-full bg-white/70" style={{ width: `${progress}%` }} />
+                    </div>
+                    <span className="tabular-nums">{progress.toFixed(0)}%</span>
+                </div>
+            ) : null}
+            {rows.map(([k, v]) => (
+                <div key={k} className="flex items-center justify-between gap-2">
+                    <span className="shrink-0 text-white/70">{k}</span>
+                    <span className="min-w-0 truncate text-right tabular-nums">{formatScalar(v)}</span>
+                </div>
+            ))}
+            {age}
+        </div>
+    )
+}
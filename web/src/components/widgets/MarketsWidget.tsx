@@ -1,17 +1,19 @@
 import { useEffect, useMemo, useState } from 'react'
 import { FaApple, FaMicrosoft, FaBitcoin, FaEthereum } from 'react-icons/fa'
-import type { MarketsResponse } from '../../types'
+import type { FormattingHints, MarketsResponse } from '../../types'
+import { formatMoney } from '../../utils'
 
 interface MarketsWidgetProps {
     data: MarketsResponse | null
     error?: string | null
     lang: 'zh' | 'en'
+    formatting?: FormattingHints | null
 }
 
 /**
  * 行情组件 - 显示股票/加密货币行情
  */
-export function MarketsWidget({ data, error, lang }: MarketsWidgetProps) {
+export function MarketsWidget({ data, error, lang, formatting }: MarketsWidgetProps) {
     if (!data) {
         const msg = String(error || '').trim()
         if (msg) return <div className="flex h-full items-center justify-center text-sm text-white/60">{msg}</div>
@@ -28,7 +30,8 @@ export function MarketsWidget({ data, error, lang }: MarketsWidgetProps) {
             {items.map((it) => {
                 const sym = String(it.symbol || '').toUpperCase() || '—'
                 const name = prettifyCompanyName(String(it.name || '').trim())
-                const price = typeof it.priceUsd === 'number' && Number.isFinite(it.priceUsd) ? `$${it.priceUsd.toFixed(2)}` : '—'
+                const amount = typeof it.price === 'number' && Number.isFinite(it.price) ? it.price : it.priceUsd
+                const price = typeof amount === 'number' && Number.isFinite(amount) ? formatMoney(amount, it.price != null ? it.currency || 'USD' : 'USD', formatting) : '—'
                 const pct = typeof it.changePct24h === 'number' && Number.isFinite(it.changePct24h) ? it.changePct24h : null
                 const pctLabel = pct == null ? '—' : `${pct >= 0 ? '+' : ''}${pct.toFixed(2)}%`
                 const pctColor = pct == null ? 'text-white/60' : pct >= 0 ? 'text-green-400/80' : 'text-red-400/80'
@@ -101,6 +101,7 @@ export function MetricsWidget({ data, netRate, config, lang }: MetricsWidgetProp
             label: string
             value: string
             percent?: number
+            title?: string
         }> = []
 
         if (config.showCpu) {
@@ -124,27 +125,55 @@ export function MetricsWidget({ data, netRate, config, lang }: MetricsWidgetProp
         }
 
         if (config.showDisk) {
-            result.push({
-                key: 'disk',
-                icon: <HardDrive className="w-4 h-4" />,
-                label: t('磁盘', 'Disk'),
-                value: `${formatBytes(data.diskUsed)} / ${formatBytes(data.diskTotal)}`,
-                percent: data.diskPercent,
-            })
+            const mounts = data.mounts ?? []
+            if (mounts.length > 1) {
+                for (const m of mounts) {
+                    result.push({
+                        key: `disk-${m.path}`,
+                        icon: <HardDrive className="w-4 h-4" />,
+                        label: m.path,
+                        value: `${formatBytes(m.used)} / ${formatBytes(m.total)}`,
+                        percent: m.percent,
+                        title: `${m.path} (${m.fsType})`,
+                    })
+                }
+            } else {
+                result.push({
+                    key: 'disk',
+                    icon: <HardDrive className="w-4 h-4" />,
+                    label: t('磁盘', 'Disk'),
+                    value: `${formatBytes(data.diskUsed)} / ${formatBytes(data.diskTotal)}`,
+                    percent: data.diskPercent,
+                })
+            }
+            if (data.diskReadRate !== undefined && data.diskWriteRate !== undefined) {
+                result.push({
+                    key: 'disk-io',
+                    icon: <HardDrive className="w-4 h-4" />,
+                    label: t('读写', 'I/O'),
+                    value: `${formatBytes(data.diskReadRate)}/s · ${formatBytes(data.diskWriteRate)}/s`,
+                    title: t('读取 · 写入', 'Read · Write'),
+                })
+            }
         }
 
         if (config.showNet && netRate) {
+            const ifaces = data.interfaces ?? []
+            const perIface = (pick: (i: (typeof ifaces)[number]) => number) =>
+                ifaces.map((i) => `${i.name}: ${formatBytes(pick(i))}/s`).join('\n') || undefined
             result.push({
                 key: 'net-up',
                 icon: <Upload className="w-4 h-4" />,
                 label: t('上传', 'Upload'),
                 value: `${formatBytes(netRate.upBps)}/s`,
+                title: perIface((i) => i.sendRate),
             })
             result.push({
                 key: 'net-down',
                 icon: <Download className="w-4 h-4" />,
                 label: t('下载', 'Download'),
                 value: `${formatBytes(netRate.downBps)}/s`,
+                title: perIface((i) => i.recvRate),
             })
         }
 
@@ -186,9 +215,9 @@ export function MetricsWidget({ data, netRate, config, lang }: MetricsWidgetProp
 
             <div className="flex-1 flex flex-col justify-center gap-2">
                 {items.map((item) => (
-                    <div key={item.key} className="flex items-center gap-2">
+                    <div key={item.key} className="flex items-center gap-2" title={item.title}>
                         <span className="text-gray-500 dark:text-gray-400">{item.icon}</span>
-                        <span className="text-xs text-gray-500 dark:text-gray-400 w-12 shrink-0">
+                        <span className="text-xs text-gray-500 dark:text-gray-400 w-12 shrink-0 truncate">
                             {item.label}
                         </span>
                         {item.percent !== undefined ? (
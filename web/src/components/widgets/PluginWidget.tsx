@@ -0,0 +1,41 @@
+import type { PluginOutput } from '../../types'
+
+interface PluginWidgetProps {
+    data: PluginOutput | null
+    error?: string | null
+    lang: 'zh' | 'en'
+}
+
+/**
+ * 插件组件 - 显示 plugins 目录中第三方插件的输出
+ */
+export function PluginWidget({ data, error, lang }: PluginWidgetProps) {
+    if (!data) {
+        const msg = String(error || '').trim()
+        return <div className="flex h-full items-center justify-center text-sm text-white/60">{msg || (lang === 'en' ? 'Loading…' : '加载中…')}</div>
+    }
+
+    const body = (
+        <div className="flex h-full flex-col gap-1.5 text-xs text-white/85">
+            {data.value ? (
+                <div className="truncate text-2xl font-semibold tabular-nums text-white/95">
+                    {data.value}
+                    {data.unit ? <span className="ml-1 text-sm font-normal text-white/60">{data.unit}</span> : null}
+                </div>
+            ) : null}
+            {(data.items || []).slice(0, 6).map((it, i) => (
+                <div key={i} className="flex items-center justify-between gap-2">
+                    <span className="shrink-0 text-white/70">{it.label}</span>
+                    <span className="min-w-0 truncate text-right tabular-nums">{it.value}</span>
+                </div>
+            ))}
+        </div>
+    )
+
+    if (!data.url) return body
+    return (
+        <a href={data.url} target="_blank" rel="noopener noreferrer" className="block h-full">
+            {body}
+        </a>
+    )
+}
@@ -0,0 +1,24 @@
+import type { Quote } from '../../types'
+
+interface QuoteWidgetProps {
+    data: Quote | null
+    error?: string | null
+    lang: 'zh' | 'en'
+}
+
+/**
+ * 每日一句组件
+ */
+export function QuoteWidget({ data, error, lang }: QuoteWidgetProps) {
+    if (!data) {
+        const msg = String(error || '').trim()
+        return <div className="flex h-full items-center justify-center text-sm text-white/60">{msg || (lang === 'en' ? 'Loading…' : '加载中…')}</div>
+    }
+
+    return (
+        <figure className="flex h-full flex-col justify-center gap-2">
+            <blockquote className="text-sm leading-relaxed text-white/90">{data.text}</blockquote>
+            {data.author ? <figcaption className="text-right text-xs text-white/60">— {data.author}</figcaption> : null}
+        </figure>
+    )
+}
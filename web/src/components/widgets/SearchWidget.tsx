@@ -0,0 +1,106 @@
+import { type FormEvent, useState } from 'react'
+import { Search } from 'lucide-react'
+import type { SearchConfig } from '../../types'
+
+interface SearchWidgetProps {
+    config: SearchConfig | null
+    /** 组件固定使用的引擎；为空时用服务端默认引擎 */
+    engine?: string
+    lang: 'zh' | 'en'
+}
+
+const HISTORY_KEY = 'hearth.search.history'
+const HISTORY_OPT_IN_KEY = 'hearth.search.remember'
+const MAX_HISTORY = 10
+
+function loadHistory(): string[] {
+    try {
+        const raw = JSON.parse(window.localStorage.getItem(HISTORY_KEY) || '[]')
+        return Array.isArray(raw) ? raw.filter((x): x is string => typeof x === 'string').slice(0, MAX_HISTORY) : []
+    } catch {
+        return []
+    }
+}
+
+/**
+ * 搜索组件 - 提交到 /search，由服务端解析 !bang 并跳转到对应引擎
+ */
+export function SearchWidget({ config, engine, lang }: SearchWidgetProps) {
+    const t = (zh: string, en: string) => (lang === 'en' ? en : zh)
+    const [query, setQuery] = useState('')
+    const [selected, setSelected] = useState(engine || '')
+    // 历史记录只保存在本机浏览器中，且需用户主动开启
+    const [remember, setRemember] = useState(() => window.localStorage.getItem(HISTORY_OPT_IN_KEY) === 'true')
+    const [history, setHistory] = useState<string[]>(() => (remember ? loadHistory() : []))
+
+    const historyAllowed = !!config?.history
+    const engineId = selected || engine || config?.defaultEngine || ''
+
+    const toggleRemember = (on: boolean) => {
+        setRemember(on)
+        if (on) {
+            window.localStorage.setItem(HISTORY_OPT_IN_KEY, 'true')
+        } else {
+            window.localStorage.removeItem(HISTORY_OPT_IN_KEY)
+            window.localStorage.removeItem(HISTORY_KEY)
+            setHistory([])
+        }
+    }
+
+    const onSubmit = (e: FormEvent) => {
+        e.preventDefault()
+        const q = query.trim()
+        if (!q) return
+        if (historyAllowed && remember) {
+            const next = [q, ...history.filter((h) => h !== q)].slice(0, MAX_HISTORY)
+            window.localStorage.setItem(HISTORY_KEY, JSON.stringify(next))
+            setHistory(next)
+        }
+        const qs = new URLSearchParams({ q, ...(engineId ? { engine: engineId } : {}) })
+        window.location.assign(`/search?${qs.toString()}`)
+    }
+
+    const bangs = [...Object.keys(config?.bangs || {}), ...(config?.engines || []).map((e) => e.id)]
+
+    return (
+        <form onSubmit={onSubmit} className="flex h-full flex-col gap-2">
+            <div className="flex items-center gap-2 rounded-lg border border-white/10 bg-white/5 px-3 py-2">
+                <Search className="h-4 w-4 shrink-0 text-white/60" />
+                <input
+                    value={query}
+                    onChange={(e) => setQuery(e.target.value)}
+                    placeholder={t('搜索，或用 !gh 等快捷方式', 'Search, or use !gh and other bangs')}
+                    list={historyAllowed && remember ? 'hearth-search-history' : undefined}
+                    className="min-w-0 flex-1 bg-transparent text-sm text-white outline-none placeholder:text-white/40"
+                />
+                {config && config.engines.length > 1 && !engine ? (
+                    <select
+                        value={engineId}
+                        onChange={(e) => setSelected(e.target.value)}
+                        className="shrink-0 rounded bg-transparent text-xs text-white/70 outline-none"
+                    >
+                        {config.engines.map((e) => (
+                            <option key={e.id} value={e.id}>{e.name}</option>
+                        ))}
+                    </select>
+                ) : null}
+            </div>
+            {historyAllowed && remember ? (
+                <datalist id="hearth-search-history">
+                    {history.map((h) => (
+                        <option key={h} value={h} />
+                    ))}
+                </datalist>
+            ) : null}
+            <div className="flex items-center justify-between gap-2 text-[11px] text-white/50">
+                <span className="truncate">{bangs.slice(0, 6).map((b) => `!${b}`).join(' ')}</span>
+                {historyAllowed ? (
+                    <label className="flex shrink-0 items-center gap-1">
+                        <input type="checkbox" checked={remember} onChange={(e) => toggleRemember(e.target.checked)} />
+                        {t('记住搜索', 'Remember searches')}
+                    </label>
+                ) : null}
+            </div>
+        </form>
+    )
+}
@@ -16,7 +16,7 @@ export function WeatherWidget({ data, error, lang }: WeatherWidgetProps) {
         if (msg) return <div className="flex h-full items-center justify-center text-sm text-white/60">{msg}</div>
         return <div className="flex h-full items-center justify-center text-sm text-white/60">{lang === 'en' ? 'Loading…' : '加载中…'}</div>
     }
-    const cond = weatherCodeLabel(data.weatherCode, lang)
+    const cond = data.label || weatherCodeLabel(data.weatherCode, lang)
 
     const daily = (Array.isArray(data.daily) ? data.daily : []).slice(0, 5)
 
@@ -41,7 +41,7 @@ export function WeatherWidget({ data, error, lang }: WeatherWidgetProps) {
             {daily.length ? (
                 <div className="grid grid-cols-5 gap-1">
                     {daily.map((d) => (
-                        <div key={d.date} className="flex flex-col items-center gap-0.5 text-center">
+                        <div key={d.date} className="flex flex-col items-center gap-0.5 text-center" title={d.label}>
                             <div className="text-[10px] sm:text-[11px] leading-tight text-white/65">{weekdayLabel(d.date, lang)}</div>
                             <WeatherGlyph code={d.weatherCode ?? 0} windKph={0} className="w-7 h-7 sm:w-8 sm:h-8" />
                             <div className="tabular-nums text-[10px] sm:text-[11px] leading-tight text-white/80">
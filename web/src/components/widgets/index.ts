@@ -3,6 +3,10 @@ export { MetricsWidget } from './MetricsWidget'
 export { TimezonesWidget } from './TimezonesWidget'
 export { MarketsWidget } from './MarketsWidget'
 export { HolidaysWidget } from './HolidaysWidget'
+export { CustomWidget } from './CustomWidget'
+export { SearchWidget } from './SearchWidget'
+export { QuoteWidget } from './QuoteWidget'
+export { PluginWidget } from './PluginWidget'
 export { WeatherGlyph } from './WeatherGlyph'
 export { AppleClock } from './AppleClock'
 export { MiniSparkline } from './MiniSparkline'
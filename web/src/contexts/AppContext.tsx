@@ -1,6 +1,7 @@
 import { createContext, useContext, useState, useCallback, useEffect, type ReactNode } from 'react'
 import type { AppItem, BackgroundInfo, Group, Settings, Me, Language, CreateAppRequest, UpdateAppRequest } from '../types'
 import { apiGet, apiPost, apiPut, apiDelete } from '../api'
+import { uiLanguage } from '../utils'
 
 interface AppContextType {
     // State
@@ -47,7 +48,7 @@ export function AppProvider({ children }: { children: ReactNode }) {
     const [loading, setLoading] = useState(true)
     const [error, setError] = useState<string | null>(null)
 
-    const lang: Language = settings?.language === 'en' ? 'en' : 'zh'
+    const lang: Language = uiLanguage(settings?.language)
     const isAdmin = !!me?.admin
 
     const t = useCallback(
@@ -1,5 +1,8 @@
 export { useNow } from './useNow'
+export { useAppShortcuts } from './useAppShortcuts'
 export { useDashboard } from './useDashboard'
 export { useAuth, type UseAuthResult } from './useAuth'
 export { useDragSort, type UseDragSortResult, type DragHandlers, type UseDragSortOptions } from './useDragSort'
 export { useWidgets, type UseWidgetsResult } from './useWidgets'
+export { useTheme } from './useTheme'
+export { useCustomization } from './useCustomization'
@@ -0,0 +1,53 @@
+import { useEffect } from 'react'
+import type { AppItem } from '../types'
+
+// 两次按键之间超过该时长则重新开始
+const SEQUENCE_TIMEOUT_MS = 1000
+
+/**
+ * 按 App 的快捷键序列（如先按 g 再按 p）打开对应链接
+ * 输入框内的按键和带修饰键的按键会被忽略
+ */
+export function useAppShortcuts(apps: AppItem[], enabled: boolean = true) {
+    useEffect(() => {
+        const withShortcut = apps.filter((a) => a.shortcut)
+        if (!enabled || withShortcut.length === 0) return
+
+        let keys: string[] = []
+        let timer: number | undefined
+
+        const onKeyDown = (e: KeyboardEvent) => {
+            if (e.ctrlKey || e.metaKey || e.altKey || e.key.length !== 1) return
+            const target = e.target as HTMLElement | null
+            if (target && (target.isContentEditable || ['INPUT', 'TEXTAREA', 'SELECT'].includes(target.tagName))) return
+
+            window.clearTimeout(timer)
+            keys.push(e.key.toLowerCase())
+            const seq = keys.join(' ')
+            const app = withShortcut.find((a) => a.shortcut === seq)
+            if (app) {
+                keys = []
+                const href = app.href || app.url
+                if (app.openMode === 'same_tab') {
+                    window.location.href = href
+                } else {
+                    window.open(href, '_blank', 'noreferrer')
+                }
+                return
+            }
+            if (!withShortcut.some((a) => a.shortcut!.startsWith(seq + ' '))) {
+                keys = []
+                return
+            }
+            timer = window.setTimeout(() => {
+                keys = []
+            }, SEQUENCE_TIMEOUT_MS)
+        }
+
+        window.addEventListener('keydown', onKeyDown)
+        return () => {
+            window.removeEventListener('keydown', onKeyDown)
+            window.clearTimeout(timer)
+        }
+    }, [apps, enabled])
+}
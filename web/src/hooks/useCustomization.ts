@@ -0,0 +1,39 @@
+import { useEffect } from 'react'
+import { settingsApi } from '../api'
+
+// 与 useTheme 共用：主题的 customCss 就是这份样式表
+export const CUSTOM_CSS_ID = 'hearth-custom-css'
+const JS_ID = 'hearth-custom-js'
+
+/**
+ * 加载管理员自定义的 CSS 与 JS（/api/customization）并注入页面
+ * JS 仅在服务端开启 HEARTH_CUSTOM_JS 时返回，且每次页面加载只执行一次
+ */
+export function useCustomization(): void {
+    useEffect(() => {
+        let cancelled = false
+            ; (async () => {
+                try {
+                    const c = await settingsApi.getCustomization()
+                    if (cancelled) return
+                    if (c.css && !document.getElementById(CUSTOM_CSS_ID)) {
+                        const style = document.createElement('style')
+                        style.id = CUSTOM_CSS_ID
+                        style.textContent = c.css
+                        document.head.appendChild(style)
+                    }
+                    if (c.jsEnabled && c.js && !document.getElementById(JS_ID)) {
+                        const script = document.createElement('script')
+                        script.id = JS_ID
+                        script.textContent = c.js
+                        document.body.appendChild(script)
+                    }
+                } catch {
+                    // 自定义内容加载失败不影响页面
+                }
+            })()
+        return () => {
+            cancelled = true
+        }
+    }, [])
+}
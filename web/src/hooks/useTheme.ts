@@ -0,0 +1,42 @@
+import { useEffect, useState } from 'react'
+import type { ThemeSettings } from '../types'
+import { CUSTOM_CSS_ID } from './useCustomization'
+
+/**
+ * 将服务端保存的主题应用到页面：强调色、明暗模式、卡片不透明度、字号与自定义 CSS
+ * 自定义 CSS 与 useCustomization 注入的是同一份样式表，这里更新同一个 <style>，便于编辑时预览
+ */
+export function useTheme(theme: ThemeSettings | undefined): void {
+    const [prefersDark, setPrefersDark] = useState(() => window.matchMedia?.('(prefers-color-scheme: dark)').matches ?? true)
+
+    useEffect(() => {
+        const mq = window.matchMedia?.('(prefers-color-scheme: dark)')
+        if (!mq) return
+        const onChange = (e: MediaQueryListEvent) => setPrefersDark(e.matches)
+        mq.addEventListener('change', onChange)
+        return () => mq.removeEventListener('change', onChange)
+    }, [])
+
+    useEffect(() => {
+        if (!theme) return
+        const root = document.documentElement
+        const mode = theme.mode === 'auto' ? (prefersDark ? 'dark' : 'light') : theme.mode
+        root.dataset.theme = mode
+        root.style.colorScheme = mode
+        root.style.setProperty('--hearth-accent', theme.accent)
+        root.style.setProperty('--hearth-tile-opacity', String(theme.tileOpacity))
+        root.style.fontSize = theme.fontScale && theme.fontScale !== 1 ? `${theme.fontScale * 100}%` : ''
+
+        let el = document.getElementById(CUSTOM_CSS_ID) as HTMLStyleElement | null
+        if (!theme.customCss) {
+            el?.remove()
+            return
+        }
+        if (!el) {
+            el = document.createElement('style')
+            el.id = CUSTOM_CSS_ID
+            document.head.appendChild(el)
+        }
+        el.textContent = theme.customCss
+    }, [theme, prefersDark])
+}
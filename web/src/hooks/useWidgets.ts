@@ -1,6 +1,6 @@
-import { useEffect, useRef, useState } from 'react'
+import { useEffect, useState } from 'react'
 import { apiGet } from '../api'
-import type { AppItem, Weather, MarketsResponse, HolidaysResponse, HostMetrics } from '../types'
+import type { AppItem, Weather, MarketsResponse, HolidaysResponse, HostMetrics, MetricsHistory, DockerContainer, IngestValue, Embed, SearchConfig, Quote, PluginOutput, Language } from '../types'
 import { safeParseJSON, widgetKindFromUrl } from '../utils'
 
 export interface UseWidgetsResult {
@@ -16,15 +16,35 @@ export interface UseWidgetsResult {
     /** Holidays data by widget ID */
     holidaysById: Record<string, HolidaysResponse | null>
     holidaysErrById: Record<string, string | null>
+    /** Pushed custom data by widget ID */
+    customById: Record<string, IngestValue | null>
+    customErrById: Record<string, string | null>
+    /** Registered embeds, when an iframe widget is shown */
+    embeds: Embed[] | null
+    /** Search engines and bangs, when a search widget is shown */
+    searchConfig: SearchConfig | null
+    /** Quote of the day by widget ID */
+    quotesById: Record<string, Quote | null>
+    quotesErrById: Record<string, string | null>
+    /** Plugin output by widget ID */
+    pluginsById: Record<string, PluginOutput | null>
+    pluginsErrById: Record<string, string | null>
     /** Host metrics */
     metrics: HostMetrics | null
     /** Network rate */
     netRate: { upBps: number; downBps: number } | null
+    /** Last hour of host metrics, for trend charts */
+    metricsHistory: MetricsHistory | null
+    /** Docker containers, when a metrics widget shows them */
+    dockerContainers: DockerContainer[] | null
+    /** Metrics of remote agents, by host name */
+    metricsByHost: Record<string, HostMetrics | null>
 }
 
 interface UseWidgetsOptions {
     apps: AppItem[]
-    lang: 'zh' | 'en'
+    /** BCP-47 tag passed to the widget APIs */
+    lang: Language
     defaultCity?: string
 }
 
@@ -60,9 +80,23 @@ export function useWidgets({ apps, lang, defaultCity }: UseWidgetsOptions): UseW
     const [holidaysById, setHolidaysById] = useState<Record<string, HolidaysResponse | null>>({})
     const [holidaysErrById, setHolidaysErrById] = useState<Record<string, string | null>>({})
 
+    const [customById, setCustomById] = useState<Record<string, IngestValue | null>>({})
+    const [customErrById, setCustomErrById] = useState<Record<string, string | null>>({})
+
+    const [embeds, setEmbeds] = useState<Embed[] | null>(null)
+    const [searchConfig, setSearchConfig] = useState<SearchConfig | null>(null)
+
+    const [quotesById, setQuotesById] = useState<Record<string, Quote | null>>({})
+    const [quotesErrById, setQuotesErrById] = useState<Record<string, string | null>>({})
+
+    const [pluginsById, setPluginsById] = useState<Record<string, PluginOutput | null>>({})
+    const [pluginsErrById, setPluginsErrById] = useState<Record<string, string | null>>({})
+
     const [metrics, setMetrics] = useState<HostMetrics | null>(null)
     const [netRate, setNetRate] = useState<{ upBps: number; downBps: number } | null>(null)
-    const lastMetricsRef = useRef<HostMetrics | null>(null)
+    const [metricsHistory, setMetricsHistory] = useState<MetricsHistory | null>(null)
+    const [dockerContainers, setDockerContainers] = useState<DockerContainer[] | null>(null)
+    const [metricsByHost, setMetricsByHost] = useState<Record<string, HostMetrics | null>>({})
 
     // Fetch default weather
     useEffect(() => {
@@ -237,6 +271,182 @@ export function useWidgets({ apps, lang, defaultCity }: UseWidgetsOptions): UseW
         }
     }, [apps])
 
+    // Fetch pushed data for custom widgets
+    useEffect(() => {
+        let cancelled = false
+        const ws = apps.filter((a) => widgetKindFromUrl(a.url) === 'custom')
+        if (ws.length === 0) {
+            setCustomById({})
+            setCustomErrById({})
+            return
+        }
+
+        const run = async () => {
+            const next: Record<string, IngestValue | null> = {}
+            const nextErr: Record<string, string | null> = {}
+
+            await Promise.all(
+                ws.map(async (a) => {
+                    const cfg = safeParseJSON(a.description)
+                    const key = String(cfg?.key ?? '').trim()
+                    if (!key) {
+                        next[a.id] = null
+                        nextErr[a.id] = null
+                        return
+                    }
+                    try {
+                        next[a.id] = await apiGet<IngestValue>(`/api/ingest/${encodeURIComponent(key)}`)
+                        nextErr[a.id] = null
+                    } catch (e) {
+                        next[a.id] = null
+                        nextErr[a.id] = e instanceof Error ? e.message : 'failed'
+                    }
+                })
+            )
+
+            if (!cancelled) {
+                setCustomById(next)
+                setCustomErrById(nextErr)
+            }
+        }
+
+        void run()
+        const id = window.setInterval(run, 30_000)
+        return () => {
+            cancelled = true
+            window.clearInterval(id)
+        }
+    }, [apps])
+
+    // Fetch registered embeds for iframe widgets
+    useEffect(() => {
+        let cancelled = false
+        if (!apps.some((a) => widgetKindFromUrl(a.url) === 'iframe')) {
+            setEmbeds(null)
+            return
+        }
+
+        void (async () => {
+            try {
+                const res = await apiGet<{ frames: Embed[] }>('/api/widgets/embeds')
+                if (!cancelled) setEmbeds(res.frames)
+            } catch {
+                if (!cancelled) setEmbeds([])
+            }
+        })()
+
+        return () => {
+            cancelled = true
+        }
+    }, [apps])
+
+    // Fetch search config for search widgets
+    useEffect(() => {
+        let cancelled = false
+        if (!apps.some((a) => widgetKindFromUrl(a.url) === 'search')) {
+            setSearchConfig(null)
+            return
+        }
+
+        void (async () => {
+            try {
+                const res = await apiGet<SearchConfig>('/api/widgets/search-config')
+                if (!cancelled) setSearchConfig(res)
+            } catch {
+                if (!cancelled) setSearchConfig(null)
+            }
+        })()
+
+        return () => {
+            cancelled = true
+        }
+    }, [apps])
+
+    // Fetch the quote of the day for quote widgets
+    useEffect(() => {
+        let cancelled = false
+        const ws = apps.filter((a) => widgetKindFromUrl(a.url) === 'quote')
+        if (ws.length === 0) {
+            setQuotesById({})
+            setQuotesErrById({})
+            return
+        }
+
+        const run = async () => {
+            const next: Record<string, Quote | null> = {}
+            const nextErr: Record<string, string | null> = {}
+
+            await Promise.all(
+                ws.map(async (a) => {
+                    const cfg = safeParseJSON(a.description)
+                    const source = String(cfg?.source ?? 'bundled')
+                    try {
+                        const qs = new URLSearchParams({ source, lang })
+                        next[a.id] = await apiGet<Quote>(`/api/widgets/quote?${qs.toString()}`)
+                        nextErr[a.id] = null
+                    } catch (e) {
+                        next[a.id] = null
+                        nextErr[a.id] = e instanceof Error ? e.message : 'failed'
+                    }
+                })
+            )
+
+            if (!cancelled) {
+                setQuotesById(next)
+                setQuotesErrById(nextErr)
+            }
+        }
+
+        void run()
+        // The quote changes at midnight; hourly is often enough to pick it up.
+        const id = window.setInterval(run, 60 * 60 * 1000)
+        return () => {
+            cancelled = true
+            window.clearInterval(id)
+        }
+    }, [apps, lang])
+
+    // Fetch plugin output for plugin widgets (the server caches per plugin)
+    useEffect(() => {
+        let cancelled = false
+        const ws = apps.filter((a) => widgetKindFromUrl(a.url) === 'plugin')
+        if (ws.length === 0) {
+            setPluginsById({})
+            setPluginsErrById({})
+            return
+        }
+
+        const run = async () => {
+            const next: Record<string, PluginOutput | null> = {}
+            const nextErr: Record<string, string | null> = {}
+
+            await Promise.all(
+                ws.map(async (a) => {
+                    try {
+                        const qs = new URLSearchParams({ lang })
+                        next[a.id] = await apiGet<PluginOutput>(`/api/widgets/${a.id}/plugin?${qs.toString()}`)
+                        nextErr[a.id] = null
+                    } catch (e) {
+                        next[a.id] = null
+                        nextErr[a.id] = e instanceof Error ? e.message : 'failed'
+                    }
+                })
+            )
+
+            if (!cancelled) {
+                setPluginsById(next)
+                setPluginsErrById(nextErr)
+            }
+        }
+
+        void run()
+        const id = window.setInterval(run, 60_000)
+        return () => {
+            cancelled = true
+            window.clearInterval(id)
+        }
+    }, [apps, lang])
+
     // Fetch host metrics
     useEffect(() => {
         let cancelled = false
@@ -258,21 +468,7 @@ export function useWidgets({ apps, lang, defaultCity }: UseWidgetsOptions): UseW
             try {
                 const m = await apiGet<HostMetrics>('/api/metrics/host')
                 if (cancelled) return
-                const prev = lastMetricsRef.current
-                if (
-                    prev &&
-                    typeof prev.collectedAt === 'number' &&
-                    typeof m.collectedAt === 'number' &&
-                    m.collectedAt > prev.collectedAt
-                ) {
-                    const dt = (m.collectedAt - prev.collectedAt) / 1000
-                    if (dt > 0) {
-                        const sentDiff = Math.max(0, m.netBytesSent - prev.netBytesSent)
-                        const recvDiff = Math.max(0, m.netBytesRecv - prev.netBytesRecv)
-                        setNetRate({ upBps: sentDiff / dt, downBps: recvDiff / dt })
-                    }
-                }
-                lastMetricsRef.current = m
+                setNetRate({ upBps: m.netSendRate ?? 0, downBps: m.netRecvRate ?? 0 })
                 setMetrics(m)
             } catch {
                 if (!cancelled) {
@@ -290,6 +486,93 @@ export function useWidgets({ apps, lang, defaultCity }: UseWidgetsOptions): UseW
         }
     }, [apps])
 
+    // Fetch host metrics history (recorded server-side; refreshed once a minute)
+    useEffect(() => {
+        if (!apps.some((a) => widgetKindFromUrl(a.url) === 'metrics')) return
+        let cancelled = false
+
+        const run = async () => {
+            try {
+                const h = await apiGet<MetricsHistory>('/api/metrics/host/history?range=1h')
+                if (!cancelled) setMetricsHistory(h)
+            } catch {
+                if (!cancelled) setMetricsHistory(null)
+            }
+        }
+
+        void run()
+        const id = window.setInterval(run, 60_000)
+        return () => {
+            cancelled = true
+            window.clearInterval(id)
+        }
+    }, [apps])
+
+    // Fetch metrics of remote agents (the server polls them; this reads its latest reading)
+    useEffect(() => {
+        const hosts = Array.from(
+            new Set(
+                apps
+                    .filter((a) => widgetKindFromUrl(a.url) === 'metrics')
+                    .map((a) => safeParseJSON(a.description)?.host)
+                    .filter((h): h is string => typeof h === 'string' && h !== ''),
+            ),
+        )
+        if (hosts.length === 0) {
+            setMetricsByHost({})
+            return
+        }
+        let cancelled = false
+
+        const run = async () => {
+            const entries = await Promise.all(
+                hosts.map(async (h) => {
+                    try {
+                        return [h, await apiGet<HostMetrics>(`/api/metrics/host?host=${encodeURIComponent(h)}`)] as const
+                    } catch {
+                        return [h, null] as const
+                    }
+                }),
+            )
+            if (!cancelled) setMetricsByHost(Object.fromEntries(entries))
+        }
+
+        void run()
+        const id = window.setInterval(run, 10_000)
+        return () => {
+            cancelled = true
+            window.clearInterval(id)
+        }
+    }, [apps])
+
+    // Fetch Docker container stats (server caches them for a few seconds)
+    useEffect(() => {
+        const wanted = apps.some(
+            (a) => widgetKindFromUrl(a.url) === 'metrics' && safeParseJSON(a.description)?.showDocker === true,
+        )
+        if (!wanted) {
+            setDockerContainers(null)
+            return
+        }
+        let cancelled = false
+
+        const run = async () => {
+            try {
+                const res = await apiGet<{ containers: DockerContainer[] }>('/api/metrics/docker')
+                if (!cancelled) setDockerContainers(res.containers)
+            } catch {
+                if (!cancelled) setDockerContainers(null)
+            }
+        }
+
+        void run()
+        const id = window.setInterval(run, 10_000)
+        return () => {
+            cancelled = true
+            window.clearInterval(id)
+        }
+    }, [apps])
+
     return {
         weather,
         weatherErr,
@@ -299,8 +582,19 @@ export function useWidgets({ apps, lang, defaultCity }: UseWidgetsOptions): UseW
         marketsErrById,
         holidaysById,
         holidaysErrById,
+        customById,
+        customErrById,
+        embeds,
+        searchConfig,
+        quotesById,
+        quotesErrById,
+        pluginsById,
+        pluginsErrById,
         metrics,
         netRate,
+        metricsHistory,
+        dockerContainers,
+        metricsByHost,
     }
 }
 
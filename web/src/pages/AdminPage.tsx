@@ -11,6 +11,7 @@ import { CSS } from '@dnd-kit/utilities'
 import { useEffect, useMemo, useState } from 'react'
 import { apiDelete, apiDownload, apiGet, apiPost, apiPut } from '../api'
 import type { AppItem, Group, Settings } from '../types'
+import { uiLanguage } from '../utils'
 
 type Me = { admin: boolean }
 
@@ -67,7 +68,7 @@ export default function AdminPage() {
 
     const sensors = useSensors(useSensor(PointerSensor, { activationConstraint: { distance: 6 } }))
 
-    const lang: 'zh' | 'en' = settings?.language === 'en' ? 'en' : 'zh'
+    const lang: 'zh' | 'en' = uiLanguage(settings?.language)
     const t = (zh: string, en: string) => (lang === 'en' ? en : zh)
 
     const displayGroupName = (raw: string): string => {
@@ -339,14 +340,15 @@ export default function AdminPage() {
         }
     }
 
-    const doExport = async () => {
+    // scope 为空时导出完整备份，否则只导出这些部分（如 groups,apps,widgets）
+    const doExport = async (scope?: string) => {
         setErr(null)
         try {
-            const blob = await apiDownload('/api/export')
+            const blob = await apiDownload(scope ? `/api/export?scope=${encodeURIComponent(scope)}` : '/api/export')
             const url = URL.createObjectURL(blob)
             const a = document.createElement('a')
             a.href = url
-            a.download = `hearth-export-${new Date().toISOString().slice(0, 10)}.json`
+            a.download = `hearth-${scope ? 'layout' : 'export'}-${new Date().toISOString().slice(0, 10)}.json`
             document.body.appendChild(a)
             a.click()
             a.remove()
@@ -522,9 +524,12 @@ export default function AdminPage() {
                 <section className="rounded-xl border border-white/10 bg-black/40 p-4">
                     <h2 className="mb-3 text-sm font-semibold">{t('导入 / 导出', 'Import / Export')}</h2>
                     <div className="flex flex-wrap items-center gap-3">
-                        <button onClick={doExport} className="rounded-lg bg-white/10 px-4 py-2 text-sm hover:bg-white/20">
+                        <button onClick={() => void doExport()} className="rounded-lg bg-white/10 px-4 py-2 text-sm hover:bg-white/20">
                             {t('导出 JSON', 'Export JSON')}
                         </button>
+                        <button onClick={() => void doExport('groups,apps,widgets')} className="rounded-lg bg-white/10 px-4 py-2 text-sm hover:bg-white/20">
+                            {t('仅导出布局', 'Export layout only')}
+                        </button>
                         <label className="rounded-lg bg-white/10 px-4 py-2 text-sm hover:bg-white/20">
                             <input
                                 type="file"
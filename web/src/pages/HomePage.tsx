@@ -1,8 +1,8 @@
 import { type FormEvent, useCallback, useEffect, useMemo, useRef, useState } from 'react'
-import { apiDelete, apiGet, apiPost, apiPut } from '../api'
+import { apiDelete, apiGet, apiPost, apiPut, widgetsApi } from '../api'
 import { Cog } from 'lucide-react'
-import type { AppItem, BackgroundInfo, Group, Settings, Me, IconResolve } from '../types'
-import { useNow, useWidgets } from '../hooks'
+import type { AppItem, BackgroundAction, BackgroundInfo, Group, Settings, Me, IconResolve, QuoteSource } from '../types'
+import { useAppShortcuts, useCustomization, useNow, useTheme, useWidgets } from '../hooks'
 import { UserIcon } from '../components/ui/UserIcon'
 import { TimeDisplay } from '../components/layout/TimeDisplay'
 import { GroupBlock } from '../components/layout/GroupBlock'
@@ -17,6 +17,7 @@ import {
     widgetQueryFromUrl,
     safeParseJSON,
     DEFAULT_CLOCKS,
+    uiLanguage,
 } from '../utils'
 
 export default function HomePage({ initialDialog }: { initialDialog?: 'login' } = {}) {
@@ -56,7 +57,7 @@ export default function HomePage({ initialDialog }: { initialDialog?: 'login' }
     const [editLucideIcon, setEditLucideIcon] = useState<string | null>(null)
     const [iconResolving, setIconResolving] = useState(false)
 
-    const [widgetKind, setWidgetKind] = useState<'weather' | 'timezones' | 'metrics' | 'markets' | 'holidays' | null>(null)
+    const [widgetKind, setWidgetKind] = useState<'weather' | 'timezones' | 'metrics' | 'markets' | 'holidays' | 'custom' | 'iframe' | 'search' | 'quote' | 'plugin' | null>(null)
     const [wCity, setWCity] = useState('')
 
     const DEFAULT_MARKET_SYMBOLS = ['BTC', 'ETH', 'AAPL', 'MSFT']
@@ -95,9 +96,22 @@ export default function HomePage({ initialDialog }: { initialDialog?: 'login' }
     const [mShowMem, setMShowMem] = useState(true)
     const [mShowDisk, setMShowDisk] = useState(true)
     const [mShowNet, setMShowNet] = useState(true)
+    const [mShowCores, setMShowCores] = useState(false)
+    const [mShowDocker, setMShowDocker] = useState(false)
+    const [mHost, setMHost] = useState('')
     const [mRefreshSec, setMRefreshSec] = useState<1 | 5 | 10>(1)
 
-    const [siteDraft, setSiteDraft] = useState<Pick<Settings, 'siteTitle' | 'background' | 'time' | 'language'> | null>(null)
+    const [cKey, setCKey] = useState('')
+    const [cTitle, setCTitle] = useState('')
+    const [cField, setCField] = useState('')
+
+    const [iEmbed, setIEmbed] = useState('')
+    const [sEngine, setSEngine] = useState('')
+    const [qSource, setQSource] = useState<QuoteSource>('bundled')
+    const [pPlugin, setPPlugin] = useState('')
+    const [pOptions, setPOptions] = useState<Record<string, string>>({})
+
+    const [siteDraft, setSiteDraft] = useState<Pick<Settings, 'siteTitle' | 'background' | 'time' | 'language' | 'theme'> | null>(null)
     const [siteSaveErr, setSiteSaveErr] = useState<string | null>(null)
 
     // Group drag-and-drop states
@@ -112,7 +126,7 @@ export default function HomePage({ initialDialog }: { initialDialog?: 'login' }
     const now = useNow(1000)
     // Timezone is now auto-detected from the user's system.
 
-    const lang: 'zh' | 'en' = settings?.language === 'en' ? 'en' : 'zh'
+    const lang: 'zh' | 'en' = uiLanguage(settings?.language)
     const t = (zh: string, en: string) => (lang === 'en' ? en : zh)
 
     // Use the useWidgets hook for widget data fetching
@@ -125,14 +139,30 @@ export default function HomePage({ initialDialog }: { initialDialog?: 'login' }
         marketsErrById,
         holidaysById,
         holidaysErrById,
+        customById,
+        customErrById,
+        embeds,
+        searchConfig,
+        quotesById,
+        quotesErrById,
+        pluginsById,
+        pluginsErrById,
         metrics,
         netRate,
+        metricsHistory,
+        dockerContainers,
+        metricsByHost,
     } = useWidgets({
         apps,
-        lang,
+        // 服务端文案（天气描述、节日名称）按完整语言标签本地化
+        lang: settings?.language || lang,
         defaultCity: settings?.weather?.city,
     })
 
+    // 草稿优先，设置中调整外观时即时预览
+    useTheme(siteDraft?.theme ?? settings?.theme)
+    useCustomization()
+
     const reloadDashboard = async () => {
         const [m, st, bgInfo, gs, as] = await Promise.all([
             apiGet<Me>('/api/auth/me'),
@@ -142,6 +172,7 @@ export default function HomePage({ initialDialog }: { initialDialog?: 'login' }
             apiGet<AppItem[]>('/api/apps'),
         ])
         setMe(m)
+        if (m?.mustChangePassword) setSettingsOpen(true)
         setSettings(st)
         setBg(bgInfo)
         setGroups(Array.isArray(gs) ? gs : [])
@@ -174,7 +205,7 @@ export default function HomePage({ initialDialog }: { initialDialog?: 'login' }
     useEffect(() => {
         if (apps.length === 0) return
 
-        const autoIconSources = new Set(['site', 'fallback', 'google', 'auto'])
+        const autoIconSources = new Set(['site', 'fallback', 'og', 'google', 'auto'])
 
         // Only refresh non-widget apps that have URLs and auto icon sources
         const customApps = apps.filter((a) => {
@@ -304,6 +335,9 @@ export default function HomePage({ initialDialog }: { initialDialog?: 'login' }
                 setMShowMem(cfg?.showMem !== false)
                 setMShowDisk(cfg?.showDisk !== false)
                 setMShowNet(cfg?.showNet !== false)
+                setMShowCores(cfg?.showCores === true)
+                setMShowDocker(cfg?.showDocker === true)
+                setMHost(typeof cfg?.host === 'string' ? cfg.host : '')
                 const rs = Number(cfg?.refreshSec)
                 setMRefreshSec(rs === 5 || rs === 10 ? rs : 1)
             }
@@ -326,6 +360,30 @@ export default function HomePage({ initialDialog }: { initialDialog?: 'login' }
                 setHCountryQuery('')
                 setCityQuery('')
             }
+            if (widgetType === 'custom') {
+                setCKey(typeof cfg?.key === 'string' ? cfg.key : '')
+                setCTitle(typeof cfg?.title === 'string' ? cfg.title : '')
+                setCField(typeof cfg?.field === 'string' ? cfg.field : '')
+            }
+            if (widgetType === 'iframe') {
+                setIEmbed(typeof cfg?.embed === 'string' ? cfg.embed : '')
+            }
+            if (widgetType === 'search') {
+                setSEngine(typeof cfg?.engine === 'string' ? cfg.engine : '')
+            }
+            if (widgetType === 'quote') {
+                setQSource(cfg?.source === 'quotable' || cfg?.source === 'custom' ? cfg.source : 'bundled')
+            }
+            if (widgetType === 'plugin') {
+                setPPlugin(typeof cfg?.plugin === 'string' ? cfg.plugin : '')
+                const opts: Record<string, string> = {}
+                if (cfg?.options && typeof cfg.options === 'object') {
+                    for (const [k, v] of Object.entries(cfg.options as Record<string, unknown>)) {
+                        if (typeof v === 'string') opts[k] = v
+                    }
+                }
+                setPOptions(opts)
+            }
         } else {
             setWidgetKind(null)
         }
@@ -472,6 +530,9 @@ export default function HomePage({ initialDialog }: { initialDialog?: 'login' }
                             showMem: !!mShowMem,
                             showDisk: !!mShowDisk,
                             showNet: !!mShowNet,
+                            showCores: !!mShowCores,
+                            showDocker: !!mShowDocker,
+                            ...(mHost ? { host: mHost } : {}),
                             refreshSec: mRefreshSec,
                         })
                     } else if (widgetKind === 'markets') {
@@ -480,6 +541,16 @@ export default function HomePage({ initialDialog }: { initialDialog?: 'login' }
                     } else if (widgetKind === 'holidays') {
                         const countries = normalizeCountryCodes(hCountryCodes)
                         description = JSON.stringify({ countries })
+                    } else if (widgetKind === 'custom') {
+                        description = JSON.stringify({ key: cKey.trim(), ...(cTitle.trim() ? { title: cTitle.trim() } : {}), ...(cField.trim() ? { field: cField.trim() } : {}) })
+                    } else if (widgetKind === 'iframe') {
+                        description = JSON.stringify({ embed: iEmbed })
+                    } else if (widgetKind === 'search') {
+                        description = JSON.stringify(sEngine ? { engine: sEngine } : {})
+                    } else if (widgetKind === 'quote') {
+                        description = JSON.stringify({ source: qSource })
+                    } else if (widgetKind === 'plugin') {
+                        description = JSON.stringify({ plugin: pPlugin, options: pOptions })
                     } else if (widgetKind === 'timezones') {
                         // IMPORTANT: do NOT auto-resolve/overwrite city strings while typing.
                         // We only resolve (city->timezone & full city label) when the user picks a suggestion.
@@ -500,14 +571,7 @@ export default function HomePage({ initialDialog }: { initialDialog?: 'login' }
                     if (description === widgetLastSavedDescRef.current) return
 
                     widgetLastSavedDescRef.current = description
-                    await apiPut(`/api/apps/${itemId}`, {
-                        groupId: editItem.groupId,
-                        name: editItem.name,
-                        description,
-                        url: editItem.url,
-                        iconPath: editItem.iconPath,
-                        iconSource: editItem.iconSource,
-                    })
+                    await widgetsApi.putWidgetConfig(itemId, JSON.parse(description))
 
                     if (widgetSaveSeqRef.current === seq) {
                         setApps((prev) => prev.map((a) => (a.id === itemId ? { ...a, description } : a)))
@@ -537,10 +601,21 @@ export default function HomePage({ initialDialog }: { initialDialog?: 'login' }
         mShowMem,
         mShowDisk,
         mShowNet,
+        mShowCores,
+        mShowDocker,
+        mHost,
         mRefreshSec,
         mkSymbols,
         ensureFourMarketSymbols,
         hCountryCodes,
+        cKey,
+        cTitle,
+        cField,
+        iEmbed,
+        sEngine,
+        qSource,
+        pPlugin,
+        pOptions,
         hCountryQuery,
     ])
 
@@ -592,7 +667,17 @@ export default function HomePage({ initialDialog }: { initialDialog?: 'login' }
                     clocks: resolved.map((c) => ({ city: c.city, timezone: c.timezone })),
                 })
             } else if (widgetKind === 'metrics') {
-                description = JSON.stringify({ showCpu: !!mShowCpu, showMem: !!mShowMem, showDisk: !!mShowDisk, showNet: !!mShowNet, refreshSec: mRefreshSec })
+                description = JSON.stringify({ showCpu: !!mShowCpu, showMem: !!mShowMem, showDisk: !!mShowDisk, showNet: !!mShowNet, showCores: !!mShowCores, showDocker: !!mShowDocker, ...(mHost ? { host: mHost } : {}), refreshSec: mRefreshSec })
+            } else if (widgetKind === 'custom') {
+                description = JSON.stringify({ key: cKey.trim(), ...(cTitle.trim() ? { title: cTitle.trim() } : {}), ...(cField.trim() ? { field: cField.trim() } : {}) })
+            } else if (widgetKind === 'iframe') {
+                description = JSON.stringify({ embed: iEmbed })
+            } else if (widgetKind === 'search') {
+                description = JSON.stringify(sEngine ? { engine: sEngine } : {})
+            } else if (widgetKind === 'quote') {
+                description = JSON.stringify({ source: qSource })
+            } else if (widgetKind === 'plugin') {
+                description = JSON.stringify({ plugin: pPlugin, options: pOptions })
             }
         } else if (!isWidget) {
             description = editDesc || null  // Keep spaces if user wants blank display
@@ -633,6 +718,12 @@ export default function HomePage({ initialDialog }: { initialDialog?: 'login' }
                 url,
                 iconPath,
                 iconSource,
+                // 对话框不编辑的字段原样保留
+                internalUrl: editItem.internalUrl,
+                openMode: editItem.openMode,
+                wakeMac: editItem.wakeMac,
+                shortcut: editItem.shortcut,
+                actions: editItem.actions,
             })
             setEditOpen(false)
             setEditItem(null)
@@ -697,7 +788,7 @@ export default function HomePage({ initialDialog }: { initialDialog?: 'login' }
         }
     }
 
-    const persistSiteDraft = async (draft: Pick<Settings, 'siteTitle' | 'background' | 'time' | 'language'>) => {
+    const persistSiteDraft = async (draft: Pick<Settings, 'siteTitle' | 'background' | 'time' | 'language' | 'theme'>) => {
         if (!isAdmin || !settings) return
         const token = ++siteSaveSeqRef.current
         setSiteSaveErr(null)
@@ -715,6 +806,7 @@ export default function HomePage({ initialDialog }: { initialDialog?: 'login' }
                 language: draft.language,
                 background: draft.background,
                 time: normalizedTime,
+                theme: draft.theme,
             }
             await apiPut('/api/settings', next)
             if (token !== siteSaveSeqRef.current) return
@@ -725,7 +817,7 @@ export default function HomePage({ initialDialog }: { initialDialog?: 'login' }
         }
     }
 
-    const schedulePersistSiteDraft = (draft: Pick<Settings, 'siteTitle' | 'background' | 'time' | 'language'>, mode: 'now' | 'debounce') => {
+    const schedulePersistSiteDraft = (draft: Pick<Settings, 'siteTitle' | 'background' | 'time' | 'language' | 'theme'>, mode: 'now' | 'debounce') => {
         if (siteSaveTimerRef.current) {
             window.clearTimeout(siteSaveTimerRef.current)
             siteSaveTimerRef.current = null
@@ -815,6 +907,9 @@ export default function HomePage({ initialDialog }: { initialDialog?: 'login' }
     const title = settings?.siteTitle || 'Hearth'
     const baseBgUrl = bg?.imageUrl || '/api/background/image'
     const bgUrl = baseBgUrl + (baseBgUrl.includes('?') ? '&' : '?') + `v=${bgNonce}`
+    // Tiny server-rendered placeholder shown until the full image has loaded.
+    const bgThumbUrl = `${bgUrl}&variant=thumb`
+    const [bgLoadedUrl, setBgLoadedUrl] = useState<string | null>(null)
     const isAdmin = !!me?.admin
 
     const systemTimezone = useMemo(() => {
@@ -847,6 +942,8 @@ export default function HomePage({ initialDialog }: { initialDialog?: 'login' }
         document.title = lang === 'en' ? `Hearth: ${siteTitle}` : `Hearth：${siteTitle}`
     }, [lang, settings?.siteTitle])
 
+    useAppShortcuts(apps, !(loginOpen || settingsOpen || createGroupOpen || addItemOpen || editOpen))
+
     useEffect(() => {
         if (!editOpen) {
             // Reset when dialog closes
@@ -881,7 +978,7 @@ export default function HomePage({ initialDialog }: { initialDialog?: 'login' }
         return () => window.clearTimeout(id)
     }, [editOpen, widgetKind, cityQuery, lang])
 
-    const refreshBackground = async () => {
+    const refreshBackground = async (action: BackgroundAction = 'refresh') => {
         if (!isAdmin) return
         if (bgRefreshing) return
         setBgRefreshing(true)
@@ -895,7 +992,7 @@ export default function HomePage({ initialDialog }: { initialDialog?: 'login' }
 
             // Avoid spinning forever if the network/proxy hangs.
             const refreshRes = await fetchWithTimeout(
-                `/api/background/refresh?${new URLSearchParams({ provider }).toString()}`,
+                `/api/background/${action}?${new URLSearchParams({ provider }).toString()}`,
                 { method: 'POST', credentials: 'include' },
                 15000,
             )
@@ -955,6 +1052,8 @@ export default function HomePage({ initialDialog }: { initialDialog?: 'login' }
             ])
 
             setBgNonce(nextNonce)
+            // Pick up the attribution of the new image.
+            setBg(await apiGet<BackgroundInfo>('/api/background'))
         } catch (e2) {
             setBgRefreshErr(e2 instanceof Error ? e2.message : 'failed')
         } finally {
@@ -982,6 +1081,7 @@ export default function HomePage({ initialDialog }: { initialDialog?: 'login' }
             language: settings.language || 'zh',
             background: { ...settings.background, provider, interval },
             time: settings.time ? { ...settings.time, timezone: systemTimezone } : settings.time,
+            theme: settings.theme,
         })
     }, [settings, siteDraft])
 
@@ -1001,10 +1101,33 @@ export default function HomePage({ initialDialog }: { initialDialog?: 'login' }
             }}
         >
             <div className="pointer-events-none fixed inset-0 -z-10 overflow-hidden">
-                <img src={bgUrl} alt="background" className="h-full w-full scale-105 object-cover blur-sm" />
+                <div
+                    className="absolute inset-0 scale-110 bg-cover bg-center blur-md"
+                    style={{ backgroundImage: `url(${bgThumbUrl})` }}
+                />
+                <img
+                    src={bgUrl}
+                    alt="background"
+                    onLoad={() => setBgLoadedUrl(bgUrl)}
+                    className={`relative h-full w-full scale-105 object-cover blur-sm transition-opacity duration-500 ${
+                        bgLoadedUrl === bgUrl ? 'opacity-100' : 'opacity-0'
+                    }`}
+                />
                 <div className="absolute inset-0 bg-black/60" />
             </div>
 
+            {bg?.title || bg?.credit ? (
+                <div className="fixed bottom-2 right-3 z-10 max-w-[40vw] truncate text-[11px] text-white/50">
+                    {bg.link ? (
+                        <a href={bg.link} target="_blank" rel="noreferrer" className="hover:text-white/80">
+                            {[bg.title, bg.credit && `© ${bg.credit}`].filter(Boolean).join(' · ')}
+                        </a>
+                    ) : (
+                        [bg.title, bg.credit && `© ${bg.credit}`].filter(Boolean).join(' · ')
+                    )}
+                </div>
+            ) : null}
+
             <div className="fixed right-4 top-4 z-20 flex items-center gap-2">
                 {isAdmin ? (
                     <button
@@ -1150,9 +1273,21 @@ export default function HomePage({ initialDialog }: { initialDialog?: 'login' }
                                         marketsErrById={marketsErrById}
                                         holidaysById={holidaysById}
                                         holidaysErrById={holidaysErrById}
+                                        customById={customById}
+                                        customErrById={customErrById}
+                                        embeds={embeds}
+                                        searchConfig={searchConfig}
+                                        quotesById={quotesById}
+                                        quotesErrById={quotesErrById}
+                                        pluginsById={pluginsById}
+                                        pluginsErrById={pluginsErrById}
                                         metrics={metrics}
                                         netRate={netRate}
+                                        metricsHistory={metricsHistory}
+                                        dockerContainers={dockerContainers}
+                                        metricsByHost={metricsByHost}
                                         localTimezone={systemTimezone}
+                                        formatting={settings?.formatting}
                                         lang={lang}
                                     />
                                 )
@@ -1216,9 +1351,21 @@ export default function HomePage({ initialDialog }: { initialDialog?: 'login' }
                                         marketsErrById={marketsErrById}
                                         holidaysById={holidaysById}
                                         holidaysErrById={holidaysErrById}
+                                        customById={customById}
+                                        customErrById={customErrById}
+                                        embeds={embeds}
+                                        searchConfig={searchConfig}
+                                        quotesById={quotesById}
+                                        quotesErrById={quotesErrById}
+                                        pluginsById={pluginsById}
+                                        pluginsErrById={pluginsErrById}
                                         metrics={metrics}
                                         netRate={netRate}
+                                        metricsHistory={metricsHistory}
+                                        dockerContainers={dockerContainers}
+                                        metricsByHost={metricsByHost}
                                         localTimezone={systemTimezone}
+                                        formatting={settings?.formatting}
                                         lang={lang}
                                     />
                                 </div>
@@ -1289,6 +1436,10 @@ export default function HomePage({ initialDialog }: { initialDialog?: 'login' }
                 bgRefreshErr={bgRefreshErr}
                 refreshBackground={refreshBackground}
                 onLogout={onLogout}
+                initialTab={me?.mustChangePassword ? 'account' : undefined}
+                onPasswordChanged={() => {
+                    if (me?.mustChangePassword) void reloadDashboard()
+                }}
             />
 
             <CreateGroupDialog
@@ -1349,6 +1500,28 @@ export default function HomePage({ initialDialog }: { initialDialog?: 'login' }
                 setMShowDisk={setMShowDisk}
                 mShowNet={mShowNet}
                 setMShowNet={setMShowNet}
+                mShowCores={mShowCores}
+                setMShowCores={setMShowCores}
+                mShowDocker={mShowDocker}
+                setMShowDocker={setMShowDocker}
+                mHost={mHost}
+                setMHost={setMHost}
+                cKey={cKey}
+                setCKey={setCKey}
+                cTitle={cTitle}
+                setCTitle={setCTitle}
+                cField={cField}
+                setCField={setCField}
+                iEmbed={iEmbed}
+                setIEmbed={setIEmbed}
+                sEngine={sEngine}
+                setSEngine={setSEngine}
+                qSource={qSource}
+                setQSource={setQSource}
+                pPlugin={pPlugin}
+                setPPlugin={setPPlugin}
+                pOptions={pOptions}
+                setPOptions={setPOptions}
                 mkSymbols={mkSymbols}
                 setMkSymbols={setMkSymbols}
                 mkQueries={mkQueries}
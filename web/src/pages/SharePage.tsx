@@ -0,0 +1,73 @@
+import { useEffect, useState } from 'react'
+import { useParams } from 'react-router-dom'
+import { sharesApi } from '../api'
+import { AppIcon } from '../components/cards'
+import type { SharedPage } from '../types'
+import { uiLanguage } from '../utils'
+
+/**
+ * 分享链接的只读页面：只展示分享的分组和链接，不加载任何管理功能
+ */
+export default function SharePage() {
+    const { token = '' } = useParams()
+    const [page, setPage] = useState<SharedPage | null>(null)
+    const [failed, setFailed] = useState(false)
+    const lang = uiLanguage(navigator.language)
+    const t = (zh: string, en: string) => (lang === 'en' ? en : zh)
+
+    useEffect(() => {
+        sharesApi
+            .get(token)
+            .then((p) => {
+                setPage(p)
+                document.title = `${p.name} · ${p.siteTitle}`
+            })
+            .catch(() => setFailed(true))
+    }, [token])
+
+    if (failed) {
+        return (
+            <div className="flex min-h-screen items-center justify-center text-white/70">
+                {t('分享链接不存在或已撤销', 'This link does not exist or was revoked')}
+            </div>
+        )
+    }
+    if (!page) return null
+
+    return (
+        <div className="mx-auto max-w-5xl px-6 py-10">
+            <h1 className="mb-8 text-2xl font-semibold text-white">{page.name}</h1>
+            {page.groups.map((g) => (
+                <section key={g.id || 'ungrouped'} className="mb-8">
+                    {g.name ? <h2 className="mb-3 text-sm font-medium text-white/80">{g.name}</h2> : null}
+                    <div className="grid grid-cols-2 gap-3 sm:grid-cols-3 lg:grid-cols-4">
+                        {g.apps.map((app) => (
+                            <a
+                                key={app.id}
+                                href={app.href}
+                                target={app.openMode === 'same_tab' ? undefined : '_blank'}
+                                rel="noreferrer"
+                                className="block rounded-2xl border border-white/10 tile-bg p-3 transition-all duration-200 ease-out hover:bg-black/30"
+                            >
+                                <div className="flex items-center gap-3">
+                                    <AppIcon iconPath={app.iconPath} name={app.name} />
+                                    <div className="min-w-0">
+                                        <div className="truncate text-sm font-medium text-white">{app.name}</div>
+                                        {app.descriptionHtml ? (
+                                            <div
+                                                className="app-markdown mt-1 line-clamp-2 text-xs text-white/70"
+                                                dangerouslySetInnerHTML={{ __html: app.descriptionHtml }}
+                                            />
+                                        ) : app.description ? (
+                                            <div className="mt-1 line-clamp-2 text-xs text-white/70">{app.description}</div>
+                                        ) : null}
+                                    </div>
+                                </div>
+                            </a>
+                        ))}
+                    </div>
+                </section>
+            ))}
+        </div>
+    )
+}
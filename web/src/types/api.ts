@@ -7,6 +7,8 @@
  */
 export interface Me {
     admin: boolean
+    // 使用自动生成的初始密码登录，改密码前不能做其他操作
+    mustChangePassword?: boolean
 }
 
 /**
@@ -14,6 +16,8 @@ export interface Me {
  */
 export interface ApiError {
     error?: string
+    // 设置校验失败时按字段路径（如 weather.provider）列出的错误
+    fields?: Record<string, string>
 }
 
 /**
@@ -5,15 +5,69 @@
 // 业务模型
 export type {
     Settings,
+    UserSettings,
+    SettingDef,
+    SettingChange,
     BackgroundSettings,
     TimeSettings,
     WeatherSettings,
+    ThemeSettings,
+    ThemeMode,
+    FormattingHints,
+    Customization,
+    LocaleInfo,
+    LocaleBundle,
     Group,
     AppItem,
+    AppOpenMode,
+    AppAction,
+    LinkPreview,
+    Share,
+    CreateShareResponse,
+    SharedApp,
+    SharedPage,
+    UndoEntry,
+    ReplicaStatus,
+    ReplicationInfo,
+    BackupTargetKind,
+    BackupTargetConfig,
+    BackupTarget,
+    BackupTargetRequest,
+    RemoteBackup,
     BackgroundInfo,
+    BackgroundUpload,
+    BackgroundHistoryItem,
+    BackgroundAction,
     Weather,
     WeatherDaily,
     HostMetrics,
+    NetInterfaceMetrics,
+    MountMetrics,
+    MetricHost,
+    DockerContainer,
+    DockerMetrics,
+    SmartDrive,
+    SmartMetrics,
+    IngestValue,
+    Embed,
+    SearchEngine,
+    SearchConfig,
+    Quote,
+    QuoteSource,
+    WidgetField,
+    PluginOption,
+    PluginInfo,
+    PluginOutput,
+    WidgetConfig,
+    WidgetCredential,
+    WidgetTypeInfo,
+    TemperatureSensor,
+    FanSensor,
+    BatteryMetrics,
+    UpsMetrics,
+    MetricsHistory,
+    MetricsHistoryPoint,
+    MetricsHistoryRange,
     MarketQuote,
     MarketsResponse,
     HolidayItem,
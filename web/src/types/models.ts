@@ -8,17 +8,72 @@
 export interface Settings {
     siteTitle: string
     language: Language
+    // 由 language 推导的数字/日期格式，只读
+    formatting?: FormattingHints
     background: BackgroundSettings
     time: TimeSettings
     timezones: string[]
     weather: WeatherSettings
+    theme?: ThemeSettings
+    // 仅管理员可见
+    network?: NetworkSettings
     titleSortOrder?: number
+    // 固定显示的组件 ID
+    pinnedWidgets?: string[]
+}
+
+/**
+ * 设置项的声明：类型、默认值与可选值（GET /api/settings/schema）
+ */
+export interface SettingDef {
+    key: string
+    // 在 Settings 中的字段路径，如 weather.provider
+    field: string
+    type: 'string' | 'enum' | 'bool' | 'int' | 'float' | 'duration'
+    default: string | number | boolean
+    values?: string[]
+    min?: number
+    max?: number
+}
+
+/**
+ * 设置的一次修改（GET /api/settings/history）；值为 null 表示未设置
+ */
+export interface SettingChange {
+    id: number
+    key: string
+    oldValue: string | null
+    newValue: string | null
+    actor: string
+    changedAt: number
+    // 密钥类设置不保留值，也不能撤销
+    redacted?: boolean
+    // 这次修改撤销了哪一条
+    revertOf?: number
+}
+
+/**
+ * 当前用户自己的设置，覆盖共享设置；省略的字段跟随共享设置
+ */
+export interface UserSettings {
+    // 传空字符串时改回共享语言
+    language?: Language | ''
+    units?: { system: string; bytes: string }
+    theme?: Partial<Omit<ThemeSettings, 'customCss'>>
+    pinnedWidgets?: string[]
+}
+
+export interface NetworkSettings {
+    // 视为内网的客户端网段，为空时使用私有地址段
+    internalCidrs: string[]
 }
 
 export interface BackgroundSettings {
     provider: BackgroundProvider
     unsplashQuery: string
     interval: string
+    // Bing 市场/地区，如 en-US、zh-CN
+    bingMarket?: string
 }
 
 export interface TimeSettings {
@@ -32,6 +87,62 @@ export interface WeatherSettings {
     city: string
 }
 
+/**
+ * 语言对应的格式约定（1.234,56 €、12/24 小时制、每周第一天）
+ */
+export interface FormattingHints {
+    locale: string
+    decimal: string
+    group: string
+    hour12: boolean
+    // 0 = 周日 … 6 = 周六，与 Date.getDay 一致
+    firstDayOfWeek: number
+    dateOrder: 'ymd' | 'dmy' | 'mdy'
+    currencyAfter: boolean
+}
+
+export type ThemeMode = 'light' | 'dark' | 'auto'
+
+export interface ThemeSettings {
+    // #rrggbb
+    accent: string
+    mode: ThemeMode
+    // 卡片背景不透明度，0-1
+    tileOpacity: number
+    // 字号缩放，0.75-1.5
+    fontScale: number
+    // 即 /api/customization 的 css，服务端按同样规则过滤
+    customCss: string
+}
+
+/**
+ * 有翻译目录的语言（GET /api/i18n）
+ */
+export interface LocaleInfo {
+    locale: string
+    // 该语言自身的名称，如 Deutsch
+    name: string
+}
+
+/**
+ * 翻译目录（GET /api/i18n/{locale}）
+ */
+export interface LocaleBundle {
+    locale: string
+    messages: Record<string, string>
+}
+
+/**
+ * 管理员自定义的 CSS / JS（GET /api/customization）
+ */
+export interface Customization {
+    css: string
+    // 仅在服务端启用 HEARTH_CUSTOM_JS 时返回
+    js: string
+    jsEnabled: boolean
+    allowRemoteUrls?: boolean
+}
+
 /**
  * 分组
  */
@@ -52,10 +163,188 @@ export interface AppItem {
     name: string
     description: string | null
     url: string
+    /** 局域网地址，内网访问时替代 url */
+    internalUrl: string | null
+    openMode: AppOpenMode
+    /** 网络唤醒的 MAC 地址 */
+    wakeMac: string | null
+    /** 键盘快捷键（按键序列，如 "g p"），在首页内唯一 */
+    shortcut: string | null
+    /** 快捷操作，仅管理员可见 */
+    actions: AppAction[]
+    /** 服务端按访问来源选出的链接 */
+    href?: string
+    /** 服务端渲染并过滤过的 Markdown 描述 */
+    descriptionHtml?: string
+    /** 抓取的页面标题与描述 */
+    preview: LinkPreview | null
+    /** 已归档：首页不显示，但保留配置 */
+    archived: boolean
     iconPath: string | null
     iconSource: string | null
     sortOrder: number
     createdAt: number
+    updatedAt: number
+}
+
+export type AppOpenMode = 'new_tab' | 'same_tab' | 'iframe'
+
+/**
+ * 链接预览（页面自己的标题与描述）
+ */
+export interface LinkPreview {
+    title: string
+    description: string
+    fetchedAt: number
+}
+
+/**
+ * App 快捷操作
+ */
+export interface AppAction {
+    id: string
+    label: string
+    kind: 'webhook' | 'docker_restart' | 'ssh'
+    url?: string
+    method?: string
+    body?: string
+    container?: string
+    runner?: string
+}
+
+/**
+ * 分享链接（令牌只在创建时返回一次）
+ */
+export interface Share {
+    id: string
+    name: string
+    /** 为空时分享整个首页 */
+    groupId: string | null
+    createdAt: number
+}
+
+export interface CreateShareResponse {
+    share: Share
+    token: string
+    url: string
+}
+
+/**
+ * 分享页面的只读数据
+ */
+export interface SharedApp {
+    id: string
+    name: string
+    description: string | null
+    descriptionHtml?: string
+    href: string
+    openMode: AppOpenMode
+    iconPath: string | null
+}
+
+export interface SharedPage {
+    name: string
+    siteTitle: string
+    groups: { id: string; name: string; apps: SharedApp[] }[]
+}
+
+/**
+ * 可撤销的操作（删除应用/分组、导入、重置）
+ */
+export interface UndoEntry {
+    id: string
+    op: 'delete_app' | 'delete_group' | 'import' | 'reset'
+    summary: string
+    createdAt: number
+    expiresAt: number
+}
+
+/**
+ * 副本从主实例同步配置的状态（GET /api/admin/replication）
+ */
+export interface ReplicaStatus {
+    primary: string
+    intervalSeconds: number
+    lastAttemptAt?: number
+    lastSyncAt?: number
+    // 最近一次实际改动了配置的同步
+    lastChangeAt?: number
+    // 主实例导出所应用配置的时间
+    exportedAt?: number
+    lastError?: string
+}
+
+/**
+ * 复制状态：本实例是否允许副本拉取，以及作为副本时的同步状态
+ */
+export interface ReplicationInfo {
+    tokenSet: boolean
+    replica: ReplicaStatus | null
+}
+
+export type BackupTargetKind = 's3' | 'webdav' | 'sftp'
+
+/**
+ * 远程备份目标的设置；各类型只用自己的字段，密钥不在其中
+ */
+export interface BackupTargetConfig {
+    // s3：密钥为 Secret Access Key
+    endpoint?: string
+    region?: string
+    bucket?: string
+    accessKey?: string
+    prefix?: string
+    pathStyle?: boolean
+    // webdav：密钥为密码
+    url?: string
+    username?: string
+    // sftp：密钥为可选的私钥
+    host?: string
+    port?: number
+    user?: string
+    path?: string
+    hostKey?: string
+}
+
+/**
+ * 远程备份目标（GET /api/admin/backups/targets）
+ */
+export interface BackupTarget {
+    id: number
+    name: string
+    kind: BackupTargetKind
+    config: BackupTargetConfig
+    hasSecret: boolean
+    // 目标上保留的备份数，0 表示全部保留
+    keep: number
+    intervalSeconds: number
+    enabled: boolean
+    createdAt: number
+    lastAttemptAt?: number
+    lastSuccessAt?: number
+    lastError?: string
+}
+
+/**
+ * 创建或更新备份目标；更新时 secret 留空则保留原密钥
+ */
+export interface BackupTargetRequest {
+    name: string
+    kind: BackupTargetKind
+    config: BackupTargetConfig
+    secret?: string
+    keep?: number
+    intervalSeconds?: number
+    enabled?: boolean
+}
+
+/**
+ * 备份目标上的一份备份
+ */
+export interface RemoteBackup {
+    key: string
+    size: number
+    modTime?: number
 }
 
 /**
@@ -64,8 +353,35 @@ export interface AppItem {
 export interface BackgroundInfo {
     provider: string
     imageUrl: string
+    // 图片署名（APOD / Wikimedia / Chromecast 提供）
+    title?: string
+    credit?: string
+    link?: string
+}
+
+export interface BackgroundUpload {
+    name: string
+    size: number
+    uploadedAt: string
+    url: string
 }
 
+/**
+ * 背景历史中的一张图片（最新在前）
+ */
+export interface BackgroundHistoryItem {
+    name: string
+    url: string
+    sourceUrl?: string
+    fetchedAt: string
+    current: boolean
+    title?: string
+    credit?: string
+    link?: string
+}
+
+export type BackgroundAction = 'refresh' | 'previous' | 'next'
+
 /**
  * 天气数据
  */
@@ -73,6 +389,8 @@ export interface Weather {
     city: string
     temperatureC: number
     weatherCode: number
+    // 服务端按请求语言给出的天气描述
+    label?: string
     windSpeedKph: number
     fetchedAt: number
     daily: WeatherDaily[]
@@ -81,6 +399,7 @@ export interface Weather {
 export interface WeatherDaily {
     date: string
     weatherCode: number
+    label?: string
     tempMaxC: number
     tempMinC: number
 }
@@ -99,8 +418,285 @@ export interface HostMetrics {
     diskUsed: number
     diskTotal: number
     diskPercent: number
+    /** 磁盘读写（字节累计与每秒速率） */
+    diskReadBytes?: number
+    diskWriteBytes?: number
+    diskReadRate?: number
+    diskWriteRate?: number
+    /** 每个逻辑核心的占用百分比 */
+    cpuPerCore?: number[]
+    /** 运行时长（秒）与 1/5/15 分钟负载 */
+    uptime?: number
+    load1?: number
+    load5?: number
+    load15?: number
     netBytesSent: number
     netBytesRecv: number
+    netSendRate?: number
+    netRecvRate?: number
+    /** 选中的网卡（设置中可按名称过滤） */
+    interfaces?: NetInterfaceMetrics[]
+    /** 各挂载点的磁盘占用（已排除 tmpfs/overlay 等） */
+    mounts?: MountMetrics[]
+    /** 温度传感器（含 nvidia-smi 报告的 GPU） */
+    temperatures?: TemperatureSensor[]
+    fans?: FanSensor[]
+    /** 笔记本电池 */
+    batteries?: BatteryMetrics[]
+    /** UPS（服务端配置 HEARTH_UPS 时） */
+    ups?: UpsMetrics
+}
+
+export interface TemperatureSensor {
+    sensor: string
+    celsius: number
+    high?: number
+    critical?: number
+}
+
+export interface FanSensor {
+    sensor: string
+    /** 主板风扇转速 */
+    rpm?: number
+    /** GPU 风扇占空比 */
+    percent?: number
+}
+
+export interface BatteryMetrics {
+    name: string
+    percent: number
+    status: 'charging' | 'discharging' | 'full' | 'not charging' | 'unknown'
+}
+
+export interface UpsMetrics {
+    name: string
+    /** NUT（如 "OL CHRG"）或 apcupsd（如 "ONBATT"）的原始状态 */
+    status: string
+    onBattery: boolean
+    lowBattery: boolean
+    charge: number
+    /** 剩余续航（秒） */
+    runtime?: number
+    load?: number
+}
+
+/**
+ * 主机指标历史（按 step 秒降采样的平均值）
+ */
+export type MetricsHistoryRange = '1h' | '24h' | '7d'
+
+export interface MetricsHistoryPoint {
+    /** unix 秒 */
+    t: number
+    cpu: number
+    mem: number
+    disk: number
+    netSend: number
+    netRecv: number
+    diskRead: number
+    diskWrite: number
+}
+
+export interface MetricsHistory {
+    range: MetricsHistoryRange
+    step: number
+    points: MetricsHistoryPoint[]
+}
+
+/**
+ * Docker 容器资源占用（/api/metrics/docker）
+ */
+export interface DockerContainer {
+    id: string
+    name: string
+    image: string
+    state: string
+    status: string
+    /** 相对单核，可超过 100 */
+    cpuPercent: number
+    memUsed: number
+    memLimit: number
+    memPercent: number
+    netRx: number
+    netTx: number
+    netRxRate: number
+    netTxRate: number
+}
+
+export interface DockerMetrics {
+    containers: DockerContainer[]
+}
+
+/**
+ * 硬盘 SMART 健康状态（/api/metrics/smart）
+ */
+export interface SmartDrive {
+    device: string
+    model?: string
+    /** 仅管理员可见 */
+    serial?: string
+    health: 'passed' | 'failed' | 'unknown' | 'standby'
+    temperature?: number
+    /** 重映射扇区数（NVMe 为介质错误数） */
+    reallocated: number
+    powerOnHours?: number
+    percentUsed?: number
+}
+
+export interface SmartMetrics {
+    drives: SmartDrive[]
+}
+
+/**
+ * 管理员在设置中登记的嵌入网页；iframe 组件只按 id 引用
+ */
+export interface Embed {
+    id: string
+    name: string
+    url: string
+    /** iframe sandbox 属性 */
+    sandbox: string[]
+    height: number
+}
+
+/**
+ * 搜索引擎；url 中的 {q} 会被替换为查询词
+ */
+export interface SearchEngine {
+    id: string
+    name: string
+    url: string
+}
+
+export interface SearchConfig {
+    engines: SearchEngine[]
+    defaultEngine: string
+    /** 快捷方式（不含 !）到 URL 模板 */
+    bangs: Record<string, string>
+    /** 是否允许浏览器在本机保存搜索历史 */
+    history?: boolean
+}
+
+/**
+ * 每日一句
+ */
+export type QuoteSource = 'bundled' | 'quotable' | 'custom'
+
+export interface Quote {
+    text: string
+    author?: string
+    /** 实际使用的来源；外部来源不可用时回退为 bundled */
+    source: QuoteSource
+    date: string
+}
+
+/**
+ * 插件（plugins 目录中安装的第三方组件）
+ */
+export interface PluginOption {
+    name: string
+    label?: string
+    default?: string
+}
+
+export interface PluginInfo {
+    id: string
+    name: string
+    description?: string
+    options: PluginOption[]
+}
+
+/**
+ * 插件输出
+ */
+export interface PluginOutput {
+    title?: string
+    value?: string
+    unit?: string
+    items?: { label: string; value: string }[]
+    url?: string
+}
+
+/**
+ * 组件配置的字段描述（服务端按此校验）
+ */
+export interface WidgetField {
+    name: string
+    type: 'string' | 'bool' | 'int' | 'strings' | 'objects'
+    enum?: Array<string | number>
+    maxLength?: number
+    maxItems?: number
+    fields?: WidgetField[]
+}
+
+/**
+ * 组件依赖的上游凭据
+ */
+export interface WidgetCredential {
+    id: string
+    /** 配置位置：设置字段或管理接口 */
+    setting: string
+    /** 当前所选数据源是否需要 */
+    required: boolean
+    configured: boolean
+}
+
+/**
+ * /api/widgets/registry 中的组件类型
+ */
+export interface WidgetTypeInfo {
+    id: string
+    name: string
+    schema: WidgetField[]
+    defaultConfig: Record<string, unknown>
+    credentials: WidgetCredential[]
+}
+
+export interface WidgetConfig<T = Record<string, unknown>> {
+    appId: string
+    kind: string
+    config: T
+    schema: WidgetField[]
+    updatedAt: number
+}
+
+/**
+ * 通过 /api/ingest/{key} 推送的自定义数据
+ */
+export interface IngestValue {
+    key: string
+    value: unknown
+    updatedAt: number
+}
+
+/**
+ * 远程主机（运行 hearth --agent）
+ */
+export interface MetricHost {
+    name: string
+    /** 仅管理员可见 */
+    url?: string
+    online: boolean
+    lastSeen?: number
+    error?: string
+}
+
+export interface NetInterfaceMetrics {
+    name: string
+    bytesSent: number
+    bytesRecv: number
+    sendRate: number
+    recvRate: number
+}
+
+export interface MountMetrics {
+    path: string
+    /** HEARTH_METRICS_DISK_PATHS 中配置的名称 */
+    label?: string
+    fsType: string
+    used: number
+    total: number
+    percent: number
 }
 
 /**
@@ -111,6 +707,9 @@ export interface MarketQuote {
     kind: MarketKind
     name?: string
     priceUsd: number
+    // 按设置换算后的价格与币种（ISO 4217 或加密货币报价资产）
+    price?: number
+    currency?: string
     changePct24h: number
     series: number[]
 }
@@ -147,6 +746,16 @@ export type Language = 'zh' | 'en' | string
 
 export type GroupKind = 'system' | 'app' | string
 
-export type BackgroundProvider = 'bing' | 'bing_daily' | 'bing_random' | 'picsum' | 'default' | string
+export type BackgroundProvider =
+    | 'bing'
+    | 'bing_daily'
+    | 'bing_random'
+    | 'picsum'
+    | 'apod'
+    | 'wikimedia'
+    | 'chromecast'
+    | 'custom'
+    | 'default'
+    | string
 
 export type MarketKind = 'stock' | 'crypto' | string
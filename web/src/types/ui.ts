@@ -5,12 +5,12 @@
 /**
  * Widget 类型
  */
-export type WidgetKind = 'weather' | 'metrics' | 'timezones' | 'markets' | 'holidays'
+export type WidgetKind = 'weather' | 'metrics' | 'timezones' | 'markets' | 'holidays' | 'custom' | 'iframe' | 'search' | 'quote' | 'plugin'
 
 /**
  * 设置对话框标签页
  */
-export type SettingsTab = 'general' | 'time' | 'background' | 'account'
+export type SettingsTab = 'general' | 'time' | 'background' | 'appearance' | 'account'
 
 /**
  * 图标模式
@@ -33,6 +33,12 @@ export interface MetricsConfig {
     showMem: boolean
     showDisk: boolean
     showNet: boolean
+    /** 每个核心的占用热力条（默认关闭） */
+    showCores?: boolean
+    /** Docker 容器占用（默认关闭） */
+    showDocker?: boolean
+    /** 远程主机名（hearth --agent），为空表示本机 */
+    host?: string
     refreshSec: 1 | 5 | 10
 }
 
@@ -2,7 +2,7 @@
  * 格式化函数
  */
 
-import type { Language } from '../types'
+import type { FormattingHints, Language } from '../types'
 
 /**
  * 格式化字节数
@@ -35,6 +35,31 @@ export function formatPrice(price: number): string {
     return price.toFixed(4)
 }
 
+/**
+ * 按服务端给出的格式约定格式化数字，如德语 1.234,56
+ */
+export function formatNumber(value: number, hints: FormattingHints | null | undefined, fractionDigits = 2): string {
+    if (!Number.isFinite(value)) return '—'
+    const [int, frac] = Math.abs(value).toFixed(fractionDigits).split('.')
+    const group = hints?.group ?? ','
+    const grouped = int.replace(/\B(?=(\d{3})+(?!\d))/g, group)
+    const sign = value < 0 ? '-' : ''
+    return sign + (frac ? grouped + (hints?.decimal ?? '.') + frac : grouped)
+}
+
+const CURRENCY_SYMBOLS: Record<string, string> = { USD: '$', EUR: '€', GBP: '£', CNY: '¥', JPY: '¥' }
+
+/**
+ * 按格式约定格式化金额：$1,234.56 或 1.234,56 €
+ */
+export function formatMoney(value: number, currency: string, hints: FormattingHints | null | undefined, fractionDigits = 2): string {
+    const code = String(currency || 'USD').toUpperCase()
+    const symbol = CURRENCY_SYMBOLS[code] ?? code
+    const num = formatNumber(value, hints, fractionDigits)
+    if (num === '—') return num
+    return hints?.currencyAfter ? `${num}\u00a0${symbol}` : symbol.length > 1 ? `${symbol}\u00a0${num}` : `${symbol}${num}`
+}
+
 /**
  * 格式化时间
  */
@@ -163,6 +188,20 @@ export function formatGiB(bytes: number): string {
     return `${Math.round(gib)}GiB`
 }
 
+/**
+ * 格式化运行时长（秒），如 3d 4h / 3天4小时
+ */
+export function formatUptime(seconds: number, lang: 'zh' | 'en'): string {
+    if (!Number.isFinite(seconds) || seconds < 0) return '—'
+    const d = Math.floor(seconds / 86400)
+    const h = Math.floor((seconds % 86400) / 3600)
+    const m = Math.floor((seconds % 3600) / 60)
+    if (lang === 'zh') {
+        return d > 0 ? `${d}天${h}小时` : h > 0 ? `${h}小时${m}分` : `${m}分`
+    }
+    return d > 0 ? `${d}d ${h}h` : h > 0 ? `${h}h ${m}m` : `${m}m`
+}
+
 /**
  * 获取星期几标签
  */
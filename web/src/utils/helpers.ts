@@ -169,6 +169,14 @@ export function createTranslator(lang: 'zh' | 'en') {
     return (zh: string, en: string) => (lang === 'en' ? en : zh)
 }
 
+/**
+ * 界面文案只有中英文：中文类语言显示中文，其他语言（de、fr-CA…）显示英文
+ */
+export function uiLanguage(locale?: string | null): 'zh' | 'en' {
+    const primary = String(locale ?? 'zh').trim().toLowerCase().split(/[-_]/)[0]
+    return primary === 'zh' || primary === '' ? 'zh' : 'en'
+}
+
 /**
  * 显示分组名称（支持中英文切换）
  */
@@ -18,6 +18,8 @@ export {
     formatBytes,
     formatPercent,
     formatPrice,
+    formatNumber,
+    formatMoney,
     formatTime,
     formatDate,
     formatDateStr,
@@ -26,6 +28,7 @@ export {
     ymdKey,
     formatBytesPerSec,
     formatGiB,
+    formatUptime,
     weekdayLabel,
     shortenCpuModelName,
 } from './formatting'
@@ -45,6 +48,7 @@ export {
     isWidgetItem,
     isSystemGroup,
     createTranslator,
+    uiLanguage,
     displayGroupName,
     timeZoneOffsetMinutes,
     tzDeltaMeta,